@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/ai"
+	"perfugo/internal/config"
+	"perfugo/internal/db"
+	"perfugo/internal/db/mock"
+	"perfugo/models"
+)
+
+type options struct {
+	DryRun   bool
+	Interval time.Duration
+	Limit    int
+}
+
+type enrichSummary struct {
+	Scanned int
+	Updated int
+	Skipped int
+	Failed  int
+}
+
+func main() {
+	var opts options
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "print the changes without writing them")
+	flag.DurationVar(&opts.Interval, "interval", time.Second, "minimum delay between AI requests")
+	flag.IntVar(&opts.Limit, "limit", 0, "maximum number of ingredients to process (0 for all)")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "enrichment failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts options) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if cfg.Library.ScalesFile != "" {
+		if err := models.LoadScalesFile(cfg.Library.ScalesFile); err != nil {
+			return err
+		}
+	}
+
+	database, err := openDatabase(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	var client ai.Client
+	if cfg.AI.UseMock {
+		client = ai.NewMockClient()
+	} else {
+		openAIClient, err := ai.NewClient(ai.Config{
+			APIKey:  cfg.AI.APIKey,
+			Model:   cfg.AI.Model,
+			BaseURL: cfg.AI.BaseURL,
+			Timeout: cfg.AI.RequestTimeout,
+		})
+		if err != nil {
+			return fmt.Errorf("configure ai client: %w", err)
+		}
+		client = openAIClient
+	}
+
+	summary, err := enrich(ctx, database, client, opts, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	mode := "Updated"
+	if opts.DryRun {
+		mode = "Would update"
+	}
+	fmt.Fprintf(os.Stdout, "Scanned %d ingredients. %s %d, skipped %d, failed %d.\n", summary.Scanned, mode, summary.Updated, summary.Skipped, summary.Failed)
+	return nil
+}
+
+// openDatabase connects to DATABASE_URL. The throwaway mock database is only
+// used when DATABASE_USE_MOCK asks for it, so a missing URL cannot turn a run
+// into one that reports updates but keeps none.
+func openDatabase(ctx context.Context, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	if cfg.UseMock {
+		database, err := mock.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("open mock database: %w", err)
+		}
+		return database, nil
+	}
+	if strings.TrimSpace(cfg.URL) == "" {
+		return nil, errors.New("DATABASE_URL is required")
+	}
+	database, err := db.Initialize(cfg)
+	if err == nil {
+		err = db.AutoMigrate(database)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return database, nil
+}
+
+// enrich fills missing pyramid, IFRA and usage fields for every incomplete
+// ingredient, pacing AI requests by opts.Interval.
+func enrich(ctx context.Context, database *gorm.DB, client ai.Client, opts options, out io.Writer) (enrichSummary, error) {
+	var summary enrichSummary
+	if database == nil {
+		return summary, errors.New("database handle is nil")
+	}
+	if client == nil {
+		return summary, errors.New("ai client is nil")
+	}
+
+	query := database.WithContext(ctx).
+		Where("pyramid_position = '' OR pyramid_position IS NULL OR max_ifra_percentage = 0 OR max_ifra_percentage IS NULL OR usage = '' OR usage IS NULL").
+		Order("id asc")
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	var chemicals []models.AromaChemical
+	if err := query.Find(&chemicals).Error; err != nil {
+		return summary, fmt.Errorf("load incomplete ingredients: %w", err)
+	}
+
+	var throttle <-chan time.Time
+	if opts.Interval > 0 {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	for idx := range chemicals {
+		chemical := &chemicals[idx]
+		summary.Scanned++
+
+		if idx > 0 && throttle != nil {
+			select {
+			case <-ctx.Done():
+				return summary, ctx.Err()
+			case <-throttle:
+			}
+		}
+
+		profile, err := client.FetchAromaProfile(ctx, chemical.IngredientName, ai.FetchOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return summary, ctx.Err()
+			}
+			summary.Failed++
+			fmt.Fprintf(out, "! %s: %v\n", chemical.IngredientName, err)
+			continue
+		}
+
+		updates := missingFieldUpdates(*chemical, profile)
+		if len(updates) == 0 {
+			summary.Skipped++
+			fmt.Fprintf(out, "- %s: no new data\n", chemical.IngredientName)
+			continue
+		}
+
+		for _, column := range []string{"pyramid_position", "max_ifra_percentage", "usage"} {
+			if value, ok := updates[column]; ok {
+				fmt.Fprintf(out, "+ %s: %s = %v\n", chemical.IngredientName, column, value)
+			}
+		}
+
+		if !opts.DryRun {
+			updates["ai_model"] = profile.Model
+			updates["ai_fetched_at"] = time.Now()
+			if err := database.WithContext(ctx).Model(chemical).Updates(updates).Error; err != nil {
+				summary.Failed++
+				fmt.Fprintf(out, "! %s: save: %v\n", chemical.IngredientName, err)
+				continue
+			}
+		}
+		summary.Updated++
+	}
+
+	return summary, nil
+}
+
+func missingFieldUpdates(chemical models.AromaChemical, profile ai.Profile) map[string]any {
+	updates := map[string]any{}
+	if strings.TrimSpace(chemical.PyramidPosition) == "" && strings.TrimSpace(profile.PyramidPosition) != "" {
+		updates["pyramid_position"] = strings.TrimSpace(profile.PyramidPosition)
+	}
+	if chemical.MaxIFRAPercentage == 0 && profile.MaxIFRAPercentage > 0 {
+		updates["max_ifra_percentage"] = profile.MaxIFRAPercentage
+	}
+	if strings.TrimSpace(chemical.Usage) == "" && strings.TrimSpace(profile.Usage) != "" {
+		updates["usage"] = strings.TrimSpace(profile.Usage)
+	}
+	return updates
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/internal/ai"
+	"perfugo/internal/config"
+	"perfugo/models"
+)
+
+func newEnrichTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:enrich-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.AromaChemical{}, &models.OtherName{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	return db
+}
+
+func TestEnrichFillsOnlyMissingFields(t *testing.T) {
+	db := newEnrichTestDB(t)
+
+	complete := models.AromaChemical{IngredientName: "Iso E Super", PyramidPosition: "Base", MaxIFRAPercentage: 21.4, Usage: "Woody volume."}
+	partial := models.AromaChemical{IngredientName: "Hedione", PyramidPosition: "Top"}
+	for _, chemical := range []*models.AromaChemical{&complete, &partial} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("seed chemical: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	summary, err := enrich(context.Background(), db, ai.NewMockClient(), options{}, &out)
+	if err != nil {
+		t.Fatalf("enrich returned error: %v", err)
+	}
+	if summary.Scanned != 1 || summary.Updated != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	var reloaded models.AromaChemical
+	if err := db.First(&reloaded, partial.ID).Error; err != nil {
+		t.Fatalf("reload chemical: %v", err)
+	}
+	if reloaded.PyramidPosition != "Top" {
+		t.Fatalf("expected existing pyramid to be preserved, got %q", reloaded.PyramidPosition)
+	}
+	if reloaded.Usage == "" {
+		t.Fatal("expected usage to be filled")
+	}
+	if !bytes.Contains(out.Bytes(), []byte("+ Hedione: usage")) {
+		t.Fatalf("expected change output, got %q", out.String())
+	}
+}
+
+func TestEnrichDryRunLeavesRecordsUntouched(t *testing.T) {
+	db := newEnrichTestDB(t)
+
+	chemical := models.AromaChemical{IngredientName: "Ambroxan"}
+	if err := db.Create(&chemical).Error; err != nil {
+		t.Fatalf("seed chemical: %v", err)
+	}
+
+	var out bytes.Buffer
+	summary, err := enrich(context.Background(), db, ai.NewMockClient(), options{DryRun: true}, &out)
+	if err != nil {
+		t.Fatalf("enrich returned error: %v", err)
+	}
+	if summary.Updated != 1 {
+		t.Fatalf("expected one planned update, got %+v", summary)
+	}
+
+	var reloaded models.AromaChemical
+	if err := db.First(&reloaded, chemical.ID).Error; err != nil {
+		t.Fatalf("reload chemical: %v", err)
+	}
+	if reloaded.PyramidPosition != "" || reloaded.Usage != "" {
+		t.Fatalf("dry run should not persist changes, got %+v", reloaded)
+	}
+}
+
+func TestOpenDatabaseRequiresURLUnlessMocked(t *testing.T) {
+	if _, err := openDatabase(context.Background(), config.DatabaseConfig{}); err == nil || err.Error() != "DATABASE_URL is required" {
+		t.Fatalf("expected a missing DATABASE_URL to fail, got %v", err)
+	}
+	if _, err := openDatabase(context.Background(), config.DatabaseConfig{UseMock: true}); err != nil {
+		t.Fatalf("expected the mock database when asked for, got %v", err)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"perfugo/internal/ai"
+	"perfugo/internal/config"
+)
+
+// expectedSuffix names the file holding a case's expected extraction.
+const expectedSuffix = ".expected.json"
+
+type options struct {
+	Dir               string
+	Model             string
+	Baseline          string
+	Out               string
+	QuantityTolerance float64
+	MaxDrop           float64
+}
+
+// evalCase is one sample document and the formula it should yield.
+type evalCase struct {
+	Name     string
+	Path     string
+	Expected ai.FormulaImportResult
+}
+
+// metrics score an extraction against its expectation, each from 0 to 1.
+// Precision is the share of extracted ingredients that were expected,
+// Recall the share of expected ingredients that were found, and Quantity
+// the share of found ingredients whose proportion of the formula is within
+// tolerance.
+type metrics struct {
+	Name      float64 `json:"name"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// caseResult is the outcome of one case.
+type caseResult struct {
+	Case    string   `json:"case"`
+	Error   string   `json:"error,omitempty"`
+	Metrics metrics  `json:"metrics"`
+	Missed  []string `json:"missed,omitempty"`
+	Extra   []string `json:"extra,omitempty"`
+}
+
+// report is what a run produces and what later runs compare against.
+type report struct {
+	Model   string       `json:"model"`
+	RanAt   time.Time    `json:"ran_at"`
+	Cases   []caseResult `json:"cases"`
+	Overall metrics      `json:"overall"`
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.Dir, "dir", "evals/formulas", "directory of sample documents, each with a <name>"+expectedSuffix+" file")
+	flag.StringVar(&opts.Model, "model", "", "model to evaluate instead of OPENAI_MODEL")
+	flag.StringVar(&opts.Baseline, "baseline", "", "earlier report to compare against")
+	flag.StringVar(&opts.Out, "out", "", "write this run's report as JSON to the file")
+	flag.Float64Var(&opts.QuantityTolerance, "quantity-tolerance", 1, "percentage points an ingredient's share may differ and still count as correct")
+	flag.Float64Var(&opts.MaxDrop, "max-drop", 0.02, "fail when an overall score drops by more than this against the baseline")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "evaluation failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts options) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	model := cfg.AI.Model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	var client ai.Client
+	if cfg.AI.UseMock {
+		client = ai.NewMockClient()
+		model = "mock"
+	} else {
+		openAIClient, err := ai.NewClient(ai.Config{
+			APIKey:  cfg.AI.APIKey,
+			Model:   model,
+			BaseURL: cfg.AI.BaseURL,
+			Timeout: cfg.AI.RequestTimeout,
+		})
+		if err != nil {
+			return fmt.Errorf("configure ai client: %w", err)
+		}
+		client = openAIClient
+	}
+
+	cases, err := loadCases(opts.Dir)
+	if err != nil {
+		return err
+	}
+	current := evaluate(ctx, client, cases, opts.QuantityTolerance)
+	current.Model = model
+	current.RanAt = time.Now().UTC()
+
+	var baseline *report
+	if opts.Baseline != "" {
+		data, err := os.ReadFile(opts.Baseline)
+		if err != nil {
+			return fmt.Errorf("read baseline: %w", err)
+		}
+		baseline = &report{}
+		if err := json.Unmarshal(data, baseline); err != nil {
+			return fmt.Errorf("parse baseline: %w", err)
+		}
+	}
+	regressed := printReport(os.Stdout, current, baseline, opts.MaxDrop)
+
+	if opts.Out != "" {
+		data, err := json.MarshalIndent(current, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode report: %w", err)
+		}
+		if err := os.WriteFile(opts.Out, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+	}
+	if len(regressed) > 0 {
+		return fmt.Errorf("scores dropped against the baseline: %s", strings.Join(regressed, ", "))
+	}
+	return nil
+}
+
+// loadCases reads every document in dir that has an expectation beside it.
+func loadCases(dir string) ([]evalCase, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read eval set: %w", err)
+	}
+	var cases []evalCase
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, expectedSuffix) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		data, err := os.ReadFile(filepath.Join(dir, base+expectedSuffix))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read expectation for %s: %w", name, err)
+		}
+		c := evalCase{Name: base, Path: filepath.Join(dir, name)}
+		if err := json.Unmarshal(data, &c.Expected); err != nil {
+			return nil, fmt.Errorf("parse expectation for %s: %w", name, err)
+		}
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no cases with %s files in %s", expectedSuffix, dir)
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// evaluate runs each case through ExtractFormula, preparing documents as
+// the formula import tool does, and scores the results.
+func evaluate(ctx context.Context, client ai.Client, cases []evalCase, tolerance float64) report {
+	var result report
+	for _, c := range cases {
+		outcome := caseResult{Case: c.Name}
+		input, err := caseInput(c)
+		var extracted ai.FormulaImportResult
+		if err == nil {
+			extracted, err = client.ExtractFormula(ctx, input)
+		}
+		if err != nil {
+			outcome.Error = err.Error()
+		} else {
+			outcome.Metrics, outcome.Missed, outcome.Extra = score(c.Expected, extracted, tolerance)
+		}
+		result.Cases = append(result.Cases, outcome)
+	}
+	for _, outcome := range result.Cases {
+		result.Overall.Name += outcome.Metrics.Name
+		result.Overall.Precision += outcome.Metrics.Precision
+		result.Overall.Recall += outcome.Metrics.Recall
+		result.Overall.Quantity += outcome.Metrics.Quantity
+	}
+	if n := float64(len(result.Cases)); n > 0 {
+		result.Overall = metrics{
+			Name:      result.Overall.Name / n,
+			Precision: result.Overall.Precision / n,
+			Recall:    result.Overall.Recall / n,
+			Quantity:  result.Overall.Quantity / n,
+		}
+	}
+	return result
+}
+
+func caseInput(c evalCase) (ai.FormulaImportInput, error) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return ai.FormulaImportInput{}, err
+	}
+	mime := ai.MimeTypeFromName(c.Path)
+	text, encoded, err := ai.DocumentText(data, mime)
+	if err != nil {
+		return ai.FormulaImportInput{}, fmt.Errorf("read document: %w", err)
+	}
+	input := ai.FormulaImportInput{RawText: strings.TrimSpace(text)}
+	if input.RawText == "" && encoded != "" {
+		input.Base64File, input.FileName, input.FileType = encoded, filepath.Base(c.Path), mime
+	}
+	return input, nil
+}
+
+// score compares an extraction with its expectation. Ingredients match on
+// their name or any expected alias, ignoring case and spacing; quantities
+// are compared as shares of the formula, as the model may scale them.
+func score(expected, got ai.FormulaImportResult, tolerance float64) (metrics, []string, []string) {
+	var m metrics
+	if normalize(expected.FormulaName) == normalize(got.FormulaName) {
+		m.Name = 1
+	}
+
+	expectedShares := shares(expected.Ingredients)
+	gotShares := shares(got.Ingredients)
+	matched := make([]bool, len(got.Ingredients))
+	found, accurate := 0, 0
+	var missed []string
+	for i, want := range expected.Ingredients {
+		keys := map[string]bool{normalize(want.IngredientName): true}
+		for _, alias := range want.OtherNames {
+			keys[normalize(alias)] = true
+		}
+		hit := -1
+		for j, ingredient := range got.Ingredients {
+			if !matched[j] && keys[normalize(ingredient.IngredientName)] {
+				hit = j
+				break
+			}
+		}
+		if hit < 0 {
+			missed = append(missed, want.IngredientName)
+			continue
+		}
+		matched[hit] = true
+		found++
+		if math.Abs(expectedShares[i]-gotShares[hit]) <= tolerance {
+			accurate++
+		}
+	}
+	var extra []string
+	for j, ingredient := range got.Ingredients {
+		if !matched[j] {
+			extra = append(extra, ingredient.IngredientName)
+		}
+	}
+
+	m.Recall = ratio(found, len(expected.Ingredients))
+	m.Precision = ratio(found, len(got.Ingredients))
+	m.Quantity = ratio(accurate, found)
+	return m, missed, extra
+}
+
+// shares expresses each ingredient's quantity as a percentage of the total.
+func shares(ingredients []ai.FormulaImportIngredient) []float64 {
+	total := 0.0
+	for _, ingredient := range ingredients {
+		total += ingredient.QuantityMG
+	}
+	result := make([]float64, len(ingredients))
+	if total <= 0 {
+		return result
+	}
+	for i, ingredient := range ingredients {
+		result[i] = ingredient.QuantityMG / total * 100
+	}
+	return result
+}
+
+func ratio(n, of int) float64 {
+	if of == 0 {
+		return 1
+	}
+	return float64(n) / float64(of)
+}
+
+func normalize(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// printReport writes the run's scores, with their change from baseline
+// when one is given, and returns the overall scores that fell by more than
+// maxDrop.
+func printReport(out io.Writer, current report, baseline *report, maxDrop float64) []string {
+	previous := map[string]metrics{}
+	if baseline != nil {
+		fmt.Fprintf(out, "Model %s against baseline %s from %s\n\n", current.Model, baseline.Model, baseline.RanAt.Format("2006-01-02"))
+		for _, outcome := range baseline.Cases {
+			previous[outcome.Case] = outcome.Metrics
+		}
+	} else {
+		fmt.Fprintf(out, "Model %s\n\n", current.Model)
+	}
+
+	format := func(now, before float64, compare bool) string {
+		if !compare {
+			return fmt.Sprintf("%5.1f%%", now*100)
+		}
+		return fmt.Sprintf("%5.1f%% (%+.1f)", now*100, (now-before)*100)
+	}
+	row := func(label string, m metrics, before metrics, compare bool) {
+		fmt.Fprintf(out, "%-24s name %s  precision %s  recall %s  quantity %s\n", label,
+			format(m.Name, before.Name, compare), format(m.Precision, before.Precision, compare),
+			format(m.Recall, before.Recall, compare), format(m.Quantity, before.Quantity, compare))
+	}
+	for _, outcome := range current.Cases {
+		before, ok := previous[outcome.Case]
+		if outcome.Error != "" {
+			fmt.Fprintf(out, "%-24s error: %s\n", outcome.Case, outcome.Error)
+			continue
+		}
+		row(outcome.Case, outcome.Metrics, before, ok)
+		if len(outcome.Missed) > 0 {
+			fmt.Fprintf(out, "%-24s   missed: %s\n", "", strings.Join(outcome.Missed, ", "))
+		}
+		if len(outcome.Extra) > 0 {
+			fmt.Fprintf(out, "%-24s   unexpected: %s\n", "", strings.Join(outcome.Extra, ", "))
+		}
+	}
+	fmt.Fprintln(out)
+	var overallBefore metrics
+	if baseline != nil {
+		overallBefore = baseline.Overall
+	}
+	row("overall", current.Overall, overallBefore, baseline != nil)
+
+	if baseline == nil {
+		return nil
+	}
+	var regressed []string
+	for _, check := range []struct {
+		name        string
+		now, before float64
+	}{
+		{"name", current.Overall.Name, overallBefore.Name},
+		{"precision", current.Overall.Precision, overallBefore.Precision},
+		{"recall", current.Overall.Recall, overallBefore.Recall},
+		{"quantity", current.Overall.Quantity, overallBefore.Quantity},
+	} {
+		if check.before-check.now > maxDrop+1e-9 {
+			regressed = append(regressed, check.name)
+		}
+	}
+	return regressed
+}
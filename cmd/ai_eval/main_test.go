@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"perfugo/internal/ai"
+)
+
+func TestScoreMatchesAliasesAndShares(t *testing.T) {
+	expected := ai.FormulaImportResult{
+		FormulaName: "Nuit",
+		Ingredients: []ai.FormulaImportIngredient{
+			{IngredientName: "Iso E Super", OtherNames: []string{"OTNE"}, QuantityMG: 500},
+			{IngredientName: "Hedione", QuantityMG: 300},
+			{IngredientName: "Ambroxan", QuantityMG: 200},
+		},
+	}
+	got := ai.FormulaImportResult{
+		FormulaName: " nuit ",
+		Ingredients: []ai.FormulaImportIngredient{
+			{IngredientName: "otne", QuantityMG: 50},
+			{IngredientName: "Hedione", QuantityMG: 40},
+			{IngredientName: "Calone", QuantityMG: 10},
+		},
+	}
+
+	m, missed, extra := score(expected, got, 1)
+	if m.Name != 1 || m.Recall != 2.0/3 || m.Precision != 2.0/3 || m.Quantity != 0.5 {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+	if len(missed) != 1 || missed[0] != "Ambroxan" || len(extra) != 1 || extra[0] != "Calone" {
+		t.Fatalf("unexpected differences: missed %v, extra %v", missed, extra)
+	}
+}
+
+func TestEvaluateReportsRegressions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("accord.txt", "Hedione 600\nIso E Super 400\n")
+	write("accord.expected.json", `{"formula_name":"Mock Imported Formula","ingredients":[{"ingredient_name":"Hedione","quantity_mg":60},{"ingredient_name":"Iso E Super","quantity_mg":40}]}`)
+	write("notes.md", "ignored: no expectation")
+
+	cases, err := loadCases(dir)
+	if err != nil {
+		t.Fatalf("load cases: %v", err)
+	}
+	if len(cases) != 1 {
+		t.Fatalf("expected one case, got %d", len(cases))
+	}
+	current := evaluate(context.Background(), ai.NewMockClient(), cases, 1)
+	if current.Overall != (metrics{Name: 1, Precision: 1, Recall: 1, Quantity: 1}) {
+		t.Fatalf("unexpected overall metrics: %+v", current.Overall)
+	}
+
+	var out bytes.Buffer
+	if regressed := printReport(&out, current, &current, 0.02); len(regressed) != 0 {
+		t.Fatalf("identical runs should not regress: %v", regressed)
+	}
+	worse := current
+	worse.Overall.Recall = 0.9
+	out.Reset()
+	regressed := printReport(&out, worse, &current, 0.02)
+	if len(regressed) != 1 || regressed[0] != "recall" || !strings.Contains(out.String(), "(-10.0)") {
+		t.Fatalf("expected a recall regression, got %v:\n%s", regressed, out.String())
+	}
+}
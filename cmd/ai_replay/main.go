@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/ai"
+	"perfugo/internal/config"
+	"perfugo/internal/db"
+	"perfugo/internal/db/mock"
+	"perfugo/models"
+)
+
+type options struct {
+	ID     uint
+	JobID  string
+	All    bool
+	Limit  int
+	Replay bool
+}
+
+func main() {
+	var opts options
+	var id uint64
+	flag.Uint64Var(&id, "id", 0, "show the recorded exchange with this ID")
+	flag.StringVar(&opts.JobID, "job", "", "list the exchanges recorded for an import job")
+	flag.BoolVar(&opts.All, "all", false, "list successful exchanges as well as failed ones")
+	flag.IntVar(&opts.Limit, "limit", 20, "maximum number of exchanges to list")
+	flag.BoolVar(&opts.Replay, "replay", false, "with -id, run the recorded input again with the current prompt and model")
+	flag.Parse()
+	opts.ID = uint(id)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "ai replay failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts options) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	var database *gorm.DB
+	if cfg.Database.UseMock || strings.TrimSpace(cfg.Database.URL) == "" {
+		database, err = mock.New(ctx)
+	} else {
+		database, err = db.Initialize(cfg.Database)
+		if err == nil {
+			err = db.AutoMigrate(database)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+
+	if opts.ID == 0 {
+		return list(ctx, database, opts, os.Stdout)
+	}
+	exchange, err := show(ctx, database, opts.ID, os.Stdout)
+	if err != nil || !opts.Replay {
+		return err
+	}
+
+	var client ai.Client
+	if cfg.AI.UseMock {
+		client = ai.NewMockClient()
+	} else {
+		openAIClient, err := ai.NewClient(ai.Config{
+			APIKey:  cfg.AI.APIKey,
+			Model:   cfg.AI.Model,
+			BaseURL: cfg.AI.BaseURL,
+			Timeout: cfg.AI.RequestTimeout,
+		})
+		if err != nil {
+			return fmt.Errorf("configure ai client: %w", err)
+		}
+		client = openAIClient
+	}
+	return replay(ctx, client, exchange, os.Stdout)
+}
+
+// list prints recent exchanges, newest first: failed ones unless opts.All
+// is set, and only those of opts.JobID when it is given.
+func list(ctx context.Context, database *gorm.DB, opts options, out io.Writer) error {
+	query := database.WithContext(ctx).Order("id desc")
+	if opts.JobID != "" {
+		query = query.Where("job_id = ?", opts.JobID)
+	}
+	if !opts.All {
+		query = query.Where("error <> ''")
+	}
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+	var exchanges []models.AIExchange
+	if err := query.Find(&exchanges).Error; err != nil {
+		return fmt.Errorf("load exchanges: %w", err)
+	}
+	if len(exchanges) == 0 {
+		fmt.Fprintln(out, "No recorded exchanges. Set AI_DEBUG_LOG=true on the server to record them.")
+		return nil
+	}
+	for _, exchange := range exchanges {
+		outcome := "ok"
+		if exchange.Failed() {
+			outcome = exchange.Error
+		}
+		fmt.Fprintf(out, "%d\t%s\t%s\t%s\t%dms\t%s\n", exchange.ID, exchange.CreatedAt.Format("2006-01-02 15:04"), exchange.JobID, exchange.Operation, exchange.DurationMs, outcome)
+	}
+	return nil
+}
+
+// show prints one exchange in full.
+func show(ctx context.Context, database *gorm.DB, id uint, out io.Writer) (models.AIExchange, error) {
+	var exchange models.AIExchange
+	if err := database.WithContext(ctx).First(&exchange, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exchange, fmt.Errorf("no exchange with ID %d", id)
+		}
+		return exchange, fmt.Errorf("load exchange: %w", err)
+	}
+	fmt.Fprintf(out, "Exchange %d (%s) for job %s at %s\n", exchange.ID, exchange.Operation, exchange.JobID, exchange.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(out, "Model %s, HTTP status %d, %dms\n", exchange.ModelName, exchange.Status, exchange.DurationMs)
+	if exchange.Failed() {
+		fmt.Fprintf(out, "Error: %s\n", exchange.Error)
+	}
+	if exchange.Truncated {
+		fmt.Fprintln(out, "Some fields were truncated when recorded.")
+	}
+	fmt.Fprintf(out, "\nInput:\n%s\n\nRequest:\n%s\n\nResponse:\n%s\n", exchange.Input, exchange.Request, exchange.Response)
+	return exchange, nil
+}
+
+// replay runs the exchange's recorded input through client again and
+// prints what the current prompt makes of it.
+func replay(ctx context.Context, client ai.Client, exchange models.AIExchange, out io.Writer) error {
+	if exchange.Truncated {
+		return errors.New("the exchange was truncated when recorded and cannot be replayed")
+	}
+	var result any
+	var err error
+	switch exchange.Operation {
+	case ai.OperationAromaProfile:
+		var input struct {
+			Ingredient string `json:"ingredient"`
+		}
+		if err := json.Unmarshal([]byte(exchange.Input), &input); err != nil {
+			return fmt.Errorf("decode recorded input: %w", err)
+		}
+		result, err = client.FetchAromaProfile(ctx, input.Ingredient, ai.FetchOptions{})
+	case ai.OperationFormulaExtraction:
+		var input ai.FormulaImportInput
+		if err := json.Unmarshal([]byte(exchange.Input), &input); err != nil {
+			return fmt.Errorf("decode recorded input: %w", err)
+		}
+		result, err = client.ExtractFormula(ctx, input)
+	default:
+		return fmt.Errorf("cannot replay %q exchanges", exchange.Operation)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "\nReplay failed: %v\n", err)
+		return nil
+	}
+	encoded, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode replay result: %w", err)
+	}
+	fmt.Fprintf(out, "\nReplay result:\n%s\n", encoded)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"perfugo/internal/config"
+	"perfugo/internal/db"
+	"perfugo/internal/importer"
+)
+
+type options struct {
+	// Source is a local CSV path or an https:// URL to a CSV export.
+	Source string
+	// Dataset names the rows so a later import of the same data replaces
+	// them. It defaults to the source file name.
+	Dataset string
+	// Owner and OwnerID name the user whose chemicals receive the
+	// constituents; one is required. Owner falls back to
+	// PERFUGO_AROMA_OWNER_EMAIL.
+	Owner        string
+	OwnerID      uint
+	ReportPath   string
+	ReportFormat string
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.Dataset, "dataset", "", "name of the dataset the rows replace (defaults to the file name)")
+	flag.StringVar(&opts.Owner, "owner", "", "email of the user whose chemicals receive the constituents (defaults to $"+importer.OwnerEnv+")")
+	flag.UintVar(&opts.OwnerID, "owner-id", 0, "id of the user whose chemicals receive the constituents")
+	flag.StringVar(&opts.ReportPath, "report", "", "write a row-level import report to this file")
+	flag.StringVar(&opts.ReportFormat, "report-format", "", "report format: json or csv (defaults to the report file extension)")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: import_allergens [flags] <csv path or https URL>")
+		os.Exit(2)
+	}
+	opts.Source = flag.Arg(0)
+
+	if err := run(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "import failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts options) (err error) {
+	ctx := context.Background()
+	if opts.Owner == "" && opts.OwnerID == 0 {
+		opts.Owner = os.Getenv(importer.OwnerEnv)
+	}
+
+	reportFormat := ""
+	if opts.ReportPath != "" {
+		if reportFormat, err = importer.ResolveReportFormat(opts.ReportPath, opts.ReportFormat); err != nil {
+			return err
+		}
+	}
+
+	source, err := importer.LoadSource(ctx, opts.Source)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Loaded %s (%d bytes, sha256 %s)\n", source.Name, len(source.Data), source.Checksum)
+
+	dataset := strings.TrimSpace(opts.Dataset)
+	if dataset == "" {
+		dataset = strings.TrimSuffix(filepath.Base(source.Name), filepath.Ext(source.Name))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	database, err := db.Initialize(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+
+	if err := db.AutoMigrate(database); err != nil {
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+
+	records, err := importer.ParseCSV(bytes.NewReader(source.Data))
+	if err != nil {
+		return fmt.Errorf("read csv: %w", err)
+	}
+
+	ownerID, err := importer.ResolveOwner(ctx, database, opts.Owner, opts.OwnerID)
+	if err != nil {
+		return fmt.Errorf("resolve owner: %w", err)
+	}
+
+	report := importer.Report{Source: source.Name, Checksum: source.Checksum}
+	if opts.ReportPath != "" {
+		defer func() {
+			report.Complete = err == nil
+			if writeErr := importer.WriteReportFile(opts.ReportPath, reportFormat, report); writeErr != nil && err == nil {
+				err = fmt.Errorf("write report: %w", writeErr)
+			}
+		}()
+	}
+
+	if err := importer.ImportConstituents(ctx, database, records, dataset, ownerID, &report); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Imported %d allergen constituents into %s (%d skipped; origin updated on %d chemicals; %d coercions)\n",
+		report.Created, dataset, report.Skipped, report.Updated, report.Count(importer.IssueCoercion))
+	if opts.ReportPath != "" {
+		fmt.Fprintf(os.Stdout, "Wrote %s import report to %s\n", reportFormat, opts.ReportPath)
+	}
+	return nil
+}
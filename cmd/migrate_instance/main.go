@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"perfugo/internal/bundle"
+	"perfugo/internal/config"
+	"perfugo/internal/db"
+	"perfugo/internal/storage"
+)
+
+const usage = `usage:
+  migrate_instance export [-user email] <bundle.zip>
+  migrate_instance import <bundle.zip>`
+
+type options struct {
+	Command string
+	Path    string
+	// UserEmail limits an export to one user; everyone is exported when empty.
+	UserEmail string
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	opts := options{Command: os.Args[1]}
+	flags := flag.NewFlagSet(opts.Command, flag.ExitOnError)
+	if opts.Command == "export" {
+		flags.StringVar(&opts.UserEmail, "user", "", "export only the user with this email")
+	}
+	_ = flags.Parse(os.Args[2:])
+	if (opts.Command != "export" && opts.Command != "import") || flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	opts.Path = flags.Arg(0)
+
+	if err := run(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", opts.Command, err)
+		os.Exit(1)
+	}
+}
+
+func run(opts options) error {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	database, err := db.Initialize(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	if err := db.AutoMigrate(database); err != nil {
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+
+	var store storage.Store
+	if cfg.Storage.Dir != "" {
+		filesystem, err := storage.NewFilesystem(cfg.Storage.Dir)
+		if err != nil {
+			return fmt.Errorf("open storage: %w", err)
+		}
+		store = filesystem
+	}
+
+	var summary bundle.Summary
+	switch opts.Command {
+	case "export":
+		file, err := os.OpenFile(opts.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		summary, err = bundle.Export(ctx, database, store, file, bundle.ExportOptions{UserEmail: opts.UserEmail})
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(opts.Path)
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Exported %s to %s\n", summary, opts.Path)
+	case "import":
+		file, err := os.Open(opts.Path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if summary, err = bundle.Import(ctx, database, store, file, info.Size()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Imported %s from %s\n", summary, opts.Path)
+	}
+	return nil
+}
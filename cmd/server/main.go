@@ -8,15 +8,28 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"gorm.io/gorm"
 
 	"perfugo/internal/ai"
+	"perfugo/internal/captcha"
 	"perfugo/internal/config"
 	"perfugo/internal/db"
 	"perfugo/internal/db/mock"
+	"perfugo/internal/importer"
+	"perfugo/internal/jobs"
+	"perfugo/internal/labels"
+	"perfugo/internal/ldap"
 	applog "perfugo/internal/log"
+	"perfugo/internal/mail"
+	"perfugo/internal/oidc"
+	"perfugo/internal/onboarding"
 	"perfugo/internal/server"
+	"perfugo/internal/storage"
+	"perfugo/internal/telemetry"
+	"perfugo/internal/version"
+	"perfugo/models"
 )
 
 var (
@@ -25,6 +38,8 @@ var (
 	setLogLevelFunc      = applog.SetLevel
 	configureDatabase    = db.Configure
 	newMockDatabaseFunc  = mock.New
+	newMockAIClientFunc  = func() ai.Client { return ai.NewMockClient() }
+	discoverOIDCFunc     = oidc.Discover
 	newServerFunc        = func(cfg server.Config) (serverLifecycle, error) { return server.New(cfg) }
 	subscribeShutdownSig = func() (<-chan os.Signal, func()) {
 		sigCh := make(chan os.Signal, 1)
@@ -49,6 +64,8 @@ func run(ctx context.Context) int {
 		return 1
 	}
 
+	build := version.Get()
+	applog.Info(ctx, "perfugo starting", "version", build.Version, "commit", build.Commit, "buildDate", build.BuildDate)
 	applog.Debug(ctx, "configuration loaded", "addr", cfg.Server.Addr, "logLevel", cfg.Logging.Level)
 
 	if err := setLogLevelFunc(cfg.Logging.Level); err != nil {
@@ -58,6 +75,21 @@ func run(ctx context.Context) int {
 
 	applog.Debug(ctx, "log level configured", "level", cfg.Logging.Level)
 
+	if cfg.Library.ScalesFile != "" {
+		if err := models.LoadScalesFile(cfg.Library.ScalesFile); err != nil {
+			applog.Error(ctx, "failed to load library scales", "path", cfg.Library.ScalesFile, "error", err)
+			return 1
+		}
+		applog.Info(ctx, "custom library scales loaded", "path", cfg.Library.ScalesFile)
+	}
+
+	collisions, err := importer.ParseCollisionStrategy(cfg.Library.ImportCollisions)
+	if err != nil {
+		applog.Error(ctx, "invalid import collision strategy", "strategy", cfg.Library.ImportCollisions, "error", err)
+		return 1
+	}
+	importer.SetCollisionStrategy(collisions)
+
 	var database *gorm.DB
 	if cfg.Database.UseMock || strings.TrimSpace(cfg.Database.URL) == "" {
 		applog.Info(ctx, "using in-memory mock database")
@@ -72,35 +104,183 @@ func run(ctx context.Context) int {
 
 	applog.Debug(ctx, "database configured", "hasDB", database != nil)
 
-	var aiClient *ai.Client
-	if strings.TrimSpace(cfg.AI.APIKey) == "" {
+	var aiClient ai.Client
+	if cfg.AI.UseMock {
+		applog.Info(ctx, "using mock ai client")
+		aiClient = newMockAIClientFunc()
+	} else if strings.TrimSpace(cfg.AI.APIKey) == "" {
 		applog.Info(ctx, "ai integration disabled", "reason", "missing api key")
 	} else {
-		aiClient, err = ai.NewClient(ai.Config{
+		aiConfig := ai.Config{
 			APIKey:      cfg.AI.APIKey,
 			Model:       cfg.AI.Model,
 			BaseURL:     cfg.AI.BaseURL,
 			Timeout:     cfg.AI.RequestTimeout,
 			HTTPClient:  nil,
 			Temperature: 0,
-		})
+		}
+		if cfg.AI.DebugLog && database != nil {
+			aiConfig.Recorder = ai.NewDBRecorder(database, func(ctx context.Context, err error) {
+				applog.Error(ctx, "failed to record ai exchange", "error", err)
+			})
+			aiConfig.RecordLimit = cfg.AI.DebugLogMaxBytes
+			applog.Info(ctx, "ai debug logging enabled", "maxBytes", cfg.AI.DebugLogMaxBytes)
+		}
+		openAIClient, err := ai.NewClient(aiConfig)
 		if err != nil {
 			applog.Error(ctx, "failed to initialise ai client", "error", err)
 		} else {
+			aiClient = openAIClient
 			applog.Debug(ctx, "ai client configured", "model", cfg.AI.Model)
 		}
 	}
 
+	var oidcProvider *oidc.Provider
+	if cfg.Auth.OIDC.IssuerURL != "" {
+		oidcProvider, err = discoverOIDCFunc(ctx, oidc.Config{
+			IssuerURL:             cfg.Auth.OIDC.IssuerURL,
+			ClientID:              cfg.Auth.OIDC.ClientID,
+			ClientSecret:          cfg.Auth.OIDC.ClientSecret,
+			RedirectURL:           cfg.Auth.OIDC.RedirectURL,
+			PostLogoutRedirectURL: cfg.Auth.OIDC.PostLogoutRedirectURL,
+			Scopes:                cfg.Auth.OIDC.Scopes,
+			RolesClaim:            cfg.Auth.OIDC.RolesClaim,
+			RoleMapping:           cfg.Auth.OIDC.RoleMapping,
+			LinkByEmail:           cfg.Auth.OIDC.LinkByEmail,
+		})
+		if err != nil {
+			applog.Error(ctx, "failed to discover oidc provider", "issuer", cfg.Auth.OIDC.IssuerURL, "error", err)
+			return 1
+		}
+		applog.Info(ctx, "oidc single sign-on enabled", "issuer", oidcProvider.Issuer, "logout", oidcProvider.SupportsLogout())
+	}
+
+	var ldapAuthenticator *ldap.Authenticator
+	if cfg.Auth.Backend == "ldap" {
+		ldapAuthenticator, err = ldap.NewAuthenticator(ldap.Config{
+			URL:            cfg.Auth.LDAP.URL,
+			BindDN:         cfg.Auth.LDAP.BindDN,
+			BindPassword:   cfg.Auth.LDAP.BindPassword,
+			BaseDN:         cfg.Auth.LDAP.BaseDN,
+			UserFilter:     cfg.Auth.LDAP.UserFilter,
+			EmailAttribute: cfg.Auth.LDAP.EmailAttribute,
+			NameAttribute:  cfg.Auth.LDAP.NameAttribute,
+			Timeout:        cfg.Auth.LDAP.Timeout,
+		})
+		if err != nil {
+			applog.Error(ctx, "failed to configure ldap backend", "url", cfg.Auth.LDAP.URL, "error", err)
+			return 1
+		}
+		applog.Info(ctx, "ldap credentials backend enabled", "url", cfg.Auth.LDAP.URL, "baseDN", cfg.Auth.LDAP.BaseDN)
+	}
+
+	var captchaVerifier *captcha.Verifier
+	if cfg.Auth.Captcha.Provider != "" {
+		captchaVerifier, err = captcha.New(captcha.Config{
+			Provider:  cfg.Auth.Captcha.Provider,
+			SiteKey:   cfg.Auth.Captcha.SiteKey,
+			SecretKey: cfg.Auth.Captcha.SecretKey,
+		})
+		if err != nil {
+			applog.Error(ctx, "failed to configure captcha", "provider", cfg.Auth.Captcha.Provider, "error", err)
+			return 1
+		}
+		applog.Info(ctx, "captcha verification enabled", "provider", cfg.Auth.Captcha.Provider)
+	}
+
+	onboardingTemplate, err := loadOnboardingTemplate(cfg.Onboarding)
+	if err != nil {
+		applog.Error(ctx, "failed to load onboarding template", "file", cfg.Onboarding.TemplateFile, "error", err)
+		return 1
+	}
+
+	var mailer mail.Sender
+	if cfg.Mail.Host != "" {
+		smtpSender, err := mail.NewSMTPSender(mail.Config{
+			Host:     cfg.Mail.Host,
+			Port:     cfg.Mail.Port,
+			Username: cfg.Mail.Username,
+			Password: cfg.Mail.Password,
+			From:     cfg.Mail.From,
+		})
+		if err != nil {
+			applog.Error(ctx, "failed to configure mail sender", "error", err)
+			return 1
+		}
+		mailer = smtpSender
+		applog.Debug(ctx, "smtp mail sender configured", "host", cfg.Mail.Host)
+	}
+
+	var store storage.Store
+	if cfg.Storage.Dir != "" {
+		filesystem, err := storage.NewFilesystem(cfg.Storage.Dir)
+		if err != nil {
+			applog.Error(ctx, "failed to configure storage", "error", err)
+			return 1
+		}
+		store = filesystem
+		applog.Debug(ctx, "filesystem storage configured", "dir", cfg.Storage.Dir)
+	}
+
+	var labelPrinter *labels.Printer
+	if cfg.Labels.PrinterAddr != "" {
+		labelPrinter, err = labels.NewPrinter(cfg.Labels.PrinterAddr, cfg.Labels.PrinterLanguage)
+		if err != nil {
+			applog.Error(ctx, "failed to configure label printer", "error", err)
+			return 1
+		}
+		applog.Debug(ctx, "label printer configured", "addr", cfg.Labels.PrinterAddr, "language", labelPrinter.Language())
+	}
+
 	srv, err := newServerFunc(server.Config{
-		Addr: cfg.Server.Addr,
+		Addr:           cfg.Server.Addr,
+		BasePath:       cfg.Server.BasePath,
+		TrustedProxies: cfg.Server.TrustedProxies,
 		Session: server.SessionConfig{
-			Lifetime:     cfg.Auth.Session.Lifetime,
-			CookieName:   cfg.Auth.Session.CookieName,
-			CookieDomain: cfg.Auth.Session.CookieDomain,
-			CookieSecure: cfg.Auth.Session.CookieSecure,
+			Lifetime:          cfg.Auth.Session.Lifetime,
+			CookieName:        cfg.Auth.Session.CookieName,
+			CookieDomain:      cfg.Auth.Session.CookieDomain,
+			CookiePath:        cfg.Auth.Session.CookiePath,
+			CookieSecure:      cfg.Auth.Session.CookieSecure,
+			CookieSameSite:    cfg.Auth.Session.CookieSameSite,
+			CookiePartitioned: cfg.Auth.Session.CookiePartitioned,
+		},
+		Database:           database,
+		AIClient:           aiClient,
+		MaintenanceMode:    cfg.Server.MaintenanceMode,
+		InviteOnly:         cfg.Auth.InviteOnly,
+		Captcha:            captchaVerifier,
+		Mailer:             mailer,
+		Storage:            store,
+		LabelPrinter:       labelPrinter,
+		OnboardingTemplate: onboardingTemplate,
+		OIDCProvider:       oidcProvider,
+		SCIMToken:          cfg.Auth.SCIMToken,
+		LDAPAuthenticator:  ldapAuthenticator,
+		Timeouts: server.TimeoutConfig{
+			AI:     cfg.Server.Timeouts.AI,
+			Report: cfg.Server.Timeouts.Report,
+			Import: cfg.Server.Timeouts.Import,
+		},
+		Quotas: server.QuotaConfig{
+			MaxFormulas:     cfg.Quotas.MaxFormulas,
+			MaxIngredients:  cfg.Quotas.MaxIngredients,
+			MaxStorageBytes: int64(cfg.Quotas.MaxStorageMB) << 20,
+		},
+		Retention: retentionPolicy(cfg.Retention),
+		StaleProfiles: server.StaleProfileConfig{
+			Months:           cfg.AI.StaleAfterMonths,
+			DeprecatedModels: cfg.AI.DeprecatedModels,
+		},
+		Probes: server.ProbeConfig{
+			PublicPaths:     cfg.Server.Probes.PublicPaths,
+			AllowedNetworks: cfg.Server.Probes.AllowedNetworks,
+			Token:           cfg.Server.Probes.Token,
+		},
+		Gallery: server.GalleryConfig{
+			PerMinute: cfg.Server.Gallery.PerMinute,
+			Burst:     cfg.Server.Gallery.Burst,
 		},
-		Database: database,
-		AIClient: aiClient,
 	})
 	if err != nil {
 		applog.Error(ctx, "failed to initialize http server", "error", err)
@@ -109,9 +289,22 @@ func run(ctx context.Context) int {
 
 	applog.Debug(ctx, "http server initialized", "addr", cfg.Server.Addr)
 
+	scheduler := jobs.NewScheduler()
+	scheduler.Register(jobs.UsagePopularityJob(database, cfg.Jobs.PopularityInterval))
+	scheduler.Register(jobs.ScheduledImportsJob(database, cfg.Jobs.ImportInterval))
+	scheduler.Register(jobs.ShelfLifeJob(database, cfg.Jobs.ShelfLifeInterval, server.SendExpiryAlert))
+	if mailer != nil {
+		scheduler.Register(jobs.WeeklyDigestJob(database, cfg.Jobs.DigestInterval, server.SendWeeklyDigest))
+	}
+	scheduler.Register(jobs.RetentionJob(database, cfg.Jobs.RetentionInterval, retentionPolicy(cfg.Retention)))
+	if job, ok := telemetryJob(ctx, cfg, database, aiClient != nil); ok {
+		scheduler.Register(job)
+	}
+	scheduler.Start(ctx)
+
 	serverErrCh := make(chan error, 1)
 	go func() {
-		applog.Info(ctx, "starting http server", "addr", cfg.Server.Addr)
+		applog.Info(ctx, "starting http server", "addr", cfg.Server.Addr, "version", build.Version, "commit", build.Commit)
 		if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			serverErrCh <- err
 			return
@@ -124,18 +317,93 @@ func run(ctx context.Context) int {
 
 	select {
 	case err := <-serverErrCh:
+		shutdownWorkers(ctx, scheduler, cfg.Jobs.ShutdownTimeout)
 		if err != nil {
 			applog.Error(ctx, "server encountered an error", "error", err)
 			return 1
 		}
 		return 0
 	case <-shutdownCh:
+		// Stop taking requests first so no new work reaches the database, then
+		// give running jobs their deadline to finish.
 		applog.Info(ctx, "shutting down http server")
+		code := 0
 		if err := srv.Stop(); err != nil {
 			applog.Error(ctx, "graceful shutdown failed", "error", err)
-			return 1
+			code = 1
 		}
+		if err := shutdownWorkers(ctx, scheduler, cfg.Jobs.ShutdownTimeout); err != nil {
+			code = 1
+		}
+		applog.Info(ctx, "shutdown complete")
+		return code
+	}
+}
+
+// retentionPolicy converts the configured retention days into the policy
+// the retention job enforces.
+func retentionPolicy(cfg config.RetentionConfig) jobs.RetentionPolicy {
+	day := 24 * time.Hour
+	return jobs.RetentionPolicy{
+		Audit:       time.Duration(cfg.AuditDays) * day,
+		AIExchanges: time.Duration(cfg.AIExchangeDays) * day,
+		Deleted:     time.Duration(cfg.DeletedDays) * day,
+	}
+}
+
+// shutdownWorkers stops the background job scheduler, waiting at most timeout
+// for running jobs. Jobs that are cut short run again on the next start.
+func shutdownWorkers(ctx context.Context, scheduler *jobs.Scheduler, timeout time.Duration) error {
+	applog.Info(ctx, "stopping background workers", "timeout", timeout.String())
+	if timeout <= 0 {
+		return scheduler.Shutdown(ctx)
+	}
+	shutdownCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return scheduler.Shutdown(shutdownCtx)
+}
+
+// telemetryJob builds the opt-in telemetry reporter. It is only scheduled when
+// TELEMETRY_ENABLED is set and an endpoint is configured.
+func telemetryJob(ctx context.Context, cfg config.Config, database *gorm.DB, aiEnabled bool) (jobs.Job, bool) {
+	if !cfg.Telemetry.Enabled {
+		applog.Debug(ctx, "telemetry disabled")
+		return jobs.Job{}, false
+	}
+	if cfg.Telemetry.Endpoint == "" {
+		applog.Info(ctx, "telemetry enabled without an endpoint; skipping reports")
+		return jobs.Job{}, false
 	}
 
-	return 0
+	reporter, err := telemetry.NewReporter(cfg.Telemetry.Endpoint, cfg.Telemetry.InstanceID, database, telemetry.Features{
+		AI:              aiEnabled,
+		AIMock:          cfg.AI.UseMock,
+		MockDatabase:    cfg.Database.UseMock || strings.TrimSpace(cfg.Database.URL) == "",
+		MaintenanceMode: cfg.Server.MaintenanceMode,
+		CustomScales:    cfg.Library.ScalesFile != "",
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to configure telemetry", "error", err)
+		return jobs.Job{}, false
+	}
+
+	applog.Info(ctx, "anonymous telemetry enabled", "endpoint", cfg.Telemetry.Endpoint, "instanceID", reporter.InstanceID)
+	return jobs.Job{Name: "telemetry", Interval: cfg.Telemetry.Interval, Run: reporter.Send}, true
+}
+
+// loadOnboardingTemplate resolves the starter library for new accounts, or
+// nil when seeding is disabled.
+func loadOnboardingTemplate(cfg config.OnboardingConfig) (*onboarding.Template, error) {
+	if !cfg.SeedEnabled {
+		return nil, nil
+	}
+	tmpl := onboarding.DefaultTemplate
+	if cfg.TemplateFile != "" {
+		loaded, err := onboarding.LoadTemplate(cfg.TemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		tmpl = loaded
+	}
+	return &tmpl, nil
 }
@@ -11,6 +11,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"perfugo/internal/ai"
 	"perfugo/internal/config"
 	"perfugo/internal/server"
 )
@@ -227,3 +228,90 @@ func TestRunReturnsErrorWhenLogLevelInvalid(t *testing.T) {
 		t.Fatalf("expected exit code 1 for invalid log level, got %d", code)
 	}
 }
+
+func TestRunUsesMockAIClientWhenConfigured(t *testing.T) {
+	originalLoadConfig := loadConfigFunc
+	originalSetLogLevel := setLogLevelFunc
+	originalMock := newMockDatabaseFunc
+	originalMockAI := newMockAIClientFunc
+	originalNewServer := newServerFunc
+
+	t.Cleanup(func() {
+		loadConfigFunc = originalLoadConfig
+		setLogLevelFunc = originalSetLogLevel
+		newMockDatabaseFunc = originalMock
+		newMockAIClientFunc = originalMockAI
+		newServerFunc = originalNewServer
+	})
+
+	cfg := config.Config{
+		Server:   config.ServerConfig{Addr: ":8080"},
+		Database: config.DatabaseConfig{UseMock: true},
+		Logging:  config.LoggingConfig{Level: "info"},
+		AI:       config.AIConfig{UseMock: true},
+	}
+
+	mockClient := ai.NewMockClient()
+	loadConfigFunc = func() (config.Config, error) { return cfg, nil }
+	setLogLevelFunc = func(string) error { return nil }
+	newMockDatabaseFunc = func(context.Context) (*gorm.DB, error) { return &gorm.DB{}, nil }
+	newMockAIClientFunc = func() ai.Client { return mockClient }
+
+	var captured server.Config
+	newServerFunc = func(cfg server.Config) (serverLifecycle, error) {
+		captured = cfg
+		return nil, errors.New("stop after configuration")
+	}
+
+	if code := run(context.Background()); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if captured.AIClient != ai.Client(mockClient) {
+		t.Fatalf("expected mock ai client to be passed to the server, got %#v", captured.AIClient)
+	}
+}
+
+func TestTelemetryJobIsOptIn(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.TelemetryConfig
+		want bool
+	}{
+		{name: "disabled by default", cfg: config.TelemetryConfig{}, want: false},
+		{name: "enabled without endpoint", cfg: config.TelemetryConfig{Enabled: true}, want: false},
+		{name: "endpoint without opt-in", cfg: config.TelemetryConfig{Endpoint: "https://telemetry.example"}, want: false},
+		{name: "enabled with endpoint", cfg: config.TelemetryConfig{Enabled: true, Endpoint: "https://telemetry.example", Interval: time.Hour}, want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			job, ok := telemetryJob(context.Background(), config.Config{Telemetry: tt.cfg}, nil, false)
+			if ok != tt.want {
+				t.Fatalf("expected scheduled=%v, got %v", tt.want, ok)
+			}
+			if ok && (job.Run == nil || job.Interval != time.Hour) {
+				t.Fatalf("unexpected job: %+v", job)
+			}
+		})
+	}
+}
+
+func TestLoadOnboardingTemplate(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := loadOnboardingTemplate(config.OnboardingConfig{})
+	if err != nil || tmpl != nil {
+		t.Fatalf("expected seeding to be disabled by default, got %v, %v", tmpl, err)
+	}
+
+	tmpl, err = loadOnboardingTemplate(config.OnboardingConfig{SeedEnabled: true})
+	if err != nil || tmpl == nil || len(tmpl.Chemicals) == 0 {
+		t.Fatalf("expected the built-in template, got %v, %v", tmpl, err)
+	}
+
+	if _, err := loadOnboardingTemplate(config.OnboardingConfig{SeedEnabled: true, TemplateFile: "/nonexistent/onboarding.json"}); err == nil {
+		t.Fatal("expected an error for a missing template file")
+	}
+}
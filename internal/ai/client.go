@@ -6,11 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"perfugo/models"
 )
 
 const (
@@ -28,15 +31,27 @@ type Config struct {
 	Temperature float64
 	Timeout     time.Duration
 	HTTPClient  *http.Client
+	// Recorder, when set, keeps a redacted copy of every request and
+	// response, each field capped at RecordLimit bytes.
+	Recorder    Recorder
+	RecordLimit int
+}
+
+// Client describes the AI capabilities consumed by the application.
+type Client interface {
+	FetchAromaProfile(ctx context.Context, ingredient string, opts FetchOptions) (Profile, error)
+	ExtractFormula(ctx context.Context, input FormulaImportInput) (FormulaImportResult, error)
 }
 
-// Client offers a thin wrapper around the OpenAI Chat Completions API.
-type Client struct {
+// OpenAIClient offers a thin wrapper around the OpenAI Chat Completions API.
+type OpenAIClient struct {
 	apiKey      string
 	model       string
 	baseURL     string
 	temperature float64
 	httpClient  *http.Client
+	recorder    Recorder
+	recordLimit int
 }
 
 // FetchOptions control per-request overrides.
@@ -49,6 +64,7 @@ type Profile struct {
 	IngredientName      string
 	CASNumber           string
 	OtherNames          []string
+	LocalizedNames      []LocalizedName
 	Notes               string
 	WheelPosition       string
 	PyramidPosition     string
@@ -61,10 +77,21 @@ type Profile struct {
 	HistoricRole        string
 	Popularity          int
 	Usage               string
+	// Model is the model that generated the profile.
+	Model string
+}
+
+// LocalizedName is a name used for the ingredient in one language, such as
+// its French INCI name. Language is an ISO 639-1 code and Kind one of the
+// models.AliasKind constants.
+type LocalizedName struct {
+	Name     string
+	Language string
+	Kind     string
 }
 
-// NewClient builds a Client that can query OpenAI for aroma data.
-func NewClient(cfg Config) (*Client, error) {
+// NewClient builds an OpenAIClient that can query OpenAI for aroma data.
+func NewClient(cfg Config) (*OpenAIClient, error) {
 	apiKey := strings.TrimSpace(cfg.APIKey)
 	if apiKey == "" {
 		return nil, errors.New("ai: api key must not be empty")
@@ -97,24 +124,30 @@ func NewClient(cfg Config) (*Client, error) {
 		}
 	}
 
-	return &Client{
+	return &OpenAIClient{
 		apiKey:      apiKey,
 		model:       model,
 		baseURL:     strings.TrimRight(baseURL, "/"),
 		temperature: temp,
 		httpClient:  httpClient,
+		recorder:    cfg.Recorder,
+		recordLimit: cfg.RecordLimit,
 	}, nil
 }
 
 // FetchAromaProfile contacts OpenAI and returns a normalised aroma profile.
-func (c *Client) FetchAromaProfile(ctx context.Context, ingredient string, opts FetchOptions) (Profile, error) {
+func (c *OpenAIClient) FetchAromaProfile(ctx context.Context, ingredient string, opts FetchOptions) (profile Profile, err error) {
 	ingredient = strings.TrimSpace(ingredient)
 	if ingredient == "" {
 		return Profile{}, errors.New("ai: ingredient name must not be empty")
 	}
 
+	model := c.effectiveModel(opts)
+	call := c.startCall(OperationAromaProfile, map[string]string{"ingredient": ingredient})
+	defer func() { c.finishCall(ctx, call, model, err) }()
+
 	payload := map[string]any{
-		"model":       c.effectiveModel(opts),
+		"model":       model,
 		"temperature": c.temperature,
 		"messages": []map[string]string{
 			{
@@ -133,7 +166,7 @@ func (c *Client) FetchAromaProfile(ctx context.Context, ingredient string, opts
 		return Profile{}, fmt.Errorf("ai: encode request: %w", err)
 	}
 
-	content, err := c.performChatCompletion(ctx, payload, body)
+	content, err := c.performChatCompletion(ctx, call, payload, body)
 	if err != nil {
 		return Profile{}, err
 	}
@@ -144,10 +177,12 @@ func (c *Client) FetchAromaProfile(ctx context.Context, ingredient string, opts
 		return Profile{}, fmt.Errorf("ai: parse JSON payload: %w", err)
 	}
 
-	return normaliseAromaData(ingredient, parsed)
+	profile, err = normaliseAromaData(ingredient, parsed)
+	profile.Model = model
+	return profile, err
 }
 
-func (c *Client) effectiveModel(opts FetchOptions) string {
+func (c *OpenAIClient) effectiveModel(opts FetchOptions) string {
 	model := strings.TrimSpace(opts.ModelOverride)
 	if model != "" {
 		return model
@@ -161,6 +196,7 @@ func buildPrompt(ingredient string) string {
   "ingredient_name": string,
   "cas_number": string | "",
   "other_names": string[] (omit unverified aliases),
+  "localized_names": [{"name": string, "language": ISO 639-1 code, "kind": "inci" | "translation"}] (names published in other languages, notably French and German; omit unverified names),
   "notes": short string,
   "wheel_position": string (taxonomy category similar to the scent wheel),
   "pyramid_position": string (Top, Heart, Base, etc.),
@@ -174,13 +210,14 @@ func buildPrompt(ingredient string) string {
   "popularity_label": string from {Low, Medium, High, High Impact, Specialist, Niche, Foundational, Restricted},
   "usage": concise guidance string
 }
-Strict rules: respond with raw JSON, no Markdown, no comments. Use empty string instead of unknown text fields. Use empty list for other_names if none.`, ingredient)
+Strict rules: respond with raw JSON, no Markdown, no comments. Use empty string instead of unknown text fields. Use empty lists for other_names and localized_names if none.`, ingredient)
 }
 
 type aiAromaResponse struct {
 	IngredientName         string            `json:"ingredient_name"`
 	CASNumber              string            `json:"cas_number"`
 	OtherNames             any               `json:"other_names"`
+	LocalizedNames         any               `json:"localized_names"`
 	Notes                  string            `json:"notes"`
 	WheelPosition          string            `json:"wheel_position"`
 	PyramidPosition        string            `json:"pyramid_position"`
@@ -215,12 +252,13 @@ func normaliseAromaData(requestedName string, aiData aiAromaResponse) (Profile,
 		Duration:            normaliseValue(aiData.Duration),
 		HistoricRole:        normaliseValue(aiData.HistoricRole),
 		Usage:               normaliseText(aiData.Usage),
-		Strength:            mapStrength(aiData.StrengthLabel),
-		Popularity:          mapPopularity(aiData.PopularityLabel),
+		Strength:            models.StrengthScale().Value(aiData.StrengthLabel),
+		Popularity:          models.PopularityScale().Value(aiData.PopularityLabel),
 		RecommendedDilution: parseNumeric(aiData.RecommendedDilution),
 		DilutionPercentage:  parseNumeric(aiData.DilutionPercentage),
 		MaxIFRAPercentage:   parseNumeric(aiData.MaxIFRAPercentage),
 		OtherNames:          sanitiseOtherNames(aiData.OtherNames, name),
+		LocalizedNames:      sanitiseLocalizedNames(aiData.LocalizedNames, name),
 	}
 
 	if result.RecommendedDilution == 0 {
@@ -251,44 +289,6 @@ func normaliseText(value string) string {
 	return strings.Join(strings.Fields(value), " ")
 }
 
-func mapStrength(value string) int {
-	switch strings.ToLower(strings.TrimSpace(value)) {
-	case "very low":
-		return 1
-	case "low":
-		return 2
-	case "low-medium":
-		return 3
-	case "medium":
-		return 4
-	case "medium-high":
-		return 5
-	case "high", "high (in effect)":
-		return 6
-	case "very high":
-		return 7
-	case "extreme":
-		return 8
-	default:
-		return 0
-	}
-}
-
-func mapPopularity(value string) int {
-	switch strings.ToLower(strings.TrimSpace(value)) {
-	case "low", "low (perfumery)", "specialist":
-		return 1
-	case "medium", "niche":
-		return 2
-	case "high", "foundational", "restricted", "restricted/banned", "banned/restricted", "high (endangered)":
-		return 3
-	case "high impact":
-		return 4
-	default:
-		return 0
-	}
-}
-
 func parseNumeric(value any) float64 {
 	switch v := value.(type) {
 	case nil:
@@ -370,7 +370,45 @@ func sanitiseOtherNames(raw any, canonical string) []string {
 	return result
 }
 
-func (c *Client) performChatCompletion(ctx context.Context, payload map[string]any, preEncoded ...[]byte) (string, error) {
+// sanitiseLocalizedNames keeps the entries of raw that carry both a name
+// and a valid language code, once per name and language.
+func sanitiseLocalizedNames(raw any, canonical string) []LocalizedName {
+	entries, _ := raw.([]any)
+	canonical = models.FoldName(strings.TrimSpace(canonical))
+	unique := make(map[string]struct{})
+	result := []LocalizedName{}
+	for _, entry := range entries {
+		fields, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := fields["name"].(string)
+		language, _ := fields["language"].(string)
+		kind, _ := fields["kind"].(string)
+
+		name = normaliseText(name)
+		language = models.NormalizeLanguageCode(language)
+		if name == "" || language == "" || models.FoldName(name) == canonical {
+			continue
+		}
+		key := language + ":" + models.FoldName(name)
+		if _, ok := unique[key]; ok {
+			continue
+		}
+		unique[key] = struct{}{}
+
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind != models.AliasKindINCI && kind != models.AliasKindTrade {
+			kind = models.AliasKindTranslation
+		}
+		result = append(result, LocalizedName{Name: name, Language: language, Kind: kind})
+	}
+	return result
+}
+
+// performChatCompletion sends payload, or its preEncoded form, and returns
+// the reply's content. What was sent and received is noted on call.
+func (c *OpenAIClient) performChatCompletion(ctx context.Context, call *chatCall, payload map[string]any, preEncoded ...[]byte) (string, error) {
 	var body []byte
 	var err error
 	if len(preEncoded) > 0 && preEncoded[0] != nil {
@@ -382,6 +420,8 @@ func (c *Client) performChatCompletion(ctx context.Context, payload map[string]a
 		}
 	}
 
+	call.request = body
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
 	if err != nil {
 		return "", fmt.Errorf("ai: build request: %w", err)
@@ -395,6 +435,12 @@ func (c *Client) performChatCompletion(ctx context.Context, payload map[string]a
 	}
 	defer resp.Body.Close()
 
+	call.status = resp.StatusCode
+	raw, err := io.ReadAll(resp.Body)
+	call.response = raw
+	if err != nil {
+		return "", fmt.Errorf("ai: read response: %w", err)
+	}
 	if resp.StatusCode >= http.StatusMultipleChoices {
 		return "", fmt.Errorf("ai: openai returned status %s", resp.Status)
 	}
@@ -407,7 +453,7 @@ func (c *Client) performChatCompletion(ctx context.Context, payload map[string]a
 		} `json:"choices"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
+	if err := json.Unmarshal(raw, &responseData); err != nil {
 		return "", fmt.Errorf("ai: decode response: %w", err)
 	}
 
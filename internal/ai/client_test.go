@@ -0,0 +1,38 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchAromaProfileParsesLocalizedNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"ingredient_name\":\"Hedione\",\"localized_names\":[{\"name\":\" Dihydrojasmonate de  méthyle \",\"language\":\"FR\",\"kind\":\"INCI\"},{\"name\":\"Dihydrojasmonate de methyle\",\"language\":\"fr-FR\"},{\"name\":\"Methyldihydrojasmonat\",\"language\":\"de\",\"kind\":\"other\"},{\"name\":\"Hédione\",\"language\":\"fr\"},{\"name\":\"No language\"},\"stray\"]}"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(Config{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	profile, err := client.FetchAromaProfile(context.Background(), "Hedione", FetchOptions{})
+	if err != nil {
+		t.Fatalf("FetchAromaProfile() error = %v", err)
+	}
+
+	want := []LocalizedName{
+		{Name: "Dihydrojasmonate de méthyle", Language: "fr", Kind: "inci"},
+		{Name: "Methyldihydrojasmonat", Language: "de", Kind: "translation"},
+	}
+	if len(profile.LocalizedNames) != len(want) {
+		t.Fatalf("LocalizedNames = %+v, want %+v", profile.LocalizedNames, want)
+	}
+	for i := range want {
+		if profile.LocalizedNames[i] != want[i] {
+			t.Fatalf("LocalizedNames[%d] = %+v, want %+v", i, profile.LocalizedNames[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// DocumentText prepares an uploaded formula document for ExtractFormula.
+// PDFs and text files yield their text; images are returned base64-encoded
+// for the model to read.
+func DocumentText(data []byte, mime string) (text string, encoded string, err error) {
+	lower := strings.ToLower(mime)
+	switch {
+	case strings.Contains(lower, "pdf"):
+		text, err := extractTextFromPDF(data)
+		if err != nil {
+			return "", "", err
+		}
+		return text, "", nil
+	case strings.HasPrefix(lower, "text/") || strings.Contains(lower, "json"):
+		return string(data), "", nil
+	case strings.HasPrefix(lower, "image/"):
+		return "", base64.StdEncoding.EncodeToString(data), nil
+	default:
+		return string(data), "", nil
+	}
+}
+
+func extractTextFromPDF(data []byte) (string, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	numPages := reader.NumPage()
+	for i := 1; i <= numPages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(text)
+		builder.WriteString("\n")
+	}
+	return builder.String(), nil
+}
+
+// MimeTypeFromName guesses a document's type from its file extension.
+func MimeTypeFromName(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".txt":
+		return "text/plain"
+	case ".pdf":
+		return "application/pdf"
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "application/octet-stream"
+	}
+}
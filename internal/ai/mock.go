@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"perfugo/models"
+)
+
+// MockModel is the model name MockClient reports for its profiles.
+const MockModel = "mock"
+
+// MockClient returns canned, deterministic responses so AI tooling can be
+// exercised without contacting OpenAI.
+type MockClient struct{}
+
+// NewMockClient builds a MockClient.
+func NewMockClient() *MockClient {
+	return &MockClient{}
+}
+
+var mockProfiles = map[string]Profile{
+	"ambroxan": {
+		IngredientName:      "Ambroxan",
+		CASNumber:           "6790-58-5",
+		OtherNames:          []string{"Ambrox", "Ambrofix"},
+		Notes:               "Dry ambergris facet with a warm mineral glow.",
+		WheelPosition:       "Amber",
+		PyramidPosition:     "Base",
+		Type:                "Aroma Chemical (Ether)",
+		Strength:            6,
+		RecommendedDilution: 10,
+		DilutionPercentage:  10,
+		Duration:            "400 hours",
+		HistoricRole:        "Modern ambergris replacement.",
+		Popularity:          3,
+		Usage:               "Use at 1-10% of concentrate for radiance and tenacity.",
+	},
+	"hedione": {
+		IngredientName: "Hedione",
+		CASNumber:      "24851-98-7",
+		OtherNames:     []string{"Methyl Dihydrojasmonate"},
+		LocalizedNames: []LocalizedName{
+			{Name: "Dihydrojasmonate de méthyle", Language: "fr", Kind: models.AliasKindINCI},
+			{Name: "Methyldihydrojasmonat", Language: "de", Kind: models.AliasKindTranslation},
+		},
+		Notes:               "Transparent jasmine with a fresh citrus lift.",
+		WheelPosition:       "Floral",
+		PyramidPosition:     "Heart",
+		Type:                "Aroma Chemical (Ester)",
+		Strength:            3,
+		RecommendedDilution: 100,
+		DilutionPercentage:  100,
+		Duration:            "200 hours",
+		HistoricRole:        "Popularised diffusive floral accords.",
+		Popularity:          3,
+		Usage:               "Blend generously to add air and diffusion.",
+	},
+	"iso e super": {
+		IngredientName:      "Iso E Super",
+		CASNumber:           "54464-57-2",
+		OtherNames:          []string{"OTNE"},
+		Notes:               "Velvety cedar wood with an ambery hum.",
+		WheelPosition:       "Woods",
+		PyramidPosition:     "Base",
+		Type:                "Aroma Chemical (Ketone)",
+		Strength:            3,
+		RecommendedDilution: 100,
+		DilutionPercentage:  100,
+		MaxIFRAPercentage:   21.4,
+		Duration:            "300 hours",
+		HistoricRole:        "Backbone of many modern woody compositions.",
+		Popularity:          3,
+		Usage:               "Use from traces up to large overdoses for woody volume.",
+	},
+}
+
+var mockWheelPositions = []string{"Citrus", "Floral", "Green", "Woods", "Amber", "Musk"}
+
+var mockPyramidPositions = []string{"Top", "Heart", "Base"}
+
+// FetchAromaProfile returns a canned profile for well-known materials and a
+// deterministic synthetic profile for anything else.
+func (m *MockClient) FetchAromaProfile(ctx context.Context, ingredient string, opts FetchOptions) (Profile, error) {
+	ingredient = strings.TrimSpace(ingredient)
+	if ingredient == "" {
+		return Profile{}, errors.New("ai: ingredient name must not be empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return Profile{}, err
+	}
+
+	if profile, ok := mockProfiles[strings.ToLower(ingredient)]; ok {
+		profile.OtherNames = append([]string(nil), profile.OtherNames...)
+		profile.LocalizedNames = append([]LocalizedName(nil), profile.LocalizedNames...)
+		profile.Model = MockModel
+		return profile, nil
+	}
+
+	seed := mockSeed(ingredient)
+	name := normaliseText(ingredient)
+	return Profile{
+		IngredientName:      name,
+		Notes:               "Mock profile generated for local development.",
+		WheelPosition:       mockWheelPositions[seed%uint32(len(mockWheelPositions))],
+		PyramidPosition:     mockPyramidPositions[seed%uint32(len(mockPyramidPositions))],
+		Type:                "Aroma Chemical",
+		Strength:            int(seed%8) + 1,
+		RecommendedDilution: 10,
+		DilutionPercentage:  10,
+		Duration:            strconv.Itoa(int(seed%96)+4) + " hours",
+		Popularity:          int(seed%4) + 1,
+		Usage:               "Evaluate at 10% before use.",
+		Model:               MockModel,
+	}, nil
+}
+
+// ExtractFormula parses simple "name quantity" lines from the supplied text.
+// When no lines can be parsed a canned formula is returned instead.
+func (m *MockClient) ExtractFormula(ctx context.Context, input FormulaImportInput) (FormulaImportResult, error) {
+	trimmedText := strings.TrimSpace(input.RawText)
+	if trimmedText == "" && strings.TrimSpace(input.Base64File) == "" {
+		return FormulaImportResult{}, errors.New("ai: formula import requires text or file content")
+	}
+	if err := ctx.Err(); err != nil {
+		return FormulaImportResult{}, err
+	}
+
+	name := strings.TrimSpace(input.NameHint)
+	if name == "" {
+		name = "Mock Imported Formula"
+	}
+
+	result := FormulaImportResult{
+		FormulaName: name,
+		Notes:       "Extracted by the mock AI client.",
+	}
+	for _, line := range strings.Split(trimmedText, "\n") {
+		if ingredient, ok := parseMockFormulaLine(line); ok {
+			result.Ingredients = append(result.Ingredients, ingredient)
+		}
+	}
+
+	if len(result.Ingredients) == 0 {
+		result.Ingredients = []FormulaImportIngredient{
+			{IngredientName: "Hedione", OtherNames: []string{"Methyl Dihydrojasmonate"}, QuantityMG: 400},
+			{IngredientName: "Iso E Super", OtherNames: []string{"OTNE"}, QuantityMG: 350},
+			{IngredientName: "Ambroxan", OtherNames: []string{"Ambrox"}, QuantityMG: 250},
+		}
+	}
+
+	return result, nil
+}
+
+func parseMockFormulaLine(line string) (FormulaImportIngredient, bool) {
+	fields := strings.Fields(line)
+	for last := len(fields) - 1; last >= 1 && last >= len(fields)-2; last-- {
+		quantity, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(fields[last]), "mg"), 64)
+		if err != nil || quantity <= 0 {
+			continue
+		}
+		return FormulaImportIngredient{
+			IngredientName: strings.Join(fields[:last], " "),
+			QuantityMG:     quantity,
+		}, true
+	}
+	return FormulaImportIngredient{}, false
+}
+
+func mockSeed(value string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.ToLower(value)))
+	return h.Sum32()
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMockClientFetchAromaProfile(t *testing.T) {
+	t.Parallel()
+
+	client := NewMockClient()
+
+	tests := []struct {
+		name     string
+		input    string
+		wantName string
+		wantCAS  string
+		wantErr  bool
+	}{
+		{name: "known material", input: "  hedione ", wantName: "Hedione", wantCAS: "24851-98-7"},
+		{name: "unknown material", input: "Velvet  Accord", wantName: "Velvet Accord"},
+		{name: "empty name", input: " ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			profile, err := client.FetchAromaProfile(context.Background(), tt.input, FetchOptions{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchAromaProfile() error = %v", err)
+			}
+			if profile.IngredientName != tt.wantName {
+				t.Fatalf("IngredientName = %q, want %q", profile.IngredientName, tt.wantName)
+			}
+			if profile.CASNumber != tt.wantCAS {
+				t.Fatalf("CASNumber = %q, want %q", profile.CASNumber, tt.wantCAS)
+			}
+			if profile.PyramidPosition == "" || profile.Strength < 1 || profile.Strength > 8 {
+				t.Fatalf("expected populated profile, got %+v", profile)
+			}
+
+			again, err := client.FetchAromaProfile(context.Background(), tt.input, FetchOptions{})
+			if err != nil {
+				t.Fatalf("FetchAromaProfile() second call error = %v", err)
+			}
+			if again.WheelPosition != profile.WheelPosition || again.Strength != profile.Strength {
+				t.Fatalf("expected deterministic profile, got %+v then %+v", profile, again)
+			}
+		})
+	}
+}
+
+func TestMockClientExtractFormula(t *testing.T) {
+	t.Parallel()
+
+	client := NewMockClient()
+
+	result, err := client.ExtractFormula(context.Background(), FormulaImportInput{
+		NameHint: "Test Accord",
+		RawText:  "Hedione 200 mg\nIso E Super 150mg\nnot a quantity",
+	})
+	if err != nil {
+		t.Fatalf("ExtractFormula() error = %v", err)
+	}
+	if result.FormulaName != "Test Accord" {
+		t.Fatalf("FormulaName = %q", result.FormulaName)
+	}
+	if len(result.Ingredients) != 2 {
+		t.Fatalf("expected 2 ingredients, got %+v", result.Ingredients)
+	}
+	if result.Ingredients[1].IngredientName != "Iso E Super" || result.Ingredients[1].QuantityMG != 150 {
+		t.Fatalf("unexpected second ingredient %+v", result.Ingredients[1])
+	}
+
+	canned, err := client.ExtractFormula(context.Background(), FormulaImportInput{RawText: "no structured lines"})
+	if err != nil {
+		t.Fatalf("ExtractFormula() canned error = %v", err)
+	}
+	if len(canned.Ingredients) == 0 {
+		t.Fatal("expected canned ingredients when nothing could be parsed")
+	}
+
+	if _, err := client.ExtractFormula(context.Background(), FormulaImportInput{}); err == nil {
+		t.Fatal("expected error for empty input")
+	}
+}
@@ -0,0 +1,212 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+// DefaultRecordLimit caps each recorded field when Config.RecordLimit is unset.
+const DefaultRecordLimit = 32 << 10
+
+// Operations recorded for model calls.
+const (
+	OperationAromaProfile      = "aroma-profile"
+	OperationFormulaExtraction = "formula-extraction"
+)
+
+// Exchange is one request to the model and what came back, already
+// redacted and capped. Input is the operation's own input as JSON, enough
+// to replay the call against a changed prompt.
+type Exchange struct {
+	JobID     string
+	UserID    uint
+	Operation string
+	Model     string
+	Input     string
+	Request   string
+	Response  string
+	Status    int
+	Error     string
+	Duration  time.Duration
+	Truncated bool
+}
+
+// Recorder keeps exchanges for later diagnosis. Recording is best effort:
+// implementations log their own failures rather than failing the call.
+type Recorder interface {
+	RecordExchange(ctx context.Context, exchange Exchange)
+}
+
+// Job identifies the import a model call belongs to. Redact lists values,
+// such as the importing user's name and email, that must not be recorded.
+type Job struct {
+	ID     string
+	UserID uint
+	Redact []string
+}
+
+type jobKey struct{}
+
+// WithJob attaches job to ctx so exchanges made under it are linked to it.
+func WithJob(ctx context.Context, job Job) context.Context {
+	return context.WithValue(ctx, jobKey{}, job)
+}
+
+// JobFrom returns the job attached to ctx, if any.
+func JobFrom(ctx context.Context) (Job, bool) {
+	job, ok := ctx.Value(jobKey{}).(Job)
+	return job, ok
+}
+
+var (
+	emailPattern     = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	secretKeyPattern = regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`)
+	bearerPattern    = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=-]+`)
+)
+
+// redactor removes secrets and personal data from recorded text.
+type redactor struct {
+	values []string
+}
+
+func newRedactor(apiKey string, job Job) redactor {
+	var values []string
+	for _, value := range append([]string{apiKey}, job.Redact...) {
+		if value = strings.TrimSpace(value); len(value) >= 3 {
+			values = append(values, value)
+		}
+	}
+	return redactor{values: values}
+}
+
+func (r redactor) apply(text string) string {
+	for _, value := range r.values {
+		text = replaceFold(text, value, "[redacted]")
+	}
+	text = secretKeyPattern.ReplaceAllString(text, "[redacted-key]")
+	text = bearerPattern.ReplaceAllString(text, "Bearer [redacted-key]")
+	return emailPattern.ReplaceAllString(text, "[redacted-email]")
+}
+
+// replaceFold replaces every case-insensitive occurrence of old in s.
+func replaceFold(s, old, replacement string) string {
+	lower, target := strings.ToLower(s), strings.ToLower(old)
+	if len(lower) != len(s) || !strings.Contains(lower, target) {
+		return strings.ReplaceAll(s, old, replacement)
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, target)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		b.WriteString(replacement)
+		s, lower = s[i+len(target):], lower[i+len(target):]
+	}
+}
+
+// capText cuts text to limit bytes on a rune boundary and notes the cut.
+func capText(text string, limit int) (string, bool) {
+	if limit <= 0 || len(text) <= limit {
+		return text, false
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s…[truncated %d bytes]", text[:cut], len(text)-cut), true
+}
+
+// chatCall collects what one chat completion sent and received.
+type chatCall struct {
+	operation string
+	input     any
+	started   time.Time
+	request   []byte
+	response  []byte
+	status    int
+}
+
+func (c *OpenAIClient) startCall(operation string, input any) *chatCall {
+	return &chatCall{operation: operation, input: input, started: time.Now()}
+}
+
+// finishCall hands the call to the recorder, if one is configured.
+func (c *OpenAIClient) finishCall(ctx context.Context, call *chatCall, model string, err error) {
+	if c.recorder == nil || call == nil {
+		return
+	}
+	job, _ := JobFrom(ctx)
+	redact := newRedactor(c.apiKey, job)
+	limit := c.recordLimit
+	if limit <= 0 {
+		limit = DefaultRecordLimit
+	}
+
+	exchange := Exchange{
+		JobID:     job.ID,
+		UserID:    job.UserID,
+		Operation: call.operation,
+		Model:     model,
+		Status:    call.status,
+		Duration:  time.Since(call.started),
+	}
+	var cut bool
+	input, _ := json.Marshal(call.input)
+	exchange.Input, cut = capText(redact.apply(string(input)), limit)
+	exchange.Truncated = exchange.Truncated || cut
+	exchange.Request, cut = capText(redact.apply(string(call.request)), limit)
+	exchange.Truncated = exchange.Truncated || cut
+	exchange.Response, cut = capText(redact.apply(string(call.response)), limit)
+	exchange.Truncated = exchange.Truncated || cut
+	if err != nil {
+		exchange.Error = redact.apply(err.Error())
+	}
+	c.recorder.RecordExchange(ctx, exchange)
+}
+
+// DBRecorder stores exchanges as models.AIExchange rows.
+type DBRecorder struct {
+	db    *gorm.DB
+	onErr func(ctx context.Context, err error)
+}
+
+// NewDBRecorder returns a Recorder writing to db. onErr, which may be nil,
+// is told about rows that could not be saved.
+func NewDBRecorder(db *gorm.DB, onErr func(ctx context.Context, err error)) *DBRecorder {
+	return &DBRecorder{db: db, onErr: onErr}
+}
+
+// RecordExchange saves the exchange. It is written even when ctx has been
+// cancelled, as timed-out calls are the ones most worth diagnosing.
+func (r *DBRecorder) RecordExchange(ctx context.Context, exchange Exchange) {
+	row := models.AIExchange{
+		JobID:      exchange.JobID,
+		Operation:  exchange.Operation,
+		ModelName:  exchange.Model,
+		Input:      exchange.Input,
+		Request:    exchange.Request,
+		Response:   exchange.Response,
+		Status:     exchange.Status,
+		Error:      exchange.Error,
+		DurationMs: exchange.Duration.Milliseconds(),
+		Truncated:  exchange.Truncated,
+	}
+	if exchange.UserID != 0 {
+		row.UserID = &exchange.UserID
+	}
+	if err := r.db.WithContext(context.WithoutCancel(ctx)).Create(&row).Error; err != nil && r.onErr != nil {
+		r.onErr(ctx, err)
+	}
+}
@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type captureRecorder struct {
+	exchanges []Exchange
+}
+
+func (r *captureRecorder) RecordExchange(_ context.Context, exchange Exchange) {
+	r.exchanges = append(r.exchanges, exchange)
+}
+
+func TestExtractFormulaRecordsRedactedExchange(t *testing.T) {
+	const apiKey = "sk-test-abcdefghijklmnopqrstuvwxyz"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"Sorry, I cannot read this formula for Ana Costa."}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	recorder := &captureRecorder{}
+	client, err := NewClient(Config{APIKey: apiKey, BaseURL: server.URL, Recorder: recorder, RecordLimit: 400})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	ctx := WithJob(context.Background(), Job{ID: "job-1", UserID: 7, Redact: []string{"Ana Costa", "ana@example.com"}})
+	text := "Formula by ana costa (ana@example.com, backup ana.c@lab.example)\n" + strings.Repeat("Hedione 10 g\n", 60)
+	if _, err := client.ExtractFormula(ctx, FormulaImportInput{RawText: text}); err == nil {
+		t.Fatal("expected a parse error")
+	}
+
+	if len(recorder.exchanges) != 1 {
+		t.Fatalf("expected one exchange, got %d", len(recorder.exchanges))
+	}
+	exchange := recorder.exchanges[0]
+	if exchange.JobID != "job-1" || exchange.UserID != 7 || exchange.Operation != OperationFormulaExtraction || exchange.Status != http.StatusOK {
+		t.Fatalf("unexpected exchange metadata: %+v", exchange)
+	}
+	if !strings.Contains(exchange.Error, "parse formula payload") {
+		t.Fatalf("error not recorded: %q", exchange.Error)
+	}
+	for _, field := range []string{exchange.Input, exchange.Request, exchange.Response, exchange.Error} {
+		lower := strings.ToLower(field)
+		if strings.Contains(field, apiKey) || strings.Contains(lower, "ana costa") || strings.Contains(field, "@example.com") || strings.Contains(field, "@lab.example") {
+			t.Fatalf("field not redacted: %s", field)
+		}
+		if len(field) > 400+len("…[truncated 000000 bytes]") {
+			t.Fatalf("field not capped: %d bytes", len(field))
+		}
+	}
+	if !exchange.Truncated || !strings.Contains(exchange.Request, "[truncated") {
+		t.Fatalf("expected the request to be truncated: %+v", exchange)
+	}
+	if !strings.Contains(exchange.Response, "[redacted]") {
+		t.Fatalf("response should keep its redacted content: %q", exchange.Response)
+	}
+}
+
+func TestCapTextKeepsRunesWhole(t *testing.T) {
+	capped, cut := capText(strings.Repeat("é", 10), 5)
+	if !cut || !strings.HasPrefix(capped, "éé…") {
+		t.Fatalf("capText = %q, %t", capped, cut)
+	}
+	if same, cut := capText("short", 10); cut || same != "short" {
+		t.Fatalf("short text changed: %q", same)
+	}
+}
@@ -0,0 +1,122 @@
+// Package analytics maintains lightweight per-entity usage counters.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"perfugo/models"
+)
+
+// Event identifies the kind of interaction being counted.
+type Event string
+
+const (
+	EventView  Event = "view"
+	EventEdit  Event = "edit"
+	EventBatch Event = "batch"
+)
+
+// Key identifies a tracked entity.
+type Key struct {
+	EntityType string
+	EntityID   uint
+}
+
+// Period returns the monthly bucket used for the supplied time.
+func Period(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// Record increments the counter for event on the entity's current monthly bucket.
+func Record(ctx context.Context, db *gorm.DB, key Key, event Event, at time.Time) error {
+	if db == nil {
+		return gorm.ErrInvalidDB
+	}
+	if key.EntityType == "" || key.EntityID == 0 {
+		return errors.New("analytics: entity type and id are required")
+	}
+
+	counter := models.ActivityCounter{
+		EntityType:     key.EntityType,
+		EntityID:       key.EntityID,
+		Period:         Period(at),
+		LastActivityAt: at,
+	}
+	var column string
+	switch event {
+	case EventView:
+		counter.Views = 1
+		column = "views"
+	case EventEdit:
+		counter.Edits = 1
+		column = "edits"
+	case EventBatch:
+		counter.Batches = 1
+		column = "batches"
+	default:
+		return errors.New("analytics: unknown event " + string(event))
+	}
+
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "entity_type"}, {Name: "entity_id"}, {Name: "period"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			column:             gorm.Expr("activity_counters." + column + " + 1"),
+			"last_activity_at": at,
+			"updated_at":       at,
+		}),
+	}).Create(&counter).Error
+}
+
+// MostActive returns the counters for the month containing now, busiest first.
+func MostActive(ctx context.Context, db *gorm.DB, now time.Time, limit int) ([]models.ActivityCounter, error) {
+	if db == nil {
+		return nil, gorm.ErrInvalidDB
+	}
+
+	var counters []models.ActivityCounter
+	if err := db.WithContext(ctx).
+		Where("period = ?", Period(now)).
+		Find(&counters).Error; err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(counters, func(i, j int) bool {
+		if counters[i].Total() != counters[j].Total() {
+			return counters[i].Total() > counters[j].Total()
+		}
+		return counters[i].LastActivityAt.After(counters[j].LastActivityAt)
+	})
+	if limit > 0 && len(counters) > limit {
+		counters = counters[:limit]
+	}
+	return counters, nil
+}
+
+// LastActivity returns the most recent recorded interaction for every tracked entity.
+func LastActivity(ctx context.Context, db *gorm.DB) (map[Key]time.Time, error) {
+	if db == nil {
+		return nil, gorm.ErrInvalidDB
+	}
+
+	var counters []models.ActivityCounter
+	if err := db.WithContext(ctx).
+		Select("entity_type", "entity_id", "last_activity_at").
+		Find(&counters).Error; err != nil {
+		return nil, err
+	}
+
+	latest := make(map[Key]time.Time, len(counters))
+	for _, counter := range counters {
+		key := Key{EntityType: counter.EntityType, EntityID: counter.EntityID}
+		if counter.LastActivityAt.After(latest[key]) {
+			latest[key] = counter.LastActivityAt
+		}
+	}
+	return latest, nil
+}
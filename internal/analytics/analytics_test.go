@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func newAnalyticsTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:analytics-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.ActivityCounter{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	return db
+}
+
+func TestRecordAccumulatesPerMonth(t *testing.T) {
+	db := newAnalyticsTestDB(t)
+	ctx := context.Background()
+
+	formula := Key{EntityType: models.ActivityEntityFormula, EntityID: 7}
+	chemical := Key{EntityType: models.ActivityEntityAromaChemical, EntityID: 3}
+	september := time.Date(2026, time.September, 28, 10, 0, 0, 0, time.UTC)
+	october := time.Date(2026, time.October, 2, 10, 0, 0, 0, time.UTC)
+
+	events := []struct {
+		key   Key
+		event Event
+		at    time.Time
+	}{
+		{formula, EventView, september},
+		{formula, EventView, october},
+		{formula, EventView, october.Add(time.Hour)},
+		{formula, EventEdit, october.Add(2 * time.Hour)},
+		{formula, EventBatch, october.Add(3 * time.Hour)},
+		{chemical, EventView, october},
+	}
+	for _, e := range events {
+		if err := Record(ctx, db, e.key, e.event, e.at); err != nil {
+			t.Fatalf("record %s: %v", e.event, err)
+		}
+	}
+
+	if err := Record(ctx, db, formula, Event("share"), october); err == nil {
+		t.Fatal("expected unknown event to be rejected")
+	}
+
+	active, err := MostActive(ctx, db, october, 0)
+	if err != nil {
+		t.Fatalf("most active: %v", err)
+	}
+	if len(active) != 2 {
+		t.Fatalf("expected 2 counters for October, got %d", len(active))
+	}
+	top := active[0]
+	if top.EntityID != formula.EntityID || top.Views != 2 || top.Edits != 1 || top.Batches != 1 {
+		t.Fatalf("unexpected top counter: %+v", top)
+	}
+
+	limited, err := MostActive(ctx, db, october, 1)
+	if err != nil || len(limited) != 1 {
+		t.Fatalf("expected limit to apply, got %d (%v)", len(limited), err)
+	}
+
+	latest, err := LastActivity(ctx, db)
+	if err != nil {
+		t.Fatalf("last activity: %v", err)
+	}
+	if got := latest[formula]; !got.Equal(october.Add(3 * time.Hour)) {
+		t.Fatalf("expected latest formula activity at %s, got %s", october.Add(3*time.Hour), got)
+	}
+}
@@ -0,0 +1,241 @@
+// Package bundle moves workspaces between Perfugo instances, such as from
+// the hosted service to a self-hosted one. A bundle is a zip archive holding
+// a JSON manifest of every exported record, user preferences included, and
+// the uploaded files those records point at. Importing a bundle recreates
+// the records under new IDs and rewrites every reference to match.
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/storage"
+	"perfugo/models"
+)
+
+// Format versions the bundle manifest.
+const Format = "perfugo.bundle/v1"
+
+const (
+	manifestName  = "bundle.json"
+	attachmentDir = "attachments/"
+)
+
+// ErrUserNotFound is returned by Export when the requested user does not exist.
+var ErrUserNotFound = errors.New("bundle: user not found")
+
+// Manifest lists the exported records with the IDs they had on the source
+// instance. Users carry their password hashes and preferences, so a bundle
+// must be kept as private as the database it came from.
+//
+// Blind tests, organ layouts, substitutions, audit and activity history are
+// specific to the source instance and are not exported.
+type Manifest struct {
+	Format             string                      `json:"format"`
+	ExportedAt         time.Time                   `json:"exported_at"`
+	Users              []models.User               `json:"users"`
+	AromaChemicals     []models.AromaChemical      `json:"aroma_chemicals"`
+	Formulas           []models.Formula            `json:"formulas"`
+	FormulaIngredients []models.FormulaIngredient  `json:"formula_ingredients"`
+	InventoryItems     []models.InventoryItem      `json:"inventory_items"`
+	WishlistItems      []models.WishlistItem       `json:"wishlist_items"`
+	IngredientNotes    []models.UserIngredientNote `json:"ingredient_notes"`
+	BatchPresets       []models.BatchPreset        `json:"batch_presets"`
+	ReportDefinitions  []models.ReportDefinition   `json:"report_definitions"`
+	ProductionBatches  []models.ProductionBatch    `json:"production_batches"`
+}
+
+// ExportOptions selects what Export writes. An empty UserEmail exports
+// every user.
+type ExportOptions struct {
+	UserEmail string
+}
+
+// Summary counts the records written or created.
+type Summary struct {
+	Users          int
+	AromaChemicals int
+	Formulas       int
+	Batches        int
+	Attachments    int
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d users, %d aroma chemicals, %d formulas, %d production batches, %d attachments",
+		s.Users, s.AromaChemicals, s.Formulas, s.Batches, s.Attachments)
+}
+
+// Export writes a bundle to w. Exporting a single user also takes along
+// the shared chemicals and formulas their own records use, so the bundle
+// is complete on its own. Store may be nil when no uploads are kept.
+func Export(ctx context.Context, db *gorm.DB, store storage.Store, w io.Writer, opts ExportOptions) (Summary, error) {
+	manifest, err := collect(db.WithContext(ctx), strings.TrimSpace(opts.UserEmail))
+	if err != nil {
+		return Summary{}, err
+	}
+	manifest.Format = Format
+	manifest.ExportedAt = time.Now().UTC()
+
+	archive := zip.NewWriter(w)
+	entry, err := archive.Create(manifestName)
+	if err != nil {
+		return Summary{}, err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return Summary{}, fmt.Errorf("bundle: encode manifest: %w", err)
+	}
+
+	summary := Summary{
+		Users:          len(manifest.Users),
+		AromaChemicals: len(manifest.AromaChemicals),
+		Formulas:       len(manifest.Formulas),
+		Batches:        len(manifest.ProductionBatches),
+	}
+	for _, user := range manifest.Users {
+		if user.AvatarKey == "" || store == nil {
+			continue
+		}
+		data, err := store.Get(ctx, user.AvatarKey)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Summary{}, fmt.Errorf("bundle: read %s: %w", user.AvatarKey, err)
+		}
+		entry, err := archive.Create(attachmentDir + user.AvatarKey)
+		if err != nil {
+			return Summary{}, err
+		}
+		if _, err := entry.Write(data); err != nil {
+			return Summary{}, err
+		}
+		summary.Attachments++
+	}
+	if err := archive.Close(); err != nil {
+		return Summary{}, err
+	}
+	return summary, nil
+}
+
+// collect loads the records to export for one user, or for everyone when
+// email is empty.
+func collect(db *gorm.DB, email string) (Manifest, error) {
+	var manifest Manifest
+	users := db.Order("id asc")
+	if email != "" {
+		users = users.Where("LOWER(email) = ?", strings.ToLower(email))
+	}
+	if err := users.Find(&manifest.Users).Error; err != nil {
+		return manifest, fmt.Errorf("bundle: load users: %w", err)
+	}
+	if email != "" && len(manifest.Users) == 0 {
+		return manifest, ErrUserNotFound
+	}
+	userIDs := make([]uint, 0, len(manifest.Users))
+	for _, user := range manifest.Users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	for _, records := range []struct {
+		column string
+		dest   any
+	}{
+		{"owner_id", &manifest.InventoryItems},
+		{"owner_id", &manifest.WishlistItems},
+		{"user_id", &manifest.IngredientNotes},
+		{"owner_id", &manifest.BatchPresets},
+		{"owner_id", &manifest.ReportDefinitions},
+	} {
+		if err := db.Where(records.column+" IN ?", userIDs).Order("id asc").Find(records.dest).Error; err != nil {
+			return manifest, fmt.Errorf("bundle: load records: %w", err)
+		}
+	}
+	if err := db.Preload("Lines").Where("owner_id IN ?", userIDs).Order("id asc").Find(&manifest.ProductionBatches).Error; err != nil {
+		return manifest, fmt.Errorf("bundle: load production batches: %w", err)
+	}
+
+	formulas := db.Order("id asc")
+	if email != "" {
+		// The user's formulas, those their batches were made from and,
+		// below, every sub-formula these use.
+		batchFormulas := make([]uint, 0, len(manifest.ProductionBatches))
+		for _, batch := range manifest.ProductionBatches {
+			batchFormulas = append(batchFormulas, batch.FormulaID)
+		}
+		formulas = formulas.Where("created_by_id IN ? OR id IN ?", userIDs, append(batchFormulas, 0))
+	}
+	if err := formulas.Find(&manifest.Formulas).Error; err != nil {
+		return manifest, fmt.Errorf("bundle: load formulas: %w", err)
+	}
+	included := make(map[uint]bool, len(manifest.Formulas))
+	for _, formula := range manifest.Formulas {
+		included[formula.ID] = true
+	}
+	for pending := keys(included); len(pending) > 0; {
+		var rows []models.FormulaIngredient
+		if err := db.Where("formula_id IN ?", pending).Order("formula_id asc, position asc, id asc").Find(&rows).Error; err != nil {
+			return manifest, fmt.Errorf("bundle: load formula ingredients: %w", err)
+		}
+		manifest.FormulaIngredients = append(manifest.FormulaIngredients, rows...)
+		var missing []uint
+		for _, row := range rows {
+			if row.SubFormulaID != nil && !included[*row.SubFormulaID] {
+				included[*row.SubFormulaID] = true
+				missing = append(missing, *row.SubFormulaID)
+			}
+		}
+		pending = nil
+		if len(missing) > 0 {
+			var subFormulas []models.Formula
+			if err := db.Where("id IN ?", missing).Find(&subFormulas).Error; err != nil {
+				return manifest, fmt.Errorf("bundle: load sub-formulas: %w", err)
+			}
+			manifest.Formulas = append(manifest.Formulas, subFormulas...)
+			for _, formula := range subFormulas {
+				pending = append(pending, formula.ID)
+			}
+		}
+	}
+
+	chemicals := db.Preload("OtherNames").Order("id asc")
+	if email != "" {
+		referenced := []uint{0}
+		for _, row := range manifest.FormulaIngredients {
+			if row.AromaChemicalID != nil {
+				referenced = append(referenced, *row.AromaChemicalID)
+			}
+		}
+		for _, item := range manifest.InventoryItems {
+			referenced = append(referenced, item.AromaChemicalID)
+		}
+		for _, item := range manifest.WishlistItems {
+			referenced = append(referenced, item.AromaChemicalID)
+		}
+		for _, note := range manifest.IngredientNotes {
+			referenced = append(referenced, note.AromaChemicalID)
+		}
+		chemicals = chemicals.Where("owner_id IN ? OR id IN ?", userIDs, referenced)
+	}
+	if err := chemicals.Find(&manifest.AromaChemicals).Error; err != nil {
+		return manifest, fmt.Errorf("bundle: load aroma chemicals: %w", err)
+	}
+	return manifest, nil
+}
+
+func keys(set map[uint]bool) []uint {
+	out := make([]uint, 0, len(set))
+	for id := range set {
+		out = append(out, id)
+	}
+	return out
+}
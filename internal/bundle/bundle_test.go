@@ -0,0 +1,190 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/internal/storage"
+	"perfugo/models"
+)
+
+func newBundleTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:bundle-%s-%d?mode=memory&cache=shared", name, time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.AromaChemical{},
+		&models.OtherName{},
+		&models.Formula{},
+		&models.FormulaIngredient{},
+		&models.InventoryItem{},
+		&models.WishlistItem{},
+		&models.UserIngredientNote{},
+		&models.BatchPreset{},
+		&models.ReportDefinition{},
+		&models.ProductionBatch{},
+		&models.ProductionBatchLine{},
+	); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	return db
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, values ...any) {
+	t.Helper()
+	for _, value := range values {
+		if err := db.Create(value).Error; err != nil {
+			t.Fatalf("create %T: %v", value, err)
+		}
+	}
+}
+
+func TestExportImportSingleUser(t *testing.T) {
+	ctx := context.Background()
+	source := newBundleTestDB(t, "source")
+	sourceStore, err := storage.NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatalf("storage: %v", err)
+	}
+
+	curator := models.User{Email: "curator@example.com", PasswordHash: "x"}
+	perfumer := models.User{Email: "Perfumer@example.com", PasswordHash: "hash", Theme: models.ThemeAtelierIvory, AmountDecimals: 3,
+		KeyboardShortcuts: `{"search":"k"}`}
+	mustCreate(t, source, &curator, &perfumer)
+	perfumer.AvatarKey = fmt.Sprintf("avatars/%d-abc.png", perfumer.ID)
+	source.Save(&perfumer)
+	if err := sourceStore.Put(ctx, perfumer.AvatarKey, []byte("png")); err != nil {
+		t.Fatalf("put avatar: %v", err)
+	}
+
+	shared := models.AromaChemical{IngredientName: "Hedione", CASNumber: "24851-98-7", OwnerID: curator.ID, Public: true}
+	unrelated := models.AromaChemical{IngredientName: "Unused", OwnerID: curator.ID, Public: true}
+	private := models.AromaChemical{IngredientName: "House musk", OwnerID: perfumer.ID, OtherNames: []models.OtherName{{Name: "HM-1"}}}
+	mustCreate(t, source, &shared, &unrelated, &private)
+	stock := models.InventoryItem{OwnerID: perfumer.ID, AromaChemicalID: private.ID, QuantityMg: 900, DilutionPercent: 10}
+	mustCreate(t, source, &stock)
+
+	accord := models.Formula{Name: "Accord", Version: 1, IsLatest: true, CreatedByID: &curator.ID}
+	mustCreate(t, source, &accord)
+	first := models.Formula{Name: "Nuit", Version: 1, CreatedByID: &perfumer.ID}
+	mustCreate(t, source, &first)
+	second := models.Formula{Name: "Nuit", Version: 2, IsLatest: true, ParentFormulaID: &first.ID, CreatedByID: &perfumer.ID}
+	mustCreate(t, source, &second)
+	mustCreate(t, source,
+		&models.FormulaIngredient{FormulaID: accord.ID, AromaChemicalID: &shared.ID, Amount: 1, Unit: "g"},
+		&models.FormulaIngredient{FormulaID: second.ID, SubFormulaID: &accord.ID, Amount: 2, Unit: "g", Position: 1},
+		&models.FormulaIngredient{FormulaID: second.ID, AromaChemicalID: &private.ID, StockSolutionID: &stock.ID, Amount: 3, Unit: "g", Position: 2},
+		&models.WishlistItem{OwnerID: perfumer.ID, AromaChemicalID: shared.ID},
+		&models.BatchPreset{OwnerID: perfumer.ID, Label: "10 g", QuantityMg: 10000},
+		&models.ProductionBatch{FormulaID: second.ID, FormulaName: "Nuit", OwnerID: perfumer.ID, LotNumber: "LOT-1", Status: models.ProductionBatchOpen,
+			Lines: []models.ProductionBatchLine{{Position: 1, AromaChemicalID: &private.ID, IngredientName: "House musk", TargetQuantity: 300}}},
+	)
+
+	var archive bytes.Buffer
+	exported, err := Export(ctx, source, sourceStore, &archive, ExportOptions{UserEmail: "perfumer@example.com"})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if exported.Users != 1 || exported.Formulas != 3 || exported.AromaChemicals != 2 || exported.Attachments != 1 {
+		t.Fatalf("unexpected export summary: %s", exported)
+	}
+
+	target := newBundleTestDB(t, "target")
+	targetStore, err := storage.NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatalf("storage: %v", err)
+	}
+	// Shift the target's IDs so stale references would be caught.
+	existing := models.AromaChemical{IngredientName: "Hedione (library)", CASNumber: "24851-98-7", OwnerID: 99, Public: true}
+	mustCreate(t, target, &models.User{Email: "admin@example.com", PasswordHash: "x"}, &models.AromaChemical{IngredientName: "Filler", OwnerID: 99}, &existing)
+
+	imported, err := Import(ctx, target, targetStore, bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if imported.Users != 1 || imported.AromaChemicals != 1 || imported.Formulas != 3 || imported.Batches != 1 || imported.Attachments != 1 {
+		t.Fatalf("unexpected import summary: %s", imported)
+	}
+
+	var user models.User
+	if err := target.Where("email = ?", "Perfumer@example.com").First(&user).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if user.PasswordHash != "hash" || user.Theme != models.ThemeAtelierIvory || user.AmountDecimals != 3 || user.KeyboardShortcuts != `{"search":"k"}` {
+		t.Fatalf("preferences were not carried over: %+v", user)
+	}
+	if want := fmt.Sprintf("avatars/%d-abc.png", user.ID); user.AvatarKey != want {
+		t.Fatalf("avatar key = %q, want %q", user.AvatarKey, want)
+	}
+	if data, err := targetStore.Get(ctx, user.AvatarKey); err != nil || string(data) != "png" {
+		t.Fatalf("avatar not copied: %q, %v", data, err)
+	}
+
+	var musk models.AromaChemical
+	if err := target.Preload("OtherNames").Where("ingredient_name = ?", "House musk").First(&musk).Error; err != nil {
+		t.Fatalf("load chemical: %v", err)
+	}
+	if musk.OwnerID != user.ID || len(musk.OtherNames) != 1 {
+		t.Fatalf("private chemical not rewritten: %+v", musk)
+	}
+
+	var latest models.Formula
+	if err := target.Where("name = ? AND version = ?", "Nuit", 2).First(&latest).Error; err != nil {
+		t.Fatalf("load formula: %v", err)
+	}
+	var parent models.Formula
+	target.First(&parent, *latest.ParentFormulaID)
+	if parent.Name != "Nuit" || parent.Version != 1 || latest.CreatedByID == nil || *latest.CreatedByID != user.ID {
+		t.Fatalf("formula references not rewritten: %+v, parent %+v", latest, parent)
+	}
+	var rows []models.FormulaIngredient
+	target.Where("formula_id = ?", latest.ID).Order("position asc").Preload("SubFormula").Preload("StockSolution").Find(&rows)
+	if len(rows) != 2 || rows[0].SubFormula == nil || rows[0].SubFormula.Name != "Accord" ||
+		*rows[1].AromaChemicalID != musk.ID || rows[1].StockSolution == nil || rows[1].StockSolution.OwnerID != user.ID {
+		t.Fatalf("ingredients not rewritten: %+v", rows)
+	}
+	var accordRow models.FormulaIngredient
+	target.Where("formula_id = ?", rows[0].SubFormula.ID).First(&accordRow)
+	if accordRow.AromaChemicalID == nil || *accordRow.AromaChemicalID != existing.ID {
+		t.Fatalf("shared chemical should map onto the existing public record, got %+v", accordRow)
+	}
+
+	var batch models.ProductionBatch
+	target.Preload("Lines").First(&batch)
+	if batch.OwnerID != user.ID || batch.FormulaID != latest.ID || len(batch.Lines) != 1 || *batch.Lines[0].AromaChemicalID != musk.ID {
+		t.Fatalf("batch not rewritten: %+v", batch)
+	}
+
+	// Importing again attaches the records to the existing account.
+	again, err := Import(ctx, target, targetStore, bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("second import: %v", err)
+	}
+	var users int64
+	target.Model(&models.User{}).Count(&users)
+	if again.Users != 0 || users != 2 {
+		t.Fatalf("expected the existing user to be reused, got %s and %d users", again, users)
+	}
+}
+
+func TestImportRejectsUnknownFormat(t *testing.T) {
+	_, err := Import(context.Background(), newBundleTestDB(t, "reject"), nil, strings.NewReader("not a zip"), 9)
+	if err == nil {
+		t.Fatal("expected an error for a non-zip archive")
+	}
+}
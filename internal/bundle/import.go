@@ -0,0 +1,318 @@
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"perfugo/internal/storage"
+	"perfugo/models"
+)
+
+// ErrUnsupportedFormat is returned by Import for archives that are not
+// bundles this version can read.
+var ErrUnsupportedFormat = errors.New("bundle: unsupported format")
+
+// idMap translates source instance IDs to the IDs records were given on
+// import.
+type idMap map[uint]uint
+
+// ref returns the new ID for a reference, or nil when it was not imported.
+func (m idMap) ref(id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+	mapped, ok := m[*id]
+	if !ok {
+		return nil
+	}
+	return &mapped
+}
+
+type attachment struct {
+	key  string
+	data []byte
+}
+
+// Import recreates the records of the bundle in r under new IDs, in one
+// transaction. A user whose email already exists keeps their account and
+// settings and receives the bundle's records. Shared chemicals owned by
+// someone outside the bundle are matched against the public library by CAS
+// number or name and only created when missing. Attachments are written to
+// store once the records are saved; store may be nil to skip them.
+func Import(ctx context.Context, db *gorm.DB, store storage.Store, r io.ReaderAt, size int64) (Summary, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return Summary{}, fmt.Errorf("bundle: open archive: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	manifestFile, ok := files[manifestName]
+	if !ok {
+		return Summary{}, fmt.Errorf("%w: %s is missing", ErrUnsupportedFormat, manifestName)
+	}
+	var manifest Manifest
+	if err := readJSON(manifestFile, &manifest); err != nil {
+		return Summary{}, fmt.Errorf("bundle: read manifest: %w", err)
+	}
+	if manifest.Format != Format {
+		return Summary{}, fmt.Errorf("%w: %q", ErrUnsupportedFormat, manifest.Format)
+	}
+
+	var summary Summary
+	var attachments []attachment
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		users := idMap{}
+		var fallbackOwner uint
+		for _, user := range manifest.Users {
+			oldID := user.ID
+			var existing models.User
+			err := tx.Where("LOWER(email) = ?", strings.ToLower(user.Email)).First(&existing).Error
+			if err == nil {
+				users[oldID] = existing.ID
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bundle: match user: %w", err)
+			} else {
+				avatar := user.AvatarKey
+				user.ID, user.AvatarKey = 0, ""
+				if err := tx.Create(&user).Error; err != nil {
+					return fmt.Errorf("bundle: create user %s: %w", user.Email, err)
+				}
+				users[oldID] = user.ID
+				summary.Users++
+
+				if file, ok := files[attachmentDir+avatar]; ok && avatar != "" {
+					data, err := readFile(file)
+					if err != nil {
+						return fmt.Errorf("bundle: read %s: %w", avatar, err)
+					}
+					key := avatarKey(avatar, user.ID)
+					if err := tx.Model(&user).Update("avatar_key", key).Error; err != nil {
+						return err
+					}
+					attachments = append(attachments, attachment{key: key, data: data})
+				}
+			}
+			if fallbackOwner == 0 {
+				fallbackOwner = users[oldID]
+			}
+		}
+
+		chemicals := idMap{}
+		for _, chemical := range manifest.AromaChemicals {
+			oldID := chemical.ID
+			owner, ok := users[chemical.OwnerID]
+			if !ok {
+				existing, err := findSharedChemical(tx, chemical)
+				if err != nil {
+					return err
+				}
+				if existing != 0 {
+					chemicals[oldID] = existing
+					continue
+				}
+				owner = fallbackOwner
+			}
+			chemical.ID, chemical.OwnerID = 0, owner
+			chemical.Owner, chemical.StockSolutions = nil, nil
+			for i := range chemical.OtherNames {
+				chemical.OtherNames[i].ID, chemical.OtherNames[i].AromaChemicalID = 0, 0
+			}
+			if err := tx.Create(&chemical).Error; err != nil {
+				return fmt.Errorf("bundle: create aroma chemical %s: %w", chemical.IngredientName, err)
+			}
+			chemicals[oldID] = chemical.ID
+			summary.AromaChemicals++
+		}
+
+		inventory := idMap{}
+		for _, item := range manifest.InventoryItems {
+			owner, ownerOK := users[item.OwnerID]
+			chemical, chemicalOK := chemicals[item.AromaChemicalID]
+			if !ownerOK || !chemicalOK {
+				continue
+			}
+			oldID := item.ID
+			item.ID, item.OwnerID, item.AromaChemicalID, item.AromaChemical = 0, owner, chemical, nil
+			if err := tx.Create(&item).Error; err != nil {
+				return fmt.Errorf("bundle: create inventory item: %w", err)
+			}
+			inventory[oldID] = item.ID
+		}
+
+		formulas := idMap{}
+		for _, formula := range manifest.Formulas {
+			oldID := formula.ID
+			formula.ID, formula.Ingredients, formula.ParentFormulaID = 0, nil, nil
+			formula.CreatedByID = users.ref(formula.CreatedByID)
+			formula.ApprovedByID = users.ref(formula.ApprovedByID)
+			if err := tx.Create(&formula).Error; err != nil {
+				return fmt.Errorf("bundle: create formula %s: %w", formula.Name, err)
+			}
+			formulas[oldID] = formula.ID
+			summary.Formulas++
+		}
+		// Versions point at their parent, which may have been created later.
+		for _, formula := range manifest.Formulas {
+			if parent := formulas.ref(formula.ParentFormulaID); parent != nil {
+				if err := tx.Model(&models.Formula{}).Where("id = ?", formulas[formula.ID]).Update("parent_formula_id", *parent).Error; err != nil {
+					return fmt.Errorf("bundle: link formula versions: %w", err)
+				}
+			}
+		}
+
+		for _, row := range manifest.FormulaIngredients {
+			formulaID, ok := formulas[row.FormulaID]
+			if !ok {
+				continue
+			}
+			row.ID, row.FormulaID = 0, formulaID
+			row.AromaChemicalID = chemicals.ref(row.AromaChemicalID)
+			row.SubFormulaID = formulas.ref(row.SubFormulaID)
+			row.StockSolutionID = inventory.ref(row.StockSolutionID)
+			row.AromaChemical, row.SubFormula, row.StockSolution, row.Formula = nil, nil, nil, nil
+			if row.AromaChemicalID == nil && row.SubFormulaID == nil {
+				continue
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return fmt.Errorf("bundle: create formula ingredient: %w", err)
+			}
+		}
+
+		// An existing user may already list a matched shared chemical.
+		ignoreDuplicates := tx.Clauses(clause.OnConflict{DoNothing: true})
+		for _, item := range manifest.WishlistItems {
+			owner, ownerOK := users[item.OwnerID]
+			chemical, chemicalOK := chemicals[item.AromaChemicalID]
+			if !ownerOK || !chemicalOK {
+				continue
+			}
+			item.ID, item.OwnerID, item.AromaChemicalID, item.AromaChemical = 0, owner, chemical, nil
+			if err := ignoreDuplicates.Create(&item).Error; err != nil {
+				return fmt.Errorf("bundle: create wishlist item: %w", err)
+			}
+		}
+		for _, note := range manifest.IngredientNotes {
+			owner, ownerOK := users[note.UserID]
+			chemical, chemicalOK := chemicals[note.AromaChemicalID]
+			if !ownerOK || !chemicalOK {
+				continue
+			}
+			note.ID, note.UserID, note.AromaChemicalID = 0, owner, chemical
+			if err := ignoreDuplicates.Create(&note).Error; err != nil {
+				return fmt.Errorf("bundle: create ingredient note: %w", err)
+			}
+		}
+		for _, preset := range manifest.BatchPresets {
+			owner, ok := users[preset.OwnerID]
+			if !ok {
+				continue
+			}
+			preset.ID, preset.OwnerID = 0, owner
+			if err := tx.Create(&preset).Error; err != nil {
+				return fmt.Errorf("bundle: create batch preset: %w", err)
+			}
+		}
+		for _, definition := range manifest.ReportDefinitions {
+			owner, ok := users[definition.OwnerID]
+			if !ok {
+				continue
+			}
+			definition.ID, definition.OwnerID = 0, owner
+			if err := tx.Create(&definition).Error; err != nil {
+				return fmt.Errorf("bundle: create report definition: %w", err)
+			}
+		}
+
+		for _, batch := range manifest.ProductionBatches {
+			owner, ok := users[batch.OwnerID]
+			if !ok {
+				continue
+			}
+			batch.ID, batch.OwnerID = 0, owner
+			batch.FormulaID = formulas[batch.FormulaID]
+			batch.SignedByID = users.ref(batch.SignedByID)
+			for i := range batch.Lines {
+				line := &batch.Lines[i]
+				line.ID, line.BatchID = 0, 0
+				line.AromaChemicalID = chemicals.ref(line.AromaChemicalID)
+			}
+			if err := tx.Create(&batch).Error; err != nil {
+				return fmt.Errorf("bundle: create production batch %s: %w", batch.LotNumber, err)
+			}
+			summary.Batches++
+		}
+		return nil
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+
+	if store != nil {
+		for _, file := range attachments {
+			if err := store.Put(ctx, file.key, file.data); err != nil {
+				return summary, fmt.Errorf("bundle: write %s: %w", file.key, err)
+			}
+			summary.Attachments++
+		}
+	}
+	return summary, nil
+}
+
+// findSharedChemical returns the ID of a public chemical matching by CAS
+// number, or by name when the chemical has none, or 0 when there is none.
+func findSharedChemical(tx *gorm.DB, chemical models.AromaChemical) (uint, error) {
+	query := tx.Model(&models.AromaChemical{}).Where("public = ?", true)
+	if cas := strings.TrimSpace(chemical.CASNumber); cas != "" {
+		query = query.Where("cas_number = ?", cas)
+	} else {
+		query = query.Where("LOWER(ingredient_name) = ?", strings.ToLower(strings.TrimSpace(chemical.IngredientName)))
+	}
+	var ids []uint
+	if err := query.Order("id asc").Limit(1).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("bundle: match shared chemical: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+// avatarKey moves an avatar stored as avatars/<user ID>-<suffix> under the
+// user's new ID. Other keys are kept as they are.
+func avatarKey(key string, userID uint) string {
+	name, ok := strings.CutPrefix(key, "avatars/")
+	if !ok {
+		return key
+	}
+	if _, suffix, ok := strings.Cut(name, "-"); ok {
+		return fmt.Sprintf("avatars/%d-%s", userID, suffix)
+	}
+	return key
+}
+
+func readJSON(file *zip.File, dest any) error {
+	data, err := readFile(file)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func readFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,138 @@
+// Package captcha verifies hCaptcha and Cloudflare Turnstile challenge
+// responses. Both services share the same siteverify contract and differ only
+// in endpoints, widget markup and form field names.
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ProviderHCaptcha selects hCaptcha.
+	ProviderHCaptcha = "hcaptcha"
+	// ProviderTurnstile selects Cloudflare Turnstile.
+	ProviderTurnstile = "turnstile"
+)
+
+// ErrFailed reports that the challenge response was missing or rejected.
+var ErrFailed = errors.New("captcha: verification failed")
+
+type provider struct {
+	verifyURL   string
+	scriptURL   string
+	widgetClass string
+	field       string
+}
+
+var providers = map[string]provider{
+	ProviderHCaptcha: {
+		verifyURL:   "https://api.hcaptcha.com/siteverify",
+		scriptURL:   "https://js.hcaptcha.com/1/api.js",
+		widgetClass: "h-captcha",
+		field:       "h-captcha-response",
+	},
+	ProviderTurnstile: {
+		verifyURL:   "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+		scriptURL:   "https://challenges.cloudflare.com/turnstile/v0/api.js",
+		widgetClass: "cf-turnstile",
+		field:       "cf-turnstile-response",
+	},
+}
+
+// Config selects the provider and its keys.
+type Config struct {
+	Provider   string
+	SiteKey    string
+	SecretKey  string
+	HTTPClient *http.Client
+	// VerifyURL overrides the provider's siteverify endpoint (used in tests).
+	VerifyURL string
+}
+
+// Verifier checks challenge responses submitted with a form.
+type Verifier struct {
+	provider provider
+	siteKey  string
+	secret   string
+	client   *http.Client
+}
+
+// New builds a Verifier for the configured provider.
+func New(cfg Config) (*Verifier, error) {
+	p, ok := providers[strings.ToLower(strings.TrimSpace(cfg.Provider))]
+	if !ok {
+		return nil, fmt.Errorf("captcha: unsupported provider %q", cfg.Provider)
+	}
+	if strings.TrimSpace(cfg.SiteKey) == "" || strings.TrimSpace(cfg.SecretKey) == "" {
+		return nil, errors.New("captcha: site key and secret key are required")
+	}
+	if cfg.VerifyURL != "" {
+		p.verifyURL = cfg.VerifyURL
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Verifier{provider: p, siteKey: strings.TrimSpace(cfg.SiteKey), secret: strings.TrimSpace(cfg.SecretKey), client: client}, nil
+}
+
+// SiteKey is the public key rendered into the widget.
+func (v *Verifier) SiteKey() string { return v.siteKey }
+
+// ScriptURL is the provider's widget script.
+func (v *Verifier) ScriptURL() string { return v.provider.scriptURL }
+
+// WidgetClass is the CSS class the provider script renders into.
+func (v *Verifier) WidgetClass() string { return v.provider.widgetClass }
+
+// Field is the form field the widget populates with its response token.
+func (v *Verifier) Field() string { return v.provider.field }
+
+// Verify validates a response token with the provider. remoteIP is optional.
+func (v *Verifier) Verify(ctx context.Context, response, remoteIP string) error {
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return ErrFailed
+	}
+
+	form := url.Values{"secret": {v.secret}, "response": {response}, "sitekey": {v.siteKey}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.provider.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha: siteverify request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha: siteverify returned %s", resp.Status)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("captcha: decode siteverify response: %w", err)
+	}
+	if !result.Success {
+		if len(result.ErrorCodes) > 0 {
+			return fmt.Errorf("%w: %s", ErrFailed, strings.Join(result.ErrorCodes, ", "))
+		}
+		return ErrFailed
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		ok := r.PostFormValue("secret") == "secret" && r.PostFormValue("response") == "good-token"
+		body := map[string]any{"success": ok}
+		if !ok {
+			body["error-codes"] = []string{"invalid-input-response"}
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	verifier, err := New(Config{Provider: "Turnstile", SiteKey: "site", SecretKey: "secret", VerifyURL: srv.URL})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if verifier.Field() != "cf-turnstile-response" || verifier.WidgetClass() != "cf-turnstile" {
+		t.Fatalf("unexpected widget settings: %q %q", verifier.Field(), verifier.WidgetClass())
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "accepted", token: "good-token"},
+		{name: "rejected", token: "bad-token", wantErr: ErrFailed},
+		{name: "missing", token: "", wantErr: ErrFailed},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := verifier.Verify(context.Background(), tt.token, "203.0.113.9")
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(Config{Provider: "recaptcha", SiteKey: "a", SecretKey: "b"}); err == nil {
+		t.Fatal("expected unsupported provider error")
+	}
+	if _, err := New(Config{Provider: ProviderHCaptcha, SiteKey: "a"}); err == nil {
+		t.Fatal("expected missing secret error")
+	}
+}
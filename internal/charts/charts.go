@@ -0,0 +1,190 @@
+// Package charts draws the small charts of reports and printed sheets as
+// standalone SVG, so they render on screen, in print and in PDFs without a
+// JavaScript charting library. Charts take their colours from the
+// surrounding text through currentColor, bars and lines aside.
+package charts
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Layout defaults, in SVG user units.
+const (
+	defaultWidth  = 480
+	defaultHeight = 160
+	barHeight     = 14
+	barGap        = 8
+	labelWidth    = 120
+	valueWidth    = 72
+	axisPadding   = 24
+	// Accent is the fill of bars and the stroke of lines.
+	Accent = "#38bdf8"
+)
+
+// Bar is one labelled value of a bar chart. Note is printed after the bar,
+// such as "42.0%"; when empty the value is printed with %g.
+type Bar struct {
+	Label string
+	Value float64
+	Note  string
+}
+
+// BarChart is a horizontal bar chart. Bars are scaled to Max, or to the
+// largest value when Max is not set. Negative values draw as empty bars.
+type BarChart struct {
+	// Title is read out by screen readers and shown as a tooltip.
+	Title string
+	Bars  []Bar
+	Max   float64
+	Width int
+}
+
+// Render writes the chart as an <svg> element. A chart without bars writes
+// nothing.
+func (c BarChart) Render(w io.Writer) error {
+	if len(c.Bars) == 0 {
+		return nil
+	}
+	width := c.Width
+	if width <= 0 {
+		width = defaultWidth
+	}
+	scale := c.Max
+	for _, bar := range c.Bars {
+		scale = math.Max(scale, bar.Value)
+	}
+	track := float64(width - labelWidth - valueWidth)
+	height := len(c.Bars)*(barHeight+barGap) - barGap
+
+	var buf bytes.Buffer
+	openSVG(&buf, width, height, c.Title)
+	for i, bar := range c.Bars {
+		y := i * (barHeight + barGap)
+		length := 0.0
+		if scale > 0 && bar.Value > 0 {
+			length = track * bar.Value / scale
+		}
+		note := bar.Note
+		if note == "" {
+			note = strconv.FormatFloat(bar.Value, 'g', -1, 64)
+		}
+		fmt.Fprintf(&buf, `<text x="0" y="%d" font-size="11" fill="currentColor" dominant-baseline="middle">%s</text>`,
+			y+barHeight/2, html.EscapeString(bar.Label))
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="currentColor" opacity="0.12" rx="3"></rect>`,
+			labelWidth, y, track, barHeight)
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="%s" rx="3"><title>%s</title></rect>`,
+			labelWidth, y, length, barHeight, Accent, html.EscapeString(bar.Label+": "+note))
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="11" fill="currentColor" text-anchor="end" dominant-baseline="middle">%s</text>`,
+			width, y+barHeight/2, html.EscapeString(note))
+	}
+	buf.WriteString(`</svg>`)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Point is one dated value of a line chart.
+type Point struct {
+	At    time.Time
+	Value float64
+}
+
+// LineChart plots values over time, with the first and last dates under the
+// axis and the lowest and highest values beside it. Points must be in date
+// order.
+type LineChart struct {
+	Title  string
+	Points []Point
+	Width  int
+	Height int
+	// FormatValue labels the value axis; values are printed with %g when
+	// it is nil.
+	FormatValue func(float64) string
+}
+
+// Render writes the chart as an <svg> element. A chart without points
+// writes nothing.
+func (c LineChart) Render(w io.Writer) error {
+	if len(c.Points) == 0 {
+		return nil
+	}
+	width, height := c.Width, c.Height
+	if width <= 0 {
+		width = defaultWidth
+	}
+	if height <= 0 {
+		height = defaultHeight
+	}
+	format := c.FormatValue
+	if format == nil {
+		format = func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	}
+
+	low, high := c.Points[0].Value, c.Points[0].Value
+	for _, point := range c.Points {
+		low, high = math.Min(low, point.Value), math.Max(high, point.Value)
+	}
+	first, last := c.Points[0].At, c.Points[len(c.Points)-1].At
+	span := last.Sub(first).Seconds()
+	left, right := float64(valueWidth), float64(width-barGap)
+	top, bottom := float64(barGap), float64(height-axisPadding)
+
+	x := func(at time.Time) float64 {
+		if span <= 0 {
+			return (left + right) / 2
+		}
+		return left + (right-left)*at.Sub(first).Seconds()/span
+	}
+	y := func(value float64) float64 {
+		if high == low {
+			return (top + bottom) / 2
+		}
+		return bottom - (bottom-top)*(value-low)/(high-low)
+	}
+
+	var buf bytes.Buffer
+	openSVG(&buf, width, height, c.Title)
+	fmt.Fprintf(&buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="currentColor" opacity="0.3"></line>`,
+		left, bottom, right, bottom)
+	fmt.Fprintf(&buf, `<text x="%.1f" y="%.1f" font-size="11" fill="currentColor" text-anchor="end" dominant-baseline="middle">%s</text>`,
+		left-barGap, y(high), html.EscapeString(format(high)))
+	if high != low {
+		fmt.Fprintf(&buf, `<text x="%.1f" y="%.1f" font-size="11" fill="currentColor" text-anchor="end" dominant-baseline="middle">%s</text>`,
+			left-barGap, y(low), html.EscapeString(format(low)))
+	}
+	fmt.Fprintf(&buf, `<text x="%.1f" y="%d" font-size="11" fill="currentColor">%s</text>`,
+		left, height-barGap, first.Format("02 Jan 2006"))
+	if span > 0 {
+		fmt.Fprintf(&buf, `<text x="%.1f" y="%d" font-size="11" fill="currentColor" text-anchor="end">%s</text>`,
+			right, height-barGap, last.Format("02 Jan 2006"))
+	}
+
+	buf.WriteString(`<polyline fill="none" stroke="` + Accent + `" stroke-width="2" points="`)
+	for i, point := range c.Points {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%.1f,%.1f", x(point.At), y(point.Value))
+	}
+	buf.WriteString(`"></polyline>`)
+	for _, point := range c.Points {
+		fmt.Fprintf(&buf, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s · %s</title></circle>`,
+			x(point.At), y(point.Value), Accent, point.At.Format("02 Jan 2006"), html.EscapeString(format(point.Value)))
+	}
+	buf.WriteString(`</svg>`)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func openSVG(buf *bytes.Buffer, width, height int, title string) {
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" role="img" aria-label="%s">`,
+		width, height, html.EscapeString(title))
+	if title != "" {
+		fmt.Fprintf(buf, `<title>%s</title>`, html.EscapeString(title))
+	}
+}
@@ -0,0 +1,65 @@
+package charts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBarChartScalesToLargestValue(t *testing.T) {
+	chart := BarChart{
+		Title: "Pyramid",
+		Bars: []Bar{
+			{Label: "Base", Value: 60, Note: "60%"},
+			{Label: "Top & heart", Value: 30},
+			{Label: "Unknown", Value: -1},
+		},
+	}
+	var buf bytes.Buffer
+	if err := chart.Render(&buf); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	svg := buf.String()
+	track := float64(defaultWidth - labelWidth - valueWidth)
+	for _, want := range []string{
+		`<title>Pyramid</title>`,
+		fmt.Sprintf(`width="%.1f" height="14" fill="#38bdf8"`, track),
+		fmt.Sprintf(`width="%.1f" height="14" fill="#38bdf8"`, track/2),
+		`width="0.0" height="14" fill="#38bdf8"`,
+		`Top &amp; heart`,
+		`>60%<`,
+		`>30<`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Fatalf("expected %q in %s", want, svg)
+		}
+	}
+}
+
+func TestLineChartLabelsRangeAndDates(t *testing.T) {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	chart := LineChart{
+		Points: []Point{
+			{At: start, Value: 0.5},
+			{At: start.AddDate(0, 3, 0), Value: 0.8},
+		},
+		FormatValue: func(v float64) string { return fmt.Sprintf("€%.2f", v) },
+	}
+	var buf bytes.Buffer
+	if err := chart.Render(&buf); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	svg := buf.String()
+	for _, want := range []string{"05 Jan 2026", "05 Apr 2026", ">€0.80<", ">€0.50<", "<polyline"} {
+		if !strings.Contains(svg, want) {
+			t.Fatalf("expected %q in %s", want, svg)
+		}
+	}
+
+	buf.Reset()
+	if err := (LineChart{}).Render(&buf); err != nil || buf.Len() != 0 {
+		t.Fatalf("expected an empty chart to write nothing, got %q (%v)", buf.String(), err)
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
@@ -13,16 +15,65 @@ import (
 
 // Config captures the runtime configuration for the application.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Logging  LoggingConfig
-	Auth     AuthConfig
-	AI       AIConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Logging    LoggingConfig
+	Auth       AuthConfig
+	AI         AIConfig
+	Library    LibraryConfig
+	Jobs       JobsConfig
+	Telemetry  TelemetryConfig
+	Mail       MailConfig
+	Onboarding OnboardingConfig
+	Storage    StorageConfig
+	Quotas     QuotaConfig
+	Labels     LabelsConfig
+	Retention  RetentionConfig
 }
 
 // ServerConfig configures the HTTP server runtime behavior.
 type ServerConfig struct {
-	Addr string
+	Addr            string
+	MaintenanceMode bool
+	Timeouts        HandlerTimeouts
+	Probes          ProbeConfig
+	Gallery         GalleryConfig
+	// BasePath is the prefix perfugo is served under, such as "/perfugo",
+	// or empty when it is served from the root of its host.
+	BasePath string
+	// TrustedProxies are the networks of the reverse proxies whose
+	// X-Forwarded-For, -Proto and -Host headers are believed.
+	TrustedProxies []netip.Prefix
+}
+
+// GalleryConfig throttles the public gallery API per client address.
+type GalleryConfig struct {
+	// PerMinute is the steady number of requests a client may make a minute.
+	PerMinute int
+	// Burst is how many requests a client may make at once.
+	Burst int
+}
+
+// ProbeConfig controls who may reach the probe endpoints.
+type ProbeConfig struct {
+	// PublicPaths are reachable without authentication; the other probes
+	// need Token as a Bearer token or an administrator session.
+	PublicPaths []string
+	// AllowedNetworks restricts every probe to these CIDR ranges; empty
+	// allows any address.
+	AllowedNetworks []netip.Prefix
+	Token           string
+}
+
+// HandlerTimeouts bounds how long slow handlers may work before answering
+// 503 Service Unavailable. A zero duration leaves the request unbounded.
+type HandlerTimeouts struct {
+	// AI covers a model call and saving its result.
+	AI time.Duration
+	// Report covers expanding formulas into batch reports and shopping lists.
+	Report time.Duration
+	// Import covers file imports and on-demand scheduled import runs.
+	Import time.Duration
 }
 
 // DatabaseConfig contains the database connection settings.
@@ -33,6 +84,12 @@ type DatabaseConfig struct {
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
 	UseMock         bool
+	// Logger selects where SQL logs go: "applog" (the application log,
+	// with request IDs), "gorm" (GORM's own stdout logger) or "silent".
+	Logger string
+	// SlowQueryThreshold is how long a statement may take before it is
+	// logged as slow.
+	SlowQueryThreshold time.Duration
 }
 
 // LoggingConfig controls application logging behavior.
@@ -43,6 +100,102 @@ type LoggingConfig struct {
 // AuthConfig controls authentication and session behavior for the application.
 type AuthConfig struct {
 	Session SessionConfig
+	// Backend selects where login credentials are verified: "local" checks
+	// the stored password hash and "ldap" binds against the directory.
+	Backend string
+	// InviteOnly disables open signup; new accounts then need an
+	// administrator-issued invitation.
+	InviteOnly bool
+	LDAP       LDAPConfig
+	Captcha    CaptchaConfig
+	OIDC       OIDCConfig
+	// SCIMToken is the bearer token identity providers use for SCIM
+	// provisioning. The endpoint is disabled when empty.
+	SCIMToken string
+}
+
+// CaptchaConfig enables hCaptcha or Cloudflare Turnstile challenges on the
+// login and signup forms. Verification is disabled while Provider is empty.
+type CaptchaConfig struct {
+	Provider  string
+	SiteKey   string
+	SecretKey string
+}
+
+// OnboardingConfig controls seeding of new accounts with a starter library.
+// TemplateFile replaces the built-in template with a JSON file.
+type OnboardingConfig struct {
+	SeedEnabled  bool
+	TemplateFile string
+}
+
+// MailConfig configures the SMTP relay for transactional email. Outgoing mail
+// is disabled while Host is empty.
+type MailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// StorageConfig selects where uploaded files such as avatars are kept.
+// Uploads are disabled while Dir is empty.
+type StorageConfig struct {
+	Dir string
+}
+
+// LabelsConfig names the network label printer batch labels can be sent
+// to. Labels can still be downloaded while PrinterAddr is empty.
+type LabelsConfig struct {
+	PrinterAddr     string
+	PrinterLanguage string
+}
+
+// RetentionConfig says how many days records are kept before the retention
+// job purges them for good. Zero keeps them forever.
+type RetentionConfig struct {
+	AuditDays      int
+	AIExchangeDays int
+	// DeletedDays counts from when a record was soft-deleted.
+	DeletedDays int
+}
+
+// QuotaConfig caps what a single user may create, for hosted instances
+// with a free tier. A zero limit is not enforced.
+type QuotaConfig struct {
+	MaxFormulas    int
+	MaxIngredients int
+	// MaxStorageMB caps the uploaded files, such as avatars, a user keeps.
+	MaxStorageMB int
+}
+
+// LDAPConfig configures the LDAP / Active Directory credentials backend.
+type LDAPConfig struct {
+	URL            string
+	BindDN         string
+	BindPassword   string
+	BaseDN         string
+	UserFilter     string
+	EmailAttribute string
+	NameAttribute  string
+	Timeout        time.Duration
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect provider.
+// SSO is disabled when IssuerURL is empty.
+type OIDCConfig struct {
+	IssuerURL             string
+	ClientID              string
+	ClientSecret          string
+	RedirectURL           string
+	PostLogoutRedirectURL string
+	Scopes                []string
+	RolesClaim            string
+	RoleMapping           map[string]string
+	// LinkByEmail lets a first SSO login take over an existing member
+	// account with the same verified email. Admin accounts are never linked.
+	LinkByEmail bool
 }
 
 // AIConfig controls OpenAI integration behaviour.
@@ -51,14 +204,67 @@ type AIConfig struct {
 	Model          string
 	BaseURL        string
 	RequestTimeout time.Duration
+	UseMock        bool
+	// DebugLog stores a redacted copy of each model request and response,
+	// capping every field at DebugLogMaxBytes.
+	DebugLog         bool
+	DebugLogMaxBytes int
+	// StaleAfterMonths is how old an AI-generated ingredient profile may get
+	// before the stale profile report lists it.
+	StaleAfterMonths int
+	// DeprecatedModels lists models whose profiles are always reported as
+	// stale, such as those retired by the provider.
+	DeprecatedModels []string
+}
+
+// LibraryConfig controls how ingredient library data is interpreted.
+type LibraryConfig struct {
+	ScalesFile string
+	// ImportCollisions decides what imports do when a name or CAS number
+	// matches another user's public chemical: "duplicate" creates a private
+	// copy, "skip" leaves the row out and "alias" links the imported names
+	// to the public record.
+	ImportCollisions string
 }
 
-// SessionConfig configures HTTP session cookie behavior.
+// JobsConfig controls background job scheduling. A zero interval disables a job.
+type JobsConfig struct {
+	PopularityInterval time.Duration
+	// ImportInterval is how often scheduled imports are checked for due runs.
+	ImportInterval time.Duration
+	// ShelfLifeInterval is how often opened inventory is checked for
+	// materials nearing expiry.
+	ShelfLifeInterval time.Duration
+	// DigestInterval is how often opted-in users are checked for a weekly
+	// digest that has fallen due.
+	DigestInterval time.Duration
+	// RetentionInterval is how often expired records are purged.
+	RetentionInterval time.Duration
+	// ShutdownTimeout is how long running jobs get to stop when the server
+	// exits; zero waits indefinitely.
+	ShutdownTimeout time.Duration
+}
+
+// TelemetryConfig controls the anonymous usage reporter. It is disabled unless
+// explicitly enabled and given an endpoint.
+type TelemetryConfig struct {
+	Enabled    bool
+	Endpoint   string
+	InstanceID string
+	Interval   time.Duration
+}
+
+// SessionConfig configures HTTP session cookie behavior. CookieSameSite
+// None and CookiePartitioned, needed when perfugo is embedded in another
+// site, both require CookieSecure.
 type SessionConfig struct {
-	Lifetime     time.Duration
-	CookieName   string
-	CookieDomain string
-	CookieSecure bool
+	Lifetime          time.Duration
+	CookieName        string
+	CookieDomain      string
+	CookiePath        string
+	CookieSecure      bool
+	CookieSameSite    http.SameSite
+	CookiePartitioned bool
 }
 
 // Load inspects the environment and builds a Config value.
@@ -72,9 +278,46 @@ func Load() (Config, error) {
 			os.Getenv("ADDR"),
 			":8080",
 		),
+		BasePath:        parseBasePath(os.Getenv("SERVER_BASE_PATH")),
+		MaintenanceMode: parseBoolWithDefault(os.Getenv("MAINTENANCE_MODE"), false),
+		Timeouts: HandlerTimeouts{
+			AI:     parseDurationWithDefault(os.Getenv("SERVER_AI_TIMEOUT"), 2*time.Minute),
+			Report: parseDurationWithDefault(os.Getenv("SERVER_REPORT_TIMEOUT"), 30*time.Second),
+			Import: parseDurationWithDefault(os.Getenv("SERVER_IMPORT_TIMEOUT"), 2*time.Minute),
+		},
+	}
+
+	probeNetworks, err := parsePrefixes(os.Getenv("PROBE_ALLOWED_CIDRS"))
+	if err != nil {
+		return Config{}, fmt.Errorf("PROBE_ALLOWED_CIDRS: %w", err)
+	}
+	cfg.Server.Probes = ProbeConfig{
+		PublicPaths:     parseList(firstNonEmpty(os.Getenv("PROBE_PUBLIC_PATHS"), "/healthz,/readyz")),
+		AllowedNetworks: probeNetworks,
+		Token:           strings.TrimSpace(os.Getenv("PROBE_TOKEN")),
+	}
+	trustedProxies, err := parsePrefixes(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	cfg.Server.TrustedProxies = trustedProxies
+	cfg.Server.Gallery = GalleryConfig{
+		PerMinute: parseIntWithDefault(os.Getenv("GALLERY_RATE_LIMIT"), 30),
+		Burst:     parseIntWithDefault(os.Getenv("GALLERY_RATE_BURST"), 10),
 	}
 
-	applog.Debug(context.Background(), "server configuration resolved", "addr", cfg.Server.Addr)
+	applog.Debug(context.Background(), "server configuration resolved",
+		"addr", cfg.Server.Addr,
+		"basePath", cfg.Server.BasePath,
+		"maintenanceMode", cfg.Server.MaintenanceMode,
+		"aiTimeout", cfg.Server.Timeouts.AI.String(),
+		"reportTimeout", cfg.Server.Timeouts.Report.String(),
+		"importTimeout", cfg.Server.Timeouts.Import.String(),
+		"probePublicPaths", strings.Join(cfg.Server.Probes.PublicPaths, ","),
+		"probeNetworks", len(cfg.Server.Probes.AllowedNetworks),
+		"galleryRateLimit", cfg.Server.Gallery.PerMinute,
+		"trustedProxies", len(cfg.Server.TrustedProxies),
+	)
 
 	cfg.Database = DatabaseConfig{
 		URL: firstNonEmpty(
@@ -82,11 +325,13 @@ func Load() (Config, error) {
 			os.Getenv("DB_URL"),
 			"",
 		),
-		MaxIdleConns:    parseIntWithDefault(os.Getenv("DATABASE_MAX_IDLE_CONNS"), 5),
-		MaxOpenConns:    parseIntWithDefault(os.Getenv("DATABASE_MAX_OPEN_CONNS"), 25),
-		ConnMaxLifetime: parseDurationWithDefault(os.Getenv("DATABASE_CONN_MAX_LIFETIME"), 30*time.Minute),
-		ConnMaxIdleTime: parseDurationWithDefault(os.Getenv("DATABASE_CONN_MAX_IDLE_TIME"), 5*time.Minute),
-		UseMock:         parseBoolWithDefault(os.Getenv("DATABASE_USE_MOCK"), false),
+		MaxIdleConns:       parseIntWithDefault(os.Getenv("DATABASE_MAX_IDLE_CONNS"), 5),
+		MaxOpenConns:       parseIntWithDefault(os.Getenv("DATABASE_MAX_OPEN_CONNS"), 25),
+		ConnMaxLifetime:    parseDurationWithDefault(os.Getenv("DATABASE_CONN_MAX_LIFETIME"), 30*time.Minute),
+		ConnMaxIdleTime:    parseDurationWithDefault(os.Getenv("DATABASE_CONN_MAX_IDLE_TIME"), 5*time.Minute),
+		UseMock:            parseBoolWithDefault(os.Getenv("DATABASE_USE_MOCK"), false),
+		Logger:             strings.ToLower(strings.TrimSpace(firstNonEmpty(os.Getenv("DATABASE_LOGGER"), "applog"))),
+		SlowQueryThreshold: parseDurationWithDefault(os.Getenv("DATABASE_SLOW_QUERY_THRESHOLD"), 200*time.Millisecond),
 	}
 
 	applog.Debug(context.Background(), "database configuration resolved",
@@ -94,6 +339,8 @@ func Load() (Config, error) {
 		"maxIdleConns", cfg.Database.MaxIdleConns,
 		"maxOpenConns", cfg.Database.MaxOpenConns,
 		"useMock", cfg.Database.UseMock,
+		"logger", cfg.Database.Logger,
+		"slowQueryThreshold", cfg.Database.SlowQueryThreshold.String(),
 	)
 
 	cfg.Logging = LoggingConfig{
@@ -105,27 +352,90 @@ func Load() (Config, error) {
 
 	applog.Debug(context.Background(), "logging configuration resolved", "level", cfg.Logging.Level)
 
+	sameSite, err := parseSameSite(os.Getenv("SESSION_COOKIE_SAMESITE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("SESSION_COOKIE_SAMESITE: %w", err)
+	}
 	cfg.Auth = AuthConfig{
 		Session: SessionConfig{
-			Lifetime:     parseDurationWithDefault(os.Getenv("SESSION_LIFETIME"), 12*time.Hour),
-			CookieName:   firstNonEmpty(os.Getenv("SESSION_COOKIE_NAME"), "perfugo_session"),
-			CookieDomain: os.Getenv("SESSION_COOKIE_DOMAIN"),
-			CookieSecure: parseBoolWithDefault(os.Getenv("SESSION_COOKIE_SECURE"), true),
+			Lifetime:          parseDurationWithDefault(os.Getenv("SESSION_LIFETIME"), 12*time.Hour),
+			CookieName:        firstNonEmpty(os.Getenv("SESSION_COOKIE_NAME"), "perfugo_session"),
+			CookieDomain:      os.Getenv("SESSION_COOKIE_DOMAIN"),
+			CookiePath:        strings.TrimSpace(firstNonEmpty(os.Getenv("SESSION_COOKIE_PATH"), cfg.Server.BasePath, "/")),
+			CookieSecure:      parseBoolWithDefault(os.Getenv("SESSION_COOKIE_SECURE"), true),
+			CookieSameSite:    sameSite,
+			CookiePartitioned: parseBoolWithDefault(os.Getenv("SESSION_COOKIE_PARTITIONED"), false),
+		},
+		Backend:    strings.ToLower(firstNonEmpty(os.Getenv("AUTH_BACKEND"), "local")),
+		InviteOnly: parseBoolWithDefault(os.Getenv("SIGNUP_INVITE_ONLY"), false),
+		Captcha: CaptchaConfig{
+			Provider:  strings.ToLower(strings.TrimSpace(os.Getenv("CAPTCHA_PROVIDER"))),
+			SiteKey:   strings.TrimSpace(os.Getenv("CAPTCHA_SITE_KEY")),
+			SecretKey: strings.TrimSpace(os.Getenv("CAPTCHA_SECRET_KEY")),
+		},
+		LDAP: LDAPConfig{
+			URL:            strings.TrimSpace(os.Getenv("LDAP_URL")),
+			BindDN:         strings.TrimSpace(os.Getenv("LDAP_BIND_DN")),
+			BindPassword:   os.Getenv("LDAP_BIND_PASSWORD"),
+			BaseDN:         strings.TrimSpace(os.Getenv("LDAP_BASE_DN")),
+			UserFilter:     firstNonEmpty(os.Getenv("LDAP_USER_FILTER"), "(mail=%s)"),
+			EmailAttribute: firstNonEmpty(os.Getenv("LDAP_EMAIL_ATTRIBUTE"), "mail"),
+			NameAttribute:  firstNonEmpty(os.Getenv("LDAP_NAME_ATTRIBUTE"), "displayName"),
+			Timeout:        parseDurationWithDefault(os.Getenv("LDAP_TIMEOUT"), 10*time.Second),
 		},
+		OIDC: OIDCConfig{
+			IssuerURL:             strings.TrimSpace(os.Getenv("OIDC_ISSUER_URL")),
+			ClientID:              strings.TrimSpace(os.Getenv("OIDC_CLIENT_ID")),
+			ClientSecret:          os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:           strings.TrimSpace(os.Getenv("OIDC_REDIRECT_URL")),
+			PostLogoutRedirectURL: strings.TrimSpace(os.Getenv("OIDC_POST_LOGOUT_REDIRECT_URL")),
+			Scopes:                strings.Fields(os.Getenv("OIDC_SCOPES")),
+			RolesClaim:            firstNonEmpty(os.Getenv("OIDC_ROLES_CLAIM"), "groups"),
+			RoleMapping:           parseMapping(os.Getenv("OIDC_ROLE_MAPPING")),
+			LinkByEmail:           parseBoolWithDefault(os.Getenv("OIDC_LINK_BY_EMAIL"), false),
+		},
+		SCIMToken: strings.TrimSpace(os.Getenv("SCIM_BEARER_TOKEN")),
 	}
 
 	applog.Debug(context.Background(), "session configuration resolved",
 		"lifetime", cfg.Auth.Session.Lifetime.String(),
 		"cookieName", cfg.Auth.Session.CookieName,
 		"cookieDomainSet", strings.TrimSpace(cfg.Auth.Session.CookieDomain) != "",
+		"cookiePath", cfg.Auth.Session.CookiePath,
 		"cookieSecure", cfg.Auth.Session.CookieSecure,
+		"cookieSameSite", os.Getenv("SESSION_COOKIE_SAMESITE"),
+		"cookiePartitioned", cfg.Auth.Session.CookiePartitioned,
+	)
+
+	applog.Debug(context.Background(), "credentials backend resolved",
+		"backend", cfg.Auth.Backend,
+		"inviteOnly", cfg.Auth.InviteOnly,
+		"captcha", cfg.Auth.Captcha.Provider,
+		"ldapURL", cfg.Auth.LDAP.URL,
+		"ldapBaseDN", cfg.Auth.LDAP.BaseDN,
+		"ldapServiceBind", cfg.Auth.LDAP.BindDN != "",
+	)
+
+	applog.Debug(context.Background(), "oidc configuration resolved",
+		"issuer", cfg.Auth.OIDC.IssuerURL,
+		"clientIDSet", cfg.Auth.OIDC.ClientID != "",
+		"redirectURL", cfg.Auth.OIDC.RedirectURL,
+		"rolesClaim", cfg.Auth.OIDC.RolesClaim,
+		"roleMappings", len(cfg.Auth.OIDC.RoleMapping),
+		"linkByEmail", cfg.Auth.OIDC.LinkByEmail,
+		"scimEnabled", cfg.Auth.SCIMToken != "",
 	)
 
 	cfg.AI = AIConfig{
-		APIKey:         strings.TrimSpace(os.Getenv("OPENAI_API_KEY")),
-		Model:          firstNonEmpty(os.Getenv("OPENAI_MODEL"), defaultAIModel()),
-		BaseURL:        strings.TrimSpace(os.Getenv("OPENAI_BASE_URL")),
-		RequestTimeout: parseDurationWithDefault(os.Getenv("OPENAI_TIMEOUT"), 90*time.Second),
+		APIKey:           strings.TrimSpace(os.Getenv("OPENAI_API_KEY")),
+		Model:            firstNonEmpty(os.Getenv("OPENAI_MODEL"), defaultAIModel()),
+		BaseURL:          strings.TrimSpace(os.Getenv("OPENAI_BASE_URL")),
+		RequestTimeout:   parseDurationWithDefault(os.Getenv("OPENAI_TIMEOUT"), 90*time.Second),
+		UseMock:          parseBoolWithDefault(os.Getenv("AI_USE_MOCK"), false),
+		DebugLog:         parseBoolWithDefault(os.Getenv("AI_DEBUG_LOG"), false),
+		DebugLogMaxBytes: parseIntWithDefault(os.Getenv("AI_DEBUG_LOG_MAX_BYTES"), 32<<10),
+		StaleAfterMonths: max(parseIntWithDefault(os.Getenv("AI_PROFILE_STALE_MONTHS"), 12), 1),
+		DeprecatedModels: parseList(os.Getenv("AI_DEPRECATED_MODELS")),
 	}
 
 	applog.Debug(context.Background(), "ai configuration resolved",
@@ -133,12 +443,153 @@ func Load() (Config, error) {
 		"model", cfg.AI.Model,
 		"baseURL", cfg.AI.BaseURL,
 		"timeout", cfg.AI.RequestTimeout.String(),
+		"useMock", cfg.AI.UseMock,
+		"debugLog", cfg.AI.DebugLog,
+	)
+
+	cfg.Library = LibraryConfig{
+		ScalesFile:       strings.TrimSpace(os.Getenv("LIBRARY_SCALES_FILE")),
+		ImportCollisions: strings.ToLower(strings.TrimSpace(firstNonEmpty(os.Getenv("LIBRARY_IMPORT_COLLISIONS"), "duplicate"))),
+	}
+
+	applog.Debug(context.Background(), "library configuration resolved",
+		"scalesFile", cfg.Library.ScalesFile,
+		"importCollisions", cfg.Library.ImportCollisions,
+	)
+
+	cfg.Jobs = JobsConfig{
+		PopularityInterval: parseDurationWithDefault(os.Getenv("JOBS_POPULARITY_INTERVAL"), time.Hour),
+		ImportInterval:     parseDurationWithDefault(os.Getenv("JOBS_IMPORT_INTERVAL"), time.Minute),
+		ShelfLifeInterval:  parseDurationWithDefault(os.Getenv("JOBS_SHELF_LIFE_INTERVAL"), 6*time.Hour),
+		DigestInterval:     parseDurationWithDefault(os.Getenv("JOBS_DIGEST_INTERVAL"), time.Hour),
+		RetentionInterval:  parseDurationWithDefault(os.Getenv("JOBS_RETENTION_INTERVAL"), 24*time.Hour),
+		ShutdownTimeout:    parseDurationWithDefault(os.Getenv("JOBS_SHUTDOWN_TIMEOUT"), 30*time.Second),
+	}
+
+	applog.Debug(context.Background(), "jobs configuration resolved",
+		"popularityInterval", cfg.Jobs.PopularityInterval.String(),
+		"importInterval", cfg.Jobs.ImportInterval.String(),
+		"shelfLifeInterval", cfg.Jobs.ShelfLifeInterval.String(),
+		"digestInterval", cfg.Jobs.DigestInterval.String(),
+		"retentionInterval", cfg.Jobs.RetentionInterval.String(),
+		"shutdownTimeout", cfg.Jobs.ShutdownTimeout.String(),
+	)
+
+	cfg.Telemetry = TelemetryConfig{
+		Enabled:    parseBoolWithDefault(os.Getenv("TELEMETRY_ENABLED"), false),
+		Endpoint:   strings.TrimSpace(os.Getenv("TELEMETRY_ENDPOINT")),
+		InstanceID: strings.TrimSpace(os.Getenv("TELEMETRY_INSTANCE_ID")),
+		Interval:   parseDurationWithDefault(os.Getenv("TELEMETRY_INTERVAL"), 24*time.Hour),
+	}
+
+	applog.Debug(context.Background(), "telemetry configuration resolved",
+		"enabled", cfg.Telemetry.Enabled,
+		"endpointSet", cfg.Telemetry.Endpoint != "",
+		"interval", cfg.Telemetry.Interval.String(),
+	)
+
+	cfg.Mail = MailConfig{
+		Host:     strings.TrimSpace(os.Getenv("SMTP_HOST")),
+		Port:     parseIntWithDefault(os.Getenv("SMTP_PORT"), 587),
+		Username: strings.TrimSpace(os.Getenv("SMTP_USERNAME")),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     strings.TrimSpace(os.Getenv("SMTP_FROM")),
+	}
+
+	applog.Debug(context.Background(), "mail configuration resolved",
+		"enabled", cfg.Mail.Host != "",
+		"host", cfg.Mail.Host,
+		"port", cfg.Mail.Port,
+		"from", cfg.Mail.From,
+	)
+
+	cfg.Storage = StorageConfig{
+		Dir: strings.TrimSpace(os.Getenv("STORAGE_DIR")),
+	}
+
+	applog.Debug(context.Background(), "storage configuration resolved",
+		"enabled", cfg.Storage.Dir != "",
+		"dir", cfg.Storage.Dir,
+	)
+
+	cfg.Labels = LabelsConfig{
+		PrinterAddr:     strings.TrimSpace(os.Getenv("LABEL_PRINTER_ADDR")),
+		PrinterLanguage: strings.ToLower(strings.TrimSpace(firstNonEmpty(os.Getenv("LABEL_PRINTER_LANGUAGE"), "zpl"))),
+	}
+
+	applog.Debug(context.Background(), "label printer configuration resolved",
+		"enabled", cfg.Labels.PrinterAddr != "",
+		"addr", cfg.Labels.PrinterAddr,
+		"language", cfg.Labels.PrinterLanguage,
+	)
+
+	cfg.Quotas = QuotaConfig{
+		MaxFormulas:    max(parseIntWithDefault(os.Getenv("QUOTA_MAX_FORMULAS"), 0), 0),
+		MaxIngredients: max(parseIntWithDefault(os.Getenv("QUOTA_MAX_INGREDIENTS"), 0), 0),
+		MaxStorageMB:   max(parseIntWithDefault(os.Getenv("QUOTA_MAX_STORAGE_MB"), 0), 0),
+	}
+
+	applog.Debug(context.Background(), "quota configuration resolved",
+		"maxFormulas", cfg.Quotas.MaxFormulas,
+		"maxIngredients", cfg.Quotas.MaxIngredients,
+		"maxStorageMB", cfg.Quotas.MaxStorageMB,
+	)
+
+	cfg.Retention = RetentionConfig{
+		AuditDays:      max(parseIntWithDefault(os.Getenv("RETENTION_AUDIT_DAYS"), 0), 0),
+		AIExchangeDays: max(parseIntWithDefault(os.Getenv("RETENTION_AI_EXCHANGE_DAYS"), 0), 0),
+		DeletedDays:    max(parseIntWithDefault(os.Getenv("RETENTION_DELETED_DAYS"), 0), 0),
+	}
+
+	applog.Debug(context.Background(), "retention configuration resolved",
+		"auditDays", cfg.Retention.AuditDays,
+		"aiExchangeDays", cfg.Retention.AIExchangeDays,
+		"deletedDays", cfg.Retention.DeletedDays,
+	)
+
+	cfg.Onboarding = OnboardingConfig{
+		SeedEnabled:  parseBoolWithDefault(os.Getenv("ONBOARDING_SEED_ENABLED"), false),
+		TemplateFile: strings.TrimSpace(os.Getenv("ONBOARDING_TEMPLATE_FILE")),
+	}
+
+	applog.Debug(context.Background(), "onboarding configuration resolved",
+		"seedEnabled", cfg.Onboarding.SeedEnabled,
+		"templateFile", cfg.Onboarding.TemplateFile,
 	)
 
 	if strings.TrimSpace(cfg.Server.Addr) == "" {
 		return Config{}, fmt.Errorf("server address must not be empty")
 	}
 
+	if !strings.HasPrefix(cfg.Auth.Session.CookiePath, "/") {
+		return Config{}, fmt.Errorf("SESSION_COOKIE_PATH must start with /")
+	}
+	if (cfg.Auth.Session.CookieSameSite == http.SameSiteNoneMode || cfg.Auth.Session.CookiePartitioned) && !cfg.Auth.Session.CookieSecure {
+		return Config{}, fmt.Errorf("SESSION_COOKIE_SAMESITE=none and SESSION_COOKIE_PARTITIONED require SESSION_COOKIE_SECURE")
+	}
+
+	switch cfg.Auth.Backend {
+	case "local":
+	case "ldap":
+		if cfg.Auth.LDAP.URL == "" || cfg.Auth.LDAP.BaseDN == "" {
+			return Config{}, fmt.Errorf("LDAP_URL and LDAP_BASE_DN are required when AUTH_BACKEND=ldap")
+		}
+	default:
+		return Config{}, fmt.Errorf("unsupported AUTH_BACKEND %q", cfg.Auth.Backend)
+	}
+
+	switch cfg.Database.Logger {
+	case "applog", "gorm", "silent":
+	default:
+		return Config{}, fmt.Errorf("unsupported DATABASE_LOGGER %q", cfg.Database.Logger)
+	}
+
+	switch cfg.Library.ImportCollisions {
+	case "duplicate", "skip", "alias":
+	default:
+		return Config{}, fmt.Errorf("unsupported LIBRARY_IMPORT_COLLISIONS %q", cfg.Library.ImportCollisions)
+	}
+
 	applog.Debug(context.Background(), "configuration load complete")
 
 	return cfg, nil
@@ -189,3 +640,74 @@ func parseBoolWithDefault(value string, def bool) bool {
 	}
 	return parsed
 }
+
+// parseList reads a comma-separated list, dropping blank entries.
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parsePrefixes reads a comma-separated list of CIDR ranges. A bare address
+// is a single-host range.
+func parsePrefixes(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range parseList(value) {
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// parseBasePath reads the prefix perfugo is served under as "/perfugo",
+// whatever slashes surround it. The root is the empty string.
+func parseBasePath(value string) string {
+	value = strings.Trim(strings.TrimSpace(value), "/")
+	if value == "" {
+		return ""
+	}
+	return "/" + value
+}
+
+// parseSameSite reads a SameSite mode: lax (the default), strict or none.
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unsupported mode %q", value)
+	}
+}
+
+// parseMapping reads comma-separated key=value pairs, ignoring malformed entries.
+func parseMapping(value string) map[string]string {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		key, mapped, ok := strings.Cut(pair, "=")
+		key, mapped = strings.TrimSpace(key), strings.TrimSpace(mapped)
+		if !ok || key == "" || mapped == "" {
+			continue
+		}
+		mapping[key] = mapped
+	}
+	return mapping
+}
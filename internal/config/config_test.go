@@ -1,6 +1,8 @@
 package config
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
@@ -104,6 +106,36 @@ func TestParseBoolWithDefault(t *testing.T) {
 	}
 }
 
+func TestParseMapping(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value string
+		want  map[string]string
+	}{
+		{"empty", "", map[string]string{}},
+		{"pairs", "admins=admin, staff = member", map[string]string{"admins": "admin", "staff": "member"}},
+		{"skips malformed", "admins,=admin,staff=", map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := parseMapping(tt.value)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseMapping(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Fatalf("parseMapping(%q)[%q] = %q, want %q", tt.value, key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
 func TestLoadUsesEnvironmentDefaults(t *testing.T) {
 	t.Setenv("SERVER_ADDR", "")
 	t.Setenv("ADDR", "")
@@ -118,6 +150,18 @@ func TestLoadUsesEnvironmentDefaults(t *testing.T) {
 	t.Setenv("SESSION_COOKIE_NAME", "custom_session")
 	t.Setenv("SESSION_COOKIE_DOMAIN", "example.com")
 	t.Setenv("SESSION_COOKIE_SECURE", "false")
+	t.Setenv("AI_USE_MOCK", "true")
+	t.Setenv("TELEMETRY_ENABLED", "")
+	t.Setenv("AUTH_BACKEND", "")
+	t.Setenv("SERVER_AI_TIMEOUT", "")
+	t.Setenv("SERVER_REPORT_TIMEOUT", "45s")
+	t.Setenv("QUOTA_MAX_FORMULAS", "25")
+	t.Setenv("QUOTA_MAX_INGREDIENTS", "")
+	t.Setenv("QUOTA_MAX_STORAGE_MB", "-3")
+	t.Setenv("LIBRARY_IMPORT_COLLISIONS", " Alias ")
+	t.Setenv("RETENTION_AUDIT_DAYS", "730")
+	t.Setenv("RETENTION_AI_EXCHANGE_DAYS", "-1")
+	t.Setenv("RETENTION_DELETED_DAYS", "")
 
 	cfg, err := Load()
 	if err != nil {
@@ -127,6 +171,9 @@ func TestLoadUsesEnvironmentDefaults(t *testing.T) {
 	if cfg.Server.Addr != ":8080" {
 		t.Fatalf("Server.Addr = %q, want %q", cfg.Server.Addr, ":8080")
 	}
+	if cfg.Server.Timeouts.AI != 2*time.Minute || cfg.Server.Timeouts.Report != 45*time.Second {
+		t.Fatalf("Server.Timeouts = %+v", cfg.Server.Timeouts)
+	}
 	if cfg.Database.URL != "postgres://example" {
 		t.Fatalf("Database.URL = %q", cfg.Database.URL)
 	}
@@ -160,6 +207,62 @@ func TestLoadUsesEnvironmentDefaults(t *testing.T) {
 	if cfg.Auth.Session.CookieSecure {
 		t.Fatalf("Auth.Session.CookieSecure = %t, want false", cfg.Auth.Session.CookieSecure)
 	}
+	if !cfg.AI.UseMock {
+		t.Fatalf("AI.UseMock = %t, want true", cfg.AI.UseMock)
+	}
+	if cfg.Telemetry.Enabled {
+		t.Fatalf("Telemetry.Enabled = %t, want false", cfg.Telemetry.Enabled)
+	}
+	if cfg.Auth.Backend != "local" {
+		t.Fatalf("Auth.Backend = %q, want %q", cfg.Auth.Backend, "local")
+	}
+	if cfg.Library.ImportCollisions != "alias" {
+		t.Fatalf("Library.ImportCollisions = %q, want %q", cfg.Library.ImportCollisions, "alias")
+	}
+	if cfg.Quotas != (QuotaConfig{MaxFormulas: 25}) {
+		t.Fatalf("Quotas = %+v, want only MaxFormulas 25", cfg.Quotas)
+	}
+	if cfg.Retention != (RetentionConfig{AuditDays: 730}) {
+		t.Fatalf("Retention = %+v, want only AuditDays 730", cfg.Retention)
+	}
+}
+
+func TestLoadValidatesAuthBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		ldapURL string
+		baseDN  string
+		wantErr bool
+	}{
+		{name: "local", backend: "local"},
+		{name: "ldap configured", backend: "LDAP", ldapURL: "ldaps://dir.example.com", baseDN: "dc=example,dc=com"},
+		{name: "ldap missing base dn", backend: "ldap", ldapURL: "ldaps://dir.example.com", wantErr: true},
+		{name: "unknown backend", backend: "kerberos", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SERVER_ADDR", ":8080")
+			t.Setenv("AUTH_BACKEND", tt.backend)
+			t.Setenv("LDAP_URL", tt.ldapURL)
+			t.Setenv("LDAP_BASE_DN", tt.baseDN)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Auth.Backend != strings.ToLower(tt.backend) {
+				t.Fatalf("Auth.Backend = %q", cfg.Auth.Backend)
+			}
+		})
+	}
 }
 
 func TestLoadPrefersServerAddr(t *testing.T) {
@@ -174,3 +277,132 @@ func TestLoadPrefersServerAddr(t *testing.T) {
 		t.Fatalf("Server.Addr = %q, want %q", cfg.Server.Addr, "127.0.0.1:9000")
 	}
 }
+
+func TestLoadProbeAccess(t *testing.T) {
+	t.Setenv("SERVER_ADDR", ":8080")
+	t.Setenv("PROBE_PUBLIC_PATHS", "/healthz")
+	t.Setenv("PROBE_ALLOWED_CIDRS", "10.0.0.0/8, 192.168.1.7")
+	t.Setenv("PROBE_TOKEN", "secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	probes := cfg.Server.Probes
+	if len(probes.PublicPaths) != 1 || probes.PublicPaths[0] != "/healthz" {
+		t.Fatalf("PublicPaths = %v", probes.PublicPaths)
+	}
+	if len(probes.AllowedNetworks) != 2 || probes.AllowedNetworks[1].String() != "192.168.1.7/32" {
+		t.Fatalf("AllowedNetworks = %v", probes.AllowedNetworks)
+	}
+	if probes.Token != "secret" {
+		t.Fatalf("Token = %q", probes.Token)
+	}
+
+	t.Setenv("PROBE_ALLOWED_CIDRS", "10.0.0.0/33")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an invalid network to be rejected")
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	t.Setenv("SERVER_ADDR", ":8080")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, ::1")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Server.TrustedProxies) != 2 || cfg.Server.TrustedProxies[1].String() != "::1/128" {
+		t.Fatalf("TrustedProxies = %v", cfg.Server.TrustedProxies)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "nginx")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an invalid proxy network to be rejected")
+	}
+}
+
+func TestLoadBasePath(t *testing.T) {
+	t.Setenv("SERVER_ADDR", ":8080")
+	t.Setenv("SERVER_BASE_PATH", " perfugo/ ")
+	t.Setenv("SESSION_COOKIE_PATH", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.BasePath != "/perfugo" {
+		t.Fatalf("Server.BasePath = %q, want /perfugo", cfg.Server.BasePath)
+	}
+	if cfg.Auth.Session.CookiePath != "/perfugo" {
+		t.Fatalf("Auth.Session.CookiePath = %q, want the base path", cfg.Auth.Session.CookiePath)
+	}
+
+	t.Setenv("SERVER_BASE_PATH", "/")
+	if cfg, err := Load(); err != nil || cfg.Server.BasePath != "" || cfg.Auth.Session.CookiePath != "/" {
+		t.Fatalf("root base path: %q, %q, %v", cfg.Server.BasePath, cfg.Auth.Session.CookiePath, err)
+	}
+}
+
+func TestLoadSessionCookieAttributes(t *testing.T) {
+	t.Setenv("SERVER_ADDR", ":8080")
+	t.Setenv("SESSION_COOKIE_SAMESITE", " None ")
+	t.Setenv("SESSION_COOKIE_PARTITIONED", "true")
+	t.Setenv("SESSION_COOKIE_PATH", "/perfugo")
+	t.Setenv("SESSION_COOKIE_SECURE", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	session := cfg.Auth.Session
+	if session.CookieSameSite != http.SameSiteNoneMode || !session.CookiePartitioned || session.CookiePath != "/perfugo" {
+		t.Fatalf("Session = %+v", session)
+	}
+
+	t.Setenv("SESSION_COOKIE_SECURE", "false")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected SameSite=None without Secure to be rejected")
+	}
+	t.Setenv("SESSION_COOKIE_SECURE", "")
+	t.Setenv("SESSION_COOKIE_SAMESITE", "relaxed")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an unknown SameSite mode to be rejected")
+	}
+}
+
+func TestLoadStaleProfilePolicy(t *testing.T) {
+	t.Setenv("SERVER_ADDR", ":8080")
+	t.Setenv("AI_PROFILE_STALE_MONTHS", "0")
+	t.Setenv("AI_DEPRECATED_MODELS", "gpt-3.5-turbo, ,gpt-4")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AI.StaleAfterMonths != 1 {
+		t.Fatalf("StaleAfterMonths = %d, want at least 1", cfg.AI.StaleAfterMonths)
+	}
+	if len(cfg.AI.DeprecatedModels) != 2 || cfg.AI.DeprecatedModels[1] != "gpt-4" {
+		t.Fatalf("DeprecatedModels = %v", cfg.AI.DeprecatedModels)
+	}
+}
+
+func TestLoadDatabaseLogger(t *testing.T) {
+	t.Setenv("DATABASE_LOGGER", "Silent")
+	t.Setenv("DATABASE_SLOW_QUERY_THRESHOLD", "1s")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Database.Logger != "silent" || cfg.Database.SlowQueryThreshold != time.Second {
+		t.Fatalf("Database = %q, %v", cfg.Database.Logger, cfg.Database.SlowQueryThreshold)
+	}
+
+	t.Setenv("DATABASE_LOGGER", "verbose")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an unknown database logger to be rejected")
+	}
+}
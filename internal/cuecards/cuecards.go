@@ -0,0 +1,132 @@
+// Package cuecards lays out printable ingredient reference cards as a PDF.
+// Cards are sized for an organ's card index and arranged eight to an A4 page
+// with dashed cut lines.
+package cuecards
+
+import (
+	"io"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Card dimensions and page layout, in millimetres.
+const (
+	CardWidth  = 90.0
+	CardHeight = 65.0
+	columns    = 2
+	rows       = 4
+	marginX    = 10.0
+	marginY    = 6.0
+	gap        = 10.0
+	padding    = 5.0
+	lineHeight = 4.0
+)
+
+// Card is the content of one cue card. Empty fields are left off.
+type Card struct {
+	Name     string
+	CAS      string
+	Pyramid  string
+	Strength string
+	Dilution string
+	Usage    string
+}
+
+// PerPage is the number of cards laid out on each page.
+const PerPage = columns * rows
+
+// Render writes the cards to w as a PDF, filling pages left to right and top
+// to bottom.
+func Render(w io.Writer, title string, cards []Card) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Perfugo", true)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, card := range cards {
+		slot := i % PerPage
+		if slot == 0 {
+			pdf.AddPage()
+		}
+		x := marginX + float64(slot%columns)*(CardWidth+gap)
+		y := marginY + float64(slot/columns)*(CardHeight+gap/2)
+		drawCard(pdf, tr, x, y, card)
+	}
+	if len(cards) == 0 {
+		pdf.AddPage()
+	}
+	return pdf.Output(w)
+}
+
+func drawCard(pdf *gofpdf.Fpdf, tr func(string) string, x, y float64, card Card) {
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.SetDashPattern([]float64{1.5, 1.5}, 0)
+	pdf.Rect(x, y, CardWidth, CardHeight, "D")
+	pdf.SetDashPattern(nil, 0)
+
+	width := CardWidth - 2*padding
+	bottom := y + CardHeight - padding
+	pdf.SetXY(x+padding, y+padding)
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.CellFormat(width, 6, fit(pdf, tr(card.Name), width), "", 2, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(90, 90, 90)
+	if card.CAS != "" {
+		pdf.CellFormat(width, lineHeight, tr("CAS "+card.CAS), "", 2, "L", false, 0, "")
+	}
+	pdf.Ln(1.5)
+
+	pdf.SetTextColor(0, 0, 0)
+	for _, field := range [][2]string{
+		{"Pyramid", card.Pyramid},
+		{"Strength", card.Strength},
+		{"Dilution", card.Dilution},
+	} {
+		if field[1] == "" {
+			continue
+		}
+		pdf.SetX(x + padding)
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.CellFormat(17, lineHeight, tr(field[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(width-17, lineHeight, fit(pdf, tr(field[1]), width-17), "", 2, "L", false, 0, "")
+	}
+
+	usage := strings.Join(strings.Fields(card.Usage), " ")
+	if usage == "" {
+		return
+	}
+	pdf.Ln(1.5)
+	pdf.SetFont("Helvetica", "I", 7.5)
+	lines := pdf.SplitLines([]byte(tr(usage)), width)
+	room := int((bottom - pdf.GetY()) / 3.4)
+	if room < len(lines) && room > 0 {
+		lines = lines[:room]
+		lines[room-1] = []byte(fit(pdf, string(lines[room-1])+"...", width))
+	}
+	for i, line := range lines {
+		if i >= room {
+			break
+		}
+		pdf.SetX(x + padding)
+		pdf.CellFormat(width, 3.4, string(line), "", 2, "L", false, 0, "")
+	}
+}
+
+// fit shortens text with an ellipsis until it fits the given width in the
+// current font.
+func fit(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(strings.TrimSuffix(text, "..."))
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "..."
+}
@@ -0,0 +1,37 @@
+package cuecards
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ledongthuc/pdf"
+)
+
+func TestRenderPaginatesCards(t *testing.T) {
+	cards := make([]Card, PerPage+1)
+	for i := range cards {
+		cards[i] = Card{Name: "Iso E Super", CAS: "54464-57-2", Pyramid: "Base", Dilution: "Recommended 10.00%"}
+	}
+	cards[PerPage].Name = "Hédione"
+	cards[PerPage].Usage = strings.Repeat("Radiant jasmine lift for florals. ", 40)
+
+	var buf bytes.Buffer
+	if err := Render(&buf, "Cue cards", cards); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	reader, err := pdf.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("read pdf: %v", err)
+	}
+	if got := reader.NumPage(); got != 2 {
+		t.Fatalf("expected 2 pages, got %d", got)
+	}
+	text, err := reader.Page(2).GetPlainText(nil)
+	if err != nil {
+		t.Fatalf("extract text: %v", err)
+	}
+	if !strings.Contains(text, "Radiant jasmine") || strings.Contains(text, "Iso E Super") {
+		t.Fatalf("unexpected second page: %q", text)
+	}
+}
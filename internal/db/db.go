@@ -12,7 +12,6 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
@@ -29,12 +28,13 @@ func Initialize(cfg config.DatabaseConfig) (*gorm.DB, error) {
 		"maxOpenConns", cfg.MaxOpenConns,
 		"connMaxLifetime", cfg.ConnMaxLifetime.String(),
 		"connMaxIdleTime", cfg.ConnMaxIdleTime.String(),
+		"logger", cfg.Logger,
 	)
 
 	gormCfg := &gorm.Config{
 		PrepareStmt:            true,
 		SkipDefaultTransaction: true,
-		Logger:                 logger.Default.LogMode(logger.Warn),
+		Logger:                 NewLogger(cfg.Logger, cfg.SlowQueryThreshold),
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: false,
 		},
@@ -86,13 +86,67 @@ func AutoMigrate(db *gorm.DB) error {
 
 	applog.Debug(context.Background(), "running database migrations")
 
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&models.AromaChemical{},
 		&models.OtherName{},
 		&models.Formula{},
 		&models.FormulaIngredient{},
 		&models.User{},
-	)
+		&models.ActivityCounter{},
+		&models.Invitation{},
+		&models.InventoryItem{},
+		&models.Substitution{},
+		&models.ImportSchedule{},
+		&models.ImportRun{},
+		&models.ProductionBatch{},
+		&models.ProductionBatchLine{},
+		&models.BlockedMaterial{},
+		&models.Constituent{},
+		&models.BlindTest{},
+		&models.BlindSample{},
+		&models.BlindScore{},
+		&models.Organ{},
+		&models.OrganSlot{},
+		&models.AuditEntry{},
+		&models.UserIngredientNote{},
+		&models.WishlistItem{},
+		&models.BatchPreset{},
+		&models.ReportDefinition{},
+		&models.APIToken{},
+		&models.AIExchange{},
+		&models.TaxonomyTerm{},
+	); err != nil {
+		return err
+	}
+	return migrateSyntheticCAS(db)
+}
+
+// syntheticCASPatterns match the placeholders earlier imports stored as CAS
+// numbers for materials without one.
+var syntheticCASPatterns = []string{"UNASSIGNED-%", "MIXTURE-%", "BLEND-%"}
+
+// migrateSyntheticCAS moves placeholder CAS numbers into SyntheticID and
+// clears the CAS number. It is a no-op once nothing is left to move.
+func migrateSyntheticCAS(db *gorm.DB) error {
+	query := db.Model(&models.AromaChemical{}).Unscoped()
+	for i, pattern := range syntheticCASPatterns {
+		if i == 0 {
+			query = query.Where("cas_number LIKE ?", pattern)
+		} else {
+			query = query.Or("cas_number LIKE ?", pattern)
+		}
+	}
+	result := query.Updates(map[string]any{
+		"synthetic_id": gorm.Expr("cas_number"),
+		"cas_number":   gorm.Expr("NULL"),
+	})
+	if result.Error != nil {
+		return fmt.Errorf("migrate synthetic CAS numbers: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		applog.Info(context.Background(), "moved synthetic CAS numbers to synthetic ids", "rows", result.RowsAffected)
+	}
+	return nil
 }
 
 func Configure(cfg config.DatabaseConfig) (*gorm.DB, error) {
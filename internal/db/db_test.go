@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"perfugo/internal/config"
+	"perfugo/models"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -61,3 +62,39 @@ func TestMustConfigurePanicsOnError(t *testing.T) {
 
 	MustConfigure(config.DatabaseConfig{})
 }
+
+func TestAutoMigrateMovesSyntheticCASNumbers(t *testing.T) {
+	sqliteDB, err := gorm.Open(sqlite.Open("file:synthetic-cas?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite database: %v", err)
+	}
+	if err := AutoMigrate(sqliteDB); err != nil {
+		t.Fatalf("automigrate sqlite database: %v", err)
+	}
+	chemicals := []models.AromaChemical{
+		{IngredientName: "Rose Absolute", CASNumber: "MIXTURE-rose-absolute"},
+		{IngredientName: "Linalool", CASNumber: "78-70-6"},
+	}
+	if err := sqliteDB.Create(&chemicals).Error; err != nil {
+		t.Fatalf("create chemicals: %v", err)
+	}
+
+	if err := AutoMigrate(sqliteDB); err != nil {
+		t.Fatalf("re-run migrations: %v", err)
+	}
+	var stored []models.AromaChemical
+	if err := sqliteDB.Order("id").Find(&stored).Error; err != nil {
+		t.Fatalf("load chemicals: %v", err)
+	}
+	if stored[0].CASNumber != "" || stored[0].SyntheticID != "MIXTURE-rose-absolute" {
+		t.Fatalf("expected the placeholder to move to the synthetic id, got %+v", stored[0])
+	}
+	if stored[1].CASNumber != "78-70-6" || stored[1].SyntheticID != "" {
+		t.Fatalf("expected a real CAS number to stay, got %+v", stored[1])
+	}
+	var nulls int64
+	sqliteDB.Model(&models.AromaChemical{}).Where("cas_number IS NULL").Count(&nulls)
+	if nulls != 1 {
+		t.Fatalf("expected the cleared CAS number to be NULL, got %d NULL rows", nulls)
+	}
+}
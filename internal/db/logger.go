@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	applog "perfugo/internal/log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Database loggers selectable with config.DatabaseConfig.Logger.
+const (
+	// LoggerApplog sends SQL errors, slow queries and, at the debug level,
+	// every statement through internal/log with the request ID.
+	LoggerApplog = "applog"
+	// LoggerGORM keeps GORM's own logger, printing warnings to stdout.
+	LoggerGORM = "gorm"
+	// LoggerSilent discards database logs.
+	LoggerSilent = "silent"
+)
+
+// DefaultSlowQueryThreshold is used when DatabaseConfig.SlowQueryThreshold
+// is unset.
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// NewLogger returns the GORM logger named by kind. Queries slower than
+// slowQuery are reported as warnings.
+func NewLogger(kind string, slowQuery time.Duration) logger.Interface {
+	if slowQuery <= 0 {
+		slowQuery = DefaultSlowQueryThreshold
+	}
+	switch kind {
+	case LoggerGORM:
+		return logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold: slowQuery,
+			LogLevel:      logger.Warn,
+			Colorful:      true,
+		})
+	case LoggerSilent:
+		return logger.Default.LogMode(logger.Silent)
+	default:
+		return &applogLogger{level: logger.Info, slowQuery: slowQuery}
+	}
+}
+
+// applogLogger adapts internal/log to GORM. Its own level only narrows what
+// GORM hands over; the application log level decides what is written.
+type applogLogger struct {
+	level     logger.LogLevel
+	slowQuery time.Duration
+}
+
+func (l *applogLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *applogLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Info {
+		applog.Info(ctx, "gorm: "+fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *applogLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Warn {
+		applog.Warn(ctx, "gorm: "+fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *applogLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Error {
+		applog.Error(ctx, "gorm: "+fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace logs a failed statement as an error and a slow one as a warning;
+// other statements are only logged at the debug level. Missing records are
+// an expected outcome and not logged as failures.
+func (l *applogLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		sql, rows := fc()
+		applog.Error(ctx, "sql statement failed", "error", err, "sql", sql, "rows", rows, "elapsed", elapsed.String())
+	case elapsed > l.slowQuery && l.level >= logger.Warn:
+		sql, rows := fc()
+		applog.Warn(ctx, "slow sql statement", "sql", sql, "rows", rows, "elapsed", elapsed.String(), "threshold", l.slowQuery.String())
+	case l.level >= logger.Info && applog.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		applog.Debug(ctx, "sql statement", "sql", sql, "rows", rows, "elapsed", elapsed.String())
+	}
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestApplogLoggerReportsFailuresWithRequestID(t *testing.T) {
+	buf := new(bytes.Buffer)
+	level := new(slog.LevelVar)
+	original := applog.Logger()
+	applog.ReplaceLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: level})))
+	t.Cleanup(func() { applog.ReplaceLogger(original) })
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: NewLogger(LoggerApplog, 0)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Formula{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	ctx := applog.WithRequestID(context.Background(), "req-42")
+	buf.Reset()
+
+	var formula models.Formula
+	db.WithContext(ctx).First(&formula, 99)
+	if buf.Len() != 0 {
+		t.Fatalf("expected a missing record and a plain statement to stay quiet at info, got %q", buf.String())
+	}
+
+	db.WithContext(ctx).Exec("SELECT * FROM no_such_table")
+	line := buf.String()
+	if !strings.Contains(line, "level=ERROR") || !strings.Contains(line, "sql statement failed") || !strings.Contains(line, "requestID=req-42") {
+		t.Fatalf("expected the failure logged with the request ID, got %q", line)
+	}
+
+	level.Set(slog.LevelDebug)
+	buf.Reset()
+	db.WithContext(ctx).Find(&[]models.Formula{})
+	if !strings.Contains(buf.String(), `msg="sql statement"`) {
+		t.Fatalf("expected statements logged at debug, got %q", buf.String())
+	}
+}
@@ -36,6 +36,29 @@ func New(ctx context.Context) (*gorm.DB, error) {
 		&models.Formula{},
 		&models.FormulaIngredient{},
 		&models.User{},
+		&models.ActivityCounter{},
+		&models.Invitation{},
+		&models.InventoryItem{},
+		&models.Substitution{},
+		&models.ImportSchedule{},
+		&models.ImportRun{},
+		&models.ProductionBatch{},
+		&models.ProductionBatchLine{},
+		&models.BlockedMaterial{},
+		&models.Constituent{},
+		&models.BlindTest{},
+		&models.BlindSample{},
+		&models.BlindScore{},
+		&models.Organ{},
+		&models.OrganSlot{},
+		&models.AuditEntry{},
+		&models.UserIngredientNote{},
+		&models.WishlistItem{},
+		&models.BatchPreset{},
+		&models.ReportDefinition{},
+		&models.APIToken{},
+		&models.AIExchange{},
+		&models.TaxonomyTerm{},
 	); err != nil {
 		return nil, err
 	}
@@ -60,6 +83,7 @@ func seed(ctx context.Context, db *gorm.DB) error {
 		Name:         "Avery Studio",
 		Email:        "avery@perfugo.app",
 		PasswordHash: string(password),
+		Role:         models.RoleAdmin,
 	}
 	if err := db.WithContext(ctx).Create(user).Error; err != nil {
 		return err
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/analytics"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// AccordBuilder recalculates the accord builder scratchpad, adding or
+// removing a material when asked. The scratchpad is never stored.
+func AccordBuilder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	pad, message := parseAccordScratchpad(r, snapshot.AromaChemicals)
+	if index, err := strconv.Atoi(r.FormValue("accord_remove")); err == nil && index >= 0 && index < len(pad.Rows) {
+		pad.Rows = append(pad.Rows[:index], pad.Rows[index+1:]...)
+	} else if id := pages.ParseUint(r.FormValue("accord_add")); id != 0 && !pad.Has(id) {
+		switch chemical := pages.FindAromaChemical(snapshot.AromaChemicals, id); {
+		case chemical == nil:
+			message = "That material is not in your library."
+		case len(pad.Rows) >= pages.MaxAccordRows:
+			message = fmt.Sprintf("An accord may have at most %d materials.", pages.MaxAccordRows)
+		default:
+			pad.Rows = append(pad.Rows, pages.AccordRow{Chemical: *chemical, Parts: 1})
+		}
+	}
+	pad.Message = message
+	pad.Normalize()
+	renderComponent(w, r, pages.AccordBuilder(pad, snapshot.AromaChemicals))
+}
+
+// AccordPromote saves the scratchpad as a new formula in percentage entry,
+// named after the accord.
+func AccordPromote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	pad, message := parseAccordScratchpad(r, snapshot.AromaChemicals)
+	pad.Normalize()
+	render := func(message string) {
+		pad.Message = message
+		renderComponent(w, r, pages.AccordBuilder(pad, snapshot.AromaChemicals))
+	}
+	if message != "" {
+		render(message)
+		return
+	}
+	if database == nil {
+		render(pages.DemoModeMessage)
+		return
+	}
+
+	updates := make([]formulaIngredientUpdate, 0, len(pad.Rows))
+	chemIDs := make([]uint, 0, len(pad.Rows))
+	for _, row := range pad.Rows {
+		if row.Parts <= 0 {
+			continue
+		}
+		id := row.Chemical.ID
+		chemIDs = append(chemIDs, id)
+		updates = append(updates, formulaIngredientUpdate{AromaChemicalID: &id, Amount: row.Parts, Unit: percentEntryUnit, Position: len(updates)})
+	}
+	if len(updates) == 0 {
+		render("Give at least one material some parts before saving.")
+		return
+	}
+	if prohibited, _ := blockedChemicals(loadBlocklist(r.Context()), snapshot.AromaChemicals, chemIDs); len(prohibited) > 0 {
+		render(blocklistMessage(prohibited) + " Remove prohibited materials before saving.")
+		return
+	}
+	if err := applyFormulaEntryMode(updates, true); err != nil {
+		render("Give at least one material some parts before saving.")
+		return
+	}
+
+	name := pad.Name
+	if name == "" {
+		name = pages.NextUntitledFormulaName(snapshot.Formulas)
+	}
+	formula := models.Formula{Name: name, Version: 1, IsLatest: true, PercentEntry: true}
+	ctx := r.Context()
+	userID, _ := currentUserID(r)
+	if userID != 0 {
+		formula.CreatedByID = &userID
+	}
+	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFormulaQuota(tx, userID, 1); err != nil {
+			return err
+		}
+		if err := tx.Create(&formula).Error; err != nil {
+			return err
+		}
+		for _, update := range updates {
+			record := models.FormulaIngredient{
+				FormulaID:       formula.ID,
+				Amount:          update.Amount,
+				Unit:            update.Unit,
+				AromaChemicalID: update.AromaChemicalID,
+				Percentage:      update.Percentage,
+				Position:        update.Position,
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if message, ok := quotaMessage(err); ok {
+		render(message)
+		return
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to promote accord", "error", err)
+		render("We couldn't save the accord. Please try again.")
+		return
+	}
+	applog.Info(ctx, "accord promoted to formula", "formulaID", formula.ID, "rows", len(updates))
+	recordActivity(ctx, models.ActivityEntityFormula, formula.ID, analytics.EventEdit)
+
+	pad.SavedID = formula.ID
+	render(fmt.Sprintf("Saved as %s.", formula.Name))
+}
+
+// parseAccordScratchpad reads the scratchpad rows back from the form, keeping
+// only materials in the user's library.
+func parseAccordScratchpad(r *http.Request, chemicals []models.AromaChemical) (pages.AccordScratchpad, string) {
+	pad := pages.AccordScratchpad{Name: strings.TrimSpace(r.FormValue("accord_name"))}
+	ids := r.Form["accord_chemical_id"]
+	parts := r.Form["accord_parts"]
+	if len(ids) != len(parts) {
+		return pad, "We couldn't read the scratchpad. Please try again."
+	}
+
+	message := ""
+	for i := range ids {
+		chemical := pages.FindAromaChemical(chemicals, pages.ParseUint(ids[i]))
+		if chemical == nil || pad.Has(chemical.ID) || len(pad.Rows) >= pages.MaxAccordRows {
+			continue
+		}
+		value := 0.0
+		if raw := strings.TrimSpace(parts[i]); raw != "" {
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err != nil || parsed < 0 {
+				message = "Parts must be positive numbers."
+			} else {
+				value = parsed
+			}
+		}
+		pad.Rows = append(pad.Rows, pages.AccordRow{Chemical: *chemical, Parts: value})
+	}
+	return pad, message
+}
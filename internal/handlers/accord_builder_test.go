@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"math"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestAccordBuilderNormalizesAndPromotes(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	bergamot := models.AromaChemical{IngredientName: "Bergamot", OwnerID: 6}
+	hedione := models.AromaChemical{IngredientName: "Hedione", OwnerID: 6}
+	for _, chemical := range []*models.AromaChemical{&bergamot, &hedione} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	ids := []string{strconv.Itoa(int(bergamot.ID)), strconv.Itoa(int(hedione.ID))}
+
+	rec := httptest.NewRecorder()
+	AccordBuilder(rec, authenticatedFormRequest(t, sm, "/app/sections/tools/accord", url.Values{
+		"accord_chemical_id": {ids[0]},
+		"accord_parts":       {"3"},
+		"accord_add":         {ids[1]},
+	}, 6))
+	body := rec.Body.String()
+	if !strings.Contains(body, "75.0%") || !strings.Contains(body, "25.0%") {
+		t.Fatalf("expected normalised shares after adding a material, got %q", body)
+	}
+
+	rec = httptest.NewRecorder()
+	AccordPromote(rec, authenticatedFormRequest(t, sm, "/app/sections/tools/accord/promote", url.Values{
+		"accord_name":        {"Cologne accord"},
+		"accord_chemical_id": ids,
+		"accord_parts":       {"1", "4"},
+	}, 6))
+	if body := rec.Body.String(); !strings.Contains(body, "Saved as Cologne accord.") {
+		t.Fatalf("expected the accord to be saved, got %q", body)
+	}
+
+	var formula models.Formula
+	if err := db.Where("name = ?", "Cologne accord").First(&formula).Error; err != nil {
+		t.Fatalf("load promoted formula: %v", err)
+	}
+	if !formula.PercentEntry || formula.CreatedByID == nil || *formula.CreatedByID != 6 {
+		t.Fatalf("unexpected formula: %+v", formula)
+	}
+	var rows []models.FormulaIngredient
+	db.Where("formula_id = ?", formula.ID).Order("position asc").Find(&rows)
+	if len(rows) != 2 || math.Abs(rows[0].Percentage-20) > 1e-9 || math.Abs(rows[1].Percentage-80) > 1e-9 {
+		t.Fatalf("unexpected composition: %+v", rows)
+	}
+}
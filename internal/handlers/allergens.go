@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// allergenBasisMg is the batch size the allergen report scales a formula to.
+// It is large so rounding to whole milligrams does not hide trace allergens.
+const allergenBasisMg = 1e9
+
+// FormulaAllergens reports the allergens in a formula's expanded composition,
+// counting the imported constituents of naturals as well as pure materials.
+func FormulaAllergens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, pages.ParseUint(r.URL.Query().Get("id")))
+	if formula == nil {
+		http.Error(w, "formula not found", http.StatusNotFound)
+		return
+	}
+
+	concentration := pages.DefaultAllergenConcentration
+	if raw := strings.TrimSpace(r.URL.Query().Get("concentration")); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value <= 0 || value > 100 {
+			renderComponent(w, r, pages.FormulaAllergens(pages.AllergenReport{FormulaID: formula.ID, Concentration: concentration, Message: "Enter a concentration between 0 and 100%."}))
+			return
+		}
+		concentration = value
+	}
+
+	report, err := buildAllergenReport(r, formula.ID, concentration)
+	if err != nil {
+		report = pages.AllergenReport{FormulaID: formula.ID, Concentration: concentration}
+		switch {
+		case errors.Is(err, errBatchEmptyComposition):
+			report.Message = "Add ingredients to see the allergens in this formula."
+		case errors.Is(err, errBatchInvalidQuantity):
+			report.Message = "The composition has no weight to report on."
+		default:
+			applog.Error(r.Context(), "failed to build allergen report", "error", err, "formulaID", formula.ID)
+			report.Message = "We couldn't calculate the allergens. Please try again."
+		}
+	}
+	renderComponent(w, r, pages.FormulaAllergens(report))
+}
+
+func buildAllergenReport(r *http.Request, formulaID uint, concentration float64) (pages.AllergenReport, error) {
+	ctx := r.Context()
+	data, err := buildBatchProductionReportData(ctx, formulaID, allergenBasisMg, nil)
+	if err != nil {
+		return pages.AllergenReport{}, err
+	}
+
+	total := 0.0
+	ids := make([]uint, 0, len(data.Ingredients))
+	for _, ingredient := range data.Ingredients {
+		total += ingredient.FinalQuantity
+		ids = append(ids, ingredient.ChemicalID)
+	}
+	if total <= 0 {
+		return pages.AllergenReport{}, errBatchInvalidQuantity
+	}
+
+	chemicals, err := loadReportChemicals(ctx, ids)
+	if err != nil {
+		return pages.AllergenReport{}, err
+	}
+	byID := make(map[uint]models.AromaChemical, len(chemicals))
+	for _, chemical := range chemicals {
+		byID[chemical.ID] = chemical
+	}
+
+	// The demo library has no imported constituents.
+	var constituents []models.Constituent
+	if database != nil {
+		if err := database.WithContext(ctx).Find(&constituents).Error; err != nil {
+			return pages.AllergenReport{}, err
+		}
+	}
+
+	sources := make([]pages.AllergenSource, 0, len(data.Ingredients))
+	for _, ingredient := range data.Ingredients {
+		chemical, ok := byID[ingredient.ChemicalID]
+		if !ok {
+			continue
+		}
+		sources = append(sources, pages.AllergenSource{Chemical: chemical, Percent: ingredient.NeatQuantity / total * 100})
+	}
+	return pages.BuildAllergenReport(formulaID, concentration, sources, constituents), nil
+}
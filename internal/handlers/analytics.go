@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"perfugo/internal/analytics"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	activityListLimit = 5
+	untouchedAfter    = 365 * 24 * time.Hour
+)
+
+// recordActivity bumps a usage counter. Failures are logged and never block the request.
+func recordActivity(ctx context.Context, entityType string, entityID uint, event analytics.Event) {
+	if database == nil || entityID == 0 {
+		return
+	}
+	key := analytics.Key{EntityType: entityType, EntityID: entityID}
+	if err := analytics.Record(ctx, database, key, event, nowFunc()); err != nil {
+		applog.Error(ctx, "failed to record activity", "error", err, "entityType", entityType, "entityID", entityID, "event", string(event))
+		return
+	}
+	applog.Debug(ctx, "activity recorded", "entityType", entityType, "entityID", entityID, "event", string(event))
+}
+
+// loadActivityInsights builds the "most worked on this month" and "untouched
+// for a year" lists for the entities visible in the snapshot.
+func loadActivityInsights(ctx context.Context, snapshot pages.WorkspaceSnapshot) pages.ActivityInsights {
+	insights := pages.ActivityInsights{}
+	if database == nil {
+		return insights
+	}
+
+	names := activityNames(snapshot)
+	now := nowFunc()
+
+	counters, err := analytics.MostActive(ctx, database, now, 0)
+	if err != nil {
+		applog.Error(ctx, "failed to load activity counters", "error", err)
+		return insights
+	}
+	for _, counter := range counters {
+		key := analytics.Key{EntityType: counter.EntityType, EntityID: counter.EntityID}
+		name, ok := names[key]
+		if !ok {
+			continue
+		}
+		insights.MostActive = append(insights.MostActive, pages.ActivityItem{
+			Kind:   activityKindLabel(counter.EntityType),
+			Name:   name,
+			Detail: fmt.Sprintf("%d views · %d edits · %d batches", counter.Views, counter.Edits, counter.Batches),
+		})
+		if len(insights.MostActive) == activityListLimit {
+			break
+		}
+	}
+
+	lastActivity, err := analytics.LastActivity(ctx, database)
+	if err != nil {
+		applog.Error(ctx, "failed to load last activity", "error", err)
+		return insights
+	}
+
+	type candidate struct {
+		key     analytics.Key
+		touched time.Time
+	}
+	cutoff := now.Add(-untouchedAfter)
+	var stale []candidate
+	consider := func(key analytics.Key, updatedAt time.Time) {
+		touched := updatedAt
+		if last := lastActivity[key]; last.After(touched) {
+			touched = last
+		}
+		if touched.Before(cutoff) {
+			stale = append(stale, candidate{key: key, touched: touched})
+		}
+	}
+	for _, formula := range snapshot.Formulas {
+		consider(analytics.Key{EntityType: models.ActivityEntityFormula, EntityID: formula.ID}, formula.UpdatedAt)
+	}
+	for _, chemical := range snapshot.AromaChemicals {
+		if snapshot.UserID == 0 || chemical.OwnerID != snapshot.UserID {
+			continue
+		}
+		consider(analytics.Key{EntityType: models.ActivityEntityAromaChemical, EntityID: chemical.ID}, chemical.UpdatedAt)
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].touched.Before(stale[j].touched)
+	})
+	for _, item := range stale {
+		if len(insights.Untouched) == activityListLimit {
+			break
+		}
+		insights.Untouched = append(insights.Untouched, pages.ActivityItem{
+			Kind:   activityKindLabel(item.key.EntityType),
+			Name:   names[item.key],
+			Detail: "Last touched " + item.touched.Format("2 Jan 2006"),
+		})
+	}
+
+	applog.Debug(ctx, "activity insights loaded", "mostActive", len(insights.MostActive), "untouched", len(insights.Untouched))
+	return insights
+}
+
+func activityNames(snapshot pages.WorkspaceSnapshot) map[analytics.Key]string {
+	names := make(map[analytics.Key]string, len(snapshot.Formulas)+len(snapshot.AromaChemicals))
+	for _, formula := range snapshot.Formulas {
+		names[analytics.Key{EntityType: models.ActivityEntityFormula, EntityID: formula.ID}] = formula.Name
+	}
+	for _, chemical := range snapshot.AromaChemicals {
+		names[analytics.Key{EntityType: models.ActivityEntityAromaChemical, EntityID: chemical.ID}] = chemical.IngredientName
+	}
+	return names
+}
+
+func activityKindLabel(entityType string) string {
+	switch entityType {
+	case models.ActivityEntityFormula:
+		return "Formula"
+	case models.ActivityEntityAromaChemical:
+		return "Ingredient"
+	default:
+		return entityType
+	}
+}
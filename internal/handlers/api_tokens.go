@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// apiTokenPrefix marks raw API tokens so they are recognisable in secret
+// scanners and configuration files.
+const apiTokenPrefix = "pfg_"
+
+type apiTokenKey struct{}
+
+// requestAPIToken returns the token a request was authenticated with by
+// RequireAPIToken.
+func requestAPIToken(ctx context.Context) (models.APIToken, bool) {
+	token, ok := ctx.Value(apiTokenKey{}).(models.APIToken)
+	return token, ok
+}
+
+// apiTokenUser returns the user a request was authenticated as by
+// RequireAPIToken.
+func apiTokenUser(ctx context.Context) (uint, bool) {
+	token, ok := requestAPIToken(ctx)
+	return token.OwnerID, ok && token.OwnerID != 0
+}
+
+// requireAPIScope answers 403 Forbidden and returns false when the request
+// carries an API token whose scopes do not cover resource, or writing to it
+// when write is set. Requests from a signed-in session always pass.
+func requireAPIScope(w http.ResponseWriter, r *http.Request, resource string, write bool) bool {
+	token, ok := requestAPIToken(r.Context())
+	if !ok || token.Allows(resource, write) {
+		return true
+	}
+	message := "This token is read-only."
+	if !token.Allows(resource, false) {
+		message = fmt.Sprintf("This token is limited to %s.", strings.Join(token.Resources(), " and "))
+	}
+	applog.Debug(r.Context(), "api token scope refused", "tokenID", token.ID, "resource", resource, "write", write)
+	writeProblem(w, r, http.StatusForbidden, message)
+	return false
+}
+
+// RequireAuthenticationOrAPIToken lets JSON endpoints serve both the
+// signed-in browser and scripts: requests with a Bearer token go through
+// RequireAPIToken, the rest through RequireAuthentication.
+func RequireAuthenticationOrAPIToken(next http.Handler) http.Handler {
+	withToken := RequireAPIToken(next)
+	withSession := RequireAuthentication(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			withToken.ServeHTTP(w, r)
+			return
+		}
+		withSession.ServeHTTP(w, r)
+	})
+}
+
+// RequireAPIToken authenticates requests carrying "Authorization: Bearer"
+// with one of a user's API tokens. Handlers behind it see the token's owner
+// as the current user. Failures answer with a problem document rather than
+// a login redirect, as the callers are scripts.
+func RequireAPIToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if database == nil {
+			writeProblem(w, r, http.StatusServiceUnavailable, pages.DemoModeMessage)
+			return
+		}
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		presented = strings.TrimSpace(presented)
+		if !ok || !strings.HasPrefix(presented, apiTokenPrefix) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="perfugo"`)
+			writeProblem(w, r, http.StatusUnauthorized, "Send an API token as a Bearer token in the Authorization header.")
+			return
+		}
+
+		ctx := r.Context()
+		var token models.APIToken
+		err := database.WithContext(ctx).Where("token_hash = ?", hashToken(presented)).First(&token).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			applog.Error(ctx, "failed to look up api token", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "The token could not be checked. Please try again.")
+			return
+		}
+		var owner models.User
+		if err == nil {
+			err = database.WithContext(ctx).Select("id", "deactivated_at").First(&owner, token.OwnerID).Error
+		}
+		if err != nil || !owner.IsActive() {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="perfugo", error="invalid_token"`)
+			writeProblem(w, r, http.StatusUnauthorized, "The API token is invalid or has been revoked.")
+			return
+		}
+
+		if err := database.WithContext(ctx).Model(&token).UpdateColumn("last_used_at", nowFunc()).Error; err != nil {
+			applog.Error(ctx, "failed to record api token use", "error", err, "tokenID", token.ID)
+		}
+		applog.Debug(ctx, "api token request proceeding", "path", r.URL.Path, "tokenID", token.ID, "scopes", token.Scopes)
+		r = r.WithContext(context.WithValue(ctx, apiTokenKey{}, token))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// APITokenCreate issues a named API token for the current user and shows
+// it once.
+func APITokenCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" || len(name) > 80 {
+		renderAPITokenControl(w, r, userID, pages.APITokenPanel{Message: "Name the token in at most 80 characters, for example after the tool using it."})
+		return
+	}
+	scopes, ok := models.NormalizeScopes(r.Form["scopes"])
+	if !ok {
+		renderAPITokenControl(w, r, userID, pages.APITokenPanel{Message: "Choose read-only, ingredients or reports, or no scope for full access."})
+		return
+	}
+	var count int64
+	if err := database.WithContext(ctx).Model(&models.APIToken{}).Where("owner_id = ?", userID).Count(&count).Error; err != nil {
+		applog.Error(ctx, "failed to count api tokens", "error", err, "userID", userID)
+		http.Error(w, "unable to create token", http.StatusInternalServerError)
+		return
+	}
+	if count >= models.MaxAPITokens {
+		renderAPITokenControl(w, r, userID, pages.APITokenPanel{Message: fmt.Sprintf("You can hold at most %d tokens. Revoke one you no longer use first.", models.MaxAPITokens)})
+		return
+	}
+
+	secret, err := newToken()
+	if err != nil {
+		applog.Error(ctx, "failed to generate api token", "error", err)
+		http.Error(w, "unable to create token", http.StatusInternalServerError)
+		return
+	}
+	raw := apiTokenPrefix + secret
+	token := models.APIToken{OwnerID: userID, Name: name, TokenHash: hashToken(raw), Scopes: scopes}
+	if err := database.WithContext(ctx).Create(&token).Error; err != nil {
+		applog.Error(ctx, "failed to create api token", "error", err, "userID", userID)
+		http.Error(w, "unable to create token", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "api token created", "tokenID", token.ID, "userID", userID, "scopes", scopes)
+	renderAPITokenControl(w, r, userID, pages.APITokenPanel{NewToken: raw, Message: fmt.Sprintf("Token %q created. Copy it now; it is not shown again.", name)})
+}
+
+// APITokenRevoke deletes one of the current user's API tokens.
+func APITokenRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	id := pages.ParseUint(r.FormValue("id"))
+	result := database.WithContext(ctx).Unscoped().Where("id = ? AND owner_id = ?", id, userID).Delete(&models.APIToken{})
+	if result.Error != nil {
+		applog.Error(ctx, "failed to revoke api token", "error", result.Error, "tokenID", id)
+		http.Error(w, "unable to revoke token", http.StatusInternalServerError)
+		return
+	}
+	message := "Token revoked."
+	if result.RowsAffected == 0 {
+		message = "That token no longer exists."
+	}
+	applog.Info(ctx, "api token revoked", "tokenID", id, "userID", userID)
+	renderAPITokenControl(w, r, userID, pages.APITokenPanel{Message: message})
+}
+
+func renderAPITokenControl(w http.ResponseWriter, r *http.Request, userID uint, panel pages.APITokenPanel) {
+	panel.Tokens = loadAPITokens(r.Context(), userID)
+	renderComponent(w, r, pages.APITokenControl(panel))
+}
+
+// loadAPITokens lists the user's tokens, newest first.
+func loadAPITokens(ctx context.Context, userID uint) []models.APIToken {
+	if database == nil || userID == 0 {
+		return nil
+	}
+	var tokens []models.APIToken
+	if err := database.WithContext(ctx).Where("owner_id = ?", userID).Order("created_at desc, id desc").Find(&tokens).Error; err != nil {
+		applog.Error(ctx, "failed to load api tokens", "error", err, "userID", userID)
+		return nil
+	}
+	return tokens
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// AromaChemicalResource handles GET /app/api/aroma-chemicals/{id}. The
+// aroma chemical is returned as JSON, as a one-row CSV or as the workspace's
+// ingredient detail fragment, depending on the Accept header.
+func AromaChemicalResource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use GET to read an aroma chemical.")
+		return
+	}
+	if !requireAPIScope(w, r, models.ScopeIngredients, false) {
+		return
+	}
+	offers := []string{mediaJSON, mediaCSV, mediaHTML}
+	media := negotiateMedia(r, offers...)
+	if media == "" {
+		writeNotAcceptable(w, r, offers...)
+		return
+	}
+
+	userID, _ := currentUserID(r)
+	chemical := pages.FindAromaChemical(loadAromaChemicals(r.Context(), userID), pages.ParseUint(r.PathValue("id")))
+	if chemical == nil {
+		writeProblem(w, r, http.StatusNotFound, "Aroma chemical not found.")
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	switch media {
+	case mediaHTML:
+		renderComponent(w, r, pages.IngredientDetail(chemical, loadIngredientNote(r, chemical.ID)))
+	case mediaCSV:
+		writeAromaChemicalCSV(w, r, chemical)
+	default:
+		w.Header().Set("Content-Type", mediaJSON)
+		if err := json.NewEncoder(w).Encode(chemical); err != nil {
+			applog.Error(r.Context(), "failed to encode aroma chemical", "error", err, "aromaChemicalID", chemical.ID)
+		}
+	}
+}
+
+func writeAromaChemicalCSV(w http.ResponseWriter, r *http.Request, chemical *models.AromaChemical) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"aroma-chemical-%d.csv\"", chemical.ID))
+
+	names := make([]string, 0, len(chemical.OtherNames))
+	for _, other := range chemical.OtherNames {
+		names = append(names, other.Name)
+	}
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"ID", "Ingredient", "CAS", "INCI", "Type", "Pyramid", "Wheel", "Strength", "Recommended dilution (%)", "Max IFRA (%)", "Other names"})
+	_ = writer.Write([]string{
+		strconv.FormatUint(uint64(chemical.ID), 10),
+		chemical.IngredientName,
+		chemical.CASNumber,
+		chemical.INCIName,
+		chemical.Type,
+		chemical.PyramidPosition,
+		chemical.WheelPosition,
+		strconv.Itoa(chemical.Strength),
+		strconv.FormatFloat(chemical.RecommendedDilution, 'f', -1, 64),
+		strconv.FormatFloat(chemical.MaxIFRAPercentage, 'f', -1, 64),
+		strings.Join(names, "; "),
+	})
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		applog.Error(r.Context(), "failed to write aroma chemical csv", "error", err)
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+// recordAudit appends an entry to the audit log, attributed to the signed-in
+// user and the address they made the change from. It runs in the caller's
+// transaction so the entry is only kept when the change it describes is.
+func recordAudit(tx *gorm.DB, r *http.Request, action, entityType string, entityID uint, summary string) error {
+	entry := models.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Summary:    summary,
+	}
+	if userID, ok := currentUserID(r); ok {
+		entry.ActorID = &userID
+	}
+	if addr, ok := clientAddr(r); ok {
+		entry.ClientIP = addr.String()
+	}
+	return tx.Create(&entry).Error
+}
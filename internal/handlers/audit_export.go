@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// maxAuditLineage bounds how many earlier versions a formula's audit trail
+// walks back through.
+const maxAuditLineage = 200
+
+// auditTrail is the audit history of one formula or aroma chemical, oldest
+// entry first.
+type auditTrail struct {
+	EntityType string            `json:"entity_type"`
+	EntityID   uint              `json:"entity_id"`
+	Name       string            `json:"name"`
+	Entries    []auditTrailEntry `json:"entries"`
+}
+
+// auditTrailEntry is one audited change. Version is the formula version the
+// entry was recorded against and is zero for aroma chemicals.
+type auditTrailEntry struct {
+	At         time.Time `json:"at"`
+	Action     string    `json:"action"`
+	ActorID    *uint     `json:"actor_id,omitempty"`
+	ActorName  string    `json:"actor_name,omitempty"`
+	ActorEmail string    `json:"actor_email,omitempty"`
+	Version    int       `json:"version,omitempty"`
+	Summary    string    `json:"summary"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+
+	entityID uint
+}
+
+// FormulaAudit handles GET /app/api/formulas/{id}/audit: who changed the
+// formula, how and when, across all of its earlier versions, as JSON or CSV
+// depending on the Accept header.
+func FormulaAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use GET to read a formula's audit trail.")
+		return
+	}
+	if !requireAPIScope(w, r, models.APIResourceFormulas, false) {
+		return
+	}
+	media, ok := auditTrailMedia(w, r)
+	if !ok {
+		return
+	}
+
+	trail, err := loadFormulaAuditTrail(r.Context(), pages.ParseUint(r.PathValue("id")))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeProblem(w, r, http.StatusNotFound, "Formula not found.")
+		return
+	}
+	if err != nil {
+		applog.Error(r.Context(), "failed to load formula audit trail", "error", err, "formulaID", r.PathValue("id"))
+		writeProblem(w, r, http.StatusInternalServerError, "We couldn't load the audit trail. Please try again.")
+		return
+	}
+	writeAuditTrail(w, r, media, trail)
+}
+
+// AromaChemicalAudit handles GET /app/api/aroma-chemicals/{id}/audit for an
+// aroma chemical the caller can see, as JSON or CSV depending on the Accept
+// header.
+func AromaChemicalAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use GET to read an aroma chemical's audit trail.")
+		return
+	}
+	if !requireAPIScope(w, r, models.ScopeIngredients, false) {
+		return
+	}
+	media, ok := auditTrailMedia(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := currentUserID(r)
+	trail, err := loadAromaChemicalAuditTrail(r.Context(), userID, pages.ParseUint(r.PathValue("id")))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeProblem(w, r, http.StatusNotFound, "Aroma chemical not found.")
+		return
+	}
+	if err != nil {
+		applog.Error(r.Context(), "failed to load aroma chemical audit trail", "error", err, "aromaChemicalID", r.PathValue("id"))
+		writeProblem(w, r, http.StatusInternalServerError, "We couldn't load the audit trail. Please try again.")
+		return
+	}
+	writeAuditTrail(w, r, media, trail)
+}
+
+// auditTrailMedia negotiates the representation of an audit trail, refusing
+// the request when none is acceptable or there is no database to read.
+func auditTrailMedia(w http.ResponseWriter, r *http.Request) (string, bool) {
+	offers := []string{mediaJSON, mediaCSV}
+	media := negotiateMedia(r, offers...)
+	if media == "" {
+		writeNotAcceptable(w, r, offers...)
+		return "", false
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return "", false
+	}
+	return media, true
+}
+
+// loadFormulaAuditTrail gathers the audit entries of a formula and of the
+// versions it descends from.
+func loadFormulaAuditTrail(ctx context.Context, formulaID uint) (auditTrail, error) {
+	var formula models.Formula
+	if err := database.WithContext(ctx).First(&formula, formulaID).Error; err != nil {
+		return auditTrail{}, err
+	}
+	trail := auditTrail{EntityType: models.ActivityEntityFormula, EntityID: formula.ID, Name: formula.Name}
+
+	versions := map[uint]int{formula.ID: formula.Version}
+	parent := formula.ParentFormulaID
+	for parent != nil && len(versions) < maxAuditLineage {
+		if _, seen := versions[*parent]; seen {
+			break
+		}
+		var ancestor models.Formula
+		if err := database.WithContext(ctx).Unscoped().Select("id", "version", "parent_formula_id").First(&ancestor, *parent).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return trail, err
+		}
+		versions[ancestor.ID] = ancestor.Version
+		parent = ancestor.ParentFormulaID
+	}
+
+	ids := make([]uint, 0, len(versions))
+	for id := range versions {
+		ids = append(ids, id)
+	}
+	entries, err := loadAuditEntries(ctx, models.ActivityEntityFormula, ids)
+	if err != nil {
+		return trail, err
+	}
+	trail.Entries = entries
+	for i := range trail.Entries {
+		trail.Entries[i].Version = versions[trail.Entries[i].entityID]
+	}
+	return trail, nil
+}
+
+// loadAromaChemicalAuditTrail gathers the audit entries of an aroma chemical
+// owned by userID or public.
+func loadAromaChemicalAuditTrail(ctx context.Context, userID, chemicalID uint) (auditTrail, error) {
+	var chemical models.AromaChemical
+	if err := database.WithContext(ctx).
+		Where("owner_id = ? OR public = ?", userID, true).
+		First(&chemical, chemicalID).Error; err != nil {
+		return auditTrail{}, err
+	}
+	trail := auditTrail{EntityType: models.ActivityEntityAromaChemical, EntityID: chemical.ID, Name: chemical.IngredientName}
+	entries, err := loadAuditEntries(ctx, models.ActivityEntityAromaChemical, []uint{chemical.ID})
+	trail.Entries = entries
+	return trail, err
+}
+
+// loadAuditEntries returns the entries recorded against the given entities,
+// oldest first, with the actor's name and email. Deactivated and deleted
+// actors are still named.
+func loadAuditEntries(ctx context.Context, entityType string, ids []uint) ([]auditTrailEntry, error) {
+	var rows []struct {
+		CreatedAt  time.Time
+		Action     string
+		ActorID    *uint
+		ActorName  string
+		ActorEmail string
+		EntityID   uint
+		Summary    string
+		ClientIP   string
+	}
+	if err := database.WithContext(ctx).
+		Table("audit_entries AS a").
+		Select("a.created_at, a.action, a.actor_id, COALESCE(u.name, '') AS actor_name, COALESCE(u.email, '') AS actor_email, a.entity_id, a.summary, COALESCE(a.client_ip, '') AS client_ip").
+		Joins("LEFT JOIN users AS u ON u.id = a.actor_id").
+		Where("a.entity_type = ? AND a.entity_id IN ? AND a.deleted_at IS NULL", entityType, ids).
+		Order("a.created_at asc").Order("a.id asc").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]auditTrailEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, auditTrailEntry{
+			At:         row.CreatedAt.UTC(),
+			Action:     row.Action,
+			ActorID:    row.ActorID,
+			ActorName:  row.ActorName,
+			ActorEmail: row.ActorEmail,
+			Summary:    row.Summary,
+			ClientIP:   row.ClientIP,
+			entityID:   row.EntityID,
+		})
+	}
+	return entries, nil
+}
+
+func writeAuditTrail(w http.ResponseWriter, r *http.Request, media string, trail auditTrail) {
+	w.Header().Set("Vary", "Accept")
+	if media == mediaCSV {
+		writeAuditTrailCSV(w, r, trail)
+		return
+	}
+	w.Header().Set("Content-Type", mediaJSON)
+	if err := json.NewEncoder(w).Encode(trail); err != nil {
+		applog.Error(r.Context(), "failed to encode audit trail", "error", err, "entityType", trail.EntityType, "entityID", trail.EntityID)
+	}
+}
+
+func writeAuditTrailCSV(w http.ResponseWriter, r *http.Request, trail auditTrail) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%d-audit.csv\"", trail.EntityType, trail.EntityID))
+
+	writer := csv.NewWriter(w)
+	header := []string{"When (UTC)", "Action", "Actor", "Actor email", "Summary", "Client IP"}
+	if trail.EntityType == models.ActivityEntityFormula {
+		header = []string{"When (UTC)", "Version", "Action", "Actor", "Actor email", "Summary", "Client IP"}
+	}
+	_ = writer.Write(header)
+	for _, entry := range trail.Entries {
+		record := []string{entry.At.Format(time.RFC3339), entry.Action, entry.ActorName, entry.ActorEmail, entry.Summary, entry.ClientIP}
+		if trail.EntityType == models.ActivityEntityFormula {
+			record = append([]string{record[0], strconv.Itoa(entry.Version)}, record[1:]...)
+		}
+		_ = writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		applog.Error(r.Context(), "failed to write audit trail csv", "error", err, "entityType", trail.EntityType, "entityID", trail.EntityID)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestAuditTrailExport(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	ada := models.User{Name: "Ada", Email: "ada@example.com", PasswordHash: "x"}
+	other := models.User{Email: "other@example.com", PasswordHash: "x"}
+	for _, user := range []*models.User{&ada, &other} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	first := models.Formula{Name: "Chypre", Version: 1}
+	db.Create(&first)
+	second := models.Formula{Name: "Chypre", Version: 2, IsLatest: true, ParentFormulaID: &first.ID}
+	db.Create(&second)
+	unrelated := models.Formula{Name: "Cologne", Version: 1, IsLatest: true}
+	db.Create(&unrelated)
+	private := models.AromaChemical{IngredientName: "Secret base", OwnerID: other.ID}
+	db.Create(&private)
+
+	for _, entry := range []models.AuditEntry{
+		{ActorID: &ada.ID, Action: models.AuditFormulaSubmitted, EntityType: models.ActivityEntityFormula, EntityID: first.ID, Summary: "Submitted for approval"},
+		{ActorID: &ada.ID, Action: models.AuditFormulaVersioned, EntityType: models.ActivityEntityFormula, EntityID: second.ID, Summary: "Version 2, with a comma"},
+		{Action: models.AuditFormulaApproved, EntityType: models.ActivityEntityFormula, EntityID: unrelated.ID},
+		{ActorID: &other.ID, Action: models.AuditChemicalPublished, EntityType: models.ActivityEntityAromaChemical, EntityID: private.ID},
+	} {
+		if err := db.Create(&entry).Error; err != nil {
+			t.Fatalf("create audit entry: %v", err)
+		}
+	}
+
+	get := func(handler http.HandlerFunc, path string, id uint, accept string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, fmt.Sprintf(path, id), nil, int(ada.ID))
+		req.Method = http.MethodGet
+		req.Header.Set("Accept", accept)
+		req.SetPathValue("id", strconv.Itoa(int(id)))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := get(FormulaAudit, "/app/api/formulas/%d/audit", second.ID, "application/json")
+	var trail auditTrail
+	if err := json.NewDecoder(rec.Body).Decode(&trail); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("json trail: status %d, %v", rec.Code, err)
+	}
+	if len(trail.Entries) != 2 || trail.Entries[0].Version != 1 || trail.Entries[1].Version != 2 || trail.Entries[0].ActorEmail != "ada@example.com" {
+		t.Fatalf("expected both versions' entries attributed to Ada, got %+v", trail.Entries)
+	}
+
+	rec = get(FormulaAudit, "/app/api/formulas/%d/audit", second.ID, "text/csv")
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("csv trail: %v, %v", err, records)
+	}
+	if records[0][1] != "Version" || records[2][1] != "2" || records[2][5] != "Version 2, with a comma" {
+		t.Fatalf("unexpected csv rows: %v", records)
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "formula-") {
+		t.Fatalf("expected a download, got %q", rec.Header().Get("Content-Disposition"))
+	}
+
+	if rec := get(AromaChemicalAudit, "/app/api/aroma-chemicals/%d/audit", private.ID, "application/json"); rec.Code != http.StatusNotFound {
+		t.Fatalf("another member's private chemical: status %d", rec.Code)
+	}
+	if rec := get(FormulaAudit, "/app/api/formulas/%d/audit", second.ID, "text/html"); rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("html: status %d", rec.Code)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"gorm.io/gorm"
 
 	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
 	"perfugo/models"
 )
 
@@ -20,6 +21,10 @@ const (
 	sessionUserEmailKey     = "auth:user:email"
 	sessionUserNameKey      = "auth:user:name"
 	sessionUserThemeKey     = "auth:user:theme"
+	sessionUserRoleKey      = "auth:user:role"
+	sessionSignedInAtKey    = "auth:signed_in_at"
+
+	accountDeactivatedMessage = "This account has been deactivated. Contact your administrator."
 )
 
 var (
@@ -39,7 +44,12 @@ func createUser(r *http.Request, email, name, password string) (*models.User, er
 	if database == nil {
 		return nil, gorm.ErrInvalidDB
 	}
+	return insertUser(r, database, email, name, password)
+}
 
+// insertUser hashes the password and persists a member account using db,
+// which may be a transaction.
+func insertUser(r *http.Request, db *gorm.DB, email, name, password string) (*models.User, error) {
 	applog.Debug(r.Context(), "creating user", "email", strings.ToLower(email))
 
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -53,9 +63,10 @@ func createUser(r *http.Request, email, name, password string) (*models.User, er
 		Name:         strings.TrimSpace(name),
 		PasswordHash: string(hashed),
 		Theme:        models.DefaultTheme,
+		Role:         models.RoleMember,
 	}
 
-	if err := database.WithContext(r.Context()).Create(user).Error; err != nil {
+	if err := db.WithContext(r.Context()).Create(user).Error; err != nil {
 		applog.Error(r.Context(), "failed to persist user", "error", err)
 		return nil, err
 	}
@@ -64,6 +75,20 @@ func createUser(r *http.Request, email, name, password string) (*models.User, er
 	return user, nil
 }
 
+// unusablePasswordHash returns a hash of a random secret for accounts that
+// sign in through an external identity provider.
+func unusablePasswordHash() (string, error) {
+	secret, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
 func findUserByEmail(r *http.Request, email string) (*models.User, error) {
 	if database == nil {
 		return nil, gorm.ErrInvalidDB
@@ -93,6 +118,37 @@ func authenticate(w http.ResponseWriter, r *http.Request, email, password string
 	}
 
 	applog.Debug(r.Context(), "beginning authentication", "email", strings.ToLower(email))
+	var (
+		user *models.User
+		ok   bool
+	)
+	if directory != nil {
+		user, ok = verifyDirectoryCredentials(r, email, password)
+	} else {
+		user, ok = verifyLocalCredentials(r, email, password)
+	}
+	if !ok {
+		return false
+	}
+
+	if !user.IsActive() {
+		applog.Debug(r.Context(), "authentication failed: account deactivated", "userID", user.ID)
+		sessionManager.Put(r.Context(), sessionLoginMessageKey, accountDeactivatedMessage)
+		return false
+	}
+
+	if err := establishSession(r, user); err != nil {
+		applog.Error(r.Context(), "failed to establish session", "error", err)
+		sessionManager.Put(r.Context(), sessionLoginMessageKey, "We were unable to sign you in. Please try again.")
+		return false
+	}
+
+	applog.Debug(r.Context(), "authentication complete", "userID", user.ID)
+	return true
+}
+
+// verifyLocalCredentials checks the password against the stored hash.
+func verifyLocalCredentials(r *http.Request, email, password string) (*models.User, bool) {
 	user, err := findUserByEmail(r, email)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -102,23 +158,15 @@ func authenticate(w http.ResponseWriter, r *http.Request, email, password string
 			applog.Error(r.Context(), "failed to load user during login", "error", err)
 			sessionManager.Put(r.Context(), sessionLoginMessageKey, "We were unable to sign you in. Please try again.")
 		}
-		return false
+		return nil, false
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		applog.Debug(r.Context(), "authentication failed: invalid password", "userID", user.ID)
 		sessionManager.Put(r.Context(), sessionLoginMessageKey, "Invalid email or password. Please try again.")
-		return false
-	}
-
-	if err := establishSession(r, user); err != nil {
-		applog.Error(r.Context(), "failed to establish session", "error", err)
-		sessionManager.Put(r.Context(), sessionLoginMessageKey, "We were unable to sign you in. Please try again.")
-		return false
+		return nil, false
 	}
-
-	applog.Debug(r.Context(), "authentication complete", "userID", user.ID)
-	return true
+	return user, true
 }
 
 func establishSession(r *http.Request, user *models.User) error {
@@ -137,6 +185,10 @@ func establishSession(r *http.Request, user *models.User) error {
 	sessionManager.Put(r.Context(), sessionUserEmailKey, user.Email)
 	sessionManager.Put(r.Context(), sessionUserNameKey, user.Name)
 	sessionManager.Put(r.Context(), sessionUserThemeKey, user.Theme)
+	sessionManager.Put(r.Context(), sessionUserRoleKey, models.NormalizeRole(user.Role))
+	sessionManager.Put(r.Context(), sessionSignedInAtKey, nowFunc().Unix())
+	sessionManager.Remove(r.Context(), sessionNumberFormatKey)
+	sessionManager.Remove(r.Context(), sessionShortcutsKey)
 	applog.Debug(r.Context(), "session established", "userID", user.ID)
 	return nil
 }
@@ -149,7 +201,67 @@ func RequireAuthentication(next http.Handler) http.Handler {
 			redirectToLogin(w, r)
 			return
 		}
+		if !verifySessionAccount(w, r) {
+			return
+		}
+		if !enforceImpersonation(w, r) {
+			return
+		}
 		applog.Debug(r.Context(), "authenticated request proceeding", "path", r.URL.Path)
+		next.ServeHTTP(w, withShortcuts(withNumberFormat(r)))
+	})
+}
+
+// verifySessionAccount reloads the signed-in account so a deactivation, such
+// as SCIM deprovisioning, or a role change from SSO group claims applies to
+// its sessions on their next request instead of when they expire. Sessions of
+// deactivated or deleted accounts are destroyed.
+func verifySessionAccount(w http.ResponseWriter, r *http.Request) bool {
+	userID, ok := currentUserID(r)
+	if !ok || database == nil {
+		return true
+	}
+	ctx := r.Context()
+	var user models.User
+	err := database.WithContext(ctx).Select("id", "role", "deactivated_at").First(&user, userID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		applog.Error(ctx, "failed to load session account", "error", err, "userID", userID)
+		http.Error(w, "unable to verify your session", http.StatusInternalServerError)
+		return false
+	}
+	if err == nil && user.IsActive() {
+		refreshSessionRole(r, user.Role)
+		return true
+	}
+
+	applog.Info(ctx, "session ended: account deactivated or removed", "userID", userID)
+	if err := sessionManager.Destroy(ctx); err != nil {
+		applog.Error(ctx, "failed to destroy session", "error", err)
+	}
+	sessionManager.Put(ctx, sessionLoginMessageKey, accountDeactivatedMessage)
+	redirectToLogin(w, r)
+	return false
+}
+
+// refreshSessionRole updates the role kept in the session when the stored
+// role has changed since sign-in.
+func refreshSessionRole(r *http.Request, role string) {
+	role = models.NormalizeRole(role)
+	if sessionManager.GetString(r.Context(), sessionUserRoleKey) == role {
+		return
+	}
+	sessionManager.Put(r.Context(), sessionUserRoleKey, role)
+	applog.Info(r.Context(), "session role refreshed", "role", role)
+}
+
+// RequireAdmin restricts the resource to authenticated administrators.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !currentUserIsAdmin(r) {
+			applog.Debug(r.Context(), "admin route requested by non-admin", "path", r.URL.Path)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -165,7 +277,9 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	idToken := ""
 	if sessionManager != nil {
+		idToken = sessionManager.GetString(r.Context(), sessionOIDCIDTokenKey)
 		if err := sessionManager.Destroy(r.Context()); err != nil {
 			applog.Error(r.Context(), "failed to destroy session", "error", err)
 		} else {
@@ -173,27 +287,40 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if idToken != "" {
+		if endSession, ok := oidcProvider.EndSessionURL(idToken, ""); ok {
+			applog.Debug(r.Context(), "redirecting to oidc end_session_endpoint")
+			if isHTMX(r) {
+				w.Header().Set("HX-Redirect", endSession)
+				w.WriteHeader(http.StatusSeeOther)
+				return
+			}
+			http.Redirect(w, r, endSession, http.StatusSeeOther)
+			return
+		}
+	}
+
 	redirectToLogin(w, r)
 }
 
 func redirectToLogin(w http.ResponseWriter, r *http.Request) {
 	applog.Debug(r.Context(), "redirecting to login", "htmx", isHTMX(r))
 	if isHTMX(r) {
-		w.Header().Set("HX-Redirect", "/login")
+		w.Header().Set("HX-Redirect", pages.Path("/login"))
 		w.WriteHeader(http.StatusSeeOther)
 		return
 	}
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	http.Redirect(w, r, pages.Path("/login"), http.StatusSeeOther)
 }
 
 func redirectToApp(w http.ResponseWriter, r *http.Request) {
 	applog.Debug(r.Context(), "redirecting to app", "htmx", isHTMX(r))
 	if isHTMX(r) {
-		w.Header().Set("HX-Redirect", "/app")
+		w.Header().Set("HX-Redirect", pages.Path("/app"))
 		w.WriteHeader(http.StatusSeeOther)
 		return
 	}
-	http.Redirect(w, r, "/app", http.StatusSeeOther)
+	http.Redirect(w, r, pages.Path("/app"), http.StatusSeeOther)
 }
 
 // ActiveSession returns true when the current request has an authenticated session.
@@ -203,3 +330,10 @@ func ActiveSession(r *http.Request) bool {
 	}
 	return sessionManager.GetBool(r.Context(), sessionAuthenticatedKey) && sessionManager.GetInt(r.Context(), sessionUserIDKey) > 0
 }
+
+func currentUserIsAdmin(r *http.Request) bool {
+	if !ActiveSession(r) {
+		return false
+	}
+	return sessionManager.GetString(r.Context(), sessionUserRoleKey) == models.RoleAdmin
+}
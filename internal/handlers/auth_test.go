@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -158,6 +159,81 @@ func TestEstablishSession(t *testing.T) {
 	}
 }
 
+func TestRequireAuthenticationEndsDeactivatedSessions(t *testing.T) {
+	db, cleanupDB := withTestDatabase(t)
+	t.Cleanup(cleanupDB)
+	sm, cleanup := withTestSessionManager(t)
+	t.Cleanup(cleanup)
+
+	user := models.User{Email: "deprovisioned@example.com", PasswordHash: "x", Role: models.RoleMember}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/app", nil)
+	ctx, err := sm.Load(req.Context(), "")
+	if err != nil {
+		t.Fatalf("failed to load session context: %v", err)
+	}
+	req = req.WithContext(ctx)
+	if err := establishSession(req, &user); err != nil {
+		t.Fatalf("establishSession returned error: %v", err)
+	}
+
+	handler := RequireAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected active account to pass, got %d", rec.Code)
+	}
+
+	if err := db.Model(&user).Update("deactivated_at", time.Now()).Error; err != nil {
+		t.Fatalf("deactivate user: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login" {
+		t.Fatalf("expected redirect to login, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if ActiveSession(req) {
+		t.Fatal("expected the deactivated account's session to be destroyed")
+	}
+}
+
+func TestRequireAuthenticationRefreshesSessionRole(t *testing.T) {
+	db, cleanupDB := withTestDatabase(t)
+	t.Cleanup(cleanupDB)
+	sm, cleanup := withTestSessionManager(t)
+	t.Cleanup(cleanup)
+
+	user := models.User{Email: "demoted@example.com", PasswordHash: "x", Role: models.RoleAdmin}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/app", nil)
+	ctx, err := sm.Load(req.Context(), "")
+	if err != nil {
+		t.Fatalf("failed to load session context: %v", err)
+	}
+	req = req.WithContext(ctx)
+	if err := establishSession(req, &user); err != nil {
+		t.Fatalf("establishSession returned error: %v", err)
+	}
+	if err := db.Model(&user).Update("role", models.RoleMember).Error; err != nil {
+		t.Fatalf("demote user: %v", err)
+	}
+
+	handler := RequireAuthentication(RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the demotion to apply to the session, got %d", rec.Code)
+	}
+}
+
 func TestEstablishSessionWithoutManager(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/app", nil)
 	if err := establishSession(req, &models.User{}); err == nil {
@@ -254,6 +330,17 @@ func TestAuthenticate(t *testing.T) {
 	if message := sm.PopString(req.Context(), sessionLoginMessageKey); message == "" {
 		t.Fatal("expected login failure message to be set")
 	}
+	deactivated := time.Now()
+	if err := database.Model(&models.User{}).Where("email = ?", "user@example.com").Update("deactivated_at", &deactivated).Error; err != nil {
+		t.Fatalf("failed to deactivate user: %v", err)
+	}
+	w = httptest.NewRecorder()
+	if ok := authenticate(w, req, "user@example.com", "password123"); ok {
+		t.Fatal("expected authentication failure for a deactivated account")
+	}
+	if message := sm.PopString(req.Context(), sessionLoginMessageKey); message != accountDeactivatedMessage {
+		t.Fatalf("expected deactivation message, got %q", message)
+	}
 }
 
 func TestRedirectToLogin(t *testing.T) {
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/storage"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	// avatarSize is the edge length, in pixels, of stored avatars.
+	avatarSize = 256
+	// maxAvatarUpload caps the uploaded file size.
+	maxAvatarUpload = 5 << 20
+	// maxAvatarEdge caps the width and height of an uploaded image so a
+	// small file cannot decode into a huge bitmap.
+	maxAvatarEdge = 6000
+)
+
+var fileStore storage.Store
+
+// ConfigureStorage installs the object store used for uploads. A nil store
+// disables avatar uploads.
+func ConfigureStorage(store storage.Store) {
+	fileStore = store
+	applog.Debug(nil, "file storage configured", "enabled", store != nil)
+}
+
+// AvatarUpload crops the uploaded image to a square, scales it to
+// avatarSize and stores it as the current user's avatar. The crop square is
+// given in source pixels by crop_x, crop_y and crop_size; without it the
+// largest centred square is used.
+func AvatarUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil || fileStore == nil {
+		http.Error(w, "avatar uploads are not available", http.StatusServiceUnavailable)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+	profile := loadUserProfile(ctx, userID)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarUpload+(64<<10))
+	if err := r.ParseMultipartForm(maxAvatarUpload); err != nil {
+		renderAvatarControl(w, r, http.StatusBadRequest, profile, "Choose an image of at most 5 MB.")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		renderAvatarControl(w, r, http.StatusBadRequest, profile, "Choose an image to upload.")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		renderAvatarControl(w, r, http.StatusBadRequest, profile, "The image could not be read.")
+		return
+	}
+
+	src, problem := decodeAvatar(data)
+	if problem != "" {
+		renderAvatarControl(w, r, http.StatusBadRequest, profile, problem)
+		return
+	}
+	crop, problem := avatarCrop(src.Bounds(), r.FormValue("crop_x"), r.FormValue("crop_y"), r.FormValue("crop_size"))
+	if problem != "" {
+		renderAvatarControl(w, r, http.StatusBadRequest, profile, problem)
+		return
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, cropAvatar(src, crop, avatarSize)); err != nil {
+		applog.Error(ctx, "failed to encode avatar", "error", err, "userID", userID)
+		http.Error(w, "unable to save avatar", http.StatusInternalServerError)
+		return
+	}
+
+	if err := checkStorageQuota(ctx, profile, profile.AvatarKey, int64(encoded.Len())); err != nil {
+		message, ok := quotaMessage(err)
+		if !ok {
+			applog.Error(ctx, "failed to check storage quota", "error", err, "userID", userID)
+			http.Error(w, "unable to save avatar", http.StatusInternalServerError)
+			return
+		}
+		renderAvatarControl(w, r, http.StatusRequestEntityTooLarge, profile, message)
+		return
+	}
+
+	key, err := newAvatarKey(userID)
+	if err != nil {
+		applog.Error(ctx, "failed to generate avatar key", "error", err)
+		http.Error(w, "unable to save avatar", http.StatusInternalServerError)
+		return
+	}
+	if err := fileStore.Put(ctx, key, encoded.Bytes()); err != nil {
+		applog.Error(ctx, "failed to store avatar", "error", err, "userID", userID)
+		http.Error(w, "unable to save avatar", http.StatusInternalServerError)
+		return
+	}
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("avatar_key", key).Error; err != nil {
+		applog.Error(ctx, "failed to save avatar key", "error", err, "userID", userID)
+		_ = fileStore.Delete(ctx, key)
+		http.Error(w, "unable to save avatar", http.StatusInternalServerError)
+		return
+	}
+	removeAvatar(ctx, profile.AvatarKey)
+	applog.Debug(ctx, "avatar updated", "userID", userID, "key", key)
+
+	profile.AvatarKey = key
+	if !isHTMX(r) {
+		http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+		return
+	}
+	renderAvatarControl(w, r, http.StatusOK, profile, "Avatar saved.")
+}
+
+// AvatarDelete removes the current user's avatar, returning the header to
+// their initials.
+func AvatarDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+	profile := loadUserProfile(ctx, userID)
+
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("avatar_key", "").Error; err != nil {
+		applog.Error(ctx, "failed to clear avatar", "error", err, "userID", userID)
+		http.Error(w, "unable to remove avatar", http.StatusInternalServerError)
+		return
+	}
+	removeAvatar(ctx, profile.AvatarKey)
+
+	profile.AvatarKey = ""
+	if !isHTMX(r) {
+		http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+		return
+	}
+	renderAvatarControl(w, r, http.StatusOK, profile, "Avatar removed.")
+}
+
+// Avatar serves a user's avatar to signed-in users.
+func Avatar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil || fileStore == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	id := pages.ParseUint(r.URL.Query().Get("id"))
+	profile := loadUserProfile(r.Context(), id)
+	if id == 0 || !profile.HasAvatar() {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := fileStore.Get(r.Context(), profile.AvatarKey)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			applog.Error(r.Context(), "failed to load avatar", "error", err, "userID", id)
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	// Each upload gets a new key, and the URL carries it.
+	w.Header().Set("Cache-Control", "private, max-age=604800, immutable")
+	_, _ = w.Write(data)
+}
+
+// loadUserProfile reads the name, email and avatar of a user. A missing
+// user yields a profile with only the ID set.
+func loadUserProfile(ctx context.Context, userID uint) pages.UserProfile {
+	profile := pages.UserProfile{ID: userID, UploadsEnabled: fileStore != nil, LandingSection: pages.DefaultWorkspaceSection()}
+	if database == nil || userID == 0 {
+		return profile
+	}
+	var user models.User
+	if err := database.WithContext(ctx).Select("id", "name", "email", "avatar_key", "feed_token_hash", "calendar_token_hash", "landing_section", "weekly_digest").First(&user, userID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			applog.Error(ctx, "failed to load user profile", "error", err, "userID", userID)
+		}
+		return profile
+	}
+	profile.Name = user.Name
+	profile.Email = user.Email
+	profile.AvatarKey = user.AvatarKey
+	profile.FeedEnabled = user.FeedTokenHash != ""
+	profile.CalendarEnabled = user.CalendarTokenHash != ""
+	profile.LandingSection = pages.NormalizeLandingSection(user.LandingSection)
+	profile.WeeklyDigest = user.WeeklyDigest
+	return profile
+}
+
+func renderAvatarControl(w http.ResponseWriter, r *http.Request, status int, profile pages.UserProfile, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.AvatarControl(profile, message).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render avatar control", "error", err)
+	}
+}
+
+func removeAvatar(ctx context.Context, key string) {
+	if key == "" || fileStore == nil {
+		return
+	}
+	if err := fileStore.Delete(ctx, key); err != nil {
+		applog.Error(ctx, "failed to delete previous avatar", "error", err, "key", key)
+	}
+}
+
+func newAvatarKey(userID uint) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("avatars/%d-%s.png", userID, hex.EncodeToString(buf)), nil
+}
+
+// decodeAvatar decodes a PNG, JPEG or GIF upload after checking its
+// dimensions, or returns a message explaining why it cannot be used.
+func decodeAvatar(data []byte) (image.Image, string) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "Upload a PNG, JPEG or GIF image."
+	}
+	if cfg.Width > maxAvatarEdge || cfg.Height > maxAvatarEdge {
+		return nil, fmt.Sprintf("Images may be at most %d pixels wide and high.", maxAvatarEdge)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "The image could not be decoded."
+	}
+	return img, ""
+}
+
+// avatarCrop resolves the requested crop square within bounds. Blank values
+// select the largest centred square.
+func avatarCrop(bounds image.Rectangle, rawX, rawY, rawSize string) (image.Rectangle, string) {
+	edge := min(bounds.Dx(), bounds.Dy())
+	if edge <= 0 {
+		return image.Rectangle{}, "The image is empty."
+	}
+	rawX, rawY, rawSize = strings.TrimSpace(rawX), strings.TrimSpace(rawY), strings.TrimSpace(rawSize)
+	if rawX == "" && rawY == "" && rawSize == "" {
+		x := bounds.Min.X + (bounds.Dx()-edge)/2
+		y := bounds.Min.Y + (bounds.Dy()-edge)/2
+		return image.Rect(x, y, x+edge, y+edge), ""
+	}
+
+	x, errX := strconv.Atoi(rawX)
+	y, errY := strconv.Atoi(rawY)
+	size, errSize := strconv.Atoi(rawSize)
+	if errX != nil || errY != nil || errSize != nil || x < 0 || y < 0 || size <= 0 {
+		return image.Rectangle{}, "Give the crop position and size as whole pixels, or leave them blank."
+	}
+	crop := image.Rect(x, y, x+size, y+size).Add(bounds.Min)
+	if !crop.In(bounds) {
+		return image.Rectangle{}, fmt.Sprintf("The crop square must fit inside the %d×%d image.", bounds.Dx(), bounds.Dy())
+	}
+	return crop, ""
+}
+
+// cropAvatar scales the square crop of src to size×size, averaging the
+// source pixels that fall into each target pixel.
+func cropAvatar(src image.Image, crop image.Rectangle, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	scale := float64(crop.Dx()) / float64(size)
+	for dy := 0; dy < size; dy++ {
+		y0 := crop.Min.Y + int(float64(dy)*scale)
+		y1 := max(crop.Min.Y+int(float64(dy+1)*scale), y0+1)
+		for dx := 0; dx < size; dx++ {
+			x0 := crop.Min.X + int(float64(dx)*scale)
+			x1 := max(crop.Min.X+int(float64(dx+1)*scale), x0+1)
+			var r, g, b, a, n uint64
+			for y := y0; y < y1 && y < crop.Max.Y; y++ {
+				for x := x0; x < x1 && x < crop.Max.X; x++ {
+					cr, cg, cb, ca := src.At(x, y).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.Set(dx, dy, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+
+	"perfugo/internal/storage"
+	"perfugo/models"
+)
+
+func avatarUploadRequest(t *testing.T, sm *scs.SessionManager, img image.Image, fields map[string]string, userID int) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if err := png.Encode(part, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatalf("write field: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/app/preferences/avatar", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("HX-Request", "true")
+	ctx, err := sm.Load(req.Context(), "")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	req = req.WithContext(ctx)
+	sm.Put(req.Context(), sessionAuthenticatedKey, true)
+	sm.Put(req.Context(), sessionUserIDKey, userID)
+	return req
+}
+
+func TestAvatarUploadCropsAndServes(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	store, err := storage.NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFilesystem: %v", err)
+	}
+	prevStore := fileStore
+	ConfigureStorage(store)
+	t.Cleanup(func() { fileStore = prevStore })
+
+	user := models.User{Email: "ada@example.com", PasswordHash: "x", Name: "Ada Lovelace"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// A 400×200 image: red on the left half, blue on the right.
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 200 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	AvatarUpload(rec, avatarUploadRequest(t, sm, src, map[string]string{"crop_x": "300", "crop_y": "0", "crop_size": "150"}, int(user.ID)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("crop outside the image status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	AvatarUpload(rec, avatarUploadRequest(t, sm, src, map[string]string{"crop_x": "0", "crop_y": "0", "crop_size": "200"}, int(user.ID)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	if err := db.First(&user, user.ID).Error; err != nil {
+		t.Fatalf("reload user: %v", err)
+	}
+	if user.AvatarKey == "" {
+		t.Fatalf("avatar key not saved")
+	}
+	firstKey := user.AvatarKey
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/app/avatar?id=%d", user.ID), nil)
+	rec = httptest.NewRecorder()
+	Avatar(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("serve status = %d, type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	avatar, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("decode served avatar: %v", err)
+	}
+	if avatar.Bounds().Dx() != avatarSize || avatar.Bounds().Dy() != avatarSize {
+		t.Fatalf("avatar is %v, want %dpx square", avatar.Bounds(), avatarSize)
+	}
+	if r, _, b, _ := avatar.At(avatarSize-1, avatarSize-1).RGBA(); r>>8 != 255 || b != 0 {
+		t.Fatalf("left crop should be red, got r=%d b=%d", r>>8, b>>8)
+	}
+
+	// A second upload replaces the stored object.
+	rec = httptest.NewRecorder()
+	AvatarUpload(rec, avatarUploadRequest(t, sm, src, nil, int(user.ID)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("second upload status = %d", rec.Code)
+	}
+	if _, err := store.Get(context.Background(), firstKey); err != storage.ErrNotFound {
+		t.Fatalf("previous avatar still stored: %v", err)
+	}
+}
+
+func TestAvatarCropDefaultsToCentredSquare(t *testing.T) {
+	t.Parallel()
+
+	crop, problem := avatarCrop(image.Rect(0, 0, 300, 100), "", "", "")
+	if problem != "" {
+		t.Fatalf("unexpected problem %q", problem)
+	}
+	if want := image.Rect(100, 0, 200, 100); crop != want {
+		t.Fatalf("crop = %v, want %v", crop, want)
+	}
+}
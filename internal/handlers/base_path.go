@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/layout"
+)
+
+// ConfigureBasePath sets the prefix, such as "/perfugo", that links and
+// redirects carry when perfugo is served below the root of its host. The
+// router strips it before requests reach the handlers.
+func ConfigureBasePath(basePath string) {
+	layout.SetBasePath(basePath)
+	applog.Debug(nil, "base path configured", "basePath", layout.BasePath())
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"perfugo/internal/labels"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// Label kinds printed for a production batch: one for the batch container
+// and one for each bottle filled from it.
+const (
+	batchLabelKindBatch  = "batch"
+	batchLabelKindBottle = "bottle"
+)
+
+var labelPrinter *labels.Printer
+
+// ConfigureLabelPrinter sets the network printer batch labels are sent to.
+// Labels can only be downloaded while it is nil.
+func ConfigureLabelPrinter(printer *labels.Printer) {
+	labelPrinter = printer
+	applog.Debug(nil, "label printer configured", "enabled", printer != nil)
+}
+
+func labelPrinterLanguage() string {
+	if labelPrinter == nil {
+		return ""
+	}
+	return labelPrinter.Language()
+}
+
+// BatchLabels downloads a batch's labels as a ZPL or EPL job, for sending to
+// a thermal printer by hand or through the printer's own tools.
+func BatchLabels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	language := strings.ToLower(strings.TrimSpace(query.Get("format")))
+	if language == "" {
+		language = labels.LanguageZPL
+	}
+	if !labels.ValidLanguage(language) {
+		http.Error(w, "Choose ZPL or EPL.", http.StatusBadRequest)
+		return
+	}
+	kind, copies, problem := parseBatchLabelRequest(query.Get("kind"), query.Get("copies"))
+	if problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
+		return
+	}
+
+	batch, ok := requireProductionBatch(w, r, query.Get("id"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", labels.ContentType(language))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.%s", batch.LotNumber, kind, language)))
+	if err := labels.Render(w, language, []labels.Label{batchLabel(batch, kind)}, copies); err != nil {
+		applog.Error(r.Context(), "failed to render batch labels", "error", err, "batchID", batch.ID)
+	}
+}
+
+// BatchLabelsPrint sends a batch's labels straight to the configured label
+// printer and reports the outcome on the weighing page.
+func BatchLabelsPrint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid submission.", http.StatusBadRequest)
+		return
+	}
+
+	batch, ok := requireProductionBatch(w, r, r.FormValue("batch_id"))
+	if !ok {
+		return
+	}
+	if labelPrinter == nil {
+		renderProductionBatch(w, r, http.StatusServiceUnavailable, pages.ProductionBatchPage{Batch: batch, Message: "No label printer is configured. Download the labels instead."})
+		return
+	}
+	kind, copies, problem := parseBatchLabelRequest(r.FormValue("kind"), r.FormValue("copies"))
+	if problem != "" {
+		renderProductionBatch(w, r, http.StatusUnprocessableEntity, pages.ProductionBatchPage{Batch: batch, Message: problem})
+		return
+	}
+
+	if err := labelPrinter.Print(r.Context(), []labels.Label{batchLabel(batch, kind)}, copies); err != nil {
+		applog.Error(r.Context(), "failed to print batch labels", "error", err, "batchID", batch.ID)
+		renderProductionBatch(w, r, http.StatusBadGateway, pages.ProductionBatchPage{Batch: batch, Message: "The label printer could not be reached. Check that it is on and try again."})
+		return
+	}
+	applog.Info(r.Context(), "batch labels printed", "batchID", batch.ID, "kind", kind, "copies", copies)
+	renderProductionBatch(w, r, http.StatusOK, pages.ProductionBatchPage{Batch: batch, Message: fmt.Sprintf("Sent %d %s label(s) to the printer.", copies, kind)})
+}
+
+// parseBatchLabelRequest reads the label kind and copy count, returning a
+// message when either is unusable.
+func parseBatchLabelRequest(rawKind, rawCopies string) (string, int, string) {
+	kind := strings.ToLower(strings.TrimSpace(rawKind))
+	if kind == "" {
+		kind = batchLabelKindBatch
+	}
+	if kind != batchLabelKindBatch && kind != batchLabelKindBottle {
+		return "", 0, "Choose batch or bottle labels."
+	}
+	copies := 1
+	if raw := strings.TrimSpace(rawCopies); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > labels.MaxCopies {
+			return "", 0, fmt.Sprintf("Copies must be a whole number between 1 and %d.", labels.MaxCopies)
+		}
+		copies = value
+	}
+	return kind, copies, ""
+}
+
+// batchLabel lays out the label of kind for batch. Both carry the lot number
+// as a barcode; the batch label adds the version and quantity made.
+func batchLabel(batch models.ProductionBatch, kind string) labels.Label {
+	made := batch.CreatedAt
+	if batch.FinalizedAt != nil {
+		made = *batch.FinalizedAt
+	}
+	label := labels.Label{Title: batch.FormulaName, Barcode: batch.LotNumber}
+	if kind == batchLabelKindBottle {
+		label.Lines = []string{
+			"Lot " + batch.LotNumber,
+			"Filled " + pages.FormatReportDate(made),
+		}
+		return label
+	}
+	label.Lines = []string{
+		fmt.Sprintf("v%d / Lot %s", batch.FormulaVersion, batch.LotNumber),
+		fmt.Sprintf("%s g / %s", strconv.FormatFloat(batch.TargetQuantity/1000, 'f', -1, 64), pages.FormatReportDate(made)),
+	}
+	if !batch.Finalized() {
+		label.Lines = append(label.Lines, "In progress")
+	}
+	return label
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/internal/labels"
+	"perfugo/models"
+)
+
+func TestBatchLabelsDownloadAndPrint(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.ProductionBatch{}, &models.ProductionBatchLine{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	batch := models.ProductionBatch{FormulaID: 1, FormulaName: "Dew", FormulaVersion: 2, OwnerID: 7, LotNumber: "PERF-20250301-002", TargetQuantity: 50000, Status: models.ProductionBatchOpen}
+	if err := db.Create(&batch).Error; err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	batchID := strconv.Itoa(int(batch.ID))
+
+	req := authenticatedFormRequest(t, sm, "/app/production/batch/labels?id="+batchID+"&kind=batch&format=epl&copies=2", nil, 7)
+	req.Method = http.MethodGet
+	rec := httptest.NewRecorder()
+	BatchLabels(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != labels.ContentType(labels.LanguageEPL) {
+		t.Fatalf("download status = %d, headers %v", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "PERF-20250301-002-batch.epl") {
+		t.Fatalf("unexpected filename: %q", rec.Header().Get("Content-Disposition"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`"Dew"`, `"v2 / Lot PERF-20250301-002"`, `"50 g / `, `"In progress"`, `N,"PERF-20250301-002"`, "\nP2\n"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("EPL job missing %q:\n%s", want, body)
+		}
+	}
+
+	req = authenticatedFormRequest(t, sm, "/app/production/batch/labels?id="+batchID, nil, 8)
+	req.Method = http.MethodGet
+	rec = httptest.NewRecorder()
+	BatchLabels(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("another user's batch: status = %d", rec.Code)
+	}
+
+	send := func(form url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		BatchLabelsPrint(rec, authenticatedFormRequest(t, sm, "/app/production/batch/labels/print", form, 7))
+		return rec
+	}
+	ConfigureLabelPrinter(nil)
+	if rec := send(url.Values{"batch_id": {batchID}}); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("print without a printer: status = %d", rec.Code)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	jobs := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			jobs <- ""
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		jobs <- string(data)
+	}()
+	printer, err := labels.NewPrinter(listener.Addr().String(), labels.LanguageZPL)
+	if err != nil {
+		t.Fatalf("new printer: %v", err)
+	}
+	ConfigureLabelPrinter(printer)
+	t.Cleanup(func() { ConfigureLabelPrinter(nil) })
+
+	if rec := send(url.Values{"batch_id": {batchID}, "kind": {"bottle"}, "copies": {"0"}}); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("zero copies: status = %d", rec.Code)
+	}
+	rec = send(url.Values{"batch_id": {batchID}, "kind": {"bottle"}, "copies": {"12"}})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Sent 12 bottle label(s)") {
+		t.Fatalf("print status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	job := <-jobs
+	if !strings.Contains(job, "^FDLot PERF-20250301-002^FS") || !strings.Contains(job, "^PQ12") {
+		t.Fatalf("unexpected ZPL job: %q", job)
+	}
+}
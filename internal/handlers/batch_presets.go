@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// BatchPresetSave stores the batch report's current target quantity as one
+// of the user's presets. A blank label is filled from the quantity.
+func BatchPresetSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	quantity, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("target_quantity")), 64)
+	if err != nil || quantity <= 0 {
+		renderBatchPresets(w, r, userID, "Enter a target quantity to save it as a preset.")
+		return
+	}
+	label := strings.TrimSpace(r.FormValue("preset_label"))
+	if label == "" {
+		label = pages.PresetQuantityLabel(quantity)
+	}
+	if len([]rune(label)) > 60 {
+		renderBatchPresets(w, r, userID, "Keep preset names to 60 characters.")
+		return
+	}
+
+	var count int64
+	if err := database.WithContext(ctx).Model(&models.BatchPreset{}).Where("owner_id = ?", userID).Count(&count).Error; err != nil {
+		applog.Error(ctx, "failed to count batch presets", "error", err, "userID", userID)
+		http.Error(w, "unable to save preset", http.StatusInternalServerError)
+		return
+	}
+	if count >= models.MaxBatchPresets {
+		renderBatchPresets(w, r, userID, fmt.Sprintf("You can keep up to %d presets. Remove one first.", models.MaxBatchPresets))
+		return
+	}
+
+	preset := models.BatchPreset{OwnerID: userID, Label: label, QuantityMg: quantity}
+	if err := database.WithContext(ctx).Create(&preset).Error; err != nil {
+		applog.Error(ctx, "failed to save batch preset", "error", err, "userID", userID)
+		http.Error(w, "unable to save preset", http.StatusInternalServerError)
+		return
+	}
+	renderBatchPresets(w, r, userID, "Saved "+label+".")
+}
+
+// BatchPresetDelete removes one of the current user's presets.
+func BatchPresetDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := database.WithContext(ctx).
+		Where("id = ? AND owner_id = ?", pages.ParseUint(r.FormValue("id")), userID).
+		Delete(&models.BatchPreset{}).Error; err != nil {
+		applog.Error(ctx, "failed to delete batch preset", "error", err, "userID", userID)
+		http.Error(w, "unable to delete preset", http.StatusInternalServerError)
+		return
+	}
+	renderBatchPresets(w, r, userID, "")
+}
+
+func renderBatchPresets(w http.ResponseWriter, r *http.Request, userID uint, message string) {
+	renderComponent(w, r, pages.BatchPresetBar(loadBatchPresets(r.Context(), userID), message))
+}
+
+// loadBatchPresets lists the owner's presets from smallest to largest.
+func loadBatchPresets(ctx context.Context, ownerID uint) []models.BatchPreset {
+	var presets []models.BatchPreset
+	if database == nil || ownerID == 0 {
+		return presets
+	}
+	if err := database.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Order("quantity_mg asc, id asc").
+		Find(&presets).Error; err != nil {
+		applog.Error(ctx, "failed to load batch presets", "error", err, "userID", ownerID)
+	}
+	return presets
+}
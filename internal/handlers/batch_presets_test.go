@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBatchPresetLifecycle(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.BatchPreset{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	post := func(handler http.HandlerFunc, path string, form url.Values, userID int) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, authenticatedFormRequest(t, sm, path, form, userID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	if body := post(BatchPresetSave, "/app/reports/batch-presets", url.Values{"target_quantity": {"10000"}}, 7); !strings.Contains(body, "Saved 10 g.") {
+		t.Fatalf("expected a label from the quantity, got %q", body)
+	}
+	body := post(BatchPresetSave, "/app/reports/batch-presets", url.Values{"target_quantity": {"1000000"}, "preset_label": {"Production"}}, 7)
+	if !strings.Contains(body, `data-fill-value="1000000"`) || strings.Index(body, "10 g") > strings.Index(body, "Production") {
+		t.Fatalf("expected presets ordered by quantity, got %q", body)
+	}
+	if body := post(BatchPresetSave, "/app/reports/batch-presets", url.Values{"target_quantity": {"0"}}, 7); !strings.Contains(body, "Enter a target quantity") {
+		t.Fatalf("expected a validation message, got %q", body)
+	}
+
+	var production models.BatchPreset
+	db.Where("label = ?", "Production").First(&production)
+	post(BatchPresetDelete, "/app/reports/batch-presets/delete", url.Values{"id": {strconv.Itoa(int(production.ID))}}, 8)
+	if loaded := loadBatchPresets(t.Context(), 7); len(loaded) != 2 {
+		t.Fatalf("another user must not delete presets, got %+v", loaded)
+	}
+	post(BatchPresetDelete, "/app/reports/batch-presets/delete", url.Values{"id": {strconv.Itoa(int(production.ID))}}, 7)
+	if loaded := loadBatchPresets(t.Context(), 7); len(loaded) != 1 || loaded[0].Label != "10 g" {
+		t.Fatalf("unexpected presets after delete: %+v", loaded)
+	}
+
+	for i := 1; i < models.MaxBatchPresets; i++ {
+		post(BatchPresetSave, "/app/reports/batch-presets", url.Values{"target_quantity": {strconv.Itoa(i * 500)}}, 7)
+	}
+	if body := post(BatchPresetSave, "/app/reports/batch-presets", url.Values{"target_quantity": {"42"}}, 7); !strings.Contains(body, "You can keep up to 8 presets.") {
+		t.Fatalf("expected the preset cap to apply, got %q", body)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"perfugo/internal/analytics"
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// maxBatchReportBody caps the JSON accepted by BatchReportAPI.
+const maxBatchReportBody = 1 << 16
+
+// batchReportRequest is the body accepted by BatchReportAPI. The preset and
+// basis take the same values as the batch report form.
+type batchReportRequest struct {
+	FormulaID           uint    `json:"formula_id"`
+	TargetQuantity      float64 `json:"target_quantity"`
+	ConcentrationPreset string  `json:"concentration_preset,omitempty"`
+	AlcoholBasis        string  `json:"alcohol_basis,omitempty"`
+}
+
+// batchReportLine is one weigh-out line. Dilution names the stock solution a
+// line is weighed from; NeatQuantity is the neat chemical it contains.
+type batchReportLine struct {
+	Order          int     `json:"order"`
+	ChemicalID     uint    `json:"chemical_id,omitempty"`
+	IngredientName string  `json:"ingredient_name"`
+	CASNumber      string  `json:"cas_number,omitempty"`
+	Pyramid        string  `json:"pyramid,omitempty"`
+	BaseQuantity   float64 `json:"base_quantity"`
+	Quantity       float64 `json:"quantity"`
+	Dilution       string  `json:"dilution,omitempty"`
+	NeatQuantity   float64 `json:"neat_quantity"`
+	Unit           string  `json:"unit"`
+	Solvent        bool    `json:"solvent,omitempty"`
+}
+
+// batchReportFinish describes how a finished-product batch is diluted.
+type batchReportFinish struct {
+	Label               string  `json:"label"`
+	ConcentratePercent  float64 `json:"concentrate_percent"`
+	ByVolume            bool    `json:"by_volume"`
+	ConcentrateQuantity float64 `json:"concentrate_quantity"`
+	SolventName         string  `json:"solvent_name"`
+	SolventQuantity     float64 `json:"solvent_quantity"`
+}
+
+// batchReportResponse is the computed batch returned by BatchReportAPI.
+type batchReportResponse struct {
+	FormulaID         uint               `json:"formula_id"`
+	FormulaName       string             `json:"formula_name"`
+	FormulaVersion    int                `json:"formula_version"`
+	TargetQuantity    float64            `json:"target_quantity"`
+	TargetUnit        string             `json:"target_unit"`
+	BaseBatchQuantity float64            `json:"base_batch_quantity"`
+	BaseBatchUnit     string             `json:"base_batch_unit"`
+	ScaleFactor       float64            `json:"scale_factor"`
+	LotNumber         string             `json:"lot_number"`
+	RunDate           time.Time          `json:"run_date"`
+	Lines             []batchReportLine  `json:"lines"`
+	Finish            *batchReportFinish `json:"finish,omitempty"`
+}
+
+// BatchReportAPI handles POST /app/api/reports/batch. It computes the same
+// weigh-out list as the batch production report and returns it as JSON, so
+// lab automation can fetch quantities without parsing the HTML form.
+func BatchReportAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use POST to generate a batch report.")
+		return
+	}
+	// Generating a report stores nothing, so read-only tokens may POST.
+	if !requireAPIScope(w, r, models.ScopeReports, false) {
+		return
+	}
+
+	var body batchReportRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchReportBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "The body must be a JSON object describing the batch.")
+		return
+	}
+
+	var problems []fieldProblem
+	if body.FormulaID == 0 {
+		problems = append(problems, fieldProblem{Field: "formula_id", Message: "Select a formula before running the report."})
+	}
+	if body.TargetQuantity <= 0 {
+		problems = append(problems, fieldProblem{Field: "target_quantity", Message: "Provide a positive target quantity."})
+	}
+	if len(problems) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "The batch cannot be computed.", problems...)
+		return
+	}
+	finish, problem := batchFinishFor(r, body.ConcentrationPreset, body.AlcoholBasis)
+	if problem != "" {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "The batch cannot be computed.", fieldProblem{Field: "concentration_preset", Message: problem})
+		return
+	}
+
+	ctx, cancel := withDeadline(r, handlerTimeouts.Report)
+	defer cancel()
+	report, err := buildBatchProductionReportData(ctx, body.FormulaID, body.TargetQuantity, finish)
+	if err != nil {
+		if contextExpired(err) {
+			writeTimeout(w, r, nil)
+			return
+		}
+		status, message := batchReportFailure(r, err, body.FormulaID)
+		writeProblem(w, r, status, message)
+		return
+	}
+
+	recordActivity(r.Context(), models.ActivityEntityFormula, body.FormulaID, analytics.EventBatch)
+
+	response := batchReportResponse{
+		FormulaID:         body.FormulaID,
+		FormulaName:       report.FormulaName,
+		FormulaVersion:    report.FormulaVersion,
+		TargetQuantity:    report.TargetQuantity,
+		TargetUnit:        report.TargetUnit,
+		BaseBatchQuantity: report.BaseBatchQuantity,
+		BaseBatchUnit:     report.BaseBatchUnit,
+		ScaleFactor:       report.ScaleFactor,
+		LotNumber:         report.LotNumber,
+		RunDate:           report.RunDate,
+		Lines:             make([]batchReportLine, 0, len(report.Ingredients)),
+	}
+	for _, item := range report.Ingredients {
+		response.Lines = append(response.Lines, batchReportLine{
+			Order:          item.Order,
+			ChemicalID:     item.ChemicalID,
+			IngredientName: item.IngredientName,
+			CASNumber:      item.CASNumber,
+			Pyramid:        item.Pyramid,
+			BaseQuantity:   item.BaseQuantity,
+			Quantity:       item.FinalQuantity,
+			Dilution:       item.Dilution,
+			NeatQuantity:   item.NeatQuantity,
+			Unit:           item.Unit,
+			Solvent:        item.Solvent,
+		})
+	}
+	if report.ConcentratePercent > 0 {
+		response.Finish = &batchReportFinish{
+			Label:               report.ConcentrateLabel,
+			ConcentratePercent:  report.ConcentratePercent,
+			ByVolume:            report.ConcentrateByVolume,
+			ConcentrateQuantity: report.ConcentrateQuantity,
+			SolventName:         report.SolventName,
+			SolventQuantity:     report.SolventQuantity,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		applog.Error(r.Context(), "failed to encode batch report response", "error", err)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBatchReportAPI(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	hedione := models.AromaChemical{IngredientName: "Hedione", CASNumber: "24851-98-7", OwnerID: 7}
+	if err := db.Create(&hedione).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	formula := models.Formula{Name: "Fresh", Version: 3, IsLatest: true}
+	empty := models.Formula{Name: "Draft", Version: 1, IsLatest: true}
+	for _, f := range []*models.Formula{&formula, &empty} {
+		if err := db.Create(f).Error; err != nil {
+			t.Fatalf("create formula: %v", err)
+		}
+	}
+	if err := db.Create(&models.FormulaIngredient{FormulaID: formula.ID, AromaChemicalID: &hedione.ID, Amount: 2, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create ingredient: %v", err)
+	}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/app/api/reports/batch", nil, 7)
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		BatchReportAPI(rec, req)
+		return rec
+	}
+
+	rec := post(fmt.Sprintf(`{"formula_id": %d, "target_quantity": 10000, "concentration_preset": "edp"}`, formula.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var report batchReportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if report.FormulaName != "Fresh" || report.FormulaVersion != 3 || len(report.Lines) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if line := report.Lines[0]; line.IngredientName != "Hedione" || line.CASNumber != "24851-98-7" || line.Unit != "mg" || line.Quantity <= 0 {
+		t.Fatalf("unexpected weigh-out line: %+v", line)
+	}
+	if !report.Lines[1].Solvent || report.Finish == nil || report.Finish.ConcentratePercent != 18 {
+		t.Fatalf("expected a finished-product batch, got %+v", report)
+	}
+
+	for name, tc := range map[string]struct {
+		body   string
+		status int
+	}{
+		"unknown field":   {`{"formula": 1}`, http.StatusBadRequest},
+		"missing fields":  {`{}`, http.StatusUnprocessableEntity},
+		"unknown preset":  {fmt.Sprintf(`{"formula_id": %d, "target_quantity": 5, "concentration_preset": "cologne"}`, formula.ID), http.StatusUnprocessableEntity},
+		"missing formula": {`{"formula_id": 999, "target_quantity": 5}`, http.StatusNotFound},
+		"no ingredients":  {fmt.Sprintf(`{"formula_id": %d, "target_quantity": 5}`, empty.ID), http.StatusBadRequest},
+	} {
+		rec := post(tc.body)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", name, rec.Code, tc.status)
+		}
+		if got := rec.Header().Get("Content-Type"); got != problemContentType {
+			t.Errorf("%s: content type = %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// signatureSessionWindow is how recently a single sign-on user must have
+// signed in to sign a batch by confirming their session.
+const signatureSessionWindow = 15 * time.Minute
+
+// batchSignature is the electronic signature applied when a batch is
+// finalized.
+type batchSignature struct {
+	UserID uint
+	Name   string
+	Method string
+	At     time.Time
+}
+
+// verifyBatchSignature checks the signature submitted with a finalize
+// request. Accounts with a password sign by re-entering it, checked against
+// the directory when LDAP is configured. Single sign-on accounts have no
+// usable password and instead confirm a session started within
+// signatureSessionWindow. The returned problem explains a refusal.
+func verifyBatchSignature(r *http.Request) (batchSignature, string) {
+	var signature batchSignature
+	userID, ok := currentUserID(r)
+	if !ok {
+		return signature, "Sign in again to sign the batch."
+	}
+	var user models.User
+	if err := database.WithContext(r.Context()).First(&user, userID).Error; err != nil {
+		applog.Error(r.Context(), "failed to load signer", "error", err, "userID", userID)
+		return signature, "We couldn't verify your signature. Please try again."
+	}
+
+	signature = batchSignature{UserID: user.ID, Name: signerName(user), At: nowFunc().UTC()}
+	password := r.FormValue("signature_password")
+	switch {
+	case password != "":
+		if !signerPasswordMatches(r, user, password) {
+			applog.Info(r.Context(), "batch signature rejected", "userID", user.ID, "method", models.SignatureMethodPassword)
+			return signature, "The password is incorrect. The batch was not signed."
+		}
+		signature.Method = models.SignatureMethodPassword
+	case checkboxChecked(r.FormValue("signature_confirm")) && user.OIDCSubject != "":
+		signedInAt := time.Unix(sessionManager.GetInt64(r.Context(), sessionSignedInAtKey), 0)
+		if nowFunc().Sub(signedInAt) > signatureSessionWindow {
+			return signature, "Your sign-in is too old to sign with. Sign out and in again, then finalize the batch."
+		}
+		signature.Method = models.SignatureMethodSession
+	case user.OIDCSubject != "":
+		return signature, "Confirm your signature to finalize the batch."
+	default:
+		return signature, "Enter your password to sign the batch."
+	}
+	return signature, ""
+}
+
+func signerPasswordMatches(r *http.Request, user models.User, password string) bool {
+	if directory != nil {
+		_, err := directory.Authenticate(r.Context(), user.Email, password)
+		return err == nil
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// signerName is the name recorded on a signed batch.
+func signerName(user models.User) string {
+	if name := strings.TrimSpace(user.Name); name != "" {
+		return name
+	}
+	return user.Email
+}
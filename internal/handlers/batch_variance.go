@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// BatchVariance reports how the actual weights of a formula's finalized
+// batches compare with their targets, per ingredient and overall.
+func BatchVariance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	formulaID := pages.ParseUint(r.URL.Query().Get("formula_id"))
+	if formulaID == 0 {
+		http.Error(w, "Select a formula.", http.StatusBadRequest)
+		return
+	}
+
+	var batches []models.ProductionBatch
+	if err := database.WithContext(r.Context()).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("position asc") }).
+		Where("owner_id = ? AND formula_id = ? AND status = ?", userID, formulaID, models.ProductionBatchFinalized).
+		Order("created_at asc").
+		Find(&batches).Error; err != nil {
+		applog.Error(r.Context(), "failed to load batches for variance", "error", err, "formulaID", formulaID)
+		http.Error(w, "We were unable to load the batches. Please try again.", http.StatusInternalServerError)
+		return
+	}
+
+	report := pages.BuildBatchVariance(formulaID, batches)
+	if report.FormulaName == "" {
+		if formula := pages.FindFormula(buildWorkspaceSnapshot(r).Formulas, formulaID); formula != nil {
+			report.FormulaName = formula.Name
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.BatchVariance(report).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render batch variance", "error", err)
+	}
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// blindCodeSpace is the range of three-digit sample codes, 100 to 999.
+const blindCodeSpace = 900
+
+// BlindTests lists the user's blind tests on GET and starts a new one on
+// POST, giving every chosen formula a random sample code.
+func BlindTests(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	userID, _ := currentUserID(r)
+	render := func(message string) {
+		renderComponent(w, r, pages.BlindTestList(pages.BlindTestsPanel{
+			Tests:    loadBlindTests(r, userID),
+			Formulas: snapshot.Formulas,
+			Message:  message,
+		}))
+	}
+	if r.Method == http.MethodGet {
+		render("")
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		render("Name the blind test.")
+		return
+	}
+	var formulaIDs []uint
+	for _, raw := range r.Form["formula_id"] {
+		formula := pages.FindFormula(snapshot.Formulas, pages.ParseUint(raw))
+		if formula == nil {
+			render("Choose formulas from your library.")
+			return
+		}
+		if !slices.Contains(formulaIDs, formula.ID) {
+			formulaIDs = append(formulaIDs, formula.ID)
+		}
+	}
+	if len(formulaIDs) < pages.MinBlindSamples || len(formulaIDs) > pages.MaxBlindSamples {
+		render(fmt.Sprintf("Choose between %d and %d formulas.", pages.MinBlindSamples, pages.MaxBlindSamples))
+		return
+	}
+	if database == nil {
+		render(pages.DemoModeMessage)
+		return
+	}
+
+	codes, err := newBlindCodes(len(formulaIDs))
+	if err != nil {
+		applog.Error(r.Context(), "failed to generate blind sample codes", "error", err)
+		render("We couldn't start the blind test. Please try again.")
+		return
+	}
+	test := models.BlindTest{OwnerID: userID, Name: name}
+	for i, id := range formulaIDs {
+		test.Samples = append(test.Samples, models.BlindSample{FormulaID: id, Code: codes[i]})
+	}
+	ctx := r.Context()
+	if err := database.WithContext(ctx).Create(&test).Error; err != nil {
+		applog.Error(ctx, "failed to create blind test", "error", err)
+		render("We couldn't start the blind test. Please try again.")
+		return
+	}
+	applog.Info(ctx, "blind test created", "blindTestID", test.ID, "samples", len(test.Samples))
+
+	renderBlindTest(w, r, test.ID, "")
+}
+
+// BlindTestView shows a blind test: the sample codes and score form until
+// it is revealed, the ranked results afterwards.
+func BlindTestView(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	renderBlindTest(w, r, pages.ParseUint(r.URL.Query().Get("id")), "")
+}
+
+// BlindTestScore records one evaluator's scores for every sample. Scoring
+// again under the same name replaces the earlier scores.
+func BlindTestScore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	id := pages.ParseUint(r.FormValue("id"))
+	test, ok := findBlindTest(w, r, id)
+	if !ok {
+		return
+	}
+	if test.Revealed() {
+		renderBlindTest(w, r, id, "This blind test has been revealed and no longer takes scores.")
+		return
+	}
+	evaluator := strings.TrimSpace(r.FormValue("evaluator"))
+	if evaluator == "" || len(evaluator) > 80 {
+		renderBlindTest(w, r, id, "Enter the evaluator's name, up to 80 characters.")
+		return
+	}
+
+	scores := make([]models.BlindScore, 0, len(test.Samples))
+	for _, sample := range test.Samples {
+		raw := strings.TrimSpace(r.FormValue(fmt.Sprintf("score_%d", sample.ID)))
+		if raw == "" {
+			continue
+		}
+		score, err := strconv.Atoi(raw)
+		if err != nil || score < models.BlindScoreMin || score > models.BlindScoreMax {
+			renderBlindTest(w, r, id, fmt.Sprintf("Scores must be whole numbers from %d to %d.", models.BlindScoreMin, models.BlindScoreMax))
+			return
+		}
+		scores = append(scores, models.BlindScore{
+			BlindSampleID: sample.ID,
+			Evaluator:     evaluator,
+			Score:         score,
+			Comment:       strings.TrimSpace(r.FormValue(fmt.Sprintf("comment_%d", sample.ID))),
+		})
+	}
+	if len(scores) == 0 {
+		renderBlindTest(w, r, id, "Score at least one sample.")
+		return
+	}
+
+	sampleIDs := make([]uint, 0, len(test.Samples))
+	for _, sample := range test.Samples {
+		sampleIDs = append(sampleIDs, sample.ID)
+	}
+	ctx := r.Context()
+	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("blind_sample_id IN ? AND LOWER(evaluator) = ?", sampleIDs, strings.ToLower(evaluator)).
+			Delete(&models.BlindScore{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&scores).Error
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to record blind test scores", "error", err, "blindTestID", id)
+		renderBlindTest(w, r, id, "We couldn't save the scores. Please try again.")
+		return
+	}
+	applog.Info(ctx, "blind test scored", "blindTestID", id, "scores", len(scores))
+
+	renderBlindTest(w, r, id, fmt.Sprintf("Saved %s's scores.", evaluator))
+}
+
+// BlindTestReveal maps the sample codes back to their formulas. Scoring
+// closes once a test is revealed.
+func BlindTestReveal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	id := pages.ParseUint(r.FormValue("id"))
+	test, ok := findBlindTest(w, r, id)
+	if !ok {
+		return
+	}
+	if test.Revealed() {
+		renderBlindTest(w, r, id, "")
+		return
+	}
+	ctx := r.Context()
+	if err := database.WithContext(ctx).Model(&test).Update("revealed_at", nowFunc()).Error; err != nil {
+		applog.Error(ctx, "failed to reveal blind test", "error", err, "blindTestID", id)
+		renderBlindTest(w, r, id, "We couldn't reveal the blind test. Please try again.")
+		return
+	}
+	applog.Info(ctx, "blind test revealed", "blindTestID", id)
+
+	renderBlindTest(w, r, id, "")
+}
+
+// renderBlindTest loads a blind test with its samples and scores and renders
+// it. The formulas behind the codes are only loaded once it is revealed.
+func renderBlindTest(w http.ResponseWriter, r *http.Request, id uint, message string) {
+	test, ok := findBlindTest(w, r, id)
+	if !ok {
+		return
+	}
+	view := pages.BlindTestView{Test: test, Evaluators: pages.BlindEvaluators(test), Message: message}
+	if test.Revealed() {
+		view.Results = pages.BlindTestResults(test)
+	}
+	renderComponent(w, r, pages.BlindTestDetail(view))
+}
+
+// findBlindTest loads one of the user's blind tests, writing an error
+// response when it cannot.
+func findBlindTest(w http.ResponseWriter, r *http.Request, id uint) (models.BlindTest, bool) {
+	var test models.BlindTest
+	if database == nil {
+		writeNoDatabase(w, r)
+		return test, false
+	}
+	if id == 0 {
+		http.Error(w, "blind test not found", http.StatusNotFound)
+		return test, false
+	}
+	userID, _ := currentUserID(r)
+	err := database.WithContext(r.Context()).
+		Preload("Samples.Scores", func(db *gorm.DB) *gorm.DB { return db.Order("id asc") }).
+		Preload("Samples.Formula").
+		Where("owner_id = ?", userID).
+		First(&test, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "blind test not found", http.StatusNotFound)
+		return test, false
+	}
+	if err != nil {
+		applog.Error(r.Context(), "failed to load blind test", "error", err, "blindTestID", id)
+		http.Error(w, "failed to load blind test", http.StatusInternalServerError)
+		return test, false
+	}
+	if !test.Revealed() {
+		for i := range test.Samples {
+			test.Samples[i].Formula = nil
+		}
+	}
+	return test, true
+}
+
+// loadBlindTests lists the user's blind tests, newest first.
+func loadBlindTests(r *http.Request, userID uint) []models.BlindTest {
+	if database == nil {
+		return nil
+	}
+	var tests []models.BlindTest
+	if err := database.WithContext(r.Context()).Preload("Samples").
+		Where("owner_id = ?", userID).Order("created_at desc").Find(&tests).Error; err != nil {
+		applog.Error(r.Context(), "failed to load blind tests", "error", err)
+		return nil
+	}
+	return tests
+}
+
+// newBlindCodes draws n distinct random three-digit sample codes.
+func newBlindCodes(n int) ([]string, error) {
+	seen := make(map[int64]bool, n)
+	codes := make([]string, 0, n)
+	for len(codes) < n {
+		value, err := rand.Int(rand.Reader, big.NewInt(blindCodeSpace))
+		if err != nil {
+			return nil, err
+		}
+		code := value.Int64() + 100
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, strconv.FormatInt(code, 10))
+	}
+	return codes, nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBlindTestScoreAndReveal(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}, &models.BlindTest{}, &models.BlindSample{}, &models.BlindScore{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	first := models.Formula{Name: "Rose M01", Version: 1, IsLatest: true}
+	second := models.Formula{Name: "Rose M02", Version: 1, IsLatest: true}
+	for _, formula := range []*models.Formula{&first, &second} {
+		if err := db.Create(formula).Error; err != nil {
+			t.Fatalf("create formula: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	BlindTests(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/blind-tests", url.Values{
+		"name":       {"Rose round 1"},
+		"formula_id": {strconv.Itoa(int(first.ID)), strconv.Itoa(int(second.ID))},
+	}, 7))
+	if body := rec.Body.String(); !strings.Contains(body, "Rose round 1") || strings.Contains(body, "Rose M01") {
+		t.Fatalf("blind test should show codes only: %q", body)
+	}
+
+	var test models.BlindTest
+	if err := db.Preload("Samples").First(&test).Error; err != nil {
+		t.Fatalf("load blind test: %v", err)
+	}
+	if test.OwnerID != 7 || len(test.Samples) != 2 || test.Samples[0].Code == test.Samples[1].Code {
+		t.Fatalf("unexpected blind test: %+v", test)
+	}
+	scoreFor := func(formulaID uint) string {
+		if test.Samples[0].FormulaID == formulaID {
+			return "score_" + strconv.Itoa(int(test.Samples[0].ID))
+		}
+		return "score_" + strconv.Itoa(int(test.Samples[1].ID))
+	}
+	id := strconv.Itoa(int(test.ID))
+
+	for _, form := range []url.Values{
+		{"id": {id}, "evaluator": {"Ana"}, scoreFor(first.ID): {"3"}, scoreFor(second.ID): {"4"}},
+		{"id": {id}, "evaluator": {"ana"}, scoreFor(first.ID): {"6"}, scoreFor(second.ID): {"9"}},
+		{"id": {id}, "evaluator": {"Ben"}, scoreFor(first.ID): {"8"}, scoreFor(second.ID): {"11"}},
+		{"id": {id}, "evaluator": {"Ben"}, scoreFor(first.ID): {"8"}, scoreFor(second.ID): {"7"}},
+	} {
+		BlindTestScore(httptest.NewRecorder(), authenticatedFormRequest(t, sm, "/app/sections/formulas/blind-tests/score", form, 7))
+	}
+	var scores int64
+	db.Model(&models.BlindScore{}).Count(&scores)
+	if scores != 4 {
+		t.Fatalf("expected rescoring to replace scores and invalid scores to be rejected, got %d", scores)
+	}
+
+	rec = httptest.NewRecorder()
+	BlindTestReveal(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/blind-tests/reveal", url.Values{"id": {id}}, 8))
+	if rec.Code != 404 {
+		t.Fatalf("another user revealed the test: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	BlindTestReveal(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/blind-tests/reveal", url.Values{"id": {id}}, 7))
+	body := rec.Body.String()
+	top, runnerUp := strings.Index(body, "Rose M02"), strings.Index(body, "Rose M01")
+	if top < 0 || runnerUp < 0 || top > runnerUp || !strings.Contains(body, "8.0") || !strings.Contains(body, "7.0") {
+		t.Fatalf("unexpected reveal: %q", body)
+	}
+
+	rec = httptest.NewRecorder()
+	BlindTestScore(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/blind-tests/score", url.Values{
+		"id": {id}, "evaluator": {"Cleo"}, scoreFor(first.ID): {"5"},
+	}, 7))
+	if !strings.Contains(rec.Body.String(), "no longer takes scores") {
+		t.Fatalf("revealed test accepted scores: %q", rec.Body.String())
+	}
+}
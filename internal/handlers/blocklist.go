@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	// maxBlocklistUpload caps an imported blocklist dataset.
+	maxBlocklistUpload = 4 << 20
+	// blocklistManualSource marks entries added by hand.
+	blocklistManualSource = "manual"
+)
+
+// loadBlocklist returns every blocklist entry, or nil without a database.
+func loadBlocklist(ctx context.Context) models.Blocklist {
+	if database == nil {
+		return nil
+	}
+	var entries []models.BlockedMaterial
+	if err := database.WithContext(ctx).Order("name asc, cas_number asc").Find(&entries).Error; err != nil {
+		applog.Error(ctx, "failed to load blocklist", "error", err)
+		return nil
+	}
+	return entries
+}
+
+// blocklistHit is a blocklist entry matched by a material in use.
+type blocklistHit struct {
+	Chemical models.AromaChemical
+	Entry    models.BlockedMaterial
+}
+
+// blockedChemicals checks the chemicals with the given IDs against the
+// blocklist, returning prohibited and restricted hits separately.
+func blockedChemicals(list models.Blocklist, chemicals []models.AromaChemical, ids []uint) (prohibited, restricted []blocklistHit) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		chemical := pages.FindAromaChemical(chemicals, id)
+		if chemical == nil {
+			continue
+		}
+		entry, ok := list.Match(*chemical)
+		if !ok {
+			continue
+		}
+		hit := blocklistHit{Chemical: *chemical, Entry: entry}
+		if entry.Prohibited() {
+			prohibited = append(prohibited, hit)
+		} else {
+			restricted = append(restricted, hit)
+		}
+	}
+	return prohibited, restricted
+}
+
+// blocklistMessage explains hits in one sentence per material.
+func blocklistMessage(hits []blocklistHit) string {
+	parts := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		part := fmt.Sprintf("%s is %s", hit.Chemical.IngredientName, hit.Entry.Level)
+		if reason := strings.TrimSpace(hit.Entry.Reason); reason != "" {
+			part += ": " + strings.TrimSuffix(reason, ".")
+		}
+		parts = append(parts, part+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// constraintWarning names the materials, sub-formulas included, that break
+// the formula's naturals-only or vegan constraint.
+func constraintWarning(formula models.Formula, snapshot pages.WorkspaceSnapshot) string {
+	if !formula.HasConstraints() {
+		return ""
+	}
+	report := pages.BuildComplianceReport(formula, snapshot.FormulaIngredients, snapshot.AromaChemicals, nil)
+	if len(report.Constraints) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(report.Constraints))
+	for _, finding := range report.Constraints {
+		name := finding.Ingredient
+		if finding.Via != "" {
+			name += " via " + finding.Via
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", name, strings.Join(finding.Violations, ", ")))
+	}
+	return "Breaks the formula's constraints: " + strings.Join(parts, "; ") + "."
+}
+
+// BlocklistAdd lets administrators add a single prohibited or restricted
+// material by hand.
+func BlocklistAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	entry, problem := blocklistEntry(r.FormValue("cas_number"), r.FormValue("name"), r.FormValue("level"), r.FormValue("reason"))
+	if problem != "" {
+		renderBlocklistControl(w, r, problem)
+		return
+	}
+	entry.Source = blocklistManualSource
+	if err := database.WithContext(r.Context()).Create(&entry).Error; err != nil {
+		applog.Error(r.Context(), "failed to add blocklist entry", "error", err)
+		http.Error(w, "unable to save blocklist entry", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(r.Context(), "blocklist entry added", "entryID", entry.ID, "level", entry.Level)
+	renderBlocklistControl(w, r, entry.Label()+" added to the blocklist.")
+}
+
+// BlocklistImport replaces the entries of one dataset with the rows of an
+// uploaded CSV file. The header row names the columns; cas_number (or cas)
+// and name identify a material, level defaults to prohibited and reason is
+// optional. Entries from other datasets and manual entries are kept.
+func BlocklistImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBlocklistUpload+(64<<10))
+	if err := r.ParseMultipartForm(maxBlocklistUpload); err != nil {
+		renderBlocklistControl(w, r, "Choose a CSV file of at most 4 MB.")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("blocklist_file")
+	if err != nil {
+		renderBlocklistControl(w, r, "Choose a CSV file to import.")
+		return
+	}
+	defer file.Close()
+
+	source := strings.TrimSpace(r.FormValue("source"))
+	if source == "" {
+		source = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+	if source == "" || source == blocklistManualSource {
+		renderBlocklistControl(w, r, "Name the dataset so it can be replaced by a later import.")
+		return
+	}
+
+	entries, problem := parseBlocklistCSV(file)
+	if problem != "" {
+		renderBlocklistControl(w, r, problem)
+		return
+	}
+
+	ctx := r.Context()
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source = ?", source).Delete(&models.BlockedMaterial{}).Error; err != nil {
+			return err
+		}
+		for i := range entries {
+			entries[i].Source = source
+		}
+		return tx.CreateInBatches(entries, 200).Error
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to import blocklist", "error", err, "source", source)
+		http.Error(w, "unable to import blocklist", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "blocklist imported", "source", source, "entries", len(entries))
+	renderBlocklistControl(w, r, fmt.Sprintf("Imported %d entries from %s.", len(entries), source))
+}
+
+// parseBlocklistCSV reads blocklist rows, naming the first bad row in the
+// returned problem.
+func parseBlocklistCSV(src io.Reader) ([]models.BlockedMaterial, string) {
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	head, err := reader.Read()
+	if err != nil {
+		return nil, "The file is empty or not a CSV file."
+	}
+	columns := map[string]int{}
+	for i, name := range head {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "cas" {
+			name = "cas_number"
+		}
+		columns[name] = i
+	}
+	_, hasCAS := columns["cas_number"]
+	_, hasName := columns["name"]
+	if !hasCAS && !hasName {
+		return nil, "The header row must name a cas_number or name column."
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var entries []models.BlockedMaterial
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Sprintf("Line %d could not be read.", line)
+		}
+		entry, problem := blocklistEntry(field(record, "cas_number"), field(record, "name"), field(record, "level"), field(record, "reason"))
+		if problem != "" {
+			return nil, fmt.Sprintf("Line %d: %s", line, problem)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, "The file has no blocklist rows."
+	}
+	return entries, ""
+}
+
+// blocklistEntry validates one entry from a form or a dataset row.
+func blocklistEntry(cas, name, level, reason string) (models.BlockedMaterial, string) {
+	entry := models.BlockedMaterial{
+		CASNumber: strings.TrimSpace(cas),
+		Name:      strings.TrimSpace(name),
+		Level:     strings.ToLower(strings.TrimSpace(level)),
+		Reason:    strings.TrimSpace(reason),
+	}
+	if entry.CASNumber == "" && entry.Name == "" {
+		return entry, "Give a CAS number or a name."
+	}
+	switch entry.Level {
+	case "":
+		entry.Level = models.BlockProhibited
+	case models.BlockProhibited, models.BlockRestricted:
+	default:
+		return entry, "Level must be prohibited or restricted."
+	}
+	return entry, ""
+}
+
+// BlocklistDelete removes one blocklist entry.
+func BlocklistDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	id := pages.ParseUint(r.FormValue("id"))
+	if id == 0 {
+		http.Error(w, "missing blocklist entry", http.StatusBadRequest)
+		return
+	}
+	if err := database.WithContext(r.Context()).Delete(&models.BlockedMaterial{}, id).Error; err != nil {
+		applog.Error(r.Context(), "failed to delete blocklist entry", "error", err, "entryID", id)
+		http.Error(w, "unable to delete blocklist entry", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(r.Context(), "blocklist entry deleted", "entryID", id)
+	renderBlocklistControl(w, r, "Entry removed from the blocklist.")
+}
+
+func renderBlocklistControl(w http.ResponseWriter, r *http.Request, message string) {
+	renderComponent(w, r, pages.BlocklistControl(pages.BlocklistPanel{
+		Entries: loadBlocklist(r.Context()),
+		Message: message,
+	}))
+}
+
+// prohibitedCompositionRows reports every row of a composition that uses a
+// prohibited material.
+func prohibitedCompositionRows(ctx context.Context, snapshot pages.WorkspaceSnapshot, updates []formulaIngredientUpdate) []fieldProblem {
+	list := loadBlocklist(ctx)
+	if len(list) == 0 {
+		return nil
+	}
+	var problems []fieldProblem
+	for _, update := range updates {
+		if update.AromaChemicalID == nil {
+			continue
+		}
+		prohibited, _ := blockedChemicals(list, snapshot.AromaChemicals, []uint{*update.AromaChemicalID})
+		if len(prohibited) > 0 {
+			problems = append(problems, fieldProblem{
+				Field:   fmt.Sprintf("ingredients[%d].aroma_chemical_id", update.Position),
+				Message: blocklistMessage(prohibited),
+			})
+		}
+	}
+	return problems
+}
+
+// FormulaCompliance checks a formula's expanded composition, sub-formulas
+// included, against the blocklist.
+func FormulaCompliance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, pages.ParseUint(r.URL.Query().Get("id")))
+	if formula == nil {
+		http.Error(w, "formula not found", http.StatusNotFound)
+		return
+	}
+
+	report := pages.BuildComplianceReport(*formula, snapshot.FormulaIngredients, snapshot.AromaChemicals, loadBlocklist(r.Context()))
+	renderComponent(w, r, pages.FormulaCompliance(report))
+}
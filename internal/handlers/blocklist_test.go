@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestParseBlocklistCSV(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		problem string
+	}{
+		{name: "cas alias and defaults", input: "\ufeffCAS,Name\n83-66-9,Musk ambrette\n,Lilial\n", want: 2},
+		{name: "levels", input: "name,level,reason\nOakmoss,restricted,IFRA\nLilial,PROHIBITED,\n", want: 2},
+		{name: "missing identifier column", input: "level\nprohibited\n", problem: "header row"},
+		{name: "bad level", input: "name,level\nLilial,banned\n", problem: "Line 2"},
+		{name: "empty row", input: "cas_number,name\n,\n", problem: "Line 2"},
+		{name: "no rows", input: "cas_number\n", problem: "no blocklist rows"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			entries, problem := parseBlocklistCSV(strings.NewReader(tc.input))
+			if tc.problem != "" {
+				if !strings.Contains(problem, tc.problem) {
+					t.Fatalf("problem = %q, want it to mention %q", problem, tc.problem)
+				}
+				return
+			}
+			if problem != "" || len(entries) != tc.want {
+				t.Fatalf("got %d entries, problem %q", len(entries), problem)
+			}
+			for _, entry := range entries {
+				if entry.Level != models.BlockProhibited && entry.Level != models.BlockRestricted {
+					t.Fatalf("unexpected level %q", entry.Level)
+				}
+			}
+		})
+	}
+}
+
+func TestBlocklistBlocksProhibitedMaterials(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}, &models.BlockedMaterial{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "perfumer@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	musk := models.AromaChemical{IngredientName: "Musk ambrette", CASNumber: "83-66-9", OwnerID: user.ID}
+	oakmoss := models.AromaChemical{IngredientName: "Oakmoss absolute", OwnerID: user.ID}
+	for _, chemical := range []*models.AromaChemical{&musk, &oakmoss} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	for _, entry := range []models.BlockedMaterial{
+		{CASNumber: "83-66-9", Level: models.BlockProhibited, Reason: "Phototoxic", Source: "ifra"},
+		{Name: "oakmoss absolute", Level: models.BlockRestricted, Source: "manual"},
+	} {
+		if err := db.Create(&entry).Error; err != nil {
+			t.Fatalf("create blocklist entry: %v", err)
+		}
+	}
+	formula := models.Formula{Name: "Chypre", Version: 1, IsLatest: true}
+	accord := models.Formula{Name: "Old accord", Version: 1, IsLatest: true}
+	for _, f := range []*models.Formula{&formula, &accord} {
+		if err := db.Create(f).Error; err != nil {
+			t.Fatalf("create formula: %v", err)
+		}
+	}
+	if err := db.Create(&models.FormulaIngredient{FormulaID: accord.ID, AromaChemicalID: &musk.ID, Amount: 1, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create accord ingredient: %v", err)
+	}
+
+	edit := func(source string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		FormulaUpdate(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/update", url.Values{
+			"id":                  {strconv.Itoa(int(formula.ID))},
+			"formula_name":        {"Chypre"},
+			"ingredient_row_key":  {"new-1"},
+			"ingredient_entry_id": {""},
+			"ingredient_source":   {source},
+			"ingredient_amount":   {"2"},
+			"ingredient_unit":     {"g"},
+		}, int(user.ID)))
+		return rec.Body.String()
+	}
+	count := func() int64 {
+		var n int64
+		db.Model(&models.FormulaIngredient{}).Where("formula_id = ?", formula.ID).Count(&n)
+		return n
+	}
+
+	if body := edit(fmt.Sprintf("chem:%d", musk.ID)); !strings.Contains(body, "Musk ambrette is prohibited: Phototoxic.") {
+		t.Fatalf("expected the editor to refuse the prohibited material, got %q", body)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("prohibited material was saved: %d rows", n)
+	}
+	if body := edit(fmt.Sprintf("chem:%d", oakmoss.ID)); !strings.Contains(body, "Oakmoss absolute is restricted.") {
+		t.Fatalf("expected a restricted warning, got %q", body)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("restricted material was not saved: %d rows", n)
+	}
+
+	req := authenticatedFormRequest(t, sm, fmt.Sprintf("/app/api/formulas/%d/ingredients", formula.ID), nil, int(user.ID))
+	req.Method = http.MethodPut
+	req.Body = io.NopCloser(strings.NewReader(fmt.Sprintf(`[{"aroma_chemical_id": %d, "amount": 1, "unit": "g"}]`, musk.ID)))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", strconv.Itoa(int(formula.ID)))
+	rec := httptest.NewRecorder()
+	FormulaIngredientsReplace(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "ingredients[0].aroma_chemical_id") {
+		t.Fatalf("api: status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	// The compliance report finds prohibited materials inside sub-formulas.
+	if err := db.Create(&models.FormulaIngredient{FormulaID: formula.ID, SubFormulaID: &accord.ID, Amount: 1, Unit: "g", Position: 1}).Error; err != nil {
+		t.Fatalf("create sub-formula ingredient: %v", err)
+	}
+	req = authenticatedFormRequest(t, sm, fmt.Sprintf("/app/sections/formulas/compliance?id=%d", formula.ID), nil, int(user.ID))
+	req.Method = http.MethodGet
+	rec = httptest.NewRecorder()
+	FormulaCompliance(rec, req)
+	body := rec.Body.String()
+	for _, want := range []string{"Musk ambrette", "via Old accord", "Oakmoss absolute", "cannot be saved"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("compliance report missing %q: %q", want, body)
+		}
+	}
+}
+
+func TestFormulaUpdateFlagsConstraintViolations(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	iso := models.AromaChemical{IngredientName: "Iso E Super", Origin: models.OriginSynthetic, OwnerID: 5}
+	if err := db.Create(&iso).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	formula := models.Formula{Name: "Forest", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	FormulaUpdate(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/update", url.Values{
+		"id":                  {strconv.Itoa(int(formula.ID))},
+		"formula_name":        {"Forest"},
+		"naturals_only":       {"true"},
+		"ingredient_row_key":  {"new-1"},
+		"ingredient_entry_id": {""},
+		"ingredient_source":   {fmt.Sprintf("chem:%d", iso.ID)},
+		"ingredient_amount":   {"2"},
+		"ingredient_unit":     {"g"},
+	}, 5))
+	if body := rec.Body.String(); !strings.Contains(body, "Breaks the formula&#39;s constraints: Iso E Super (synthetic).") {
+		t.Fatalf("expected a constraint warning, got %q", body)
+	}
+	var stored models.Formula
+	if err := db.First(&stored, formula.ID).Error; err != nil {
+		t.Fatalf("reload formula: %v", err)
+	}
+	if !stored.NaturalsOnly || stored.Vegan {
+		t.Fatalf("constraints not saved: %+v", stored)
+	}
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"perfugo/internal/jobs"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const calendarFeedPath = "/feed/calendar.ics"
+
+// calendarLookback is how far back the calendar feed reaches, so recently
+// missed checks stay visible without the feed growing forever.
+const calendarLookback = 90 * 24 * time.Hour
+
+// calendarEvent is one all-day entry in the calendar feed.
+type calendarEvent struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	URL         string
+}
+
+// CalendarFeed serves a user's upcoming maceration checkpoints, stability
+// checks, formula deadlines and material expiries as an iCalendar feed to
+// anyone holding their calendar token, so calendar apps can subscribe to
+// it. Unknown and revoked tokens get a plain 404.
+func CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	var users []models.User
+	if token != "" {
+		if err := database.WithContext(ctx).Where("calendar_token_hash = ?", hashToken(token)).Limit(1).Find(&users).Error; err != nil {
+			applog.Error(ctx, "failed to look up calendar feed", "error", err)
+			http.Error(w, "unable to load calendar", http.StatusInternalServerError)
+			return
+		}
+	}
+	if len(users) == 0 || !users[0].IsActive() {
+		http.NotFound(w, r)
+		return
+	}
+	user := users[0]
+
+	events, err := loadCalendarEvents(r, user, nowFunc())
+	if err != nil {
+		applog.Error(ctx, "failed to load calendar feed", "error", err, "userID", user.ID)
+		http.Error(w, "unable to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	name := pages.UserProfile{Name: user.Name, Email: user.Email}.DisplayName() + "'s perfugo schedule"
+	if _, err := w.Write([]byte(renderCalendar(name, requestHostname(r), nowFunc(), events))); err != nil {
+		applog.Error(ctx, "failed to write calendar feed", "error", err)
+	}
+}
+
+// loadCalendarEvents collects the user's dated work from now-calendarLookback
+// on: checkpoints of finalized batches, deadlines of unapproved briefs, the
+// expiry of opened materials and, when the user has opted in, the next weekly
+// digest, perfugo's only scheduled report.
+func loadCalendarEvents(r *http.Request, user models.User, now time.Time) ([]calendarEvent, error) {
+	ctx := r.Context()
+	userID := user.ID
+	since := now.Add(-calendarLookback)
+	var events []calendarEvent
+
+	lastCheck := max(slices.Max(models.MacerationCheckpointDays), slices.Max(models.StabilityCheckDays))
+	var batches []models.ProductionBatch
+	if err := database.WithContext(ctx).
+		Where("owner_id = ? AND status = ? AND finalized_at >= ?", userID, models.ProductionBatchFinalized, since.AddDate(0, 0, -lastCheck)).
+		Find(&batches).Error; err != nil {
+		return nil, err
+	}
+	for _, batch := range batches {
+		for _, checkpoint := range batch.Checkpoints() {
+			if checkpoint.Due.Before(since) {
+				continue
+			}
+			summary := fmt.Sprintf("Smell %s lot %s (day %d of maceration)", batch.FormulaName, batch.LotNumber, checkpoint.Day)
+			description := "Check how the batch is maturing and note any changes."
+			if checkpoint.Kind == models.CheckpointStability {
+				summary = fmt.Sprintf("Stability check: %s lot %s (day %d)", batch.FormulaName, batch.LotNumber, checkpoint.Day)
+				description = "Compare the retained sample's colour, clarity and odour with the reference."
+			}
+			events = append(events, calendarEvent{
+				UID:         fmt.Sprintf("batch-%d-%s-%d", batch.ID, checkpoint.Kind, checkpoint.Day),
+				Date:        checkpoint.Due,
+				Summary:     summary,
+				Description: description,
+				URL:         absoluteURL(r, pages.ProductionBatchURL(batch.ID)),
+			})
+		}
+	}
+
+	var formulas []models.Formula
+	if err := database.WithContext(ctx).
+		Where("created_by_id = ? AND is_latest = ? AND status <> ? AND deadline >= ?", userID, true, models.FormulaStatusApproved, since).
+		Find(&formulas).Error; err != nil {
+		return nil, err
+	}
+	for _, formula := range formulas {
+		description := "Brief deadline."
+		if client := strings.TrimSpace(formula.Client); client != "" {
+			description = "Brief deadline for " + client + "."
+		}
+		events = append(events, calendarEvent{
+			UID:         fmt.Sprintf("formula-%d-deadline", formula.ID),
+			Date:        *formula.Deadline,
+			Summary:     "Deadline: " + formula.Name,
+			Description: description,
+		})
+	}
+
+	var items []models.InventoryItem
+	if err := database.WithContext(ctx).Preload("AromaChemical").
+		Where("owner_id = ? AND opened_on IS NOT NULL AND shelf_life_months > 0", userID).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		expires, ok := item.ExpiresOn()
+		if !ok || expires.Before(since) {
+			continue
+		}
+		name := "A material"
+		if item.AromaChemical != nil {
+			name = item.AromaChemical.IngredientName
+		}
+		description := "Shelf life ends; check the material before using it."
+		if lot := strings.TrimSpace(item.LotNumber); lot != "" {
+			description = "Shelf life of lot " + lot + " ends; check the material before using it."
+		}
+		events = append(events, calendarEvent{
+			UID:         fmt.Sprintf("stock-%d-expiry", item.ID),
+			Date:        expires,
+			Summary:     name + " expires",
+			Description: description,
+		})
+	}
+
+	if user.WeeklyDigest {
+		// The digest goes out on the first job run a DigestPeriod after the
+		// last one, so the date is approximate; one UID keeps a single entry
+		// that moves forward each week.
+		due := now
+		if user.DigestSentAt != nil && user.DigestSentAt.Add(jobs.DigestPeriod).After(now) {
+			due = user.DigestSentAt.Add(jobs.DigestPeriod)
+		}
+		events = append(events, calendarEvent{
+			UID:         fmt.Sprintf("user-%d-digest", userID),
+			Date:        due,
+			Summary:     "Weekly digest",
+			Description: "Your weekly perfugo digest is sent, unless the week was quiet.",
+			URL:         absoluteURL(r, pages.Path("/app/preferences")),
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
+	return events, nil
+}
+
+// renderCalendar writes events as an RFC 5545 calendar of all-day entries.
+// UIDs are stable across refreshes so clients update events in place.
+func renderCalendar(name, host string, stamp time.Time, events []calendarEvent) string {
+	var b strings.Builder
+	line := func(content string) {
+		b.WriteString(foldICSLine(content))
+		b.WriteString("\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//perfugo//calendar feed//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICSText(name))
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT6H")
+	for _, event := range events {
+		day := event.Date.Format("20060102")
+		line("BEGIN:VEVENT")
+		line("UID:" + event.UID + "@" + host)
+		line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:" + day)
+		line("DTEND;VALUE=DATE:" + event.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeICSText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:" + escapeICSText(event.Description))
+		}
+		if event.URL != "" {
+			line("URL:" + event.URL)
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICSText escapes a TEXT value as RFC 5545 section 3.3.11 requires.
+func escapeICSText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(value)
+}
+
+// foldICSLine splits a content line into 75-octet pieces joined by CRLF and
+// a space, without breaking a UTF-8 sequence.
+func foldICSLine(content string) string {
+	const limit = 75
+	if len(content) <= limit {
+		return content
+	}
+	var b strings.Builder
+	width := limit
+	for len(content) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines lose an octet to the leading space.
+		width = limit - 1
+	}
+	b.WriteString(content)
+	return b.String()
+}
+
+// CalendarFeedPreferences turns the user's calendar feed on or off.
+// Enabling it issues a new token, replacing any earlier subscription link;
+// as only the token's hash is kept, the link is shown once.
+func CalendarFeedPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	panel := pages.CalendarFeedPanel{}
+	hash := ""
+	if r.FormValue("action") == "enable" {
+		token, err := newToken()
+		if err != nil {
+			applog.Error(ctx, "failed to generate calendar token", "error", err)
+			http.Error(w, "unable to enable calendar", http.StatusInternalServerError)
+			return
+		}
+		hash = hashToken(token)
+		panel = pages.CalendarFeedPanel{
+			Enabled: true,
+			URL:     absoluteURL(r, pages.Path(calendarFeedPath+"?token="+url.QueryEscape(token))),
+			Message: "Calendar enabled. Copy the link now; it is not shown again.",
+		}
+	} else {
+		panel.Message = "Calendar disabled. Existing subscriptions stop updating."
+	}
+
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("calendar_token_hash", hash).Error; err != nil {
+		applog.Error(ctx, "failed to save calendar token", "error", err, "userID", userID)
+		http.Error(w, "unable to save calendar settings", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "calendar feed updated", "userID", userID, "enabled", panel.Enabled)
+	renderComponent(w, r, pages.CalendarFeedControl(panel))
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+func TestCalendarFeedListsScheduledChecks(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.ProductionBatch{}, &models.ProductionBatchLine{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	prevNow := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = prevNow })
+
+	digestSent := now.AddDate(0, 0, -2)
+	user := models.User{Email: "perfumer@example.com", Name: "Ana Costa", PasswordHash: "x", WeeklyDigest: true, DigestSentAt: &digestSent}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	finalized := now.AddDate(0, 0, -10)
+	stale := now.AddDate(-2, 0, 0)
+	deadline := now.AddDate(0, 0, 20)
+	opened := now.AddDate(0, -11, 0)
+	musk := models.AromaChemical{IngredientName: "Musk; ketone", OwnerID: user.ID}
+	if err := db.Create(&musk).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	for _, record := range []any{
+		&models.ProductionBatch{FormulaID: 1, FormulaName: "Nuit", OwnerID: user.ID, LotNumber: "L-7", Status: models.ProductionBatchFinalized, FinalizedAt: &finalized},
+		&models.ProductionBatch{FormulaID: 1, FormulaName: "Old", OwnerID: user.ID, LotNumber: "L-1", Status: models.ProductionBatchFinalized, FinalizedAt: &stale},
+		&models.ProductionBatch{FormulaID: 1, FormulaName: "Open", OwnerID: user.ID, LotNumber: "L-9", Status: models.ProductionBatchOpen},
+		&models.Formula{Name: "Brief", Version: 1, IsLatest: true, CreatedByID: &user.ID, Deadline: &deadline, Status: models.FormulaStatusDraft},
+		&models.InventoryItem{OwnerID: user.ID, AromaChemicalID: musk.ID, OpenedOn: &opened, ShelfLifeMonths: 12},
+	} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("create %T: %v", record, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	CalendarFeedPreferences(rec, authenticatedFormRequest(t, sm, "/app/preferences/calendar", url.Values{"action": {"enable"}}, int(user.ID)))
+	match := regexp.MustCompile(`/feed/calendar\.ics\?token=([^"&]+)`).FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		t.Fatalf("calendar link not shown: %s", rec.Body.String())
+	}
+
+	fetch := func(token string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		CalendarFeed(rec, httptest.NewRequest(http.MethodGet, "/feed/calendar.ics?token="+token, nil))
+		return rec
+	}
+
+	rec = fetch(match[1])
+	body := rec.Body.String()
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("content type = %q", rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"SUMMARY:Smell Nuit lot L-7 (day 7 of maceration)",
+		"SUMMARY:Stability check: Nuit lot L-7 (day 180)",
+		"DTSTART;VALUE=DATE:20250319",
+		"SUMMARY:Deadline: Brief",
+		`SUMMARY:Musk\; ketone expires`,
+		"DTSTART;VALUE=DATE:20250306\r\nDTEND;VALUE=DATE:20250307\r\nSUMMARY:Weekly digest\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("calendar missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Old") || strings.Contains(body, "Open") {
+		t.Fatalf("calendar lists stale or open batches:\n%s", body)
+	}
+
+	if rec := fetch("guess"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown token status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	CalendarFeedPreferences(rec, authenticatedFormRequest(t, sm, "/app/preferences/calendar", url.Values{"action": {"disable"}}, int(user.ID)))
+	if rec := fetch(match[1]); rec.Code != http.StatusNotFound {
+		t.Fatalf("disabled calendar status = %d", rec.Code)
+	}
+}
+
+func TestFoldICSLineKeepsRunesWhole(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("é", 60)
+	folded := foldICSLine(line)
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > 75 {
+			t.Fatalf("folded line too long (%d): %q", len(part), part)
+		}
+	}
+	if got := strings.ReplaceAll(folded, "\r\n ", ""); got != line {
+		t.Fatalf("unfolded line = %q, want %q", got, line)
+	}
+}
+
+func TestEscapeICSTextEscapesEveryLineBreak(t *testing.T) {
+	got := escapeICSText("a\r\nb\nc\rd; e, f\\")
+	if want := `a\nb\nc\nd\; e\, f\\`; got != want {
+		t.Fatalf("escapeICSText = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+
+	"perfugo/internal/captcha"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+)
+
+const captchaFailedMessage = "Please complete the verification challenge and try again."
+
+var captchaVerifier *captcha.Verifier
+
+// ConfigureCaptcha enables challenge verification on login and signup. A nil
+// verifier disables it.
+func ConfigureCaptcha(verifier *captcha.Verifier) {
+	captchaVerifier = verifier
+	applog.Debug(nil, "captcha configured", "enabled", verifier != nil)
+}
+
+func captchaWidget() pages.CaptchaWidget {
+	if captchaVerifier == nil {
+		return pages.CaptchaWidget{}
+	}
+	return pages.CaptchaWidget{
+		ScriptURL: captchaVerifier.ScriptURL(),
+		Class:     captchaVerifier.WidgetClass(),
+		SiteKey:   captchaVerifier.SiteKey(),
+	}
+}
+
+// verifyCaptcha checks the challenge response on a parsed form submission.
+// It always passes when no verifier is configured.
+func verifyCaptcha(r *http.Request) bool {
+	if captchaVerifier == nil {
+		return true
+	}
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+	if err := captchaVerifier.Verify(r.Context(), r.PostFormValue(captchaVerifier.Field()), remoteIP); err != nil {
+		applog.Debug(r.Context(), "captcha verification failed", "path", r.URL.Path, "error", err)
+		return false
+	}
+	return true
+}
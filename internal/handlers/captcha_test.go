@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/captcha"
+)
+
+func withTestCaptcha(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		_ = json.NewEncoder(w).Encode(map[string]bool{"success": r.PostFormValue("response") == "human"})
+	}))
+	t.Cleanup(srv.Close)
+
+	verifier, err := captcha.New(captcha.Config{Provider: captcha.ProviderHCaptcha, SiteKey: "site-key", SecretKey: "secret", VerifyURL: srv.URL})
+	if err != nil {
+		t.Fatalf("captcha.New: %v", err)
+	}
+	previous := captchaVerifier
+	ConfigureCaptcha(verifier)
+	t.Cleanup(func() { captchaVerifier = previous })
+}
+
+func TestLoginRequiresCaptcha(t *testing.T) {
+	_, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+	_, dbCleanup := withTestDatabase(t)
+	t.Cleanup(dbCleanup)
+	withTestCaptcha(t)
+
+	seed := httptest.NewRequest(http.MethodPost, "/signup", nil)
+	if _, err := createUser(seed, "captcha@example.com", "Captcha", "password123"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	get := httptest.NewRecorder()
+	sessionManager.LoadAndSave(http.HandlerFunc(Login)).ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if !strings.Contains(get.Body.String(), `data-sitekey="site-key"`) {
+		t.Fatal("expected the captcha widget on the login form")
+	}
+
+	tests := []struct {
+		name         string
+		response     string
+		wantRedirect bool
+	}{
+		{name: "missing response", response: ""},
+		{name: "rejected response", response: "bot"},
+		{name: "accepted response", response: "human", wantRedirect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"email": {"captcha@example.com"}, "password": {"password123"}, "h-captcha-response": {tt.response}}
+			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			sessionManager.LoadAndSave(http.HandlerFunc(Login)).ServeHTTP(w, req)
+
+			if redirected := w.Code == http.StatusSeeOther; redirected != tt.wantRedirect {
+				t.Fatalf("redirect = %t, want %t (status %d)", redirected, tt.wantRedirect, w.Code)
+			}
+			if !tt.wantRedirect && !strings.Contains(w.Body.String(), "verification challenge") {
+				t.Fatal("expected captcha failure message")
+			}
+		})
+	}
+}
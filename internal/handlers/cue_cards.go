@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"perfugo/internal/cuecards"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// IngredientCueCards downloads printable cue cards for the selected
+// ingredients as a PDF, or for the whole library when none are selected.
+func IngredientCueCards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	chemicals := snapshot.AromaChemicals
+	if ids := r.URL.Query()["id"]; len(ids) > 0 {
+		chemicals = make([]models.AromaChemical, 0, len(ids))
+		for _, raw := range ids {
+			if chemical := pages.FindAromaChemical(snapshot.AromaChemicals, pages.ParseUint(raw)); chemical != nil {
+				chemicals = append(chemicals, *chemical)
+			}
+		}
+	}
+	if len(chemicals) == 0 {
+		http.Error(w, "Select at least one ingredient.", http.StatusBadRequest)
+		return
+	}
+
+	cards := make([]cuecards.Card, 0, len(chemicals))
+	for _, chemical := range chemicals {
+		cards = append(cards, cueCardFor(chemical))
+	}
+	var buf bytes.Buffer
+	if err := cuecards.Render(&buf, "Ingredient cue cards", cards); err != nil {
+		applog.Error(r.Context(), "failed to render cue cards", "error", err)
+		http.Error(w, "We couldn't generate the cue cards.", http.StatusInternalServerError)
+		return
+	}
+	applog.Debug(r.Context(), "cue cards rendered", "cards", len(cards))
+
+	name := "cue-cards.pdf"
+	if len(chemicals) == 1 {
+		name = fmt.Sprintf("cue-card-%d.pdf", chemicals[0].ID)
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	_, _ = w.Write(buf.Bytes())
+}
+
+// cueCardFor summarises a chemical for its cue card. Usage guidance is
+// preferred over general notes.
+func cueCardFor(chemical models.AromaChemical) cuecards.Card {
+	card := cuecards.Card{
+		Name:  chemical.IngredientName,
+		CAS:   strings.TrimSpace(chemical.CASNumber),
+		Usage: strings.TrimSpace(chemical.Usage),
+	}
+	if position, _ := pages.NormalizePyramidPosition(chemical.PyramidPosition); position != "" {
+		card.Pyramid = pages.PyramidPositionLabel(position)
+	}
+	if chemical.Strength > 0 {
+		card.Strength = fmt.Sprintf("%s (%s)", pages.FormatStrength(chemical.Strength), pages.AromaChemicalPotencyLabel(chemical.Strength))
+	}
+	var dilution []string
+	if chemical.RecommendedDilution > 0 {
+		dilution = append(dilution, "Recommended "+pages.FormatPercentage(chemical.RecommendedDilution))
+	}
+	if chemical.DilutionPercentage > 0 {
+		dilution = append(dilution, "Stock "+pages.FormatPercentage(chemical.DilutionPercentage))
+	}
+	if chemical.MaxIFRAPercentage > 0 {
+		dilution = append(dilution, "IFRA max "+pages.FormatPercentage(chemical.MaxIFRAPercentage))
+	}
+	if len(dilution) > 0 {
+		card.Dilution = strings.Join(dilution, " · ")
+	}
+	if card.Usage == "" {
+		card.Usage = strings.TrimSpace(chemical.Notes)
+	}
+	return card
+}
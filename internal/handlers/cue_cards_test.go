@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestCueCardFor(t *testing.T) {
+	card := cueCardFor(models.AromaChemical{
+		IngredientName:      "Hedione",
+		CASNumber:           " 24851-98-7 ",
+		PyramidPosition:     "heart",
+		Strength:            4,
+		RecommendedDilution: 10,
+		MaxIFRAPercentage:   20,
+		Notes:               "Transparent jasmine.",
+	})
+	if card.CAS != "24851-98-7" || card.Pyramid != "Heart" || card.Usage != "Transparent jasmine." {
+		t.Fatalf("unexpected card: %+v", card)
+	}
+	if card.Dilution != "Recommended 10.00% · IFRA max 20.00%" {
+		t.Fatalf("unexpected dilution advice: %q", card.Dilution)
+	}
+	if empty := cueCardFor(models.AromaChemical{IngredientName: "Unknown"}); empty.Pyramid != "" || empty.Strength != "" || empty.Dilution != "" {
+		t.Fatalf("expected empty fields to be left off: %+v", empty)
+	}
+}
+
+func TestIngredientCueCardsServesPDF(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	chemical := models.AromaChemical{IngredientName: "Ambroxan", OwnerID: 9}
+	db.Create(&chemical)
+
+	req := httptest.NewRequest(http.MethodGet, "/app/sections/ingredients/cards?id="+strconv.Itoa(int(chemical.ID)), nil)
+	ctx, err := sm.Load(req.Context(), "")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	req = req.WithContext(ctx)
+	sm.Put(ctx, sessionAuthenticatedKey, true)
+	sm.Put(ctx, sessionUserIDKey, 9)
+
+	rec := httptest.NewRecorder()
+	IngredientCueCards(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("expected a PDF, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="cue-card-`+strconv.Itoa(int(chemical.ID))+`.pdf"` {
+		t.Fatalf("unexpected disposition: %q", got)
+	}
+}
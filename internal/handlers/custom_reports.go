@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// CustomReportSave stores a report definition built from the report builder
+// form. Only the columns offered for the chosen source are kept, and filter
+// rows without a column are ignored.
+func CustomReportSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	definition := models.ReportDefinition{
+		OwnerID: userID,
+		Name:    strings.TrimSpace(r.FormValue("name")),
+		Source:  strings.TrimSpace(r.FormValue("source")),
+	}
+	definition.Columns = strings.Join(r.Form["columns_"+definition.Source], ",")
+	if len([]rune(definition.Name)) > 120 {
+		renderCustomReports(w, r, userID, "Keep report names to 120 characters.")
+		return
+	}
+
+	operators, values := r.Form["filter_operator"], r.Form["filter_value"]
+	var filters []models.ReportFilter
+	for i, column := range r.Form["filter_column"] {
+		if column = strings.TrimSpace(column); column == "" {
+			continue
+		}
+		filter := models.ReportFilter{Column: column, Operator: models.ReportFilterContains}
+		if i < len(operators) {
+			filter.Operator = operators[i]
+		}
+		if i < len(values) {
+			filter.Value = strings.TrimSpace(values[i])
+		}
+		filters = append(filters, filter)
+	}
+	if err := definition.SetFilters(filters); err != nil {
+		applog.Error(ctx, "failed to encode report filters", "error", err, "userID", userID)
+		http.Error(w, "unable to save report", http.StatusInternalServerError)
+		return
+	}
+	if problem := pages.CheckReportDefinition(definition); problem != "" {
+		renderCustomReports(w, r, userID, problem)
+		return
+	}
+
+	var count int64
+	if err := database.WithContext(ctx).Model(&models.ReportDefinition{}).Where("owner_id = ?", userID).Count(&count).Error; err != nil {
+		applog.Error(ctx, "failed to count report definitions", "error", err, "userID", userID)
+		http.Error(w, "unable to save report", http.StatusInternalServerError)
+		return
+	}
+	if count >= models.MaxReportDefinitions {
+		renderCustomReports(w, r, userID, fmt.Sprintf("You can keep up to %d reports. Delete one first.", models.MaxReportDefinitions))
+		return
+	}
+
+	if err := database.WithContext(ctx).Create(&definition).Error; err != nil {
+		applog.Error(ctx, "failed to save report definition", "error", err, "userID", userID)
+		http.Error(w, "unable to save report", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "report definition saved", "reportID", definition.ID, "source", definition.Source, "userID", userID)
+	renderCustomReports(w, r, userID, "Saved "+definition.Name+".")
+}
+
+// CustomReportDelete removes one of the current user's report definitions.
+func CustomReportDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := database.WithContext(ctx).
+		Where("id = ? AND owner_id = ?", pages.ParseUint(r.FormValue("id")), userID).
+		Delete(&models.ReportDefinition{}).Error; err != nil {
+		applog.Error(ctx, "failed to delete report definition", "error", err, "userID", userID)
+		http.Error(w, "unable to delete report", http.StatusInternalServerError)
+		return
+	}
+	renderCustomReports(w, r, userID, "")
+}
+
+// CustomReportRun executes one of the current user's saved reports over
+// the records they can see. Passing format=csv downloads the rows instead
+// of rendering them.
+func CustomReportRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := withDeadline(r, handlerTimeouts.Report)
+	defer cancel()
+	var definition models.ReportDefinition
+	if err := database.WithContext(ctx).
+		Where("id = ? AND owner_id = ?", pages.ParseUint(r.URL.Query().Get("id")), userID).
+		First(&definition).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Report not found.", http.StatusNotFound)
+			return
+		}
+		if contextExpired(err) {
+			writeTimeout(w, r, nil)
+			return
+		}
+		applog.Error(r.Context(), "failed to load report definition", "error", err, "userID", userID)
+		http.Error(w, "We were unable to run the report. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	if problem := pages.CheckReportDefinition(definition); problem != "" {
+		http.Error(w, problem, http.StatusUnprocessableEntity)
+		return
+	}
+
+	data, err := loadCustomReportData(ctx, r, definition.Source, userID)
+	if err != nil {
+		if contextExpired(err) {
+			writeTimeout(w, r, nil)
+			return
+		}
+		applog.Error(r.Context(), "failed to load custom report data", "error", err, "reportID", definition.ID)
+		http.Error(w, "We were unable to run the report. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	report := pages.RunCustomReport(definition, data)
+	applog.Debug(r.Context(), "custom report run", "reportID", definition.ID, "rows", len(report.Rows))
+
+	if strings.EqualFold(r.URL.Query().Get("format"), "csv") {
+		writeCustomReportCSV(w, r, report)
+		return
+	}
+	renderComponent(w, r, pages.CustomReportResult(report))
+}
+
+// loadCustomReportData loads the records a report's source lists: the
+// user's workspace for ingredients and formulas, or their batches.
+func loadCustomReportData(ctx context.Context, r *http.Request, source string, userID uint) (pages.CustomReportData, error) {
+	var data pages.CustomReportData
+	if source == models.ReportSourceBatches {
+		err := database.WithContext(ctx).
+			Preload("Lines").
+			Where("owner_id = ?", userID).
+			Order("created_at desc").
+			Find(&data.Batches).Error
+		return data, err
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	data.AromaChemicals = snapshot.AromaChemicals
+	data.Formulas = snapshot.Formulas
+	data.FormulaIngredients = snapshot.FormulaIngredients
+	return data, nil
+}
+
+func writeCustomReportCSV(w http.ResponseWriter, r *http.Request, report pages.CustomReport) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-%d.csv"`, report.ID))
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write(report.Headers)
+	for _, row := range report.Rows {
+		_ = writer.Write(row)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		applog.Error(r.Context(), "failed to write custom report csv", "error", err)
+	}
+}
+
+func renderCustomReports(w http.ResponseWriter, r *http.Request, userID uint, message string) {
+	renderComponent(w, r, pages.CustomReportBuilder(loadReportDefinitions(r.Context(), userID), message))
+}
+
+// loadReportDefinitions lists the owner's saved reports by name.
+func loadReportDefinitions(ctx context.Context, ownerID uint) []models.ReportDefinition {
+	var definitions []models.ReportDefinition
+	if database == nil || ownerID == 0 {
+		return definitions
+	}
+	if err := database.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Order("name asc, id asc").
+		Find(&definitions).Error; err != nil {
+		applog.Error(ctx, "failed to load report definitions", "error", err, "userID", ownerID)
+	}
+	return definitions
+}
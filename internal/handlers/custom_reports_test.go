@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestCustomReportSaveAndExport(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}, &models.WishlistItem{}, &models.ProductionBatch{}, &models.ProductionBatchLine{}, &models.ReportDefinition{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	batches := []models.ProductionBatch{
+		{FormulaName: "Nuit", FormulaID: 1, OwnerID: 7, LotNumber: "LOT-1", TargetQuantity: 5000, Status: models.ProductionBatchOpen},
+		{FormulaName: "Jour", FormulaID: 2, OwnerID: 7, LotNumber: "LOT-2", TargetQuantity: 100, Status: models.ProductionBatchFinalized},
+		{FormulaName: "Other", FormulaID: 3, OwnerID: 8, LotNumber: "LOT-3", TargetQuantity: 9000, Status: models.ProductionBatchOpen},
+	}
+	if err := db.Create(&batches).Error; err != nil {
+		t.Fatalf("create batches: %v", err)
+	}
+
+	save := func(form url.Values) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		CustomReportSave(rec, authenticatedFormRequest(t, sm, "/app/reports/custom", form, 7))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("save: status %d", rec.Code)
+		}
+		return rec.Body.String()
+	}
+	if body := save(url.Values{"name": {"Empty"}, "source": {models.ReportSourceBatches}, "columns_formulas": {"name"}}); !strings.Contains(body, "Choose at least one column.") {
+		t.Fatalf("expected columns of other sources to be ignored, got %q", body)
+	}
+	body := save(url.Values{
+		"name":              {"Large batches"},
+		"source":            {models.ReportSourceBatches},
+		"columns_batches":   {"lot", "formula", "target"},
+		"filter_column":     {"target", ""},
+		"filter_operator":   {models.ReportFilterGreater, models.ReportFilterContains},
+		"filter_value":      {"1000", "ignored"},
+		"columns_formulas":  {"client"},
+		"columns_unrelated": {"x"},
+	})
+	if !strings.Contains(body, "Saved Large batches.") {
+		t.Fatalf("unexpected save response: %q", body)
+	}
+
+	var definition models.ReportDefinition
+	if err := db.Where("owner_id = ?", 7).First(&definition).Error; err != nil {
+		t.Fatalf("load definition: %v", err)
+	}
+	if definition.Columns != "lot,formula,target" || len(definition.FilterList()) != 1 {
+		t.Fatalf("unexpected stored definition: %+v", definition)
+	}
+
+	run := func(userID int, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/app/reports/custom/run?"+query, nil, userID)
+		req.Method = http.MethodGet
+		rec := httptest.NewRecorder()
+		CustomReportRun(rec, req)
+		return rec
+	}
+	id := strconv.Itoa(int(definition.ID))
+	rec := run(7, "id="+id+"&format=csv")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("csv: status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got, want := rec.Body.String(), "Lot,Formula,Target (mg)\nLOT-1,Nuit,5000\n"; got != want {
+		t.Fatalf("csv = %q, want %q", got, want)
+	}
+	if rec := run(7, "id="+id); !strings.Contains(rec.Body.String(), "LOT-1") || strings.Contains(rec.Body.String(), "LOT-3") {
+		t.Fatalf("unexpected report table: %q", rec.Body.String())
+	}
+	if rec := run(8, "id="+id); rec.Code != http.StatusNotFound {
+		t.Fatalf("another user ran the report: status %d", rec.Code)
+	}
+}
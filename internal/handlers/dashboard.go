@@ -10,6 +10,7 @@ import (
 	applog "perfugo/internal/log"
 
 	"github.com/a-h/templ"
+	"gorm.io/gorm"
 )
 
 // Dashboard renders the main application workspace once a user is authenticated.
@@ -20,11 +21,30 @@ func Dashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	section := pages.NormalizeWorkspaceSection(workspaceSectionFromPath(r.URL.Path))
+	snapshot := buildWorkspaceSnapshot(r)
+	requested := workspaceSectionFromPath(r.URL.Path)
+	if requested == "" {
+		// A bare /app opens with the user's landing section.
+		requested = pages.NormalizeLandingSection(snapshot.Profile.LandingSection)
+	}
+	section := pages.NormalizeWorkspaceSection(requested)
 	applog.Debug(r.Context(), "rendering workspace", "htmx", isHTMX(r), "section", section)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	snapshot := buildWorkspaceSnapshot(r)
+	switch section {
+	case "reports":
+		snapshot.Activity = loadActivityInsights(r.Context(), snapshot)
+		snapshot.LibraryHealth = pages.BuildLibraryHealth(snapshot.AromaChemicals, snapshot.UserID)
+		snapshot.ProductionBatches = loadProductionBatches(r.Context(), snapshot.UserID)
+		snapshot.BatchPresets = loadBatchPresets(r.Context(), snapshot.UserID)
+		snapshot.CustomReports = loadReportDefinitions(r.Context(), snapshot.UserID)
+	case "preferences":
+		snapshot.APITokens = loadAPITokens(r.Context(), snapshot.UserID)
+	case "ingredients":
+		snapshot.EditIngredientID = pages.ParseUint(r.URL.Query().Get("edit"))
+	case "formulas":
+		snapshot.EditFormulaID = pages.ParseUint(r.URL.Query().Get("edit"))
+	}
 
 	var component templ.Component
 	if isHTMX(r) {
@@ -69,6 +89,33 @@ func buildWorkspaceSnapshot(r *http.Request) pages.WorkspaceSnapshot {
 	if database != nil {
 		formulas, ingredients, chemicals := loadWorkspaceData(r, userID)
 		snapshot = pages.NewWorkspaceSnapshot(formulas, ingredients, chemicals, theme, userID)
+	} else {
+		ctx := r.Context()
+		snapshot = pages.NewWorkspaceSnapshot(demoData.Formulas(ctx), demoData.FormulaIngredients(ctx), demoData.AromaChemicals(ctx), theme, userID)
+		snapshot.DemoMode = true
+	}
+	snapshot.IsAdmin = currentUserIsAdmin(r)
+	snapshot.Impersonation = loadImpersonationBanner(r)
+	if userID != 0 {
+		snapshot.Profile = loadUserProfile(r.Context(), userID)
+	}
+	snapshot.MaintenanceMode = MaintenanceMode()
+	snapshot.Production = loadProductionDefaults(r)
+	snapshot.Print = loadPrintOptions(r)
+	snapshot.Inventory = loadInventoryPanel(r.Context(), userID)
+	stocked := snapshot.Inventory.Stocked()
+	snapshot.Wishlist = loadWishlistPanel(r.Context(), userID, stocked)
+	pages.AnnotateFormulaStock(snapshot.Formulas, snapshot.FormulaIngredients, stocked, snapshot.Wishlist.Materials())
+	snapshot.Substitutions = loadSubstitutionPanel(r.Context(), userID)
+	if snapshot.IsAdmin {
+		snapshot.Invitations = pages.InvitationPanel{
+			InviteOnly: InviteOnly(),
+			Pending:    loadPendingInvitations(r.Context()),
+		}
+		snapshot.ImportSchedules = loadImportSchedulePanel(r.Context())
+		snapshot.Blocklist = pages.BlocklistPanel{Entries: loadBlocklist(r.Context())}
+		snapshot.Taxonomy = loadTaxonomyPanel(r.Context())
+		snapshot.Retention = loadRetentionPanel(r.Context())
 	}
 	return snapshot
 }
@@ -80,9 +127,12 @@ func loadFormulas(ctx context.Context) []models.Formula {
 	}
 
 	if err := database.WithContext(ctx).
-		Preload("Ingredients").
+		Preload("Ingredients", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position asc, id asc")
+		}).
 		Preload("Ingredients.AromaChemical").
 		Preload("Ingredients.SubFormula").
+		Preload("Ingredients.StockSolution").
 		Order("name asc").
 		Find(&results).Error; err != nil {
 		applog.Error(ctx, "failed to load formulas for workspace", "error", err)
@@ -99,6 +149,7 @@ func loadFormulaIngredients(ctx context.Context) []models.FormulaIngredient {
 	if err := database.WithContext(ctx).
 		Preload("AromaChemical").
 		Preload("SubFormula").
+		Preload("StockSolution").
 		Preload("Formula").
 		Order("formula_id asc").
 		Find(&results).Error; err != nil {
@@ -121,7 +172,8 @@ func loadAromaChemicals(ctx context.Context, userID uint) []models.AromaChemical
 	if userID == 0 {
 		query = query.Where("public = ?", true)
 	} else {
-		query = query.Where("owner_id = ? OR public = ?", userID, true)
+		query = query.Where("owner_id = ? OR public = ?", userID, true).
+			Preload("StockSolutions", "owner_id = ? AND dilution_percent > 0 AND dilution_percent < 100", userID)
 	}
 
 	if err := query.Find(&results).Error; err != nil {
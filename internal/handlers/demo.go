@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// DemoData supplies the read-only library the workspace shows when no
+// database is configured, so listings, reports and detail cards render the
+// same sample formulas and materials instead of empty panels or errors.
+// Every call returns fresh values that the caller may sort and annotate.
+type DemoData interface {
+	Formulas(ctx context.Context) []models.Formula
+	FormulaIngredients(ctx context.Context) []models.FormulaIngredient
+	AromaChemicals(ctx context.Context) []models.AromaChemical
+}
+
+var demoData DemoData = sampleLibrary{}
+
+// ConfigureDemoData replaces the library shown without a database. Passing
+// nil restores the built-in sample library.
+func ConfigureDemoData(data DemoData) {
+	if data == nil {
+		data = sampleLibrary{}
+	}
+	demoData = data
+}
+
+// writeNoDatabase refuses a request that needs the database, as problem
+// details for JSON clients and plain text otherwise.
+func writeNoDatabase(w http.ResponseWriter, r *http.Request) {
+	applog.Debug(r.Context(), "request needs a database in demo mode", "path", r.URL.Path)
+	if acceptsJSON(r) {
+		writeProblem(w, r, http.StatusServiceUnavailable, pages.DemoModeMessage)
+		return
+	}
+	http.Error(w, pages.DemoModeMessage, http.StatusServiceUnavailable)
+}
+
+// loadReportComposition returns a formula and every formula row the batch
+// expansion may need, from the demo library when there is no database.
+func loadReportComposition(ctx context.Context, formulaID uint) (models.Formula, []models.FormulaIngredient, error) {
+	if database == nil {
+		formula := pages.FindFormula(demoData.Formulas(ctx), formulaID)
+		if formula == nil {
+			return models.Formula{}, nil, errBatchFormulaNotFound
+		}
+		return *formula, demoData.FormulaIngredients(ctx), nil
+	}
+
+	var formula models.Formula
+	if err := database.WithContext(ctx).First(&formula, formulaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return formula, nil, errBatchFormulaNotFound
+		}
+		return formula, nil, err
+	}
+
+	var ingredients []models.FormulaIngredient
+	if err := database.WithContext(ctx).
+		Preload("AromaChemical").
+		Preload("SubFormula").
+		Preload("StockSolution").
+		Find(&ingredients).Error; err != nil {
+		return formula, nil, err
+	}
+	return formula, ingredients, nil
+}
+
+// loadReportChemicals returns the chemicals with the given IDs, with their
+// other names, from the demo library when there is no database.
+func loadReportChemicals(ctx context.Context, ids []uint) ([]models.AromaChemical, error) {
+	var chemicals []models.AromaChemical
+	if database == nil {
+		for _, chemical := range demoData.AromaChemicals(ctx) {
+			if slices.Contains(ids, chemical.ID) {
+				chemicals = append(chemicals, chemical)
+			}
+		}
+		return chemicals, nil
+	}
+	err := database.WithContext(ctx).Preload("OtherNames").Where("id IN ?", ids).Find(&chemicals).Error
+	return chemicals, err
+}
+
+// sampleLibrary is the built-in demo library: a handful of public materials
+// and two formulas built from them.
+type sampleLibrary struct{}
+
+func (sampleLibrary) Formulas(context.Context) []models.Formula {
+	formulas, _, _ := buildSampleLibrary()
+	return formulas
+}
+
+func (sampleLibrary) FormulaIngredients(context.Context) []models.FormulaIngredient {
+	_, ingredients, _ := buildSampleLibrary()
+	return ingredients
+}
+
+func (sampleLibrary) AromaChemicals(context.Context) []models.AromaChemical {
+	_, _, chemicals := buildSampleLibrary()
+	return chemicals
+}
+
+func buildSampleLibrary() ([]models.Formula, []models.FormulaIngredient, []models.AromaChemical) {
+	chemicals := []models.AromaChemical{
+		{
+			Model:               gorm.Model{ID: 1},
+			IngredientName:      "Bergamot Essential",
+			CASNumber:           "8007-75-8",
+			Notes:               "Cold-pressed citrus brightness harvested from Calabria groves.",
+			Type:                "Essential oil",
+			PyramidPosition:     "top",
+			WheelPosition:       "Citrus",
+			Strength:            3,
+			RecommendedDilution: 10,
+			PricePerMg:          0.0004,
+			Public:              true,
+		},
+		{
+			Model:               gorm.Model{ID: 2},
+			IngredientName:      "Iris Pallida Butter",
+			CASNumber:           "8002-65-1",
+			Notes:               "Velvety floral heart with powdery texture and persistence.",
+			Type:                "Concrete",
+			PyramidPosition:     "heart",
+			WheelPosition:       "Floral",
+			Strength:            4,
+			RecommendedDilution: 5,
+			PricePerMg:          0.02,
+			Public:              true,
+		},
+		{
+			Model:               gorm.Model{ID: 3},
+			IngredientName:      "Ambroxan",
+			CASNumber:           "6790-58-5",
+			Notes:               "Modern ambergris profile delivering warmth and diffusion.",
+			Type:                "Synthetic",
+			PyramidPosition:     "base",
+			WheelPosition:       "Amber",
+			Strength:            5,
+			RecommendedDilution: 2,
+			PricePerMg:          0.003,
+			Public:              true,
+		},
+	}
+
+	formulas := []models.Formula{
+		{
+			Model:    gorm.Model{ID: 1},
+			Name:     "Aurum Nocturne",
+			Notes:    "Resinous amber core balanced with luminous citrus facets.",
+			Version:  1,
+			IsLatest: true,
+			Status:   models.FormulaStatusDraft,
+		},
+		{
+			Model:    gorm.Model{ID: 2},
+			Name:     "Lumen Céleste",
+			Notes:    "Radiant iris halo with cool musk trails for longevity.",
+			Version:  2,
+			IsLatest: true,
+			Status:   models.FormulaStatusDraft,
+		},
+	}
+
+	rows := []struct {
+		formula  int
+		chemical int
+		amount   float64
+	}{
+		{0, 0, 18}, {0, 2, 12.5},
+		{1, 1, 9.2}, {1, 0, 4.8},
+	}
+	ingredients := make([]models.FormulaIngredient, 0, len(rows))
+	for i, row := range rows {
+		chemical := &chemicals[row.chemical]
+		ingredient := models.FormulaIngredient{
+			Model:           gorm.Model{ID: uint(i + 1)},
+			FormulaID:       formulas[row.formula].ID,
+			Amount:          row.amount,
+			Unit:            "g",
+			Position:        len(formulas[row.formula].Ingredients),
+			AromaChemicalID: &chemical.ID,
+			AromaChemical:   chemical,
+		}
+		ingredients = append(ingredients, ingredient)
+		formulas[row.formula].Ingredients = append(formulas[row.formula].Ingredients, ingredient)
+	}
+	return formulas, ingredients, chemicals
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+func withoutDatabase(t *testing.T) {
+	t.Helper()
+	prevDB, prevDemo := database, demoData
+	database = nil
+	t.Cleanup(func() { database, demoData = prevDB, prevDemo })
+}
+
+func TestWorkspaceFallsBackToDemoLibrary(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+	withoutDatabase(t)
+
+	req := authenticatedFormRequest(t, sm, "/app/formulas", nil, 1)
+	req.Method = http.MethodGet
+	rec := httptest.NewRecorder()
+	Dashboard(rec, req)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "data-demo-mode") || !strings.Contains(body, "Aurum Nocturne") {
+		t.Fatalf("expected the demo banner and library, got %d: %s", rec.Code, body)
+	}
+
+	req = authenticatedFormRequest(t, sm, "/app/sections/ingredients/detail", nil, 1)
+	req.Method = http.MethodGet
+	req.URL.RawQuery = "id=3"
+	rec = httptest.NewRecorder()
+	IngredientDetail(rec, req)
+	if !strings.Contains(rec.Body.String(), "Ambroxan") {
+		t.Fatalf("expected the demo ingredient's detail card, got %s", rec.Body.String())
+	}
+
+	form := url.Values{"formula_id": {"1"}, "target_quantity": {"10000"}}
+	rec = httptest.NewRecorder()
+	GenerateBatchProductionReport(rec, authenticatedFormRequest(t, sm, "/app/reports/batch", form, 1))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Bergamot Essential") {
+		t.Fatalf("expected a batch report from the demo library, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = authenticatedFormRequest(t, sm, "/app/sections/formulas/allergens", nil, 1)
+	req.Method = http.MethodGet
+	req.URL.RawQuery = "id=2"
+	rec = httptest.NewRecorder()
+	FormulaAllergens(rec, req)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "couldn't calculate") {
+		t.Fatalf("expected an allergen report from the demo library, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestConfigureDemoDataReplacesLibrary(t *testing.T) {
+	withoutDatabase(t)
+	ConfigureDemoData(emptyDemoData{})
+
+	req := httptest.NewRequest(http.MethodGet, "/app", nil)
+	if snapshot := buildWorkspaceSnapshot(req); !snapshot.DemoMode || len(snapshot.AromaChemicals) != 0 {
+		t.Fatalf("expected an empty demo workspace, got %d chemicals", len(snapshot.AromaChemicals))
+	}
+
+	ConfigureDemoData(nil)
+	if snapshot := buildWorkspaceSnapshot(req); len(snapshot.AromaChemicals) != 3 {
+		t.Fatalf("expected the sample library back, got %d chemicals", len(snapshot.AromaChemicals))
+	}
+}
+
+func TestChangesWithoutDatabaseAreRefusedConsistently(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+	withoutDatabase(t)
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"wishlist":       WishlistUpdate,
+		"landing":        LandingPreferences,
+		"custom reports": CustomReportSave,
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, authenticatedFormRequest(t, sm, "/app", url.Values{}, 1))
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), pages.DemoModeMessage) {
+			t.Errorf("%s: expected the demo mode refusal, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := authenticatedFormRequest(t, sm, "/app/wishlist", url.Values{}, 1)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	WishlistUpdate(rec, req)
+	if rec.Header().Get("Content-Type") != problemContentType || !strings.Contains(rec.Body.String(), pages.DemoModeMessage) {
+		t.Fatalf("expected problem details for a JSON client, got %q: %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
+type emptyDemoData struct{}
+
+func (emptyDemoData) Formulas(context.Context) []models.Formula { return nil }
+
+func (emptyDemoData) FormulaIngredients(context.Context) []models.FormulaIngredient { return nil }
+
+func (emptyDemoData) AromaChemicals(context.Context) []models.AromaChemical { return nil }
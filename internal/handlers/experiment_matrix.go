@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/analytics"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// ExperimentMatrix shows the experiment matrix form for a formula on GET and
+// generates the mods on POST. Mods are saved as new formulas whose parent is
+// the base formula.
+func ExperimentMatrix(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	base := pages.FindFormula(snapshot.Formulas, pages.ParseUint(r.FormValue("id")))
+	if base == nil {
+		http.Error(w, "formula not found", http.StatusNotFound)
+		return
+	}
+	fail := func(message string) {
+		renderComponent(w, r, pages.ExperimentMatrixForm(base, snapshot.AromaChemicals, message))
+	}
+	if r.Method == http.MethodGet {
+		fail("")
+		return
+	}
+
+	var variables []pages.MatrixVariable
+	for i := 1; i <= pages.MaxMatrixVariables; i++ {
+		chemicalID := pages.ParseUint(r.FormValue(fmt.Sprintf("variable_%d_chemical", i)))
+		rawLevels := strings.TrimSpace(r.FormValue(fmt.Sprintf("variable_%d_levels", i)))
+		if chemicalID == 0 && rawLevels == "" {
+			continue
+		}
+		chemical := pages.FindAromaChemical(snapshot.AromaChemicals, chemicalID)
+		if chemical == nil {
+			fail(fmt.Sprintf("Choose a material for variable %d.", i))
+			return
+		}
+		levels, err := pages.ParseMatrixLevels(rawLevels)
+		if err != nil {
+			fail(fmt.Sprintf("Variable %d: %s.", i, err))
+			return
+		}
+		variables = append(variables, pages.MatrixVariable{Chemical: *chemical, Levels: levels})
+	}
+
+	ingredients := pages.FormulaIngredientsFor(snapshot.FormulaIngredients, base.ID)
+	mods, err := pages.BuildExperimentMatrix(*base, ingredients, variables)
+	if err != nil {
+		fail(fmt.Sprintf("The matrix could not be generated: %s.", err))
+		return
+	}
+	if database == nil {
+		fail(pages.DemoModeMessage)
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := currentUserID(r)
+	created := make([]models.Formula, 0, len(mods))
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFormulaQuota(tx, userID, len(mods)); err != nil {
+			return err
+		}
+		for _, mod := range mods {
+			formula := models.Formula{
+				Name:            mod.Name,
+				Notes:           fmt.Sprintf("Mod %d of %s v%d: %s.", mod.Number, base.Name, base.Version, mod.Label()),
+				Version:         1,
+				IsLatest:        true,
+				ParentFormulaID: &base.ID,
+			}
+			if userID != 0 {
+				formula.CreatedByID = &userID
+			}
+			if err := tx.Create(&formula).Error; err != nil {
+				return err
+			}
+			for _, row := range mod.Ingredients {
+				row.FormulaID = formula.ID
+				if err := tx.Create(&row).Error; err != nil {
+					return err
+				}
+			}
+			created = append(created, formula)
+		}
+		return nil
+	})
+	if message, ok := quotaMessage(err); ok {
+		fail(message)
+		return
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to generate experiment matrix", "error", err, "formulaID", base.ID)
+		fail("We couldn't create the mods. Please try again.")
+		return
+	}
+	applog.Info(ctx, "experiment matrix generated", "formulaID", base.ID, "mods", len(created))
+	for _, formula := range created {
+		recordActivity(ctx, models.ActivityEntityFormula, formula.ID, analytics.EventEdit)
+	}
+
+	refreshed := buildWorkspaceSnapshot(r)
+	filtered := pages.FilterFormulas(refreshed.Formulas, pages.FormulaFilters{})
+	renderComponent(w, r, pages.ExperimentMatrixResult(base, mods, created, filtered, len(refreshed.Formulas)))
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestExperimentMatrixCreatesLinkedMods(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	bergamot := models.AromaChemical{IngredientName: "Bergamot", OwnerID: 7}
+	iso := models.AromaChemical{IngredientName: "Iso E Super", OwnerID: 7}
+	for _, chemical := range []*models.AromaChemical{&bergamot, &iso} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	base := models.Formula{Name: "Eau", Version: 2, IsLatest: true}
+	if err := db.Create(&base).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	if err := db.Create(&models.FormulaIngredient{FormulaID: base.ID, AromaChemicalID: &bergamot.ID, Amount: 10, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create ingredient: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ExperimentMatrix(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/matrix", url.Values{
+		"id":                  {strconv.Itoa(int(base.ID))},
+		"variable_1_chemical": {strconv.Itoa(int(iso.ID))},
+		"variable_1_levels":   {"5/10/15"},
+	}, 7))
+	if body := rec.Body.String(); !strings.Contains(body, "3 mods of Eau") || !strings.Contains(body, "Iso E Super 15%") {
+		t.Fatalf("unexpected response: %q", body)
+	}
+
+	var mods []models.Formula
+	if err := db.Where("parent_formula_id = ?", base.ID).Order("name asc").Find(&mods).Error; err != nil {
+		t.Fatalf("load mods: %v", err)
+	}
+	if len(mods) != 3 || mods[0].Name != "Eau M01" || !strings.Contains(mods[2].Notes, "Mod 3 of Eau v2: Iso E Super 15%.") {
+		t.Fatalf("unexpected mods: %+v", mods)
+	}
+	var rows []models.FormulaIngredient
+	db.Where("formula_id = ?", mods[1].ID).Order("position asc").Find(&rows)
+	if len(rows) != 2 || rows[0].Amount != 9 || rows[1].Amount != 1 {
+		t.Fatalf("unexpected mod composition: %+v", rows)
+	}
+}
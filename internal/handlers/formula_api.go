@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/analytics"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+	"perfugo/models/validation"
+)
+
+const (
+	// maxCompositionBody caps the JSON accepted by FormulaIngredientsReplace.
+	maxCompositionBody = 1 << 20
+	// maxCompositionRows caps the rows in one composition.
+	maxCompositionRows = 500
+)
+
+// compositionRow is one ingredient line sent to FormulaIngredientsReplace.
+// Exactly one of the source IDs is set; a stock solution implies its neat
+// aroma chemical.
+type compositionRow struct {
+	AromaChemicalID *uint   `json:"aroma_chemical_id,omitempty"`
+	SubFormulaID    *uint   `json:"sub_formula_id,omitempty"`
+	StockSolutionID *uint   `json:"stock_solution_id,omitempty"`
+	Amount          float64 `json:"amount"`
+	Unit            string  `json:"unit"`
+}
+
+// compositionResponse is the stored composition returned after a replace.
+type compositionResponse struct {
+	FormulaID   uint                       `json:"formula_id"`
+	Ingredients []models.FormulaIngredient `json:"ingredients"`
+}
+
+// FormulaIngredients serves /app/api/formulas/{id}/ingredients: GET reads
+// the composition and PUT replaces it.
+func FormulaIngredients(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		formulaIngredientsRead(w, r)
+		return
+	}
+	FormulaIngredientsReplace(w, r)
+}
+
+// formulaIngredientsRead returns a formula's composition as JSON, as CSV
+// with one row per ingredient or as the workspace's formula detail fragment,
+// depending on the Accept header.
+func formulaIngredientsRead(w http.ResponseWriter, r *http.Request) {
+	if !requireAPIScope(w, r, models.APIResourceFormulas, false) {
+		return
+	}
+	offers := []string{mediaJSON, mediaCSV, mediaHTML}
+	media := negotiateMedia(r, offers...)
+	if media == "" {
+		writeNotAcceptable(w, r, offers...)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, pages.ParseUint(r.PathValue("id")))
+	if formula == nil {
+		writeProblem(w, r, http.StatusNotFound, "Formula not found.")
+		return
+	}
+	ingredients := pages.FormulaIngredientsFor(snapshot.FormulaIngredients, formula.ID)
+
+	w.Header().Set("Vary", "Accept")
+	switch media {
+	case mediaHTML:
+		renderComponent(w, r, pages.FormulaDetail(formula, ingredients))
+	case mediaCSV:
+		writeCompositionCSV(w, r, formula, ingredients)
+	default:
+		w.Header().Set("Content-Type", mediaJSON)
+		if err := json.NewEncoder(w).Encode(compositionResponse{FormulaID: formula.ID, Ingredients: ingredients}); err != nil {
+			applog.Error(r.Context(), "failed to encode composition", "error", err, "formulaID", formula.ID)
+		}
+	}
+}
+
+func writeCompositionCSV(w http.ResponseWriter, r *http.Request, formula *models.Formula, ingredients []models.FormulaIngredient) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"formula-%d-ingredients.csv\"", formula.ID))
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"Position", "Ingredient", "Source", "CAS", "Amount", "Unit", "Percentage"})
+	for _, ingredient := range ingredients {
+		cas := ""
+		if ingredient.AromaChemical != nil {
+			cas = ingredient.AromaChemical.CASNumber
+		}
+		_ = writer.Write([]string{
+			strconv.Itoa(ingredient.Position),
+			pages.IngredientDisplayName(ingredient),
+			pages.IngredientSourceKind(ingredient),
+			cas,
+			strconv.FormatFloat(ingredient.Amount, 'f', -1, 64),
+			ingredient.Unit,
+			strconv.FormatFloat(ingredient.Percentage, 'f', -1, 64),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		applog.Error(r.Context(), "failed to write composition csv", "error", err, "formulaID", formula.ID)
+	}
+}
+
+// FormulaIngredientsReplace handles PUT /app/api/formulas/{id}/ingredients.
+// The JSON array in the body becomes the formula's entire composition, in
+// order, in one transaction: either every row is valid and stored or the
+// formula is left untouched. Rows go through the same validation and
+// sub-formula cycle checks as the formula editor.
+func FormulaIngredientsReplace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use GET to read a formula's ingredients or PUT to replace them.")
+		return
+	}
+	if !requireAPIScope(w, r, models.APIResourceFormulas, true) {
+		return
+	}
+	if database == nil {
+		writeProblem(w, r, http.StatusServiceUnavailable, pages.DemoModeMessage)
+		return
+	}
+
+	id := pages.ParseUint(r.PathValue("id"))
+	if id == 0 {
+		writeProblem(w, r, http.StatusNotFound, "Formula not found.")
+		return
+	}
+
+	var rows []compositionRow
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCompositionBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rows); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "The body must be a JSON array of ingredient rows.")
+		return
+	}
+	if rows == nil {
+		writeProblem(w, r, http.StatusBadRequest, "The body must be a JSON array of ingredient rows.")
+		return
+	}
+	if len(rows) > maxCompositionRows {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("A formula may have at most %d ingredients.", maxCompositionRows))
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, id)
+	if formula == nil {
+		writeProblem(w, r, http.StatusNotFound, "Formula not found.")
+		return
+	}
+	if formula.Locked() {
+		writeProblem(w, r, http.StatusConflict, fmt.Sprintf("Version %d is approved and locked. Save a new version to make changes.", formula.Version))
+		return
+	}
+
+	updates, problems := resolveCompositionRows(snapshot, formula.ID, rows)
+	if len(problems) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "The composition was not saved because some rows are invalid.", problems...)
+		return
+	}
+	if problems := prohibitedCompositionRows(r.Context(), snapshot, updates); len(problems) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "The composition was not saved because it contains prohibited materials.", problems...)
+		return
+	}
+	if err := applyFormulaEntryMode(updates, formula.PercentEntry); err != nil {
+		detail := "Percentages must add up to more than zero."
+		if errors.Is(err, errMixedEntryModes) {
+			detail = "Use either percentages or amounts for every row, not both."
+		}
+		writeProblem(w, r, http.StatusUnprocessableEntity, detail)
+		return
+	}
+
+	ctx := r.Context()
+	stored := make([]models.FormulaIngredient, 0, len(updates))
+	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("formula_id = ?", id).Delete(&models.FormulaIngredient{}).Error; err != nil {
+			return err
+		}
+		for _, update := range updates {
+			record := models.FormulaIngredient{
+				FormulaID:       id,
+				Amount:          update.Amount,
+				Unit:            update.Unit,
+				AromaChemicalID: update.AromaChemicalID,
+				SubFormulaID:    update.SubFormulaID,
+				StockSolutionID: update.StockSolutionID,
+				Percentage:      update.Percentage,
+				Position:        update.Position,
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			stored = append(stored, record)
+		}
+		return nil
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to replace formula composition", "error", err, "formulaID", id)
+		writeProblem(w, r, http.StatusInternalServerError, "We couldn't save the composition. Please try again.")
+		return
+	}
+	applog.Info(ctx, "formula composition replaced", "formulaID", id, "rows", len(stored))
+	recordActivity(ctx, models.ActivityEntityFormula, id, analytics.EventEdit)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(compositionResponse{FormulaID: id, Ingredients: stored}); err != nil {
+		applog.Error(ctx, "failed to encode composition response", "error", err)
+	}
+}
+
+// resolveCompositionRows validates every row against the user's library and
+// the formula graph, reporting all problems at once with fields named
+// ingredients[i].field.
+func resolveCompositionRows(snapshot pages.WorkspaceSnapshot, formulaID uint, rows []compositionRow) ([]formulaIngredientUpdate, []fieldProblem) {
+	graph := buildFormulaDependencyGraph(snapshot.Formulas)
+	// The current sub-formulas are being replaced, so they cannot close a cycle.
+	delete(graph, formulaID)
+
+	updates := make([]formulaIngredientUpdate, 0, len(rows))
+	var problems []fieldProblem
+	for i, row := range rows {
+		field := func(name string) string { return fmt.Sprintf("ingredients[%d].%s", i, name) }
+		before := len(problems)
+
+		chemID, subID, stockID := row.AromaChemicalID, row.SubFormulaID, row.StockSolutionID
+		switch {
+		case stockID != nil && (chemID != nil || subID != nil):
+			problems = append(problems, fieldProblem{Field: field("source"), Message: "Choose one of aroma_chemical_id, sub_formula_id or stock_solution_id."})
+		case stockID != nil:
+			if stock := pages.FindStockSolution(snapshot.AromaChemicals, *stockID); stock == nil {
+				problems = append(problems, fieldProblem{Field: field("stock_solution_id"), Message: "Stock solution not found."})
+			} else {
+				neatID := stock.AromaChemicalID
+				chemID = &neatID
+			}
+		case chemID != nil && *chemID != 0 && pages.FindAromaChemical(snapshot.AromaChemicals, *chemID) == nil:
+			problems = append(problems, fieldProblem{Field: field("aroma_chemical_id"), Message: "Aroma chemical not found."})
+		case subID != nil && *subID != 0:
+			switch {
+			case *subID == formulaID:
+				problems = append(problems, fieldProblem{Field: field("sub_formula_id"), Message: "A formula cannot include itself as a sub-formula."})
+			case pages.FindFormula(snapshot.Formulas, *subID) == nil:
+				problems = append(problems, fieldProblem{Field: field("sub_formula_id"), Message: "Sub-formula not found."})
+			case wouldCreateFormulaCycle(graph, formulaID, *subID):
+				problems = append(problems, fieldProblem{Field: field("sub_formula_id"), Message: "This sub-formula would create a circular dependency between formulas."})
+			}
+		}
+
+		unit := strings.TrimSpace(row.Unit)
+		if unit == "" {
+			problems = append(problems, fieldProblem{Field: field("unit"), Message: "Unit is required."})
+		}
+		if errs := validation.FormulaIngredient(models.FormulaIngredient{Amount: row.Amount, Unit: unit, AromaChemicalID: chemID, SubFormulaID: subID}); len(errs) > 0 {
+			for _, fe := range errs {
+				problems = append(problems, fieldProblem{Field: field(fe.Field), Message: fe.Message})
+			}
+		}
+		if len(problems) > before {
+			continue
+		}
+
+		if subID != nil {
+			graph[formulaID] = append(graph[formulaID], *subID)
+		}
+		updates = append(updates, formulaIngredientUpdate{
+			Amount:          row.Amount,
+			Unit:            unit,
+			AromaChemicalID: chemID,
+			SubFormulaID:    subID,
+			StockSolutionID: stockID,
+			Position:        i,
+		})
+	}
+	return updates, problems
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestFormulaIngredientsReplace(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "perfumer@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	hedione := models.AromaChemical{IngredientName: "Hedione", OwnerID: user.ID}
+	iso := models.AromaChemical{IngredientName: "Iso E Super", OwnerID: user.ID}
+	for _, chemical := range []*models.AromaChemical{&hedione, &iso} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	parent := models.Formula{Name: "Parent", Version: 1, IsLatest: true}
+	accord := models.Formula{Name: "Accord", Version: 1, IsLatest: true}
+	for _, formula := range []*models.Formula{&parent, &accord} {
+		if err := db.Create(formula).Error; err != nil {
+			t.Fatalf("create formula: %v", err)
+		}
+	}
+	// The accord already uses the parent, so the parent cannot use the accord.
+	if err := db.Create(&models.FormulaIngredient{FormulaID: accord.ID, SubFormulaID: &parent.ID, Amount: 1, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create accord ingredient: %v", err)
+	}
+	if err := db.Create(&models.FormulaIngredient{FormulaID: parent.ID, AromaChemicalID: &hedione.ID, Amount: 5, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create parent ingredient: %v", err)
+	}
+
+	put := func(formulaID uint, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		path := fmt.Sprintf("/app/api/formulas/%d/ingredients", formulaID)
+		req := authenticatedFormRequest(t, sm, path, nil, int(user.ID))
+		req.Method = http.MethodPut
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetPathValue("id", strconv.Itoa(int(formulaID)))
+		rec := httptest.NewRecorder()
+		FormulaIngredientsReplace(rec, req)
+		return rec
+	}
+	composition := func() []models.FormulaIngredient {
+		t.Helper()
+		var rows []models.FormulaIngredient
+		if err := db.Where("formula_id = ?", parent.ID).Order("position asc").Find(&rows).Error; err != nil {
+			t.Fatalf("load composition: %v", err)
+		}
+		return rows
+	}
+
+	rec := put(parent.ID, fmt.Sprintf(`[
+		{"aroma_chemical_id": %d, "amount": 2, "unit": "g"},
+		{"sub_formula_id": %d, "amount": 1, "unit": "g"}
+	]`, iso.ID, accord.ID))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("cycle: status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var problem problemDetails
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "ingredients[1].sub_formula_id" {
+		t.Fatalf("unexpected problems: %+v", problem.Errors)
+	}
+	if rows := composition(); len(rows) != 1 || *rows[0].AromaChemicalID != hedione.ID {
+		t.Fatalf("rejected composition changed the formula: %+v", rows)
+	}
+
+	rec = put(parent.ID, fmt.Sprintf(`[
+		{"aroma_chemical_id": %d, "amount": 3, "unit": "g"},
+		{"aroma_chemical_id": %d, "amount": 500, "unit": "mg"}
+	]`, iso.ID, hedione.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("replace: status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	rows := composition()
+	if len(rows) != 2 || *rows[0].AromaChemicalID != iso.ID || *rows[1].AromaChemicalID != hedione.ID || rows[1].Unit != "mg" {
+		t.Fatalf("unexpected composition: %+v", rows)
+	}
+
+	for name, body := range map[string]string{
+		"not an array":  `{"amount": 1}`,
+		"unknown field": `[{"aroma_chemical_id": 1, "amount": 1, "unit": "g", "grams": 1}]`,
+		"empty body":    ``,
+	} {
+		if rec := put(parent.ID, body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+	if rec := put(9999, `[]`); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing formula: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestFormulaIngredientsNegotiatesRepresentation(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "perfumer@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	hedione := models.AromaChemical{IngredientName: "Hedione", CASNumber: "24851-98-7", OwnerID: user.ID}
+	if err := db.Create(&hedione).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	formula := models.Formula{Name: "Cologne", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	if err := db.Create(&models.FormulaIngredient{FormulaID: formula.ID, AromaChemicalID: &hedione.ID, Amount: 5, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create ingredient: %v", err)
+	}
+
+	get := func(handler http.HandlerFunc, id uint, accept string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/", nil, int(user.ID))
+		req.Method = http.MethodGet
+		req.Header.Set("Accept", accept)
+		req.SetPathValue("id", strconv.Itoa(int(id)))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := get(FormulaIngredients, formula.ID, "")
+	var body compositionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Ingredients) != 1 {
+		t.Fatalf("expected JSON by default, got %q", rec.Body.String())
+	}
+	rec = get(FormulaIngredients, formula.ID, "text/csv, application/json;q=0.5")
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") || !strings.Contains(rec.Body.String(), "Hedione,Aroma Chemical,24851-98-7,5,g") {
+		t.Fatalf("expected a CSV composition, got %s %q", ct, rec.Body.String())
+	}
+	rec = get(FormulaIngredients, formula.ID, "text/html,*/*;q=0.8")
+	if !strings.Contains(rec.Body.String(), "Cologne") || !strings.Contains(rec.Body.String(), "<") {
+		t.Fatalf("expected the formula detail fragment, got %q", rec.Body.String())
+	}
+	if rec := get(FormulaIngredients, formula.ID, "image/png"); rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected 406 for an unsupported type, got %d", rec.Code)
+	}
+
+	rec = get(AromaChemicalResource, hedione.ID, "text/csv")
+	if !strings.Contains(rec.Body.String(), "Hedione,24851-98-7") || rec.Header().Get("Vary") != "Accept" {
+		t.Fatalf("expected a CSV aroma chemical, got %q", rec.Body.String())
+	}
+	rec = get(AromaChemicalResource, hedione.ID, "application/*")
+	if ct := rec.Header().Get("Content-Type"); ct != mediaJSON || !strings.Contains(rec.Body.String(), `"cas_number":"24851-98-7"`) {
+		t.Fatalf("expected JSON for application/*, got %s %q", ct, rec.Body.String())
+	}
+	if rec := get(AromaChemicalResource, hedione.ID+100, "application/json"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown chemical, got %d", rec.Code)
+	}
+}
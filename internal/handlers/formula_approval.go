@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/analytics"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// FormulaSubmit sends a draft formula for approval.
+func FormulaSubmit(w http.ResponseWriter, r *http.Request) {
+	formulaWorkflow(w, r, models.FormulaStatusInReview)
+}
+
+// FormulaApprove approves a formula that is in review. Only administrators
+// may approve, and the approved revision is locked from then on.
+func FormulaApprove(w http.ResponseWriter, r *http.Request) {
+	formulaWorkflow(w, r, models.FormulaStatusApproved)
+}
+
+// FormulaReturn sends a formula in review back to draft, optionally with a
+// reason supplied through the HTMX prompt.
+func FormulaReturn(w http.ResponseWriter, r *http.Request) {
+	formulaWorkflow(w, r, models.FormulaStatusDraft)
+}
+
+func formulaWorkflow(w http.ResponseWriter, r *http.Request, to string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		applog.Error(r.Context(), "failed to parse formula workflow form", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	id := pages.ParseUint(r.FormValue("id"))
+	if id == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, id)
+	if formula == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	ingredients := pages.FormulaIngredientsFor(snapshot.FormulaIngredients, id)
+	render := func(formula *models.Formula, ingredients []models.FormulaIngredient, message string) {
+		renderComponent(w, r, pages.FormulaWorkflowResult(formula, ingredients, message))
+	}
+
+	action, refusal := formulaTransition(r, *formula, to)
+	if refusal != "" {
+		render(formula, ingredients, refusal)
+		return
+	}
+	if database == nil {
+		render(formula, ingredients, pages.DemoModeMessage)
+		return
+	}
+
+	ctx := r.Context()
+	note := strings.TrimSpace(r.Header.Get("HX-Prompt"))
+	if note == "" {
+		note = strings.TrimSpace(r.FormValue("note"))
+	}
+	if err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return applyFormulaTransition(tx, r, *formula, to, action, note)
+	}); err != nil {
+		applog.Error(ctx, "failed to change formula status", "error", err, "formulaID", id, "to", to)
+		render(formula, ingredients, "We couldn't update the formula's status. Please try again.")
+		return
+	}
+	applog.Info(ctx, "formula status changed", "formulaID", id, "from", pages.FormulaStatus(*formula), "to", to)
+	recordActivity(ctx, models.ActivityEntityFormula, id, analytics.EventEdit)
+
+	refreshed := buildWorkspaceSnapshot(r)
+	updated := pages.FindFormula(refreshed.Formulas, id)
+	if updated == nil {
+		updated = formula
+	}
+	message := map[string]string{
+		models.AuditFormulaSubmitted: "Submitted for approval.",
+		models.AuditFormulaApproved:  fmt.Sprintf("Version %d approved. It is now locked; save a new version to make changes.", updated.Version),
+		models.AuditFormulaReturned:  "Returned to draft.",
+	}[action]
+	render(updated, pages.FormulaIngredientsFor(refreshed.FormulaIngredients, id), message)
+}
+
+// formulaTransition checks whether the signed-in user may move formula to
+// status to. It returns the audit action to record, or a message explaining
+// why the move is refused.
+func formulaTransition(r *http.Request, formula models.Formula, to string) (action, refusal string) {
+	from := pages.FormulaStatus(formula)
+	switch {
+	case formula.Locked():
+		return "", fmt.Sprintf("Version %d is approved and locked. Save a new version to make changes.", formula.Version)
+	case from == models.FormulaStatusDraft && to == models.FormulaStatusInReview:
+		return models.AuditFormulaSubmitted, ""
+	case from == models.FormulaStatusInReview && to == models.FormulaStatusDraft:
+		return models.AuditFormulaReturned, ""
+	case from == models.FormulaStatusInReview && to == models.FormulaStatusApproved:
+		if !currentUserIsAdmin(r) {
+			return "", "Only an administrator can approve formulas."
+		}
+		return models.AuditFormulaApproved, ""
+	case to == models.FormulaStatusApproved:
+		return "", "Submit the formula for approval first."
+	default:
+		return "", fmt.Sprintf("A %s formula cannot be moved to %s.", strings.ToLower(pages.FormulaStatusLabel(from)), strings.ToLower(pages.FormulaStatusLabel(to)))
+	}
+}
+
+// applyFormulaTransition stores the new status and records it in the audit
+// log. Approving also stamps who approved the revision and when.
+func applyFormulaTransition(tx *gorm.DB, r *http.Request, formula models.Formula, to, action, note string) error {
+	updates := map[string]interface{}{"status": to}
+	if to == models.FormulaStatusApproved {
+		updates["approved_at"] = nowFunc()
+		if userID, ok := currentUserID(r); ok {
+			updates["approved_by_id"] = userID
+		}
+	}
+	if err := tx.Model(&models.Formula{}).Where("id = ?", formula.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	summary := fmt.Sprintf("%s v%d: %s → %s", formula.Name, formula.Version, pages.FormulaStatusLabel(pages.FormulaStatus(formula)), pages.FormulaStatusLabel(to))
+	if note != "" {
+		summary += ". " + note
+	}
+	return recordAudit(tx, r, action, models.ActivityEntityFormula, formula.ID, summary)
+}
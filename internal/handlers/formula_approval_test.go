@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestFormulaApprovalWorkflow(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	formula := models.Formula{Name: "Night Garden", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	id := strconv.Itoa(int(formula.ID))
+	reload := func(id uint) models.Formula {
+		t.Helper()
+		var stored models.Formula
+		if err := db.First(&stored, id).Error; err != nil {
+			t.Fatalf("reload formula: %v", err)
+		}
+		return stored
+	}
+
+	rec := httptest.NewRecorder()
+	FormulaSubmit(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/submit", url.Values{"id": {id}}, 3))
+	if !strings.Contains(rec.Body.String(), "Submitted for approval.") || reload(formula.ID).Status != models.FormulaStatusInReview {
+		t.Fatalf("submit: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	FormulaApprove(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/approve", url.Values{"id": {id}}, 3))
+	if !strings.Contains(rec.Body.String(), "Only an administrator can approve formulas.") || reload(formula.ID).Status != models.FormulaStatusInReview {
+		t.Fatalf("member approve: %s", rec.Body.String())
+	}
+
+	req := authenticatedFormRequest(t, sm, "/app/sections/formulas/approve", url.Values{"id": {id}}, 9)
+	sm.Put(req.Context(), sessionUserRoleKey, models.RoleAdmin)
+	rec = httptest.NewRecorder()
+	FormulaApprove(rec, req)
+	approved := reload(formula.ID)
+	if !approved.Locked() || approved.ApprovedAt == nil || approved.ApprovedByID == nil || *approved.ApprovedByID != 9 {
+		t.Fatalf("admin approve: %+v: %s", approved, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	FormulaUpdate(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/update", url.Values{
+		"id": {id}, "formula_name": {"Renamed"}, "form_action": {"update"},
+	}, 3))
+	if !strings.Contains(rec.Body.String(), "Version 1 is approved and locked.") || reload(formula.ID).Name != "Night Garden" {
+		t.Fatalf("locked update: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	FormulaBoardMove(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/board/move", url.Values{
+		"id": {id}, "status": {models.FormulaStatusDraft},
+	}, 3))
+	if reload(formula.ID).Status != models.FormulaStatusApproved {
+		t.Fatalf("board moved a locked formula: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	FormulaUpdate(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/update", url.Values{
+		"id": {id}, "formula_name": {"Night Garden"}, "notes": {"Softer dry-down"}, "form_action": {"new_version"},
+	}, 3))
+	if !strings.Contains(rec.Body.String(), "Started version 2 as a draft. Version 1 stays approved.") {
+		t.Fatalf("new version: %s", rec.Body.String())
+	}
+	var next models.Formula
+	if err := db.Where("parent_formula_id = ?", formula.ID).First(&next).Error; err != nil {
+		t.Fatalf("load new version: %v", err)
+	}
+	if next.Version != 2 || next.Status != models.FormulaStatusDraft || !next.IsLatest || next.Notes != "Softer dry-down" {
+		t.Fatalf("unexpected new version: %+v", next)
+	}
+	if old := reload(formula.ID); old.IsLatest || !old.Locked() || old.Notes != "" {
+		t.Fatalf("approved revision changed: %+v", old)
+	}
+
+	var entries []models.AuditEntry
+	if err := db.Order("id asc").Find(&entries).Error; err != nil {
+		t.Fatalf("load audit log: %v", err)
+	}
+	actions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	want := []string{models.AuditFormulaSubmitted, models.AuditFormulaApproved, models.AuditFormulaVersioned}
+	if strings.Join(actions, ",") != strings.Join(want, ",") {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+	if entries[1].ActorID == nil || *entries[1].ActorID != 9 || entries[2].EntityID != next.ID {
+		t.Fatalf("unexpected audit entries: %+v", entries)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/analytics"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// FormulaBoard renders the formulas grouped into status lanes.
+func FormulaBoard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	renderComponent(w, r, pages.FormulaBoard(snapshot.Formulas, ""))
+}
+
+// FormulaBoardMove sets a formula's brief status when its card is dropped on
+// another lane, then re-renders the board. Moves follow the approval
+// workflow: approved cards stay put and only administrators approve.
+func FormulaBoardMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		applog.Error(r.Context(), "failed to parse formula board form", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	id := pages.ParseUint(r.FormValue("id"))
+	status := strings.TrimSpace(r.FormValue("status"))
+	if id == 0 || !slices.Contains(models.FormulaStatuses(), status) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, id)
+	if formula == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if pages.FormulaStatus(*formula) == status {
+		renderComponent(w, r, pages.FormulaBoard(snapshot.Formulas, ""))
+		return
+	}
+
+	action, refusal := formulaTransition(r, *formula, status)
+	if refusal != "" {
+		renderComponent(w, r, pages.FormulaBoard(snapshot.Formulas, refusal))
+		return
+	}
+
+	if database == nil {
+		renderComponent(w, r, pages.FormulaBoard(snapshot.Formulas, pages.DemoModeMessage))
+		return
+	}
+
+	ctx := r.Context()
+	if err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return applyFormulaTransition(tx, r, *formula, status, action, "")
+	}); err != nil {
+		applog.Error(ctx, "failed to update formula status", "error", err, "formulaID", id)
+		renderComponent(w, r, pages.FormulaBoard(snapshot.Formulas, "We couldn't move that formula. Please try again."))
+		return
+	}
+	applog.Info(ctx, "formula status changed", "formulaID", id, "from", pages.FormulaStatus(*formula), "to", status)
+	recordActivity(ctx, models.ActivityEntityFormula, id, analytics.EventEdit)
+
+	renderComponent(w, r, pages.FormulaBoard(buildWorkspaceSnapshot(r).Formulas, ""))
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestFormulaBoardMove(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	formula := models.Formula{Name: "Night Garden", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+
+	move := func(status string) *httptest.ResponseRecorder {
+		t.Helper()
+		form := url.Values{"id": {strconv.Itoa(int(formula.ID))}, "status": {status}}
+		req := authenticatedFormRequest(t, sm, "/app/sections/formulas/board/move", form, 1)
+		rec := httptest.NewRecorder()
+		FormulaBoardMove(rec, req)
+		return rec
+	}
+
+	if rec := move("shipped"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown status: got %d", rec.Code)
+	}
+
+	rec := move(models.FormulaStatusInReview)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("move: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `id="formula-board"`) {
+		t.Fatalf("expected the refreshed board, got %s", rec.Body.String())
+	}
+	var stored models.Formula
+	if err := db.First(&stored, formula.ID).Error; err != nil {
+		t.Fatalf("reload formula: %v", err)
+	}
+	if stored.Status != models.FormulaStatusInReview {
+		t.Fatalf("status = %q, want %q", stored.Status, models.FormulaStatusInReview)
+	}
+}
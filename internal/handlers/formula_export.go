@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+	"perfugo/models/validation"
+)
+
+// formulaExportFormat versions the JSON document produced by FormulaExport.
+const formulaExportFormat = "perfugo.formula/v1"
+
+// Export modes. Reference exports list every sub-formula once and point at it
+// by UUID; inline exports nest each sub-formula where it is used.
+const (
+	formulaExportReference = "reference"
+	formulaExportInline    = "inline"
+)
+
+var errFormulaExists = errors.New("formula already exists")
+
+// formulaExportDocument is the portable representation of a formula tree.
+type formulaExportDocument struct {
+	Format      string                 `json:"format"`
+	Mode        string                 `json:"mode"`
+	Formula     formulaExportFormula   `json:"formula"`
+	SubFormulas []formulaExportFormula `json:"sub_formulas,omitempty"`
+}
+
+type formulaExportFormula struct {
+	UUID         string                    `json:"uuid"`
+	Name         string                    `json:"name"`
+	Notes        string                    `json:"notes,omitempty"`
+	Version      int                       `json:"version"`
+	PercentEntry bool                      `json:"percent_entry,omitempty"`
+	Ingredients  []formulaExportIngredient `json:"ingredients"`
+}
+
+// formulaExportIngredient is one formula row, in order. Exactly one of
+// AromaChemical, SubFormulaUUID (reference mode) or SubFormula (inline mode)
+// is set. Stock solution links are local inventory and are not exported.
+type formulaExportIngredient struct {
+	Amount         float64                `json:"amount"`
+	Unit           string                 `json:"unit"`
+	Percentage     float64                `json:"percentage,omitempty"`
+	AromaChemical  *formulaExportChemical `json:"aroma_chemical,omitempty"`
+	SubFormulaUUID string                 `json:"sub_formula_uuid,omitempty"`
+	SubFormula     *formulaExportFormula  `json:"sub_formula,omitempty"`
+}
+
+// formulaExportChemical identifies an aroma chemical by CAS number and name,
+// which are resolved against the importing instance's library.
+type formulaExportChemical struct {
+	Name      string `json:"name"`
+	CASNumber string `json:"cas_number,omitempty"`
+}
+
+// FormulaExport downloads a formula and the sub-formulas it uses as JSON.
+// Sub-formulas are embedded by reference unless mode=inline is requested.
+func FormulaExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use GET to export a formula.")
+		return
+	}
+	if database == nil {
+		writeProblem(w, r, http.StatusServiceUnavailable, pages.DemoModeMessage)
+		return
+	}
+
+	id := pages.ParseUint(r.URL.Query().Get("id"))
+	if id == 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Select a formula to export.", fieldProblem{Field: "id", Message: "Formula is required."})
+		return
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("mode")))
+	switch mode {
+	case "":
+		mode = formulaExportReference
+	case formulaExportReference, formulaExportInline:
+	default:
+		writeProblem(w, r, http.StatusBadRequest, "Mode must be reference or inline.", fieldProblem{Field: "mode", Message: "Use reference or inline."})
+		return
+	}
+
+	ctx := r.Context()
+	doc, err := buildFormulaExport(ctx, database, id, mode)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeProblem(w, r, http.StatusNotFound, "Formula not found.")
+		return
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to export formula", "error", err, "formulaID", id)
+		writeProblem(w, r, http.StatusInternalServerError, "Unable to export the formula.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFileName(doc.Formula)))
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		applog.Error(ctx, "failed to encode formula export", "error", err, "formulaID", id)
+	}
+}
+
+// buildFormulaExport walks the formula tree rooted at id. Formulas without a
+// UUID are given one so later exports refer to them consistently.
+func buildFormulaExport(ctx context.Context, db *gorm.DB, id uint, mode string) (formulaExportDocument, error) {
+	doc := formulaExportDocument{Format: formulaExportFormat, Mode: mode}
+	listed := map[uint]bool{}
+	visiting := map[uint]bool{}
+
+	var walk func(id uint) (formulaExportFormula, error)
+	walk = func(id uint) (formulaExportFormula, error) {
+		if visiting[id] {
+			return formulaExportFormula{}, fmt.Errorf("formula %d contains itself", id)
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+
+		var formula models.Formula
+		if err := db.WithContext(ctx).
+			Preload("Ingredients", func(db *gorm.DB) *gorm.DB {
+				return db.Order("position asc, id asc")
+			}).
+			Preload("Ingredients.AromaChemical").
+			First(&formula, id).Error; err != nil {
+			return formulaExportFormula{}, err
+		}
+		if formula.UUID == "" {
+			formula.UUID = models.NewUUID()
+			if err := db.WithContext(ctx).Model(&models.Formula{}).Where("id = ?", formula.ID).Update("uuid", formula.UUID).Error; err != nil {
+				return formulaExportFormula{}, err
+			}
+		}
+
+		out := formulaExportFormula{
+			UUID:         formula.UUID,
+			Name:         formula.Name,
+			Notes:        formula.Notes,
+			Version:      formula.Version,
+			PercentEntry: formula.PercentEntry,
+			Ingredients:  make([]formulaExportIngredient, 0, len(formula.Ingredients)),
+		}
+		for _, ingredient := range formula.Ingredients {
+			line := formulaExportIngredient{
+				Amount:     ingredient.Amount,
+				Unit:       ingredient.Unit,
+				Percentage: ingredient.Percentage,
+			}
+			switch {
+			case ingredient.SubFormulaID != nil:
+				sub, err := walk(*ingredient.SubFormulaID)
+				if err != nil {
+					return formulaExportFormula{}, err
+				}
+				if mode == formulaExportInline {
+					line.SubFormula = &sub
+				} else {
+					line.SubFormulaUUID = sub.UUID
+					if !listed[*ingredient.SubFormulaID] {
+						listed[*ingredient.SubFormulaID] = true
+						doc.SubFormulas = append(doc.SubFormulas, sub)
+					}
+				}
+			case ingredient.AromaChemical != nil:
+				line.AromaChemical = &formulaExportChemical{
+					Name:      ingredient.AromaChemical.IngredientName,
+					CASNumber: strings.TrimSpace(ingredient.AromaChemical.CASNumber),
+				}
+			default:
+				continue
+			}
+			out.Ingredients = append(out.Ingredients, line)
+		}
+		return out, nil
+	}
+
+	root, err := walk(id)
+	if err != nil {
+		return doc, err
+	}
+	doc.Formula = root
+	return doc, nil
+}
+
+func exportFileName(formula formulaExportFormula) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, strings.TrimSpace(formula.Name))
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		slug = "formula"
+	}
+	return fmt.Sprintf("%s-v%d.json", slug, formula.Version)
+}
+
+// ToolsImportFormulaJSON imports a document produced by FormulaExport.
+// Sub-formulas whose UUID already exists locally are linked instead of
+// duplicated, so re-importing a shared accord reuses it.
+func ToolsImportFormulaJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	if database == nil {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", pages.DemoModeMessage))
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxFormulaUploadSize); err != nil {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "Upload is too large or invalid. Please retry with a smaller file."))
+		return
+	}
+	file, _, err := r.FormFile("formula_json")
+	if err != nil {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "Choose an exported formula file to import."))
+		return
+	}
+	defer file.Close()
+
+	var doc formulaExportDocument
+	if err := json.NewDecoder(io.LimitReader(file, maxFormulaUploadSize)).Decode(&doc); err != nil {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "That file is not a formula export."))
+		return
+	}
+
+	ctx, cancel := withDeadline(r, handlerTimeouts.Import)
+	defer cancel()
+	chemicals := snapshotChemicalPointers(snapshot.AromaChemicals)
+	var formula *models.Formula
+	created := 0
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var importErr error
+		formula, created, importErr = importFormulaDocument(tx, doc, chemicals, userID)
+		if importErr != nil {
+			return importErr
+		}
+		// The export may carry any number of sub-formulas, so the quota is
+		// checked once they exist and the import rolled back if it is exceeded.
+		return checkFormulaQuota(tx, userID, 0)
+	})
+	switch {
+	case contextExpired(err):
+		writeTimeout(w, r, func(message string) {
+			renderComponent(w, r, pages.ToolsPanel(snapshot, "", message))
+		})
+		return
+	case errors.As(err, &quotaError{}):
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", err.Error()))
+		return
+	case errors.Is(err, errFormulaExists):
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", fmt.Sprintf("\"%s\" has already been imported.", doc.Formula.Name)))
+		return
+	case err != nil:
+		applog.Error(ctx, "formula json import failed", "error", err, "userID", userID)
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "Unable to import the formula: "+err.Error()+"."))
+		return
+	}
+
+	applog.Info(ctx, "formula imported from json", "formulaID", formula.ID, "created", created, "userID", userID)
+	snapshot = buildWorkspaceSnapshot(r)
+	message := fmt.Sprintf("Imported formula \"%s\".", formula.Name)
+	if created > 1 {
+		message = fmt.Sprintf("Imported formula \"%s\" with %d new sub-formulas.", formula.Name, created-1)
+	}
+	if warning := duplicateFormulaWarning(formula.ID, snapshot.Formulas, snapshot.FormulaIngredients); warning != "" {
+		message = fmt.Sprintf("%s %s", message, warning)
+	}
+	renderComponent(w, r, pages.ToolsPanel(snapshot, message, ""))
+}
+
+// importFormulaDocument creates the formulas described by doc, recording
+// createdBy as their creator, and returns the root and how many formulas
+// were created. Aroma chemicals are matched by CAS
+// number, then by name or alias; any that cannot be found abort the import.
+func importFormulaDocument(tx *gorm.DB, doc formulaExportDocument, chemicals []*models.AromaChemical, createdBy uint) (*models.Formula, int, error) {
+	if doc.Format != formulaExportFormat {
+		return nil, 0, fmt.Errorf("unsupported export format %q", doc.Format)
+	}
+
+	definitions := map[string]*formulaExportFormula{}
+	for i := range doc.SubFormulas {
+		if uuid := strings.TrimSpace(doc.SubFormulas[i].UUID); uuid != "" {
+			definitions[uuid] = &doc.SubFormulas[i]
+		}
+	}
+
+	resolved := map[string]uint{}
+	visiting := map[string]bool{}
+	var missing []string
+	created := 0
+	var root *models.Formula
+
+	var create func(def *formulaExportFormula, isRoot bool) (uint, error)
+	create = func(def *formulaExportFormula, isRoot bool) (uint, error) {
+		uuid := strings.TrimSpace(def.UUID)
+		if uuid != "" {
+			if id, ok := resolved[uuid]; ok {
+				return id, nil
+			}
+			if visiting[uuid] {
+				return 0, fmt.Errorf("sub-formula %q contains itself", def.Name)
+			}
+			var existing models.Formula
+			err := tx.Where("uuid = ?", uuid).First(&existing).Error
+			if err == nil {
+				if isRoot {
+					return 0, errFormulaExists
+				}
+				resolved[uuid] = existing.ID
+				return existing.ID, nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, err
+			}
+			if def.Name == "" && len(def.Ingredients) == 0 {
+				return 0, fmt.Errorf("sub-formula %s is missing from the export", uuid)
+			}
+			visiting[uuid] = true
+			defer delete(visiting, uuid)
+		}
+
+		formula := models.Formula{
+			Name:         def.Name,
+			Notes:        strings.TrimSpace(def.Notes),
+			Version:      def.Version,
+			IsLatest:     true,
+			PercentEntry: def.PercentEntry,
+			UUID:         uuid,
+		}
+		if formula.Version <= 0 {
+			formula.Version = 1
+		}
+		if createdBy != 0 {
+			formula.CreatedByID = &createdBy
+		}
+		if errs := validation.Formula(&formula); len(errs) > 0 {
+			return 0, fmt.Errorf("formula %q: %s", def.Name, errs.Error())
+		}
+
+		rows := make([]models.FormulaIngredient, 0, len(def.Ingredients))
+		for position, line := range def.Ingredients {
+			row := models.FormulaIngredient{
+				Amount:     line.Amount,
+				Unit:       strings.TrimSpace(line.Unit),
+				Percentage: line.Percentage,
+				Position:   position,
+			}
+			switch {
+			case line.SubFormula != nil:
+				id, err := create(line.SubFormula, false)
+				if err != nil {
+					return 0, err
+				}
+				row.SubFormulaID = &id
+			case strings.TrimSpace(line.SubFormulaUUID) != "":
+				ref := strings.TrimSpace(line.SubFormulaUUID)
+				sub, ok := definitions[ref]
+				if !ok {
+					sub = &formulaExportFormula{UUID: ref}
+				}
+				id, err := create(sub, false)
+				if err != nil {
+					return 0, err
+				}
+				row.SubFormulaID = &id
+			case line.AromaChemical != nil:
+				chemical := matchExportedChemical(chemicals, *line.AromaChemical)
+				if chemical == nil {
+					missing = append(missing, line.AromaChemical.Name)
+					continue
+				}
+				row.AromaChemicalID = &chemical.ID
+			default:
+				continue
+			}
+			if row.Unit == "" {
+				row.Unit = "g"
+			}
+			if errs := validation.FormulaIngredient(row); len(errs) > 0 {
+				return 0, fmt.Errorf("formula %q: %s", def.Name, errs.Error())
+			}
+			rows = append(rows, row)
+		}
+
+		if err := tx.Create(&formula).Error; err != nil {
+			return 0, err
+		}
+		for i := range rows {
+			rows[i].FormulaID = formula.ID
+		}
+		if len(rows) > 0 {
+			if err := tx.Create(&rows).Error; err != nil {
+				return 0, err
+			}
+		}
+		created++
+		if uuid != "" {
+			resolved[uuid] = formula.ID
+		}
+		if isRoot {
+			root = &formula
+		}
+		return formula.ID, nil
+	}
+
+	if _, err := create(&doc.Formula, true); err != nil {
+		return nil, 0, err
+	}
+	if len(missing) > 0 {
+		return nil, 0, fmt.Errorf("these ingredients are not in your library: %s", strings.Join(uniqueStrings(missing), ", "))
+	}
+	return root, created, nil
+}
+
+// matchExportedChemical resolves an exported chemical by CAS number first,
+// since names differ between libraries more often than CAS numbers do.
+func matchExportedChemical(chemicals []*models.AromaChemical, exported formulaExportChemical) *models.AromaChemical {
+	if cas := strings.TrimSpace(exported.CASNumber); cas != "" {
+		for _, chemical := range chemicals {
+			if chemical != nil && strings.TrimSpace(chemical.CASNumber) == cas {
+				return chemical
+			}
+		}
+	}
+	return matchChemicalByAliases(chemicals, exported.Name, nil)
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		result = append(result, value)
+	}
+	return result
+}
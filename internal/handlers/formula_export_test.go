@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+// seedExportTree creates Chypre, which uses Amber base both directly and
+// through Oakmoss accord, and returns the root formula.
+func seedExportTree(t *testing.T, db *gorm.DB) models.Formula {
+	t.Helper()
+	hedione := models.AromaChemical{IngredientName: "Hedione", CASNumber: "24851-98-7"}
+	iso := models.AromaChemical{IngredientName: "Iso E Super", CASNumber: "54464-57-2"}
+	for _, chemical := range []*models.AromaChemical{&hedione, &iso} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("seed chemical: %v", err)
+		}
+	}
+
+	amber := models.Formula{Name: "Amber base", Version: 1, IsLatest: true}
+	oakmoss := models.Formula{Name: "Oakmoss accord", Version: 2, IsLatest: true}
+	chypre := models.Formula{Name: "Chypre", Version: 1, IsLatest: true}
+	for _, formula := range []*models.Formula{&amber, &oakmoss, &chypre} {
+		if err := db.Create(formula).Error; err != nil {
+			t.Fatalf("seed formula: %v", err)
+		}
+	}
+
+	rows := []models.FormulaIngredient{
+		{FormulaID: amber.ID, Amount: 5, Unit: "g", AromaChemicalID: &iso.ID},
+		{FormulaID: oakmoss.ID, Amount: 2, Unit: "g", SubFormulaID: &amber.ID},
+		{FormulaID: chypre.ID, Amount: 10, Unit: "g", AromaChemicalID: &hedione.ID, Position: 0},
+		{FormulaID: chypre.ID, Amount: 3, Unit: "g", SubFormulaID: &oakmoss.ID, Position: 1},
+		{FormulaID: chypre.ID, Amount: 1, Unit: "g", SubFormulaID: &amber.ID, Position: 2},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatalf("seed rows: %v", err)
+	}
+	return chypre
+}
+
+func TestBuildFormulaExportModes(t *testing.T) {
+	db := newToolsTestDB(t)
+	chypre := seedExportTree(t, db)
+	ctx := context.Background()
+
+	doc, err := buildFormulaExport(ctx, db, chypre.ID, formulaExportReference)
+	if err != nil {
+		t.Fatalf("reference export: %v", err)
+	}
+	if doc.Formula.UUID == "" || len(doc.Formula.Ingredients) != 3 {
+		t.Fatalf("unexpected root: %+v", doc.Formula)
+	}
+	if len(doc.SubFormulas) != 2 || doc.SubFormulas[0].Name != "Amber base" || doc.SubFormulas[1].Name != "Oakmoss accord" {
+		t.Fatalf("expected each sub-formula listed once, dependencies first: %+v", doc.SubFormulas)
+	}
+	if doc.Formula.Ingredients[2].SubFormulaUUID != doc.SubFormulas[0].UUID {
+		t.Fatalf("expected the amber row to reference its UUID")
+	}
+	if chemical := doc.Formula.Ingredients[0].AromaChemical; chemical == nil || chemical.CASNumber != "24851-98-7" {
+		t.Fatalf("unexpected chemical reference: %+v", chemical)
+	}
+
+	inline, err := buildFormulaExport(ctx, db, chypre.ID, formulaExportInline)
+	if err != nil {
+		t.Fatalf("inline export: %v", err)
+	}
+	if len(inline.SubFormulas) != 0 {
+		t.Fatalf("inline export should not list sub-formulas separately")
+	}
+	nested := inline.Formula.Ingredients[1].SubFormula
+	if nested == nil || nested.Name != "Oakmoss accord" || nested.Ingredients[0].SubFormula == nil {
+		t.Fatalf("expected nested sub-formulas, got %+v", nested)
+	}
+	if inline.Formula.UUID != doc.Formula.UUID {
+		t.Fatalf("expected UUIDs to be stable across exports")
+	}
+}
+
+func TestImportFormulaDocumentRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := newToolsTestDB(t)
+	chypre := seedExportTree(t, source)
+
+	for _, mode := range []string{formulaExportReference, formulaExportInline} {
+		doc, err := buildFormulaExport(ctx, source, chypre.ID, mode)
+		if err != nil {
+			t.Fatalf("%s export: %v", mode, err)
+		}
+		// Round trip through JSON as a real transfer would.
+		payload, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var decoded formulaExportDocument
+		if err := json.Unmarshal(payload, &decoded); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+
+		target := newToolsTestDB(t)
+		chemicals := []*models.AromaChemical{
+			{IngredientName: "Hedione", CASNumber: "24851-98-7"},
+			{IngredientName: "Iso E Super"},
+		}
+		for _, chemical := range chemicals {
+			if err := target.Create(chemical).Error; err != nil {
+				t.Fatalf("seed target chemical: %v", err)
+			}
+		}
+
+		root, created, err := importFormulaDocument(target, decoded, chemicals, 0)
+		if err != nil {
+			t.Fatalf("%s import: %v", mode, err)
+		}
+		if created != 3 || root.UUID != doc.Formula.UUID {
+			t.Fatalf("%s import: created %d formulas, root %+v", mode, created, root)
+		}
+
+		var formulas []models.Formula
+		if err := target.Preload("Ingredients", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position asc")
+		}).Order("id asc").Find(&formulas).Error; err != nil {
+			t.Fatalf("load imported formulas: %v", err)
+		}
+		if len(formulas) != 3 {
+			t.Fatalf("%s import: expected shared amber base to be created once, got %d formulas", mode, len(formulas))
+		}
+		imported := formulas[len(formulas)-1]
+		if imported.Name != "Chypre" || len(imported.Ingredients) != 3 || imported.Ingredients[0].AromaChemicalID == nil {
+			t.Fatalf("%s import: unexpected root %+v", mode, imported)
+		}
+
+		if _, _, err := importFormulaDocument(target, decoded, chemicals, 0); !errors.Is(err, errFormulaExists) {
+			t.Fatalf("%s import: expected re-import to be refused, got %v", mode, err)
+		}
+	}
+}
+
+func TestImportFormulaDocumentReportsMissingChemicals(t *testing.T) {
+	db := newToolsTestDB(t)
+	doc := formulaExportDocument{
+		Format: formulaExportFormat,
+		Formula: formulaExportFormula{
+			UUID:    models.NewUUID(),
+			Name:    "Fougère",
+			Version: 1,
+			Ingredients: []formulaExportIngredient{
+				{Amount: 1, Unit: "g", AromaChemical: &formulaExportChemical{Name: "Coumarin", CASNumber: "91-64-5"}},
+			},
+		},
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, _, err := importFormulaDocument(tx, doc, nil, 0)
+		return err
+	})
+	if err == nil {
+		t.Fatal("expected missing chemicals to abort the import")
+	}
+	var count int64
+	db.Model(&models.Formula{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected the import to roll back, found %d formulas", count)
+	}
+}
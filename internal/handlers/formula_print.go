@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// FormulaPrint renders a standalone, print-optimised sheet for a formula. The
+// optional CAS, cost, supplier and notes columns follow the user's print
+// preferences so the sheet can be handed to a manufacturer as is.
+func FormulaPrint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := pages.ParseUint(r.URL.Query().Get("id"))
+	if id == 0 {
+		http.Error(w, "Select a formula to print.", http.StatusBadRequest)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, id)
+	if formula == nil {
+		http.Error(w, "Formula not found.", http.StatusNotFound)
+		return
+	}
+
+	ingredients := pages.FormulaIngredientsFor(snapshot.FormulaIngredients, id)
+	suppliers := loadInventorySuppliers(r.Context(), snapshot.UserID)
+	data := buildFormulaPrintData(formula, ingredients, suppliers, loadPrintOptions(r))
+	data.Pyramid = formulaPyramid(snapshot, id)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.FormulaPrint(data).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render formula print view", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// buildFormulaPrintData flattens a formula's top-level rows into print lines.
+// Costs are only known for aroma chemicals; sub-formulas are listed without one.
+func buildFormulaPrintData(formula *models.Formula, ingredients []models.FormulaIngredient, suppliers map[uint]string, options pages.PrintOptions) pages.FormulaPrintData {
+	data := pages.FormulaPrintData{
+		FormulaName:    formula.Name,
+		FormulaVersion: formula.Version,
+		Notes:          strings.TrimSpace(formula.Notes),
+		Options:        options,
+		PrintedAt:      nowFunc(),
+	}
+
+	total := 0.0
+	for _, ingredient := range ingredients {
+		total += normalizeAmount(ingredient.Amount, ingredient.Unit)
+	}
+
+	for idx, ingredient := range ingredients {
+		line := pages.FormulaPrintLine{
+			Order:  idx + 1,
+			Name:   pages.IngredientDisplayName(ingredient),
+			Kind:   pages.IngredientSourceKind(ingredient),
+			Amount: ingredient.Amount,
+			Unit:   ingredient.Unit,
+		}
+		grams := normalizeAmount(ingredient.Amount, ingredient.Unit)
+		if total > 0 {
+			line.Percent = grams / total * 100
+		}
+		if chemical := ingredient.AromaChemical; chemical != nil {
+			line.Name = chemical.IngredientName
+			line.CASNumber = strings.TrimSpace(chemical.CASNumber)
+			line.Supplier = suppliers[chemical.ID]
+			neatMg := grams * 1000
+			if stock := ingredient.StockSolution; stock != nil && stock.Diluted() {
+				line.Dilution = stock.DilutionLabel()
+				neatMg *= stock.NeatFraction()
+				if supplier := strings.TrimSpace(stock.Supplier); supplier != "" {
+					line.Supplier = supplier
+				}
+			}
+			line.Cost = neatMg * chemical.PricePerMg
+			data.TotalCost += line.Cost
+		}
+		data.Lines = append(data.Lines, line)
+	}
+	return data
+}
+
+// formulaPyramid spreads a saved formula, sub-formulas included, over the
+// pyramid positions of its materials, as the editor's totals do.
+func formulaPyramid(snapshot pages.WorkspaceSnapshot, formulaID uint) []pages.PyramidShare {
+	byFormula := make(map[uint][]models.FormulaIngredient)
+	for _, ingredient := range snapshot.FormulaIngredients {
+		byFormula[ingredient.FormulaID] = append(byFormula[ingredient.FormulaID], ingredient)
+	}
+	shares := map[uint]float64{}
+	addSubFormulaShares(shares, formulaID, 1, byFormula, snapshot.AromaChemicals, map[uint]bool{}, false)
+	if len(shares) == 0 {
+		return nil
+	}
+	return pyramidDistribution(snapshot.AromaChemicals, shares)
+}
+
+// loadInventorySuppliers maps each of the owner's stocked chemicals to the
+// first supplier recorded against it.
+func loadInventorySuppliers(ctx context.Context, ownerID uint) map[uint]string {
+	suppliers := map[uint]string{}
+	if database == nil || ownerID == 0 {
+		return suppliers
+	}
+
+	var items []models.InventoryItem
+	if err := database.WithContext(ctx).
+		Where("owner_id = ? AND supplier <> ''", ownerID).
+		Order("id asc").
+		Find(&items).Error; err != nil {
+		applog.Error(ctx, "failed to load inventory suppliers", "error", err, "ownerID", ownerID)
+		return suppliers
+	}
+	for _, item := range items {
+		if suppliers[item.AromaChemicalID] == "" {
+			suppliers[item.AromaChemicalID] = strings.TrimSpace(item.Supplier)
+		}
+	}
+	return suppliers
+}
+
+// PrintPreferences saves which optional columns the formula print view shows.
+func PrintPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if sessionManager == nil || database == nil {
+		http.Error(w, "preferences not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	options := pages.PrintOptions{
+		CAS:      checkboxChecked(r.FormValue("print_cas")),
+		Cost:     checkboxChecked(r.FormValue("print_cost")),
+		Supplier: checkboxChecked(r.FormValue("print_supplier")),
+		Notes:    checkboxChecked(r.FormValue("print_notes")),
+	}
+
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"print_hide_cas":      !options.CAS,
+		"print_hide_cost":     !options.Cost,
+		"print_hide_supplier": !options.Supplier,
+		"print_hide_notes":    !options.Notes,
+	}).Error; err != nil {
+		applog.Error(ctx, "failed to update print preferences", "error", err, "userID", userID)
+		http.Error(w, "unable to save preferences", http.StatusInternalServerError)
+		return
+	}
+	applog.Debug(ctx, "print preferences persisted", "userID", userID, "options", options)
+
+	if !isHTMX(r) {
+		http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.PrintOptionsControl(options, "Print options saved.").Render(ctx, w); err != nil {
+		applog.Error(ctx, "failed to render print options", "error", err)
+	}
+}
+
+// loadPrintOptions reads the current user's print preferences, including
+// every column when none are stored.
+func loadPrintOptions(r *http.Request) pages.PrintOptions {
+	options := pages.DefaultPrintOptions()
+	if database == nil {
+		return options
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		return options
+	}
+
+	var user models.User
+	if err := database.WithContext(r.Context()).
+		Select("print_hide_cas", "print_hide_cost", "print_hide_supplier", "print_hide_notes").
+		First(&user, userID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			applog.Error(r.Context(), "failed to load print preferences", "error", err, "userID", userID)
+		}
+		return options
+	}
+	return pages.PrintOptions{
+		CAS:      !user.PrintHideCAS,
+		Cost:     !user.PrintHideCost,
+		Supplier: !user.PrintHideSupplier,
+		Notes:    !user.PrintHideNotes,
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+func TestBuildFormulaPrintData(t *testing.T) {
+	chemical := &models.AromaChemical{IngredientName: "Iso E Super", CASNumber: "54464-57-2", PricePerMg: 0.01}
+	chemical.ID = 7
+	stock := &models.InventoryItem{AromaChemicalID: 7, DilutionPercent: 10, Solvent: models.SolventDPG, Supplier: "Dilutions Ltd"}
+	sub := &models.Formula{Name: "Amber base"}
+	chemID := chemical.ID
+
+	formula := &models.Formula{Name: "Chypre", Version: 2, Notes: "  Keep cool.  "}
+	ingredients := []models.FormulaIngredient{
+		{Amount: 750, Unit: "mg", AromaChemicalID: &chemID, AromaChemical: chemical},
+		{Amount: 100, Unit: "mg", AromaChemicalID: &chemID, AromaChemical: chemical, StockSolution: stock},
+		{Amount: 150, Unit: "mg", SubFormula: sub},
+	}
+
+	data := buildFormulaPrintData(formula, ingredients, map[uint]string{7: "Aroma Co"}, pages.DefaultPrintOptions())
+	if data.Notes != "Keep cool." || len(data.Lines) != 3 {
+		t.Fatalf("unexpected print data: %+v", data)
+	}
+	if line := data.Lines[0]; line.Supplier != "Aroma Co" || line.Percent != 75 || line.Cost != 7.5 {
+		t.Fatalf("unexpected neat line: %+v", line)
+	}
+	if line := data.Lines[1]; line.Dilution != "10% in DPG" || line.Supplier != "Dilutions Ltd" || math.Abs(line.Cost-0.1) > 1e-9 {
+		t.Fatalf("unexpected stock solution line: %+v", line)
+	}
+	if line := data.Lines[2]; line.Cost != 0 || line.Name != "Amber base" {
+		t.Fatalf("unexpected sub-formula line: %+v", line)
+	}
+	if math.Abs(data.TotalCost-7.6) > 1e-9 {
+		t.Fatalf("expected total cost 7.6, got %v", data.TotalCost)
+	}
+
+	data.Options = pages.PrintOptions{Notes: true}
+	var buf bytes.Buffer
+	if err := pages.FormulaPrint(data).Render(context.Background(), &buf); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	html := buf.String()
+	for _, hidden := range []string{"54464-57-2", "Aroma Co", "Estimated total"} {
+		if strings.Contains(html, hidden) {
+			t.Fatalf("expected %q to be excluded from the print view", hidden)
+		}
+	}
+	if !strings.Contains(html, "Keep cool.") {
+		t.Fatalf("expected notes in the print view")
+	}
+
+	if strings.Contains(html, "Cost breakdown") {
+		t.Fatalf("expected no cost chart without the cost column")
+	}
+
+	data.Options = pages.PrintOptions{Cost: true}
+	data.Pyramid = []pages.PyramidShare{{Position: "base", Label: "Base", Share: 100}}
+	buf.Reset()
+	if err := pages.FormulaPrint(data).Render(context.Background(), &buf); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	html = buf.String()
+	for _, want := range []string{"data-pyramid-chart", "data-cost-chart", ">7.50<", "<svg"} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("expected %q in the print view", want)
+		}
+	}
+}
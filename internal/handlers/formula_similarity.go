@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// duplicateSimilarityThreshold is the score at which a saved or imported
+// formula is flagged as a likely duplicate of an existing one.
+const duplicateSimilarityThreshold = 0.9
+
+// formulaSimilarity pairs a formula with its similarity to the one being
+// checked, from 0 (nothing in common) to 1 (identical proportions).
+type formulaSimilarity struct {
+	Formula *models.Formula
+	Score   float64
+}
+
+type similarFormulaResponse struct {
+	FormulaID uint                  `json:"formula_id"`
+	Threshold float64               `json:"threshold"`
+	Matches   []similarFormulaMatch `json:"matches"`
+}
+
+type similarFormulaMatch struct {
+	ID      uint    `json:"id"`
+	Name    string  `json:"name"`
+	Version int     `json:"version"`
+	Score   float64 `json:"score"`
+}
+
+// FormulaSimilar lists the latest formulas whose composition is at least as
+// similar to the given formula as the threshold (default 0.9).
+func FormulaSimilar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use GET to find similar formulas.")
+		return
+	}
+
+	id := pages.ParseUint(r.URL.Query().Get("id"))
+	if id == 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Select a formula to compare.", fieldProblem{Field: "id", Message: "Formula is required."})
+		return
+	}
+	threshold := duplicateSimilarityThreshold
+	if raw := strings.TrimSpace(r.URL.Query().Get("threshold")); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value <= 0 || value > 1 {
+			writeProblem(w, r, http.StatusBadRequest, "Threshold must be a number between 0 and 1.", fieldProblem{Field: "threshold", Message: "Use a value such as 0.8."})
+			return
+		}
+		threshold = value
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	if pages.FindFormula(snapshot.Formulas, id) == nil {
+		writeProblem(w, r, http.StatusNotFound, "Formula not found.")
+		return
+	}
+
+	response := similarFormulaResponse{FormulaID: id, Threshold: threshold, Matches: []similarFormulaMatch{}}
+	for _, match := range findSimilarFormulas(id, snapshot.Formulas, snapshot.FormulaIngredients, threshold) {
+		response.Matches = append(response.Matches, similarFormulaMatch{
+			ID:      match.Formula.ID,
+			Name:    match.Formula.Name,
+			Version: match.Formula.Version,
+			Score:   math.Round(match.Score*1000) / 1000,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		applog.Error(r.Context(), "failed to encode similar formulas", "error", err)
+	}
+}
+
+// formulaComposition reduces a formula's rows to each ingredient's share of
+// the total weight. Rows are keyed by aroma chemical or sub-formula, so the
+// same material entered twice, or in different units, counts once.
+func formulaComposition(ingredients []models.FormulaIngredient) map[string]float64 {
+	composition := map[string]float64{}
+	total := 0.0
+	for _, ingredient := range ingredients {
+		var key string
+		switch {
+		case ingredient.AromaChemicalID != nil:
+			key = fmt.Sprintf("chemical:%d", *ingredient.AromaChemicalID)
+		case ingredient.SubFormulaID != nil:
+			key = fmt.Sprintf("formula:%d", *ingredient.SubFormulaID)
+		default:
+			continue
+		}
+		grams := normalizeAmount(ingredient.Amount, ingredient.Unit)
+		if grams <= 0 {
+			continue
+		}
+		composition[key] += grams
+		total += grams
+	}
+	for key, grams := range composition {
+		composition[key] = grams / total
+	}
+	return composition
+}
+
+// formulaSimilarityScore is the weighted Jaccard index of two compositions:
+// the sum of the smaller share of each ingredient over the sum of the larger.
+func formulaSimilarityScore(a, b map[string]float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	minSum, maxSum := 0.0, 0.0
+	for key, share := range a {
+		other := b[key]
+		minSum += math.Min(share, other)
+		maxSum += math.Max(share, other)
+	}
+	for key, share := range b {
+		if _, ok := a[key]; !ok {
+			maxSum += share
+		}
+	}
+	if maxSum == 0 {
+		return 0
+	}
+	return minSum / maxSum
+}
+
+// findSimilarFormulas scores the formula against every other latest-version
+// formula and returns those at or above threshold, most similar first.
+func findSimilarFormulas(formulaID uint, formulas []models.Formula, ingredients []models.FormulaIngredient, threshold float64) []formulaSimilarity {
+	rows := map[uint][]models.FormulaIngredient{}
+	for _, ingredient := range ingredients {
+		rows[ingredient.FormulaID] = append(rows[ingredient.FormulaID], ingredient)
+	}
+	target := formulaComposition(rows[formulaID])
+	if len(target) == 0 {
+		return nil
+	}
+
+	var matches []formulaSimilarity
+	for i := range formulas {
+		candidate := &formulas[i]
+		if candidate.ID == formulaID || !candidate.IsLatest {
+			continue
+		}
+		score := formulaSimilarityScore(target, formulaComposition(rows[candidate.ID]))
+		if score >= threshold {
+			matches = append(matches, formulaSimilarity{Formula: candidate, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Formula.Name < matches[j].Formula.Name
+	})
+	return matches
+}
+
+// duplicateFormulaWarning describes the closest likely duplicate of a formula,
+// or returns an empty string when there is none.
+func duplicateFormulaWarning(formulaID uint, formulas []models.Formula, ingredients []models.FormulaIngredient) string {
+	matches := findSimilarFormulas(formulaID, formulas, ingredients, duplicateSimilarityThreshold)
+	if len(matches) == 0 {
+		return ""
+	}
+	best := matches[0]
+	warning := fmt.Sprintf("It is %.0f%% similar to \"%s\" (v%d)", math.Floor(best.Score*100), best.Formula.Name, best.Formula.Version)
+	switch extra := len(matches) - 1; extra {
+	case 0:
+		return warning + "."
+	case 1:
+		return warning + " and 1 other formula."
+	default:
+		return fmt.Sprintf("%s and %d other formulas.", warning, extra)
+	}
+}
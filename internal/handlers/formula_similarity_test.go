@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func chemicalRow(formulaID, chemicalID uint, amount float64, unit string) models.FormulaIngredient {
+	id := chemicalID
+	return models.FormulaIngredient{FormulaID: formulaID, AromaChemicalID: &id, Amount: amount, Unit: unit}
+}
+
+func TestFormulaSimilarityScore(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		a, b []models.FormulaIngredient
+		want float64
+	}{
+		{
+			name: "identical proportions in different units",
+			a:    []models.FormulaIngredient{chemicalRow(1, 1, 600, "mg"), chemicalRow(1, 2, 400, "mg")},
+			b:    []models.FormulaIngredient{chemicalRow(2, 1, 3, "g"), chemicalRow(2, 2, 2, "g")},
+			want: 1,
+		},
+		{
+			name: "repeated rows are merged",
+			a:    []models.FormulaIngredient{chemicalRow(1, 1, 5, "g"), chemicalRow(1, 1, 5, "g")},
+			b:    []models.FormulaIngredient{chemicalRow(2, 1, 1, "g")},
+			want: 1,
+		},
+		{
+			name: "shifted balance",
+			a:    []models.FormulaIngredient{chemicalRow(1, 1, 50, "g"), chemicalRow(1, 2, 50, "g")},
+			b:    []models.FormulaIngredient{chemicalRow(2, 1, 60, "g"), chemicalRow(2, 2, 40, "g")},
+			want: 0.9 / 1.1,
+		},
+		{
+			name: "disjoint",
+			a:    []models.FormulaIngredient{chemicalRow(1, 1, 1, "g")},
+			b:    []models.FormulaIngredient{chemicalRow(2, 2, 1, "g")},
+			want: 0,
+		},
+		{
+			name: "empty formulas never match",
+			a:    nil,
+			b:    nil,
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := formulaSimilarityScore(formulaComposition(tt.a), formulaComposition(tt.b))
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("score = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateFormulaWarning(t *testing.T) {
+	t.Parallel()
+
+	formulas := []models.Formula{
+		{Name: "Chypre", Version: 1, IsLatest: true},
+		{Name: "Chypre copy", Version: 1, IsLatest: true},
+		{Name: "Chypre", Version: 0, IsLatest: false},
+		{Name: "Citrus", Version: 1, IsLatest: true},
+	}
+	for i := range formulas {
+		formulas[i].ID = uint(i + 1)
+	}
+	ingredients := []models.FormulaIngredient{
+		chemicalRow(1, 1, 95, "g"), chemicalRow(1, 2, 5, "g"),
+		chemicalRow(2, 1, 96, "g"), chemicalRow(2, 2, 4, "g"),
+		chemicalRow(3, 1, 95, "g"), chemicalRow(3, 2, 5, "g"),
+		chemicalRow(4, 3, 100, "g"),
+	}
+
+	warning := duplicateFormulaWarning(1, formulas, ingredients)
+	if !strings.Contains(warning, "\"Chypre copy\" (v1)") || strings.Contains(warning, "other") {
+		t.Fatalf("expected only the latest copy to be flagged, got %q", warning)
+	}
+	if warning := duplicateFormulaWarning(4, formulas, ingredients); warning != "" {
+		t.Fatalf("expected no warning for a distinct formula, got %q", warning)
+	}
+}
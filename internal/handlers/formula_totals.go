@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// FormulaTotals recomputes the total, each row's share and the pyramid
+// distribution of the formula editor's unsaved rows, so the editor shows
+// the same figures the batch report will produce. Nothing is saved.
+func FormulaTotals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formulaID := pages.ParseUint(r.FormValue("id"))
+	rows := formulaDraftRows(r, snapshot)
+	renderComponent(w, r, pages.FormulaTotalsSummary(formulaDraftTotals(snapshot, formulaID, rows)))
+}
+
+// formulaDraftTotals sums the draft rows. Rows that mix percentages and
+// amounts cannot be compared, so they yield no totals; FormulaValidate warns
+// about them.
+func formulaDraftTotals(snapshot pages.WorkspaceSnapshot, formulaID uint, rows []formulaDraftRow) pages.FormulaTotals {
+	var totals pages.FormulaTotals
+	percentRows := 0
+	for _, row := range rows {
+		if row.Percent {
+			percentRows++
+		}
+		totals.Total += row.Amount
+	}
+	if percentRows > 0 && percentRows < len(rows) || totals.Total <= 0 {
+		return pages.FormulaTotals{}
+	}
+	totals.Percent = percentRows > 0
+
+	byFormula := make(map[uint][]models.FormulaIngredient)
+	for _, ingredient := range snapshot.FormulaIngredients {
+		byFormula[ingredient.FormulaID] = append(byFormula[ingredient.FormulaID], ingredient)
+	}
+	shares := map[uint]float64{}
+	for _, row := range rows {
+		share := row.Amount / totals.Total
+		totals.Rows = append(totals.Rows, pages.FormulaTotalRow{
+			Name:   ingredientRowName(snapshot, row.AromaChemicalID, row.SubFormulaID, row.Stock),
+			Amount: row.Amount,
+			Share:  share * 100,
+		})
+		switch {
+		case row.SubFormulaID != nil:
+			addSubFormulaShares(shares, *row.SubFormulaID, share, byFormula, snapshot.AromaChemicals, map[uint]bool{formulaID: true}, false)
+		case row.AromaChemicalID != nil:
+			shares[*row.AromaChemicalID] += share
+		}
+	}
+	totals.Pyramid = pyramidDistribution(snapshot.AromaChemicals, shares)
+	return totals
+}
+
+// pyramidDistribution groups chemical shares by pyramid position in the
+// order the batch report lists them: base notes first, then materials
+// spanning the whole pyramid and those without a position.
+func pyramidDistribution(chemicals []models.AromaChemical, shares map[uint]float64) []pages.PyramidShare {
+	byPosition := map[string]float64{}
+	for id, share := range shares {
+		position := ""
+		if chemical := pages.FindAromaChemical(chemicals, id); chemical != nil {
+			position = pages.CanonicalPyramidPosition(chemical.PyramidPosition)
+		}
+		byPosition[position] += share
+	}
+
+	distribution := make([]pages.PyramidShare, 0, len(byPosition))
+	for position, share := range byPosition {
+		distribution = append(distribution, pages.PyramidShare{
+			Position: position,
+			Label:    pages.PyramidPositionLabel(position),
+			Share:    share * 100,
+		})
+	}
+	sort.Slice(distribution, func(i, j int) bool {
+		ri, rj := pyramidRank(distribution[i].Position), pyramidRank(distribution[j].Position)
+		if ri != rj {
+			return ri < rj
+		}
+		return distribution[i].Position > distribution[j].Position
+	})
+	return distribution
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+func TestFormulaTotalsSummarisesDraft(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	bergamot := models.AromaChemical{IngredientName: "Bergamot", OwnerID: 3, Public: true, PyramidPosition: "top"}
+	ambrox := models.AromaChemical{IngredientName: "Ambroxan", OwnerID: 3, Public: true, PyramidPosition: "base"}
+	hedione := models.AromaChemical{IngredientName: "Hedione", OwnerID: 3, Public: true, PyramidPosition: "heart"}
+	for _, chemical := range []*models.AromaChemical{&bergamot, &ambrox, &hedione} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	accord := models.Formula{Name: "Amber accord", Version: 1, IsLatest: true}
+	if err := db.Create(&accord).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	for _, ingredient := range []models.FormulaIngredient{
+		{FormulaID: accord.ID, AromaChemicalID: &ambrox.ID, Amount: 750, Unit: "mg"},
+		{FormulaID: accord.ID, AromaChemicalID: &hedione.ID, Amount: 250, Unit: "mg"},
+	} {
+		if err := db.Create(&ingredient).Error; err != nil {
+			t.Fatalf("create ingredient: %v", err)
+		}
+	}
+
+	req := authenticatedFormRequest(t, sm, "/app/sections/formulas/totals", url.Values{
+		"ingredient_row_key": {"a", "b", "c"},
+		"ingredient_source":  {fmt.Sprintf("chem:%d", bergamot.ID), fmt.Sprintf("formula:%d", accord.ID), fmt.Sprintf("chem:%d", hedione.ID)},
+		"ingredient_amount":  {"6", "4000", "5"},
+		"ingredient_unit":    {"g", "mg", "g"},
+		"ingredient_remove":  {"c"},
+	}, 3)
+	snapshot := buildWorkspaceSnapshot(req)
+	if err := req.ParseForm(); err != nil {
+		t.Fatalf("parse form: %v", err)
+	}
+	totals := formulaDraftTotals(snapshot, 0, formulaDraftRows(req, snapshot))
+
+	if totals.Percent || math.Abs(totals.Total-10) > 1e-9 {
+		t.Fatalf("expected a 10 g total, got %+v", totals)
+	}
+	if len(totals.Rows) != 2 || math.Abs(totals.Rows[0].Share-60) > 1e-9 || totals.Rows[1].Name != "Amber accord" {
+		t.Fatalf("unexpected rows: %+v", totals.Rows)
+	}
+	want := []pages.PyramidShare{
+		{Position: "base", Label: "Base", Share: 30},
+		{Position: "heart", Label: "Heart", Share: 10},
+		{Position: "top", Label: "Top", Share: 60},
+	}
+	if len(totals.Pyramid) != len(want) {
+		t.Fatalf("unexpected pyramid: %+v", totals.Pyramid)
+	}
+	for i, level := range totals.Pyramid {
+		if level.Position != want[i].Position || level.Label != want[i].Label || math.Abs(level.Share-want[i].Share) > 1e-9 {
+			t.Fatalf("pyramid[%d] = %+v, want %+v", i, level, want[i])
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	FormulaTotals(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/totals", url.Values{
+		"ingredient_row_key": {"a", "b"},
+		"ingredient_source":  {fmt.Sprintf("chem:%d", bergamot.ID), fmt.Sprintf("chem:%d", ambrox.ID)},
+		"ingredient_amount":  {"60", "20"},
+		"ingredient_unit":    {"%", "%"},
+	}, 3))
+	body := rec.Body.String()
+	if !strings.Contains(body, "80.00%") || !strings.Contains(body, "75.00%") {
+		t.Fatalf("expected the percentage total and normalised shares: %s", body)
+	}
+}
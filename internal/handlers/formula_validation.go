@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// percentTotalTolerance is how far, in percentage points, rows entered as
+// percentages may add up from 100% before the editor warns.
+const percentTotalTolerance = 1.0
+
+// formulaDraftRow is one editor row that names an ingredient. Amount is in
+// grams, or in percent when Percent is set.
+type formulaDraftRow struct {
+	Source          string
+	Amount          float64
+	Percent         bool
+	AromaChemicalID *uint
+	SubFormulaID    *uint
+	Stock           *models.InventoryItem
+}
+
+// FormulaValidate checks the formula editor's unsaved rows and renders any
+// warnings, so problems surface while the formula is being edited. Nothing
+// is saved and incomplete rows are skipped; FormulaUpdate still has the
+// final say on what can be stored.
+func FormulaValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formulaID := pages.ParseUint(r.FormValue("id"))
+	rows := formulaDraftRows(r, snapshot)
+	warnings := formulaDraftWarnings(r, snapshot, formulaID, rows)
+	renderComponent(w, r, pages.FormulaWarnings(warnings))
+}
+
+// formulaDraftRows reads the editor rows that are not marked for removal
+// and name a usable ingredient and amount.
+func formulaDraftRows(r *http.Request, snapshot pages.WorkspaceSnapshot) []formulaDraftRow {
+	removals := map[string]struct{}{}
+	for _, key := range r.Form["ingredient_remove"] {
+		removals[strings.TrimSpace(key)] = struct{}{}
+	}
+
+	rowKeys := r.Form["ingredient_row_key"]
+	sources := r.Form["ingredient_source"]
+	amounts := r.Form["ingredient_amount"]
+	units := r.Form["ingredient_unit"]
+
+	var rows []formulaDraftRow
+	for i, source := range sources {
+		if i < len(rowKeys) {
+			if _, marked := removals[strings.TrimSpace(rowKeys[i])]; marked {
+				continue
+			}
+		}
+		if i >= len(amounts) || i >= len(units) {
+			break
+		}
+		chemID, subID, stockID, err := parseIngredientSource(source)
+		if err != nil {
+			continue
+		}
+		amount, err := strconv.ParseFloat(strings.TrimSpace(amounts[i]), 64)
+		if err != nil || amount <= 0 {
+			continue
+		}
+
+		row := formulaDraftRow{Source: strings.TrimSpace(source), AromaChemicalID: chemID, SubFormulaID: subID}
+		if stockID != nil {
+			if row.Stock = pages.FindStockSolution(snapshot.AromaChemicals, *stockID); row.Stock == nil {
+				continue
+			}
+			neatID := row.Stock.AromaChemicalID
+			row.AromaChemicalID = &neatID
+		}
+		unit := strings.TrimSpace(units[i])
+		if row.Percent = unit == percentEntryUnit; row.Percent {
+			row.Amount = amount
+		} else {
+			row.Amount = normalizeAmount(amount, unit)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// formulaDraftWarnings lists repeated ingredients, percentages that do not
+// add up to 100% and materials whose neat share of the concentrate exceeds
+// their IFRA limit, counting what sub-formulas contribute.
+func formulaDraftWarnings(r *http.Request, snapshot pages.WorkspaceSnapshot, formulaID uint, rows []formulaDraftRow) []string {
+	var warnings []string
+	format := pages.NumberFormatFrom(r.Context())
+
+	seen := map[string]int{}
+	var order []string
+	names := map[string]string{}
+	for _, row := range rows {
+		if seen[row.Source] == 0 {
+			order = append(order, row.Source)
+			names[row.Source] = ingredientRowName(snapshot, row.AromaChemicalID, row.SubFormulaID, row.Stock)
+		}
+		seen[row.Source]++
+	}
+	for _, source := range order {
+		if count := seen[source]; count > 1 {
+			warnings = append(warnings, fmt.Sprintf("%s is listed %d times; combine the rows.", names[source], count))
+		}
+	}
+
+	percentRows := 0
+	total := 0.0
+	for _, row := range rows {
+		if row.Percent {
+			percentRows++
+		}
+		total += row.Amount
+	}
+	if percentRows > 0 && percentRows < len(rows) {
+		warnings = append(warnings, "Use either percentages or amounts for every row, not both.")
+		return warnings
+	}
+	if percentRows > 0 && math.Abs(total-100) > percentTotalTolerance {
+		warnings = append(warnings, fmt.Sprintf("Percentages add up to %s; they will be scaled to 100%% on save.", format.FormatPercent(total)))
+	}
+	if total <= 0 {
+		return warnings
+	}
+
+	byFormula := make(map[uint][]models.FormulaIngredient)
+	for _, ingredient := range snapshot.FormulaIngredients {
+		byFormula[ingredient.FormulaID] = append(byFormula[ingredient.FormulaID], ingredient)
+	}
+	shares := map[uint]float64{}
+	for _, row := range rows {
+		share := row.Amount / total
+		switch {
+		case row.SubFormulaID != nil:
+			addSubFormulaShares(shares, *row.SubFormulaID, share, byFormula, snapshot.AromaChemicals, map[uint]bool{formulaID: true}, true)
+		case row.AromaChemicalID != nil:
+			if row.Stock != nil {
+				share *= row.Stock.NeatFraction()
+			}
+			shares[*row.AromaChemicalID] += share
+		}
+	}
+	for _, chemical := range snapshot.AromaChemicals {
+		share, ok := shares[chemical.ID]
+		if !ok || chemical.MaxIFRAPercentage <= 0 {
+			continue
+		}
+		if percent := share * 100; percent > chemical.MaxIFRAPercentage+1e-9 {
+			warnings = append(warnings, fmt.Sprintf("%s makes up %s of the concentrate, above its IFRA limit of %s.",
+				chemical.IngredientName, format.FormatPercent(percent), format.FormatPercent(chemical.MaxIFRAPercentage)))
+		}
+	}
+	return warnings
+}
+
+// addSubFormulaShares spreads factor, a sub-formula's share of the draft,
+// over the chemicals of its saved composition. With neat, stock solutions
+// count only their neat material; otherwise they count at full weight, as
+// the batch report weighs them. Cycles are skipped; FormulaUpdate reports
+// them.
+func addSubFormulaShares(shares map[uint]float64, formulaID uint, factor float64, byFormula map[uint][]models.FormulaIngredient, chemicals []models.AromaChemical, path map[uint]bool, neat bool) {
+	if path[formulaID] {
+		return
+	}
+	rows := byFormula[formulaID]
+	total := 0.0
+	for _, row := range rows {
+		total += normalizeAmount(row.Amount, row.Unit)
+	}
+	if total <= 0 {
+		return
+	}
+
+	path[formulaID] = true
+	defer delete(path, formulaID)
+	for _, row := range rows {
+		share := factor * normalizeAmount(row.Amount, row.Unit) / total
+		switch {
+		case row.SubFormulaID != nil:
+			addSubFormulaShares(shares, *row.SubFormulaID, share, byFormula, chemicals, path, neat)
+		case row.AromaChemicalID != nil:
+			if neat && row.StockSolutionID != nil {
+				if stock := pages.FindStockSolution(chemicals, *row.StockSolutionID); stock != nil {
+					share *= stock.NeatFraction()
+				}
+			}
+			shares[*row.AromaChemicalID] += share
+		}
+	}
+}
+
+// ingredientRowName labels a composition row for warnings, naming the
+// dilution of a stock solution.
+func ingredientRowName(snapshot pages.WorkspaceSnapshot, chemicalID, subFormulaID *uint, stock *models.InventoryItem) string {
+	switch {
+	case subFormulaID != nil:
+		if formula := pages.FindFormula(snapshot.Formulas, *subFormulaID); formula != nil {
+			return formula.Name
+		}
+	case chemicalID != nil:
+		if chemical := pages.FindAromaChemical(snapshot.AromaChemicals, *chemicalID); chemical != nil {
+			if stock != nil && stock.Diluted() {
+				return chemical.IngredientName + " (" + stock.DilutionLabel() + ")"
+			}
+			return chemical.IngredientName
+		}
+	}
+	return "An ingredient"
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestFormulaValidateReportsDraftProblems(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	limited := models.AromaChemical{IngredientName: "Eugenol", OwnerID: 3, Public: true, MaxIFRAPercentage: 5}
+	free := models.AromaChemical{IngredientName: "Iso E Super", OwnerID: 3, Public: true}
+	for _, chemical := range []*models.AromaChemical{&limited, &free} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	accord := models.Formula{Name: "Clove accord", Version: 1, IsLatest: true}
+	if err := db.Create(&accord).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	if err := db.Create(&models.FormulaIngredient{FormulaID: accord.ID, AromaChemicalID: &limited.ID, Amount: 1, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create ingredient: %v", err)
+	}
+
+	validate := func(values url.Values) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		FormulaValidate(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/validate", values, 3))
+		return rec.Body.String()
+	}
+	chem := func(c models.AromaChemical) string { return fmt.Sprintf("chem:%d", c.ID) }
+
+	body := validate(url.Values{
+		"ingredient_row_key": {"a", "b", "c"},
+		"ingredient_source":  {chem(free), chem(free), fmt.Sprintf("formula:%d", accord.ID)},
+		"ingredient_amount":  {"40", "40", "20"},
+		"ingredient_unit":    {"g", "g", "g"},
+	})
+	if !strings.Contains(body, "Iso E Super is listed 2 times") {
+		t.Fatalf("expected a duplicate warning: %s", body)
+	}
+	if !strings.Contains(body, "Eugenol makes up 20") {
+		t.Fatalf("expected the sub-formula to count towards the IFRA limit: %s", body)
+	}
+
+	body = validate(url.Values{
+		"ingredient_row_key": {"a", "b"},
+		"ingredient_source":  {chem(free), chem(limited)},
+		"ingredient_amount":  {"60", "20"},
+		"ingredient_unit":    {"%", "%"},
+		"ingredient_remove":  {},
+	})
+	if !strings.Contains(body, "Percentages add up to 80") {
+		t.Fatalf("expected a percentage total warning: %s", body)
+	}
+
+	body = validate(url.Values{
+		"ingredient_row_key": {"a", "b", "c"},
+		"ingredient_source":  {chem(free), chem(limited), chem(limited)},
+		"ingredient_amount":  {"97", "3", "50"},
+		"ingredient_unit":    {"%", "%", "%"},
+		"ingredient_remove":  {"c"},
+	})
+	if strings.TrimSpace(body) != "" {
+		t.Fatalf("expected no warnings for a valid draft: %s", body)
+	}
+}
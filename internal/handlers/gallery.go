@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/ratelimit"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	galleryPath = "/api/gallery/formulas"
+	// galleryPageSize is how many formulas one page of the gallery lists.
+	galleryPageSize = 20
+	// galleryAnonymousAuthor credits creators who have not set a name, so
+	// the public API never shows an email address.
+	galleryAnonymousAuthor = "A perfugo member"
+)
+
+// galleryLimiter throttles the public gallery API per client address.
+var galleryLimiter = ratelimit.New(30, 10)
+
+// ConfigureGalleryRateLimit lets each client make perMinute gallery requests
+// a minute after an initial burst.
+func ConfigureGalleryRateLimit(perMinute, burst int) {
+	galleryLimiter = ratelimit.New(perMinute, burst)
+	applog.Debug(nil, "gallery rate limit configured", "perMinute", perMinute, "burst", burst)
+}
+
+// LimitGalleryRequests answers 429 Too Many Requests, with a Retry-After
+// header, to clients that exceed the gallery rate limit.
+func LimitGalleryRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.RemoteAddr
+		if addr, ok := clientAddr(r); ok {
+			key = addr.String()
+		}
+		if ok, wait := galleryLimiter.Allow(key); !ok {
+			applog.Debug(r.Context(), "gallery request throttled", "client", key, "retryAfter", wait.String())
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeProblem(w, r, http.StatusTooManyRequests, "Too many gallery requests; try again later.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// galleryPage is one page of the community gallery.
+type galleryPage struct {
+	Formulas []galleryFormula `json:"formulas"`
+	Page     int              `json:"page"`
+	Next     string           `json:"next,omitempty"`
+}
+
+// galleryFormula is a published formula with the credit embedding sites
+// must show. Composition is only filled in for a single formula.
+type galleryFormula struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	Version     int                `json:"version"`
+	Inspiration string             `json:"inspiration,omitempty"`
+	Published   time.Time          `json:"published"`
+	Updated     time.Time          `json:"updated"`
+	URL         string             `json:"url"`
+	Attribution galleryAttribution `json:"attribution"`
+	Composition []galleryRow       `json:"composition,omitempty"`
+}
+
+type galleryAttribution struct {
+	Author string `json:"author"`
+	Source string `json:"source"`
+	Text   string `json:"text"`
+}
+
+// galleryRow is one line of a published formula as a share of its weight.
+// Kind is "material" or "accord" for a sub-formula.
+type galleryRow struct {
+	Name    string  `json:"name"`
+	Kind    string  `json:"kind"`
+	Percent float64 `json:"percent"`
+}
+
+// GalleryFormulas handles GET /api/gallery/formulas: the latest version of
+// every published formula by an active member, most recently updated first,
+// a page at a time. It needs no authentication and is meant to sit behind
+// LimitGalleryRequests.
+func GalleryFormulas(w http.ResponseWriter, r *http.Request) {
+	if !galleryRequest(w, r) {
+		return
+	}
+	page := max(int(pages.ParseUint(r.URL.Query().Get("page"))), 1)
+
+	var formulas []models.Formula
+	if err := galleryQuery(r.Context()).
+		Order("updated_at desc").Order("id desc").
+		Offset((page - 1) * galleryPageSize).Limit(galleryPageSize + 1).
+		Find(&formulas).Error; err != nil {
+		applog.Error(r.Context(), "failed to list gallery formulas", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "We couldn't load the gallery. Please try again.")
+		return
+	}
+
+	result := galleryPage{Formulas: []galleryFormula{}, Page: page}
+	if len(formulas) > galleryPageSize {
+		formulas = formulas[:galleryPageSize]
+		result.Next = absoluteURL(r, pages.Path(fmt.Sprintf("%s?page=%d", galleryPath, page+1)))
+	}
+	authors, err := loadGalleryAuthors(r.Context(), formulas)
+	if err != nil {
+		applog.Error(r.Context(), "failed to load gallery authors", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "We couldn't load the gallery. Please try again.")
+		return
+	}
+	for _, formula := range formulas {
+		result.Formulas = append(result.Formulas, newGalleryFormula(r, formula, authors))
+	}
+	writeGalleryJSON(w, r, result)
+}
+
+// GalleryFormula handles GET /api/gallery/formulas/{id}: one published
+// formula with its composition as percentages.
+func GalleryFormula(w http.ResponseWriter, r *http.Request) {
+	if !galleryRequest(w, r) {
+		return
+	}
+
+	var formula models.Formula
+	err := galleryQuery(r.Context()).
+		Preload("Ingredients", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		Preload("Ingredients.AromaChemical").
+		Preload("Ingredients.SubFormula").
+		Preload("Ingredients.StockSolution").
+		First(&formula, pages.ParseUint(r.PathValue("id"))).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeProblem(w, r, http.StatusNotFound, "Formula not found in the gallery.")
+		return
+	}
+	authors, authorErr := loadGalleryAuthors(r.Context(), []models.Formula{formula})
+	if err == nil {
+		err = authorErr
+	}
+	if err != nil {
+		applog.Error(r.Context(), "failed to load gallery formula", "error", err, "formulaID", r.PathValue("id"))
+		writeProblem(w, r, http.StatusInternalServerError, "We couldn't load the formula. Please try again.")
+		return
+	}
+
+	item := newGalleryFormula(r, formula, authors)
+	item.Composition = galleryComposition(formula.Ingredients)
+	writeGalleryJSON(w, r, item)
+}
+
+// galleryRequest checks the method and that there is a database to read.
+func galleryRequest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "The gallery is read-only; use GET.")
+		return false
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return false
+	}
+	return true
+}
+
+// galleryQuery selects the latest published versions created by members
+// whose accounts are active.
+func galleryQuery(ctx context.Context) *gorm.DB {
+	active := database.Model(&models.User{}).Select("id").Where("deactivated_at IS NULL")
+	return database.WithContext(ctx).
+		Where("published = ? AND is_latest = ?", true, true).
+		Where("created_by_id IN (?)", active)
+}
+
+func loadGalleryAuthors(ctx context.Context, formulas []models.Formula) (map[uint]string, error) {
+	ids := make([]uint, 0, len(formulas))
+	for _, formula := range formulas {
+		if formula.CreatedByID != nil {
+			ids = append(ids, *formula.CreatedByID)
+		}
+	}
+	authors := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return authors, nil
+	}
+	var users []models.User
+	if err := database.WithContext(ctx).Select("id", "name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		authors[user.ID] = pages.UserProfile{Name: user.Name}.DisplayName()
+	}
+	return authors, nil
+}
+
+func newGalleryFormula(r *http.Request, formula models.Formula, authors map[uint]string) galleryFormula {
+	author := galleryAnonymousAuthor
+	if formula.CreatedByID != nil && authors[*formula.CreatedByID] != "" {
+		author = authors[*formula.CreatedByID]
+	}
+	source := requestHostname(r)
+	return galleryFormula{
+		ID:          formula.ID,
+		Name:        formula.Name,
+		Version:     formula.Version,
+		Inspiration: formula.Inspiration,
+		Published:   formula.CreatedAt.UTC(),
+		Updated:     formula.UpdatedAt.UTC(),
+		URL:         absoluteURL(r, pages.Path(fmt.Sprintf("%s/%d", galleryPath, formula.ID))),
+		Attribution: galleryAttribution{
+			Author: author,
+			Source: source,
+			Text:   fmt.Sprintf("“%s” v%d by %s, published on perfugo (%s)", formula.Name, formula.Version, author, source),
+		},
+	}
+}
+
+// galleryComposition turns a formula's rows into shares of its weight,
+// rounded to two decimals.
+func galleryComposition(ingredients []models.FormulaIngredient) []galleryRow {
+	total := 0.0
+	for _, ingredient := range ingredients {
+		total += normalizeAmount(ingredient.Amount, ingredient.Unit)
+	}
+	rows := make([]galleryRow, 0, len(ingredients))
+	if total <= 0 {
+		return rows
+	}
+	for _, ingredient := range ingredients {
+		kind := "material"
+		if ingredient.SubFormulaID != nil {
+			kind = "accord"
+		}
+		share := normalizeAmount(ingredient.Amount, ingredient.Unit) / total * 100
+		rows = append(rows, galleryRow{
+			Name:    pages.IngredientDisplayName(ingredient),
+			Kind:    kind,
+			Percent: math.Round(share*100) / 100,
+		})
+	}
+	return rows
+}
+
+// writeGalleryJSON answers with a body any site may fetch and cache briefly.
+func writeGalleryJSON(w http.ResponseWriter, r *http.Request, body any) {
+	w.Header().Set("Content-Type", mediaJSON)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		applog.Error(r.Context(), "failed to encode gallery response", "error", err)
+	}
+}
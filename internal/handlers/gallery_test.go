@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/internal/ratelimit"
+	"perfugo/models"
+)
+
+func TestGalleryListsPublishedFormulas(t *testing.T) {
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	gone := time.Now()
+	ada := models.User{Name: "Ada", Email: "ada@example.com", PasswordHash: "x"}
+	nameless := models.User{Email: "quiet@example.com", PasswordHash: "x"}
+	departed := models.User{Name: "Bo", Email: "bo@example.com", PasswordHash: "x", DeactivatedAt: &gone}
+	for _, user := range []*models.User{&ada, &nameless, &departed} {
+		db.Create(user)
+	}
+	bergamot := models.AromaChemical{IngredientName: "Bergamot", OwnerID: ada.ID}
+	db.Create(&bergamot)
+	accord := models.Formula{Name: "Amber accord", Version: 1, IsLatest: true, CreatedByID: &ada.ID, Published: true}
+	db.Create(&accord)
+	db.Create(&models.FormulaIngredient{FormulaID: accord.ID, AromaChemicalID: &bergamot.ID, Amount: 1, Unit: "g"})
+	chypre := models.Formula{Name: "Chypre", Version: 2, IsLatest: true, CreatedByID: &ada.ID, Published: true}
+	db.Create(&chypre)
+	db.Create(&models.FormulaIngredient{FormulaID: chypre.ID, AromaChemicalID: &bergamot.ID, Amount: 750, Unit: "mg", Position: 0})
+	db.Create(&models.FormulaIngredient{FormulaID: chypre.ID, SubFormulaID: &accord.ID, Amount: 0.25, Unit: "g", Position: 1})
+	for _, formula := range []models.Formula{
+		{Name: "Draft", Version: 1, IsLatest: true, CreatedByID: &ada.ID},
+		{Name: "Departed", Version: 1, IsLatest: true, CreatedByID: &departed.ID, Published: true},
+		{Name: "Quiet", Version: 1, IsLatest: true, CreatedByID: &nameless.ID, Published: true},
+	} {
+		db.Create(&formula)
+	}
+	superseded := models.Formula{Name: "Old chypre", Version: 1, CreatedByID: &ada.ID, Published: true}
+	db.Create(&superseded)
+	db.Model(&superseded).Update("is_latest", false)
+
+	rec := httptest.NewRecorder()
+	GalleryFormulas(rec, httptest.NewRequest(http.MethodGet, galleryPath, nil))
+	var page galleryPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list: status %d, %v", rec.Code, err)
+	}
+	names := map[string]string{}
+	for _, formula := range page.Formulas {
+		names[formula.Name] = formula.Attribution.Author
+	}
+	if len(names) != 3 || names["Chypre"] != "Ada" || names["Quiet"] != galleryAnonymousAuthor {
+		t.Fatalf("unexpected gallery: %v", names)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatal("expected the gallery to be embeddable from any site")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, galleryPath+"/"+strconv.Itoa(int(chypre.ID)), nil)
+	req.SetPathValue("id", strconv.Itoa(int(chypre.ID)))
+	rec = httptest.NewRecorder()
+	GalleryFormula(rec, req)
+	var formula galleryFormula
+	if err := json.NewDecoder(rec.Body).Decode(&formula); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("detail: status %d, %v", rec.Code, err)
+	}
+	want := []galleryRow{{Name: "Bergamot", Kind: "material", Percent: 75}, {Name: "Amber accord", Kind: "accord", Percent: 25}}
+	if len(formula.Composition) != 2 || formula.Composition[0] != want[0] || formula.Composition[1] != want[1] {
+		t.Fatalf("unexpected composition: %+v", formula.Composition)
+	}
+	if !strings.Contains(formula.Attribution.Text, "by Ada") {
+		t.Fatalf("unexpected attribution: %+v", formula.Attribution)
+	}
+}
+
+func TestGalleryRateLimit(t *testing.T) {
+	prevLimiter := galleryLimiter
+	galleryLimiter = ratelimit.New(1, 2)
+	t.Cleanup(func() { galleryLimiter = prevLimiter })
+
+	handler := LimitGalleryRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, galleryPath, nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := send("203.0.113.7:4000"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i+1, rec.Code)
+		}
+	}
+	rec := send("203.0.113.7:4001")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := send("198.51.100.1:4000"); rec.Code != http.StatusOK {
+		t.Fatalf("another client was throttled: %d", rec.Code)
+	}
+}
@@ -32,3 +32,25 @@ func TestHealth(t *testing.T) {
 		t.Fatal("expected response time to be populated")
 	}
 }
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	w := httptest.NewRecorder()
+	Version(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, key := range []string{"version", "commit", "buildDate"} {
+		if resp[key] == "" {
+			t.Fatalf("expected %q to be populated: %v", key, resp)
+		}
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"net/http"
 
 	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
 )
 
 // Home renders the landing experience for the perfumery platform.
 func Home(w http.ResponseWriter, r *http.Request) {
 	applog.Debug(r.Context(), "redirecting home request to landing page", "method", r.Method)
-	http.Redirect(w, r, "/assets/index.html", 302)
+	http.Redirect(w, r, pages.Path("/assets/index.html"), 302)
 }
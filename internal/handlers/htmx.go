@@ -1,7 +1,134 @@
 package handlers
 
-import "net/http"
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+)
+
+// requestErrorTarget is the layout region failed HTMX requests render into.
+const requestErrorTarget = "#request-error"
+
+// maxEnvelopeMessage caps how much of a bare error body is kept as the
+// message of the error envelope.
+const maxEnvelopeMessage = 512
 
 func isHTMX(r *http.Request) bool {
 	return r.Header.Get("HX-Request") == "true" || r.Header.Get("HX-Boosted") == "true"
 }
+
+// writeHTMXError answers a failed request with status. HTMX requests get an
+// inline error fragment retargeted at the layout's error region, because
+// HTMX does not swap error responses into the element that made them; other
+// clients get a plain text error.
+func writeHTMXError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	message = envelopeMessage(status, message)
+	if !isHTMX(r) {
+		http.Error(w, message, status)
+		return
+	}
+
+	applog.Debug(r.Context(), "responding with htmx error envelope", "path", r.URL.Path, "status", status)
+	w.Header().Del("Content-Length")
+	w.Header().Set("HX-Retarget", requestErrorTarget)
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.RequestError(status, message).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render htmx error envelope", "error", err)
+	}
+}
+
+// envelopeMessage turns a terse handler error such as "invalid form
+// submission" into a sentence, falling back to the status text.
+func envelopeMessage(status int, message string) string {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	first, size := utf8.DecodeRuneInString(message)
+	message = string(unicode.ToUpper(first)) + message[size:]
+	if !strings.HasSuffix(message, ".") && !strings.HasSuffix(message, "!") && !strings.HasSuffix(message, "?") {
+		message += "."
+	}
+	return message
+}
+
+// HTMXErrors gives the bare error responses of every handler, those written
+// with http.Error or only a status code, the HTMX error envelope. Responses
+// that render their own HTML or JSON, redirect with HX-Redirect or choose
+// their own target are passed through untouched.
+func HTMXErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isHTMX(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ew := &envelopeWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, r)
+		if ew.status != 0 {
+			writeHTMXError(w, r, ew.status, ew.body.String())
+		}
+	})
+}
+
+// envelopeWriter holds back bare error responses so HTMXErrors can replace
+// them with the error envelope.
+type envelopeWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	// status is set once a bare error response has been intercepted.
+	status int
+	body   bytes.Buffer
+}
+
+func (w *envelopeWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status >= http.StatusBadRequest && bareError(w.Header()) {
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *envelopeWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status == 0 {
+		return w.ResponseWriter.Write(p)
+	}
+	if room := maxEnvelopeMessage - w.body.Len(); room > 0 {
+		w.body.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (w *envelopeWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok && w.status == 0 {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *envelopeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bareError reports whether the headers describe a plain text or empty error
+// body rather than a response the handler rendered for the client.
+func bareError(header http.Header) bool {
+	if header.Get("HX-Redirect") != "" || header.Get("HX-Retarget") != "" || header.Get("HX-Location") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	return contentType == "" || strings.HasPrefix(contentType, "text/plain")
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTMXErrorsWrapsBareErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		htmx     bool
+		handler  http.HandlerFunc
+		status   int
+		envelope bool
+		body     string
+	}{
+		{
+			name: "http.Error",
+			htmx: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "invalid form submission", http.StatusBadRequest)
+			},
+			status:   http.StatusBadRequest,
+			envelope: true,
+			body:     "Invalid form submission.",
+		},
+		{
+			name:     "status only",
+			htmx:     true,
+			handler:  func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusMethodNotAllowed) },
+			status:   http.StatusMethodNotAllowed,
+			envelope: true,
+			body:     "Method Not Allowed.",
+		},
+		{
+			name: "rendered fragment",
+			htmx: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte("<form>fix the name</form>"))
+			},
+			status: http.StatusUnprocessableEntity,
+			body:   "<form>fix the name</form>",
+		},
+		{
+			name: "htmx redirect",
+			htmx: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("HX-Redirect", "/maintenance")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name: "plain request",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "invalid form submission", http.StatusBadRequest)
+			},
+			status: http.StatusBadRequest,
+			body:   "invalid form submission",
+		},
+		{
+			name:    "success",
+			htmx:    true,
+			handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("saved")) },
+			status:  http.StatusOK,
+			body:    "saved",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPost, "/app/formulas", nil)
+			if tt.htmx {
+				req.Header.Set("HX-Request", "true")
+			}
+			w := httptest.NewRecorder()
+			HTMXErrors(tt.handler).ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			retarget := w.Header().Get("HX-Retarget")
+			if tt.envelope != (retarget == requestErrorTarget) {
+				t.Fatalf("expected envelope %v, got HX-Retarget %q", tt.envelope, retarget)
+			}
+			if tt.envelope {
+				if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+					t.Fatalf("expected an HTML fragment, got %q", ct)
+				}
+				if !strings.Contains(w.Body.String(), `role="alert"`) {
+					t.Fatalf("expected an alert fragment, got %q", w.Body.String())
+				}
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Fatalf("expected body to contain %q, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	sessionImpersonatorIDKey    = "auth:impersonator:id"
+	sessionImpersonatorEmailKey = "auth:impersonator:email"
+	sessionImpersonationEndsKey = "auth:impersonation:ends_at"
+
+	// impersonationTTL bounds an impersonation; the administrator's own
+	// session comes back once it passes.
+	impersonationTTL = 30 * time.Minute
+	// maxImpersonationReason caps the reason recorded in the audit log.
+	maxImpersonationReason = 255
+)
+
+// impersonator returns the administrator behind an impersonated session.
+func impersonator(r *http.Request) (uint, bool) {
+	if sessionManager == nil {
+		return 0, false
+	}
+	id := sessionManager.GetInt(r.Context(), sessionImpersonatorIDKey)
+	return uint(id), id > 0
+}
+
+// loadImpersonationBanner describes the running impersonation for the
+// workspace banner, or returns the zero banner when there is none.
+func loadImpersonationBanner(r *http.Request) pages.ImpersonationBanner {
+	if _, ok := impersonator(r); !ok {
+		return pages.ImpersonationBanner{}
+	}
+	ctx := r.Context()
+	return pages.ImpersonationBanner{
+		Active:     true,
+		UserEmail:  sessionManager.GetString(ctx, sessionUserEmailKey),
+		AdminEmail: sessionManager.GetString(ctx, sessionImpersonatorEmailKey),
+		EndsAt:     time.Unix(sessionManager.GetInt64(ctx, sessionImpersonationEndsKey), 0),
+	}
+}
+
+// recordImpersonationAudit appends an impersonation entry attributed to the
+// administrator.
+func recordImpersonationAudit(ctx context.Context, action string, adminID, userID uint, summary string) {
+	if database == nil {
+		return
+	}
+	entry := models.AuditEntry{
+		ActorID:    &adminID,
+		Action:     action,
+		EntityType: models.AuditEntityUser,
+		EntityID:   userID,
+		Summary:    summary,
+	}
+	if err := database.WithContext(ctx).Create(&entry).Error; err != nil {
+		applog.Error(ctx, "failed to audit impersonation", "error", err, "action", action, "adminID", adminID, "userID", userID)
+	}
+}
+
+// enforceImpersonation runs on every authenticated request. It ends an
+// expired impersonation and audits each change made while impersonating.
+// It returns false when it has answered the request itself.
+func enforceImpersonation(w http.ResponseWriter, r *http.Request) bool {
+	adminID, ok := impersonator(r)
+	if !ok {
+		return true
+	}
+	userID, _ := currentUserID(r)
+	if nowFunc().Unix() >= sessionManager.GetInt64(r.Context(), sessionImpersonationEndsKey) {
+		applog.Info(r.Context(), "impersonation expired", "adminID", adminID, "userID", userID)
+		if err := endImpersonation(r, "Impersonation expired."); err != nil {
+			http.Error(w, "unable to restore your session", http.StatusInternalServerError)
+			return false
+		}
+		redirectToApp(w, r)
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		recordImpersonationAudit(r.Context(), models.AuditImpersonatedRequest, adminID, userID, r.Method+" "+r.URL.Path)
+	}
+	return true
+}
+
+// DenyWhileImpersonating guards endpoints that reveal or issue credentials,
+// such as API tokens and feed URLs, so an impersonating administrator
+// cannot take them away.
+func DenyWhileImpersonating(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := impersonator(r); ok {
+			applog.Debug(r.Context(), "credential endpoint refused during impersonation", "path", r.URL.Path)
+			http.Error(w, "not available while impersonating a user", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ImpersonationStart lets an administrator act as a member to reproduce a
+// problem in their workspace. The session switches to the member without
+// their password; the start, every change and the end are audited, and
+// the administrator's session returns after impersonationTTL.
+func ImpersonationStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil || sessionManager == nil {
+		http.Error(w, "impersonation not available", http.StatusServiceUnavailable)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	adminID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if _, nested := impersonator(r); nested {
+		renderComponent(w, r, pages.ImpersonationControl("Stop the current impersonation first."))
+		return
+	}
+
+	ctx := r.Context()
+	email := strings.TrimSpace(r.FormValue("email"))
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if email == "" || reason == "" || len(reason) > maxImpersonationReason {
+		renderComponent(w, r, pages.ImpersonationControl("Give the member's email and a reason of at most 255 characters; both go into the audit log."))
+		return
+	}
+	user, err := findUserByEmail(r, email)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		renderComponent(w, r, pages.ImpersonationControl("No account uses that email."))
+		return
+	case err != nil:
+		http.Error(w, "unable to load user", http.StatusInternalServerError)
+		return
+	case user.ID == adminID:
+		renderComponent(w, r, pages.ImpersonationControl("You are already signed in as that account."))
+		return
+	case user.IsAdmin():
+		renderComponent(w, r, pages.ImpersonationControl("Administrators cannot be impersonated."))
+		return
+	case !user.IsActive():
+		renderComponent(w, r, pages.ImpersonationControl("That account is deactivated."))
+		return
+	}
+
+	adminEmail := sessionManager.GetString(ctx, sessionUserEmailKey)
+	recordImpersonationAudit(ctx, models.AuditImpersonationStarted, adminID, user.ID,
+		fmt.Sprintf("%s started impersonating %s: %s", adminEmail, user.Email, reason))
+	if err := establishSession(r, user); err != nil {
+		http.Error(w, "unable to start impersonation", http.StatusInternalServerError)
+		return
+	}
+	sessionManager.Put(ctx, sessionImpersonatorIDKey, int(adminID))
+	sessionManager.Put(ctx, sessionImpersonatorEmailKey, adminEmail)
+	sessionManager.Put(ctx, sessionImpersonationEndsKey, nowFunc().Add(impersonationTTL).Unix())
+	applog.Info(ctx, "impersonation started", "adminID", adminID, "userID", user.ID)
+	redirectToApp(w, r)
+}
+
+// ImpersonationStop returns the administrator to their own session.
+func ImpersonationStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := impersonator(r); ok {
+		if err := endImpersonation(r, "Impersonation stopped."); err != nil {
+			http.Error(w, "unable to restore your session", http.StatusInternalServerError)
+			return
+		}
+	}
+	redirectToApp(w, r)
+}
+
+// endImpersonation audits the end of an impersonation and signs the
+// administrator back in as themselves.
+func endImpersonation(r *http.Request, summary string) error {
+	ctx := r.Context()
+	adminID, _ := impersonator(r)
+	userID, _ := currentUserID(r)
+	recordImpersonationAudit(ctx, models.AuditImpersonationEnded, adminID, userID, summary)
+
+	var admin models.User
+	if err := database.WithContext(ctx).First(&admin, adminID).Error; err != nil {
+		applog.Error(ctx, "failed to load administrator after impersonation", "error", err, "adminID", adminID)
+		if destroyErr := sessionManager.Destroy(ctx); destroyErr != nil {
+			applog.Error(ctx, "failed to destroy session", "error", destroyErr)
+		}
+		return err
+	}
+	if err := establishSession(r, &admin); err != nil {
+		return err
+	}
+	sessionManager.Remove(ctx, sessionImpersonatorIDKey)
+	sessionManager.Remove(ctx, sessionImpersonatorEmailKey)
+	sessionManager.Remove(ctx, sessionImpersonationEndsKey)
+	applog.Info(ctx, "impersonation ended", "adminID", adminID, "userID", userID)
+	return nil
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+func TestImpersonationIsAuditedAndExpires(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate users: %v", err)
+	}
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	prevDB, prevNow := database, nowFunc
+	database = db
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { database, nowFunc = prevDB, prevNow })
+
+	admin := models.User{Email: "admin@example.com", PasswordHash: "x", Role: models.RoleAdmin}
+	member := models.User{Email: "member@example.com", PasswordHash: "x"}
+	if err := db.Create(&admin).Error; err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	if err := db.Create(&member).Error; err != nil {
+		t.Fatalf("create member: %v", err)
+	}
+
+	req := authenticatedFormRequest(t, sm, "/app/admin/impersonate", url.Values{"email": {member.Email}}, int(admin.ID))
+	sm.Put(req.Context(), sessionUserEmailKey, admin.Email)
+	rec := httptest.NewRecorder()
+	ImpersonationStart(rec, req)
+	if !strings.Contains(rec.Body.String(), "a reason") {
+		t.Fatalf("expected a reason to be required, got %q", rec.Body.String())
+	}
+
+	ctx := req.Context()
+	start := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/app/admin/impersonate", strings.NewReader(form.Encode())).WithContext(ctx)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		ImpersonationStart(rec, r)
+		return rec
+	}
+	if rec := start(url.Values{"email": {admin.Email}, "reason": {"ticket 12"}}); !strings.Contains(rec.Body.String(), "already signed in") {
+		t.Fatalf("expected self impersonation to be refused, got %q", rec.Body.String())
+	}
+	if rec := start(url.Values{"email": {member.Email}, "reason": {"ticket 12"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected a redirect into the workspace, got %d", rec.Code)
+	}
+	if got := sm.GetInt(ctx, sessionUserIDKey); got != int(member.ID) {
+		t.Fatalf("expected the session to belong to the member, got user %d", got)
+	}
+	if banner := loadImpersonationBanner(req.WithContext(ctx)); !banner.Active || banner.AdminEmail != admin.Email {
+		t.Fatalf("expected the banner to name the administrator, got %+v", banner)
+	}
+
+	protected := RequireAuthentication(DenyWhileImpersonating(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/app/preferences/api-tokens", nil).WithContext(ctx))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected credential endpoints to be refused, got %d", rec.Code)
+	}
+
+	var entries []models.AuditEntry
+	if err := db.Order("id").Find(&entries).Error; err != nil {
+		t.Fatalf("load audit: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != models.AuditImpersonationStarted || entries[1].Action != models.AuditImpersonatedRequest {
+		t.Fatalf("expected the start and the request to be audited, got %+v", entries)
+	}
+	if *entries[1].ActorID != admin.ID || entries[1].EntityID != member.ID || entries[1].Summary != "POST /app/preferences/api-tokens" {
+		t.Fatalf("expected the request to be attributed to the administrator, got %+v", entries[1])
+	}
+	if !strings.Contains(entries[0].Summary, "ticket 12") {
+		t.Fatalf("expected the reason to be recorded, got %q", entries[0].Summary)
+	}
+
+	now = now.Add(impersonationTTL)
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app", nil).WithContext(ctx))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected an expired impersonation to redirect, got %d", rec.Code)
+	}
+	if got := sm.GetInt(ctx, sessionUserIDKey); got != int(admin.ID) {
+		t.Fatalf("expected the administrator back after expiry, got user %d", got)
+	}
+	if _, ok := impersonator(req.WithContext(ctx)); ok {
+		t.Fatalf("expected impersonation to be cleared")
+	}
+	var ended int64
+	db.Model(&models.AuditEntry{}).Where("action = ?", models.AuditImpersonationEnded).Count(&ended)
+	if ended != 1 {
+		t.Fatalf("expected the end to be audited once, got %d", ended)
+	}
+}
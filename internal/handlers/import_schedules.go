@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"perfugo/internal/importer"
+	"perfugo/internal/jobs"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	importRunHistory    = 5
+	importChangePreview = 8
+)
+
+// ImportSchedules lets administrators add a recurring aroma chemical import
+// from an https:// CSV export. Imported chemicals belong to the administrator.
+func ImportSchedules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	ownerID, _ := currentUserID(r)
+	schedule := models.ImportSchedule{
+		Name:    strings.TrimSpace(r.FormValue("name")),
+		Source:  strings.TrimSpace(r.FormValue("source")),
+		Cron:    strings.TrimSpace(r.FormValue("cron")),
+		OwnerID: ownerID,
+		Enabled: true,
+	}
+	if schedule.Name == "" {
+		renderImportScheduleControl(w, r, "Give the schedule a name.")
+		return
+	}
+	if parsed, err := url.Parse(schedule.Source); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		renderImportScheduleControl(w, r, "The source must be an https:// URL.")
+		return
+	}
+	cron, err := jobs.ParseCron(schedule.Cron)
+	if err != nil {
+		renderImportScheduleControl(w, r, "Use a five-field cron expression that matches a real date, such as \"0 3 * * *\" or @daily.")
+		return
+	}
+	next := cron.Next(nowFunc())
+	schedule.NextRunAt = &next
+
+	if err := database.WithContext(ctx).Create(&schedule).Error; err != nil {
+		applog.Error(ctx, "failed to create import schedule", "error", err)
+		http.Error(w, "unable to save schedule", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "import schedule created", "scheduleID", schedule.ID, "ownerID", ownerID, "cron", schedule.Cron)
+	renderImportScheduleControl(w, r, fmt.Sprintf("%s will first run on %s.", schedule.Name, next.Format("02 Jan 2006 15:04")))
+}
+
+// ImportScheduleRun runs a schedule immediately, outside its cron timing.
+func ImportScheduleRun(w http.ResponseWriter, r *http.Request) {
+	schedule, ok := requireImportSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := withDeadline(r, handlerTimeouts.Import)
+	defer cancel()
+	run, err := jobs.RunImportSchedule(ctx, database, schedule, nowFunc())
+	if contextExpired(err) {
+		writeTimeout(w, r, func(message string) {
+			renderImportScheduleControl(w, r, message)
+		})
+		return
+	}
+	message := fmt.Sprintf("%s finished: %s.", schedule.Name, run.Status)
+	if err != nil {
+		message = fmt.Sprintf("%s failed: %v", schedule.Name, err)
+	}
+	renderImportScheduleControl(w, r, message)
+}
+
+// ImportScheduleDelete removes a schedule together with its run history.
+func ImportScheduleDelete(w http.ResponseWriter, r *http.Request) {
+	schedule, ok := requireImportSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := database.WithContext(ctx).Where("schedule_id = ?", schedule.ID).Delete(&models.ImportRun{}).Error; err != nil {
+		applog.Error(ctx, "failed to delete import runs", "error", err, "scheduleID", schedule.ID)
+		http.Error(w, "unable to delete schedule", http.StatusInternalServerError)
+		return
+	}
+	if err := database.WithContext(ctx).Delete(schedule).Error; err != nil {
+		applog.Error(ctx, "failed to delete import schedule", "error", err, "scheduleID", schedule.ID)
+		http.Error(w, "unable to delete schedule", http.StatusInternalServerError)
+		return
+	}
+	renderImportScheduleControl(w, r, schedule.Name+" deleted.")
+}
+
+// requireImportSchedule loads the schedule named by the posted id, writing an
+// error response when it cannot.
+func requireImportSchedule(w http.ResponseWriter, r *http.Request) (*models.ImportSchedule, bool) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return nil, false
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var schedule models.ImportSchedule
+	if err := database.WithContext(r.Context()).First(&schedule, pages.ParseUint(r.FormValue("id"))).Error; err != nil {
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &schedule, true
+}
+
+func renderImportScheduleControl(w http.ResponseWriter, r *http.Request, message string) {
+	panel := loadImportSchedulePanel(r.Context())
+	panel.Message = message
+	renderComponent(w, r, pages.ImportScheduleControl(panel))
+}
+
+// loadImportSchedulePanel lists every schedule with its latest runs.
+func loadImportSchedulePanel(ctx context.Context) pages.ImportSchedulePanel {
+	panel := pages.ImportSchedulePanel{}
+	if database == nil {
+		return panel
+	}
+
+	var schedules []models.ImportSchedule
+	if err := database.WithContext(ctx).Order("name asc").Find(&schedules).Error; err != nil {
+		applog.Error(ctx, "failed to load import schedules", "error", err)
+		return panel
+	}
+
+	for _, schedule := range schedules {
+		row := pages.ImportScheduleRow{
+			ID:      schedule.ID,
+			Name:    schedule.Name,
+			Source:  schedule.Source,
+			Cron:    schedule.Cron,
+			Enabled: schedule.Enabled,
+			NextRun: formatScheduleTime(schedule.NextRunAt),
+			LastRun: formatScheduleTime(schedule.LastRunAt),
+		}
+
+		var runs []models.ImportRun
+		if err := database.WithContext(ctx).
+			Where("schedule_id = ?", schedule.ID).
+			Order("started_at desc, id desc").
+			Limit(importRunHistory).
+			Find(&runs).Error; err != nil {
+			applog.Error(ctx, "failed to load import runs", "error", err, "scheduleID", schedule.ID)
+		}
+		for _, run := range runs {
+			row.Runs = append(row.Runs, importRunRow(run))
+		}
+		panel.Schedules = append(panel.Schedules, row)
+	}
+	return panel
+}
+
+func importRunRow(run models.ImportRun) pages.ImportRunRow {
+	row := pages.ImportRunRow{
+		Started: run.StartedAt.Format("02 Jan 2006 15:04"),
+		Status:  run.Status,
+		Created: run.Created,
+		Updated: run.Updated,
+		Skipped: run.Skipped,
+		Error:   run.Error,
+	}
+	if len(run.Checksum) >= 12 {
+		row.Checksum = run.Checksum[:12]
+	}
+
+	var changes []importer.Change
+	if run.Changes != "" {
+		if err := json.Unmarshal([]byte(run.Changes), &changes); err != nil {
+			return row
+		}
+	}
+	for i, change := range changes {
+		if i == importChangePreview {
+			row.MoreChanges = len(changes) - i
+			break
+		}
+		line := "Created " + change.Ingredient
+		if change.Action == importer.ChangeUpdated {
+			line = fmt.Sprintf("Updated %s (%s)", change.Ingredient, strings.Join(change.Fields, ", "))
+		}
+		row.Changes = append(row.Changes, line)
+	}
+	return row
+}
+
+func formatScheduleTime(value *time.Time) string {
+	if value == nil || value.IsZero() {
+		return ""
+	}
+	return value.Format("02 Jan 2006 15:04")
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// FormulaINCI renders the INCI ingredient declaration of a finished product
+// made from a formula at the requested concentration and carrier. Stock
+// solutions contribute their solvent as well as the neat material.
+func FormulaINCI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, pages.ParseUint(r.URL.Query().Get("id")))
+	if formula == nil {
+		http.Error(w, "formula not found", http.StatusNotFound)
+		return
+	}
+
+	solvent := models.SolventByID(loadProductionDefaults(r).Solvent)
+	if raw := strings.TrimSpace(r.URL.Query().Get("solvent")); raw != "" && models.ValidSolvent(raw) {
+		solvent = models.SolventByID(raw)
+	}
+	concentration := pages.DefaultAllergenConcentration
+	if raw := strings.TrimSpace(r.URL.Query().Get("concentration")); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value <= 0 || value > 100 {
+			renderComponent(w, r, pages.FormulaINCIDeclaration(pages.INCIDeclaration{FormulaID: formula.ID, Concentration: concentration, Solvent: solvent.ID, Message: "Enter a concentration between 0 and 100%."}))
+			return
+		}
+		concentration = value
+	}
+
+	declaration, err := buildINCIDeclaration(r, formula.ID, concentration, solvent)
+	if err != nil {
+		declaration = pages.INCIDeclaration{FormulaID: formula.ID, Concentration: concentration, Solvent: solvent.ID}
+		switch {
+		case errors.Is(err, errBatchEmptyComposition):
+			declaration.Message = "Add ingredients to build the ingredient declaration."
+		case errors.Is(err, errBatchInvalidQuantity):
+			declaration.Message = "The composition has no weight to declare."
+		default:
+			applog.Error(r.Context(), "failed to build INCI declaration", "error", err, "formulaID", formula.ID)
+			declaration.Message = "We couldn't build the ingredient declaration. Please try again."
+		}
+	}
+	renderComponent(w, r, pages.FormulaINCIDeclaration(declaration))
+}
+
+func buildINCIDeclaration(r *http.Request, formulaID uint, concentration float64, solvent models.Solvent) (pages.INCIDeclaration, error) {
+	ctx := r.Context()
+	var finish *batchFinish
+	if concentration < 100 {
+		finish = &batchFinish{ConcentratePercent: concentration, Solvent: solvent}
+	}
+	data, err := buildBatchProductionReportData(ctx, formulaID, allergenBasisMg, finish)
+	if err != nil {
+		return pages.INCIDeclaration{}, err
+	}
+
+	total := 0.0
+	ids := make([]uint, 0, len(data.Ingredients))
+	for _, ingredient := range data.Ingredients {
+		total += ingredient.FinalQuantity
+		if !ingredient.Solvent {
+			ids = append(ids, ingredient.ChemicalID)
+		}
+	}
+	if total <= 0 {
+		return pages.INCIDeclaration{}, errBatchInvalidQuantity
+	}
+
+	chemicals, err := loadReportChemicals(ctx, ids)
+	if err != nil {
+		return pages.INCIDeclaration{}, err
+	}
+	byID := make(map[uint]models.AromaChemical, len(chemicals))
+	for _, chemical := range chemicals {
+		byID[chemical.ID] = chemical
+	}
+
+	sources := make([]pages.INCISource, 0, len(data.Ingredients)+1)
+	for _, ingredient := range data.Ingredients {
+		if ingredient.Solvent {
+			sources = append(sources, pages.INCISource{INCIName: solvent.INCIName, IngredientName: solvent.ShortLabel, Percent: ingredient.FinalQuantity / total * 100})
+			continue
+		}
+		chemical, ok := byID[ingredient.ChemicalID]
+		if !ok {
+			continue
+		}
+		sources = append(sources, pages.INCISource{INCIName: chemical.INCIName, IngredientName: chemical.IngredientName, Percent: ingredient.NeatQuantity / total * 100})
+		if diluent := ingredient.FinalQuantity - ingredient.NeatQuantity; diluent > 0 && ingredient.DilutionSolvent != "" {
+			carrier := models.SolventByID(ingredient.DilutionSolvent)
+			sources = append(sources, pages.INCISource{INCIName: carrier.INCIName, IngredientName: carrier.ShortLabel, Percent: diluent / total * 100})
+		}
+	}
+	return pages.BuildINCIDeclaration(formulaID, concentration, solvent.ID, sources), nil
+}
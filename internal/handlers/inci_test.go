@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBuildINCIDeclarationCountsCarrierAndStockSolvent(t *testing.T) {
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate inventory: %v", err)
+	}
+
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	cashmeran := models.AromaChemical{IngredientName: "Cashmeran", INCIName: "Dimethyl Tetrahydro Indanone", OwnerID: 1}
+	if err := db.Create(&cashmeran).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	stock := models.InventoryItem{OwnerID: 1, AromaChemicalID: cashmeran.ID, QuantityMg: 20000, DilutionPercent: 10, Solvent: models.SolventDPG}
+	if err := db.Create(&stock).Error; err != nil {
+		t.Fatalf("create stock solution: %v", err)
+	}
+	formula := models.Formula{Name: "Soft Wood", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	lines := []models.FormulaIngredient{
+		{FormulaID: formula.ID, AromaChemicalID: &cashmeran.ID, Amount: 2, Unit: "g"},
+		{FormulaID: formula.ID, AromaChemicalID: &cashmeran.ID, StockSolutionID: &stock.ID, Amount: 8, Unit: "g"},
+	}
+	if err := db.Create(&lines).Error; err != nil {
+		t.Fatalf("create ingredients: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/app/sections/formulas/inci", nil)
+	declaration, err := buildINCIDeclaration(r, formula.ID, 20, models.SolventByID(models.SolventEthanol))
+	if err != nil {
+		t.Fatalf("buildINCIDeclaration returned error: %v", err)
+	}
+	if got, want := declaration.Text(), "Alcohol Denat., Dipropylene Glycol, Dimethyl Tetrahydro Indanone"; got != want {
+		t.Fatalf("Text() = %q, want %q", got, want)
+	}
+	// 2.8 g of the 10 g concentrate is neat Cashmeran; the rest is DPG.
+	want := []float64{80, 14.4, 5.6}
+	for i, line := range declaration.Lines {
+		if math.Abs(line.Percent-want[i]) > 1e-6 {
+			t.Fatalf("line %d (%s) = %.6f%%, want %.1f%%", i, line.Name, line.Percent, want[i])
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// IngredientNoteSave stores the current user's private note on an aroma
+// chemical, which may be a shared record owned by someone else. Clearing
+// every field removes the note.
+func IngredientNoteSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	chemical := pages.FindAromaChemical(snapshot.AromaChemicals, pages.ParseUint(r.FormValue("id")))
+	if chemical == nil {
+		http.Error(w, "ingredient not found", http.StatusNotFound)
+		return
+	}
+
+	note := models.UserIngredientNote{
+		UserID:          userID,
+		AromaChemicalID: chemical.ID,
+		Perception:      strings.TrimSpace(r.FormValue("perception")),
+		DilutionHabit:   strings.TrimSpace(r.FormValue("dilution_habit")),
+	}
+	if database == nil {
+		renderComponent(w, r, pages.IngredientNoteCard(chemical.ID, note, pages.DemoModeMessage))
+		return
+	}
+
+	ctx := r.Context()
+	scope := database.WithContext(ctx).Where("user_id = ? AND aroma_chemical_id = ?", userID, chemical.ID)
+	var err error
+	message := "Your note was saved."
+	if note.Blank() {
+		err = scope.Unscoped().Delete(&models.UserIngredientNote{}).Error
+		message = "Your note was cleared."
+	} else {
+		var stored models.UserIngredientNote
+		err = scope.First(&stored).Error
+		switch {
+		case err == nil:
+			err = database.WithContext(ctx).Model(&stored).Updates(map[string]any{
+				"perception":     note.Perception,
+				"dilution_habit": note.DilutionHabit,
+			}).Error
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			err = database.WithContext(ctx).Create(&note).Error
+		}
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to save ingredient note", "error", err, "ingredientID", chemical.ID)
+		renderComponent(w, r, pages.IngredientNoteCard(chemical.ID, note, "We couldn't save your note. Please try again."))
+		return
+	}
+	renderComponent(w, r, pages.IngredientNoteCard(chemical.ID, note, message))
+}
+
+// loadIngredientNote returns the current user's note on a chemical, or nil
+// when there is none.
+func loadIngredientNote(r *http.Request, chemicalID uint) *models.UserIngredientNote {
+	userID, ok := currentUserID(r)
+	if !ok || database == nil {
+		return nil
+	}
+	var note models.UserIngredientNote
+	err := database.WithContext(r.Context()).
+		Where("user_id = ? AND aroma_chemical_id = ?", userID, chemicalID).
+		First(&note).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			applog.Error(r.Context(), "failed to load ingredient note", "error", err, "ingredientID", chemicalID)
+		}
+		return nil
+	}
+	return &note
+}
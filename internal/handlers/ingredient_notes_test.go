@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestIngredientNoteOnSharedChemical(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.UserIngredientNote{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	shared := models.AromaChemical{IngredientName: "Ambroxan", OwnerID: 2, Public: true, Notes: "Shared profile"}
+	if err := db.Create(&shared).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	id := strconv.Itoa(int(shared.ID))
+
+	save := func(userID int, perception, habit string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		IngredientNoteSave(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/note", url.Values{
+			"id":             {id},
+			"perception":     {perception},
+			"dilution_habit": {habit},
+		}, userID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("save note: status %d", rec.Code)
+		}
+		return rec.Body.String()
+	}
+	detail := func(userID int) string {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/app/sections/ingredients/detail?id="+id, nil, userID)
+		req.Method = http.MethodGet
+		rec := httptest.NewRecorder()
+		IngredientDetail(rec, req)
+		return rec.Body.String()
+	}
+
+	save(7, "Warm, salty skin", "10% in DPG")
+	if body := save(7, "Warm, mineral skin", "10% in DPG"); !strings.Contains(body, "Your note was saved.") {
+		t.Fatalf("unexpected save response: %q", body)
+	}
+	var notes []models.UserIngredientNote
+	db.Find(&notes)
+	if len(notes) != 1 || notes[0].Perception != "Warm, mineral skin" {
+		t.Fatalf("expected one updated note, got %+v", notes)
+	}
+
+	if body := detail(7); !strings.Contains(body, "Shared profile") || !strings.Contains(body, "Warm, mineral skin") {
+		t.Fatalf("detail card is missing the note: %q", body)
+	}
+	if body := detail(8); strings.Contains(body, "Warm, mineral skin") {
+		t.Fatalf("another user's note leaked into the detail card")
+	}
+
+	save(7, "", " ")
+	var count int64
+	db.Unscoped().Model(&models.UserIngredientNote{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected the cleared note to be removed, found %d", count)
+	}
+	var stored models.AromaChemical
+	db.First(&stored, shared.ID)
+	if stored.Notes != "Shared profile" {
+		t.Fatalf("shared chemical was modified: %+v", stored)
+	}
+}
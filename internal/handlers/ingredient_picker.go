@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// ingredientPickerPageSize is how many chemicals and how many formulas one
+// page of the ingredient picker lists.
+const ingredientPickerPageSize = 25
+
+// likeEscaper escapes the wildcards of a LIKE pattern so a search for
+// "50%" matches the text literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// FormulaIngredientOptions serves the options of a composition row's
+// ingredient select: the chemicals, stock solutions and formulas whose name
+// matches ingredient_query, a page at a time, so the editor no longer
+// renders the whole library into every row.
+func FormulaIngredientOptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	options := pages.IngredientOptionPage{
+		Row:       int(pages.ParseUint(query.Get("row"))),
+		FormulaID: pages.ParseUint(query.Get("formula_id")),
+		Query:     strings.TrimSpace(query.Get("ingredient_query")),
+		Page:      max(int(pages.ParseUint(query.Get("page"))), 1),
+	}
+	if err := searchIngredientOptions(r.Context(), userID, &options); err != nil {
+		applog.Error(r.Context(), "failed to search ingredient options", "error", err, "userID", userID)
+		http.Error(w, "unable to search ingredients", http.StatusInternalServerError)
+		return
+	}
+	if options.Page == 1 {
+		options.Selected = resolveIngredientOption(r.Context(), userID, query.Get("ingredient_source"))
+	}
+	renderComponent(w, r, pages.IngredientPickerOptions(options))
+}
+
+// searchIngredientOptions fills one page of options with the chemicals the
+// user can see, their stock solutions and the formulas other than the one
+// being edited. A chemical also matches on its other names.
+func searchIngredientOptions(ctx context.Context, userID uint, options *pages.IngredientOptionPage) error {
+	offset := (options.Page - 1) * ingredientPickerPageSize
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(options.Query)) + "%"
+
+	chemicalQuery := database.WithContext(ctx).
+		Where("owner_id = ? OR public = ?", userID, true).
+		Preload("StockSolutions", "owner_id = ? AND dilution_percent > 0 AND dilution_percent < 100", userID)
+	if options.Query != "" {
+		aliases := database.Model(&models.OtherName{}).Select("aroma_chemical_id").Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern)
+		chemicalQuery = chemicalQuery.Where(`LOWER(ingredient_name) LIKE ? ESCAPE '\' OR id IN (?)`, pattern, aliases)
+	}
+	var chemicals []models.AromaChemical
+	if err := chemicalQuery.Order("ingredient_name asc").Order("id asc").
+		Offset(offset).Limit(ingredientPickerPageSize + 1).
+		Find(&chemicals).Error; err != nil {
+		return err
+	}
+
+	formulaQuery := database.WithContext(ctx).Where("id <> ?", options.FormulaID)
+	if options.Query != "" {
+		formulaQuery = formulaQuery.Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern)
+	}
+	var formulas []models.Formula
+	if err := formulaQuery.Order("name asc").Order("id asc").
+		Offset(offset).Limit(ingredientPickerPageSize + 1).
+		Find(&formulas).Error; err != nil {
+		return err
+	}
+
+	options.More = len(chemicals) > ingredientPickerPageSize || len(formulas) > ingredientPickerPageSize
+	chemicals = chemicals[:min(len(chemicals), ingredientPickerPageSize)]
+	formulas = formulas[:min(len(formulas), ingredientPickerPageSize)]
+	for _, chemical := range chemicals {
+		options.Chemicals = append(options.Chemicals, pages.IngredientOption{
+			Value: fmt.Sprintf("chem:%d", chemical.ID),
+			Label: chemical.IngredientName,
+		})
+		for _, stock := range chemical.StockSolutions {
+			options.Stocks = append(options.Stocks, pages.IngredientOption{
+				Value: fmt.Sprintf("stock:%d", stock.ID),
+				Label: chemical.IngredientName + " " + stock.DilutionLabel(),
+			})
+		}
+	}
+	for _, formula := range formulas {
+		options.Formulas = append(options.Formulas, pages.IngredientOption{
+			Value: fmt.Sprintf("formula:%d", formula.ID),
+			Label: formula.Name,
+		})
+	}
+	return nil
+}
+
+// resolveIngredientOption labels a row's current choice, or returns nil when
+// it is empty or no longer available to the user.
+func resolveIngredientOption(ctx context.Context, userID uint, source string) *pages.IngredientOption {
+	chemID, subID, stockID, err := parseIngredientSource(source)
+	if err != nil {
+		return nil
+	}
+	db := database.WithContext(ctx)
+	visible := func(tx *gorm.DB) *gorm.DB { return tx.Where("owner_id = ? OR public = ?", userID, true) }
+	option := &pages.IngredientOption{Value: strings.TrimSpace(source)}
+	switch {
+	case chemID != nil:
+		var chemical models.AromaChemical
+		if err := db.Scopes(visible).First(&chemical, *chemID).Error; err != nil {
+			return nil
+		}
+		option.Label = chemical.IngredientName
+	case stockID != nil:
+		var stock models.InventoryItem
+		if err := db.Where("owner_id = ?", userID).First(&stock, *stockID).Error; err != nil {
+			return nil
+		}
+		var chemical models.AromaChemical
+		if err := db.First(&chemical, stock.AromaChemicalID).Error; err != nil {
+			return nil
+		}
+		option.Label = chemical.IngredientName + " " + stock.DilutionLabel()
+	case subID != nil:
+		var formula models.Formula
+		if err := db.First(&formula, *subID).Error; err != nil {
+			return nil
+		}
+		option.Label = formula.Name
+	}
+	return option
+}
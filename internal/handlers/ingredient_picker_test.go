@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+func TestFormulaIngredientOptionsSearchesAndPages(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	for i := 0; i < 30; i++ {
+		if err := db.Create(&models.AromaChemical{IngredientName: fmt.Sprintf("Musk %02d", i), OwnerID: 3}).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	ambroxan := models.AromaChemical{IngredientName: "Ambroxan", OwnerID: 9, Public: true, OtherNames: []models.OtherName{{Name: "Ambrofix"}}}
+	hidden := models.AromaChemical{IngredientName: "Ambrette seed", OwnerID: 9}
+	for _, chemical := range []*models.AromaChemical{&ambroxan, &hidden} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	stock := models.InventoryItem{OwnerID: 3, AromaChemicalID: ambroxan.ID, DilutionPercent: 10, Solvent: models.SolventDPG}
+	if err := db.Create(&stock).Error; err != nil {
+		t.Fatalf("create stock solution: %v", err)
+	}
+	current := models.Formula{Name: "Amber base", Version: 1, IsLatest: true}
+	accord := models.Formula{Name: "Amber accord", Version: 1, IsLatest: true}
+	for _, formula := range []*models.Formula{&current, &accord} {
+		if err := db.Create(formula).Error; err != nil {
+			t.Fatalf("create formula: %v", err)
+		}
+	}
+
+	search := func(query string, page int, selected string) string {
+		t.Helper()
+		target := pages.IngredientOptionsURL(current.ID, 2, query, page) + "&" + url.Values{"ingredient_source": {selected}}.Encode()
+		req := authenticatedFormRequest(t, sm, target, nil, 3)
+		req.Method = http.MethodGet
+		rec := httptest.NewRecorder()
+		FormulaIngredientOptions(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+
+	body := search("ambrofix", 1, fmt.Sprintf("formula:%d", accord.ID))
+	if !strings.Contains(body, fmt.Sprintf(`value="chem:%d"`, ambroxan.ID)) || !strings.Contains(body, "Ambroxan 10% in DPG") {
+		t.Fatalf("expected Ambroxan and its stock solution to match its other name: %s", body)
+	}
+	if strings.Contains(body, "Musk") || strings.Contains(body, "Ambrette") {
+		t.Fatalf("expected only matching, visible chemicals: %s", body)
+	}
+	if !strings.Contains(body, fmt.Sprintf(`value="formula:%d" selected`, accord.ID)) {
+		t.Fatalf("expected the current selection to be kept: %s", body)
+	}
+
+	body = search("amber", 1, "")
+	if strings.Contains(body, fmt.Sprintf(`value="formula:%d"`, current.ID)) || !strings.Contains(body, "Amber accord") {
+		t.Fatalf("expected matching formulas other than the one being edited: %s", body)
+	}
+
+	body = search("musk", 1, "")
+	if got := strings.Count(body, `value="chem:`); got != ingredientPickerPageSize {
+		t.Fatalf("expected a full first page, got %d options", got)
+	}
+	if !strings.Contains(body, "More matches") || !strings.Contains(body, `id="ingredient-more-2"`) {
+		t.Fatalf("expected a control loading the next page: %s", body)
+	}
+	body = search("musk", 2, "")
+	if got := strings.Count(body, `value="chem:`); got != 5 {
+		t.Fatalf("expected the remaining 5 options, got %d", got)
+	}
+	if strings.Contains(body, "More matches") || strings.Contains(body, "Select ingredient") {
+		t.Fatalf("expected the last page to append options only: %s", body)
+	}
+
+	if body := search("100%", 1, ""); strings.Contains(body, `value="chem:`) {
+		t.Fatalf("expected wildcards in the search to match literally: %s", body)
+	}
+}
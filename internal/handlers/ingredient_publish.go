@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// IngredientPublish shares or withdraws the ticked aroma chemicals the member
+// owns. The first request summarises how many formulas by other members use
+// them; the change is only made once that summary is confirmed.
+func IngredientPublish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	var public bool
+	switch r.FormValue("public") {
+	case "true":
+		public = true
+	case "false":
+	default:
+		http.Error(w, "public must be true or false", http.StatusBadRequest)
+		return
+	}
+	var ticked []uint
+	for _, raw := range r.Form["id"] {
+		if id := pages.ParseUint(raw); id != 0 {
+			ticked = append(ticked, id)
+		}
+	}
+	if len(ticked) == 0 {
+		http.Error(w, "Select at least one ingredient.", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var owned []uint
+	if err := database.WithContext(ctx).Model(&models.AromaChemical{}).
+		Where("id IN ? AND owner_id = ?", ticked, userID).
+		Order("id asc").
+		Pluck("id", &owned).Error; err != nil {
+		applog.Error(ctx, "failed to load ingredients to publish", "error", err, "userID", userID)
+		http.Error(w, "unable to load ingredients", http.StatusInternalServerError)
+		return
+	}
+	if len(owned) == 0 {
+		http.Error(w, "You can only publish ingredients you own.", http.StatusForbidden)
+		return
+	}
+	result := pages.IngredientPublish{IDs: owned, Skipped: len(ticked) - len(owned), Public: public}
+
+	if !checkboxChecked(r.FormValue("confirm")) {
+		used := database.WithContext(ctx).Model(&models.FormulaIngredient{}).
+			Select("formula_id").
+			Where("aroma_chemical_id IN ?", owned)
+		if err := database.WithContext(ctx).Model(&models.Formula{}).
+			Where("created_by_id IS NOT NULL AND created_by_id <> ?", userID).
+			Where("id IN (?)", used).
+			Count(&result.OtherFormulas).Error; err != nil {
+			applog.Error(ctx, "failed to count formulas using ingredients", "error", err, "userID", userID)
+			http.Error(w, "unable to summarise ingredients", http.StatusInternalServerError)
+			return
+		}
+		renderComponent(w, r, pages.IngredientPublishPanel(result))
+		return
+	}
+
+	action := models.AuditChemicalUnpublished
+	if public {
+		action = models.AuditChemicalPublished
+	}
+	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AromaChemical{}).
+			Where("id IN ? AND owner_id = ?", owned, userID).
+			Update("public", public).Error; err != nil {
+			return err
+		}
+		for _, id := range owned {
+			summary := fmt.Sprintf("%s aroma chemical %d.", result.Verb()+"ed", id)
+			if err := recordAudit(tx, r, action, models.ActivityEntityAromaChemical, id, summary); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to publish ingredients", "error", err, "userID", userID)
+		http.Error(w, "unable to update ingredients", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "ingredients publication changed", "userID", userID, "public", public, "count", len(owned))
+	result.Done = true
+	renderComponent(w, r, pages.IngredientPublishPanel(result))
+}
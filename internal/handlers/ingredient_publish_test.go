@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestIngredientPublishConfirmsBeforeChanging(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	mine := models.AromaChemical{IngredientName: "Iso E Super", OwnerID: 3}
+	alsoMine := models.AromaChemical{IngredientName: "Hedione", OwnerID: 3}
+	theirs := models.AromaChemical{IngredientName: "Ambroxan", OwnerID: 4}
+	for _, chemical := range []*models.AromaChemical{&mine, &alsoMine, &theirs} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	me, other := uint(3), uint(4)
+	ownFormula := models.Formula{Name: "Mine", CreatedByID: &me}
+	otherFormula := models.Formula{Name: "Copy of Mine", CreatedByID: &other}
+	db.Create(&ownFormula)
+	db.Create(&otherFormula)
+	for _, row := range []models.FormulaIngredient{
+		{FormulaID: ownFormula.ID, AromaChemicalID: &mine.ID, Amount: 1, Unit: "g"},
+		{FormulaID: otherFormula.ID, AromaChemicalID: &mine.ID, Amount: 1, Unit: "g"},
+		{FormulaID: otherFormula.ID, AromaChemicalID: &alsoMine.ID, Amount: 1, Unit: "g"},
+	} {
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatalf("create row: %v", err)
+		}
+	}
+
+	form := url.Values{
+		"public": {"true"},
+		"id":     {strconv.Itoa(int(mine.ID)), strconv.Itoa(int(alsoMine.ID)), strconv.Itoa(int(theirs.ID))},
+	}
+	rec := httptest.NewRecorder()
+	IngredientPublish(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/publish", form, 3))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected summary, got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Publish 2 aroma chemicals? 1 formula by another member uses them.") || !strings.Contains(body, "1 ticked chemical belongs to another member") {
+		t.Fatalf("unexpected summary: %s", body)
+	}
+	var published int64
+	db.Model(&models.AromaChemical{}).Where("public = ?", true).Count(&published)
+	if published != 0 {
+		t.Fatalf("expected nothing published before confirmation, got %d", published)
+	}
+
+	form.Set("confirm", "true")
+	rec = httptest.NewRecorder()
+	IngredientPublish(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/publish", form, 3))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Published 2 aroma chemicals.") {
+		t.Fatalf("unexpected confirmation: %d %s", rec.Code, rec.Body.String())
+	}
+	var reloaded []models.AromaChemical
+	db.Order("id asc").Find(&reloaded)
+	if !reloaded[0].Public || !reloaded[1].Public || reloaded[2].Public {
+		t.Fatalf("expected only owned chemicals published: %+v", reloaded)
+	}
+	var entries int64
+	db.Model(&models.AuditEntry{}).Where("action = ?", models.AuditChemicalPublished).Count(&entries)
+	if entries != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", entries)
+	}
+}
+
+func TestIngredientPublishRejectsForeignChemicals(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	theirs := models.AromaChemical{IngredientName: "Ambroxan", OwnerID: 4, Public: true}
+	db.Create(&theirs)
+
+	form := url.Values{"public": {"false"}, "confirm": {"true"}, "id": {strconv.Itoa(int(theirs.ID))}}
+	rec := httptest.NewRecorder()
+	IngredientPublish(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/publish", form, 3))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected forbidden, got %d", rec.Code)
+	}
+	var reloaded models.AromaChemical
+	db.First(&reloaded, theirs.ID)
+	if !reloaded.Public {
+		t.Fatal("expected another member's chemical to stay public")
+	}
+}
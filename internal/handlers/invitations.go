@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/mail"
+	"perfugo/internal/views/emails"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	invitationTTL          = 7 * 24 * time.Hour
+	invitationAdminPath    = "/app/admin/invitations"
+	inviteOnlySignupNotice = "Registration on this instance is by invitation only. Ask an administrator for an invitation link."
+	invalidInvitationError = "This invitation is invalid, has expired or was already used."
+)
+
+var (
+	inviteOnly atomic.Bool
+	mailer     mail.Sender
+
+	errInvitationUnusable = errors.New("invitation is no longer usable")
+)
+
+// SetInviteOnly toggles whether signup requires an invitation token.
+func SetInviteOnly(enabled bool) {
+	inviteOnly.Store(enabled)
+	applog.Info(nil, "signup mode updated", "inviteOnly", enabled)
+}
+
+// InviteOnly reports whether open signup is disabled.
+func InviteOnly() bool {
+	return inviteOnly.Load()
+}
+
+// ConfigureMail installs the sender used for transactional email. A nil
+// sender disables outgoing mail.
+func ConfigureMail(sender mail.Sender) {
+	mailer = sender
+	applog.Debug(nil, "mail sender configured", "enabled", sender != nil)
+}
+
+// createInvitation stores a new invitation and returns it with the raw token.
+func createInvitation(ctx context.Context, inviterID uint, email string) (models.Invitation, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return models.Invitation{}, "", err
+	}
+	invitation := models.Invitation{
+		TokenHash:   hashToken(token),
+		Email:       strings.ToLower(strings.TrimSpace(email)),
+		InvitedByID: inviterID,
+		ExpiresAt:   nowFunc().Add(invitationTTL),
+	}
+	if err := database.WithContext(ctx).Create(&invitation).Error; err != nil {
+		return models.Invitation{}, "", err
+	}
+	return invitation, token, nil
+}
+
+// findUsableInvitation resolves a raw token to an unexpired, unused invitation.
+func findUsableInvitation(ctx context.Context, token string) (*models.Invitation, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, errInvitationUnusable
+	}
+	var invitation models.Invitation
+	if err := database.WithContext(ctx).Where("token_hash = ?", hashToken(token)).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvitationUnusable
+		}
+		return nil, err
+	}
+	if !invitation.Usable(nowFunc()) {
+		return nil, errInvitationUnusable
+	}
+	return &invitation, nil
+}
+
+// acceptInvitation claims the invitation and creates the account in one
+// transaction so a token can never register two users.
+func acceptInvitation(r *http.Request, invitation *models.Invitation, email, name, password string) (*models.User, error) {
+	var user *models.User
+	err := database.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		claimed := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", nowFunc())
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			return errInvitationUnusable
+		}
+
+		created, err := insertUser(r, tx, email, name, password)
+		if err != nil {
+			return err
+		}
+		user = created
+		return tx.Model(&models.Invitation{}).Where("id = ?", invitation.ID).Update("accepted_by_id", created.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	applog.Debug(r.Context(), "invitation accepted", "invitationID", invitation.ID, "userID", user.ID)
+	return user, nil
+}
+
+// InvitationCreate lets administrators issue a single-use signup link,
+// emailing it when an address is supplied and mail is configured.
+func InvitationCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		applog.Debug(r.Context(), "failed to parse invitation form", "error", err)
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	inviterID, _ := currentUserID(r)
+	email := strings.TrimSpace(r.FormValue("email"))
+	if email != "" && !strings.Contains(email, "@") {
+		renderInvitationControl(w, r, pages.InvitationPanel{Message: "Please provide a valid email address."})
+		return
+	}
+
+	invitation, token, err := createInvitation(ctx, inviterID, email)
+	if err != nil {
+		applog.Error(ctx, "failed to create invitation", "error", err)
+		http.Error(w, "unable to create invitation", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "invitation created", "invitationID", invitation.ID, "invitedBy", inviterID, "emailSet", invitation.Email != "")
+
+	panel := pages.InvitationPanel{Link: absoluteURL(r, pages.Path("/signup?invite="+token))}
+	if invitation.Email != "" && mailer != nil {
+		msg, err := emails.Invitation(emails.InvitationData{Link: panel.Link, ExpiresAt: invitation.ExpiresAt}).Message(ctx, invitation.Email)
+		if err == nil {
+			err = mailer.Send(ctx, msg)
+		}
+		if err != nil {
+			applog.Error(ctx, "failed to email invitation", "invitationID", invitation.ID, "error", err)
+			panel.Message = "The invitation was created but could not be emailed. Share the link below instead."
+		} else {
+			panel.Message = "Invitation emailed to " + invitation.Email + "."
+		}
+	}
+
+	renderInvitationControl(w, r, panel)
+}
+
+func renderInvitationControl(w http.ResponseWriter, r *http.Request, panel pages.InvitationPanel) {
+	panel.InviteOnly = InviteOnly()
+	panel.Pending = loadPendingInvitations(r.Context())
+	// The raw token is only available now, so the fragment is rendered even
+	// for non-HTMX submissions rather than redirecting it away.
+	renderComponent(w, r, pages.InvitationControl(panel))
+}
+
+func loadPendingInvitations(ctx context.Context) []pages.InvitationItem {
+	if database == nil {
+		return nil
+	}
+	var invitations []models.Invitation
+	if err := database.WithContext(ctx).
+		Where("accepted_at IS NULL AND expires_at > ?", nowFunc()).
+		Order("expires_at asc").
+		Limit(20).
+		Find(&invitations).Error; err != nil {
+		applog.Error(ctx, "failed to load pending invitations", "error", err)
+		return nil
+	}
+
+	items := make([]pages.InvitationItem, 0, len(invitations))
+	for _, invitation := range invitations {
+		items = append(items, pages.InvitationItem{
+			Email:   invitation.Email,
+			Expires: invitation.ExpiresAt.Format("02 Jan 2006"),
+		})
+	}
+	return items
+}
+
+// absoluteURL builds an external link to path for the current request host.
+// path must already carry the base path; see pages.Path.
+func absoluteURL(r *http.Request, path string) string {
+	return requestScheme(r) + "://" + r.Host + path
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/mail"
+	"perfugo/models"
+)
+
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func withInviteOnly(t *testing.T) {
+	t.Helper()
+	previous := InviteOnly()
+	SetInviteOnly(true)
+	t.Cleanup(func() { SetInviteOnly(previous) })
+}
+
+func postSignup(t *testing.T, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	sessionManager.LoadAndSave(http.HandlerFunc(Signup)).ServeHTTP(w, req)
+	return w
+}
+
+func signupForm(email, invite string) url.Values {
+	return url.Values{
+		"name":             {"Invitee"},
+		"email":            {email},
+		"password":         {"password123"},
+		"confirm_password": {"password123"},
+		"invite":           {invite},
+	}
+}
+
+func countUsers(t *testing.T, email string) int64 {
+	t.Helper()
+	var count int64
+	if err := database.Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	return count
+}
+
+func TestInviteOnlySignup(t *testing.T) {
+	_, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+	db, dbCleanup := withTestDatabase(t)
+	t.Cleanup(dbCleanup)
+	if err := db.AutoMigrate(&models.Invitation{}); err != nil {
+		t.Fatalf("failed to migrate invitations: %v", err)
+	}
+	withInviteOnly(t)
+
+	ctx := context.Background()
+	_, openToken, err := createInvitation(ctx, 1, "")
+	if err != nil {
+		t.Fatalf("createInvitation: %v", err)
+	}
+	_, boundToken, err := createInvitation(ctx, 1, "bound@example.com")
+	if err != nil {
+		t.Fatalf("createInvitation: %v", err)
+	}
+
+	if w := postSignup(t, signupForm("nobody@example.com", "")); w.Code == http.StatusSeeOther || countUsers(t, "nobody@example.com") != 0 {
+		t.Fatal("expected signup without an invitation to be rejected")
+	}
+
+	if postSignup(t, signupForm("other@example.com", boundToken)); countUsers(t, "other@example.com") != 0 {
+		t.Fatal("expected an address-bound invitation to reject other emails")
+	}
+
+	if w := postSignup(t, signupForm("first@example.com", openToken)); w.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect after invited signup, got %d: %s", w.Code, w.Body.String())
+	}
+	if countUsers(t, "first@example.com") != 1 {
+		t.Fatal("expected invited user to be created")
+	}
+
+	if postSignup(t, signupForm("second@example.com", openToken)); countUsers(t, "second@example.com") != 0 {
+		t.Fatal("expected a used invitation to be rejected")
+	}
+
+	var accepted models.Invitation
+	if err := database.Where("token_hash = ?", hashToken(openToken)).First(&accepted).Error; err != nil {
+		t.Fatalf("load invitation: %v", err)
+	}
+	if accepted.AcceptedAt == nil || accepted.AcceptedByID == nil {
+		t.Fatalf("expected invitation to record acceptance: %+v", accepted)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/signup", nil)
+	w := httptest.NewRecorder()
+	sessionManager.LoadAndSave(http.HandlerFunc(Signup)).ServeHTTP(w, req)
+	if strings.Contains(w.Body.String(), `name="password"`) {
+		t.Fatal("expected the signup form to be hidden without an invitation")
+	}
+}
+
+func TestInvitationCreateEmailsLink(t *testing.T) {
+	db, dbCleanup := withTestDatabase(t)
+	t.Cleanup(dbCleanup)
+	if err := db.AutoMigrate(&models.Invitation{}); err != nil {
+		t.Fatalf("failed to migrate invitations: %v", err)
+	}
+	recorder := &recordingMailer{}
+	previous := mailer
+	ConfigureMail(recorder)
+	t.Cleanup(func() { mailer = previous })
+
+	form := url.Values{"email": {"Guest@Example.com"}}
+	req := httptest.NewRequest(http.MethodPost, "/app/admin/invitations", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	InvitationCreate(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if len(recorder.sent) != 1 || recorder.sent[0].To != "guest@example.com" {
+		t.Fatalf("expected one invitation email, got %+v", recorder.sent)
+	}
+	if !strings.Contains(recorder.sent[0].Text, "http://example.com/signup?invite=") {
+		t.Fatalf("expected invitation link in email: %q", recorder.sent[0].Text)
+	}
+	if !strings.Contains(recorder.sent[0].HTML, `href="http://example.com/signup?invite=`) {
+		t.Fatalf("expected invitation link in the HTML part: %q", recorder.sent[0].HTML)
+	}
+	if !strings.Contains(w.Body.String(), "/signup?invite=") {
+		t.Fatal("expected the invitation link to be shown to the administrator")
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// LandingPreferences saves the workspace section /app opens with.
+func LandingPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	section := strings.ToLower(strings.TrimSpace(r.FormValue("section")))
+	if pages.NormalizeLandingSection(section) != section {
+		renderComponent(w, r, pages.LandingSectionControl(loadUserProfile(ctx, userID).LandingSection, "Choose ingredients, formulas or reports."))
+		return
+	}
+
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("landing_section", section).Error; err != nil {
+		applog.Error(ctx, "failed to update landing section", "error", err, "userID", userID)
+		http.Error(w, "unable to save preferences", http.StatusInternalServerError)
+		return
+	}
+	applog.Debug(ctx, "landing section persisted", "userID", userID, "section", section)
+
+	if !isHTMX(r) {
+		http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+		return
+	}
+	renderComponent(w, r, pages.LandingSectionControl(section, "The workspace now opens with "+pages.LandingSectionLabel(section)+"."))
+}
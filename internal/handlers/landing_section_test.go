@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/db/mock"
+	"perfugo/models"
+)
+
+func TestLandingSectionOpensWorkspace(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db, err := mock.New(context.Background())
+	if err != nil {
+		t.Fatalf("mock database: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "landing@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	LandingPreferences(rec, authenticatedFormRequest(t, sm, "/app/preferences/landing", url.Values{"section": {"tools"}}, int(user.ID)))
+	if !strings.Contains(rec.Body.String(), "Choose ingredients, formulas or reports.") {
+		t.Fatalf("expected an unsupported section to be refused: %s", rec.Body.String())
+	}
+
+	req := authenticatedFormRequest(t, sm, "/app/preferences/landing", url.Values{"section": {"formulas"}}, int(user.ID))
+	req.Header.Set("HX-Request", "true")
+	rec = httptest.NewRecorder()
+	LandingPreferences(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "opens with Formulas") {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+	var reloaded models.User
+	db.First(&reloaded, user.ID)
+	if reloaded.LandingSection != "formulas" {
+		t.Fatalf("LandingSection = %q", reloaded.LandingSection)
+	}
+
+	for path, module := range map[string]string{
+		"/app":             `data-module="formulas"`,
+		"/app/ingredients": `data-module="ingredients"`,
+	} {
+		req := authenticatedFormRequest(t, sm, path, nil, int(user.ID))
+		req.Method = http.MethodGet
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+		Dashboard(rec, req)
+		if !strings.Contains(rec.Body.String(), module) {
+			t.Fatalf("%s: expected %s in workspace", path, module)
+		}
+	}
+}
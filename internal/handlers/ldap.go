@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/ldap"
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// credentialsDirectory verifies login credentials against an external
+// directory. *ldap.Authenticator is the production implementation.
+type credentialsDirectory interface {
+	Authenticate(ctx context.Context, username, password string) (ldap.Identity, error)
+}
+
+const directoryManagedAccountsMessage = "Accounts are managed by your organisation's directory. Sign in with your directory credentials."
+
+var directory credentialsDirectory
+
+// ConfigureLDAP selects the LDAP credentials backend for Login. A nil
+// authenticator keeps the local password backend.
+func ConfigureLDAP(authenticator *ldap.Authenticator) {
+	directory = nil
+	if authenticator != nil {
+		directory = authenticator
+	}
+	applog.Debug(nil, "credentials backend configured", "ldap", directory != nil)
+}
+
+// verifyDirectoryCredentials binds against the directory and returns the
+// local user mapped to the directory entry, creating it on first login.
+func verifyDirectoryCredentials(r *http.Request, username, password string) (*models.User, bool) {
+	identity, err := directory.Authenticate(r.Context(), username, password)
+	if err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) {
+			applog.Debug(r.Context(), "authentication failed: directory rejected credentials", "username", strings.ToLower(username))
+			sessionManager.Put(r.Context(), sessionLoginMessageKey, "Invalid email or password. Please try again.")
+		} else {
+			applog.Error(r.Context(), "directory authentication failed", "error", err)
+			sessionManager.Put(r.Context(), sessionLoginMessageKey, "We were unable to sign you in. Please try again.")
+		}
+		return nil, false
+	}
+
+	user, err := findOrCreateDirectoryUser(r, identity)
+	if err != nil {
+		applog.Error(r.Context(), "failed to map directory identity to user", "dn", identity.DN, "error", err)
+		sessionManager.Put(r.Context(), sessionLoginMessageKey, "We were unable to sign you in. Please try again.")
+		return nil, false
+	}
+	return user, true
+}
+
+// findOrCreateDirectoryUser matches directory entries to users by email and
+// keeps the display name in sync with the directory.
+func findOrCreateDirectoryUser(r *http.Request, identity ldap.Identity) (*models.User, error) {
+	ctx := r.Context()
+	user, err := findUserByEmail(r, identity.Email)
+	switch {
+	case err == nil:
+		if identity.Name != "" && identity.Name != user.Name {
+			if err := database.WithContext(ctx).Model(user).Update("name", identity.Name).Error; err != nil {
+				return nil, err
+			}
+			user.Name = identity.Name
+		}
+		return user, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	hashed, err := unusablePasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	user = &models.User{
+		Email:        identity.Email,
+		Name:         identity.Name,
+		PasswordHash: hashed,
+		Theme:        models.DefaultTheme,
+		Role:         models.RoleMember,
+	}
+	if err := database.WithContext(ctx).Create(user).Error; err != nil {
+		return nil, err
+	}
+	applog.Debug(ctx, "created user from directory identity", "userID", user.ID)
+	seedNewAccount(ctx, user)
+	return user, nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/ldap"
+	"perfugo/models"
+)
+
+type stubDirectory struct {
+	identities map[string]ldap.Identity
+	password   string
+}
+
+func (s stubDirectory) Authenticate(_ context.Context, username, password string) (ldap.Identity, error) {
+	identity, ok := s.identities[username]
+	if !ok || password != s.password {
+		return ldap.Identity{}, ldap.ErrInvalidCredentials
+	}
+	return identity, nil
+}
+
+func withTestDirectory(t *testing.T, dir credentialsDirectory) {
+	t.Helper()
+	previous := directory
+	directory = dir
+	t.Cleanup(func() { directory = previous })
+}
+
+func TestAuthenticateWithDirectory(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+	_, dbCleanup := withTestDatabase(t)
+	t.Cleanup(dbCleanup)
+	withTestDirectory(t, stubDirectory{
+		password: "directory-secret",
+		identities: map[string]ldap.Identity{
+			"new@lab.example":      {DN: "uid=new,dc=lab", Email: "new@lab.example", Name: "New Perfumer"},
+			"existing@lab.example": {DN: "uid=existing,dc=lab", Email: "existing@lab.example", Name: "Renamed In Directory"},
+		},
+	})
+
+	newRequest := func(t *testing.T) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		ctx, err := sm.Load(req.Context(), "")
+		if err != nil {
+			t.Fatalf("failed to load session context: %v", err)
+		}
+		return req.WithContext(ctx)
+	}
+
+	req := newRequest(t)
+	if _, err := createUser(req, "existing@lab.example", "Old Name", "local-password"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	if ok := authenticate(httptest.NewRecorder(), req, "existing@lab.example", "local-password"); ok {
+		t.Fatal("expected the local password to be ignored by the directory backend")
+	}
+
+	req = newRequest(t)
+	if ok := authenticate(httptest.NewRecorder(), req, "existing@lab.example", "directory-secret"); !ok {
+		t.Fatal("expected directory authentication to succeed")
+	}
+	if name := sm.GetString(req.Context(), sessionUserNameKey); name != "Renamed In Directory" {
+		t.Fatalf("expected name synced from directory, got %q", name)
+	}
+
+	req = newRequest(t)
+	if ok := authenticate(httptest.NewRecorder(), req, "new@lab.example", "directory-secret"); !ok {
+		t.Fatal("expected first directory login to succeed")
+	}
+	var created models.User
+	if err := database.Where("email = ?", "new@lab.example").First(&created).Error; err != nil {
+		t.Fatalf("expected user provisioned from directory: %v", err)
+	}
+	if created.Name != "New Perfumer" || models.NormalizeRole(created.Role) != models.RoleMember {
+		t.Fatalf("unexpected provisioned user: %+v", created)
+	}
+}
+
+func TestSignupDisabledWithDirectory(t *testing.T) {
+	_, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+	_, dbCleanup := withTestDatabase(t)
+	t.Cleanup(dbCleanup)
+	withTestDirectory(t, stubDirectory{})
+
+	form := url.Values{
+		"name":             {"Local"},
+		"email":            {"local@example.com"},
+		"password":         {"password123"},
+		"confirm_password": {"password123"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	sessionManager.LoadAndSave(http.HandlerFunc(Signup)).ServeHTTP(w, req)
+
+	var count int64
+	database.Model(&models.User{}).Where("email = ?", "local@example.com").Count(&count)
+	if count != 0 {
+		t.Fatal("expected signup to be rejected while the directory backend is active")
+	}
+	if !strings.Contains(w.Body.String(), "directory") {
+		t.Fatalf("expected directory message in response, got %q", w.Body.String())
+	}
+}
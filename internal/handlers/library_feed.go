@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const libraryFeedPath = "/feed/library"
+
+// libraryFeed is the JSON form of a user's public library.
+type libraryFeed struct {
+	Title     string                `json:"title"`
+	Author    string                `json:"author"`
+	Updated   time.Time             `json:"updated"`
+	Formulas  []libraryFeedFormula  `json:"formulas"`
+	Materials []libraryFeedMaterial `json:"materials"`
+}
+
+type libraryFeedFormula struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Inspiration string    `json:"inspiration,omitempty"`
+	Published   time.Time `json:"published"`
+	Updated     time.Time `json:"updated"`
+}
+
+type libraryFeedMaterial struct {
+	ID              uint      `json:"id"`
+	Name            string    `json:"name"`
+	CASNumber       string    `json:"cas_number,omitempty"`
+	Type            string    `json:"type,omitempty"`
+	PyramidPosition string    `json:"pyramid_position,omitempty"`
+	Notes           string    `json:"notes,omitempty"`
+	Published       time.Time `json:"published"`
+	Updated         time.Time `json:"updated"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Category  atomCategory `xml:"category"`
+	Summary   string       `xml:"summary,omitempty"`
+}
+
+// LibraryFeed serves a user's published formulas and public materials to
+// anyone holding their feed token, so a personal website can embed the
+// catalogue. It answers with JSON, or with Atom when format=atom. Unknown
+// and revoked tokens get a plain 404.
+func LibraryFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	var users []models.User
+	if token != "" {
+		if err := database.WithContext(ctx).Where("feed_token_hash = ?", hashToken(token)).Limit(1).Find(&users).Error; err != nil {
+			applog.Error(ctx, "failed to look up library feed", "error", err)
+			http.Error(w, "unable to load feed", http.StatusInternalServerError)
+			return
+		}
+	}
+	if len(users) == 0 || !users[0].IsActive() {
+		http.NotFound(w, r)
+		return
+	}
+	user := users[0]
+
+	feed, err := loadLibraryFeed(r, user)
+	if err != nil {
+		applog.Error(ctx, "failed to load library feed", "error", err, "userID", user.ID)
+		http.Error(w, "unable to load feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.URL.Query().Get("format") == "atom" {
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		_, _ = w.Write([]byte(xml.Header))
+		if err := xml.NewEncoder(w).Encode(libraryAtomFeed(r, user, feed)); err != nil {
+			applog.Error(ctx, "failed to encode library feed", "error", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(feed); err != nil {
+		applog.Error(ctx, "failed to encode library feed", "error", err)
+	}
+}
+
+// loadLibraryFeed gathers the latest version of each formula the user
+// created and published, and the materials they own and share.
+func loadLibraryFeed(r *http.Request, user models.User) (libraryFeed, error) {
+	// The feed is embedded on public sites, so never fall back to the email.
+	author := galleryAnonymousAuthor
+	if name := strings.TrimSpace(user.Name); name != "" {
+		author = name
+	}
+	feed := libraryFeed{
+		Title:     author + "'s perfume library",
+		Author:    author,
+		Updated:   user.UpdatedAt.UTC(),
+		Formulas:  []libraryFeedFormula{},
+		Materials: []libraryFeedMaterial{},
+	}
+
+	var formulas []models.Formula
+	if err := database.WithContext(r.Context()).
+		Where("created_by_id = ? AND published = ? AND is_latest = ?", user.ID, true, true).
+		Order("updated_at desc").
+		Find(&formulas).Error; err != nil {
+		return feed, err
+	}
+	for _, formula := range formulas {
+		feed.Formulas = append(feed.Formulas, libraryFeedFormula{
+			ID:          formula.ID,
+			Name:        formula.Name,
+			Version:     formula.Version,
+			Inspiration: strings.TrimSpace(formula.Inspiration),
+			Published:   formula.CreatedAt.UTC(),
+			Updated:     formula.UpdatedAt.UTC(),
+		})
+		if formula.UpdatedAt.After(feed.Updated) {
+			feed.Updated = formula.UpdatedAt.UTC()
+		}
+	}
+
+	var chemicals []models.AromaChemical
+	if err := database.WithContext(r.Context()).
+		Where("owner_id = ? AND public = ?", user.ID, true).
+		Order("ingredient_name asc").
+		Find(&chemicals).Error; err != nil {
+		return feed, err
+	}
+	for _, chemical := range chemicals {
+		feed.Materials = append(feed.Materials, libraryFeedMaterial{
+			ID:              chemical.ID,
+			Name:            chemical.IngredientName,
+			CASNumber:       strings.TrimSpace(chemical.CASNumber),
+			Type:            chemical.Type,
+			PyramidPosition: chemical.PyramidPosition,
+			Notes:           strings.TrimSpace(chemical.Notes),
+			Published:       chemical.CreatedAt.UTC(),
+			Updated:         chemical.UpdatedAt.UTC(),
+		})
+		if chemical.UpdatedAt.After(feed.Updated) {
+			feed.Updated = chemical.UpdatedAt.UTC()
+		}
+	}
+	return feed, nil
+}
+
+// libraryAtomFeed lists formulas and materials as Atom entries with tag
+// URIs, which stay stable as records are edited.
+func libraryAtomFeed(r *http.Request, user models.User, feed libraryFeed) atomFeed {
+	host := requestHostname(r)
+	tag := func(created time.Time, kind string, id uint) string {
+		return fmt.Sprintf("tag:%s,%s:%s/%d", host, created.Format("2006-01-02"), kind, id)
+	}
+
+	atom := atomFeed{
+		ID:      tag(user.CreatedAt.UTC(), "library", user.ID),
+		Title:   feed.Title,
+		Updated: feed.Updated.Format(time.RFC3339),
+		Author:  atomPerson{Name: feed.Author},
+		Link:    atomLink{Rel: "self", Href: absoluteURL(r, pages.Path(r.URL.RequestURI()))},
+	}
+	for _, formula := range feed.Formulas {
+		atom.Entries = append(atom.Entries, atomEntry{
+			ID:        tag(formula.Published, "formula", formula.ID),
+			Title:     fmt.Sprintf("%s v%d", formula.Name, formula.Version),
+			Published: formula.Published.Format(time.RFC3339),
+			Updated:   formula.Updated.Format(time.RFC3339),
+			Category:  atomCategory{Term: "formula"},
+			Summary:   formula.Inspiration,
+		})
+	}
+	for _, material := range feed.Materials {
+		atom.Entries = append(atom.Entries, atomEntry{
+			ID:        tag(material.Published, "material", material.ID),
+			Title:     material.Name,
+			Published: material.Published.Format(time.RFC3339),
+			Updated:   material.Updated.Format(time.RFC3339),
+			Category:  atomCategory{Term: "material"},
+			Summary:   material.Notes,
+		})
+	}
+	return atom
+}
+
+// requestHostname is the request's host without its port, for identifiers
+// such as tag URIs that must not carry one.
+func requestHostname(r *http.Request) string {
+	if parsed, err := url.Parse("//" + r.Host); err == nil && parsed.Hostname() != "" {
+		return parsed.Hostname()
+	}
+	return r.Host
+}
+
+// LibraryFeedPreferences turns the user's public library feed on or off.
+// Enabling it issues a new token, replacing any earlier links; as only the
+// token's hash is kept, the links are shown once.
+func LibraryFeedPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	panel := pages.LibraryFeedPanel{}
+	hash := ""
+	if r.FormValue("action") == "enable" {
+		token, err := newToken()
+		if err != nil {
+			applog.Error(ctx, "failed to generate feed token", "error", err)
+			http.Error(w, "unable to enable feed", http.StatusInternalServerError)
+			return
+		}
+		hash = hashToken(token)
+		link := pages.Path(libraryFeedPath + "?token=" + url.QueryEscape(token))
+		panel = pages.LibraryFeedPanel{
+			Enabled: true,
+			JSONURL: absoluteURL(r, link),
+			AtomURL: absoluteURL(r, link+"&format=atom"),
+			Message: "Feed enabled. Copy the links now; they are not shown again.",
+		}
+	} else {
+		panel.Message = "Feed disabled. Earlier links no longer work."
+	}
+
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("feed_token_hash", hash).Error; err != nil {
+		applog.Error(ctx, "failed to save feed token", "error", err, "userID", userID)
+		http.Error(w, "unable to save feed settings", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "library feed updated", "userID", userID, "enabled", panel.Enabled)
+	renderComponent(w, r, pages.LibraryFeedControl(panel))
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestLibraryFeedServesPublishedRecords(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "perfumer@example.com", Name: "Ana Costa", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	for _, record := range []any{
+		&models.Formula{Name: "Nuit", Version: 1, IsLatest: true, CreatedByID: &user.ID, Published: true, Inspiration: "A walk at dusk"},
+		&models.Formula{Name: "Secret", Version: 1, IsLatest: true, CreatedByID: &user.ID},
+		&models.AromaChemical{IngredientName: "House musk", OwnerID: user.ID, Public: true},
+		&models.AromaChemical{IngredientName: "Private base", OwnerID: user.ID},
+	} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("create %T: %v", record, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	LibraryFeedPreferences(rec, authenticatedFormRequest(t, sm, "/app/preferences/feed", url.Values{"action": {"enable"}}, int(user.ID)))
+	match := regexp.MustCompile(`/feed/library\?token=([^"&]+)`).FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		t.Fatalf("feed link not shown: %s", rec.Body.String())
+	}
+
+	fetch := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		LibraryFeed(rec, httptest.NewRequest(http.MethodGet, "/feed/library?"+query, nil))
+		return rec
+	}
+
+	rec = fetch("token=" + match[1])
+	var feed libraryFeed
+	if err := json.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("decode feed: %v: %s", err, rec.Body.String())
+	}
+	if feed.Author != "Ana Costa" || len(feed.Formulas) != 1 || feed.Formulas[0].Name != "Nuit" ||
+		len(feed.Materials) != 1 || feed.Materials[0].Name != "House musk" {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	if got := rec.Header().Get("Cache-Control"); !strings.HasPrefix(got, "private") {
+		t.Fatalf("Cache-Control = %q, want private so revoked links stop working", got)
+	}
+
+	rec = fetch("token=" + match[1] + "&format=atom")
+	if body := rec.Body.String(); !strings.Contains(rec.Header().Get("Content-Type"), "atom") ||
+		!strings.Contains(body, "<title>Nuit v1</title>") || strings.Contains(body, "Secret") {
+		t.Fatalf("unexpected atom feed: %s", body)
+	}
+
+	if rec := fetch("token=guess"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown token status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	LibraryFeedPreferences(rec, authenticatedFormRequest(t, sm, "/app/preferences/feed", url.Values{"action": {"disable"}}, int(user.ID)))
+	if rec := fetch("token=" + match[1]); rec.Code != http.StatusNotFound {
+		t.Fatalf("disabled feed status = %d", rec.Code)
+	}
+}
+
+func TestLibraryFeedHidesEmailOfUnnamedAuthors(t *testing.T) {
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "quiet@example.com", PasswordHash: "x", FeedTokenHash: hashToken("feed-token")}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	for _, query := range []string{"token=feed-token", "token=feed-token&format=atom"} {
+		rec := httptest.NewRecorder()
+		LibraryFeed(rec, httptest.NewRequest(http.MethodGet, "/feed/library?"+query, nil))
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || strings.Contains(body, "quiet@example.com") || !strings.Contains(body, galleryAnonymousAuthor) {
+			t.Fatalf("%s: expected an anonymous author, got %d: %s", query, rec.Code, body)
+		}
+	}
+}
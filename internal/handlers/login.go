@@ -46,6 +46,11 @@ func Login(w http.ResponseWriter, r *http.Request) {
 
 		applog.Debug(r.Context(), "login form parsed", "email", strings.ToLower(email))
 
+		if !verifyCaptcha(r) {
+			renderLogin(w, r, captchaFailedMessage, email)
+			return
+		}
+
 		if email == "" || password == "" {
 			applog.Debug(r.Context(), "login form missing credentials", "emailPresent", email != "", "passwordPresent", password != "")
 			renderLogin(w, r, "Email and password are required.", email)
@@ -77,10 +82,10 @@ func renderLogin(w http.ResponseWriter, r *http.Request, message, email string)
 	var component templ.Component
 	if isHTMX(r) {
 		applog.Debug(r.Context(), "rendering HTMX login partial", "messagePresent", message != "")
-		component = pages.LoginPartial(message, email)
+		component = pages.LoginPartial(message, email, OIDCEnabled(), captchaWidget())
 	} else {
 		applog.Debug(r.Context(), "rendering full login page", "messagePresent", message != "")
-		component = pages.Login(message, email)
+		component = pages.Login(message, email, OIDCEnabled(), captchaWidget())
 	}
 
 	if err := component.Render(r.Context(), w); err != nil {
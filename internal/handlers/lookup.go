@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+const (
+	defaultLookupLimit = 10
+	maxLookupLimit     = 50
+)
+
+// Match kinds reported by the alias lookup, strongest first.
+const (
+	lookupMatchName   = "name"
+	lookupMatchAlias  = "alias"
+	lookupMatchPrefix = "prefix"
+	lookupMatchFuzzy  = "fuzzy"
+)
+
+// aliasLookupResponse is the JSON document returned by AliasLookup.
+type aliasLookupResponse struct {
+	Query   string             `json:"query"`
+	Matches []aliasLookupMatch `json:"matches"`
+}
+
+// aliasLookupMatch names a chemical and the name that matched the query.
+type aliasLookupMatch struct {
+	ID             uint   `json:"id"`
+	IngredientName string `json:"ingredient_name"`
+	CASNumber      string `json:"cas_number,omitempty"`
+	MatchedName    string `json:"matched_name"`
+	Language       string `json:"language,omitempty"`
+	AliasKind      string `json:"alias_kind,omitempty"`
+	Match          string `json:"match"`
+	Distance       int    `json:"distance"`
+}
+
+// AliasLookup resolves a free-form name - a trade name, synonym, foreign
+// name or typo - to the aroma chemicals visible to the current user, checking
+// ingredient names and OtherNames before falling back to prefix and
+// edit-distance matches. Accents are ignored. An optional lang parameter
+// skips aliases tagged with another language.
+func AliasLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use GET to look up aliases.")
+		return
+	}
+	if !requireAPIScope(w, r, models.ScopeIngredients, false) {
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("name"))
+	if normalizeIngredientName(query) == "" {
+		writeProblem(w, r, http.StatusBadRequest, "Provide a name to look up.", fieldProblem{Field: "name", Message: "Name is required."})
+		return
+	}
+
+	limit := defaultLookupLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, "Limit must be a positive whole number.", fieldProblem{Field: "limit", Message: "Limit must be a positive whole number."})
+			return
+		}
+		limit = min(value, maxLookupLimit)
+	}
+
+	language := ""
+	if raw := strings.TrimSpace(r.URL.Query().Get("lang")); raw != "" {
+		language = models.NormalizeLanguageCode(raw)
+		if language == "" {
+			writeProblem(w, r, http.StatusBadRequest, "Language must be a two-letter code such as fr or de.", fieldProblem{Field: "lang", Message: "Language must be a two-letter code."})
+			return
+		}
+	}
+
+	userID, _ := currentUserID(r)
+	chemicals := loadAromaChemicals(r.Context(), userID)
+	matches := lookupAliases(chemicals, query, language, limit)
+	applog.Debug(r.Context(), "alias lookup resolved", "query", query, "matches", len(matches))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(aliasLookupResponse{Query: query, Matches: matches}); err != nil {
+		applog.Error(r.Context(), "failed to encode alias lookup response", "error", err)
+	}
+}
+
+// lookupAliases ranks chemicals against the query, keeping the best match per
+// chemical. Exact ingredient names rank above exact aliases, then prefixes,
+// then fuzzy matches by ascending edit distance. A non-empty language skips
+// aliases tagged with a different one.
+func lookupAliases(chemicals []models.AromaChemical, query, language string, limit int) []aliasLookupMatch {
+	target := normalizeIngredientName(query)
+	matches := make([]aliasLookupMatch, 0)
+	for _, chemical := range chemicals {
+		best, ok := bestAliasMatch(chemical, target, language)
+		if ok {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := lookupMatchRank(matches[i].Match), lookupMatchRank(matches[j].Match)
+		if a != b {
+			return a < b
+		}
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].IngredientName < matches[j].IngredientName
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func bestAliasMatch(chemical models.AromaChemical, target, language string) (aliasLookupMatch, bool) {
+	type candidate struct {
+		name  string
+		alias *models.OtherName
+	}
+	candidates := []candidate{{name: chemical.IngredientName}}
+	for i, other := range chemical.OtherNames {
+		if language != "" && other.Language != "" && other.Language != language {
+			continue
+		}
+		candidates = append(candidates, candidate{name: other.Name, alias: &chemical.OtherNames[i]})
+	}
+
+	var best aliasLookupMatch
+	found := false
+	for _, c := range candidates {
+		normalized := normalizeIngredientName(c.name)
+		if normalized == "" {
+			continue
+		}
+
+		kind := ""
+		distance := levenshteinDistance(normalized, target)
+		switch {
+		case normalized == target && c.alias == nil:
+			kind = lookupMatchName
+		case normalized == target:
+			kind = lookupMatchAlias
+		case strings.HasPrefix(normalized, target):
+			kind = lookupMatchPrefix
+		case similarAlias(normalized, target):
+			kind = lookupMatchFuzzy
+		default:
+			continue
+		}
+
+		match := aliasLookupMatch{
+			ID:             chemical.ID,
+			IngredientName: chemical.IngredientName,
+			CASNumber:      strings.TrimSpace(chemical.CASNumber),
+			MatchedName:    c.name,
+			Match:          kind,
+			Distance:       distance,
+		}
+		if c.alias != nil {
+			match.Language, match.AliasKind = c.alias.Language, c.alias.Kind
+		}
+		if !found || lookupMatchRank(kind) < lookupMatchRank(best.Match) ||
+			(kind == best.Match && distance < best.Distance) {
+			best = match
+			found = true
+		}
+	}
+	return best, found
+}
+
+func lookupMatchRank(kind string) int {
+	switch kind {
+	case lookupMatchName:
+		return 0
+	case lookupMatchAlias:
+		return 1
+	case lookupMatchPrefix:
+		return 2
+	default:
+		return 3
+	}
+}
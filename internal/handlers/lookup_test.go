@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+func TestLookupAliases(t *testing.T) {
+	t.Parallel()
+
+	chemicals := []models.AromaChemical{
+		{Model: gorm.Model{ID: 1}, IngredientName: "Iso E Super", OtherNames: []models.OtherName{{Name: "OTNE"}}},
+		{Model: gorm.Model{ID: 2}, IngredientName: "Ambroxan", OtherNames: []models.OtherName{{Name: "Ambrox DL"}, {Name: "Ambrofix"}}},
+		{Model: gorm.Model{ID: 3}, IngredientName: "Hedione"},
+		{Model: gorm.Model{ID: 4}, IngredientName: "Isobutyl quinoline"},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantIDs   []uint
+		wantMatch string
+	}{
+		{name: "ingredient name", query: "hedione", wantIDs: []uint{3}, wantMatch: lookupMatchName},
+		{name: "synonym", query: "Ambrofix", wantIDs: []uint{2}, wantMatch: lookupMatchAlias},
+		{name: "typo", query: "Hediome", wantIDs: []uint{3}, wantMatch: lookupMatchFuzzy},
+		{name: "prefix", query: "iso", wantIDs: []uint{1, 4}, wantMatch: lookupMatchPrefix},
+		{name: "no match", query: "Galaxolide"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			matches := lookupAliases(chemicals, tt.query, "", defaultLookupLimit)
+			if len(matches) != len(tt.wantIDs) {
+				t.Fatalf("expected %d matches, got %+v", len(tt.wantIDs), matches)
+			}
+			for i, match := range matches {
+				if match.ID != tt.wantIDs[i] || match.Match != tt.wantMatch {
+					t.Fatalf("unexpected match %d: %+v", i, match)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupAliasesAcrossLanguages(t *testing.T) {
+	t.Parallel()
+
+	chemicals := []models.AromaChemical{
+		{Model: gorm.Model{ID: 1}, IngredientName: "Hedione", OtherNames: []models.OtherName{
+			{Name: "Dihydrojasmonate de méthyle", Language: "fr", Kind: models.AliasKindINCI},
+			{Name: "Methyldihydrojasmonat", Language: "de", Kind: models.AliasKindTranslation},
+		}},
+		{Model: gorm.Model{ID: 2}, IngredientName: "Ambroxan"},
+	}
+
+	matches := lookupAliases(chemicals, "DIHYDROJASMONATE DE METHYLE", "", defaultLookupLimit)
+	if len(matches) != 1 || matches[0].ID != 1 || matches[0].Match != lookupMatchAlias || matches[0].Language != "fr" || matches[0].AliasKind != models.AliasKindINCI {
+		t.Fatalf("expected the French alias to match without its accent, got %+v", matches)
+	}
+	if matches := lookupAliases(chemicals, "Methyldihydrojasmonat", "fr", defaultLookupLimit); len(matches) != 0 {
+		t.Fatalf("German alias should be skipped for lang=fr, got %+v", matches)
+	}
+	if matches := lookupAliases(chemicals, "Hedione", "fr", defaultLookupLimit); len(matches) != 1 || matches[0].Match != lookupMatchName {
+		t.Fatalf("ingredient names should match in any language, got %+v", matches)
+	}
+}
+
+func TestAliasLookupRequiresName(t *testing.T) {
+	rr := httptest.NewRecorder()
+	AliasLookup(rr, httptest.NewRequest(http.MethodGet, "/app/api/aroma-chemicals/lookup?name=+", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var problem problemDetails
+	if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil || len(problem.Errors) != 1 {
+		t.Fatalf("expected a validation problem, got %v %+v", err, problem)
+	}
+}
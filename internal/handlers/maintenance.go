@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+)
+
+const maintenancePath = "/maintenance"
+
+var maintenanceEnabled atomic.Bool
+
+// maintenanceExemptPrefixes stay reachable while maintenance mode is active so
+// probes and metrics scrapes keep working and administrators can still sign in.
+var maintenanceExemptPrefixes = []string{
+	"/healthz",
+	"/readyz",
+	"/version",
+	"/metrics",
+	maintenancePath,
+	"/login",
+	"/logout",
+	"/auth/oidc/",
+	"/assets/",
+}
+
+// SetMaintenanceMode toggles the instance-wide maintenance switch.
+func SetMaintenanceMode(enabled bool) {
+	maintenanceEnabled.Store(enabled)
+	applog.Info(nil, "maintenance mode updated", "enabled", enabled)
+}
+
+// MaintenanceMode reports whether the instance is currently in maintenance mode.
+func MaintenanceMode() bool {
+	return maintenanceEnabled.Load()
+}
+
+// MaintenanceGate diverts non-admin traffic to the maintenance page while the switch is on.
+func MaintenanceGate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !MaintenanceMode() || maintenanceExempt(r.URL.Path) || currentUserIsAdmin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		applog.Debug(r.Context(), "request blocked by maintenance mode", "path", r.URL.Path, "htmx", isHTMX(r))
+		if acceptsJSON(r) {
+			w.Header().Set("Retry-After", "300")
+			writeProblem(w, r, http.StatusServiceUnavailable, "Perfugo is undergoing scheduled maintenance.")
+			return
+		}
+		if isHTMX(r) {
+			w.Header().Set("HX-Redirect", pages.Path(maintenancePath))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		renderMaintenancePage(w, r)
+	})
+}
+
+// Maintenance renders the maintenance notice, or returns to the app once the switch is off.
+func Maintenance(w http.ResponseWriter, r *http.Request) {
+	if !MaintenanceMode() {
+		redirectToApp(w, r)
+		return
+	}
+	renderMaintenancePage(w, r)
+}
+
+// MaintenanceToggle lets administrators enable or disable maintenance mode.
+func MaintenanceToggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		applog.Debug(r.Context(), "failed to parse maintenance form", "error", err)
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	enabled := checkboxChecked(r.FormValue("enabled"))
+	SetMaintenanceMode(enabled)
+
+	if isHTMX(r) {
+		renderComponent(w, r, pages.MaintenanceControl(enabled))
+		return
+	}
+	http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+}
+
+func renderMaintenancePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Retry-After", "300")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if err := pages.Maintenance().Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render maintenance page", "error", err)
+	}
+}
+
+func maintenanceExempt(path string) bool {
+	for _, prefix := range maintenanceExemptPrefixes {
+		if path == prefix || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestMaintenanceGate(t *testing.T) {
+	sm, cleanup := withTestSessionManager(t)
+	t.Cleanup(cleanup)
+
+	original := MaintenanceMode()
+	t.Cleanup(func() { SetMaintenanceMode(original) })
+	SetMaintenanceMode(true)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		htmx       bool
+		json       bool
+		role       string
+		wantStatus int
+		wantHeader string
+	}{
+		{name: "member page", path: "/app", role: models.RoleMember, wantStatus: http.StatusServiceUnavailable},
+		{name: "member htmx", path: "/app/sections/formulas/list", htmx: true, role: models.RoleMember, wantStatus: http.StatusServiceUnavailable, wantHeader: maintenancePath},
+		{name: "api client", path: "/app/api/formulas", json: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "anonymous probe", path: "/healthz", wantStatus: http.StatusNoContent},
+		{name: "metrics scrape", path: "/metrics", wantStatus: http.StatusNoContent},
+		{name: "anonymous asset", path: "/assets/app.css", wantStatus: http.StatusNoContent},
+		{name: "admin page", path: "/app", role: models.RoleAdmin, wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			ctx, err := sm.Load(req.Context(), "")
+			if err != nil {
+				t.Fatalf("failed to load session context: %v", err)
+			}
+			req = req.WithContext(ctx)
+			if tt.role != "" {
+				sm.Put(req.Context(), sessionAuthenticatedKey, true)
+				sm.Put(req.Context(), sessionUserIDKey, 1)
+				sm.Put(req.Context(), sessionUserRoleKey, tt.role)
+			}
+			if tt.htmx {
+				req.Header.Set("HX-Request", "true")
+			}
+			if tt.json {
+				req.Header.Set("Accept", "application/json")
+			}
+
+			rr := httptest.NewRecorder()
+			MaintenanceGate(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if got := rr.Header().Get("HX-Redirect"); got != tt.wantHeader {
+				t.Fatalf("expected HX-Redirect %q, got %q", tt.wantHeader, got)
+			}
+			if tt.json && rr.Header().Get("Content-Type") != problemContentType {
+				t.Fatalf("expected problem details for API clients, got %q", rr.Header().Get("Content-Type"))
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && !tt.htmx && !tt.json && !strings.Contains(rr.Body.String(), "maintenance") {
+				t.Fatalf("expected maintenance page body, got %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMaintenanceGatePassesThroughWhenDisabled(t *testing.T) {
+	original := MaintenanceMode()
+	t.Cleanup(func() { SetMaintenanceMode(original) })
+	SetMaintenanceMode(false)
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	MaintenanceGate(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/app", nil))
+	if !called {
+		t.Fatal("expected request to reach the next handler")
+	}
+}
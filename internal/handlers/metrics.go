@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/ai"
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// aiJobs counts the model calls made through the configured AI client since
+// the process started.
+var aiJobs struct {
+	running   atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+}
+
+// meteredAIClient counts the calls of the AI client it wraps for Metrics.
+type meteredAIClient struct {
+	ai.Client
+}
+
+func (c meteredAIClient) FetchAromaProfile(ctx context.Context, ingredient string, opts ai.FetchOptions) (ai.Profile, error) {
+	done := startAIJob()
+	profile, err := c.Client.FetchAromaProfile(ctx, ingredient, opts)
+	done(err)
+	return profile, err
+}
+
+func (c meteredAIClient) ExtractFormula(ctx context.Context, input ai.FormulaImportInput) (ai.FormulaImportResult, error) {
+	done := startAIJob()
+	result, err := c.Client.ExtractFormula(ctx, input)
+	done(err)
+	return result, err
+}
+
+func startAIJob() func(error) {
+	aiJobs.running.Add(1)
+	return func(err error) {
+		aiJobs.running.Add(-1)
+		aiJobs.completed.Add(1)
+		if err != nil {
+			aiJobs.failed.Add(1)
+		}
+	}
+}
+
+// Metrics handles GET /metrics: adoption and health gauges for dashboards in
+// the OpenMetrics text format. It is meant to sit behind RequireProbeAccess.
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body bytes.Buffer
+	if database != nil {
+		if err := writeLibraryMetrics(r.Context(), &body, nowFunc()); err != nil {
+			applog.Error(r.Context(), "failed to collect metrics", "error", err)
+			http.Error(w, "unable to collect metrics", http.StatusInternalServerError)
+			return
+		}
+	}
+	writeMetricFamily(&body, "perfugo_ai_jobs_running", "gauge", "Model calls in progress.")
+	fmt.Fprintf(&body, "perfugo_ai_jobs_running %d\n", aiJobs.running.Load())
+	writeMetricFamily(&body, "perfugo_ai_jobs", "counter", "Model calls finished since the instance started.")
+	fmt.Fprintf(&body, "perfugo_ai_jobs_total %d\n", aiJobs.completed.Load())
+	writeMetricFamily(&body, "perfugo_ai_jobs_failed", "counter", "Model calls that returned an error since the instance started.")
+	fmt.Fprintf(&body, "perfugo_ai_jobs_failed_total %d\n", aiJobs.failed.Load())
+	body.WriteString("# EOF\n")
+
+	w.Header().Set("Content-Type", openMetricsContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := body.WriteTo(w); err != nil {
+		applog.Error(r.Context(), "failed to write metrics", "error", err)
+	}
+}
+
+// writeLibraryMetrics counts what members keep in perfugo and how much of it
+// is produced.
+func writeLibraryMetrics(ctx context.Context, body *bytes.Buffer, now time.Time) error {
+	db := database.WithContext(ctx)
+
+	var formulas []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&models.Formula{}).Select("status, COUNT(*) AS count").
+		Where("is_latest = ?", true).Group("status").Order("status").Scan(&formulas).Error; err != nil {
+		return err
+	}
+	writeMetricFamily(body, "perfugo_formulas", "gauge", "Formulas by workflow status, counting the latest version of each.")
+	for _, row := range formulas {
+		fmt.Fprintf(body, "perfugo_formulas{status=%q} %d\n", row.Status, row.Count)
+	}
+
+	counts := []struct {
+		name, help string
+		query      *gorm.DB
+	}{
+		{"perfugo_aroma_chemicals", "Aroma chemicals in every member's library.", db.Model(&models.AromaChemical{})},
+		{"perfugo_users_active", "Member accounts that are not deactivated.", db.Model(&models.User{}).Where("deactivated_at IS NULL")},
+		{"perfugo_production_batches_open", "Production batches still being weighed.", db.Model(&models.ProductionBatch{}).Where("status <> ?", models.ProductionBatchFinalized)},
+		{"perfugo_production_batches_finalized", "Finalized production batches still on record.", db.Model(&models.ProductionBatch{}).Where("status = ?", models.ProductionBatchFinalized)},
+		{"perfugo_production_batches_finalized_last_day", "Production batches finalized in the last 24 hours.", db.Model(&models.ProductionBatch{}).
+			Where("status = ? AND finalized_at >= ?", models.ProductionBatchFinalized, now.Add(-24*time.Hour))},
+	}
+	for _, count := range counts {
+		var value int64
+		if err := count.query.Count(&value).Error; err != nil {
+			return err
+		}
+		writeMetricFamily(body, count.name, "gauge", count.help)
+		fmt.Fprintf(body, "%s %d\n", count.name, value)
+	}
+	return nil
+}
+
+func writeMetricFamily(body *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(body, "# TYPE %s %s\n# HELP %s %s\n", name, kind, name, help)
+}
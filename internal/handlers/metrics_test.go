@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/internal/ai"
+	"perfugo/models"
+)
+
+func TestMetricsExportsLibraryAndAIGauges(t *testing.T) {
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.ProductionBatch{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB, prevClient, prevNow := database, openAIClient, nowFunc
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	database, nowFunc = db, func() time.Time { return now }
+	t.Cleanup(func() { database, openAIClient, nowFunc = prevDB, prevClient, prevNow })
+
+	gone := now
+	db.Create(&models.User{Email: "ada@example.com", PasswordHash: "x"})
+	db.Create(&models.User{Email: "bo@example.com", PasswordHash: "x", DeactivatedAt: &gone})
+	db.Create(&models.AromaChemical{IngredientName: "Bergamot", OwnerID: 1})
+	db.Create(&models.Formula{Name: "Chypre", Version: 1, IsLatest: true, Status: models.FormulaStatusApproved})
+	db.Create(&models.Formula{Name: "Cologne", Version: 1, IsLatest: true})
+	yesterday, lastWeek := now.Add(-time.Hour), now.AddDate(0, 0, -7)
+	db.Create(&models.ProductionBatch{FormulaID: 1, FormulaName: "Chypre", OwnerID: 1, LotNumber: "A1", Status: models.ProductionBatchFinalized, FinalizedAt: &yesterday})
+	db.Create(&models.ProductionBatch{FormulaID: 1, FormulaName: "Chypre", OwnerID: 1, LotNumber: "A0", Status: models.ProductionBatchFinalized, FinalizedAt: &lastWeek})
+	db.Create(&models.ProductionBatch{FormulaID: 1, FormulaName: "Chypre", OwnerID: 1, LotNumber: "A2"})
+
+	ConfigureAI(blockingAIClient{})
+	before := aiJobs.failed.Load()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := openAIClient.FetchAromaProfile(ctx, "Bergamot", ai.FetchOptions{}); err == nil {
+		t.Fatal("expected the cancelled call to fail")
+	}
+	if aiJobs.failed.Load() != before+1 || aiJobs.running.Load() != 0 {
+		t.Fatalf("expected one failed and none running, got %d failed, %d running", aiJobs.failed.Load()-before, aiJobs.running.Load())
+	}
+
+	rec := httptest.NewRecorder()
+	Metrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/openmetrics-text") {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		`perfugo_formulas{status="approved"} 1`,
+		`perfugo_formulas{status="draft"} 1`,
+		"perfugo_aroma_chemicals 1",
+		"perfugo_users_active 1",
+		"perfugo_production_batches_open 1",
+		"perfugo_production_batches_finalized_last_day 1",
+		"perfugo_production_batches_finalized 2",
+		"# TYPE perfugo_production_batches_finalized gauge",
+		"perfugo_ai_jobs_running 0",
+		"# TYPE perfugo_ai_jobs_failed counter",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("expected %q in:\n%s", line, body)
+		}
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Fatal("expected the exposition to end with # EOF")
+	}
+}
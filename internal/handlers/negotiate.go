@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Representations a resource endpoint can negotiate between.
+const (
+	mediaJSON = "application/json"
+	mediaCSV  = "text/csv"
+	mediaHTML = "text/html"
+)
+
+// negotiateMedia picks the offer the Accept header ranks highest, preferring
+// earlier offers on ties. A request without Accept gets the first offer, and
+// HTMX requests favour HTML when the client accepts anything. It returns ""
+// when no offer is acceptable.
+func negotiateMedia(r *http.Request, offers ...string) string {
+	accept := strings.TrimSpace(r.Header.Get("Accept"))
+	if isHTMX(r) && (accept == "" || accept == "*/*") && slices.Contains(offers, mediaHTML) {
+		return mediaHTML
+	}
+	if accept == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptRange is one media range of an Accept header with its quality.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			q = value
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific range matching
+// offer, or zero when none does.
+func acceptQuality(ranges []acceptRange, offer string) float64 {
+	major, _, _ := strings.Cut(offer, "/")
+	q, specificity := 0.0, -1
+	for _, rng := range ranges {
+		level := -1
+		switch rng.mediaType {
+		case offer:
+			level = 2
+		case major + "/*":
+			level = 1
+		case "*/*":
+			level = 0
+		}
+		if level > specificity {
+			q, specificity = rng.q, level
+		}
+	}
+	return q
+}
+
+// writeNotAcceptable answers 406 listing the representations on offer.
+func writeNotAcceptable(w http.ResponseWriter, r *http.Request, offers ...string) {
+	writeProblem(w, r, http.StatusNotAcceptable, "This resource is available as "+strings.Join(offers, ", ")+".")
+}
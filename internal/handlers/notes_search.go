@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// notesSearchLimit is how many matches of each kind the notes search lists.
+const notesSearchLimit = 20
+
+// NotesSearch finds q in formula notes, the member's evaluation notes,
+// comments on their blind tests and observations on their batches.
+func NotesSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	search := pages.NotesSearch{Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+	if search.Query != "" {
+		if err := searchNotes(r.Context(), userID, &search); err != nil {
+			applog.Error(r.Context(), "failed to search notes", "error", err, "userID", userID)
+			http.Error(w, "unable to search notes", http.StatusInternalServerError)
+			return
+		}
+	}
+	renderComponent(w, r, pages.NotesSearchResults(search))
+}
+
+// searchNotes fills search with up to notesSearchLimit matches of each
+// kind, formula notes first.
+func searchNotes(ctx context.Context, userID uint, search *pages.NotesSearch) error {
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(search.Query)) + "%"
+	add := func(kind, title, text string) {
+		search.Hits = append(search.Hits, pages.NoteHit{Kind: kind, Title: title, Snippet: pages.NoteSnippetOf(text, search.Query)})
+	}
+	truncate := func(n int) int {
+		if n > notesSearchLimit {
+			search.Truncated = true
+		}
+		return min(n, notesSearchLimit)
+	}
+
+	var formulas []models.Formula
+	if err := database.WithContext(ctx).
+		Where(`LOWER(notes) LIKE ? ESCAPE '\'`, pattern).
+		Order("updated_at desc").Order("id desc").
+		Limit(notesSearchLimit + 1).
+		Find(&formulas).Error; err != nil {
+		return err
+	}
+	for _, formula := range formulas[:truncate(len(formulas))] {
+		add(pages.NoteHitFormula, fmt.Sprintf("%s · v%d", formula.Name, formula.Version), formula.Notes)
+	}
+
+	var notes []struct {
+		Perception     string
+		DilutionHabit  string
+		IngredientName string
+	}
+	if err := database.WithContext(ctx).
+		Table("user_ingredient_notes AS n").
+		Select("n.perception, n.dilution_habit, c.ingredient_name").
+		Joins("JOIN aroma_chemicals AS c ON c.id = n.aroma_chemical_id AND c.deleted_at IS NULL").
+		Where("n.user_id = ? AND n.deleted_at IS NULL", userID).
+		Where(`LOWER(n.perception) LIKE ? ESCAPE '\' OR LOWER(n.dilution_habit) LIKE ? ESCAPE '\'`, pattern, pattern).
+		Order("n.updated_at desc").
+		Limit(notesSearchLimit + 1).
+		Scan(&notes).Error; err != nil {
+		return err
+	}
+	for _, note := range notes[:truncate(len(notes))] {
+		text := note.Perception
+		if !strings.Contains(strings.ToLower(text), strings.ToLower(search.Query)) {
+			text = note.DilutionHabit
+		}
+		add(pages.NoteHitEvaluation, note.IngredientName, text)
+	}
+
+	var comments []struct {
+		TestName  string
+		Revealed  bool
+		Code      string
+		Formula   string
+		Evaluator string
+		Comment   string
+	}
+	if err := database.WithContext(ctx).
+		Table("blind_scores AS s").
+		Select("t.name AS test_name, t.revealed_at IS NOT NULL AS revealed, p.code, f.name AS formula, s.evaluator, s.comment").
+		Joins("JOIN blind_samples AS p ON p.id = s.blind_sample_id AND p.deleted_at IS NULL").
+		Joins("JOIN blind_tests AS t ON t.id = p.blind_test_id AND t.deleted_at IS NULL").
+		Joins("LEFT JOIN formulas AS f ON f.id = p.formula_id").
+		Where("t.owner_id = ? AND s.deleted_at IS NULL", userID).
+		Where(`LOWER(s.comment) LIKE ? ESCAPE '\'`, pattern).
+		Order("s.created_at desc").
+		Limit(notesSearchLimit + 1).
+		Scan(&comments).Error; err != nil {
+		return err
+	}
+	for _, comment := range comments[:truncate(len(comments))] {
+		// Until the test is revealed a sample is only known by its code.
+		sample := comment.Code
+		if comment.Revealed && comment.Formula != "" {
+			sample = fmt.Sprintf("%s (%s)", comment.Code, comment.Formula)
+		}
+		add(pages.NoteHitComment, fmt.Sprintf("%s · %s · %s", comment.TestName, sample, comment.Evaluator), comment.Comment)
+	}
+
+	var observations []struct {
+		LotNumber      string
+		FormulaName    string
+		IngredientName string
+		Justification  string
+	}
+	if err := database.WithContext(ctx).
+		Table("production_batch_lines AS l").
+		Select("b.lot_number, b.formula_name, l.ingredient_name, l.justification").
+		Joins("JOIN production_batches AS b ON b.id = l.batch_id AND b.deleted_at IS NULL").
+		Where("b.owner_id = ? AND l.deleted_at IS NULL", userID).
+		Where(`LOWER(l.justification) LIKE ? ESCAPE '\'`, pattern).
+		Order("l.updated_at desc").
+		Limit(notesSearchLimit + 1).
+		Scan(&observations).Error; err != nil {
+		return err
+	}
+	for _, observation := range observations[:truncate(len(observations))] {
+		add(pages.NoteHitBatch, fmt.Sprintf("Lot %s · %s · %s", observation.LotNumber, observation.FormulaName, observation.IngredientName), observation.Justification)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+func TestNotesSearchCoversNotesCommentsAndObservations(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(
+		&models.UserIngredientNote{},
+		&models.BlindTest{},
+		&models.BlindSample{},
+		&models.BlindScore{},
+		&models.ProductionBatch{},
+		&models.ProductionBatchLine{},
+	); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	chemical := models.AromaChemical{IngredientName: "Aldehyde C-12", OwnerID: 3}
+	db.Create(&chemical)
+	formula := models.Formula{Name: "Chypre mod 4", Version: 4, Notes: "Drop the aldehydes; mod 3 had a METALLIC off-note in the drydown."}
+	db.Create(&formula)
+	db.Create(&models.UserIngredientNote{UserID: 3, AromaChemicalID: chemical.ID, Perception: "Waxy, slightly metallic at 10%."})
+	db.Create(&models.UserIngredientNote{UserID: 4, AromaChemicalID: chemical.ID, Perception: "Metallic, someone else's note."})
+
+	revealed := time.Now()
+	test := models.BlindTest{OwnerID: 3, Name: "Spring panel", RevealedAt: &revealed}
+	db.Create(&test)
+	sample := models.BlindSample{BlindTestID: test.ID, FormulaID: formula.ID, Code: "K7"}
+	db.Create(&sample)
+	db.Create(&models.BlindScore{BlindSampleID: sample.ID, Evaluator: "Ana", Score: 6, Comment: "Metallic opening, fades fast."})
+
+	batch := models.ProductionBatch{FormulaID: formula.ID, FormulaName: formula.Name, OwnerID: 3, LotNumber: "L-101"}
+	db.Create(&batch)
+	db.Create(&models.ProductionBatchLine{BatchID: batch.ID, IngredientName: "Aldehyde C-12", Justification: "Overpoured; smelled metallic after maceration."})
+
+	req := authenticatedFormRequest(t, sm, "/app/sections/tools/notes-search", url.Values{}, 3)
+	req.Method = http.MethodGet
+	req.URL.RawQuery = url.Values{"q": {"metallic"}}.Encode()
+	rec := httptest.NewRecorder()
+	NotesSearch(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`data-note-kind="formula"`,
+		"Chypre mod 4 · v4",
+		"<mark class=\"rounded bg-sky-400/30 px-0.5 text-white\">METALLIC</mark> off-note",
+		"Waxy, slightly",
+		"Spring panel · K7 (Chypre mod 4) · Ana",
+		"Lot L-101 · Chypre mod 4 · Aldehyde C-12",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in %s", want, body)
+		}
+	}
+	if strings.Contains(body, "someone else") {
+		t.Fatalf("expected another member's notes to stay private: %s", body)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/notify"
+	"perfugo/models"
+)
+
+// notificationKeepalive is how often an idle stream sends a comment line so
+// proxies do not close it.
+const notificationKeepalive = 25 * time.Second
+
+// badgeBatches counts the user's open production batches on the Reports link.
+const badgeBatches = "batches"
+
+var notifier *notify.Hub
+
+// ConfigureNotifications installs the hub behind the push channel. A nil hub
+// disables it; the layout then simply shows no live updates.
+func ConfigureNotifications(hub *notify.Hub) {
+	notifier = hub
+	applog.Debug(nil, "notification hub configured", "enabled", hub != nil)
+}
+
+// NotificationStream pushes the signed-in user's badge counts and toast
+// messages as server-sent events. Every open tab holds one stream; the
+// current badge counts are sent first so a fresh page starts up to date.
+func NotificationStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if notifier == nil {
+		// 204 tells EventSource to stop reconnecting.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	events, unsubscribe := notifier.Subscribe(userID)
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.WriteString(w, "retry: 5000\n\n"); err != nil {
+		return
+	}
+	for _, event := range currentBadges(ctx, userID) {
+		if err := writeServerEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		applog.Error(ctx, "notification stream cannot flush", "error", err)
+		return
+	}
+	applog.Debug(ctx, "notification stream opened", "userID", userID, "streams", notifier.Subscribers(userID))
+
+	keepalive := time.NewTicker(notificationKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeServerEvent(w, event); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeServerEvent(w io.Writer, event notify.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Kind, data)
+	return err
+}
+
+// notifyUser pushes event to the user's open tabs, if any.
+func notifyUser(userID uint, event notify.Event) {
+	if notifier == nil || userID == 0 {
+		return
+	}
+	notifier.Publish(userID, event)
+}
+
+// currentBadges computes every badge count for the user.
+func currentBadges(ctx context.Context, userID uint) []notify.Event {
+	return []notify.Event{notify.Badge(badgeBatches, openBatchCount(ctx, userID))}
+}
+
+// publishBatchBadge refreshes the open batch count in the user's tabs.
+func publishBatchBadge(ctx context.Context, userID uint) {
+	if notifier == nil || notifier.Subscribers(userID) == 0 {
+		return
+	}
+	notifyUser(userID, notify.Badge(badgeBatches, openBatchCount(ctx, userID)))
+}
+
+func openBatchCount(ctx context.Context, userID uint) int {
+	if database == nil {
+		return 0
+	}
+	var count int64
+	if err := database.WithContext(ctx).Model(&models.ProductionBatch{}).
+		Where("owner_id = ? AND status = ?", userID, models.ProductionBatchOpen).
+		Count(&count).Error; err != nil {
+		applog.Error(ctx, "failed to count open production batches", "error", err, "userID", userID)
+		return 0
+	}
+	return int(count)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/internal/notify"
+	"perfugo/models"
+)
+
+func TestNotificationStreamSendsBadgesAndToasts(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.ProductionBatch{}, &models.ProductionBatchLine{}); err != nil {
+		t.Fatalf("automigrate batches: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	for _, status := range []string{models.ProductionBatchOpen, models.ProductionBatchOpen, models.ProductionBatchFinalized} {
+		if err := db.Create(&models.ProductionBatch{OwnerID: 7, Status: status}).Error; err != nil {
+			t.Fatalf("create batch: %v", err)
+		}
+	}
+	if err := db.Create(&models.ProductionBatch{OwnerID: 8, Status: models.ProductionBatchOpen}).Error; err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+
+	hub := notify.NewHub()
+	prevHub := notifier
+	ConfigureNotifications(hub)
+	t.Cleanup(func() { notifier = prevHub })
+
+	req := authenticatedFormRequest(t, sm, "/app/notifications/stream", nil, 7)
+	req.Method = http.MethodGet
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		NotificationStream(rec, req)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Subscribers(7) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stream did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	notifyUser(8, notify.Toast(notify.LevelInfo, "not for you"))
+	notifyUser(7, notify.Toast(notify.LevelSuccess, "Batch finalized."))
+	hub.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not end when the hub closed")
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "event: badge\ndata: {\"kind\":\"badge\",\"badge\":\"batches\",\"count\":2}\n\n") {
+		t.Fatalf("expected initial batch badge, got %q", body)
+	}
+	if !strings.Contains(body, "event: toast\ndata: {\"kind\":\"toast\",\"level\":\"success\",\"message\":\"Batch finalized.\",\"count\":0}\n\n") {
+		t.Fatalf("expected toast, got %q", body)
+	}
+	if strings.Contains(body, "not for you") {
+		t.Fatalf("stream leaked another user's event: %q", body)
+	}
+}
+
+func TestNotificationStreamDisabledWithoutHub(t *testing.T) {
+	prevHub := notifier
+	notifier = nil
+	t.Cleanup(func() { notifier = prevHub })
+
+	rec := httptest.NewRecorder()
+	NotificationStream(rec, httptest.NewRequest(http.MethodGet, "/app/notifications/stream", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 without a hub, got %d", rec.Code)
+	}
+}
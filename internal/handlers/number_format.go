@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// sessionNumberFormatKey caches the user's number format in the session as
+// "amount/percent/price" so it is not read from the database per request.
+const sessionNumberFormatKey = "prefs:number_format"
+
+// NumberPreferences saves how many decimals amounts, percentages and unit
+// prices are shown with.
+func NumberPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if sessionManager == nil || database == nil {
+		http.Error(w, "preferences not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	current := loadNumberFormat(r)
+	format := pages.NumberFormat{}
+	for _, field := range []struct {
+		name  string
+		label string
+		into  *int
+	}{
+		{"amount_decimals", "Amounts", &format.Amount},
+		{"percent_decimals", "Percentages", &format.Percent},
+		{"price_decimals", "Prices", &format.Price},
+	} {
+		value, err := strconv.Atoi(strings.TrimSpace(r.FormValue(field.name)))
+		if err != nil || value < 0 || value > pages.MaxDecimals {
+			renderNumberFormat(w, r, http.StatusBadRequest, current, fmt.Sprintf("%s must use 0 to %d decimals.", field.label, pages.MaxDecimals))
+			return
+		}
+		*field.into = value
+	}
+
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"amount_decimals":  format.Amount,
+		"percent_decimals": format.Percent,
+		"price_decimals":   format.Price,
+	}).Error; err != nil {
+		applog.Error(ctx, "failed to update number preferences", "error", err, "userID", userID)
+		http.Error(w, "unable to save preferences", http.StatusInternalServerError)
+		return
+	}
+	sessionManager.Put(ctx, sessionNumberFormatKey, encodeNumberFormat(format))
+	applog.Debug(ctx, "number preferences persisted", "userID", userID, "format", format)
+
+	if !isHTMX(r) {
+		http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+		return
+	}
+	renderNumberFormat(w, r, http.StatusOK, format, "Number format saved. Reload to apply it everywhere.")
+}
+
+func renderNumberFormat(w http.ResponseWriter, r *http.Request, status int, format pages.NumberFormat, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.NumberFormatControl(format, message).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render number format control", "error", err)
+	}
+}
+
+// withNumberFormat attaches the signed-in user's number format to the
+// request context for templates; see pages.NumberFormatFrom.
+func withNumberFormat(r *http.Request) *http.Request {
+	return r.WithContext(pages.WithNumberFormat(r.Context(), loadNumberFormat(r)))
+}
+
+// loadNumberFormat reads the user's number format from the session, falling
+// back to the database once per session and to the default without a user.
+func loadNumberFormat(r *http.Request) pages.NumberFormat {
+	format := pages.DefaultNumberFormat()
+	if sessionManager == nil {
+		return format
+	}
+	ctx := r.Context()
+	if cached, ok := decodeNumberFormat(sessionManager.GetString(ctx, sessionNumberFormatKey)); ok {
+		return cached
+	}
+	if database == nil {
+		return format
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		return format
+	}
+
+	var user models.User
+	if err := database.WithContext(ctx).
+		Select("amount_decimals", "percent_decimals", "price_decimals").
+		First(&user, userID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			applog.Error(ctx, "failed to load number preferences", "error", err, "userID", userID)
+		}
+		return format
+	}
+	format = pages.NumberFormat{Amount: user.AmountDecimals, Percent: user.PercentDecimals, Price: user.PriceDecimals}.Normalize()
+	sessionManager.Put(ctx, sessionNumberFormatKey, encodeNumberFormat(format))
+	return format
+}
+
+func encodeNumberFormat(format pages.NumberFormat) string {
+	return fmt.Sprintf("%d/%d/%d", format.Amount, format.Percent, format.Price)
+}
+
+func decodeNumberFormat(value string) (pages.NumberFormat, bool) {
+	var format pages.NumberFormat
+	if _, err := fmt.Sscanf(value, "%d/%d/%d", &format.Amount, &format.Percent, &format.Price); err != nil {
+		return format, false
+	}
+	return format.Normalize(), true
+}
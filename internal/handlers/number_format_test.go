@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+func TestNumberPreferences(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "perfumer@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	save := func(amount string) (*httptest.ResponseRecorder, *http.Request) {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/app/preferences/numbers", url.Values{
+			"amount_decimals":  {amount},
+			"percent_decimals": {"1"},
+			"price_decimals":   {"3"},
+		}, int(user.ID))
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+		NumberPreferences(rec, req)
+		return rec, req
+	}
+
+	if rec, _ := save("7"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Amounts must use 0 to 6 decimals.") {
+		t.Fatalf("out of range: status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	rec, req := save("3")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Number format saved.") {
+		t.Fatalf("save: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	want := pages.NumberFormat{Amount: 3, Percent: 1, Price: 3}
+	if got := loadNumberFormat(req); got != want {
+		t.Fatalf("session format = %+v, want %+v", got, want)
+	}
+
+	// A new session reads the saved format from the database.
+	fresh := authenticatedFormRequest(t, sm, "/app", url.Values{}, int(user.ID))
+	if got := pages.NumberFormatFrom(withNumberFormat(fresh).Context()); got != want {
+		t.Fatalf("stored format = %+v, want %+v", got, want)
+	}
+}
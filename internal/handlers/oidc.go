@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/oidc"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	oidcLoginPath    = "/auth/oidc/login"
+	oidcCallbackPath = "/auth/oidc/callback"
+
+	sessionOIDCStateKey    = "auth:oidc:state"
+	sessionOIDCNonceKey    = "auth:oidc:nonce"
+	sessionOIDCVerifierKey = "auth:oidc:verifier"
+	sessionOIDCIDTokenKey  = "auth:oidc:id_token"
+)
+
+var oidcProvider *oidc.Provider
+
+// errOIDCEmailTaken rejects a first SSO login whose email belongs to an
+// account the identity may not be linked to.
+var errOIDCEmailTaken = errors.New("oidc: email belongs to an unlinked account")
+
+// ConfigureOIDC installs the discovered OpenID provider. A nil provider disables SSO.
+func ConfigureOIDC(provider *oidc.Provider) {
+	oidcProvider = provider
+	applog.Debug(nil, "oidc provider configured", "enabled", provider != nil, "logout", provider.SupportsLogout())
+}
+
+// OIDCEnabled reports whether single sign-on is available.
+func OIDCEnabled() bool {
+	return oidcProvider != nil
+}
+
+// OIDCLogin starts the authorization code flow with the configured provider.
+func OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if oidcProvider == nil || sessionManager == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, errState := oidc.RandomString()
+	nonce, errNonce := oidc.RandomString()
+	verifier, errVerifier := oidc.RandomString()
+	if err := errors.Join(errState, errNonce, errVerifier); err != nil {
+		applog.Error(r.Context(), "failed to prepare oidc login", "error", err)
+		http.Error(w, "single sign-on is temporarily unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	sessionManager.Put(r.Context(), sessionOIDCStateKey, state)
+	sessionManager.Put(r.Context(), sessionOIDCNonceKey, nonce)
+	sessionManager.Put(r.Context(), sessionOIDCVerifierKey, verifier)
+
+	applog.Debug(r.Context(), "redirecting to oidc provider")
+	http.Redirect(w, r, oidcProvider.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+// OIDCCallback completes the authorization code flow and signs the user in.
+func OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if oidcProvider == nil || sessionManager == nil || database == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	query := r.URL.Query()
+	state := sessionManager.PopString(ctx, sessionOIDCStateKey)
+	nonce := sessionManager.PopString(ctx, sessionOIDCNonceKey)
+	verifier := sessionManager.PopString(ctx, sessionOIDCVerifierKey)
+
+	if providerErr := query.Get("error"); providerErr != "" {
+		applog.Debug(ctx, "oidc provider returned an error", "error", providerErr, "description", query.Get("error_description"))
+		failOIDCLogin(w, r, "Single sign-on was cancelled or denied.")
+		return
+	}
+	if state == "" || query.Get("state") != state {
+		applog.Debug(ctx, "oidc callback state mismatch")
+		failOIDCLogin(w, r, "Your sign-in attempt expired. Please try again.")
+		return
+	}
+
+	tokens, err := oidcProvider.Exchange(ctx, query.Get("code"), verifier)
+	if err != nil {
+		applog.Error(ctx, "oidc code exchange failed", "error", err)
+		failOIDCLogin(w, r, "We were unable to sign you in. Please try again.")
+		return
+	}
+	claims, err := oidcProvider.VerifyIDToken(tokens.IDToken, nonce)
+	if err != nil {
+		applog.Error(ctx, "oidc id token rejected", "error", err)
+		failOIDCLogin(w, r, "We were unable to sign you in. Please try again.")
+		return
+	}
+
+	user, err := findOrCreateOIDCUser(r, claims)
+	if errors.Is(err, errOIDCEmailTaken) {
+		applog.Info(ctx, "oidc login rejected: email belongs to an unlinked account", "subject", claims.Subject, "emailVerified", claims.EmailVerified)
+		failOIDCLogin(w, r, "An account with this email already exists. Sign in with your password to continue.")
+		return
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to resolve oidc user", "error", err, "subject", claims.Subject)
+		failOIDCLogin(w, r, "We were unable to sign you in. Please try again.")
+		return
+	}
+
+	if !user.IsActive() {
+		applog.Debug(ctx, "oidc login rejected: account deactivated", "userID", user.ID)
+		failOIDCLogin(w, r, accountDeactivatedMessage)
+		return
+	}
+
+	if err := syncOIDCRole(r, user, claims); err != nil {
+		applog.Error(ctx, "failed to refresh role from oidc claims", "error", err, "userID", user.ID)
+		failOIDCLogin(w, r, "We were unable to sign you in. Please try again.")
+		return
+	}
+
+	if err := establishSession(r, user); err != nil {
+		applog.Error(ctx, "failed to establish oidc session", "error", err)
+		failOIDCLogin(w, r, "We were unable to sign you in. Please try again.")
+		return
+	}
+	sessionManager.Put(ctx, sessionOIDCIDTokenKey, tokens.IDToken)
+
+	applog.Debug(ctx, "oidc login complete", "userID", user.ID)
+	http.Redirect(w, r, pages.Path("/app"), http.StatusSeeOther)
+}
+
+func failOIDCLogin(w http.ResponseWriter, r *http.Request, message string) {
+	sessionManager.Put(r.Context(), sessionLoginMessageKey, message)
+	http.Redirect(w, r, pages.Path("/login"), http.StatusSeeOther)
+}
+
+// findOrCreateOIDCUser finds the account linked to the identity's subject,
+// creating a member account on first login. An existing account with the same
+// email is only linked when linking by email is enabled, the provider has
+// verified the address and the account is not an admin; otherwise the login
+// fails with errOIDCEmailTaken.
+func findOrCreateOIDCUser(r *http.Request, claims oidc.Claims) (*models.User, error) {
+	ctx := r.Context()
+	user := &models.User{}
+	err := database.WithContext(ctx).Where("oidc_subject = ?", claims.Subject).First(user).Error
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" {
+		return nil, errors.New("oidc: identity has no email claim")
+	}
+
+	existing, err := findUserByEmail(r, email)
+	switch {
+	case err == nil:
+		if !oidcProvider.LinksByEmail() || !claims.EmailVerified || existing.IsAdmin() {
+			return nil, errOIDCEmailTaken
+		}
+		if err := database.WithContext(ctx).Model(existing).Update("oidc_subject", claims.Subject).Error; err != nil {
+			return nil, err
+		}
+		applog.Debug(ctx, "linked oidc identity to existing user", "userID", existing.ID)
+		return existing, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	hashed, err := unusablePasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	user = &models.User{
+		Email:        email,
+		Name:         strings.TrimSpace(claims.Name),
+		PasswordHash: hashed,
+		Theme:        models.DefaultTheme,
+		Role:         models.RoleMember,
+		OIDCSubject:  claims.Subject,
+	}
+	if err := database.WithContext(ctx).Create(user).Error; err != nil {
+		return nil, err
+	}
+	applog.Debug(ctx, "created user from oidc identity", "userID", user.ID)
+	seedNewAccount(ctx, user)
+	return user, nil
+}
+
+// syncOIDCRole assigns the role derived from the provider's group claim. Users
+// keep their stored role when no mapping is configured or the claim is absent.
+func syncOIDCRole(r *http.Request, user *models.User, claims oidc.Claims) error {
+	mapped, ok := oidcProvider.MappedRoles(claims)
+	if !ok {
+		return nil
+	}
+
+	role := models.RoleMember
+	for _, candidate := range mapped {
+		if models.NormalizeRole(candidate) == models.RoleAdmin {
+			role = models.RoleAdmin
+			break
+		}
+	}
+	if models.NormalizeRole(user.Role) == role {
+		return nil
+	}
+
+	if err := database.WithContext(r.Context()).Model(user).Update("role", role).Error; err != nil {
+		return err
+	}
+	user.Role = role
+	applog.Info(r.Context(), "user role refreshed from oidc claims", "userID", user.ID, "role", role)
+	return nil
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/internal/oidc"
+	"perfugo/models"
+)
+
+func withTestOIDCProvider(t *testing.T, claims map[string]any, roleMapping map[string]string, configure ...func(*oidc.Config)) *oidc.Provider {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"issuer":                 srv.URL,
+				"authorization_endpoint": srv.URL + "/authorize",
+				"token_endpoint":         srv.URL + "/token",
+				"end_session_endpoint":   srv.URL + "/logout",
+			})
+		case "/token":
+			payload := map[string]any{"iss": srv.URL, "aud": "perfugo", "exp": time.Now().Add(time.Hour).Unix()}
+			for k, v := range claims {
+				payload[k] = v
+			}
+			raw, _ := json.Marshal(payload)
+			idToken := "e30." + base64.RawURLEncoding.EncodeToString(raw) + ".sig"
+			_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := oidc.Config{
+		IssuerURL:             srv.URL,
+		ClientID:              "perfugo",
+		RedirectURL:           "https://app.example/auth/oidc/callback",
+		PostLogoutRedirectURL: "https://app.example/login",
+		RolesClaim:            "groups",
+		RoleMapping:           roleMapping,
+	}
+	for _, fn := range configure {
+		fn(&cfg)
+	}
+	provider, err := oidc.Discover(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+
+	original := oidcProvider
+	oidcProvider = provider
+	t.Cleanup(func() { oidcProvider = original })
+	return provider
+}
+
+func TestLogoutRedirectsThroughEndSessionEndpoint(t *testing.T) {
+	provider := withTestOIDCProvider(t, nil, nil)
+	sm, cleanup := withTestSessionManager(t)
+	t.Cleanup(cleanup)
+
+	tests := []struct {
+		name     string
+		idToken  string
+		htmx     bool
+		wantPath string
+	}{
+		{name: "oidc session", idToken: "raw-id-token", wantPath: provider.EndSessionEndpoint},
+		{name: "oidc session via htmx", idToken: "raw-id-token", htmx: true, wantPath: provider.EndSessionEndpoint},
+		{name: "password session", wantPath: "/login"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+		if tt.htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		ctx, err := sm.Load(req.Context(), "")
+		if err != nil {
+			t.Fatalf("load session: %v", err)
+		}
+		req = req.WithContext(ctx)
+		if tt.idToken != "" {
+			sm.Put(ctx, sessionOIDCIDTokenKey, tt.idToken)
+		}
+
+		rec := httptest.NewRecorder()
+		Logout(rec, req)
+
+		location := rec.Header().Get("Location")
+		if tt.htmx {
+			location = rec.Header().Get("HX-Redirect")
+		}
+		if !strings.HasPrefix(location, tt.wantPath) {
+			t.Fatalf("%s: expected redirect to %s, got %q", tt.name, tt.wantPath, location)
+		}
+		if tt.idToken != "" && !strings.Contains(location, "id_token_hint="+tt.idToken) {
+			t.Fatalf("%s: expected id_token_hint in %q", tt.name, location)
+		}
+	}
+}
+
+func TestOIDCCallbackCreatesUserAndSession(t *testing.T) {
+	db, cleanupDB := withTestDatabase(t)
+	t.Cleanup(cleanupDB)
+	sm, cleanup := withTestSessionManager(t)
+	t.Cleanup(cleanup)
+
+	withTestOIDCProvider(t, map[string]any{"sub": "sso-1", "nonce": "nonce", "email": "SSO@Example.com", "name": "Sso User"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=abc&state=state", nil)
+	ctx, err := sm.Load(req.Context(), "")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	req = req.WithContext(ctx)
+	sm.Put(ctx, sessionOIDCStateKey, "state")
+	sm.Put(ctx, sessionOIDCNonceKey, "nonce")
+	sm.Put(ctx, sessionOIDCVerifierKey, "verifier")
+
+	rec := httptest.NewRecorder()
+	OIDCCallback(rec, req)
+
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/app" {
+		t.Fatalf("expected redirect to /app, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if !ActiveSession(req) {
+		t.Fatal("expected an authenticated session")
+	}
+	if sm.GetString(ctx, sessionOIDCIDTokenKey) == "" {
+		t.Fatal("expected id token to be kept for logout")
+	}
+
+	var user models.User
+	if err := db.Where("oidc_subject = ?", "sso-1").First(&user).Error; err != nil {
+		t.Fatalf("expected user to be created: %v", err)
+	}
+	if user.Email != "sso@example.com" || user.Role != models.RoleMember {
+		t.Fatalf("unexpected user %+v", user)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	_, cleanupDB := withTestDatabase(t)
+	t.Cleanup(cleanupDB)
+	sm, cleanup := withTestSessionManager(t)
+	t.Cleanup(cleanup)
+	withTestOIDCProvider(t, map[string]any{"sub": "sso-2", "nonce": "nonce", "email": "x@example.com"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+url.Values{"code": {"abc"}, "state": {"forged"}}.Encode(), nil)
+	ctx, err := sm.Load(req.Context(), "")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	req = req.WithContext(ctx)
+	sm.Put(ctx, sessionOIDCStateKey, "state")
+
+	rec := httptest.NewRecorder()
+	OIDCCallback(rec, req)
+
+	if rec.Header().Get("Location") != "/login" {
+		t.Fatalf("expected redirect to login, got %q", rec.Header().Get("Location"))
+	}
+	if ActiveSession(req) {
+		t.Fatal("expected no session after a forged state")
+	}
+}
+
+func TestFindOrCreateOIDCUserLinksOnlyVerifiedMemberEmails(t *testing.T) {
+	db, cleanupDB := withTestDatabase(t)
+	t.Cleanup(cleanupDB)
+
+	tests := []struct {
+		name     string
+		link     bool
+		verified bool
+		role     string
+		linked   bool
+	}{
+		{name: "linking disabled", link: false, verified: true, role: models.RoleMember},
+		{name: "unverified email", link: true, verified: false, role: models.RoleMember},
+		{name: "admin account", link: true, verified: true, role: models.RoleAdmin},
+		{name: "verified member", link: true, verified: true, role: models.RoleMember, linked: true},
+	}
+
+	for i, tt := range tests {
+		withTestOIDCProvider(t, nil, nil, func(cfg *oidc.Config) { cfg.LinkByEmail = tt.link })
+		user := models.User{Email: fmt.Sprintf("linked-%d@example.com", i), PasswordHash: "x", Role: tt.role}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("%s: create user: %v", tt.name, err)
+		}
+		claims := oidc.Claims{Subject: fmt.Sprintf("sso-link-%d", i), Email: user.Email, EmailVerified: tt.verified}
+
+		got, err := findOrCreateOIDCUser(httptest.NewRequest(http.MethodGet, "/auth/oidc/callback", nil), claims)
+		if !tt.linked {
+			if !errors.Is(err, errOIDCEmailTaken) {
+				t.Fatalf("%s: expected errOIDCEmailTaken, got %v", tt.name, err)
+			}
+			var stored models.User
+			db.First(&stored, user.ID)
+			if stored.OIDCSubject != "" {
+				t.Fatalf("%s: account was linked to %q", tt.name, stored.OIDCSubject)
+			}
+			continue
+		}
+		if err != nil || got.ID != user.ID || got.OIDCSubject != claims.Subject {
+			t.Fatalf("%s: expected account %d linked, got %+v, %v", tt.name, user.ID, got, err)
+		}
+	}
+}
+
+func TestSyncOIDCRole(t *testing.T) {
+	db, cleanupDB := withTestDatabase(t)
+	t.Cleanup(cleanupDB)
+	withTestOIDCProvider(t, nil, map[string]string{"perfugo-admins": models.RoleAdmin})
+
+	tests := []struct {
+		name   string
+		start  string
+		claims map[string]any
+		want   string
+	}{
+		{name: "promotes mapped admins", start: models.RoleMember, claims: map[string]any{"groups": []any{"perfugo-admins"}}, want: models.RoleAdmin},
+		{name: "demotes when group removed", start: models.RoleAdmin, claims: map[string]any{"groups": []any{"other"}}, want: models.RoleMember},
+		{name: "keeps role without claim", start: models.RoleAdmin, claims: map[string]any{}, want: models.RoleAdmin},
+	}
+
+	for i, tt := range tests {
+		user := models.User{Email: fmt.Sprintf("role-sync-%d@example.com", i), PasswordHash: "x", Role: tt.start}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("%s: create user: %v", tt.name, err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback", nil)
+		if err := syncOIDCRole(req, &user, oidc.Claims{Raw: tt.claims}); err != nil {
+			t.Fatalf("%s: sync: %v", tt.name, err)
+		}
+		var stored models.User
+		if err := db.First(&stored, user.ID).Error; err != nil {
+			t.Fatalf("%s: reload: %v", tt.name, err)
+		}
+		if stored.Role != tt.want {
+			t.Fatalf("%s: expected role %q, got %q", tt.name, tt.want, stored.Role)
+		}
+	}
+}
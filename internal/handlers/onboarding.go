@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/onboarding"
+	"perfugo/models"
+)
+
+var onboardingTemplate *onboarding.Template
+
+// ConfigureOnboarding sets the starter library copied into new accounts. A nil
+// template disables seeding.
+func ConfigureOnboarding(tmpl *onboarding.Template) {
+	onboardingTemplate = tmpl
+	applog.Debug(nil, "onboarding seeding configured", "enabled", tmpl != nil)
+}
+
+// seedNewAccount populates a freshly registered user's library. Failures are
+// logged and never block registration.
+func seedNewAccount(ctx context.Context, user *models.User) {
+	if onboardingTemplate == nil || database == nil || user == nil {
+		return
+	}
+	result, err := onboarding.Seed(ctx, database, user.ID, *onboardingTemplate)
+	if err != nil {
+		applog.Error(ctx, "failed to seed new account", "userID", user.ID, "error", err)
+		return
+	}
+	applog.Debug(ctx, "new account seeded", "userID", user.ID, "chemicals", result.Chemicals, "formula", result.Formula)
+}
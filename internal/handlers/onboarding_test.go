@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"perfugo/internal/onboarding"
+	"perfugo/models"
+)
+
+func TestSignupSeedsNewAccount(t *testing.T) {
+	_, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+	db, dbCleanup := withTestDatabase(t)
+	t.Cleanup(dbCleanup)
+	if err := db.AutoMigrate(&models.AromaChemical{}, &models.OtherName{}, &models.Formula{}, &models.FormulaIngredient{}); err != nil {
+		t.Fatalf("failed to migrate library schema: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Migrator().DropTable(&models.AromaChemical{}, &models.OtherName{}, &models.Formula{}, &models.FormulaIngredient{})
+	})
+
+	tmpl := onboarding.Template{Chemicals: []onboarding.Chemical{{Name: "Vanillin"}, {Name: "Coumarin"}}}
+	previous := onboardingTemplate
+	ConfigureOnboarding(&tmpl)
+	t.Cleanup(func() { onboardingTemplate = previous })
+
+	if w := postSignup(t, signupForm("seeded@example.com", "")); w.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect after signup, got %d", w.Code)
+	}
+
+	var user models.User
+	if err := database.Where("email = ?", "seeded@example.com").First(&user).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	var owned int64
+	database.Model(&models.AromaChemical{}).Where("owner_id = ? AND public = ?", user.ID, false).Count(&owned)
+	if owned != 2 {
+		t.Fatalf("expected 2 seeded private chemicals, got %d", owned)
+	}
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// OrganPanel renders the organ layout card of the tools section.
+func OrganPanel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	renderOrganPanel(w, r, "")
+}
+
+// OrganResize sets the number of rows and positions of the user's organ,
+// creating it on first use. Shrinking is refused while it would drop
+// materials off the organ.
+func OrganResize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	rows, rowsErr := strconv.Atoi(strings.TrimSpace(r.FormValue("rows")))
+	positions, posErr := strconv.Atoi(strings.TrimSpace(r.FormValue("positions")))
+	if rowsErr != nil || posErr != nil || rows < 1 || rows > models.MaxOrganRows || positions < 1 || positions > models.MaxOrganPositions {
+		renderOrganPanel(w, r, fmt.Sprintf("An organ has 1 to %d rows of 1 to %d positions.", models.MaxOrganRows, models.MaxOrganPositions))
+		return
+	}
+	if database == nil {
+		renderOrganPanel(w, r, pages.DemoModeMessage)
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := currentUserID(r)
+	organ, err := loadOrgan(ctx, userID)
+	if err != nil {
+		applog.Error(ctx, "failed to load organ", "error", err)
+		renderOrganPanel(w, r, "We couldn't resize the organ. Please try again.")
+		return
+	}
+	if organ == nil {
+		organ = &models.Organ{OwnerID: userID}
+	}
+	for _, slot := range organ.Slots {
+		if slot.Row > rows || slot.Position > positions {
+			renderOrganPanel(w, r, fmt.Sprintf("Slot %s is in use. Move or clear materials outside the new size first.", models.OrganSlotLabel(slot.Row, slot.Position)))
+			return
+		}
+	}
+	organ.Rows, organ.Positions = rows, positions
+	if err := database.WithContext(ctx).Omit("Slots").Save(organ).Error; err != nil {
+		applog.Error(ctx, "failed to save organ", "error", err)
+		renderOrganPanel(w, r, "We couldn't resize the organ. Please try again.")
+		return
+	}
+	applog.Info(ctx, "organ resized", "organID", organ.ID, "rows", rows, "positions", positions)
+
+	renderOrganPanel(w, r, fmt.Sprintf("The organ has %d rows of %d positions.", rows, positions))
+}
+
+// OrganAssign places a material in a slot, moving it from any slot it held
+// before and displacing whatever was there. Without a material the slot is
+// cleared.
+func OrganAssign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	if database == nil {
+		renderOrganPanel(w, r, pages.DemoModeMessage)
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := currentUserID(r)
+	organ, err := loadOrgan(ctx, userID)
+	if err != nil || organ == nil {
+		if err != nil {
+			applog.Error(ctx, "failed to load organ", "error", err)
+		}
+		renderOrganPanel(w, r, "Set up the organ before placing materials.")
+		return
+	}
+	row, position, ok := models.ParseOrganSlotLabel(r.FormValue("slot"))
+	if !ok || row > organ.Rows || position > organ.Positions {
+		renderOrganPanel(w, r, fmt.Sprintf("Choose a slot from A1 to %s.", models.OrganSlotLabel(organ.Rows, organ.Positions)))
+		return
+	}
+	label := models.OrganSlotLabel(row, position)
+
+	var chemical *models.AromaChemical
+	if id := pages.ParseUint(r.FormValue("aroma_chemical_id")); id != 0 {
+		chemical = pages.FindAromaChemical(buildWorkspaceSnapshot(r).AromaChemicals, id)
+		if chemical == nil {
+			renderOrganPanel(w, r, "That material is not in your library.")
+			return
+		}
+	}
+
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Slots are removed outright so the unique indexes do not trip over
+		// soft-deleted rows.
+		clear := tx.Unscoped().Where("organ_id = ? AND row_number = ? AND position = ?", organ.ID, row, position)
+		if chemical != nil {
+			clear = tx.Unscoped().Where("organ_id = ? AND ((row_number = ? AND position = ?) OR aroma_chemical_id = ?)", organ.ID, row, position, chemical.ID)
+		}
+		if err := clear.Delete(&models.OrganSlot{}).Error; err != nil {
+			return err
+		}
+		if chemical == nil {
+			return nil
+		}
+		slot := models.OrganSlot{OrganID: organ.ID, Row: row, Position: position, AromaChemicalID: chemical.ID}
+		return tx.Create(&slot).Error
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to assign organ slot", "error", err, "slot", label)
+		renderOrganPanel(w, r, "We couldn't update the organ. Please try again.")
+		return
+	}
+	applog.Info(ctx, "organ slot assigned", "organID", organ.ID, "slot", label, "cleared", chemical == nil)
+
+	if chemical == nil {
+		renderOrganPanel(w, r, fmt.Sprintf("%s is free.", label))
+		return
+	}
+	renderOrganPanel(w, r, fmt.Sprintf("%s placed at %s.", chemical.IngredientName, label))
+}
+
+// OrganPrint renders a standalone, print-optimised map of the organ.
+func OrganPrint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	layout, err := buildOrganLayout(r)
+	if err != nil {
+		applog.Error(r.Context(), "failed to load organ", "error", err)
+		http.Error(w, "We couldn't load the organ.", http.StatusInternalServerError)
+		return
+	}
+	if layout.Organ == nil {
+		http.Error(w, "Set up the organ before printing its map.", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.OrganPrint(layout, nowFunc()).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render organ map", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func renderOrganPanel(w http.ResponseWriter, r *http.Request, message string) {
+	layout, err := buildOrganLayout(r)
+	if err != nil {
+		applog.Error(r.Context(), "failed to load organ", "error", err)
+		message = "We couldn't load the organ. Please try again."
+	}
+	layout.Message = message
+	renderComponent(w, r, pages.OrganPanel(layout))
+}
+
+func buildOrganLayout(r *http.Request) (pages.OrganLayout, error) {
+	snapshot := buildWorkspaceSnapshot(r)
+	userID, _ := currentUserID(r)
+	organ, err := loadOrgan(r.Context(), userID)
+	return pages.BuildOrganLayout(organ, snapshot.AromaChemicals), err
+}
+
+// loadOrgan returns the user's organ with its slots, or nil when the user has
+// not set one up.
+func loadOrgan(ctx context.Context, userID uint) (*models.Organ, error) {
+	if database == nil {
+		return nil, nil
+	}
+	var organ models.Organ
+	err := database.WithContext(ctx).Preload("Slots").Where("owner_id = ?", userID).First(&organ).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &organ, nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestOrganAssignMovesAndClears(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}, &models.Organ{}, &models.OrganSlot{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	musk := models.AromaChemical{IngredientName: "Galaxolide", OwnerID: 6}
+	citrus := models.AromaChemical{IngredientName: "Citral", OwnerID: 6}
+	for _, chemical := range []*models.AromaChemical{&musk, &citrus} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+
+	send := func(path string, form url.Values) string {
+		rec := httptest.NewRecorder()
+		req := authenticatedFormRequest(t, sm, path, form, 6)
+		switch path {
+		case "/app/sections/tools/organ/resize":
+			OrganResize(rec, req)
+		default:
+			OrganAssign(rec, req)
+		}
+		return rec.Body.String()
+	}
+
+	if body := send("/app/sections/tools/organ/assign", url.Values{"slot": {"A1"}, "aroma_chemical_id": {strconv.Itoa(int(musk.ID))}}); !strings.Contains(body, "Set up the organ") {
+		t.Fatalf("expected a prompt to set up the organ: %q", body)
+	}
+	if body := send("/app/sections/tools/organ/resize", url.Values{"rows": {"2"}, "positions": {"3"}}); !strings.Contains(body, "Free slots: 6") {
+		t.Fatalf("unexpected resize response: %q", body)
+	}
+	send("/app/sections/tools/organ/assign", url.Values{"slot": {"b3"}, "aroma_chemical_id": {strconv.Itoa(int(musk.ID))}})
+	if body := send("/app/sections/tools/organ/resize", url.Values{"rows": {"2"}, "positions": {"2"}}); !strings.Contains(body, "Slot B3 is in use") {
+		t.Fatalf("shrinking should be refused: %q", body)
+	}
+	if body := send("/app/sections/tools/organ/assign", url.Values{"slot": {"C1"}, "aroma_chemical_id": {strconv.Itoa(int(citrus.ID))}}); !strings.Contains(body, "Choose a slot from A1 to B3") {
+		t.Fatalf("slot outside the organ should be refused: %q", body)
+	}
+
+	body := send("/app/sections/tools/organ/assign", url.Values{"slot": {"A2"}, "aroma_chemical_id": {strconv.Itoa(int(musk.ID))}})
+	if !strings.Contains(body, "Galaxolide placed at A2") || !strings.Contains(body, "Free slots: 5 · Without a slot: 1") {
+		t.Fatalf("unexpected move response: %q", body)
+	}
+	var slots []models.OrganSlot
+	db.Find(&slots)
+	if len(slots) != 1 || slots[0].Row != 1 || slots[0].Position != 2 {
+		t.Fatalf("expected the material to move to A2: %+v", slots)
+	}
+
+	send("/app/sections/tools/organ/assign", url.Values{"slot": {"A2"}, "aroma_chemical_id": {strconv.Itoa(int(citrus.ID))}})
+	send("/app/sections/tools/organ/assign", url.Values{"slot": {"A2"}})
+	var remaining int64
+	db.Unscoped().Model(&models.OrganSlot{}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected the slot to be cleared, %d rows remain", remaining)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// transferToOrganization is the "to" value that hands a record to the
+// organization instead of a member.
+const transferToOrganization = "organization"
+
+// canManageChemical reports whether the signed-in user may edit, delete or
+// transfer a chemical: its owner may, and administrators may manage the
+// chemicals owned by the organization.
+func canManageChemical(r *http.Request, ownerID, userID uint) bool {
+	if ownerID == models.OrganizationOwnerID {
+		return currentUserIsAdmin(r)
+	}
+	return ownerID == userID
+}
+
+// canTransferFormula reports whether the signed-in user may transfer a
+// formula: its creator may, and administrators may transfer any formula.
+func canTransferFormula(r *http.Request, createdByID *uint, userID uint) bool {
+	if currentUserIsAdmin(r) {
+		return true
+	}
+	return createdByID != nil && *createdByID == userID
+}
+
+// transferRecipient resolves the "to" field of a transfer form: a member's
+// email, or transferToOrganization. It returns nil for the organization and
+// a message for the form when the recipient cannot receive records.
+func transferRecipient(r *http.Request) (*models.User, string, error) {
+	to := strings.TrimSpace(r.FormValue("to"))
+	switch {
+	case to == "":
+		return nil, "Give the new owner's email, or transfer to the organization.", nil
+	case strings.EqualFold(to, transferToOrganization):
+		return nil, "", nil
+	}
+	user, err := findUserByEmail(r, to)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, "No account uses that email.", nil
+	case err != nil:
+		return nil, "", err
+	case !user.IsActive():
+		return nil, "That account is deactivated.", nil
+	}
+	return user, "", nil
+}
+
+func sameOwner(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ownerLabel names the owner with id for the audit log; a nil or
+// organization id is the organization.
+func ownerLabel(ctx context.Context, id *uint) string {
+	if id == nil || *id == models.OrganizationOwnerID {
+		return "the organization"
+	}
+	var user models.User
+	if err := database.WithContext(ctx).Select("id", "email").First(&user, *id).Error; err != nil {
+		return fmt.Sprintf("user %d", *id)
+	}
+	return user.Email
+}
+
+// IngredientTransfer hands an aroma chemical, with its aliases, to another
+// member or to the organization. Chemicals given to the organization become
+// public so everyone keeps them.
+func IngredientTransfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	id := pages.ParseUint(r.FormValue("id"))
+	if id == 0 {
+		http.Error(w, "missing ingredient", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var chemical models.AromaChemical
+	if err := database.WithContext(ctx).First(&chemical, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			renderComponent(w, r, pages.TransferControl("No ingredient has that id."))
+			return
+		}
+		applog.Error(ctx, "failed to load ingredient for transfer", "error", err, "ingredientID", id)
+		http.Error(w, "unable to load ingredient", http.StatusInternalServerError)
+		return
+	}
+	if !canManageChemical(r, chemical.OwnerID, userID) {
+		http.Error(w, "only the owner or an administrator can transfer this ingredient", http.StatusForbidden)
+		return
+	}
+	recipient, message, err := transferRecipient(r)
+	if err != nil {
+		http.Error(w, "unable to load user", http.StatusInternalServerError)
+		return
+	}
+	if message != "" {
+		renderComponent(w, r, pages.TransferControl(message))
+		return
+	}
+	ownerID, public := models.OrganizationOwnerID, true
+	if recipient != nil {
+		ownerID, public = recipient.ID, chemical.Public
+	}
+	if ownerID == chemical.OwnerID {
+		renderComponent(w, r, pages.TransferControl(chemical.IngredientName+" already belongs to "+ownerLabel(ctx, &ownerID)+"."))
+		return
+	}
+
+	summary := fmt.Sprintf("Transferred %s from %s to %s.", chemical.IngredientName, ownerLabel(ctx, &chemical.OwnerID), ownerLabel(ctx, &ownerID))
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&chemical).Updates(map[string]any{"owner_id": ownerID, "public": public}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, r, models.AuditChemicalTransferred, models.ActivityEntityAromaChemical, chemical.ID, summary)
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to transfer ingredient", "error", err, "ingredientID", id)
+		http.Error(w, "unable to transfer ingredient", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "ingredient transferred", "ingredientID", id, "ownerID", ownerID, "userID", userID)
+	renderComponent(w, r, pages.TransferControl(summary))
+}
+
+// FormulaTransfer hands a formula, with its ingredient rows, to another
+// member or to the organization. The previous owner's private chemicals
+// used by the formula go with it, so the new owner can still open every
+// row.
+func FormulaTransfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	id := pages.ParseUint(r.FormValue("id"))
+	if id == 0 {
+		http.Error(w, "missing formula", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var formula models.Formula
+	if err := database.WithContext(ctx).First(&formula, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			renderComponent(w, r, pages.TransferControl("No formula has that id."))
+			return
+		}
+		applog.Error(ctx, "failed to load formula for transfer", "error", err, "formulaID", id)
+		http.Error(w, "unable to load formula", http.StatusInternalServerError)
+		return
+	}
+	if !canTransferFormula(r, formula.CreatedByID, userID) {
+		http.Error(w, "only the creator or an administrator can transfer this formula", http.StatusForbidden)
+		return
+	}
+	recipient, message, err := transferRecipient(r)
+	if err != nil {
+		http.Error(w, "unable to load user", http.StatusInternalServerError)
+		return
+	}
+	if message != "" {
+		renderComponent(w, r, pages.TransferControl(message))
+		return
+	}
+	var createdByID *uint
+	newOwnerID, newPublic := models.OrganizationOwnerID, true
+	if recipient != nil {
+		createdByID = &recipient.ID
+		newOwnerID, newPublic = recipient.ID, false
+	}
+	if sameOwner(createdByID, formula.CreatedByID) {
+		renderComponent(w, r, pages.TransferControl(formula.Name+" already belongs to "+ownerLabel(ctx, createdByID)+"."))
+		return
+	}
+
+	var chemicals []models.AromaChemical
+	if formula.CreatedByID != nil {
+		used := database.WithContext(ctx).Model(&models.FormulaIngredient{}).
+			Select("aroma_chemical_id").
+			Where("formula_id = ? AND aroma_chemical_id IS NOT NULL", formula.ID)
+		if err := database.WithContext(ctx).
+			Where("id IN (?) AND owner_id = ? AND public = ?", used, *formula.CreatedByID, false).
+			Order("id asc").
+			Find(&chemicals).Error; err != nil {
+			applog.Error(ctx, "failed to load formula chemicals for transfer", "error", err, "formulaID", id)
+			http.Error(w, "unable to transfer formula", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	from, to := ownerLabel(ctx, formula.CreatedByID), ownerLabel(ctx, createdByID)
+	summary := fmt.Sprintf("Transferred %s from %s to %s.", formula.Name, from, to)
+	if len(chemicals) > 0 {
+		summary = fmt.Sprintf("Transferred %s and %d of its private ingredients from %s to %s.", formula.Name, len(chemicals), from, to)
+	}
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&formula).Update("created_by_id", createdByID).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, r, models.AuditFormulaTransferred, models.ActivityEntityFormula, formula.ID, summary); err != nil {
+			return err
+		}
+		for _, chemical := range chemicals {
+			if err := tx.Model(&chemical).Updates(map[string]any{"owner_id": newOwnerID, "public": newPublic}).Error; err != nil {
+				return err
+			}
+			note := fmt.Sprintf("Transferred %s from %s to %s with formula %s.", chemical.IngredientName, from, to, formula.Name)
+			if err := recordAudit(tx, r, models.AuditChemicalTransferred, models.ActivityEntityAromaChemical, chemical.ID, note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to transfer formula", "error", err, "formulaID", id)
+		http.Error(w, "unable to transfer formula", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "formula transferred", "formulaID", id, "chemicals", len(chemicals), "userID", userID)
+	renderComponent(w, r, pages.TransferControl(summary))
+}
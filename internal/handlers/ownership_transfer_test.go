@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestFormulaTransferMovesPrivateIngredients(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate users: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	leaver := models.User{Email: "leaver@example.com", PasswordHash: "x"}
+	heir := models.User{Email: "heir@example.com", PasswordHash: "x"}
+	db.Create(&leaver)
+	db.Create(&heir)
+
+	private := models.AromaChemical{IngredientName: "House Musk", OwnerID: leaver.ID}
+	shared := models.AromaChemical{IngredientName: "Hedione", OwnerID: leaver.ID, Public: true}
+	db.Create(&private)
+	db.Create(&shared)
+	formula := models.Formula{Name: "Cologne", CreatedByID: &leaver.ID}
+	db.Create(&formula)
+	db.Create(&models.FormulaIngredient{FormulaID: formula.ID, AromaChemicalID: &private.ID, Amount: 1, Unit: "g"})
+	db.Create(&models.FormulaIngredient{FormulaID: formula.ID, AromaChemicalID: &shared.ID, Amount: 1, Unit: "g"})
+
+	form := url.Values{"id": {strconv.Itoa(int(formula.ID))}, "to": {"Heir@example.com"}}
+	req := authenticatedFormRequest(t, sm, "/app/sections/formulas/transfer", form, 99)
+	sm.Put(req.Context(), sessionUserRoleKey, models.RoleAdmin)
+	rec := httptest.NewRecorder()
+	FormulaTransfer(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Transferred Cologne and 1 of its private ingredients from leaver@example.com to heir@example.com.") {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	var reloaded models.Formula
+	db.First(&reloaded, formula.ID)
+	if reloaded.CreatedByID == nil || *reloaded.CreatedByID != heir.ID {
+		t.Fatalf("expected formula to belong to the heir, got %v", reloaded.CreatedByID)
+	}
+	var moved, kept models.AromaChemical
+	db.First(&moved, private.ID)
+	db.First(&kept, shared.ID)
+	if moved.OwnerID != heir.ID || kept.OwnerID != leaver.ID {
+		t.Fatalf("expected only the private chemical to move: %d %d", moved.OwnerID, kept.OwnerID)
+	}
+	var entries int64
+	db.Model(&models.AuditEntry{}).Where("action IN ?", []string{models.AuditFormulaTransferred, models.AuditChemicalTransferred}).Count(&entries)
+	if entries != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", entries)
+	}
+}
+
+func TestIngredientTransferToOrganization(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate users: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	chemical := models.AromaChemical{IngredientName: "House Musk", OwnerID: 3}
+	db.Create(&chemical)
+	form := url.Values{"id": {strconv.Itoa(int(chemical.ID))}, "to": {"organization"}}
+
+	rec := httptest.NewRecorder()
+	IngredientTransfer(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/transfer", form, 4))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected another member to be refused, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	IngredientTransfer(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/transfer", form, 3))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var reloaded models.AromaChemical
+	db.First(&reloaded, chemical.ID)
+	if reloaded.OwnerID != models.OrganizationOwnerID || !reloaded.Public {
+		t.Fatalf("expected a public organization chemical, got %+v", reloaded)
+	}
+
+	// Once the organization owns it, only administrators can manage it.
+	rec = httptest.NewRecorder()
+	IngredientTransfer(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/transfer", form, 3))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the previous owner to be refused, got %d", rec.Code)
+	}
+}
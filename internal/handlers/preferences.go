@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"gorm.io/gorm"
@@ -83,10 +85,13 @@ func Preferences(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.Redirect(w, r, "/app/preferences", http.StatusSeeOther)
+	http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
 }
 
 func currentUserID(r *http.Request) (uint, bool) {
+	if id, ok := apiTokenUser(r.Context()); ok {
+		return id, true
+	}
 	if sessionManager == nil {
 		return 0, false
 	}
@@ -148,3 +153,130 @@ func loadCurrentUserTheme(r *http.Request) string {
 	applog.Debug(ctx, "stored theme empty; using default", "userID", userID, "resolvedTheme", theme)
 	return theme
 }
+
+// ProductionPreferences saves the default solvent and target concentration
+// used when a batch report is run for a finished product, and the weighing
+// tolerances given to new production batches.
+func ProductionPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if sessionManager == nil || database == nil {
+		http.Error(w, "preferences not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	defaults := pages.ProductionDefaults{Solvent: strings.ToLower(strings.TrimSpace(r.FormValue("default_solvent")))}
+	if !models.ValidSolvent(defaults.Solvent) {
+		renderProductionDefaults(w, r, http.StatusBadRequest, loadProductionDefaults(r), "Choose one of the listed solvents.")
+		return
+	}
+
+	var problem string
+	defaults.ToleranceMg, problem = parseTolerance(r.FormValue("weigh_tolerance_mg"), models.DefaultWeighToleranceMg, "Absolute tolerance")
+	if problem == "" {
+		defaults.TolerancePercent, problem = parseTolerance(r.FormValue("weigh_tolerance_percent"), models.DefaultWeighTolerancePercent, "Percentage tolerance")
+	}
+	if problem == "" && defaults.TolerancePercent >= 100 {
+		problem = "Percentage tolerance must be below 100."
+	}
+	if problem != "" {
+		renderProductionDefaults(w, r, http.StatusBadRequest, defaults, problem)
+		return
+	}
+
+	if raw := strings.TrimSpace(r.FormValue("target_concentration")); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 || value >= 100 {
+			renderProductionDefaults(w, r, http.StatusBadRequest, defaults, "Concentration must be a percentage below 100.")
+			return
+		}
+		defaults.Concentration = value
+	}
+
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"default_solvent":         defaults.Solvent,
+		"target_concentration":    defaults.Concentration,
+		"weigh_tolerance_mg":      defaults.ToleranceMg,
+		"weigh_tolerance_percent": defaults.TolerancePercent,
+	}).Error; err != nil {
+		applog.Error(ctx, "failed to update production preferences", "error", err, "userID", userID)
+		http.Error(w, "unable to save preferences", http.StatusInternalServerError)
+		return
+	}
+	applog.Debug(ctx, "production preferences persisted", "userID", userID, "solvent", defaults.Solvent, "concentration", defaults.Concentration)
+
+	if !isHTMX(r) {
+		http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+		return
+	}
+	renderProductionDefaults(w, r, http.StatusOK, defaults, "Production defaults saved.")
+}
+
+// parseTolerance reads a non-negative tolerance, using fallback when blank.
+func parseTolerance(raw string, fallback float64, label string) (float64, string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return fallback, ""
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 {
+		return fallback, label + " must be zero or a positive number."
+	}
+	return value, ""
+}
+
+func renderProductionDefaults(w http.ResponseWriter, r *http.Request, status int, defaults pages.ProductionDefaults, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.ProductionDefaultsControl(defaults, message).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render production defaults", "error", err)
+	}
+}
+
+// loadProductionDefaults reads the current user's finished-product settings,
+// falling back to the default solvent with no concentration and the default
+// weighing tolerances.
+func loadProductionDefaults(r *http.Request) pages.ProductionDefaults {
+	defaults := pages.ProductionDefaults{
+		Solvent:          models.DefaultSolvent,
+		ToleranceMg:      models.DefaultWeighToleranceMg,
+		TolerancePercent: models.DefaultWeighTolerancePercent,
+	}
+	if database == nil {
+		return defaults
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		return defaults
+	}
+
+	var user models.User
+	if err := database.WithContext(r.Context()).Select("default_solvent", "target_concentration", "weigh_tolerance_mg", "weigh_tolerance_percent").First(&user, userID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			applog.Error(r.Context(), "failed to load production preferences", "error", err, "userID", userID)
+		}
+		return defaults
+	}
+	if models.ValidSolvent(user.DefaultSolvent) {
+		defaults.Solvent = models.SolventByID(user.DefaultSolvent).ID
+	}
+	defaults.Concentration = user.TargetConcentration
+	defaults.ToleranceMg = user.WeighToleranceMg
+	defaults.TolerancePercent = user.WeighTolerancePercent
+	return defaults
+}
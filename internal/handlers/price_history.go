@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// IngredientPriceHistory charts the price an aroma chemical had in each of
+// the member's priced production batches, ending with its current price.
+func IngredientPriceHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	id := pages.ParseUint(r.URL.Query().Get("id"))
+	if id == 0 {
+		http.Error(w, "missing ingredient", http.StatusBadRequest)
+		return
+	}
+	history, found, err := loadPriceHistory(r.Context(), userID, id)
+	if err != nil {
+		applog.Error(r.Context(), "failed to load price history", "error", err, "ingredientID", id)
+		http.Error(w, "unable to load price history", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	renderComponent(w, r, pages.IngredientPriceHistory(history))
+}
+
+// loadPriceHistory reads the prices copied into ownerID's batches for a
+// chemical they can see, one point per batch in pricing order. found is
+// false when the chemical is not in their library.
+func loadPriceHistory(ctx context.Context, ownerID, chemicalID uint) (pages.PriceHistory, bool, error) {
+	history := pages.PriceHistory{ChemicalID: chemicalID}
+	if database == nil {
+		return history, false, nil
+	}
+	var chemical models.AromaChemical
+	result := database.WithContext(ctx).
+		Where("id = ? AND (owner_id = ? OR public = ?)", chemicalID, ownerID, true).
+		Limit(1).
+		Find(&chemical)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return history, false, result.Error
+	}
+
+	var batches []models.ProductionBatch
+	priced := database.WithContext(ctx).Model(&models.ProductionBatchLine{}).
+		Select("batch_id").
+		Where("aroma_chemical_id = ? AND price_per_mg > 0", chemicalID)
+	if err := database.WithContext(ctx).
+		Where("owner_id = ? AND priced_at IS NOT NULL AND id IN (?)", ownerID, priced).
+		Preload("Lines", "aroma_chemical_id = ? AND price_per_mg > 0", chemicalID).
+		Order("priced_at asc, id asc").
+		Find(&batches).Error; err != nil {
+		return history, true, err
+	}
+	for _, batch := range batches {
+		if len(batch.Lines) == 0 {
+			continue
+		}
+		history.Points = append(history.Points, pages.PricePoint{
+			At:         *batch.PricedAt,
+			PricePerMg: batch.Lines[0].PricePerMg,
+		})
+	}
+	if chemical.PricePerMg > 0 {
+		history.Points = append(history.Points, pages.PricePoint{At: nowFunc(), PricePerMg: chemical.PricePerMg})
+	}
+	return history, true, nil
+}
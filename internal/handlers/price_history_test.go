@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+func TestIngredientPriceHistoryChartsBatchPrices(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.ProductionBatch{}, &models.ProductionBatchLine{}); err != nil {
+		t.Fatalf("automigrate batches: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	prevNow := nowFunc
+	nowFunc = func() time.Time { return time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { nowFunc = prevNow })
+
+	chemical := models.AromaChemical{IngredientName: "Hedione", OwnerID: 3, PricePerMg: 0.03}
+	private := models.AromaChemical{IngredientName: "House Musk", OwnerID: 4}
+	db.Create(&chemical)
+	db.Create(&private)
+	for i, batch := range []struct {
+		owner uint
+		at    time.Time
+		price float64
+	}{
+		{3, time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), 0.02},
+		{3, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), 0.025},
+		{4, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC), 0.9},
+	} {
+		at := batch.at
+		record := models.ProductionBatch{FormulaName: "Cologne", OwnerID: batch.owner, LotNumber: "LOT-" + strconv.Itoa(i), PricedAt: &at}
+		db.Create(&record)
+		db.Create(&models.ProductionBatchLine{BatchID: record.ID, AromaChemicalID: &chemical.ID, IngredientName: "Hedione", PricePerMg: batch.price})
+	}
+
+	req := authenticatedFormRequest(t, sm, "/app/sections/ingredients/price-history", url.Values{}, 3)
+	req.Method = http.MethodGet
+	req.URL.RawQuery = "id=" + strconv.Itoa(int(chemical.ID))
+	rec := httptest.NewRecorder()
+	IngredientPriceHistory(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"<polyline", "10 Jan 2026", "01 Jun 2026", "$0.0200", "$0.0300"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in %s", want, body)
+		}
+	}
+	if strings.Contains(body, "$0.9000") {
+		t.Fatalf("expected another member's batch to be left out: %s", body)
+	}
+
+	req.URL.RawQuery = "id=" + strconv.Itoa(int(private.ID))
+	rec = httptest.NewRecorder()
+	IngredientPriceHistory(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected another member's private chemical to be hidden, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	applog "perfugo/internal/log"
+)
+
+// ProbeAccess decides who may reach the probe endpoints such as /healthz and
+// /readyz.
+type ProbeAccess struct {
+	// Public lists the probe paths reachable without authentication. The
+	// others need Token as a Bearer token or an administrator session.
+	Public []string
+	// Networks restricts every probe to clients in these ranges; empty
+	// allows any address.
+	Networks []netip.Prefix
+	Token    string
+}
+
+// probeAccess defaults to open probes so orchestration works unconfigured.
+var probeAccess = ProbeAccess{Public: []string{"/healthz", "/readyz"}}
+
+// ConfigureProbes installs the probe access rules.
+func ConfigureProbes(access ProbeAccess) {
+	probeAccess = access
+	applog.Debug(nil, "probe access configured", "public", strings.Join(access.Public, ","), "networks", len(access.Networks), "token", access.Token != "")
+}
+
+// RequireProbeAccess guards a probe endpoint with the configured rules.
+// Clients outside the allowed networks get 403 Forbidden; private probes
+// answer 401 Unauthorized without credentials.
+func RequireProbeAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access := probeAccess
+		if len(access.Networks) > 0 && !probeClientAllowed(r, access.Networks) {
+			applog.Debug(r.Context(), "probe request rejected: client outside allowed networks", "path", r.URL.Path, "remote", r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if slices.Contains(access.Public, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if access.Token != "" {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(presented)), []byte(access.Token)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if ActiveSession(r) && currentUserIsAdmin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		applog.Debug(r.Context(), "probe request rejected: not authenticated", "path", r.URL.Path)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="perfugo"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// probeClientAllowed reports whether the request's remote address lies in
+// one of networks.
+func probeClientAllowed(r *http.Request, networks []netip.Prefix) bool {
+	addr, ok := clientAddr(r)
+	if !ok {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+type readinessResponse struct {
+	Status   string    `json:"status"`
+	Database string    `json:"database"`
+	Time     time.Time `json:"time"`
+}
+
+// Ready reports whether the instance can serve requests: it answers 503
+// Service Unavailable while the database does not respond.
+func Ready(w http.ResponseWriter, r *http.Request) {
+	resp := readinessResponse{Status: "ok", Database: "not configured", Time: time.Now().UTC()}
+	status := http.StatusOK
+	if database != nil {
+		resp.Database = "ok"
+		sqlDB, err := database.DB()
+		if err == nil {
+			err = sqlDB.PingContext(r.Context())
+		}
+		if err != nil {
+			applog.Error(r.Context(), "readiness check failed", "error", err)
+			resp.Status, resp.Database = "unavailable", "unreachable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		applog.Error(r.Context(), "failed to encode readiness response", "error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRequireProbeAccess(t *testing.T) {
+	original := probeAccess
+	t.Cleanup(func() { probeAccess = original })
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	ConfigureProbes(ProbeAccess{
+		Public:   []string{"/healthz"},
+		Networks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		Token:    "probe-secret",
+	})
+	handler := sm.LoadAndSave(RequireProbeAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		name   string
+		path   string
+		remote string
+		token  string
+		want   int
+	}{
+		{name: "public probe", path: "/healthz", remote: "10.1.2.3:4000", want: http.StatusNoContent},
+		{name: "outside allowed networks", path: "/healthz", remote: "192.0.2.10:4000", want: http.StatusForbidden},
+		{name: "private probe without credentials", path: "/readyz", remote: "10.1.2.3:4000", want: http.StatusUnauthorized},
+		{name: "private probe with wrong token", path: "/readyz", remote: "10.1.2.3:4000", token: "guess", want: http.StatusUnauthorized},
+		{name: "private probe with token", path: "/readyz", remote: "10.1.2.3:4000", token: "probe-secret", want: http.StatusNoContent},
+		{name: "token does not bypass networks", path: "/readyz", remote: "192.0.2.10:4000", token: "probe-secret", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remote
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, w.Code)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Fatal("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestReadyReportsDatabase(t *testing.T) {
+	db := newToolsTestDB(t)
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	w := httptest.NewRecorder()
+	Ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql handle: %v", err)
+	}
+	sqlDB.Close()
+	w = httptest.NewRecorder()
+	Ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 with the database closed, got %d", w.Code)
+	}
+}
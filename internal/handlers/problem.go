@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	applog "perfugo/internal/log"
+)
+
+const problemContentType = "application/problem+json"
+
+// problemDetails is an RFC 7807 error document returned by JSON endpoints.
+type problemDetails struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Errors   []fieldProblem `json:"errors,omitempty"`
+}
+
+// fieldProblem points a validation message at a specific request field.
+type fieldProblem struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// writeProblem renders an RFC 7807 problem document for the provided status.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, fields ...fieldProblem) {
+	problem := problemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   fields,
+	}
+	if len(fields) > 0 {
+		problem.Type = "/problems/validation"
+	}
+
+	applog.Debug(r.Context(), "responding with problem details", "status", status, "detail", detail, "fieldErrors", len(fields))
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		applog.Error(r.Context(), "failed to encode problem details", "error", err)
+	}
+}
+
+// acceptsJSON reports whether the client prefers a JSON response over HTML.
+func acceptsJSON(r *http.Request) bool {
+	accept := strings.ToLower(r.Header.Get("Accept"))
+	return strings.Contains(accept, "application/json") || strings.Contains(accept, problemContentType)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		status   int
+		fields   []fieldProblem
+		wantType string
+	}{
+		{name: "plain", status: http.StatusNotFound, wantType: "about:blank"},
+		{name: "validation", status: http.StatusUnprocessableEntity, fields: []fieldProblem{{Field: "amount", Message: "Amount must be positive."}}, wantType: "/problems/validation"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/app/api/example", nil)
+			rr := httptest.NewRecorder()
+			writeProblem(rr, req, tt.status, "something went wrong", tt.fields...)
+
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != problemContentType {
+				t.Fatalf("unexpected content type %q", ct)
+			}
+
+			var problem problemDetails
+			if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decode problem: %v", err)
+			}
+			if problem.Type != tt.wantType || problem.Status != tt.status || problem.Title != http.StatusText(tt.status) {
+				t.Fatalf("unexpected problem %+v", problem)
+			}
+			if problem.Instance != "/app/api/example" || problem.Detail != "something went wrong" {
+				t.Fatalf("unexpected problem metadata %+v", problem)
+			}
+			if len(problem.Errors) != len(tt.fields) {
+				t.Fatalf("expected %d field errors, got %+v", len(tt.fields), problem.Errors)
+			}
+		})
+	}
+}
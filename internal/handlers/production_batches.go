@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/analytics"
+	applog "perfugo/internal/log"
+	"perfugo/internal/notify"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// productionBatchListLimit caps the batches listed on the reports page.
+const productionBatchListLimit = 10
+
+// ProductionBatchStart expands the submitted formula exactly like the batch
+// report and saves the result as a production batch, which is then weighed
+// out line by line on its own page.
+func ProductionBatchStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	formulaID, targetQuantity, finish, problem := parseBatchForm(r)
+	if problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := withDeadline(r, handlerTimeouts.Report)
+	defer cancel()
+	report, err := buildBatchProductionReportData(ctx, formulaID, targetQuantity, finish)
+	if err != nil {
+		writeBatchReportError(w, r, err, formulaID)
+		return
+	}
+
+	batch := newProductionBatch(formulaID, userID, report, loadProductionDefaults(r))
+	if err := database.WithContext(r.Context()).Create(&batch).Error; err != nil {
+		applog.Error(r.Context(), "failed to start production batch", "error", err, "formulaID", formulaID)
+		http.Error(w, "We were unable to start the batch. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(r.Context(), "production batch started", "batchID", batch.ID, "formulaID", formulaID, "lines", len(batch.Lines))
+	publishBatchBadge(r.Context(), userID)
+
+	recordActivity(r.Context(), models.ActivityEntityFormula, formulaID, analytics.EventBatch)
+	http.Redirect(w, r, pages.ProductionBatchURL(batch.ID), http.StatusSeeOther)
+}
+
+// newProductionBatch copies the report's lines and the user's current
+// weighing tolerances into a new, open batch.
+func newProductionBatch(formulaID, ownerID uint, report pages.BatchProductionReportData, defaults pages.ProductionDefaults) models.ProductionBatch {
+	batch := models.ProductionBatch{
+		FormulaID:        formulaID,
+		FormulaName:      report.FormulaName,
+		FormulaVersion:   report.FormulaVersion,
+		OwnerID:          ownerID,
+		LotNumber:        report.LotNumber,
+		TargetQuantity:   report.TargetQuantity,
+		ToleranceMg:      defaults.ToleranceMg,
+		TolerancePercent: defaults.TolerancePercent,
+		Status:           models.ProductionBatchOpen,
+		PricedAt:         &report.RunDate,
+		Lines:            make([]models.ProductionBatchLine, 0, len(report.Ingredients)),
+	}
+	for _, item := range report.Ingredients {
+		line := models.ProductionBatchLine{
+			Position:       item.Order,
+			IngredientName: item.IngredientName,
+			CASNumber:      item.CASNumber,
+			Dilution:       item.Dilution,
+			Solvent:        item.Solvent,
+			TargetQuantity: item.FinalQuantity,
+			NeatQuantity:   item.NeatQuantity,
+			PricePerMg:     item.PricePerMg,
+		}
+		if item.ChemicalID != 0 {
+			chemicalID := item.ChemicalID
+			line.AromaChemicalID = &chemicalID
+		}
+		batch.Lines = append(batch.Lines, line)
+	}
+	return batch
+}
+
+// ProductionBatchView renders the weighing page for one of the user's batches.
+func ProductionBatchView(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	batch, ok := requireProductionBatch(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+	renderProductionBatch(w, r, http.StatusOK, pages.ProductionBatchPage{Batch: batch})
+}
+
+// ProductionBatchWeigh records the actual weight of one line. A weight
+// outside the batch tolerance keeps its justification; one back within
+// tolerance drops it, as there is nothing left to justify.
+func ProductionBatchWeigh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid submission.", http.StatusBadRequest)
+		return
+	}
+
+	batch, ok := requireProductionBatch(w, r, r.FormValue("batch_id"))
+	if !ok {
+		return
+	}
+	if batch.Finalized() {
+		renderProductionBatch(w, r, http.StatusConflict, pages.ProductionBatchPage{Batch: batch, Message: "This batch is finalized; its weights can no longer be changed."})
+		return
+	}
+
+	lineID := pages.ParseUint(r.FormValue("line_id"))
+	index := -1
+	for i := range batch.Lines {
+		if batch.Lines[i].ID == lineID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		http.Error(w, "Weighing line not found.", http.StatusNotFound)
+		return
+	}
+	line := &batch.Lines[index]
+
+	actual, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("actual_quantity")), 64)
+	if err != nil || actual < 0 {
+		renderProductionBatch(w, r, http.StatusBadRequest, pages.ProductionBatchPage{Batch: batch, Message: fmt.Sprintf("Enter the weight of %s in mg.", line.IngredientName)})
+		return
+	}
+	line.ActualQuantity = &actual
+	line.Justification = ""
+	if batch.OutOfTolerance(*line) {
+		line.Justification = strings.TrimSpace(r.FormValue("justification"))
+	}
+
+	if err := database.WithContext(r.Context()).Model(&models.ProductionBatchLine{}).Where("id = ?", line.ID).Updates(map[string]any{
+		"actual_quantity": actual,
+		"justification":   line.Justification,
+	}).Error; err != nil {
+		applog.Error(r.Context(), "failed to record weighing", "error", err, "batchID", batch.ID, "lineID", line.ID)
+		http.Error(w, "We were unable to save the weight. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	applog.Debug(r.Context(), "weighing recorded", "batchID", batch.ID, "lineID", line.ID, "outOfTolerance", batch.OutOfTolerance(*line))
+
+	http.Redirect(w, r, fmt.Sprintf("%s#line-%d", pages.ProductionBatchURL(batch.ID), line.ID), http.StatusSeeOther)
+}
+
+// ProductionBatchFinalize closes a batch once every line has been weighed
+// and every out-of-tolerance line carries a justification. The producing
+// user e-signs the batch as it is closed; see verifyBatchSignature.
+func ProductionBatchFinalize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid submission.", http.StatusBadRequest)
+		return
+	}
+
+	batch, ok := requireProductionBatch(w, r, r.FormValue("batch_id"))
+	if !ok {
+		return
+	}
+	if batch.Finalized() {
+		http.Redirect(w, r, pages.ProductionBatchURL(batch.ID), http.StatusSeeOther)
+		return
+	}
+
+	if blockers := batch.FinalizeBlockers(); len(blockers) > 0 {
+		renderProductionBatch(w, r, http.StatusUnprocessableEntity, pages.ProductionBatchPage{
+			Batch:    batch,
+			Message:  "The batch cannot be finalized yet.",
+			Problems: blockers,
+		})
+		return
+	}
+
+	signature, problem := verifyBatchSignature(r)
+	if problem != "" {
+		renderProductionBatch(w, r, http.StatusUnprocessableEntity, pages.ProductionBatchPage{
+			Batch:   batch,
+			Message: problem,
+		})
+		return
+	}
+
+	finalizedAt := signature.At
+	if err := database.WithContext(r.Context()).Model(&models.ProductionBatch{}).Where("id = ?", batch.ID).Updates(map[string]any{
+		"status":           models.ProductionBatchFinalized,
+		"finalized_at":     &finalizedAt,
+		"signed_by_id":     signature.UserID,
+		"signed_by_name":   signature.Name,
+		"signed_at":        &signature.At,
+		"signature_method": signature.Method,
+	}).Error; err != nil {
+		applog.Error(r.Context(), "failed to finalize production batch", "error", err, "batchID", batch.ID)
+		http.Error(w, "We were unable to finalize the batch. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(r.Context(), "production batch finalized", "batchID", batch.ID, "outOfTolerance", countOutOfTolerance(batch), "signedBy", signature.UserID, "signatureMethod", signature.Method)
+	publishBatchBadge(r.Context(), batch.OwnerID)
+	notifyUser(batch.OwnerID, notify.Toast(notify.LevelSuccess, fmt.Sprintf("Batch %s of %s finalized.", batch.LotNumber, batch.FormulaName)))
+
+	http.Redirect(w, r, pages.ProductionBatchURL(batch.ID), http.StatusSeeOther)
+}
+
+// requireProductionBatch loads one of the current user's batches with its
+// lines in weighing order, writing the error response when it cannot.
+func requireProductionBatch(w http.ResponseWriter, r *http.Request, rawID string) (models.ProductionBatch, bool) {
+	var batch models.ProductionBatch
+	if database == nil {
+		writeNoDatabase(w, r)
+		return batch, false
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return batch, false
+	}
+	id := pages.ParseUint(rawID)
+	if id == 0 {
+		http.Error(w, "Select a batch.", http.StatusBadRequest)
+		return batch, false
+	}
+
+	err := database.WithContext(r.Context()).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("position asc") }).
+		Where("owner_id = ?", userID).
+		First(&batch, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Batch not found.", http.StatusNotFound)
+			return batch, false
+		}
+		applog.Error(r.Context(), "failed to load production batch", "error", err, "batchID", id)
+		http.Error(w, "We were unable to load the batch. Please try again.", http.StatusInternalServerError)
+		return batch, false
+	}
+	return batch, true
+}
+
+func renderProductionBatch(w http.ResponseWriter, r *http.Request, status int, data pages.ProductionBatchPage) {
+	data.Costs = productionBatchCosts(r, data.Batch)
+	data.LabelPrinter = labelPrinterLanguage()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.ProductionBatchWeighing(data).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render production batch", "error", err)
+	}
+}
+
+// loadProductionBatches summarises the user's most recent batches for the
+// reports page.
+// productionBatchCosts prices a batch with the prices recorded when it was
+// started, or with today's prices when the request asks for prices=current
+// or none were recorded.
+func productionBatchCosts(r *http.Request, batch models.ProductionBatch) pages.BatchCosts {
+	useCurrent := r.URL.Query().Get("prices") == "current"
+	current := map[uint]float64{}
+	if useCurrent || batch.PricedAt == nil {
+		ids := make([]uint, 0, len(batch.Lines))
+		for _, line := range batch.Lines {
+			if line.AromaChemicalID != nil {
+				ids = append(ids, *line.AromaChemicalID)
+			}
+		}
+		var chemicals []models.AromaChemical
+		if len(ids) > 0 {
+			if err := database.WithContext(r.Context()).Select("id", "price_per_mg").Where("id IN ?", ids).Find(&chemicals).Error; err != nil {
+				applog.Error(r.Context(), "failed to load current prices", "error", err, "batchID", batch.ID)
+			}
+		}
+		for _, chemical := range chemicals {
+			current[chemical.ID] = chemical.PricePerMg
+		}
+	}
+	return pages.BatchCostsFor(batch, current, useCurrent, nowFunc())
+}
+
+func loadProductionBatches(ctx context.Context, userID uint) []pages.ProductionBatchSummary {
+	if database == nil || userID == 0 {
+		return nil
+	}
+
+	var batches []models.ProductionBatch
+	if err := database.WithContext(ctx).
+		Preload("Lines").
+		Where("owner_id = ?", userID).
+		Order("created_at desc").
+		Limit(productionBatchListLimit).
+		Find(&batches).Error; err != nil {
+		applog.Error(ctx, "failed to load production batches", "error", err, "userID", userID)
+		return nil
+	}
+
+	summaries := make([]pages.ProductionBatchSummary, 0, len(batches))
+	for _, batch := range batches {
+		summary := pages.ProductionBatchSummary{
+			ID:        batch.ID,
+			FormulaID: batch.FormulaID,
+			Formula:   batch.FormulaName,
+			LotNumber: batch.LotNumber,
+			Status:    batch.Status,
+			Started:   pages.FormatReportDate(batch.CreatedAt),
+			Lines:     len(batch.Lines),
+			Flagged:   countOutOfTolerance(batch),
+		}
+		for _, line := range batch.Lines {
+			if line.Weighed() {
+				summary.Weighed++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func countOutOfTolerance(batch models.ProductionBatch) int {
+	count := 0
+	for _, line := range batch.Lines {
+		if batch.OutOfTolerance(line) {
+			count++
+		}
+	}
+	return count
+}
+
+// PickList expands the submitted formula like the batch report and lays out
+// which of the user's containers to fetch, grouped by storage location.
+// Passing format=csv downloads the list instead of rendering it.
+func PickList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	formulaID, targetQuantity, finish, problem := parseBatchForm(r)
+	if problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := withDeadline(r, handlerTimeouts.Report)
+	defer cancel()
+	report, err := buildBatchProductionReportData(ctx, formulaID, targetQuantity, finish)
+	if err != nil {
+		writeBatchReportError(w, r, err, formulaID)
+		return
+	}
+
+	var stock []models.InventoryItem
+	if err := database.WithContext(ctx).
+		Where("owner_id = ?", userID).
+		Order("id asc").
+		Find(&stock).Error; err != nil {
+		if contextExpired(err) {
+			writeTimeout(w, r, nil)
+			return
+		}
+		applog.Error(r.Context(), "failed to load inventory for pick list", "error", err, "userID", userID)
+		http.Error(w, "We were unable to build the pick list. Please try again.", http.StatusInternalServerError)
+		return
+	}
+
+	list := pages.BuildPickList(report, stock, nowFunc())
+	applog.Debug(r.Context(), "pick list built", "formulaID", formulaID, "lines", list.Lines())
+
+	if strings.EqualFold(r.FormValue("format"), "csv") {
+		writePickListCSV(w, r, list)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.PickListPage(list).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render pick list", "error", err)
+	}
+}
+
+func writePickListCSV(w http.ResponseWriter, r *http.Request, list pages.PickList) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pick-list-%s.csv"`, list.LotNumber))
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"Location", "Order", "Ingredient", "CAS", "Dilution", "Lot", "Take (mg)", "On hand (mg)", "Use by"})
+	for _, group := range list.Groups {
+		for _, line := range group.Lines {
+			_ = writer.Write([]string{
+				group.Location,
+				strconv.Itoa(line.Order),
+				line.IngredientName,
+				line.CASNumber,
+				line.Dilution,
+				line.LotNumber,
+				strconv.FormatFloat(line.QuantityMg, 'f', 0, 64),
+				strconv.FormatFloat(line.OnHandMg, 'f', 0, 64),
+				line.Expires,
+			})
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		applog.Error(r.Context(), "failed to write pick list csv", "error", err)
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"perfugo/models"
+)
+
+func TestProductionBatchWeighingRequiresJustification(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.ProductionBatch{}, &models.ProductionBatchLine{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("atelier"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user := models.User{Email: "perfumer@example.com", Name: "Ana Costa", PasswordHash: string(hashed)}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := db.Model(&user).Updates(map[string]any{"weigh_tolerance_mg": 10, "weigh_tolerance_percent": 1}).Error; err != nil {
+		t.Fatalf("set tolerances: %v", err)
+	}
+	userID := int(user.ID)
+
+	chemical := models.AromaChemical{IngredientName: "Hedione", PyramidPosition: "heart", OwnerID: user.ID}
+	if err := db.Create(&chemical).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	formula := models.Formula{Name: "Dew", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	if err := db.Create(&models.FormulaIngredient{FormulaID: formula.ID, AromaChemicalID: &chemical.ID, Amount: 1, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create ingredient: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ProductionBatchStart(rec, authenticatedFormRequest(t, sm, "/app/production/batches", url.Values{
+		"formula_id":      {strconv.Itoa(int(formula.ID))},
+		"target_quantity": {"2000"},
+	}, userID))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("start status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	var batch models.ProductionBatch
+	if err := db.Preload("Lines").First(&batch).Error; err != nil {
+		t.Fatalf("load batch: %v", err)
+	}
+	if batch.ToleranceMg != 10 || batch.TolerancePercent != 1 {
+		t.Fatalf("tolerances not copied from preferences: %+v", batch)
+	}
+	if len(batch.Lines) != 1 || batch.Lines[0].TargetQuantity != 2000 {
+		t.Fatalf("unexpected lines: %+v", batch.Lines)
+	}
+	batchID := strconv.Itoa(int(batch.ID))
+	lineID := strconv.Itoa(int(batch.Lines[0].ID))
+
+	weigh := func(actual, justification string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ProductionBatchWeigh(rec, authenticatedFormRequest(t, sm, "/app/production/batch/weigh", url.Values{
+			"batch_id":        {batchID},
+			"line_id":         {lineID},
+			"actual_quantity": {actual},
+			"justification":   {justification},
+		}, userID))
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("weigh status = %d, body %q", rec.Code, rec.Body.String())
+		}
+	}
+	finalize := func(password string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ProductionBatchFinalize(rec, authenticatedFormRequest(t, sm, "/app/production/batch/finalize", url.Values{
+			"batch_id":           {batchID},
+			"signature_password": {password},
+		}, userID))
+		return rec
+	}
+
+	// 2000 mg allows 20 mg either way; 2030 mg is out of tolerance.
+	weigh("2030", "")
+	rec = finalize("atelier")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("finalize without justification status = %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "needs a justification") {
+		t.Fatalf("expected justification blocker, got %q", rec.Body.String())
+	}
+
+	weigh("2030", "Last drops from the bottle; accepted by the perfumer.")
+	for _, password := range []string{"", "wrong"} {
+		if rec := finalize(password); rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "password") {
+			t.Fatalf("finalize with password %q: status = %d, body %q", password, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := finalize("atelier"); rec.Code != http.StatusSeeOther {
+		t.Fatalf("finalize status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	if err := db.Preload("Lines").First(&batch, batch.ID).Error; err != nil {
+		t.Fatalf("reload batch: %v", err)
+	}
+	if !batch.Finalized() || batch.FinalizedAt == nil {
+		t.Fatalf("batch not finalized: %+v", batch)
+	}
+	if !batch.Signed() || batch.SignedByName != "Ana Costa" || batch.SignedByID == nil || *batch.SignedByID != user.ID || batch.SignatureMethod != models.SignatureMethodPassword {
+		t.Fatalf("batch not signed: %+v", batch)
+	}
+	if batch.Lines[0].Justification == "" {
+		t.Fatalf("justification was not stored")
+	}
+
+	rec = httptest.NewRecorder()
+	ProductionBatchWeigh(rec, authenticatedFormRequest(t, sm, "/app/production/batch/weigh", url.Values{
+		"batch_id":        {batchID},
+		"line_id":         {lineID},
+		"actual_quantity": {"2000"},
+	}, userID))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("weighing a finalized batch status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	ProductionBatchFinalize(rec, authenticatedFormRequest(t, sm, "/app/production/batch/finalize", url.Values{"batch_id": {batchID}, "signature_password": {"atelier"}}, userID+1))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("another user's batch status = %d", rec.Code)
+	}
+}
+
+func TestBatchVarianceUsesFinalizedBatchesOnly(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.ProductionBatch{}, &models.ProductionBatchLine{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	actual := func(v float64) *float64 { return &v }
+	for _, batch := range []models.ProductionBatch{
+		{FormulaID: 3, FormulaName: "Dew", OwnerID: 2, LotNumber: "DEW-1", Status: models.ProductionBatchFinalized,
+			Lines: []models.ProductionBatchLine{{IngredientName: "Hedione", TargetQuantity: 1000, ActualQuantity: actual(990)}}},
+		{FormulaID: 3, FormulaName: "Dew", OwnerID: 2, LotNumber: "DEW-2", Status: models.ProductionBatchOpen,
+			Lines: []models.ProductionBatchLine{{IngredientName: "Hedione", TargetQuantity: 1000, ActualQuantity: actual(500)}}},
+		{FormulaID: 3, FormulaName: "Dew", OwnerID: 9, LotNumber: "DEW-X", Status: models.ProductionBatchFinalized},
+	} {
+		if err := db.Create(&batch).Error; err != nil {
+			t.Fatalf("create batch: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/app/production/variance?formula_id=3", nil)
+	ctx, err := sm.Load(req.Context(), "")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	req = req.WithContext(ctx)
+	sm.Put(ctx, sessionAuthenticatedKey, true)
+	sm.Put(ctx, sessionUserIDKey, 2)
+
+	rec := httptest.NewRecorder()
+	BatchVariance(rec, req)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "1 finalized batch") || !strings.Contains(body, "99.00%") {
+		t.Fatalf("unexpected report %d: %q", rec.Code, body)
+	}
+	if strings.Contains(body, "DEW-2") || strings.Contains(body, "DEW-X") {
+		t.Fatalf("open or foreign batches leaked into the report: %q", body)
+	}
+}
+
+func TestProductionBatchSessionSignature(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.ProductionBatch{}, &models.ProductionBatchLine{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "sso@example.com", PasswordHash: "unusable", OIDCSubject: "sub-1"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	actual := 1000.0
+	batch := models.ProductionBatch{FormulaID: 1, FormulaName: "Dew", OwnerID: user.ID, LotNumber: "DEW-1", Status: models.ProductionBatchOpen,
+		Lines: []models.ProductionBatchLine{{Position: 1, IngredientName: "Hedione", TargetQuantity: 1000, ActualQuantity: &actual}}}
+	if err := db.Create(&batch).Error; err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+
+	finalize := func(signedInAgo time.Duration) *httptest.ResponseRecorder {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/app/production/batch/finalize", url.Values{
+			"batch_id":          {strconv.Itoa(int(batch.ID))},
+			"signature_confirm": {"true"},
+		}, int(user.ID))
+		sm.Put(req.Context(), sessionSignedInAtKey, time.Now().Add(-signedInAgo).Unix())
+		rec := httptest.NewRecorder()
+		ProductionBatchFinalize(rec, req)
+		return rec
+	}
+
+	if rec := finalize(time.Hour); rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "sign-in is too old") {
+		t.Fatalf("stale session: status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if rec := finalize(time.Minute); rec.Code != http.StatusSeeOther {
+		t.Fatalf("fresh session: status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if err := db.First(&batch, batch.ID).Error; err != nil {
+		t.Fatalf("reload batch: %v", err)
+	}
+	if !batch.Signed() || batch.SignedByName != "sso@example.com" || batch.SignatureMethod != models.SignatureMethodSession {
+		t.Fatalf("unexpected signature: %+v", batch)
+	}
+}
+
+func TestProductionBatchCostsUseRecordedPrices(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}, &models.ProductionBatch{}, &models.ProductionBatchLine{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	chemical := models.AromaChemical{IngredientName: "Hedione", OwnerID: 4, PricePerMg: 0.01}
+	if err := db.Create(&chemical).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	formula := models.Formula{Name: "Dew", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	if err := db.Create(&models.FormulaIngredient{FormulaID: formula.ID, AromaChemicalID: &chemical.ID, Amount: 1, Unit: "g"}).Error; err != nil {
+		t.Fatalf("create ingredient: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ProductionBatchStart(rec, authenticatedFormRequest(t, sm, "/app/production/batches", url.Values{
+		"formula_id":      {strconv.Itoa(int(formula.ID))},
+		"target_quantity": {"2000"},
+	}, 4))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("start status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var batch models.ProductionBatch
+	if err := db.Preload("Lines").First(&batch).Error; err != nil {
+		t.Fatalf("load batch: %v", err)
+	}
+	if batch.PricedAt == nil || batch.Lines[0].PricePerMg != 0.01 || batch.Lines[0].NeatQuantity != 2000 {
+		t.Fatalf("prices not recorded: %+v", batch)
+	}
+
+	if err := db.Model(&chemical).Update("price_per_mg", 0.02).Error; err != nil {
+		t.Fatalf("update price: %v", err)
+	}
+	view := func(query string) string {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/app/production/batch?id="+strconv.Itoa(int(batch.ID))+query, nil, 4)
+		req.Method = http.MethodGet
+		rec := httptest.NewRecorder()
+		ProductionBatchView(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("view status = %d, body %q", rec.Code, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+	if body := view(""); !strings.Contains(body, "20.00") || !strings.Contains(body, "Recompute at today") {
+		t.Fatalf("expected the recorded cost: %s", body)
+	}
+	if body := view("&prices=current"); !strings.Contains(body, "40.00") {
+		t.Fatalf("expected the cost at today's prices: %s", body)
+	}
+}
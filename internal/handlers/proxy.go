@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	applog "perfugo/internal/log"
+)
+
+// trustedProxies lists the networks of the reverse proxies, such as nginx or
+// Traefik, whose X-Forwarded-* headers are believed. Empty trusts none.
+var trustedProxies []netip.Prefix
+
+type forwardedSchemeKey struct{}
+
+// insecureCookieWarned records that warnInsecureSessionCookie has fired.
+var insecureCookieWarned atomic.Bool
+
+// ConfigureTrustedProxies installs the networks ForwardedHeaders trusts.
+func ConfigureTrustedProxies(networks []netip.Prefix) {
+	trustedProxies = networks
+	applog.Debug(nil, "trusted proxies configured", "networks", len(networks))
+}
+
+// ForwardedHeaders resolves the client address, scheme and host of requests
+// relayed by a trusted proxy from X-Forwarded-For, X-Forwarded-Proto and
+// X-Forwarded-Host, so rate limits, probes, captcha checks and the audit
+// trail see the real client. Those headers are dropped from any other peer
+// so they cannot be spoofed.
+func ForwardedHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, ok := clientAddr(r)
+		if !ok || !trustedProxy(peer) {
+			if r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("X-Forwarded-Proto") != "" || r.Header.Get("X-Forwarded-Host") != "" {
+				r = r.Clone(r.Context())
+				r.Header.Del("X-Forwarded-For")
+				r.Header.Del("X-Forwarded-Proto")
+				r.Header.Del("X-Forwarded-Host")
+			}
+			warnInsecureSessionCookie(r)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		if proto := strings.ToLower(firstForwarded(r.Header.Get("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+			ctx = context.WithValue(ctx, forwardedSchemeKey{}, proto)
+		}
+		r = r.WithContext(ctx)
+		if client, ok := forwardedClient(r.Header.Values("X-Forwarded-For")); ok {
+			r.RemoteAddr = netip.AddrPortFrom(client, 0).String()
+		}
+		if host := firstForwarded(r.Header.Get("X-Forwarded-Host")); host != "" {
+			r.Host = host
+		}
+		warnInsecureSessionCookie(r)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// warnInsecureSessionCookie logs once when the session cookie is marked
+// Secure but a request reached perfugo over plain http, as seen through any
+// trusted proxy. Browsers drop such cookies, so sign-in would silently fail;
+// the usual cause is a TLS-terminating proxy missing from TRUSTED_PROXIES.
+func warnInsecureSessionCookie(r *http.Request) {
+	if sessionManager == nil || !sessionManager.Cookie.Secure || requestScheme(r) == "https" {
+		return
+	}
+	if insecureCookieWarned.CompareAndSwap(false, true) {
+		applog.Warn(r.Context(), "secure session cookie requested over http; serve perfugo over https or add its proxy to TRUSTED_PROXIES",
+			"remote", r.RemoteAddr)
+	}
+}
+
+// forwardedClient walks X-Forwarded-For from the nearest hop back and
+// returns the first address that is not a trusted proxy: the furthest
+// address anyone but the client could have written.
+func forwardedClient(values []string) (netip.Addr, bool) {
+	var hops []string
+	for _, value := range values {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !trustedProxy(client) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+func trustedProxy(addr netip.Addr) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstForwarded returns the value the outermost proxy set in a
+// comma-separated forwarded header.
+func firstForwarded(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+// clientAddr is the address of the client that made the request, behind
+// any trusted proxy once ForwardedHeaders has run.
+func clientAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// requestScheme is "https" when the request reached perfugo, or the trusted
+// proxy in front of it, over TLS.
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if scheme, ok := r.Context().Value(forwardedSchemeKey{}).(string); ok {
+		return scheme
+	}
+	return "http"
+}
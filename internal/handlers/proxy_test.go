@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	applog "perfugo/internal/log"
+)
+
+func TestForwardedHeaders(t *testing.T) {
+	prev := trustedProxies
+	ConfigureTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	t.Cleanup(func() { trustedProxies = prev })
+
+	var seen *http.Request
+	handler := ForwardedHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+	}))
+	send := func(remote, forwardedFor string) {
+		req := httptest.NewRequest(http.MethodGet, "/app", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "lab.example.com")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("10.0.0.2:5000", "198.51.100.9, 203.0.113.7, 10.0.0.1")
+	if addr, _ := clientAddr(seen); addr.String() != "203.0.113.7" {
+		t.Fatalf("expected the nearest untrusted hop, got %s", addr)
+	}
+	if got := absoluteURL(seen, "/signup"); got != "https://lab.example.com/signup" {
+		t.Fatalf("absoluteURL = %q", got)
+	}
+
+	send("203.0.113.50:5000", "198.51.100.9")
+	if addr, _ := clientAddr(seen); addr.String() != "203.0.113.50" {
+		t.Fatalf("an untrusted peer chose its own address: %s", addr)
+	}
+	if got := absoluteURL(seen, "/signup"); got != "http://example.com/signup" {
+		t.Fatalf("an untrusted peer chose the scheme or host: %q", got)
+	}
+	if seen.Header.Get("X-Forwarded-For") != "" {
+		t.Fatal("expected forwarded headers from an untrusted peer to be dropped")
+	}
+}
+
+func TestForwardedHeadersWarnsAboutInsecureSessionCookies(t *testing.T) {
+	prev := trustedProxies
+	ConfigureTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	t.Cleanup(func() { trustedProxies = prev })
+	sm, cleanup := withTestSessionManager(t)
+	t.Cleanup(cleanup)
+	sm.Cookie.Secure = true
+	buf := new(bytes.Buffer)
+	original := applog.Logger()
+	applog.ReplaceLogger(slog.New(slog.NewTextHandler(buf, nil)))
+	t.Cleanup(func() {
+		applog.ReplaceLogger(original)
+		insecureCookieWarned.Store(false)
+	})
+
+	handler := ForwardedHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	send := func(remote string) {
+		req := httptest.NewRequest(http.MethodGet, "/login", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-Proto", "https")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("10.0.0.2:5000")
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected warning for https through a trusted proxy: %q", buf.String())
+	}
+	send("203.0.113.50:5000")
+	send("203.0.113.51:5000")
+	if got := strings.Count(buf.String(), "secure session cookie requested over http"); got != 1 {
+		t.Fatalf("expected one warning for plain http, got %d in %q", got, buf.String())
+	}
+}
+
+func TestRecordAuditKeepsClientIP(t *testing.T) {
+	db := newToolsTestDB(t)
+	req := httptest.NewRequest(http.MethodPost, "/app", nil)
+	req.RemoteAddr = "203.0.113.7:0"
+	if err := recordAudit(db, req, "formula.approved", "formula", 1, ""); err != nil {
+		t.Fatalf("recordAudit: %v", err)
+	}
+	var ip string
+	db.Table("audit_entries").Select("client_ip").Scan(&ip)
+	if ip != "203.0.113.7" {
+		t.Fatalf("client_ip = %q", ip)
+	}
+}
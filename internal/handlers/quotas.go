@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/storage"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// Quotas caps what a single user may create. A zero limit is not enforced.
+type Quotas struct {
+	MaxFormulas     int
+	MaxIngredients  int
+	MaxStorageBytes int64
+}
+
+var userQuotas Quotas
+
+// ConfigureQuotas installs the per-user limits enforced by create handlers.
+func ConfigureQuotas(quotas Quotas) {
+	userQuotas = quotas
+	applog.Debug(nil, "user quotas configured",
+		"maxFormulas", quotas.MaxFormulas,
+		"maxIngredients", quotas.MaxIngredients,
+		"maxStorageBytes", quotas.MaxStorageBytes,
+	)
+}
+
+// quotaError reports a create refused by a quota. Its message is written for
+// the user.
+type quotaError struct {
+	message string
+}
+
+func (e quotaError) Error() string {
+	return e.message
+}
+
+// quotaMessage returns the user-facing message when err is a quotaError.
+func quotaMessage(err error) (string, bool) {
+	var quota quotaError
+	if errors.As(err, &quota) {
+		return quota.message, true
+	}
+	return "", false
+}
+
+// checkFormulaQuota refuses adding more formulas than the user may own. Pass
+// adding as zero to check rows already created inside tx.
+func checkFormulaQuota(tx *gorm.DB, userID uint, adding int) error {
+	if userQuotas.MaxFormulas <= 0 || userID == 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Formula{}).Where("created_by_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count)+adding > userQuotas.MaxFormulas {
+		return quotaError{fmt.Sprintf("You have reached the limit of %d formulas. Delete a formula you no longer need to make room.", userQuotas.MaxFormulas)}
+	}
+	return nil
+}
+
+// checkIngredientQuota refuses adding more private ingredients than the user
+// may own.
+func checkIngredientQuota(tx *gorm.DB, userID uint, adding int) error {
+	if userQuotas.MaxIngredients <= 0 || userID == 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.AromaChemical{}).Where("owner_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count)+adding > userQuotas.MaxIngredients {
+		return quotaError{fmt.Sprintf("You have reached the limit of %d ingredients in your library. Delete an ingredient you no longer need to make room.", userQuotas.MaxIngredients)}
+	}
+	return nil
+}
+
+// checkStorageQuota refuses storing size more bytes for the user. The object
+// under replacing, if any, is about to be removed and does not count.
+func checkStorageQuota(ctx context.Context, profile pages.UserProfile, replacing string, size int64) error {
+	if userQuotas.MaxStorageBytes <= 0 || fileStore == nil {
+		return nil
+	}
+	used := int64(0)
+	for _, key := range userStorageKeys(profile) {
+		if key == replacing {
+			continue
+		}
+		n, err := fileStore.Size(ctx, key)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		used += n
+	}
+	if used+size > userQuotas.MaxStorageBytes {
+		return quotaError{fmt.Sprintf("This upload would take you past your %s storage limit.", formatBytes(userQuotas.MaxStorageBytes))}
+	}
+	return nil
+}
+
+// userStorageKeys lists the stored objects that count towards a user's
+// storage quota.
+func userStorageKeys(profile pages.UserProfile) []string {
+	if profile.AvatarKey == "" {
+		return nil
+	}
+	return []string{profile.AvatarKey}
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
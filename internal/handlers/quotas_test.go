@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/storage"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+func withTestQuotas(t *testing.T, quotas Quotas) {
+	t.Helper()
+	prev := userQuotas
+	userQuotas = quotas
+	t.Cleanup(func() { userQuotas = prev })
+}
+
+func TestFormulaCreateEnforcesQuota(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	withTestQuotas(t, Quotas{MaxFormulas: 1})
+
+	// Formulas without a creator predate quotas and are not counted.
+	if err := db.Create(&models.Formula{Name: "Legacy", Version: 1}).Error; err != nil {
+		t.Fatalf("create legacy formula: %v", err)
+	}
+
+	create := func() string {
+		rec := httptest.NewRecorder()
+		FormulaCreate(rec, authenticatedFormRequest(t, sm, "/app/sections/formulas/create", url.Values{}, 3))
+		return rec.Body.String()
+	}
+
+	create()
+	var owned int64
+	db.Model(&models.Formula{}).Where("created_by_id = ?", 3).Count(&owned)
+	if owned != 1 {
+		t.Fatalf("expected the first formula to be created for the user, got %d", owned)
+	}
+
+	if body := create(); !strings.Contains(body, "limit of 1 formulas") {
+		t.Fatalf("expected quota message, got %q", body)
+	}
+	db.Model(&models.Formula{}).Where("created_by_id = ?", 3).Count(&owned)
+	if owned != 1 {
+		t.Fatalf("quota allowed another formula: %d", owned)
+	}
+}
+
+func TestIngredientCreateEnforcesQuota(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	withTestQuotas(t, Quotas{MaxIngredients: 1})
+
+	if err := db.Create(&models.AromaChemical{IngredientName: "Hedione", OwnerID: 4}).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	IngredientCreate(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/create", url.Values{
+		"ingredient_name":  {"Iso E Super"},
+		"pyramid_position": {"base"},
+	}, 4))
+	if !strings.Contains(rec.Body.String(), "limit of 1 ingredients") {
+		t.Fatalf("expected quota message, got %q", rec.Body.String())
+	}
+	var count int64
+	db.Model(&models.AromaChemical{}).Where("owner_id = ?", 4).Count(&count)
+	if count != 1 {
+		t.Fatalf("quota allowed another ingredient: %d", count)
+	}
+}
+
+func TestCheckStorageQuota(t *testing.T) {
+	store, err := storage.NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFilesystem: %v", err)
+	}
+	prevStore := fileStore
+	fileStore = store
+	t.Cleanup(func() { fileStore = prevStore })
+	withTestQuotas(t, Quotas{MaxStorageBytes: 1 << 10})
+
+	ctx := context.Background()
+	if err := store.Put(ctx, "avatars/1-old.png", make([]byte, 800)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	profile := pages.UserProfile{ID: 1, AvatarKey: "avatars/1-old.png"}
+
+	if err := checkStorageQuota(ctx, profile, "", 300); err == nil {
+		t.Fatal("expected an upload on top of the avatar to exceed the quota")
+	} else if message, ok := quotaMessage(err); !ok || !strings.Contains(message, "1 KB") {
+		t.Fatalf("unexpected quota error %v", err)
+	}
+	if err := checkStorageQuota(ctx, profile, profile.AvatarKey, 1000); err != nil {
+		t.Fatalf("replacing the avatar should not count it twice: %v", err)
+	}
+}
@@ -11,8 +11,7 @@ import (
 	"strings"
 	"time"
 
-	"gorm.io/gorm"
-
+	"perfugo/internal/analytics"
 	applog "perfugo/internal/log"
 	"perfugo/internal/views/pages"
 	"perfugo/models"
@@ -33,68 +32,134 @@ func GenerateBatchProductionReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid submission.", http.StatusBadRequest)
+	formulaID, targetQuantity, finish, problem := parseBatchForm(r)
+	if problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
 		return
 	}
 
+	ctx, cancel := withDeadline(r, handlerTimeouts.Report)
+	defer cancel()
+	report, err := buildBatchProductionReportData(ctx, formulaID, targetQuantity, finish)
+	if err != nil {
+		writeBatchReportError(w, r, err, formulaID)
+		return
+	}
+
+	recordActivity(r.Context(), models.ActivityEntityFormula, formulaID, analytics.EventBatch)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.BatchProductionReport(report).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render batch production report", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseBatchForm reads the formula, target quantity and finish shared by the
+// batch report and the weighing workflow, or returns a message explaining
+// why the submission cannot be used.
+func parseBatchForm(r *http.Request) (uint, float64, *batchFinish, string) {
+	if err := r.ParseForm(); err != nil {
+		return 0, 0, nil, "Invalid submission."
+	}
+
 	formulaID := pages.ParseUint(r.FormValue("formula_id"))
 	if formulaID == 0 {
-		http.Error(w, "Select a formula before running the report.", http.StatusBadRequest)
-		return
+		return 0, 0, nil, "Select a formula before running the report."
 	}
 
 	targetQuantity, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("target_quantity")), 64)
 	if err != nil || targetQuantity <= 0 {
-		http.Error(w, "Provide a positive target quantity.", http.StatusBadRequest)
-		return
+		return 0, 0, nil, "Provide a positive target quantity."
 	}
 
-	report, err := buildBatchProductionReportData(r.Context(), formulaID, targetQuantity)
-	if err != nil {
-		switch {
-		case errors.Is(err, gorm.ErrInvalidDB):
-			http.Error(w, "Reporting is unavailable because no database connection is configured.", http.StatusServiceUnavailable)
-		case errors.Is(err, errBatchFormulaNotFound):
-			http.Error(w, "The selected formula no longer exists.", http.StatusNotFound)
-		case errors.Is(err, errBatchInvalidQuantity):
-			http.Error(w, "The target quantity cannot be computed for this formula.", http.StatusBadRequest)
-		case errors.Is(err, errBatchEmptyComposition):
-			http.Error(w, "The selected formula has no ingredients to report.", http.StatusBadRequest)
-		case errors.Is(err, errBatchCircularReference):
-			http.Error(w, "The formula has a circular dependency and cannot be expanded.", http.StatusBadRequest)
-		default:
-			applog.Error(r.Context(), "failed to build batch production report", "error", err, "formulaID", formulaID)
-			http.Error(w, "We were unable to generate the batch report. Please try again.", http.StatusInternalServerError)
-		}
+	finish, problem := resolveBatchFinish(r)
+	if problem != "" {
+		return 0, 0, nil, problem
+	}
+	return formulaID, targetQuantity, finish, ""
+}
+
+// writeBatchReportError maps a batch expansion failure to an HTTP response.
+func writeBatchReportError(w http.ResponseWriter, r *http.Request, err error, formulaID uint) {
+	if contextExpired(err) {
+		writeTimeout(w, r, nil)
 		return
 	}
+	status, message := batchReportFailure(r, err, formulaID)
+	http.Error(w, message, status)
+}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := pages.BatchProductionReport(report).Render(r.Context(), w); err != nil {
-		applog.Error(r.Context(), "failed to render batch production report", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// batchReportFailure returns the status and user-facing message for a batch
+// expansion failure, logging errors that are not the caller's fault.
+func batchReportFailure(r *http.Request, err error, formulaID uint) (int, string) {
+	switch {
+	case errors.Is(err, errBatchFormulaNotFound):
+		return http.StatusNotFound, "The selected formula no longer exists."
+	case errors.Is(err, errBatchInvalidQuantity):
+		return http.StatusBadRequest, "The target quantity cannot be computed for this formula."
+	case errors.Is(err, errBatchEmptyComposition):
+		return http.StatusBadRequest, "The selected formula has no ingredients to report."
+	case errors.Is(err, errBatchCircularReference):
+		return http.StatusBadRequest, "The formula has a circular dependency and cannot be expanded."
+	default:
+		applog.Error(r.Context(), "failed to build batch production report", "error", err, "formulaID", formulaID)
+		return http.StatusInternalServerError, "We were unable to generate the batch report. Please try again."
 	}
 }
 
-func buildBatchProductionReportData(ctx context.Context, formulaID uint, targetQuantity float64) (pages.BatchProductionReportData, error) {
-	if database == nil {
-		return pages.BatchProductionReportData{}, gorm.ErrInvalidDB
+// batchFinish dilutes the scaled concentrate into a finished product: the
+// concentrate makes up ConcentratePercent of the target and Solvent the rest.
+// With ByVolume the percentage is read as v/v and converted to weights.
+type batchFinish struct {
+	Label              string
+	ConcentratePercent float64
+	ByVolume           bool
+	Solvent            models.Solvent
+}
+
+// resolveBatchFinish reads the concentration preset and alcohol basis from the
+// report form. It returns nil for a concentrate-only batch, or a message
+// explaining why the selection cannot be used.
+func resolveBatchFinish(r *http.Request) (*batchFinish, string) {
+	return batchFinishFor(r, r.FormValue("concentration_preset"), r.FormValue("alcohol_basis"))
+}
+
+// batchFinishFor resolves a concentration preset and alcohol basis against
+// the current user's production defaults.
+func batchFinishFor(r *http.Request, preset, basis string) (*batchFinish, string) {
+	preset = strings.ToLower(strings.TrimSpace(preset))
+	if preset == "" || preset == "none" {
+		return nil, ""
+	}
+
+	defaults := loadProductionDefaults(r)
+	finish := &batchFinish{
+		ByVolume: strings.EqualFold(strings.TrimSpace(basis), "volume"),
+		Solvent:  models.SolventByID(defaults.Solvent),
 	}
 
-	var formula models.Formula
-	if err := database.WithContext(ctx).First(&formula, formulaID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return pages.BatchProductionReportData{}, errBatchFormulaNotFound
+	if preset == "saved" {
+		if !defaults.HasConcentration() {
+			return nil, "Set a target concentration in your preferences before running a finished-product report."
 		}
-		return pages.BatchProductionReportData{}, err
+		finish.Label = "Saved default"
+		finish.ConcentratePercent = defaults.Concentration
+		return finish, ""
 	}
 
-	var ingredients []models.FormulaIngredient
-	if err := database.WithContext(ctx).
-		Preload("AromaChemical").
-		Preload("SubFormula").
-		Find(&ingredients).Error; err != nil {
+	found, ok := models.ConcentrationPresetByID(preset)
+	if !ok {
+		return nil, "Choose one of the listed concentration presets."
+	}
+	finish.Label = found.Label
+	finish.ConcentratePercent = found.Percent
+	return finish, ""
+}
+
+func buildBatchProductionReportData(ctx context.Context, formulaID uint, targetQuantity float64, finish *batchFinish) (pages.BatchProductionReportData, error) {
+	formula, ingredients, err := loadReportComposition(ctx, formulaID)
+	if err != nil {
 		return pages.BatchProductionReportData{}, err
 	}
 
@@ -114,6 +179,12 @@ func buildBatchProductionReportData(ctx context.Context, formulaID uint, targetQ
 	}
 
 	targetQuantityMg := targetQuantity
+	if finish != nil {
+		if finish.ConcentratePercent <= 0 || finish.ConcentratePercent >= 100 {
+			return pages.BatchProductionReportData{}, errBatchInvalidQuantity
+		}
+		targetQuantityMg = targetQuantity * models.ConcentrateMassFraction(finish.ConcentratePercent, finish.ByVolume, finish.Solvent)
+	}
 	targetQuantityGrams := targetQuantityMg / 1000.0
 	if targetQuantityGrams <= 0 {
 		return pages.BatchProductionReportData{}, errBatchInvalidQuantity
@@ -127,7 +198,7 @@ func buildBatchProductionReportData(ctx context.Context, formulaID uint, targetQ
 		return pages.BatchProductionReportData{}, errBatchInvalidQuantity
 	}
 
-	accumulator := make(map[uint]*reportIngredientTotal)
+	accumulator := make(map[reportIngredientKey]*reportIngredientTotal)
 	traversal := make(map[uint]bool)
 	accumulate := func(id uint, factor float64) error {
 		return accumulateFormulaIngredients(ctx, id, factor, byFormula, accumulator, computeTotals, traversal)
@@ -151,19 +222,44 @@ func buildBatchProductionReportData(ctx context.Context, formulaID uint, targetQ
 		if finalQuantity <= 0 {
 			continue
 		}
+		neatQuantity := finalQuantity
+		dilution, dilutionSolvent := "", ""
+		if total.Stock != nil && total.Stock.Diluted() {
+			neatQuantity = finalQuantity * total.Stock.NeatFraction()
+			dilution = total.Stock.DilutionLabel()
+			dilutionSolvent = models.SolventByID(total.Stock.Solvent).ID
+		}
 		reportIngredients = append(reportIngredients, pages.BatchProductionReportIngredient{
-			IngredientName: total.Chemical.IngredientName,
-			CASNumber:      strings.TrimSpace(total.Chemical.CASNumber),
-			Pyramid:        pages.CanonicalPyramidPosition(total.Chemical.PyramidPosition),
-			PyramidLabel:   pages.PyramidPositionLabel(total.Chemical.PyramidPosition),
-			FinalQuantity:  math.Round(finalQuantity),
-			BaseQuantity:   math.Round(total.BaseAmount * 1000.0),
-			Unit:           "mg",
+			ChemicalID:      total.Chemical.ID,
+			IngredientName:  total.Chemical.IngredientName,
+			Dilution:        dilution,
+			DilutionSolvent: dilutionSolvent,
+			NeatQuantity:    math.Round(neatQuantity),
+			CASNumber:       strings.TrimSpace(total.Chemical.CASNumber),
+			Pyramid:         pages.CanonicalPyramidPosition(total.Chemical.PyramidPosition),
+			PyramidLabel:    pages.PyramidPositionLabel(total.Chemical.PyramidPosition),
+			FinalQuantity:   math.Round(finalQuantity),
+			BaseQuantity:    math.Round(total.BaseAmount * 1000.0),
+			Unit:            "mg",
+			PricePerMg:      total.Chemical.PricePerMg,
 		})
 	}
 
 	sortBatchProductionIngredients(reportIngredients)
 
+	var solventQuantity float64
+	if finish != nil {
+		solventQuantity = math.Round(targetQuantity - targetQuantityMg)
+		reportIngredients = append(reportIngredients, pages.BatchProductionReportIngredient{
+			IngredientName: finish.Solvent.Label,
+			CASNumber:      finish.Solvent.CASNumber,
+			PyramidLabel:   "—",
+			FinalQuantity:  solventQuantity,
+			Unit:           "mg",
+			Solvent:        true,
+		})
+	}
+
 	for idx := range reportIngredients {
 		reportIngredients[idx].Order = idx + 1
 	}
@@ -181,12 +277,28 @@ func buildBatchProductionReportData(ctx context.Context, formulaID uint, targetQ
 		RunDate:           runTime,
 		Ingredients:       reportIngredients,
 	}
+	if finish != nil {
+		data.ConcentrateLabel = finish.Label
+		data.ConcentratePercent = finish.ConcentratePercent
+		data.ConcentrateByVolume = finish.ByVolume
+		data.ConcentrateQuantity = math.Round(targetQuantityMg)
+		data.SolventName = finish.Solvent.Label
+		data.SolventQuantity = solventQuantity
+	}
 
 	return data, nil
 }
 
+// reportIngredientKey separates neat material from each stock solution of
+// the same chemical, since they are weighed out as different lines.
+type reportIngredientKey struct {
+	ChemicalID      uint
+	StockSolutionID uint
+}
+
 type reportIngredientTotal struct {
 	Chemical   *models.AromaChemical
+	Stock      *models.InventoryItem
 	BaseAmount float64
 }
 
@@ -225,10 +337,13 @@ func accumulateFormulaIngredients(
 	formulaID uint,
 	factor float64,
 	source map[uint][]models.FormulaIngredient,
-	accumulator map[uint]*reportIngredientTotal,
+	accumulator map[reportIngredientKey]*reportIngredientTotal,
 	totalResolver func(uint) (float64, error),
 	path map[uint]bool,
 ) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if path[formulaID] {
 		return errBatchCircularReference
 	}
@@ -254,10 +369,17 @@ func accumulateFormulaIngredients(
 				}
 				chemical = &fetched
 			}
-			total, ok := accumulator[*ing.AromaChemicalID]
+			key := reportIngredientKey{ChemicalID: *ing.AromaChemicalID}
+			if ing.StockSolutionID != nil && ing.StockSolution != nil {
+				key.StockSolutionID = *ing.StockSolutionID
+			}
+			total, ok := accumulator[key]
 			if !ok {
 				total = &reportIngredientTotal{Chemical: chemical}
-				accumulator[*ing.AromaChemicalID] = total
+				if key.StockSolutionID != 0 {
+					total.Stock = ing.StockSolution
+				}
+				accumulator[key] = total
 			}
 			total.BaseAmount += amount
 			continue
@@ -86,7 +86,7 @@ func TestBuildBatchProductionReportDataScalesAndConsolidates(t *testing.T) {
 		t.Fatalf("create parent subformula ingredient: %v", err)
 	}
 
-	report, err := buildBatchProductionReportData(ctx, parentFormula.ID, 30000)
+	report, err := buildBatchProductionReportData(ctx, parentFormula.ID, 30000, nil)
 	if err != nil {
 		t.Fatalf("buildBatchProductionReportData returned error: %v", err)
 	}
@@ -122,3 +122,119 @@ func TestBuildBatchProductionReportDataScalesAndConsolidates(t *testing.T) {
 		t.Fatalf("expected lot number with PERF- prefix, got %s", report.LotNumber)
 	}
 }
+
+func TestBuildBatchProductionReportDataAppendsSolventLine(t *testing.T) {
+	ctx := context.Background()
+	db := newToolsTestDB(t)
+
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	chemical := models.AromaChemical{IngredientName: "Iso E Super", CASNumber: "54464-57-2", OwnerID: 1}
+	if err := db.WithContext(ctx).Create(&chemical).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	formula := models.Formula{Name: "Cedar Veil", Version: 1, IsLatest: true}
+	if err := db.WithContext(ctx).Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	if err := db.WithContext(ctx).Create(&models.FormulaIngredient{
+		FormulaID:       formula.ID,
+		AromaChemicalID: &chemical.ID,
+		Amount:          10,
+		Unit:            "g",
+	}).Error; err != nil {
+		t.Fatalf("create ingredient: %v", err)
+	}
+
+	finish := &batchFinish{ConcentratePercent: 20, Solvent: models.SolventByID(models.SolventEthanol)}
+	report, err := buildBatchProductionReportData(ctx, formula.ID, 50000, finish)
+	if err != nil {
+		t.Fatalf("buildBatchProductionReportData returned error: %v", err)
+	}
+
+	if report.ConcentrateQuantity != 10000 {
+		t.Fatalf("expected concentrate 10000 mg, got %.0f", report.ConcentrateQuantity)
+	}
+	if len(report.Ingredients) != 2 {
+		t.Fatalf("expected concentrate and solvent lines, got %d", len(report.Ingredients))
+	}
+	if report.Ingredients[0].FinalQuantity != 10000 {
+		t.Fatalf("expected Iso E Super 10000 mg, got %.0f", report.Ingredients[0].FinalQuantity)
+	}
+	solvent := report.Ingredients[1]
+	if !solvent.Solvent || solvent.IngredientName != "Ethanol" || solvent.CASNumber != "64-17-5" {
+		t.Fatalf("unexpected solvent line: %+v", solvent)
+	}
+	if solvent.FinalQuantity != 40000 || solvent.Order != 2 {
+		t.Fatalf("expected solvent 40000 mg as line 2, got %.0f at %d", solvent.FinalQuantity, solvent.Order)
+	}
+
+	byVolume := &batchFinish{ConcentratePercent: 20, ByVolume: true, Solvent: models.SolventByID(models.SolventEthanol)}
+	report, err = buildBatchProductionReportData(ctx, formula.ID, 50000, byVolume)
+	if err != nil {
+		t.Fatalf("buildBatchProductionReportData by volume returned error: %v", err)
+	}
+	// 20 mL concentrate at 1.0 g/mL in 80 mL ethanol at 0.789 g/mL.
+	wantConcentrate := math.Round(50000 * 20 / (20 + 80*0.789))
+	if report.ConcentrateQuantity != wantConcentrate {
+		t.Fatalf("expected concentrate %.0f mg by volume, got %.0f", wantConcentrate, report.ConcentrateQuantity)
+	}
+	if report.ConcentrationSummary() != "20% by volume" {
+		t.Fatalf("unexpected concentration summary %q", report.ConcentrationSummary())
+	}
+
+	if _, err := buildBatchProductionReportData(ctx, formula.ID, 50000, &batchFinish{ConcentratePercent: 100}); err != errBatchInvalidQuantity {
+		t.Fatalf("expected errBatchInvalidQuantity for a 100%% concentrate, got %v", err)
+	}
+}
+
+func TestBuildBatchProductionReportDataConvertsStockSolutions(t *testing.T) {
+	ctx := context.Background()
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate inventory: %v", err)
+	}
+
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	cashmeran := models.AromaChemical{IngredientName: "Cashmeran", OwnerID: 1}
+	if err := db.Create(&cashmeran).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	stock := models.InventoryItem{OwnerID: 1, AromaChemicalID: cashmeran.ID, QuantityMg: 20000, DilutionPercent: 10, Solvent: models.SolventDPG}
+	if err := db.Create(&stock).Error; err != nil {
+		t.Fatalf("create stock solution: %v", err)
+	}
+	formula := models.Formula{Name: "Soft Wood", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	lines := []models.FormulaIngredient{
+		{FormulaID: formula.ID, AromaChemicalID: &cashmeran.ID, Amount: 2, Unit: "g"},
+		{FormulaID: formula.ID, AromaChemicalID: &cashmeran.ID, StockSolutionID: &stock.ID, Amount: 8, Unit: "g"},
+	}
+	if err := db.Create(&lines).Error; err != nil {
+		t.Fatalf("create ingredients: %v", err)
+	}
+
+	report, err := buildBatchProductionReportData(ctx, formula.ID, 10000, nil)
+	if err != nil {
+		t.Fatalf("buildBatchProductionReportData returned error: %v", err)
+	}
+	if len(report.Ingredients) != 2 || !report.HasStockSolutions() {
+		t.Fatalf("expected separate neat and stock solution lines, got %+v", report.Ingredients)
+	}
+
+	diluted := report.Ingredients[0]
+	if diluted.Dilution != "10% in DPG" || diluted.FinalQuantity != 8000 || diluted.NeatQuantity != 800 {
+		t.Fatalf("unexpected stock solution line: %+v", diluted)
+	}
+	neat := report.Ingredients[1]
+	if neat.Dilution != "" || neat.FinalQuantity != 2000 || neat.NeatQuantity != 2000 {
+		t.Fatalf("unexpected neat line: %+v", neat)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// maxRequestIDLength bounds the X-Request-ID accepted from a client or proxy.
+const maxRequestIDLength = 64
+
+// RequestID tags every request with an ID, echoed in the X-Request-ID
+// response header and attached to everything logged for the request,
+// including its SQL statements. An ID set by a proxy is kept when it is
+// short and plain; otherwise a new one is made.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = models.NewUUID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(applog.WithRequestID(r.Context(), id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
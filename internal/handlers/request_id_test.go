@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	applog "perfugo/internal/log"
+)
+
+func TestRequestIDKeepsOrGeneratesID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = applog.RequestID(r.Context())
+	}))
+	send := func(id string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("X-Request-ID"); got != seen {
+			t.Fatalf("response header %q, context %q", got, seen)
+		}
+		return seen
+	}
+
+	if got := send("edge-1.abc_2"); got != "edge-1.abc_2" {
+		t.Fatalf("expected the proxy ID to be kept, got %q", got)
+	}
+	for _, id := range []string{"", "bad id\nx", strings.Repeat("a", 65)} {
+		if got := send(id); got == "" || got == id {
+			t.Fatalf("expected a new ID for %q, got %q", id, got)
+		}
+	}
+}
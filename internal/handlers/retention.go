@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"perfugo/internal/jobs"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// retentionReports is how many past purges the admin panel lists.
+const retentionReports = 5
+
+var retentionPolicy jobs.RetentionPolicy
+
+// ConfigureRetention sets the retention policy shown to administrators and
+// applied when they purge on demand.
+func ConfigureRetention(policy jobs.RetentionPolicy) {
+	retentionPolicy = policy
+	applog.Debug(context.Background(), "retention policy configured", "enabled", policy.Enabled())
+}
+
+// loadRetentionPanel describes the retention policy and the latest purges.
+func loadRetentionPanel(ctx context.Context) pages.RetentionPanel {
+	days := func(d time.Duration) int { return int(d / (24 * time.Hour)) }
+	panel := pages.RetentionPanel{
+		Rules: []pages.RetentionRule{
+			{Label: "Audit events", Days: days(retentionPolicy.Audit)},
+			{Label: "AI exchanges", Days: days(retentionPolicy.AIExchanges)},
+			{Label: "Deleted records", Days: days(retentionPolicy.Deleted)},
+		},
+	}
+	if database == nil {
+		return panel
+	}
+	if err := database.WithContext(ctx).
+		Where("action = ?", models.AuditRetentionPurged).
+		Order("created_at desc").
+		Limit(retentionReports).
+		Find(&panel.Purges).Error; err != nil {
+		applog.Error(ctx, "failed to load retention purges", "error", err)
+	}
+	return panel
+}
+
+// RetentionRun lets administrators purge expired records now instead of
+// waiting for the scheduled job.
+func RetentionRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	message := "No retention is configured, so every record is kept."
+	if retentionPolicy.Enabled() {
+		report, err := jobs.PurgeExpired(ctx, database, nowFunc(), retentionPolicy)
+		switch {
+		case err != nil:
+			applog.Error(ctx, "failed to purge expired records", "error", err)
+			message = "Some records could not be purged; see the server log."
+		case report.Total() == 0:
+			message = "Nothing was old enough to purge."
+		default:
+			message = "Purged " + report.String() + "."
+		}
+	}
+	panel := loadRetentionPanel(ctx)
+	panel.Message = message
+	renderComponent(w, r, pages.RetentionControl(panel))
+}
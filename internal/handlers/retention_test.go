@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/jobs"
+	"perfugo/models"
+)
+
+func TestRetentionRunPurgesAndReports(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.AIExchange{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB, prevPolicy := database, retentionPolicy
+	database = db
+	t.Cleanup(func() { database, retentionPolicy = prevDB, prevPolicy })
+
+	old := nowFunc().AddDate(0, 0, -45)
+	if err := db.Create(&models.AIExchange{Model: gorm.Model{CreatedAt: old}, Operation: "profile"}).Error; err != nil {
+		t.Fatalf("seed exchange: %v", err)
+	}
+
+	run := func() string {
+		rec := httptest.NewRecorder()
+		RetentionRun(rec, authenticatedFormRequest(t, sm, "/app/admin/retention/run", nil, 1))
+		return rec.Body.String()
+	}
+
+	ConfigureRetention(jobs.RetentionPolicy{})
+	if body := run(); !strings.Contains(body, "No retention is configured") {
+		t.Fatalf("expected nothing to run without a policy, got %q", body)
+	}
+
+	ConfigureRetention(jobs.RetentionPolicy{AIExchanges: 30 * 24 * time.Hour})
+	body := run()
+	if !strings.Contains(body, "Purged 1 AI exchange.") || !strings.Contains(body, "30 days") {
+		t.Fatalf("expected the purge to be reported, got %q", body)
+	}
+	if body := run(); !strings.Contains(body, "Nothing was old enough to purge.") || !strings.Contains(body, "Purged 1 AI exchange.") {
+		t.Fatalf("expected the earlier purge to stay listed, got %q", body)
+	}
+}
@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	scimContentType = "application/scim+json"
+	scimUsersPath   = "/scim/v2/Users"
+
+	scimUserSchema  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimListSchema  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	// scimMaxBody bounds POST, PUT and PATCH request bodies.
+	scimMaxBody = 1 << 16
+	// scimMaxCount caps the page size a list request may ask for.
+	scimMaxCount = 200
+)
+
+var (
+	scimToken string
+
+	scimUserNameFilter = regexp.MustCompile(`^\s*userName\s+eq\s+"([^"]*)"\s*$`)
+)
+
+// ConfigureSCIM sets the bearer token identity providers must present. An
+// empty token disables the SCIM endpoint.
+func ConfigureSCIM(token string) {
+	scimToken = strings.TrimSpace(token)
+	applog.Debug(nil, "scim provisioning configured", "enabled", scimToken != "")
+}
+
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+	Type    string `json:"type,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	Location     string `json:"location"`
+}
+
+// scimUser is the SCIM 2.0 core User representation.
+type scimUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *scimName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []scimEmail `json:"emails,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Meta        *scimMeta   `json:"meta,omitempty"`
+}
+
+type scimListResponse struct {
+	Schemas      []string   `json:"schemas"`
+	TotalResults int        `json:"totalResults"`
+	StartIndex   int        `json:"startIndex"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []scimUser `json:"Resources"`
+}
+
+type scimPatchRequest struct {
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+type scimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// RequireSCIMToken authenticates identity provider requests with the configured bearer token.
+func RequireSCIMToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scimToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(presented)), []byte(scimToken)) != 1 {
+			applog.Debug(r.Context(), "scim request rejected: invalid bearer token")
+			writeSCIMError(w, r, http.StatusUnauthorized, "", "A valid bearer token is required.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SCIMUsers implements the SCIM 2.0 /Users resource for automatic provisioning.
+// Deleting a user deactivates the account rather than removing its data.
+func SCIMUsers(w http.ResponseWriter, r *http.Request) {
+	if database == nil {
+		writeSCIMError(w, r, http.StatusServiceUnavailable, "", pages.DemoModeMessage)
+		return
+	}
+
+	rawID := strings.Trim(strings.TrimPrefix(r.URL.Path, scimUsersPath), "/")
+	if rawID == "" {
+		switch r.Method {
+		case http.MethodGet:
+			scimListUsers(w, r)
+		case http.MethodPost:
+			scimCreateUser(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || id == 0 {
+		writeSCIMError(w, r, http.StatusNotFound, "", "User not found.")
+		return
+	}
+	user := &models.User{}
+	if err := database.WithContext(r.Context()).First(user, uint(id)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeSCIMError(w, r, http.StatusNotFound, "", "User not found.")
+			return
+		}
+		applog.Error(r.Context(), "failed to load scim user", "error", err, "userID", id)
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to load the user.")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeSCIM(w, r, http.StatusOK, toSCIMUser(*user))
+	case http.MethodPut:
+		scimReplaceUser(w, r, user)
+	case http.MethodPatch:
+		scimPatchUser(w, r, user)
+	case http.MethodDelete:
+		if err := saveSCIMUser(r, user, setUserActive(user, false)); err != nil {
+			writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to deactivate the user.")
+			return
+		}
+		applog.Info(r.Context(), "user deprovisioned via scim", "userID", user.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func scimListUsers(w http.ResponseWriter, r *http.Request) {
+	query := database.WithContext(r.Context()).Model(&models.User{}).Order("id asc")
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		match := scimUserNameFilter.FindStringSubmatch(filter)
+		if match == nil {
+			writeSCIMError(w, r, http.StatusBadRequest, "invalidFilter", "Only 'userName eq \"value\"' filters are supported.")
+			return
+		}
+		query = query.Where("lower(email) = ?", strings.ToLower(match[1]))
+	}
+
+	startIndex := max(1, parsePositiveInt(r.URL.Query().Get("startIndex"), 1))
+	count := min(parsePositiveInt(r.URL.Query().Get("count"), 100), scimMaxCount)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		applog.Error(r.Context(), "failed to count scim users", "error", err)
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to list users.")
+		return
+	}
+	var users []models.User
+	if err := query.Offset(startIndex - 1).Limit(count).Find(&users).Error; err != nil {
+		applog.Error(r.Context(), "failed to list scim users", "error", err)
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to list users.")
+		return
+	}
+
+	response := scimListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: int(total),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(users),
+		Resources:    make([]scimUser, 0, len(users)),
+	}
+	for _, user := range users {
+		response.Resources = append(response.Resources, toSCIMUser(user))
+	}
+	writeSCIM(w, r, http.StatusOK, response)
+}
+
+func scimCreateUser(w http.ResponseWriter, r *http.Request) {
+	var payload scimUser
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, scimMaxBody)).Decode(&payload); err != nil {
+		writeSCIMError(w, r, http.StatusBadRequest, "invalidSyntax", "The request body is not valid SCIM JSON.")
+		return
+	}
+	email := scimEmailAddress(payload)
+	if email == "" {
+		writeSCIMError(w, r, http.StatusBadRequest, "invalidValue", "userName must be an email address.")
+		return
+	}
+
+	if _, err := findUserByEmail(r, email); err == nil {
+		writeSCIMError(w, r, http.StatusConflict, "uniqueness", "A user with this userName already exists.")
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to create the user.")
+		return
+	}
+
+	hashed, err := unusablePasswordHash()
+	if err != nil {
+		applog.Error(r.Context(), "failed to prepare scim user credentials", "error", err)
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to create the user.")
+		return
+	}
+	user := &models.User{
+		Email:        email,
+		Name:         scimDisplayName(payload),
+		PasswordHash: hashed,
+		Theme:        models.DefaultTheme,
+		Role:         models.RoleMember,
+		ExternalID:   strings.TrimSpace(payload.ExternalID),
+	}
+	if payload.Active != nil && !*payload.Active {
+		setUserActive(user, false)
+	}
+	if err := database.WithContext(r.Context()).Create(user).Error; err != nil {
+		applog.Error(r.Context(), "failed to create scim user", "error", err)
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to create the user.")
+		return
+	}
+
+	applog.Info(r.Context(), "user provisioned via scim", "userID", user.ID)
+	w.Header().Set("Location", scimUserLocation(*user))
+	writeSCIM(w, r, http.StatusCreated, toSCIMUser(*user))
+}
+
+func scimReplaceUser(w http.ResponseWriter, r *http.Request, user *models.User) {
+	var payload scimUser
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, scimMaxBody)).Decode(&payload); err != nil {
+		writeSCIMError(w, r, http.StatusBadRequest, "invalidSyntax", "The request body is not valid SCIM JSON.")
+		return
+	}
+	email := scimEmailAddress(payload)
+	if email == "" {
+		writeSCIMError(w, r, http.StatusBadRequest, "invalidValue", "userName must be an email address.")
+		return
+	}
+
+	if !scimEmailAvailable(w, r, email, user.ID) {
+		return
+	}
+
+	updates := map[string]any{
+		"email":       email,
+		"name":        scimDisplayName(payload),
+		"external_id": strings.TrimSpace(payload.ExternalID),
+	}
+	active := payload.Active == nil || *payload.Active
+	for column, value := range setUserActive(user, active) {
+		updates[column] = value
+	}
+	if err := saveSCIMUser(r, user, updates); err != nil {
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to update the user.")
+		return
+	}
+	writeSCIM(w, r, http.StatusOK, toSCIMUser(*user))
+}
+
+func scimPatchUser(w http.ResponseWriter, r *http.Request, user *models.User) {
+	var patch scimPatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, scimMaxBody)).Decode(&patch); err != nil {
+		writeSCIMError(w, r, http.StatusBadRequest, "invalidSyntax", "The request body is not a valid PatchOp.")
+		return
+	}
+
+	updates := map[string]any{}
+	for _, operation := range patch.Operations {
+		switch strings.ToLower(operation.Op) {
+		case "replace", "add":
+		default:
+			writeSCIMError(w, r, http.StatusBadRequest, "invalidValue", fmt.Sprintf("Unsupported patch operation %q.", operation.Op))
+			return
+		}
+
+		values := map[string]json.RawMessage{}
+		if operation.Path != "" {
+			values[operation.Path] = operation.Value
+		} else if err := json.Unmarshal(operation.Value, &values); err != nil {
+			writeSCIMError(w, r, http.StatusBadRequest, "invalidValue", "Patch values must be an object when no path is given.")
+			return
+		}
+
+		for path, raw := range values {
+			switch strings.ToLower(path) {
+			case "active":
+				active, ok := scimBool(raw)
+				if !ok {
+					writeSCIMError(w, r, http.StatusBadRequest, "invalidValue", "active must be a boolean.")
+					return
+				}
+				for column, value := range setUserActive(user, active) {
+					updates[column] = value
+				}
+			case "username":
+				var email string
+				if json.Unmarshal(raw, &email) != nil || !strings.Contains(email, "@") {
+					writeSCIMError(w, r, http.StatusBadRequest, "invalidValue", "userName must be an email address.")
+					return
+				}
+				updates["email"] = strings.ToLower(strings.TrimSpace(email))
+			case "displayname", "name.formatted":
+				var name string
+				if json.Unmarshal(raw, &name) != nil {
+					writeSCIMError(w, r, http.StatusBadRequest, "invalidValue", "displayName must be a string.")
+					return
+				}
+				updates["name"] = strings.TrimSpace(name)
+			case "externalid":
+				var externalID string
+				if json.Unmarshal(raw, &externalID) != nil {
+					writeSCIMError(w, r, http.StatusBadRequest, "invalidValue", "externalId must be a string.")
+					return
+				}
+				updates["external_id"] = strings.TrimSpace(externalID)
+			default:
+				applog.Debug(r.Context(), "ignoring unsupported scim patch path", "path", path)
+			}
+		}
+	}
+
+	if email, ok := updates["email"].(string); ok && !scimEmailAvailable(w, r, email, user.ID) {
+		return
+	}
+	if err := saveSCIMUser(r, user, updates); err != nil {
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to update the user.")
+		return
+	}
+	writeSCIM(w, r, http.StatusOK, toSCIMUser(*user))
+}
+
+// scimEmailAvailable reports whether no account other than userID uses
+// email, writing a 409 uniqueness error when one does. Sign-ins resolve
+// accounts by email, so a rename must not create a duplicate.
+func scimEmailAvailable(w http.ResponseWriter, r *http.Request, email string, userID uint) bool {
+	var count int64
+	if err := database.WithContext(r.Context()).Model(&models.User{}).
+		Where("LOWER(email) = ? AND id <> ?", strings.ToLower(email), userID).Count(&count).Error; err != nil {
+		applog.Error(r.Context(), "failed to check scim userName", "error", err, "userID", userID)
+		writeSCIMError(w, r, http.StatusInternalServerError, "", "Unable to update the user.")
+		return false
+	}
+	if count > 0 {
+		writeSCIMError(w, r, http.StatusConflict, "uniqueness", "A user with this userName already exists.")
+		return false
+	}
+	return true
+}
+
+// setUserActive updates the in-memory user and returns the matching column updates.
+func setUserActive(user *models.User, active bool) map[string]any {
+	switch {
+	case active && !user.IsActive():
+		user.DeactivatedAt = nil
+	case !active && user.IsActive():
+		now := nowFunc()
+		user.DeactivatedAt = &now
+	default:
+		return map[string]any{}
+	}
+	return map[string]any{"deactivated_at": user.DeactivatedAt}
+}
+
+func saveSCIMUser(r *http.Request, user *models.User, updates map[string]any) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := database.WithContext(r.Context()).Model(user).Updates(updates).Error; err != nil {
+		applog.Error(r.Context(), "failed to save scim user", "error", err, "userID", user.ID)
+		return err
+	}
+	var reloaded models.User
+	if err := database.WithContext(r.Context()).First(&reloaded, user.ID).Error; err != nil {
+		return err
+	}
+	*user = reloaded
+	return nil
+}
+
+func toSCIMUser(user models.User) scimUser {
+	active := user.IsActive()
+	return scimUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          strconv.FormatUint(uint64(user.ID), 10),
+		ExternalID:  user.ExternalID,
+		UserName:    user.Email,
+		Name:        &scimName{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []scimEmail{{Value: user.Email, Primary: true, Type: "work"}},
+		Active:      &active,
+		Meta: &scimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: user.UpdatedAt.UTC().Format(time.RFC3339),
+			Location:     scimUserLocation(user),
+		},
+	}
+}
+
+func scimUserLocation(user models.User) string {
+	return pages.Path(fmt.Sprintf("%s/%d", scimUsersPath, user.ID))
+}
+
+func scimEmailAddress(payload scimUser) string {
+	candidates := []string{payload.UserName}
+	for _, email := range payload.Emails {
+		if email.Primary {
+			candidates = append([]string{email.Value}, candidates...)
+		} else {
+			candidates = append(candidates, email.Value)
+		}
+	}
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(strings.TrimSpace(candidate))
+		if strings.Contains(candidate, "@") {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func scimDisplayName(payload scimUser) string {
+	if name := strings.TrimSpace(payload.DisplayName); name != "" {
+		return name
+	}
+	if payload.Name == nil {
+		return ""
+	}
+	if name := strings.TrimSpace(payload.Name.Formatted); name != "" {
+		return name
+	}
+	return strings.TrimSpace(payload.Name.GivenName + " " + payload.Name.FamilyName)
+}
+
+// scimBool accepts JSON booleans and the string forms some identity providers send.
+func scimBool(raw json.RawMessage) (bool, bool) {
+	var value bool
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value, true
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return false, false
+	}
+	parsed, err := strconv.ParseBool(strings.TrimSpace(text))
+	return parsed, err == nil
+}
+
+func parsePositiveInt(value string, def int) int {
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || parsed <= 0 {
+		return def
+	}
+	return parsed
+}
+
+func writeSCIM(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		applog.Error(r.Context(), "failed to encode scim response", "error", err)
+	}
+}
+
+func writeSCIMError(w http.ResponseWriter, r *http.Request, status int, scimType, detail string) {
+	applog.Debug(r.Context(), "responding with scim error", "status", status, "scimType", scimType, "detail", detail)
+	writeSCIM(w, r, status, scimError{
+		Schemas:  []string{scimErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func withSCIMTestEnvironment(t *testing.T) (*gorm.DB, http.Handler) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:scim-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	originalDB, originalToken := database, scimToken
+	database = db
+	ConfigureSCIM("secret-token")
+	t.Cleanup(func() {
+		database = originalDB
+		scimToken = originalToken
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle(scimUsersPath, RequireSCIMToken(http.HandlerFunc(SCIMUsers)))
+	mux.Handle(scimUsersPath+"/", RequireSCIMToken(http.HandlerFunc(SCIMUsers)))
+	return db, mux
+}
+
+func scimRequest(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("Content-Type", scimContentType)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSCIMRequiresBearerToken(t *testing.T) {
+	_, handler := withSCIMTestEnvironment(t)
+
+	req := httptest.NewRequest(http.MethodGet, scimUsersPath, nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+
+	ConfigureSCIM("")
+	rec = scimRequest(t, handler, http.MethodGet, scimUsersPath, "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when scim is disabled, got %d", rec.Code)
+	}
+}
+
+func TestSCIMUserLifecycle(t *testing.T) {
+	db, handler := withSCIMTestEnvironment(t)
+
+	rec := scimRequest(t, handler, http.MethodPost, scimUsersPath, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "Ada@Example.com",
+		"externalId": "ext-1",
+		"name": {"givenName": "Ada", "familyName": "Lovelace"},
+		"active": true
+	}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created scimUser
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created user: %v", err)
+	}
+	if created.UserName != "ada@example.com" || created.DisplayName != "Ada Lovelace" || created.Active == nil || !*created.Active {
+		t.Fatalf("unexpected created user %+v", created)
+	}
+
+	rec = scimRequest(t, handler, http.MethodPost, scimUsersPath, `{"userName": "ada@example.com"}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate: expected 409, got %d", rec.Code)
+	}
+
+	rec = scimRequest(t, handler, http.MethodGet, scimUsersPath+`?filter=userName+eq+"ADA@example.com"`, "")
+	var list scimListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || list.TotalResults != 1 {
+		t.Fatalf("filter: expected one result, got %s (%v)", rec.Body.String(), err)
+	}
+
+	userPath := scimUsersPath + "/" + created.ID
+	rec = scimRequest(t, handler, http.MethodPatch, userPath, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "Replace", "path": "active", "value": "False"}, {"op": "replace", "value": {"displayName": "A. Lovelace"}}]
+	}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var stored models.User
+	if err := db.First(&stored, "email = ?", "ada@example.com").Error; err != nil {
+		t.Fatalf("reload user: %v", err)
+	}
+	if stored.IsActive() || stored.Name != "A. Lovelace" {
+		t.Fatalf("expected deactivated, renamed user, got %+v", stored)
+	}
+
+	rec = scimRequest(t, handler, http.MethodPut, userPath, `{"userName": "ada@example.com", "displayName": "Ada", "active": true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put: expected 200, got %d", rec.Code)
+	}
+	var reactivated models.User
+	if err := db.First(&reactivated, stored.ID).Error; err != nil || !reactivated.IsActive() {
+		t.Fatalf("expected user to be reactivated, got %+v (%v)", reactivated, err)
+	}
+
+	rec = scimRequest(t, handler, http.MethodDelete, userPath, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", rec.Code)
+	}
+	var deprovisioned models.User
+	if err := db.First(&deprovisioned, stored.ID).Error; err != nil || deprovisioned.IsActive() {
+		t.Fatalf("expected delete to deactivate the user, got %+v (%v)", deprovisioned, err)
+	}
+
+	rec = scimRequest(t, handler, http.MethodGet, scimUsersPath+"/9999", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing user: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestSCIMRejectsDuplicateUserNamesAndLargeRequests(t *testing.T) {
+	db, handler := withSCIMTestEnvironment(t)
+
+	ada := models.User{Email: "ada@example.com", PasswordHash: "x"}
+	bo := models.User{Email: "bo@example.com", PasswordHash: "x"}
+	for _, user := range []*models.User{&ada, &bo} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("seed user: %v", err)
+		}
+	}
+	boPath := fmt.Sprintf("%s/%d", scimUsersPath, bo.ID)
+
+	rec := scimRequest(t, handler, http.MethodPut, boPath, `{"userName": "ADA@example.com"}`)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "uniqueness") {
+		t.Fatalf("put: expected 409 uniqueness, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = scimRequest(t, handler, http.MethodPatch, boPath, `{"Operations": [{"op": "replace", "path": "userName", "value": "ada@example.com"}]}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("patch: expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = scimRequest(t, handler, http.MethodPut, boPath, `{"userName": "Bo@example.com", "displayName": "Bo"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put own userName: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stored models.User
+	if err := db.First(&stored, bo.ID).Error; err != nil || stored.Email != "bo@example.com" {
+		t.Fatalf("expected bo to keep their email, got %+v (%v)", stored, err)
+	}
+
+	rec = scimRequest(t, handler, http.MethodPost, scimUsersPath, `{"userName": "big@example.com", "displayName": "`+strings.Repeat("x", scimMaxBody)+`"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("oversized body: expected 400, got %d", rec.Code)
+	}
+
+	extra := make([]models.User, scimMaxCount)
+	for i := range extra {
+		extra[i] = models.User{Email: fmt.Sprintf("member-%d@example.com", i), PasswordHash: "x"}
+	}
+	if err := db.CreateInBatches(extra, 100).Error; err != nil {
+		t.Fatalf("seed users: %v", err)
+	}
+	rec = scimRequest(t, handler, http.MethodGet, scimUsersPath+"?count=100000", "")
+	var list scimListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || list.ItemsPerPage != scimMaxCount || list.TotalResults != scimMaxCount+2 {
+		t.Fatalf("list: unexpected response %s (%v)", rec.Body.String(), err)
+	}
+}
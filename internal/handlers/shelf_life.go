@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/notify"
+	"perfugo/internal/views/emails"
+	"perfugo/models"
+)
+
+// SendExpiryAlert tells a user that opened materials in their inventory are
+// about to expire or have expired: a toast in any open tab and, when mail is
+// configured, an email listing them. Only a failed email is reported, so the
+// alert is retried later.
+func SendExpiryAlert(ctx context.Context, ownerID uint, items []models.InventoryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	message := "1 material in your inventory is reaching the end of its shelf life."
+	if len(items) > 1 {
+		message = fmt.Sprintf("%d materials in your inventory are reaching the end of their shelf life.", len(items))
+	}
+	notifyUser(ownerID, notify.Toast(notify.LevelWarning, message))
+
+	if mailer == nil || database == nil {
+		return nil
+	}
+	var owner models.User
+	if err := database.WithContext(ctx).First(&owner, ownerID).Error; err != nil {
+		return err
+	}
+	if owner.Email == "" {
+		return nil
+	}
+
+	now := nowFunc()
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		name := "Unknown material"
+		if item.AromaChemical != nil {
+			name = item.AromaChemical.IngredientName
+		}
+		if label := item.DilutionLabel(); label != "" {
+			name += " " + label
+		}
+		expires, _ := item.ExpiresOn()
+		verb := "expires"
+		if item.ExpiryState(now) == models.InventoryExpired {
+			verb = "expired"
+		}
+		lines = append(lines, fmt.Sprintf("%s · %s %s", name, verb, expires.Format("02 Jan 2006")))
+	}
+
+	msg, err := emails.Notification("Materials reaching their shelf life", emails.NotificationData{
+		Title: "Materials reaching their shelf life",
+		Intro: fmt.Sprintf("These opened materials expire within %d days or already have. Check them before weighing them into a batch.", models.ExpiryWarningDays),
+		Lines: lines,
+	}).Message(ctx, owner.Email)
+	if err == nil {
+		err = mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		return err
+	}
+	applog.Debug(ctx, "expiry alert emailed", "userID", ownerID, "items", len(items))
+	return nil
+}
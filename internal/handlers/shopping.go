@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// maxShelfLifeMonths bounds the shelf life accepted for an inventory item.
+const maxShelfLifeMonths = 600
+
+// plannedBatch is a formula and target quantity (mg) submitted for planning.
+type plannedBatch struct {
+	FormulaID uint
+	Quantity  float64
+}
+
+// ShoppingList consolidates the materials needed for one or more planned
+// batches, subtracts the current user's inventory and groups the shortfall by
+// supplier. Passing format=csv downloads the list instead of rendering it.
+func ShoppingList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid submission.", http.StatusBadRequest)
+		return
+	}
+
+	batches, problem := parsePlannedBatches(r)
+	if problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := currentUserID(r)
+	ctx, cancel := withDeadline(r, handlerTimeouts.Report)
+	defer cancel()
+	list, failedID, err := buildShoppingList(ctx, userID, batches)
+	if err != nil {
+		writeBatchReportError(w, r, err, failedID)
+		return
+	}
+
+	if strings.EqualFold(r.FormValue("format"), "csv") {
+		writeShoppingListCSV(w, r, list)
+		return
+	}
+	renderComponent(w, r, pages.ShoppingListResult(list))
+}
+
+// parsePlannedBatches pairs the repeated formula_id and target_quantity
+// fields, skipping rows left blank.
+func parsePlannedBatches(r *http.Request) ([]plannedBatch, string) {
+	formulaIDs := r.Form["formula_id"]
+	quantities := r.Form["target_quantity"]
+
+	var batches []plannedBatch
+	for idx, rawID := range formulaIDs {
+		formulaID := pages.ParseUint(rawID)
+		rawQuantity := ""
+		if idx < len(quantities) {
+			rawQuantity = strings.TrimSpace(quantities[idx])
+		}
+		if formulaID == 0 && rawQuantity == "" {
+			continue
+		}
+		if formulaID == 0 {
+			return nil, "Select a formula for every planned batch."
+		}
+		quantity, err := strconv.ParseFloat(rawQuantity, 64)
+		if err != nil || quantity <= 0 {
+			return nil, "Provide a positive target quantity for every planned batch."
+		}
+		batches = append(batches, plannedBatch{FormulaID: formulaID, Quantity: quantity})
+	}
+	if len(batches) == 0 {
+		return nil, "Plan at least one batch before building a shopping list."
+	}
+	return batches, ""
+}
+
+// buildShoppingList expands each batch, totals the requirement per material
+// and subtracts the owner's stock. On failure it also returns the formula
+// whose expansion failed.
+func buildShoppingList(ctx context.Context, ownerID uint, batches []plannedBatch) (pages.ShoppingList, uint, error) {
+	var list pages.ShoppingList
+	required := make(map[uint]*pages.ShoppingListLine)
+	for _, batch := range batches {
+		report, err := buildBatchProductionReportData(ctx, batch.FormulaID, batch.Quantity, nil)
+		if err != nil {
+			return pages.ShoppingList{}, batch.FormulaID, err
+		}
+		list.Batches = append(list.Batches, pages.PlannedBatch{FormulaName: report.FormulaName, Quantity: report.TargetQuantity})
+		for _, item := range report.Ingredients {
+			line, ok := required[item.ChemicalID]
+			if !ok {
+				line = &pages.ShoppingListLine{
+					ChemicalID:     item.ChemicalID,
+					IngredientName: item.IngredientName,
+					CASNumber:      item.CASNumber,
+				}
+				required[item.ChemicalID] = line
+			}
+			line.RequiredMg += item.NeatQuantity
+		}
+	}
+
+	ids := make([]uint, 0, len(required))
+	for id := range required {
+		ids = append(ids, id)
+	}
+
+	prices := make(map[uint]float64, len(ids))
+	var chemicals []models.AromaChemical
+	if err := database.WithContext(ctx).Select("id", "price_per_mg").Where("id IN ?", ids).Find(&chemicals).Error; err != nil {
+		return pages.ShoppingList{}, 0, err
+	}
+	for _, chemical := range chemicals {
+		prices[chemical.ID] = chemical.PricePerMg
+	}
+
+	onHand := make(map[uint]float64, len(ids))
+	suppliers := make(map[uint]string, len(ids))
+	if ownerID != 0 {
+		var stock []models.InventoryItem
+		if err := database.WithContext(ctx).
+			Where("owner_id = ? AND aroma_chemical_id IN ?", ownerID, ids).
+			Order("id asc").
+			Find(&stock).Error; err != nil {
+			return pages.ShoppingList{}, 0, err
+		}
+		for _, item := range stock {
+			onHand[item.AromaChemicalID] += item.NeatQuantityMg()
+			if suppliers[item.AromaChemicalID] == "" {
+				suppliers[item.AromaChemicalID] = strings.TrimSpace(item.Supplier)
+			}
+		}
+	}
+
+	groups := make(map[string]*pages.ShoppingListGroup)
+	for id, line := range required {
+		line.OnHandMg = onHand[id]
+		line.ToBuyMg = line.RequiredMg - line.OnHandMg
+		if line.ToBuyMg <= 0 {
+			list.Covered++
+			continue
+		}
+		line.EstimatedCost = line.ToBuyMg * prices[id]
+
+		supplier := suppliers[id]
+		if supplier == "" {
+			supplier = pages.UnassignedSupplier
+		}
+		group, ok := groups[supplier]
+		if !ok {
+			group = &pages.ShoppingListGroup{Supplier: supplier}
+			groups[supplier] = group
+		}
+		group.Lines = append(group.Lines, *line)
+		group.EstimatedCost += line.EstimatedCost
+	}
+
+	for _, group := range groups {
+		sort.Slice(group.Lines, func(i, j int) bool {
+			return strings.ToLower(group.Lines[i].IngredientName) < strings.ToLower(group.Lines[j].IngredientName)
+		})
+		list.Groups = append(list.Groups, *group)
+	}
+	sort.Slice(list.Groups, func(i, j int) bool {
+		a, b := list.Groups[i].Supplier, list.Groups[j].Supplier
+		if (a == pages.UnassignedSupplier) != (b == pages.UnassignedSupplier) {
+			return b == pages.UnassignedSupplier
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+	return list, 0, nil
+}
+
+func writeShoppingListCSV(w http.ResponseWriter, r *http.Request, list pages.ShoppingList) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="shopping-list.csv"`)
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"Supplier", "Ingredient", "CAS", "Required (mg)", "On hand (mg)", "To buy (mg)", "Estimated cost"})
+	for _, group := range list.Groups {
+		for _, line := range group.Lines {
+			cost := ""
+			if line.EstimatedCost > 0 {
+				cost = strconv.FormatFloat(line.EstimatedCost, 'f', 2, 64)
+			}
+			_ = writer.Write([]string{
+				group.Supplier,
+				line.IngredientName,
+				line.CASNumber,
+				strconv.FormatFloat(line.RequiredMg, 'f', 0, 64),
+				strconv.FormatFloat(line.OnHandMg, 'f', 0, 64),
+				strconv.FormatFloat(line.ToBuyMg, 'f', 0, 64),
+				cost,
+			})
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		applog.Error(r.Context(), "failed to write shopping list csv", "error", err)
+	}
+}
+
+// InventoryUpdate records the quantity on hand and supplier for one of the
+// current user's materials, replacing any previous figure. Supplying a
+// dilution percentage below 100 records a stock solution in the chosen solvent
+// instead of neat material, and each supplier lot is kept separately with its
+// storage location. An opening date and shelf life in months let the
+// card flag the material as it nears expiry.
+func InventoryUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	chemicalID := pages.ParseUint(r.FormValue("aroma_chemical_id"))
+	quantity, err := parseOptionalFloat(r.FormValue("quantity_mg"))
+	if chemicalID == 0 || err != nil || quantity < 0 {
+		renderInventory(w, r, userID, "Choose a material and a quantity of zero or more.")
+		return
+	}
+
+	var chemical models.AromaChemical
+	if err := database.WithContext(ctx).
+		Where("id = ? AND (owner_id = ? OR public = ?)", chemicalID, userID, true).
+		First(&chemical).Error; err != nil {
+		renderInventory(w, r, userID, "That material is not in your library.")
+		return
+	}
+
+	dilution, err := parseOptionalFloat(r.FormValue("dilution_percent"))
+	if err != nil || dilution < 0 || dilution > 100 {
+		renderInventory(w, r, userID, "Dilution must be a percentage between 0 and 100.")
+		return
+	}
+	shelfLife, err := parseOptionalFloat(r.FormValue("shelf_life_months"))
+	if err != nil || shelfLife < 0 || shelfLife > maxShelfLifeMonths || shelfLife != math.Trunc(shelfLife) {
+		renderInventory(w, r, userID, "Shelf life must be a whole number of months.")
+		return
+	}
+	openedOn, err := pages.ParseDateInput(r.FormValue("opened_on"))
+	if err != nil {
+		renderInventory(w, r, userID, "Enter the opening date as a date.")
+		return
+	}
+	solvent := ""
+	if dilution > 0 && dilution < 100 {
+		solvent = models.SolventByID(r.FormValue("solvent")).ID
+	} else {
+		dilution = 0
+	}
+
+	lot := strings.TrimSpace(r.FormValue("lot_number"))
+	var item models.InventoryItem
+	if err := database.WithContext(ctx).
+		Where("owner_id = ? AND aroma_chemical_id = ? AND dilution_percent = ? AND COALESCE(solvent, '') = ? AND COALESCE(lot_number, '') = ?", userID, chemicalID, dilution, solvent, lot).
+		Attrs(models.InventoryItem{OwnerID: userID, AromaChemicalID: chemicalID, DilutionPercent: dilution, Solvent: solvent, LotNumber: lot}).
+		FirstOrInit(&item).Error; err != nil {
+		applog.Error(ctx, "failed to load inventory item", "error", err, "userID", userID)
+		http.Error(w, "unable to save inventory", http.StatusInternalServerError)
+		return
+	}
+	item.QuantityMg = quantity
+	item.Supplier = strings.TrimSpace(r.FormValue("supplier"))
+	item.Location = strings.TrimSpace(r.FormValue("location"))
+	if item.ShelfLifeMonths != int(shelfLife) || !sameDate(item.OpenedOn, openedOn) {
+		// A new opening or shelf life is a new expiry to announce.
+		item.ExpiryNotifiedAt = nil
+	}
+	item.ShelfLifeMonths = int(shelfLife)
+	item.OpenedOn = openedOn
+	if err := database.WithContext(ctx).Save(&item).Error; err != nil {
+		applog.Error(ctx, "failed to save inventory item", "error", err, "userID", userID)
+		http.Error(w, "unable to save inventory", http.StatusInternalServerError)
+		return
+	}
+	applog.Debug(ctx, "inventory updated", "userID", userID, "chemicalID", chemicalID, "quantityMg", quantity)
+
+	renderInventory(w, r, userID, "Stock saved for "+chemical.IngredientName+".")
+}
+
+func renderInventory(w http.ResponseWriter, r *http.Request, userID uint, message string) {
+	panel := loadInventoryPanel(r.Context(), userID)
+	panel.Message = message
+	renderComponent(w, r, pages.InventoryControl(panel, loadAromaChemicals(r.Context(), userID)))
+}
+
+// loadInventoryPanel lists the owner's stocked materials by name.
+func loadInventoryPanel(ctx context.Context, ownerID uint) pages.InventoryPanel {
+	var panel pages.InventoryPanel
+	if database == nil || ownerID == 0 {
+		return panel
+	}
+
+	var items []models.InventoryItem
+	if err := database.WithContext(ctx).
+		Preload("AromaChemical").
+		Where("owner_id = ?", ownerID).
+		Find(&items).Error; err != nil {
+		applog.Error(ctx, "failed to load inventory", "error", err, "userID", ownerID)
+		return panel
+	}
+	now := nowFunc()
+	expiring := make([]models.InventoryItem, 0)
+	for _, item := range items {
+		if item.AromaChemical == nil {
+			continue
+		}
+		panel.Items = append(panel.Items, inventoryRow(item, now))
+		if item.ExpiryState(now) != models.InventoryFresh {
+			expiring = append(expiring, item)
+		}
+	}
+	sort.SliceStable(panel.Items, func(i, j int) bool {
+		a, b := strings.ToLower(panel.Items[i].IngredientName), strings.ToLower(panel.Items[j].IngredientName)
+		if a != b {
+			return a < b
+		}
+		if panel.Items[i].Dilution != panel.Items[j].Dilution {
+			return panel.Items[i].Dilution < panel.Items[j].Dilution
+		}
+		return panel.Items[i].LotNumber < panel.Items[j].LotNumber
+	})
+	sort.SliceStable(expiring, func(i, j int) bool {
+		a, _ := expiring[i].ExpiresOn()
+		b, _ := expiring[j].ExpiresOn()
+		return a.Before(b)
+	})
+	for _, item := range expiring {
+		panel.Expiring = append(panel.Expiring, inventoryRow(item, now))
+	}
+	return panel
+}
+
+func inventoryRow(item models.InventoryItem, now time.Time) pages.InventoryRow {
+	row := pages.InventoryRow{
+		ChemicalID:     item.AromaChemicalID,
+		IngredientName: item.AromaChemical.IngredientName,
+		Dilution:       item.DilutionLabel(),
+		QuantityMg:     item.QuantityMg,
+		Supplier:       item.Supplier,
+		Location:       item.Location,
+		LotNumber:      item.LotNumber,
+		Expiry:         item.ExpiryState(now),
+	}
+	if expires, ok := item.ExpiresOn(); ok {
+		row.Expires = expires.Format("02 Jan 2006")
+	}
+	return row
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
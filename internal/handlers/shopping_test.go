@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+func TestBuildShoppingListSubtractsInventoryAndGroupsBySupplier(t *testing.T) {
+	ctx := context.Background()
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate inventory: %v", err)
+	}
+
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	const ownerID = 7
+	hedione := models.AromaChemical{IngredientName: "Hedione", OwnerID: ownerID, PricePerMg: 0.01}
+	iso := models.AromaChemical{IngredientName: "Iso E Super", OwnerID: ownerID}
+	ambrox := models.AromaChemical{IngredientName: "Ambrox", OwnerID: ownerID}
+	for _, chemical := range []*models.AromaChemical{&hedione, &iso, &ambrox} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+
+	first := models.Formula{Name: "Morning", Version: 1, IsLatest: true}
+	second := models.Formula{Name: "Evening", Version: 1, IsLatest: true}
+	for _, formula := range []*models.Formula{&first, &second} {
+		if err := db.Create(formula).Error; err != nil {
+			t.Fatalf("create formula: %v", err)
+		}
+	}
+	lines := []models.FormulaIngredient{
+		{FormulaID: first.ID, AromaChemicalID: &hedione.ID, Amount: 6, Unit: "g"},
+		{FormulaID: first.ID, AromaChemicalID: &iso.ID, Amount: 4, Unit: "g"},
+		{FormulaID: second.ID, AromaChemicalID: &hedione.ID, Amount: 5, Unit: "g"},
+		{FormulaID: second.ID, AromaChemicalID: &ambrox.ID, Amount: 5, Unit: "g"},
+	}
+	if err := db.Create(&lines).Error; err != nil {
+		t.Fatalf("create ingredients: %v", err)
+	}
+
+	stock := []models.InventoryItem{
+		{OwnerID: ownerID, AromaChemicalID: hedione.ID, QuantityMg: 4000, Supplier: "Acme Aromatics"},
+		{OwnerID: ownerID, AromaChemicalID: iso.ID, QuantityMg: 5000, Supplier: "Acme Aromatics"},
+		{OwnerID: ownerID + 1, AromaChemicalID: ambrox.ID, QuantityMg: 99999},
+	}
+	if err := db.Create(&stock).Error; err != nil {
+		t.Fatalf("create inventory: %v", err)
+	}
+
+	list, _, err := buildShoppingList(ctx, ownerID, []plannedBatch{
+		{FormulaID: first.ID, Quantity: 10000},
+		{FormulaID: second.ID, Quantity: 10000},
+	})
+	if err != nil {
+		t.Fatalf("buildShoppingList returned error: %v", err)
+	}
+
+	if len(list.Batches) != 2 || list.Covered != 1 {
+		t.Fatalf("expected 2 batches and 1 covered material, got %d and %d", len(list.Batches), list.Covered)
+	}
+	if len(list.Groups) != 2 {
+		t.Fatalf("expected 2 supplier groups, got %+v", list.Groups)
+	}
+	acme := list.Groups[0]
+	if acme.Supplier != "Acme Aromatics" || len(acme.Lines) != 1 {
+		t.Fatalf("unexpected first group: %+v", acme)
+	}
+	if line := acme.Lines[0]; line.IngredientName != "Hedione" || line.RequiredMg != 11000 || line.ToBuyMg != 7000 {
+		t.Fatalf("unexpected Hedione line: %+v", line)
+	}
+	if acme.EstimatedCost != 70 {
+		t.Fatalf("expected Acme estimate 70, got %.2f", acme.EstimatedCost)
+	}
+	unassigned := list.Groups[1]
+	if unassigned.Supplier != pages.UnassignedSupplier || unassigned.Lines[0].IngredientName != "Ambrox" || unassigned.Lines[0].ToBuyMg != 5000 {
+		t.Fatalf("unexpected unassigned group: %+v", unassigned)
+	}
+
+	recorder := httptest.NewRecorder()
+	writeShoppingListCSV(recorder, httptest.NewRequest(http.MethodPost, "/app/reports/shopping-list", nil), list)
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "Supplier,Ingredient,CAS,") {
+		t.Fatalf("unexpected csv header: %q", body)
+	}
+	if !strings.Contains(body, "Acme Aromatics,Hedione,,11000,4000,7000,70.00") {
+		t.Fatalf("csv missing Hedione row: %q", body)
+	}
+}
+
+func TestParsePlannedBatchesSkipsBlankRows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		want    int
+		problem bool
+	}{
+		{"pairs rows", url.Values{"formula_id": {"1", "", "2"}, "target_quantity": {"100", "", "50"}}, 2, false},
+		{"no batches", url.Values{"formula_id": {""}, "target_quantity": {""}}, 0, true},
+		{"missing quantity", url.Values{"formula_id": {"1"}, "target_quantity": {""}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPost, "/app/reports/shopping-list", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if err := req.ParseForm(); err != nil {
+				t.Fatalf("parse form: %v", err)
+			}
+			batches, problem := parsePlannedBatches(req)
+			if (problem != "") != tt.problem {
+				t.Fatalf("problem = %q, want problem %t", problem, tt.problem)
+			}
+			if len(batches) != tt.want {
+				t.Fatalf("got %d batches, want %d", len(batches), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInventoryPanelFlagsExpiringMaterials(t *testing.T) {
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate inventory: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	prevNow := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = prevNow })
+
+	const ownerID = 3
+	names := []string{"Aldehyde C-12", "Bergamot", "Cedarwood"}
+	opened := []time.Time{now.AddDate(0, -12, 0), now.AddDate(0, -5, -20), now.AddDate(0, -1, 0)}
+	for i, name := range names {
+		chemical := models.AromaChemical{IngredientName: name, OwnerID: ownerID}
+		if err := db.Create(&chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+		item := models.InventoryItem{OwnerID: ownerID, AromaChemicalID: chemical.ID, QuantityMg: 1000, ShelfLifeMonths: 6, OpenedOn: &opened[i]}
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("create inventory: %v", err)
+		}
+	}
+
+	panel := loadInventoryPanel(context.Background(), ownerID)
+	if len(panel.Items) != 3 {
+		t.Fatalf("expected every item listed, got %+v", panel.Items)
+	}
+	if len(panel.Expiring) != 2 {
+		t.Fatalf("expected two flagged materials, got %+v", panel.Expiring)
+	}
+	if first := panel.Expiring[0]; first.IngredientName != "Aldehyde C-12" || first.Expiry != models.InventoryExpired || first.ExpiryLabel() != "expired 01 Dec 2025" {
+		t.Fatalf("expected the expired material first, got %+v", first)
+	}
+	if second := panel.Expiring[1]; second.IngredientName != "Bergamot" || second.Expiry != models.InventoryExpiring {
+		t.Fatalf("expected the expiring material second, got %+v", second)
+	}
+}
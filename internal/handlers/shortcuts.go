@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/layout"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// sessionShortcutsKey caches the user's effective shortcut bindings in the
+// session as JSON so they are not read from the database per request.
+const sessionShortcutsKey = "prefs:shortcuts"
+
+// ShortcutPreferences serves the user's keyboard shortcuts. GET returns
+// every action with its key and default as JSON; POST saves the keys given
+// as shortcut_<action> fields, a blank field restoring the default.
+func ShortcutPreferences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeShortcutsJSON(w, r)
+		return
+	case http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if sessionManager == nil || database == nil {
+		http.Error(w, "preferences not available", http.StatusServiceUnavailable)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	overrides := map[string]string{}
+	bound := map[string]string{}
+	for _, shortcut := range models.Shortcuts {
+		key := shortcut.Default
+		if raw := strings.TrimSpace(r.FormValue("shortcut_" + shortcut.Action)); raw != "" {
+			normalized, ok := models.NormalizeShortcutKey(raw)
+			if !ok {
+				renderShortcuts(w, r, http.StatusBadRequest, loadShortcuts(r), fmt.Sprintf("%s: %q is not a key combination.", shortcut.Label, raw))
+				return
+			}
+			key = normalized
+		}
+		if other, taken := bound[key]; taken {
+			renderShortcuts(w, r, http.StatusBadRequest, loadShortcuts(r), fmt.Sprintf("%s and %s cannot share %s.", other, shortcut.Label, key))
+			return
+		}
+		bound[key] = shortcut.Label
+		if key != shortcut.Default {
+			overrides[shortcut.Action] = key
+		}
+	}
+
+	stored := ""
+	if len(overrides) > 0 {
+		encoded, err := json.Marshal(overrides)
+		if err != nil {
+			http.Error(w, "unable to save preferences", http.StatusInternalServerError)
+			return
+		}
+		stored = string(encoded)
+	}
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("keyboard_shortcuts", stored).Error; err != nil {
+		applog.Error(ctx, "failed to update keyboard shortcuts", "error", err, "userID", userID)
+		http.Error(w, "unable to save preferences", http.StatusInternalServerError)
+		return
+	}
+	bindings := models.ShortcutBindings(overrides)
+	cacheShortcuts(r, bindings)
+	applog.Debug(ctx, "keyboard shortcuts persisted", "userID", userID, "overrides", len(overrides))
+
+	if !isHTMX(r) {
+		http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+		return
+	}
+	renderShortcuts(w, r, http.StatusOK, bindings, "Shortcuts saved. Reload to use them everywhere.")
+}
+
+type shortcutResponse struct {
+	Action  string `json:"action"`
+	Label   string `json:"label"`
+	Key     string `json:"key"`
+	Default string `json:"default"`
+}
+
+func writeShortcutsJSON(w http.ResponseWriter, r *http.Request) {
+	rows := pages.ShortcutRows(loadShortcuts(r))
+	response := make([]shortcutResponse, 0, len(rows))
+	for _, row := range rows {
+		response = append(response, shortcutResponse(row))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		applog.Error(r.Context(), "failed to encode keyboard shortcuts", "error", err)
+	}
+}
+
+func renderShortcuts(w http.ResponseWriter, r *http.Request, status int, bindings map[string]string, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.ShortcutControl(pages.ShortcutRows(bindings), message).Render(r.Context(), w); err != nil {
+		applog.Error(r.Context(), "failed to render shortcut control", "error", err)
+	}
+}
+
+// withShortcuts attaches the signed-in user's shortcut bindings to the
+// request context for the layout; see layout.ShortcutsFrom.
+func withShortcuts(r *http.Request) *http.Request {
+	return r.WithContext(layout.WithShortcuts(r.Context(), loadShortcuts(r)))
+}
+
+// loadShortcuts reads the user's shortcut bindings from the session, falling
+// back to the database once per session and to the defaults without a user.
+func loadShortcuts(r *http.Request) map[string]string {
+	if sessionManager == nil {
+		return models.ShortcutBindings(nil)
+	}
+	ctx := r.Context()
+	if cached := sessionManager.GetString(ctx, sessionShortcutsKey); cached != "" {
+		var bindings map[string]string
+		if err := json.Unmarshal([]byte(cached), &bindings); err == nil {
+			return bindings
+		}
+	}
+	userID, ok := currentUserID(r)
+	if database == nil || !ok {
+		return models.ShortcutBindings(nil)
+	}
+
+	var user models.User
+	if err := database.WithContext(ctx).Select("keyboard_shortcuts").First(&user, userID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			applog.Error(ctx, "failed to load keyboard shortcuts", "error", err, "userID", userID)
+		}
+		return models.ShortcutBindings(nil)
+	}
+	bindings := models.ShortcutBindings(models.ParseShortcutOverrides(user.KeyboardShortcuts))
+	cacheShortcuts(r, bindings)
+	return bindings
+}
+
+func cacheShortcuts(r *http.Request, bindings map[string]string) {
+	if encoded, err := json.Marshal(bindings); err == nil {
+		sessionManager.Put(r.Context(), sessionShortcutsKey, string(encoded))
+	}
+}
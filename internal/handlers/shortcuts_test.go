@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"perfugo/internal/views/layout"
+	"perfugo/models"
+)
+
+func TestShortcutPreferences(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "perfumer@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	save := func(values url.Values) (*httptest.ResponseRecorder, *http.Request) {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/app/preferences/shortcuts", values, int(user.ID))
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+		ShortcutPreferences(rec, req)
+		return rec, req
+	}
+
+	if rec, _ := save(url.Values{"shortcut_save": {"ctrl+shift+?"}}); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "is not a key combination") {
+		t.Fatalf("invalid key: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	if rec, _ := save(url.Values{"shortcut_save": {"/"}}); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "cannot share /") {
+		t.Fatalf("clashing key: status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	rec, req := save(url.Values{"shortcut_new_formula": {"Shift + Ctrl + F"}, "shortcut_search": {""}})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Shortcuts saved.") {
+		t.Fatalf("save: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	if got := loadShortcuts(req)[models.ShortcutNewFormula]; got != "mod+shift+f" {
+		t.Fatalf("session binding = %q, want mod+shift+f", got)
+	}
+	var stored models.User
+	if err := db.First(&stored, user.ID).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if stored.KeyboardShortcuts != `{"new_formula":"mod+shift+f"}` {
+		t.Fatalf("expected only the override to be stored, got %q", stored.KeyboardShortcuts)
+	}
+
+	// A new session reads the overrides from the database and the layout
+	// receives them with the defaults.
+	fresh := authenticatedFormRequest(t, sm, "/app", url.Values{}, int(user.ID))
+	bindings := layout.ShortcutsFrom(withShortcuts(fresh).Context())
+	if bindings[models.ShortcutNewFormula] != "mod+shift+f" || bindings[models.ShortcutSave] != "mod+s" {
+		t.Fatalf("unexpected bindings %v", bindings)
+	}
+
+	get := authenticatedFormRequest(t, sm, "/app/preferences/shortcuts", nil, int(user.ID))
+	get.Method = http.MethodGet
+	rec = httptest.NewRecorder()
+	ShortcutPreferences(rec, get)
+	var listed []shortcutResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("decode shortcuts: %v", err)
+	}
+	if len(listed) != len(models.Shortcuts) || listed[0].Key != "mod+shift+f" || listed[0].Default != "alt+n" {
+		t.Fatalf("unexpected shortcut listing %+v", listed)
+	}
+}
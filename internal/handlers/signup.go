@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,6 +10,7 @@ import (
 
 	applog "perfugo/internal/log"
 	"perfugo/internal/views/pages"
+	"perfugo/models"
 )
 
 // Signup displays the account creation form and processes new registrations.
@@ -24,14 +26,40 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 			redirectToApp(w, r)
 			return
 		}
+		if directory != nil {
+			renderSignup(w, r, directoryManagedAccountsMessage, "", "", "")
+			return
+		}
+		if InviteOnly() {
+			token := strings.TrimSpace(r.URL.Query().Get("invite"))
+			if token == "" {
+				applog.Debug(r.Context(), "signup without invitation while invite-only")
+				renderSignup(w, r, inviteOnlySignupNotice, "", "", "")
+				return
+			}
+			invitation, err := findUsableInvitation(r.Context(), token)
+			if err != nil {
+				applog.Debug(r.Context(), "signup with unusable invitation", "error", err)
+				renderSignup(w, r, invalidInvitationError, "", "", "")
+				return
+			}
+			applog.Debug(r.Context(), "rendering invited signup form", "invitationID", invitation.ID)
+			renderSignup(w, r, "", "", invitation.Email, token)
+			return
+		}
 		applog.Debug(r.Context(), "rendering signup form")
-		renderSignup(w, r, "", "", "")
+		renderSignup(w, r, "", "", "", "")
 	case http.MethodPost:
 		if sessionManager == nil || database == nil {
 			applog.Debug(r.Context(), "registration dependencies unavailable", "hasSession", sessionManager != nil, "hasDatabase", database != nil)
 			http.Error(w, "registration not available", http.StatusServiceUnavailable)
 			return
 		}
+		if directory != nil {
+			applog.Debug(r.Context(), "signup rejected: accounts are managed by the directory")
+			renderSignup(w, r, directoryManagedAccountsMessage, "", "", "")
+			return
+		}
 		applog.Debug(r.Context(), "parsing signup form submission")
 		if err := r.ParseForm(); err != nil {
 			applog.Debug(r.Context(), "failed to parse signup form", "error", err)
@@ -43,47 +71,82 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		email := strings.TrimSpace(r.PostFormValue("email"))
 		password := r.PostFormValue("password")
 		confirm := r.PostFormValue("confirm_password")
+		token := strings.TrimSpace(r.PostFormValue("invite"))
 
 		applog.Debug(r.Context(), "signup form parsed", "email", strings.ToLower(email))
 
+		if !verifyCaptcha(r) {
+			renderSignup(w, r, captchaFailedMessage, name, email, token)
+			return
+		}
+
+		var invitation *models.Invitation
+		if InviteOnly() {
+			found, err := findUsableInvitation(r.Context(), token)
+			if err != nil {
+				applog.Debug(r.Context(), "signup rejected: unusable invitation", "error", err)
+				renderSignup(w, r, invalidInvitationError, "", "", "")
+				return
+			}
+			if found.Email != "" && !strings.EqualFold(found.Email, email) {
+				applog.Debug(r.Context(), "signup rejected: invitation issued for another address", "invitationID", found.ID)
+				renderSignup(w, r, "This invitation was issued for a different email address.", name, found.Email, token)
+				return
+			}
+			invitation = found
+		}
+
 		if email == "" || !strings.Contains(email, "@") {
 			applog.Debug(r.Context(), "invalid signup email", "email", email)
-			renderSignup(w, r, "Please provide a valid email address.", name, email)
+			renderSignup(w, r, "Please provide a valid email address.", name, email, token)
 			return
 		}
 		if len(password) < 8 {
 			applog.Debug(r.Context(), "password too short for signup", "length", len(password))
-			renderSignup(w, r, "Password must be at least 8 characters long.", name, email)
+			renderSignup(w, r, "Password must be at least 8 characters long.", name, email, token)
 			return
 		}
 		if password != confirm {
 			applog.Debug(r.Context(), "signup password mismatch")
-			renderSignup(w, r, "Passwords do not match.", name, email)
+			renderSignup(w, r, "Passwords do not match.", name, email, token)
 			return
 		}
 
 		if _, err := findUserByEmail(r, email); err == nil {
 			applog.Debug(r.Context(), "signup attempted with existing email", "email", strings.ToLower(email))
-			renderSignup(w, r, "An account with that email already exists.", name, email)
+			renderSignup(w, r, "An account with that email already exists.", name, email, token)
 			return
 		} else if err != nil && err != gorm.ErrRecordNotFound {
 			applog.Error(r.Context(), "failed to check existing user", "error", err)
-			renderSignup(w, r, "We couldn't create your account right now. Please try again.", name, email)
+			renderSignup(w, r, "We couldn't create your account right now. Please try again.", name, email, token)
 			return
 		}
 
-		user, err := createUser(r, email, name, password)
+		var (
+			user *models.User
+			err  error
+		)
+		if invitation != nil {
+			user, err = acceptInvitation(r, invitation, email, name, password)
+		} else {
+			user, err = createUser(r, email, name, password)
+		}
+		if errors.Is(err, errInvitationUnusable) {
+			renderSignup(w, r, invalidInvitationError, "", "", "")
+			return
+		}
 		if err != nil {
 			applog.Error(r.Context(), "failed to create user", "error", err)
-			renderSignup(w, r, "We couldn't create your account right now. Please try again.", name, email)
+			renderSignup(w, r, "We couldn't create your account right now. Please try again.", name, email, token)
 			return
 		}
 
 		applog.Debug(r.Context(), "user created via signup", "userID", user.ID, "email", user.Email)
+		seedNewAccount(r.Context(), user)
 
 		if err := establishSession(r, user); err != nil {
 			applog.Error(r.Context(), "failed to establish session after signup", "error", err)
-			renderSignup(w, r, "We couldn't sign you in after creating your account. Please try again.", name, email)
+			renderSignup(w, r, "We couldn't sign you in after creating your account. Please try again.", name, email, token)
 			return
 		}
 
@@ -95,14 +158,18 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func renderSignup(w http.ResponseWriter, r *http.Request, message, name, email string) {
+// renderSignup renders the signup page. The form is hidden when accounts come
+// from a directory, or when signup is invite-only and no invitation token
+// accompanies the request.
+func renderSignup(w http.ResponseWriter, r *http.Request, message, name, email, invite string) {
+	closed := directory != nil || (InviteOnly() && invite == "")
 	var component templ.Component
 	if isHTMX(r) {
-		applog.Debug(r.Context(), "rendering HTMX signup partial", "messagePresent", message != "")
-		component = pages.SignupPartial(message, name, email)
+		applog.Debug(r.Context(), "rendering HTMX signup partial", "messagePresent", message != "", "closed", closed)
+		component = pages.SignupPartial(message, name, email, invite, closed, captchaWidget())
 	} else {
-		applog.Debug(r.Context(), "rendering full signup page", "messagePresent", message != "")
-		component = pages.Signup(message, name, email)
+		applog.Debug(r.Context(), "rendering full signup page", "messagePresent", message != "", "closed", closed)
+		component = pages.Signup(message, name, email, invite, closed, captchaWidget())
 	}
 
 	if err := component.Render(r.Context(), w); err != nil {
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/ai"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	// defaultStaleMonths is the age past which an AI profile is reported as
+	// stale when no policy is configured.
+	defaultStaleMonths = 12
+	// maxStaleMonths bounds the age a member can ask the report for.
+	maxStaleMonths = 120
+)
+
+// StaleProfilePolicy decides which AI-generated profiles the stale profile
+// report lists.
+type StaleProfilePolicy struct {
+	// Months is the age past which a profile is stale unless the member
+	// asks for another.
+	Months int
+	// DeprecatedModels are the models whose profiles are always stale.
+	DeprecatedModels []string
+}
+
+var staleProfilePolicy = StaleProfilePolicy{Months: defaultStaleMonths}
+
+// ConfigureStaleProfiles sets the stale profile policy. A policy without
+// Months keeps the default of twelve months.
+func ConfigureStaleProfiles(policy StaleProfilePolicy) {
+	if policy.Months <= 0 {
+		policy.Months = defaultStaleMonths
+	}
+	staleProfilePolicy = policy
+}
+
+// staleMonths reads the months asked for, falling back to the policy.
+func staleMonths(r *http.Request) int {
+	months, err := strconv.Atoi(strings.TrimSpace(r.FormValue("months")))
+	if err != nil || months < 1 {
+		return staleProfilePolicy.Months
+	}
+	return min(months, maxStaleMonths)
+}
+
+// loadStaleProfiles returns ownerID's AI-generated chemicals fetched more
+// than months ago or by a deprecated model, oldest first.
+func loadStaleProfiles(ctx context.Context, ownerID uint, months int) ([]pages.StaleProfile, error) {
+	if database == nil {
+		return nil, nil
+	}
+	var chemicals []models.AromaChemical
+	if err := database.WithContext(ctx).
+		Where("owner_id = ? AND ai_fetched_at IS NOT NULL", ownerID).
+		Order("ai_fetched_at asc").
+		Find(&chemicals).Error; err != nil {
+		return nil, err
+	}
+	cutoff := nowFunc().AddDate(0, -months, 0)
+	profiles := []pages.StaleProfile{}
+	for _, chemical := range chemicals {
+		if !chemical.StaleAIProfile(cutoff, staleProfilePolicy.DeprecatedModels) {
+			continue
+		}
+		profiles = append(profiles, pages.StaleProfile{
+			Chemical:   chemical,
+			Deprecated: chemical.DeprecatedAIModel(staleProfilePolicy.DeprecatedModels),
+		})
+	}
+	// Deprecated models go first: their profiles are stale however new.
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Deprecated && !profiles[j].Deprecated
+	})
+	return profiles, nil
+}
+
+func renderStaleProfiles(w http.ResponseWriter, r *http.Request, userID uint, months int, message string) {
+	profiles, err := loadStaleProfiles(r.Context(), userID, months)
+	if err != nil {
+		applog.Error(r.Context(), "failed to load stale ai profiles", "error", err, "userID", userID)
+		http.Error(w, "unable to load stale profiles", http.StatusInternalServerError)
+		return
+	}
+	renderComponent(w, r, pages.StaleProfilesPanel(pages.StaleProfileReport{
+		Months:      months,
+		Profiles:    profiles,
+		Message:     message,
+		CanReenrich: openAIClient != nil,
+	}))
+}
+
+// StaleProfiles renders the stale AI profile report of the tools section.
+func StaleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	renderStaleProfiles(w, r, userID, staleMonths(r), "")
+}
+
+// StaleProfileReenrich fetches a fresh AI profile for one of the member's
+// chemicals. The name and sharing of the chemical are kept.
+func StaleProfileReenrich(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	months := staleMonths(r)
+	if openAIClient == nil {
+		renderStaleProfiles(w, r, userID, months, "AI integration is not configured, so profiles cannot be re-enriched.")
+		return
+	}
+	id := pages.ParseUint(r.FormValue("id"))
+	if id == 0 {
+		http.Error(w, "missing ingredient", http.StatusBadRequest)
+		return
+	}
+
+	var chemical models.AromaChemical
+	if err := database.WithContext(r.Context()).First(&chemical, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		applog.Error(r.Context(), "failed to load ingredient to re-enrich", "error", err, "ingredientID", id)
+		http.Error(w, "unable to load ingredient", http.StatusInternalServerError)
+		return
+	}
+	if !canManageChemical(r, chemical.OwnerID, userID) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := withDeadline(r, handlerTimeouts.AI)
+	defer cancel()
+	ctx, jobID := withAIJob(ctx, userID)
+	renderError := func(message string) {
+		renderStaleProfiles(w, r, userID, months, message)
+	}
+
+	profile, err := openAIClient.FetchAromaProfile(ctx, chemical.IngredientName, ai.FetchOptions{})
+	if contextExpired(err) {
+		writeTimeout(w, r, renderError)
+		return
+	}
+	if err != nil {
+		applog.Error(ctx, "ai re-enrich failed", "error", err, "ingredientID", id, "aiJobID", jobID)
+		renderError(fmt.Sprintf("We couldn't fetch a fresh profile for %s. Please try again shortly.", chemical.IngredientName))
+		return
+	}
+	profile.WheelPosition = strings.TrimSpace(profile.WheelPosition)
+	if term, ok := governedTerm(ctx, models.TaxonomyWheel, profile.WheelPosition, chemical.WheelPosition); ok {
+		profile.WheelPosition = term
+	} else {
+		profile.WheelPosition = chemical.WheelPosition
+	}
+
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&chemical).Updates(profileUpdates(profile)).Error; err != nil {
+			return err
+		}
+		return replaceAliases(ctx, tx, chemical.ID, profileAliases(profile))
+	})
+	if contextExpired(err) {
+		writeTimeout(w, r, renderError)
+		return
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to save re-enriched profile", "error", err, "ingredientID", id)
+		renderError(fmt.Sprintf("We couldn't save the fresh profile for %s. Please try again.", chemical.IngredientName))
+		return
+	}
+	applog.Info(ctx, "ingredient re-enriched", "ingredientID", id, "model", profile.Model, "userID", userID)
+	renderStaleProfiles(w, r, userID, months, fmt.Sprintf("Re-enriched %s.", chemical.IngredientName))
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/internal/ai"
+	"perfugo/models"
+)
+
+func TestStaleProfileReenrich(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	prevClient := openAIClient
+	openAIClient = ai.NewMockClient()
+	t.Cleanup(func() { openAIClient = prevClient })
+	prevPolicy := staleProfilePolicy
+	ConfigureStaleProfiles(StaleProfilePolicy{Months: 6, DeprecatedModels: []string{"gpt-3.5-turbo"}})
+	t.Cleanup(func() { staleProfilePolicy = prevPolicy })
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	prevNow := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = prevNow })
+
+	old := now.AddDate(-1, 0, 0)
+	recent := now.AddDate(0, -1, 0)
+	stale := models.AromaChemical{IngredientName: "Hedione", OwnerID: 3, Public: true, AIModel: "gpt-4.1-mini", AIFetchedAt: &old}
+	retired := models.AromaChemical{IngredientName: "Iso E Super", OwnerID: 3, AIModel: "gpt-3.5-turbo", AIFetchedAt: &recent}
+	fresh := models.AromaChemical{IngredientName: "Ambroxan", OwnerID: 3, AIModel: "gpt-4.1-mini", AIFetchedAt: &recent}
+	manual := models.AromaChemical{IngredientName: "Vanillin", OwnerID: 3}
+	for _, chemical := range []*models.AromaChemical{&stale, &retired, &fresh, &manual} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+
+	req := authenticatedFormRequest(t, sm, "/app/sections/tools/stale-profiles", url.Values{}, 3)
+	req.Method = http.MethodGet
+	rec := httptest.NewRecorder()
+	StaleProfiles(rec, req)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "Hedione") || !strings.Contains(body, "Iso E Super") {
+		t.Fatalf("expected stale and deprecated profiles listed: %d %s", rec.Code, body)
+	}
+	if strings.Contains(body, "Ambroxan") || strings.Contains(body, "Vanillin") {
+		t.Fatalf("expected fresh and manual ingredients left out: %s", body)
+	}
+	if strings.Index(body, "Iso E Super") > strings.Index(body, "Hedione") {
+		t.Fatal("expected deprecated models listed first")
+	}
+
+	form := url.Values{"id": {strconv.Itoa(int(stale.ID))}}
+	rec = httptest.NewRecorder()
+	StaleProfileReenrich(rec, authenticatedFormRequest(t, sm, "/app/sections/tools/stale-profiles/reenrich", form, 3))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Re-enriched Hedione.") {
+		t.Fatalf("unexpected re-enrich response: %d %s", rec.Code, rec.Body.String())
+	}
+	var reloaded models.AromaChemical
+	db.First(&reloaded, stale.ID)
+	if reloaded.AIModel != ai.MockModel || reloaded.AIFetchedAt == nil || !reloaded.AIFetchedAt.Equal(now) {
+		t.Fatalf("expected the profile restamped, got %q %v", reloaded.AIModel, reloaded.AIFetchedAt)
+	}
+	if !reloaded.Public || reloaded.IngredientName != "Hedione" {
+		t.Fatalf("expected name and sharing kept: %+v", reloaded)
+	}
+
+	rec = httptest.NewRecorder()
+	StaleProfileReenrich(rec, authenticatedFormRequest(t, sm, "/app/sections/tools/stale-profiles/reenrich", form, 4))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected another member to be refused, got %d", rec.Code)
+	}
+}
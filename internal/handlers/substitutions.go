@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// Substitutions lists the current user's substitution rules, or creates one
+// on POST.
+func Substitutions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := substitutionUser(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		renderSubstitutions(w, r, userID, "")
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form submission", http.StatusBadRequest)
+			return
+		}
+		substitution := models.Substitution{
+			OwnerID:      userID,
+			OriginalName: strings.TrimSpace(r.FormValue("original_name")),
+			SubstituteID: pages.ParseUint(r.FormValue("substitute_id")),
+		}
+		if message := applySubstitutionForm(r, &substitution); message != "" {
+			renderSubstitutions(w, r, userID, message)
+			return
+		}
+		if substitution.OriginalName == "" {
+			renderSubstitutions(w, r, userID, "Name the material being replaced.")
+			return
+		}
+		if !substituteInLibrary(r.Context(), userID, substitution.SubstituteID) {
+			renderSubstitutions(w, r, userID, "Choose a substitute from your library.")
+			return
+		}
+		if err := database.WithContext(r.Context()).Create(&substitution).Error; err != nil {
+			applog.Error(r.Context(), "failed to create substitution", "error", err, "userID", userID)
+			http.Error(w, "unable to save substitution", http.StatusInternalServerError)
+			return
+		}
+		renderSubstitutions(w, r, userID, "Substitution saved.")
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// SubstitutionUpdate changes the ratio and notes of an existing rule.
+func SubstitutionUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := substitutionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	var substitution models.Substitution
+	if err := database.WithContext(r.Context()).
+		Where("id = ? AND owner_id = ?", pages.ParseUint(r.FormValue("id")), userID).
+		First(&substitution).Error; err != nil {
+		renderSubstitutions(w, r, userID, "That substitution no longer exists.")
+		return
+	}
+	if message := applySubstitutionForm(r, &substitution); message != "" {
+		renderSubstitutions(w, r, userID, message)
+		return
+	}
+	if err := database.WithContext(r.Context()).Save(&substitution).Error; err != nil {
+		applog.Error(r.Context(), "failed to update substitution", "error", err, "substitutionID", substitution.ID)
+		http.Error(w, "unable to save substitution", http.StatusInternalServerError)
+		return
+	}
+	renderSubstitutions(w, r, userID, "Substitution updated.")
+}
+
+// SubstitutionDelete removes one of the current user's rules.
+func SubstitutionDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := substitutionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	id := pages.ParseUint(r.FormValue("id"))
+	if err := database.WithContext(r.Context()).
+		Where("id = ? AND owner_id = ?", id, userID).
+		Delete(&models.Substitution{}).Error; err != nil {
+		applog.Error(r.Context(), "failed to delete substitution", "error", err, "substitutionID", id)
+		http.Error(w, "unable to delete substitution", http.StatusInternalServerError)
+		return
+	}
+	renderSubstitutions(w, r, userID, "Substitution removed.")
+}
+
+func substitutionUser(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	if database == nil {
+		writeNoDatabase(w, r)
+		return 0, false
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+	return userID, true
+}
+
+// applySubstitutionForm reads the ratio and notes fields, returning a message
+// when the ratio is unusable. A blank ratio means one-for-one.
+func applySubstitutionForm(r *http.Request, substitution *models.Substitution) string {
+	ratio, err := parseOptionalFloat(r.FormValue("ratio"))
+	if err != nil || ratio < 0 {
+		return "Ratio must be a positive number."
+	}
+	if ratio == 0 {
+		ratio = 1
+	}
+	substitution.Ratio = ratio
+	substitution.Notes = strings.TrimSpace(r.FormValue("notes"))
+	return ""
+}
+
+func substituteInLibrary(ctx context.Context, userID, chemicalID uint) bool {
+	if chemicalID == 0 {
+		return false
+	}
+	var count int64
+	if err := database.WithContext(ctx).
+		Model(&models.AromaChemical{}).
+		Where("id = ? AND (owner_id = ? OR public = ?)", chemicalID, userID, true).
+		Count(&count).Error; err != nil {
+		applog.Error(ctx, "failed to verify substitute", "error", err, "chemicalID", chemicalID)
+		return false
+	}
+	return count > 0
+}
+
+func renderSubstitutions(w http.ResponseWriter, r *http.Request, userID uint, message string) {
+	panel := loadSubstitutionPanel(r.Context(), userID)
+	panel.Message = message
+	renderComponent(w, r, pages.SubstitutionControl(panel, loadAromaChemicals(r.Context(), userID)))
+}
+
+// loadSubstitutions returns the owner's rules with their substitutes loaded.
+func loadSubstitutions(ctx context.Context, ownerID uint) []models.Substitution {
+	var substitutions []models.Substitution
+	if database == nil || ownerID == 0 {
+		return substitutions
+	}
+	if err := database.WithContext(ctx).
+		Preload("Substitute").
+		Preload("Substitute.OtherNames").
+		Where("owner_id = ?", ownerID).
+		Order("id asc").
+		Find(&substitutions).Error; err != nil {
+		applog.Error(ctx, "failed to load substitutions", "error", err, "userID", ownerID)
+	}
+	return substitutions
+}
+
+func loadSubstitutionPanel(ctx context.Context, ownerID uint) pages.SubstitutionPanel {
+	var panel pages.SubstitutionPanel
+	for _, substitution := range loadSubstitutions(ctx, ownerID) {
+		if substitution.Substitute == nil {
+			continue
+		}
+		panel.Rows = append(panel.Rows, pages.SubstitutionRow{
+			ID:             substitution.ID,
+			OriginalName:   substitution.OriginalName,
+			SubstituteName: substitution.Substitute.IngredientName,
+			Ratio:          substitution.Ratio,
+			Notes:          substitution.Notes,
+		})
+	}
+	sort.SliceStable(panel.Rows, func(i, j int) bool {
+		return strings.ToLower(panel.Rows[i].OriginalName) < strings.ToLower(panel.Rows[j].OriginalName)
+	})
+	return panel
+}
+
+// matchSubstitution finds a rule whose original name matches the missing
+// ingredient or one of its aliases.
+func matchSubstitution(substitutions []models.Substitution, name string, aliases []string) *models.Substitution {
+	targets := uniqueAliases(append([]string{name}, aliases...))
+	for idx := range substitutions {
+		substitution := &substitutions[idx]
+		if substitution.Substitute == nil {
+			continue
+		}
+		original := normalizeIngredientName(substitution.OriginalName)
+		for _, target := range targets {
+			if original == target || similarAlias(original, target) {
+				return substitution
+			}
+		}
+	}
+	return nil
+}
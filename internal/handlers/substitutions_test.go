@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+
+	"perfugo/models"
+)
+
+// authenticatedFormRequest builds a form POST carrying a session for userID.
+func authenticatedFormRequest(t *testing.T, sm *scs.SessionManager, path string, form url.Values, userID int) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx, err := sm.Load(req.Context(), "")
+	if err != nil {
+		t.Fatalf("failed to load session context: %v", err)
+	}
+	req = req.WithContext(ctx)
+	sm.Put(req.Context(), sessionAuthenticatedKey, true)
+	sm.Put(req.Context(), sessionUserIDKey, userID)
+	return req
+}
+
+func TestSubstitutionLifecycle(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.Substitution{}); err != nil {
+		t.Fatalf("automigrate substitutions: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	const userID = 5
+	mine := models.AromaChemical{IngredientName: "Ambrofix", OwnerID: userID}
+	foreign := models.AromaChemical{IngredientName: "Secret Musk", OwnerID: userID + 1}
+	for _, chemical := range []*models.AromaChemical{&mine, &foreign} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+
+	post := func(handler http.HandlerFunc, path string, form url.Values) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler(rr, authenticatedFormRequest(t, sm, path, form, userID))
+		return rr
+	}
+
+	rr := post(Substitutions, "/app/sections/tools/substitutions", url.Values{
+		"original_name": {"Secret Musk"},
+		"substitute_id": {"2"},
+	})
+	if !strings.Contains(rr.Body.String(), "Choose a substitute from your library.") {
+		t.Fatalf("expected another user's material to be rejected, got %q", rr.Body.String())
+	}
+
+	rr = post(Substitutions, "/app/sections/tools/substitutions", url.Values{
+		"original_name": {"Ambrox DL"},
+		"substitute_id": {"1"},
+		"ratio":         {"0.8"},
+		"notes":         {"Slightly drier"},
+	})
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Ambrox DL") {
+		t.Fatalf("expected substitution to be listed, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	substitutions := loadSubstitutions(t.Context(), userID)
+	if len(substitutions) != 1 || substitutions[0].Ratio != 0.8 {
+		t.Fatalf("unexpected substitutions: %+v", substitutions)
+	}
+	if match := matchSubstitution(substitutions, "Ambrox-DL", nil); match == nil || match.Substitute.ID != mine.ID {
+		t.Fatalf("expected Ambrox-DL to match the saved rule, got %+v", match)
+	}
+	if match := matchSubstitution(substitutions, "Galaxolide", nil); match != nil {
+		t.Fatalf("expected no match for Galaxolide, got %+v", match)
+	}
+
+	id := url.Values{"id": {"1"}, "ratio": {"1.25"}}
+	post(SubstitutionUpdate, "/app/sections/tools/substitutions/update", id)
+	if got := loadSubstitutions(t.Context(), userID); got[0].Ratio != 1.25 {
+		t.Fatalf("expected ratio 1.25 after update, got %v", got[0].Ratio)
+	}
+
+	other := httptest.NewRecorder()
+	SubstitutionDelete(other, authenticatedFormRequest(t, sm, "/app/sections/tools/substitutions/delete", id, userID+1))
+	if len(loadSubstitutions(t.Context(), userID)) != 1 {
+		t.Fatal("expected another user to be unable to delete the rule")
+	}
+	post(SubstitutionDelete, "/app/sections/tools/substitutions/delete", id)
+	if len(loadSubstitutions(t.Context(), userID)) != 0 {
+		t.Fatal("expected the rule to be deleted")
+	}
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// maxTermReason caps the note a member attaches to a suggestion.
+const maxTermReason = 255
+
+// loadTaxonomyTerms returns the terms of vocabulary with the given status in
+// name order, or nil without a database.
+func loadTaxonomyTerms(ctx context.Context, vocabulary, status string) []models.TaxonomyTerm {
+	if database == nil {
+		return nil
+	}
+	var terms []models.TaxonomyTerm
+	if err := database.WithContext(ctx).
+		Where("vocabulary = ? AND status = ?", vocabulary, status).
+		Order("name asc").
+		Find(&terms).Error; err != nil {
+		applog.Error(ctx, "failed to load taxonomy terms", "error", err, "vocabulary", vocabulary)
+		return nil
+	}
+	return terms
+}
+
+// approvedTerms returns the names members must pick from in vocabulary.
+func approvedTerms(ctx context.Context, vocabulary string) []string {
+	terms := loadTaxonomyTerms(ctx, vocabulary, models.TermApproved)
+	names := make([]string, 0, len(terms))
+	for _, term := range terms {
+		names = append(names, term.Name)
+	}
+	return names
+}
+
+// governedTerm canonicalises value against the approved terms of vocabulary.
+// Any value is accepted while the vocabulary has no approved terms, and an
+// unchanged previous value is kept so older records stay editable.
+func governedTerm(ctx context.Context, vocabulary, value, previous string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", true
+	}
+	terms := approvedTerms(ctx, vocabulary)
+	if len(terms) == 0 {
+		return value, true
+	}
+	if term, ok := models.CanonicalTerm(terms, value); ok {
+		return term, true
+	}
+	return value, value == strings.TrimSpace(previous)
+}
+
+func loadTaxonomyPanel(ctx context.Context) pages.TaxonomyPanel {
+	return pages.TaxonomyPanel{
+		Terms:       loadTaxonomyTerms(ctx, models.TaxonomyWheel, models.TermApproved),
+		Suggestions: loadTaxonomyTerms(ctx, models.TaxonomyWheel, models.TermSuggested),
+	}
+}
+
+func renderTaxonomyControl(w http.ResponseWriter, r *http.Request, message string) {
+	panel := loadTaxonomyPanel(r.Context())
+	panel.Message = message
+	renderComponent(w, r, pages.TaxonomyControl(panel))
+}
+
+// TaxonomyTerms renders the approved terms of a vocabulary for the
+// ingredient editor.
+func TaxonomyTerms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	vocabulary := r.URL.Query().Get("vocabulary")
+	if !models.ValidVocabulary(vocabulary) {
+		http.Error(w, "unknown vocabulary", http.StatusBadRequest)
+		return
+	}
+	renderComponent(w, r, pages.TaxonomyTermOptions(pages.TaxonomyOptions{
+		Vocabulary: vocabulary,
+		Terms:      approvedTerms(r.Context(), vocabulary),
+	}))
+}
+
+// TaxonomySuggest queues a member's new term for administrator review.
+func TaxonomySuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	vocabulary := r.FormValue("vocabulary")
+	if !models.ValidVocabulary(vocabulary) {
+		http.Error(w, "unknown vocabulary", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	options := pages.TaxonomyOptions{Vocabulary: vocabulary, Terms: approvedTerms(ctx, vocabulary)}
+	name, ok := models.NormalizeTerm(r.FormValue("term"))
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	switch {
+	case !ok:
+		options.Message = "Give the new term in at most 80 characters."
+	case len(reason) > maxTermReason:
+		options.Message = "Keep the reason under 255 characters."
+	default:
+		if term, found := models.CanonicalTerm(options.Terms, name); found {
+			options.Message = term + " is already in the shared list."
+			break
+		}
+		message, err := queueTermSuggestion(ctx, vocabulary, name, reason, userID)
+		if err != nil {
+			applog.Error(ctx, "failed to queue taxonomy suggestion", "error", err, "userID", userID)
+			http.Error(w, "unable to save suggestion", http.StatusInternalServerError)
+			return
+		}
+		options.Message = message
+	}
+	renderComponent(w, r, pages.TaxonomyTermOptions(options))
+}
+
+// queueTermSuggestion records a suggestion unless the same term is already
+// queued or was rejected, returning the message shown to the member.
+func queueTermSuggestion(ctx context.Context, vocabulary, name, reason string, userID uint) (string, error) {
+	var existing []models.TaxonomyTerm
+	if err := database.WithContext(ctx).
+		Where("vocabulary = ? AND status IN ?", vocabulary, []string{models.TermSuggested, models.TermRejected}).
+		Find(&existing).Error; err != nil {
+		return "", err
+	}
+	for _, term := range existing {
+		if _, same := models.CanonicalTerm([]string{term.Name}, name); !same {
+			continue
+		}
+		if term.Status == models.TermRejected {
+			return term.Name + " was declined by an administrator.", nil
+		}
+		return term.Name + " is already waiting for review.", nil
+	}
+
+	term := models.TaxonomyTerm{
+		Vocabulary:    vocabulary,
+		Name:          name,
+		Status:        models.TermSuggested,
+		Reason:        reason,
+		SuggestedByID: &userID,
+	}
+	if err := database.WithContext(ctx).Create(&term).Error; err != nil {
+		return "", err
+	}
+	applog.Info(ctx, "taxonomy term suggested", "termID", term.ID, "vocabulary", vocabulary, "userID", userID)
+	return name + " was sent to the administrators for review.", nil
+}
+
+// TaxonomyAdd lets administrators approve a new wheel family directly. A
+// pending suggestion for the same term is approved instead of duplicated.
+func TaxonomyAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	name, ok := models.NormalizeTerm(r.FormValue("name"))
+	if !ok {
+		renderTaxonomyControl(w, r, "Give the term in at most 80 characters.")
+		return
+	}
+
+	ctx := r.Context()
+	var existing []models.TaxonomyTerm
+	if err := database.WithContext(ctx).Where("vocabulary = ?", models.TaxonomyWheel).Find(&existing).Error; err != nil {
+		applog.Error(ctx, "failed to load taxonomy terms", "error", err)
+		http.Error(w, "unable to save term", http.StatusInternalServerError)
+		return
+	}
+	term := models.TaxonomyTerm{Vocabulary: models.TaxonomyWheel, Name: name}
+	for _, candidate := range existing {
+		if _, same := models.CanonicalTerm([]string{candidate.Name}, name); !same {
+			continue
+		}
+		if candidate.Status == models.TermApproved {
+			renderTaxonomyControl(w, r, candidate.Name+" is already approved.")
+			return
+		}
+		term = candidate
+	}
+	term.Name = name
+	term.Status = models.TermApproved
+	if err := database.WithContext(ctx).Save(&term).Error; err != nil {
+		applog.Error(ctx, "failed to save taxonomy term", "error", err)
+		http.Error(w, "unable to save term", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "taxonomy term approved", "termID", term.ID, "vocabulary", term.Vocabulary)
+	renderTaxonomyControl(w, r, name+" added to the wheel taxonomy.")
+}
+
+// TaxonomyReview approves or rejects a suggested term.
+func TaxonomyReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	id := pages.ParseUint(r.FormValue("id"))
+	if id == 0 {
+		http.Error(w, "missing taxonomy term", http.StatusBadRequest)
+		return
+	}
+	var status string
+	switch r.FormValue("decision") {
+	case "approve":
+		status = models.TermApproved
+	case "reject":
+		status = models.TermRejected
+	default:
+		http.Error(w, "decision must be approve or reject", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var term models.TaxonomyTerm
+	if err := database.WithContext(ctx).Where("status = ?", models.TermSuggested).First(&term, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			renderTaxonomyControl(w, r, "That suggestion was already reviewed.")
+			return
+		}
+		applog.Error(ctx, "failed to load taxonomy suggestion", "error", err, "termID", id)
+		http.Error(w, "unable to review suggestion", http.StatusInternalServerError)
+		return
+	}
+	message := term.Name + " rejected."
+	if status == models.TermApproved {
+		message = term.Name + " approved."
+		if existing, ok := models.CanonicalTerm(approvedTerms(ctx, term.Vocabulary), term.Name); ok {
+			status = models.TermRejected
+			message = existing + " is already approved."
+		}
+	}
+	if err := database.WithContext(ctx).Model(&term).Update("status", status).Error; err != nil {
+		applog.Error(ctx, "failed to review taxonomy suggestion", "error", err, "termID", id)
+		http.Error(w, "unable to review suggestion", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(ctx, "taxonomy suggestion reviewed", "termID", id, "status", status)
+	renderTaxonomyControl(w, r, message)
+}
+
+// TaxonomyDelete removes an approved term. Ingredients keep the value until
+// they are next edited.
+func TaxonomyDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+	id := pages.ParseUint(r.FormValue("id"))
+	if id == 0 {
+		http.Error(w, "missing taxonomy term", http.StatusBadRequest)
+		return
+	}
+	if err := database.WithContext(r.Context()).Delete(&models.TaxonomyTerm{}, id).Error; err != nil {
+		applog.Error(r.Context(), "failed to delete taxonomy term", "error", err, "termID", id)
+		http.Error(w, "unable to delete term", http.StatusInternalServerError)
+		return
+	}
+	applog.Info(r.Context(), "taxonomy term deleted", "termID", id)
+	renderTaxonomyControl(w, r, "Term removed from the wheel taxonomy.")
+}
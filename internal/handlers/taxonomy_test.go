@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestTaxonomyGovernsWheelPositions(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}, &models.TaxonomyTerm{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "perfumer@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	chemical := models.AromaChemical{IngredientName: "Bergamot", WheelPosition: "Legacy zest", OwnerID: user.ID}
+	if err := db.Create(&chemical).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+
+	update := func(wheel string) string {
+		rec := httptest.NewRecorder()
+		IngredientUpdate(rec, authenticatedFormRequest(t, sm, "/app/sections/ingredients/update", url.Values{
+			"id":              {strconv.Itoa(int(chemical.ID))},
+			"ingredient_name": {"Bergamot"},
+			"wheel_position":  {wheel},
+		}, int(user.ID)))
+		return rec.Body.String()
+	}
+	stored := func() string {
+		var reloaded models.AromaChemical
+		db.First(&reloaded, chemical.ID)
+		return reloaded.WheelPosition
+	}
+
+	if body := update("Anything goes"); !strings.Contains(body, "Ingredient updated successfully.") {
+		t.Fatalf("expected free text without approved terms, got %q", body)
+	}
+	update("Legacy zest") // restore the pre-governance value
+
+	rec := httptest.NewRecorder()
+	TaxonomyAdd(rec, authenticatedFormRequest(t, sm, "/app/admin/taxonomy", url.Values{"name": {" Fresh   Citrus "}}, int(user.ID)))
+	if !strings.Contains(rec.Body.String(), "Fresh Citrus added to the wheel taxonomy.") {
+		t.Fatalf("unexpected add response %q", rec.Body.String())
+	}
+
+	if body := update("Legacy zest"); !strings.Contains(body, "Ingredient updated successfully.") {
+		t.Fatalf("expected the unchanged legacy value to be kept, got %q", body)
+	}
+	if body := update("Gourmand"); !strings.Contains(body, "shared wheel families") {
+		t.Fatalf("expected an unknown family to be refused, got %q", body)
+	}
+	if body := update("fresh citrus"); !strings.Contains(body, "Ingredient updated successfully.") || stored() != "Fresh Citrus" {
+		t.Fatalf("expected the canonical family to be stored, got %q (%q)", stored(), body)
+	}
+
+	suggest := func(term string) string {
+		rec := httptest.NewRecorder()
+		TaxonomySuggest(rec, authenticatedFormRequest(t, sm, "/app/taxonomy/suggest", url.Values{"vocabulary": {models.TaxonomyWheel}, "term": {term}}, int(user.ID)))
+		return rec.Body.String()
+	}
+	if body := suggest("Gourmand"); !strings.Contains(body, "sent to the administrators") {
+		t.Fatalf("unexpected suggestion response %q", body)
+	}
+	if body := suggest("gourmand"); !strings.Contains(body, "already waiting for review") {
+		t.Fatalf("expected a duplicate suggestion to be refused, got %q", body)
+	}
+	if body := suggest("FRESH CITRUS"); !strings.Contains(body, "already in the shared list") {
+		t.Fatalf("expected an approved term to be refused, got %q", body)
+	}
+
+	var suggestion models.TaxonomyTerm
+	if err := db.Where("status = ?", models.TermSuggested).First(&suggestion).Error; err != nil {
+		t.Fatalf("load suggestion: %v", err)
+	}
+	if suggestion.SuggestedByID == nil || *suggestion.SuggestedByID != user.ID {
+		t.Fatalf("expected the suggestion to record its author, got %+v", suggestion.SuggestedByID)
+	}
+	rec = httptest.NewRecorder()
+	TaxonomyReview(rec, authenticatedFormRequest(t, sm, "/app/admin/taxonomy/review", url.Values{
+		"id":       {strconv.Itoa(int(suggestion.ID))},
+		"decision": {"approve"},
+	}, int(user.ID)))
+	if !strings.Contains(rec.Body.String(), "Gourmand approved.") {
+		t.Fatalf("unexpected review response %q", rec.Body.String())
+	}
+	if body := update("gourmand"); !strings.Contains(body, "Ingredient updated successfully.") || stored() != "Gourmand" {
+		t.Fatalf("expected the approved suggestion to be usable, got %q (%q)", stored(), body)
+	}
+}
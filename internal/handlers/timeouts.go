@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	applog "perfugo/internal/log"
+)
+
+const timeoutMessage = "This is taking longer than expected. Please try again in a moment."
+
+// Timeouts bounds how long slow handlers may work. A zero duration leaves
+// the request bounded only by the client.
+type Timeouts struct {
+	AI     time.Duration
+	Report time.Duration
+	Import time.Duration
+}
+
+var handlerTimeouts Timeouts
+
+// ConfigureTimeouts installs the per-handler deadlines.
+func ConfigureTimeouts(timeouts Timeouts) {
+	handlerTimeouts = timeouts
+}
+
+// withDeadline derives a context from the request that also ends after d, so
+// work stops when either the deadline passes or the client goes away.
+func withDeadline(r *http.Request, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), d)
+}
+
+// contextExpired reports whether err stems from a cancelled or expired context.
+func contextExpired(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// writeTimeout answers a request whose deadline passed before its work
+// finished. HTMX only swaps successful responses, so when render is given an
+// HTMX request gets the message in its panel instead of a bare 503. Clients
+// that have already disconnected get nothing.
+func writeTimeout(w http.ResponseWriter, r *http.Request, render func(message string)) {
+	if r.Context().Err() != nil {
+		applog.Debug(r.Context(), "client went away before the request finished", "path", r.URL.Path)
+		return
+	}
+
+	applog.Error(r.Context(), "request deadline exceeded", "path", r.URL.Path)
+	if render != nil && isHTMX(r) {
+		render(timeoutMessage)
+		return
+	}
+	w.Header().Set("Retry-After", "30")
+	if acceptsJSON(r) {
+		writeProblem(w, r, http.StatusServiceUnavailable, timeoutMessage)
+		return
+	}
+	http.Error(w, timeoutMessage, http.StatusServiceUnavailable)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/internal/ai"
+)
+
+// blockingAIClient waits for its context to end, like a model call that
+// outlives the handler's deadline.
+type blockingAIClient struct{}
+
+func (blockingAIClient) FetchAromaProfile(ctx context.Context, _ string, _ ai.FetchOptions) (ai.Profile, error) {
+	<-ctx.Done()
+	return ai.Profile{}, ctx.Err()
+}
+
+func (blockingAIClient) ExtractFormula(ctx context.Context, _ ai.FormulaImportInput) (ai.FormulaImportResult, error) {
+	<-ctx.Done()
+	return ai.FormulaImportResult{}, ctx.Err()
+}
+
+func TestToolsImportIngredientTimesOut(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	prevDB, prevClient, prevTimeouts := database, openAIClient, handlerTimeouts
+	database = nil
+	openAIClient = blockingAIClient{}
+	handlerTimeouts = Timeouts{AI: 10 * time.Millisecond}
+	t.Cleanup(func() {
+		database, openAIClient, handlerTimeouts = prevDB, prevClient, prevTimeouts
+	})
+
+	form := url.Values{"ingredient_name": {"Ambroxan"}}
+
+	req := authenticatedFormRequest(t, sm, "/app/sections/tools/import", form, 1)
+	rec := httptest.NewRecorder()
+	ToolsImportIngredient(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected a Retry-After header")
+	}
+
+	req = authenticatedFormRequest(t, sm, "/app/sections/tools/import", form, 1)
+	req.Header.Set("HX-Request", "true")
+	rec = httptest.NewRecorder()
+	ToolsImportIngredient(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "taking longer than expected") {
+		t.Fatalf("expected the timeout message in the tools panel, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWriteTimeoutSkipsDisconnectedClients(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/app/reports/batch-production", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	writeTimeout(rec, req, nil)
+	if rec.Body.Len() != 0 || rec.Header().Get("Retry-After") != "" {
+		t.Fatalf("expected no response for a disconnected client, got %d: %q", rec.Code, rec.Body.String())
+	}
+}
@@ -10,16 +10,31 @@ import (
 	"gorm.io/gorm"
 
 	"perfugo/internal/ai"
+	"perfugo/internal/importer"
 	applog "perfugo/internal/log"
 	"perfugo/internal/views/pages"
 	"perfugo/models"
 )
 
-var openAIClient *ai.Client
+var openAIClient ai.Client
 
-// ConfigureAI installs the OpenAI client used by tooling endpoints.
-func ConfigureAI(client *ai.Client) {
-	openAIClient = client
+// ConfigureAI installs the AI client used by tooling endpoints. Its calls
+// are counted for Metrics.
+func ConfigureAI(client ai.Client) {
+	if client == nil {
+		openAIClient = nil
+		return
+	}
+	openAIClient = meteredAIClient{client}
+}
+
+// withAIJob tags ctx with a new import job so the model calls made for it
+// are recorded together, with the user's name and email redacted. The job
+// ID goes into the logs of a failed import to find its exchanges.
+func withAIJob(ctx context.Context, userID uint) (context.Context, string) {
+	profile := loadUserProfile(ctx, userID)
+	job := ai.Job{ID: models.NewUUID(), UserID: userID, Redact: []string{profile.Name, profile.Email}}
+	return ai.WithJob(ctx, job), job.ID
 }
 
 // ToolsImportIngredient handles the AI-assisted ingredient import workflow.
@@ -44,19 +59,41 @@ func ToolsImportIngredient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if openAIClient == nil {
-		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "AI integration is not configured. Set OPENAI_API_KEY to enable this tool."))
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "AI integration is not configured. Set OPENAI_API_KEY (or AI_USE_MOCK for local development) to enable this tool."))
 		return
 	}
 
-	ctx := r.Context()
+	ctx, cancel := withDeadline(r, handlerTimeouts.AI)
+	defer cancel()
+	ctx, jobID := withAIJob(ctx, userID)
+	renderError := func(message string) {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", message))
+	}
+
 	profile, err := openAIClient.FetchAromaProfile(ctx, ingredientName, ai.FetchOptions{})
+	if contextExpired(err) {
+		writeTimeout(w, r, renderError)
+		return
+	}
 	if err != nil {
-		applog.Error(ctx, "ai fetch failed", "error", err)
+		applog.Error(ctx, "ai fetch failed", "error", err, "aiJobID", jobID)
 		renderComponent(w, r, pages.ToolsPanel(snapshot, "", fmt.Sprintf("We couldn't fetch data for %q. Please try again shortly.", ingredientName)))
 		return
 	}
 
-	record, created, warning, err := persistAromaProfile(ctx, profile, userID)
+	record, outcome, warning, err := persistAromaProfile(ctx, profile, userID)
+	if contextExpired(err) {
+		writeTimeout(w, r, renderError)
+		return
+	}
+	if message, ok := quotaMessage(err); ok {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", message))
+		return
+	}
+	if errors.Is(err, gorm.ErrInvalidDB) {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", pages.DemoModeMessage))
+		return
+	}
 	if err != nil {
 		applog.Error(ctx, "persist ai aroma", "error", err)
 		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "We couldn't store the generated ingredient. Please try again."))
@@ -64,9 +101,16 @@ func ToolsImportIngredient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	snapshot = buildWorkspaceSnapshot(r)
-	message := fmt.Sprintf("Added %s to your private library.", record.IngredientName)
-	if !created {
+	var message string
+	switch outcome {
+	case importUpdated:
 		message = fmt.Sprintf("Updated %s with the latest AI profile.", record.IngredientName)
+	case importSkipped:
+		message = fmt.Sprintf("%s is already in the shared library, so nothing was imported.", record.IngredientName)
+	case importLinked:
+		message = fmt.Sprintf("%s is already in the shared library; %s was added as one of its other names.", record.IngredientName, profile.IngredientName)
+	default:
+		message = fmt.Sprintf("Added %s to your private library.", record.IngredientName)
 	}
 	if strings.TrimSpace(warning) != "" {
 		message = fmt.Sprintf("%s %s", message, warning)
@@ -75,9 +119,25 @@ func ToolsImportIngredient(w http.ResponseWriter, r *http.Request) {
 	renderComponent(w, r, pages.ToolsPanel(snapshot, message, ""))
 }
 
-func persistAromaProfile(ctx context.Context, profile ai.Profile, ownerID uint) (*models.AromaChemical, bool, string, error) {
+// importOutcome records what persistAromaProfile did with a profile.
+type importOutcome int
+
+const (
+	importCreated importOutcome = iota
+	importUpdated
+	// importSkipped and importLinked leave the user with another user's
+	// public chemical, per the configured importer.CollisionStrategy.
+	importSkipped
+	importLinked
+)
+
+// persistAromaProfile stores profile in ownerID's library. A profile matching
+// another user's public chemical is skipped, linked as an alias or copied
+// under a "(Private)" name depending on importer.CurrentCollisionStrategy; a
+// match with another user's private chemical is always copied.
+func persistAromaProfile(ctx context.Context, profile ai.Profile, ownerID uint) (*models.AromaChemical, importOutcome, string, error) {
 	if database == nil {
-		return nil, false, "", gorm.ErrInvalidDB
+		return nil, importCreated, "", gorm.ErrInvalidDB
 	}
 
 	profile.IngredientName = fallbackIngredientName(profile.IngredientName)
@@ -86,7 +146,13 @@ func persistAromaProfile(ctx context.Context, profile ai.Profile, ownerID uint)
 
 	var result models.AromaChemical
 	warnings := []string{}
-	created := false
+	if term, ok := governedTerm(ctx, models.TaxonomyWheel, profile.WheelPosition, ""); ok {
+		profile.WheelPosition = term
+	} else {
+		warnings = append(warnings, fmt.Sprintf("Wheel family %q is not in the shared taxonomy and was left blank.", profile.WheelPosition))
+		profile.WheelPosition = ""
+	}
+	outcome := importCreated
 
 	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Attempt to locate an existing record by name.
@@ -121,12 +187,29 @@ func persistAromaProfile(ctx context.Context, profile ai.Profile, ownerID uint)
 				return err
 			}
 			result = *existing
-			created = false
+			outcome = importUpdated
 			return nil
 		}
 
-		if existing != nil && existing.OwnerID != ownerID {
-			uniqueName, err := generatePrivateName(ctx, tx, profile.IngredientName)
+		if existing != nil && existing.Public {
+			switch importer.CurrentCollisionStrategy() {
+			case importer.CollisionSkip:
+				result = *existing
+				outcome = importSkipped
+				return tx.WithContext(ctx).Preload("OtherNames").First(&result, existing.ID).Error
+			case importer.CollisionAlias:
+				aliases := append([]models.OtherName{{Name: profile.IngredientName}}, profileAliases(profile)...)
+				if _, err := importer.LinkTypedAliases(tx.WithContext(ctx), *existing, aliases...); err != nil {
+					return err
+				}
+				result = *existing
+				outcome = importLinked
+				return tx.WithContext(ctx).Preload("OtherNames").First(&result, existing.ID).Error
+			}
+		}
+
+		if existing != nil {
+			uniqueName, err := importer.PrivateCopyName(tx.WithContext(ctx), profile.IngredientName)
 			if err != nil {
 				return err
 			}
@@ -138,13 +221,13 @@ func persistAromaProfile(ctx context.Context, profile ai.Profile, ownerID uint)
 			return err
 		}
 		result = *record
-		created = true
+		outcome = importCreated
 		return nil
 	})
 	if err != nil {
-		return nil, false, strings.Join(warnings, " "), err
+		return nil, outcome, strings.Join(warnings, " "), err
 	}
-	return &result, created, strings.Join(warnings, " "), nil
+	return &result, outcome, strings.Join(warnings, " "), nil
 }
 
 func findChemicalByName(ctx context.Context, tx *gorm.DB, name string) (*models.AromaChemical, error) {
@@ -181,6 +264,11 @@ func findChemicalByCAS(ctx context.Context, tx *gorm.DB, cas string) (*models.Ar
 func createChemicalFromProfile(ctx context.Context, tx *gorm.DB, profile ai.Profile, ownerID uint) (*models.AromaChemical, error) {
 	canonicalPyramid := pages.CanonicalPyramidPosition(profile.PyramidPosition)
 
+	if err := checkIngredientQuota(tx.WithContext(ctx), ownerID, 1); err != nil {
+		return nil, err
+	}
+
+	fetchedAt := nowFunc()
 	record := models.AromaChemical{
 		IngredientName:      profile.IngredientName,
 		CASNumber:           strings.TrimSpace(profile.CASNumber),
@@ -198,13 +286,15 @@ func createChemicalFromProfile(ctx context.Context, tx *gorm.DB, profile ai.Prof
 		Usage:               profile.Usage,
 		OwnerID:             ownerID,
 		Public:              false,
+		AIModel:             profile.Model,
+		AIFetchedAt:         &fetchedAt,
 	}
 
 	if err := tx.WithContext(ctx).Create(&record).Error; err != nil {
 		return nil, err
 	}
 
-	if err := replaceOtherNames(ctx, tx, record.ID, profile.OtherNames); err != nil {
+	if err := replaceAliases(ctx, tx, record.ID, profileAliases(profile)); err != nil {
 		return nil, err
 	}
 
@@ -216,10 +306,28 @@ func createChemicalFromProfile(ctx context.Context, tx *gorm.DB, profile ai.Prof
 }
 
 func applyProfileToChemical(ctx context.Context, tx *gorm.DB, existing *models.AromaChemical, profile ai.Profile, ownerID uint) error {
+	updates := profileUpdates(profile)
+	updates["ingredient_name"] = profile.IngredientName
+	updates["owner_id"] = ownerID
+	updates["public"] = false
+	if err := tx.WithContext(ctx).Model(existing).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	if err := replaceAliases(ctx, tx, existing.ID, profileAliases(profile)); err != nil {
+		return err
+	}
+
+	return tx.WithContext(ctx).Preload("OtherNames").First(existing, existing.ID).Error
+}
+
+// profileUpdates lists the columns an AI profile refreshes on an existing
+// chemical, stamped with the model and fetch time. The name, owner and
+// visibility are left to the caller.
+func profileUpdates(profile ai.Profile) map[string]any {
 	canonicalPyramid := pages.CanonicalPyramidPosition(profile.PyramidPosition)
 
 	updates := map[string]any{
-		"ingredient_name":      profile.IngredientName,
 		"notes":                profile.Notes,
 		"wheel_position":       profile.WheelPosition,
 		"pyramid_position":     canonicalPyramid,
@@ -232,42 +340,15 @@ func applyProfileToChemical(ctx context.Context, tx *gorm.DB, existing *models.A
 		"historic_role":        profile.HistoricRole,
 		"popularity":           profile.Popularity,
 		"usage":                profile.Usage,
-		"owner_id":             ownerID,
-		"public":               false,
+		"ai_model":             profile.Model,
+		"ai_fetched_at":        nowFunc(),
 	}
 
 	cas := strings.TrimSpace(profile.CASNumber)
 	if cas != "" {
 		updates["cas_number"] = cas
 	}
-	if err := tx.WithContext(ctx).Model(existing).Updates(updates).Error; err != nil {
-		return err
-	}
-
-	if err := replaceOtherNames(ctx, tx, existing.ID, profile.OtherNames); err != nil {
-		return err
-	}
-
-	return tx.WithContext(ctx).Preload("OtherNames").First(existing, existing.ID).Error
-}
-
-func generatePrivateName(ctx context.Context, tx *gorm.DB, base string) (string, error) {
-	base = fallbackIngredientName(base)
-	candidate := fmt.Sprintf("%s (Private)", base)
-	suffix := 2
-	for {
-		var count int64
-		if err := tx.WithContext(ctx).Model(&models.AromaChemical{}).
-			Where("lower(ingredient_name) = ?", strings.ToLower(candidate)).
-			Count(&count).Error; err != nil {
-			return "", err
-		}
-		if count == 0 {
-			return candidate, nil
-		}
-		candidate = fmt.Sprintf("%s (Private %d)", base, suffix)
-		suffix++
-	}
+	return updates
 }
 
 func fallbackIngredientName(value string) string {
@@ -278,19 +359,34 @@ func fallbackIngredientName(value string) string {
 	return "Untitled Ingredient"
 }
 
-func replaceOtherNames(ctx context.Context, tx *gorm.DB, chemicalID uint, names []string) error {
+// profileAliases lists the profile's other names followed by its
+// language-tagged names.
+func profileAliases(profile ai.Profile) []models.OtherName {
+	aliases := make([]models.OtherName, 0, len(profile.OtherNames)+len(profile.LocalizedNames))
+	for _, name := range profile.OtherNames {
+		aliases = append(aliases, models.OtherName{Name: name})
+	}
+	for _, localized := range profile.LocalizedNames {
+		aliases = append(aliases, models.OtherName{Name: localized.Name, Language: localized.Language, Kind: localized.Kind})
+	}
+	return aliases
+}
+
+// replaceAliases swaps the chemical's other names for aliases, keeping the
+// first of any names differing only in case.
+func replaceAliases(ctx context.Context, tx *gorm.DB, chemicalID uint, aliases []models.OtherName) error {
 	if err := tx.WithContext(ctx).Where("aroma_chemical_id = ?", chemicalID).Delete(&models.OtherName{}).Error; err != nil {
 		return err
 	}
 
-	if len(names) == 0 {
+	if len(aliases) == 0 {
 		return nil
 	}
 
-	entries := make([]models.OtherName, 0, len(names))
-	seen := make(map[string]struct{}, len(names))
-	for _, name := range names {
-		trimmed := strings.TrimSpace(name)
+	entries := make([]models.OtherName, 0, len(aliases))
+	seen := make(map[string]struct{}, len(aliases))
+	for _, alias := range aliases {
+		trimmed := strings.TrimSpace(alias.Name)
 		if trimmed == "" {
 			continue
 		}
@@ -301,6 +397,8 @@ func replaceOtherNames(ctx context.Context, tx *gorm.DB, chemicalID uint, names
 		seen[key] = struct{}{}
 		entries = append(entries, models.OtherName{
 			Name:            trimmed,
+			Language:        models.NormalizeLanguageCode(alias.Language),
+			Kind:            alias.Kind,
 			AromaChemicalID: chemicalID,
 		})
 	}
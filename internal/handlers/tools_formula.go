@@ -3,16 +3,13 @@ package handlers
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
-	"path/filepath"
 	"strings"
 
-	"github.com/ledongthuc/pdf"
 	"gorm.io/gorm"
 
 	"perfugo/internal/ai"
@@ -47,7 +44,7 @@ func ToolsImportFormula(w http.ResponseWriter, r *http.Request) {
 	snapshot := buildWorkspaceSnapshot(r)
 
 	if openAIClient == nil {
-		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "AI integration is not configured. Set OPENAI_API_KEY to enable this tool."))
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "AI integration is not configured. Set OPENAI_API_KEY (or AI_USE_MOCK for local development) to enable this tool."))
 		return
 	}
 
@@ -57,6 +54,15 @@ func ToolsImportFormula(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Checked before the model call; persistImportedFormula checks again.
+	if err := checkFormulaQuota(database.WithContext(r.Context()), userID, 1); err != nil {
+		if message, ok := quotaMessage(err); ok {
+			renderComponent(w, r, pages.ToolsPanel(snapshot, "", message))
+			return
+		}
+		applog.Error(r.Context(), "failed to check formula quota", "error", err)
+	}
+
 	if err := r.ParseMultipartForm(maxFormulaUploadSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
 		applog.Error(r.Context(), "failed to parse formula import form", "error", err)
 		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "Upload is too large or invalid. Please retry with a smaller file."))
@@ -75,7 +81,7 @@ func ToolsImportFormula(w http.ResponseWriter, r *http.Request) {
 
 	var base64Payload string
 	if len(fileBytes) > 0 {
-		processed, encoded, convErr := deriveTextFromUpload(fileBytes, fileType)
+		processed, encoded, convErr := ai.DocumentText(fileBytes, fileType)
 		if convErr != nil {
 			applog.Error(r.Context(), "failed to extract formula text", "error", convErr, "mime", fileType)
 			renderComponent(w, r, pages.ToolsPanel(snapshot, "", "We couldn't interpret the uploaded document. Try a different format."))
@@ -96,7 +102,13 @@ func ToolsImportFormula(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
+	ctx, cancel := withDeadline(r, handlerTimeouts.AI)
+	defer cancel()
+	ctx, jobID := withAIJob(ctx, userID)
+	renderError := func(message string) {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", message))
+	}
+
 	aiResult, err := openAIClient.ExtractFormula(ctx, ai.FormulaImportInput{
 		NameHint:   nameHint,
 		RawText:    rawText,
@@ -104,8 +116,12 @@ func ToolsImportFormula(w http.ResponseWriter, r *http.Request) {
 		FileName:   fileName,
 		FileType:   fileType,
 	})
+	if contextExpired(err) {
+		writeTimeout(w, r, renderError)
+		return
+	}
 	if err != nil {
-		applog.Error(ctx, "formula extraction failed", "error", err)
+		applog.Error(ctx, "formula extraction failed", "error", err, "aiJobID", jobID)
 		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "We couldn't interpret that formula. Please refine the input and try again."))
 		return
 	}
@@ -118,14 +134,38 @@ func ToolsImportFormula(w http.ResponseWriter, r *http.Request) {
 
 	chemicals := snapshotChemicalPointers(snapshot.AromaChemicals)
 	resolved, warnings, err := resolveFormulaIngredients(ctx, userID, scaled, chemicals)
+	if contextExpired(err) {
+		writeTimeout(w, r, renderError)
+		return
+	}
+	if message, ok := quotaMessage(err); ok {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", message))
+		return
+	}
+	if errors.Is(err, gorm.ErrInvalidDB) {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", pages.DemoModeMessage))
+		return
+	}
 	if err != nil {
-		applog.Error(ctx, "resolve ingredients failed", "error", err)
+		applog.Error(ctx, "resolve ingredients failed", "error", err, "aiJobID", jobID)
 		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "Unable to map ingredients to the catalog. Please review the names and retry."))
 		return
 	}
 
 	formulaName := determineFormulaName(snapshot.Formulas, aiResult.FormulaName)
-	formula, err := persistImportedFormula(ctx, formulaName, aiResult.Notes, resolved)
+	formula, err := persistImportedFormula(ctx, userID, formulaName, aiResult.Notes, resolved)
+	if contextExpired(err) {
+		writeTimeout(w, r, renderError)
+		return
+	}
+	if message, ok := quotaMessage(err); ok {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", message))
+		return
+	}
+	if errors.Is(err, gorm.ErrInvalidDB) {
+		renderComponent(w, r, pages.ToolsPanel(snapshot, "", pages.DemoModeMessage))
+		return
+	}
 	if err != nil {
 		applog.Error(ctx, "persist imported formula failed", "error", err)
 		renderComponent(w, r, pages.ToolsPanel(snapshot, "", "We couldn't save the imported formula. Please try again."))
@@ -137,6 +177,9 @@ func ToolsImportFormula(w http.ResponseWriter, r *http.Request) {
 	if len(warnings) > 0 {
 		message = fmt.Sprintf("%s %s", message, strings.Join(warnings, " "))
 	}
+	if warning := duplicateFormulaWarning(formula.ID, snapshot.Formulas, snapshot.FormulaIngredients); warning != "" {
+		message = fmt.Sprintf("%s %s", message, warning)
+	}
 	renderComponent(w, r, pages.ToolsPanel(snapshot, message, ""))
 }
 
@@ -161,70 +204,12 @@ func readFormulaUpload(r *http.Request) (string, []byte, string, error) {
 
 	mime := header.Header.Get("Content-Type")
 	if mime == "" {
-		mime = mimeTypeFromName(header.Filename)
+		mime = ai.MimeTypeFromName(header.Filename)
 	}
 
 	return header.Filename, buf.Bytes(), mime, nil
 }
 
-func deriveTextFromUpload(data []byte, mime string) (string, string, error) {
-	lower := strings.ToLower(mime)
-	switch {
-	case strings.Contains(lower, "pdf"):
-		text, err := extractTextFromPDF(data)
-		if err != nil {
-			return "", "", err
-		}
-		return text, "", nil
-	case strings.HasPrefix(lower, "text/") || strings.Contains(lower, "json"):
-		return string(data), "", nil
-	case strings.HasPrefix(lower, "image/"):
-		return "", base64.StdEncoding.EncodeToString(data), nil
-	default:
-		return string(data), "", nil
-	}
-}
-
-func extractTextFromPDF(data []byte) (string, error) {
-	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
-	if err != nil {
-		return "", err
-	}
-	var builder strings.Builder
-	numPages := reader.NumPage()
-	for i := 1; i <= numPages; i++ {
-		page := reader.Page(i)
-		if page.V.IsNull() {
-			continue
-		}
-		text, err := page.GetPlainText(nil)
-		if err != nil {
-			return "", err
-		}
-		builder.WriteString(text)
-		builder.WriteString("\n")
-	}
-	return builder.String(), nil
-}
-
-func mimeTypeFromName(name string) string {
-	ext := strings.ToLower(filepath.Ext(name))
-	switch ext {
-	case ".txt":
-		return "text/plain"
-	case ".pdf":
-		return "application/pdf"
-	case ".png":
-		return "image/png"
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".webp":
-		return "image/webp"
-	default:
-		return "application/octet-stream"
-	}
-}
-
 func scaleFormulaComponents(ingredients []ai.FormulaImportIngredient, target float64) ([]formulaImportIngredient, error) {
 	scaled := make([]formulaImportIngredient, 0, len(ingredients))
 	total := 0.0
@@ -265,9 +250,18 @@ func snapshotChemicalPointers(source []models.AromaChemical) []*models.AromaChem
 func resolveFormulaIngredients(ctx context.Context, userID uint, candidates []formulaImportIngredient, chemicals []*models.AromaChemical) ([]resolvedIngredient, []string, error) {
 	resolved := make([]resolvedIngredient, 0, len(candidates))
 	warnings := []string{}
+	substitutions := loadSubstitutions(ctx, userID)
 	for _, candidate := range candidates {
 		match := matchChemicalByAliases(chemicals, candidate.Name, candidate.OtherNames)
 		if match == nil {
+			if substitution := matchSubstitution(substitutions, candidate.Name, candidate.OtherNames); substitution != nil {
+				warnings = append(warnings, fmt.Sprintf("Used %s in place of %s (ratio %s).", substitution.Substitute.IngredientName, candidate.Name, pages.FormatRatio(substitution.Ratio)))
+				resolved = append(resolved, resolvedIngredient{
+					Chemical: substitution.Substitute,
+					AmountMG: candidate.QuantityMG * substitution.Ratio,
+				})
+				continue
+			}
 			profile, err := openAIClient.FetchAromaProfile(ctx, candidate.Name, ai.FetchOptions{})
 			if err != nil {
 				return nil, nil, err
@@ -351,8 +345,11 @@ func uniqueAliases(values []string) []string {
 	return result
 }
 
+// normalizeIngredientName reduces value to its unaccented lowercase letters
+// and digits, so "Dihydrojasmonate de méthyle" matches its unaccented
+// spelling.
 func normalizeIngredientName(value string) string {
-	trimmed := strings.ToLower(strings.TrimSpace(value))
+	trimmed := models.FoldName(strings.TrimSpace(value))
 	if trimmed == "" {
 		return ""
 	}
@@ -429,7 +426,7 @@ func minInt(a, b int) int {
 	return b
 }
 
-func persistImportedFormula(ctx context.Context, name, notes string, entries []resolvedIngredient) (*models.Formula, error) {
+func persistImportedFormula(ctx context.Context, createdBy uint, name, notes string, entries []resolvedIngredient) (*models.Formula, error) {
 	if database == nil {
 		return nil, gorm.ErrInvalidDB
 	}
@@ -440,12 +437,18 @@ func persistImportedFormula(ctx context.Context, name, notes string, entries []r
 	if formula.Name == "" {
 		formula.Name = "Imported Formula"
 	}
+	if createdBy != 0 {
+		formula.CreatedByID = &createdBy
+	}
 
 	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFormulaQuota(tx, createdBy, 1); err != nil {
+			return err
+		}
 		if err := tx.Create(&formula).Error; err != nil {
 			return err
 		}
-		for _, entry := range entries {
+		for position, entry := range entries {
 			if entry.Chemical == nil || entry.AmountMG <= 0 {
 				continue
 			}
@@ -455,6 +458,7 @@ func persistImportedFormula(ctx context.Context, name, notes string, entries []r
 				Amount:          entry.AmountMG,
 				Unit:            "mg",
 				AromaChemicalID: &chemID,
+				Position:        position,
 			}
 			if err := tx.Create(&ing).Error; err != nil {
 				return err
@@ -31,13 +31,14 @@ func newToolsTestDB(t *testing.T) *gorm.DB {
 		&models.OtherName{},
 		&models.Formula{},
 		&models.FormulaIngredient{},
+		&models.AuditEntry{},
 	); err != nil {
 		t.Fatalf("automigrate: %v", err)
 	}
 	return db
 }
 
-func TestReplaceOtherNamesReplacesExistingEntries(t *testing.T) {
+func TestReplaceAliasesReplacesExistingEntries(t *testing.T) {
 	ctx := context.Background()
 	db := newToolsTestDB(t)
 
@@ -57,7 +58,7 @@ func TestReplaceOtherNamesReplacesExistingEntries(t *testing.T) {
 		t.Fatalf("seed other names: %v", err)
 	}
 
-	if err := replaceOtherNames(ctx, db, chemical.ID, []string{" Gamma ", "delta", "gamma"}); err != nil {
+	if err := replaceAliases(ctx, db, chemical.ID, []models.OtherName{{Name: " Gamma "}, {Name: "delta"}, {Name: "gamma"}}); err != nil {
 		t.Fatalf("replace other names: %v", err)
 	}
 
@@ -103,15 +104,16 @@ func TestPersistAromaProfileCanonicalisesData(t *testing.T) {
 		PyramidPosition:     "Heart Base",
 		WheelPosition:       " Floral ",
 		OtherNames:          []string{" Aurora ", "aurora"},
+		LocalizedNames:      []ai.LocalizedName{{Name: "Musc céleste", Language: "FR", Kind: models.AliasKindTranslation}},
 		Strength:            4,
 		RecommendedDilution: 12.5,
 	}
 
-	record, created, _, err := persistAromaProfile(ctx, profile, ownerID)
+	record, outcome, _, err := persistAromaProfile(ctx, profile, ownerID)
 	if err != nil {
 		t.Fatalf("persist profile: %v", err)
 	}
-	if !created {
+	if outcome != importCreated {
 		t.Fatalf("expected new record to be created")
 	}
 	if record.OwnerID != ownerID {
@@ -126,9 +128,12 @@ func TestPersistAromaProfileCanonicalisesData(t *testing.T) {
 	if record.WheelPosition != "Floral" {
 		t.Fatalf("expected trimmed wheel position, got %q", record.WheelPosition)
 	}
-	if len(record.OtherNames) != 1 || record.OtherNames[0].Name != "Aurora" {
+	if len(record.OtherNames) != 2 || record.OtherNames[0].Name != "Aurora" {
 		t.Fatalf("expected deduplicated other names, got %+v", record.OtherNames)
 	}
+	if localized := record.OtherNames[1]; localized.Name != "Musc céleste" || localized.Language != "fr" || localized.Kind != models.AliasKindTranslation {
+		t.Fatalf("expected the French name with its language, got %+v", localized)
+	}
 	if record.RecommendedDilution != 12.5 {
 		t.Fatalf("expected recommended dilution 12.5, got %.2f", record.RecommendedDilution)
 	}
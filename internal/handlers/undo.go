@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+const (
+	sessionUndoKey = "undo:action"
+	// undoWindow is how long a delete can be undone.
+	undoWindow = 2 * time.Minute
+)
+
+// Kinds of delete that can be undone.
+const (
+	undoKindFormula    = "formula"
+	undoKindIngredient = "ingredient"
+)
+
+// undoAction records the rows soft-deleted by the user's most recent delete.
+// Only one is kept per session; a newer delete replaces it.
+type undoAction struct {
+	Token   string    `json:"token"`
+	Kind    string    `json:"kind"`
+	Label   string    `json:"label"`
+	IDs     []uint    `json:"ids"`
+	Related []uint    `json:"related,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// rememberUndo stores the deleted rows in the session and returns the token
+// the Undo button posts back. It returns "" when undo is unavailable.
+func rememberUndo(r *http.Request, kind, label string, ids, related []uint) string {
+	if sessionManager == nil {
+		return ""
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	action := undoAction{
+		Token:   hex.EncodeToString(buf),
+		Kind:    kind,
+		Label:   label,
+		IDs:     ids,
+		Related: related,
+		Expires: nowFunc().Add(undoWindow),
+	}
+	encoded, err := json.Marshal(action)
+	if err != nil {
+		return ""
+	}
+	sessionManager.Put(r.Context(), sessionUndoKey, string(encoded))
+	return action.Token
+}
+
+// takeUndo returns the pending undo action when the token matches it and it
+// has not expired. A matching action is removed so it can only run once.
+func takeUndo(r *http.Request, kind, token string) (undoAction, bool) {
+	var action undoAction
+	if sessionManager == nil || token == "" {
+		return action, false
+	}
+	encoded := sessionManager.GetString(r.Context(), sessionUndoKey)
+	if encoded == "" || json.Unmarshal([]byte(encoded), &action) != nil {
+		return action, false
+	}
+	if action.Token != token || action.Kind != kind {
+		return action, false
+	}
+	sessionManager.Remove(r.Context(), sessionUndoKey)
+	if nowFunc().After(action.Expires) {
+		return action, false
+	}
+	return action, true
+}
+
+// FormulaUndo restores the formula, and its ingredient rows, removed by the
+// user's most recent delete.
+func FormulaUndo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	filters := pages.FormulaFiltersFromRequest(r)
+	render := func(message string) {
+		snapshot := buildWorkspaceSnapshot(r)
+		filtered := pages.FilterFormulas(snapshot.Formulas, filters)
+		renderComponent(w, r, pages.FormulaDeletionResult(message, "", filtered, filters, len(snapshot.Formulas)))
+	}
+	action, ok := takeUndo(r, undoKindFormula, r.FormValue("token"))
+	if !ok || database == nil {
+		render("This delete can no longer be undone.")
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := currentUserID(r)
+	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFormulaQuota(tx, userID, len(action.IDs)); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Formula{}).Where("id IN ?", action.IDs).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if len(action.Related) == 0 {
+			return nil
+		}
+		return tx.Unscoped().Model(&models.FormulaIngredient{}).Where("id IN ?", action.Related).Update("deleted_at", nil).Error
+	})
+	if message, ok := quotaMessage(err); ok {
+		render(message)
+		return
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to undo formula delete", "error", err, "formulaIDs", action.IDs)
+		render("We couldn't restore this formula. Please try again.")
+		return
+	}
+	applog.Info(ctx, "formula delete undone", "formulaIDs", action.IDs, "ingredients", len(action.Related))
+
+	render(fmt.Sprintf("\"%s\" restored.", action.Label))
+}
+
+// IngredientUndo restores the ingredient removed by the user's most recent
+// delete. Its aliases were never removed and come back with it.
+func IngredientUndo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	filters := pages.IngredientFiltersFromRequest(r)
+	render := func(message string) {
+		snapshot := buildWorkspaceSnapshot(r)
+		filtered := pages.FilterAromaChemicals(snapshot.AromaChemicals, filters)
+		renderComponent(w, r, pages.IngredientDeletionResult(message, "", filtered, filters, len(snapshot.AromaChemicals)))
+	}
+	action, ok := takeUndo(r, undoKindIngredient, r.FormValue("token"))
+	if !ok || database == nil {
+		render("This delete can no longer be undone.")
+		return
+	}
+
+	ctx := r.Context()
+	if err := database.WithContext(ctx).Unscoped().Model(&models.AromaChemical{}).
+		Where("id IN ?", action.IDs).Update("deleted_at", nil).Error; err != nil {
+		applog.Error(ctx, "failed to undo ingredient delete", "error", err, "ingredientIDs", action.IDs)
+		render("We couldn't restore this ingredient. Please try again.")
+		return
+	}
+	applog.Info(ctx, "ingredient delete undone", "ingredientIDs", action.IDs)
+
+	render(fmt.Sprintf("\"%s\" restored.", action.Label))
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+var undoTokenPattern = regexp.MustCompile(`token&#34;:&#34;([0-9a-f]+)&#34;`)
+
+func TestFormulaDeleteUndo(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	formula := models.Formula{Name: "Chypre", Version: 1, IsLatest: true}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	chemical := models.AromaChemical{IngredientName: "Oakmoss", OwnerID: 3}
+	db.Create(&chemical)
+	db.Create(&models.FormulaIngredient{FormulaID: formula.ID, AromaChemicalID: &chemical.ID, Amount: 2, Unit: "g"})
+
+	deleteReq := authenticatedFormRequest(t, sm, "/app/sections/formulas/delete", url.Values{"id": {strconv.Itoa(int(formula.ID))}}, 3)
+	rec := httptest.NewRecorder()
+	FormulaDelete(rec, deleteReq)
+	match := undoTokenPattern.FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		t.Fatalf("expected an undo button: %q", rec.Body.String())
+	}
+
+	undo := func(token string) string {
+		req := httptest.NewRequest(http.MethodPost, "/app/sections/formulas/undo", strings.NewReader(url.Values{"token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		FormulaUndo(rec, req.WithContext(deleteReq.Context()))
+		return rec.Body.String()
+	}
+	if body := undo("bogus"); !strings.Contains(body, "no longer be undone") {
+		t.Fatalf("wrong token should not restore: %q", body)
+	}
+
+	if body := undo(match[1]); !strings.Contains(body, "&#34;Chypre&#34; restored.") {
+		t.Fatalf("unexpected undo response: %q", body)
+	}
+	var rows int64
+	db.Model(&models.FormulaIngredient{}).Where("formula_id = ?", formula.ID).Count(&rows)
+	if err := db.First(&models.Formula{}, formula.ID).Error; err != nil || rows != 1 {
+		t.Fatalf("formula not restored: err=%v rows=%d", err, rows)
+	}
+	if body := undo(match[1]); !strings.Contains(body, "no longer be undone") {
+		t.Fatalf("undo ran twice: %q", body)
+	}
+}
+
+func TestIngredientDeleteUndoExpires(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	prevNow := nowFunc
+	t.Cleanup(func() { nowFunc = prevNow })
+
+	chemical := models.AromaChemical{IngredientName: "Hedione", OwnerID: 4}
+	db.Create(&chemical)
+
+	deleteReq := authenticatedFormRequest(t, sm, "/app/sections/ingredients/delete", url.Values{"id": {strconv.Itoa(int(chemical.ID))}}, 4)
+	rec := httptest.NewRecorder()
+	IngredientDelete(rec, deleteReq)
+	match := undoTokenPattern.FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		t.Fatalf("expected an undo button: %q", rec.Body.String())
+	}
+
+	nowFunc = func() time.Time { return time.Now().Add(undoWindow + time.Minute) }
+	req := httptest.NewRequest(http.MethodPost, "/app/sections/ingredients/undo", strings.NewReader(url.Values{"token": {match[1]}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	IngredientUndo(rec, req.WithContext(deleteReq.Context()))
+	if !strings.Contains(rec.Body.String(), "no longer be undone") {
+		t.Fatalf("expired undo should be refused: %q", rec.Body.String())
+	}
+	if err := db.First(&models.AromaChemical{}, chemical.ID).Error; err == nil {
+		t.Fatal("expired undo restored the ingredient")
+	}
+}
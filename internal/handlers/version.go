@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/version"
+)
+
+// Version reports the build metadata of the running binary.
+func Version(w http.ResponseWriter, r *http.Request) {
+	applog.Debug(r.Context(), "version requested", "method", r.Method)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(version.Get()); err != nil {
+		applog.Error(r.Context(), "failed to encode version response", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
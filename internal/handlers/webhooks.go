@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+	"perfugo/models/validation"
+)
+
+const (
+	// maxHookBody caps the JSON accepted by the inbound webhooks.
+	maxHookBody = 1 << 14
+	// maxEvaluationNote caps the text one evaluation note may append.
+	maxEvaluationNote = 2000
+	// maxHookOtherNames caps the aliases sent with a new ingredient.
+	maxHookOtherNames = 20
+)
+
+// hookIngredientRequest is the body of HookIngredientCreate.
+type hookIngredientRequest struct {
+	IngredientName  string   `json:"ingredient_name"`
+	CASNumber       string   `json:"cas_number"`
+	OtherNames      []string `json:"other_names"`
+	Type            string   `json:"type"`
+	PyramidPosition string   `json:"pyramid_position"`
+	Notes           string   `json:"notes"`
+}
+
+// hookIngredientResponse describes the ingredient a webhook created.
+type hookIngredientResponse struct {
+	ID             uint     `json:"id"`
+	IngredientName string   `json:"ingredient_name"`
+	CASNumber      string   `json:"cas_number,omitempty"`
+	OtherNames     []string `json:"other_names"`
+}
+
+// hookNoteRequest is the body of HookEvaluationNote. The ingredient is
+// named by exactly one of AromaChemicalID and IngredientName.
+type hookNoteRequest struct {
+	AromaChemicalID uint   `json:"aroma_chemical_id"`
+	IngredientName  string `json:"ingredient_name"`
+	Note            string `json:"note"`
+}
+
+// hookNoteResponse returns the user's whole note after the append.
+type hookNoteResponse struct {
+	AromaChemicalID uint   `json:"aroma_chemical_id"`
+	IngredientName  string `json:"ingredient_name"`
+	Perception      string `json:"perception"`
+}
+
+// HookIngredientCreate handles POST /hooks/v1/ingredients, creating a
+// private ingredient for the token's owner from a flat JSON object, the
+// shape no-code automation tools send. Unknown fields are refused, and an
+// ingredient the owner already has under the same name is a conflict so
+// retried deliveries do not create duplicates.
+func HookIngredientCreate(w http.ResponseWriter, r *http.Request) {
+	var body hookIngredientRequest
+	userID, ok := decodeHook(w, r, &body)
+	if !ok {
+		return
+	}
+
+	chemical := models.AromaChemical{
+		IngredientName:  body.IngredientName,
+		CASNumber:       strings.TrimSpace(body.CASNumber),
+		Type:            strings.TrimSpace(body.Type),
+		PyramidPosition: body.PyramidPosition,
+		Notes:           strings.TrimSpace(body.Notes),
+		OwnerID:         userID,
+	}
+	errs := validation.AromaChemical(&chemical)
+	seen := map[string]bool{strings.ToLower(chemical.IngredientName): true}
+	for _, name := range body.OtherNames {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		chemical.OtherNames = append(chemical.OtherNames, models.OtherName{Name: name})
+	}
+	if len(chemical.OtherNames) > maxHookOtherNames {
+		errs.Add("other_names", fmt.Sprintf("Send at most %d other names.", maxHookOtherNames))
+	}
+	if len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return
+	}
+
+	ctx := r.Context()
+	var existing int64
+	if err := database.WithContext(ctx).Model(&models.AromaChemical{}).
+		Where("owner_id = ? AND LOWER(ingredient_name) = ?", userID, strings.ToLower(chemical.IngredientName)).
+		Count(&existing).Error; err != nil {
+		applog.Error(ctx, "failed to check for duplicate ingredient", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "The ingredient could not be created. Please try again.")
+		return
+	}
+	if existing > 0 {
+		writeProblem(w, r, http.StatusConflict, fmt.Sprintf("You already have an ingredient named %q.", chemical.IngredientName))
+		return
+	}
+	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkIngredientQuota(tx, userID, 1); err != nil {
+			return err
+		}
+		return tx.Create(&chemical).Error
+	})
+	if message, ok := quotaMessage(err); ok {
+		writeProblem(w, r, http.StatusForbidden, message)
+		return
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to create ingredient from webhook", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "The ingredient could not be created. Please try again.")
+		return
+	}
+	applog.Info(ctx, "ingredient created from webhook", "ingredientID", chemical.ID, "userID", userID)
+
+	response := hookIngredientResponse{
+		ID:             chemical.ID,
+		IngredientName: chemical.IngredientName,
+		CASNumber:      chemical.CASNumber,
+		OtherNames:     []string{},
+	}
+	for _, other := range chemical.OtherNames {
+		response.OtherNames = append(response.OtherNames, other.Name)
+	}
+	writeHookJSON(w, r, http.StatusCreated, response)
+}
+
+// HookEvaluationNote handles POST /hooks/v1/evaluation-notes, appending a
+// dated line to the token owner's private note on an ingredient they can
+// see, such as a smelling-strip impression logged from a form or a chat.
+func HookEvaluationNote(w http.ResponseWriter, r *http.Request) {
+	var body hookNoteRequest
+	userID, ok := decodeHook(w, r, &body)
+	if !ok {
+		return
+	}
+
+	var errs validation.Errors
+	text := strings.TrimSpace(body.Note)
+	switch {
+	case text == "":
+		errs.Add("note", "Note is required.")
+	case len(text) > maxEvaluationNote:
+		errs.Add("note", fmt.Sprintf("Note must be at most %d characters.", maxEvaluationNote))
+	}
+	name := strings.TrimSpace(body.IngredientName)
+	if (body.AromaChemicalID == 0) == (name == "") {
+		errs.Add("aroma_chemical_id", "Send either aroma_chemical_id or ingredient_name.")
+	}
+	if len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return
+	}
+
+	ctx := r.Context()
+	visible := database.WithContext(ctx).Where("owner_id = ? OR public = ?", userID, true)
+	var chemicals []models.AromaChemical
+	var err error
+	if body.AromaChemicalID != 0 {
+		err = visible.Where("id = ?", body.AromaChemicalID).Find(&chemicals).Error
+	} else {
+		err = visible.Where("LOWER(ingredient_name) = ?", strings.ToLower(name)).Order("id asc").Find(&chemicals).Error
+	}
+	if err != nil {
+		applog.Error(ctx, "failed to find ingredient for webhook note", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "The note could not be saved. Please try again.")
+		return
+	}
+	// The user's own record wins over a shared one with the same name.
+	var owned []models.AromaChemical
+	for _, chemical := range chemicals {
+		if chemical.OwnerID == userID {
+			owned = append(owned, chemical)
+		}
+	}
+	if len(owned) > 0 {
+		chemicals = owned
+	}
+	if len(chemicals) == 0 {
+		writeProblem(w, r, http.StatusNotFound, "Ingredient not found.")
+		return
+	}
+	if len(chemicals) > 1 {
+		writeValidationProblem(w, r, validation.Errors{{Field: "ingredient_name", Message: "Several ingredients have this name; send aroma_chemical_id instead."}})
+		return
+	}
+	chemical := chemicals[0]
+
+	line := nowFunc().UTC().Format("2006-01-02") + ": " + text
+	var note models.UserIngredientNote
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND aroma_chemical_id = ?", userID, chemical.ID).First(&note).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			note = models.UserIngredientNote{UserID: userID, AromaChemicalID: chemical.ID, Perception: line}
+			return tx.Create(&note).Error
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(note.Perception) == "" {
+			note.Perception = line
+		} else {
+			note.Perception = strings.TrimRight(note.Perception, "\n") + "\n" + line
+		}
+		return tx.Model(&note).Update("perception", note.Perception).Error
+	})
+	if err != nil {
+		applog.Error(ctx, "failed to append webhook note", "error", err, "ingredientID", chemical.ID)
+		writeProblem(w, r, http.StatusInternalServerError, "The note could not be saved. Please try again.")
+		return
+	}
+	applog.Info(ctx, "evaluation note appended from webhook", "ingredientID", chemical.ID, "userID", userID)
+	writeHookJSON(w, r, http.StatusOK, hookNoteResponse{
+		AromaChemicalID: chemical.ID,
+		IngredientName:  chemical.IngredientName,
+		Perception:      note.Perception,
+	})
+}
+
+// decodeHook checks the method and content type of a webhook delivery and
+// decodes its JSON body into dest, refusing unknown fields and trailing
+// data. It writes the problem response and returns false when it cannot.
+func decodeHook(w http.ResponseWriter, r *http.Request, dest any) (uint, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeProblem(w, r, http.StatusMethodNotAllowed, "Use POST to deliver a webhook.")
+		return 0, false
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, "Send an API token as a Bearer token in the Authorization header.")
+		return 0, false
+	}
+	// Both webhooks write to the owner's ingredients.
+	if !requireAPIScope(w, r, models.ScopeIngredients, true) {
+		return 0, false
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "Send the body as application/json.")
+		return 0, false
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHookBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dest); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "The body must be a single JSON object with only the documented fields: "+err.Error())
+		return 0, false
+	}
+	if decoder.More() {
+		writeProblem(w, r, http.StatusBadRequest, "The body must be a single JSON object.")
+		return 0, false
+	}
+	return userID, true
+}
+
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, errs validation.Errors) {
+	fields := make([]fieldProblem, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, fieldProblem{Field: fe.Field, Message: fe.Message})
+	}
+	writeProblem(w, r, http.StatusUnprocessableEntity, "The request has invalid fields.", fields...)
+}
+
+func writeHookJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		applog.Error(r.Context(), "failed to encode webhook response", "error", err)
+	}
+}
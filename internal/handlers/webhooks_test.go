@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+func TestWebhooksAuthenticateWithAPITokens(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.APIToken{}, &models.UserIngredientNote{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	prevNow := nowFunc
+	nowFunc = func() time.Time { return time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { nowFunc = prevNow })
+
+	user := models.User{Email: "perfumer@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	APITokenCreate(rec, authenticatedFormRequest(t, sm, "/app/preferences/api-tokens", url.Values{"name": {"Zapier"}}, int(user.ID)))
+	token := regexp.MustCompile(`pfg_[A-Za-z0-9_-]+`).FindString(rec.Body.String())
+	if token == "" {
+		t.Fatalf("token not shown: %s", rec.Body.String())
+	}
+
+	call := func(path, contentType, auth, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		handler := HookIngredientCreate
+		if strings.HasSuffix(path, "evaluation-notes") {
+			handler = HookEvaluationNote
+		}
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		rec := httptest.NewRecorder()
+		RequireAPIToken(http.HandlerFunc(handler)).ServeHTTP(rec, req)
+		return rec
+	}
+	const ingredients = "/hooks/v1/ingredients"
+	const notes = "/hooks/v1/evaluation-notes"
+
+	if rec := call(ingredients, "application/json", "", `{"ingredient_name":"Iso E Super"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token status = %d", rec.Code)
+	}
+	if rec := call(ingredients, "application/json", "pfg_guess", `{"ingredient_name":"Iso E Super"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown token status = %d", rec.Code)
+	}
+	if rec := call(ingredients, "text/plain", token, `{"ingredient_name":"Iso E Super"}`); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("wrong content type status = %d", rec.Code)
+	}
+	if rec := call(ingredients, "application/json", token, `{"ingredient_name":"Iso E Super","color":"amber"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown field status = %d", rec.Code)
+	}
+	rec = call(ingredients, "application/json", token, `{"ingredient_name":"Iso E Super","cas_number":"not-a-cas"}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "cas_number") {
+		t.Fatalf("invalid CAS status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = call(ingredients, "application/json; charset=utf-8", token,
+		`{"ingredient_name":"Iso E Super","cas_number":"54464-57-2","pyramid_position":"base","other_names":["OTNE","otne"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var created hookIngredientResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var chemical models.AromaChemical
+	if err := db.Preload("OtherNames").First(&chemical, created.ID).Error; err != nil {
+		t.Fatalf("load chemical: %v", err)
+	}
+	if chemical.OwnerID != user.ID || chemical.Public || len(chemical.OtherNames) != 1 {
+		t.Fatalf("unexpected chemical: %+v", chemical)
+	}
+	if rec := call(ingredients, "application/json", token, `{"ingredient_name":"iso e super"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate status = %d", rec.Code)
+	}
+
+	call(notes, "application/json", token, `{"ingredient_name":"ISO E SUPER","note":"Velvety cedar."}`)
+	rec = call(notes, "application/json", token, fmt.Sprintf(`{"aroma_chemical_id":%d,"note":"Fades by noon."}`, chemical.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("note status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var note models.UserIngredientNote
+	db.Where("user_id = ? AND aroma_chemical_id = ?", user.ID, chemical.ID).First(&note)
+	if want := "2025-03-04: Velvety cedar.\n2025-03-04: Fades by noon."; note.Perception != want {
+		t.Fatalf("perception = %q, want %q", note.Perception, want)
+	}
+	if rec := call(notes, "application/json", token, `{"aroma_chemical_id":999,"note":"?"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown ingredient status = %d", rec.Code)
+	}
+	if rec := call(notes, "application/json", token, `{"note":""}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("empty note status = %d", rec.Code)
+	}
+
+	var stored models.APIToken
+	db.First(&stored)
+	if stored.LastUsedAt == nil {
+		t.Fatal("token use was not recorded")
+	}
+	rec = httptest.NewRecorder()
+	APITokenRevoke(rec, authenticatedFormRequest(t, sm, "/app/preferences/api-tokens/revoke", url.Values{"id": {fmt.Sprint(stored.ID)}}, int(user.ID)))
+	if rec := call(notes, "application/json", token, `{"aroma_chemical_id":1,"note":"x"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token status = %d", rec.Code)
+	}
+}
+
+func TestAPITokenScopesAreEnforced(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.APIToken{}, &models.UserIngredientNote{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	user := models.User{Email: "perfumer@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	issue := func(name string, scopes ...string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		APITokenCreate(rec, authenticatedFormRequest(t, sm, "/app/preferences/api-tokens", url.Values{"name": {name}, "scopes": scopes}, int(user.ID)))
+		token := regexp.MustCompile(`pfg_[A-Za-z0-9_-]+`).FindString(rec.Body.String())
+		if token == "" {
+			t.Fatalf("token not shown: %s", rec.Body.String())
+		}
+		return token
+	}
+	readOnly := issue("Dashboard", models.ScopeReadOnly)
+	reports := issue("Reports", models.ScopeReports)
+
+	call := func(handler http.HandlerFunc, method, target, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		RequireAuthenticationOrAPIToken(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call(HookIngredientCreate, http.MethodPost, "/hooks/v1/ingredients", readOnly, `{"ingredient_name":"Iso E Super"}`)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "read-only") {
+		t.Fatalf("read-only write status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(AliasLookup, http.MethodGet, "/app/api/aroma-chemicals/lookup?name=hedione", readOnly, ""); rec.Code != http.StatusOK {
+		t.Fatalf("read-only lookup status = %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = call(AliasLookup, http.MethodGet, "/app/api/aroma-chemicals/lookup?name=hedione", reports, "")
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "limited to reports") {
+		t.Fatalf("reports-only lookup status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(BatchReportAPI, http.MethodPost, "/app/api/reports/batch", reports, `{}`); rec.Code == http.StatusForbidden {
+		t.Fatalf("reports-only report was refused: %s", rec.Body.String())
+	}
+
+	var stored models.APIToken
+	db.Where("name = ?", "Reports").First(&stored)
+	if stored.Scopes != models.ScopeReports {
+		t.Fatalf("scopes = %q, want %q", stored.Scopes, models.ScopeReports)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"perfugo/internal/jobs"
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/emails"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// SendWeeklyDigest emails a user the summary of their week. It is the
+// sender behind the weekly digest job.
+func SendWeeklyDigest(ctx context.Context, digest jobs.WeeklyDigest) error {
+	if mailer == nil {
+		return errors.New("mail is not configured")
+	}
+	if digest.User.Email == "" {
+		return nil
+	}
+	msg, err := emails.WeeklyDigest(weeklyDigestData(digest)).Message(ctx, digest.User.Email)
+	if err == nil {
+		err = mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		return err
+	}
+	applog.Debug(ctx, "weekly digest emailed", "userID", digest.User.ID)
+	return nil
+}
+
+func weeklyDigestData(digest jobs.WeeklyDigest) emails.WeeklyDigestData {
+	data := emails.WeeklyDigestData{Since: digest.Since, Until: digest.Until}
+	user := digest.User
+	format := pages.NumberFormat{Amount: user.AmountDecimals, Percent: user.PercentDecimals, Price: user.PriceDecimals}.Normalize()
+
+	formulas := emails.DigestSection{Heading: "Formulas edited"}
+	for _, formula := range digest.Formulas {
+		formulas.Lines = append(formulas.Lines, fmt.Sprintf("%s · v%d · %s", formula.Name, formula.Version, pages.FormulaStatusLabel(pages.FormulaStatus(formula))))
+	}
+
+	batches := emails.DigestSection{Heading: "Batches made"}
+	for _, batch := range digest.Batches {
+		state := "in progress"
+		if batch.Finalized() {
+			state = "finalized"
+		}
+		batches.Lines = append(batches.Lines, fmt.Sprintf("%s lot %s · %s · %s", batch.FormulaName, batch.LotNumber, format.FormatQuantity(batch.TargetQuantity, "mg"), state))
+	}
+
+	stock := emails.DigestSection{Heading: "Running low"}
+	for _, item := range digest.LowStock {
+		name := "Unknown material"
+		if item.AromaChemical != nil {
+			name = item.AromaChemical.IngredientName
+		}
+		if label := item.DilutionLabel(); label != "" {
+			name += " " + label
+		}
+		stock.Lines = append(stock.Lines, fmt.Sprintf("%s · %s neat left", name, format.FormatQuantity(item.NeatQuantityMg(), "mg")))
+	}
+
+	checks := emails.DigestSection{Heading: "Maceration checks"}
+	for _, check := range digest.Checks {
+		verb := "due"
+		if check.Checkpoint.Due.Before(digest.Until) {
+			verb = "was due"
+		}
+		checks.Lines = append(checks.Lines, fmt.Sprintf("%s lot %s · day %d %s %s", check.Batch.FormulaName, check.Batch.LotNumber, check.Checkpoint.Day, verb, check.Checkpoint.Due.Format("02 Jan 2006")))
+	}
+
+	data.Sections = []emails.DigestSection{formulas, batches, stock, checks}
+	return data
+}
+
+// DigestPreferences turns the weekly digest email on or off.
+func DigestPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	enabled := checkboxChecked(r.FormValue("weekly_digest"))
+	if err := database.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("weekly_digest", enabled).Error; err != nil {
+		applog.Error(ctx, "failed to update weekly digest preference", "error", err, "userID", userID)
+		http.Error(w, "unable to save preferences", http.StatusInternalServerError)
+		return
+	}
+	applog.Debug(ctx, "weekly digest preference persisted", "userID", userID, "enabled", enabled)
+
+	if !isHTMX(r) {
+		http.Redirect(w, r, pages.Path("/app/preferences"), http.StatusSeeOther)
+		return
+	}
+	message := "The weekly digest is off."
+	switch {
+	case enabled && mailer == nil:
+		message = "Saved, but this instance has no mail server configured, so no digest will be sent."
+	case enabled:
+		message = "You will receive a digest of your week every seven days."
+	}
+	renderComponent(w, r, pages.WeeklyDigestControl(enabled, message))
+}
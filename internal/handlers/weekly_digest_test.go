@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"perfugo/internal/db/mock"
+	"perfugo/internal/jobs"
+	"perfugo/models"
+)
+
+func TestDigestPreferencesOptIn(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db, err := mock.New(context.Background())
+	if err != nil {
+		t.Fatalf("mock database: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+	previous := mailer
+	ConfigureMail(&recordingMailer{})
+	t.Cleanup(func() { mailer = previous })
+
+	user := models.User{Email: "digest@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	req := authenticatedFormRequest(t, sm, "/app/preferences/digest", url.Values{"weekly_digest": {"true"}}, int(user.ID))
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	DigestPreferences(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "digest of your week every seven days") {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+	var reloaded models.User
+	db.First(&reloaded, user.ID)
+	if !reloaded.WeeklyDigest {
+		t.Fatal("expected the weekly digest to be on")
+	}
+
+	req = authenticatedFormRequest(t, sm, "/app/preferences/digest", url.Values{}, int(user.ID))
+	req.Header.Set("HX-Request", "true")
+	rec = httptest.NewRecorder()
+	DigestPreferences(rec, req)
+	db.First(&reloaded, user.ID)
+	if reloaded.WeeklyDigest || !strings.Contains(rec.Body.String(), "The weekly digest is off.") {
+		t.Fatalf("expected the weekly digest to be off: %s", rec.Body.String())
+	}
+}
+
+func TestSendWeeklyDigestEmailsSummary(t *testing.T) {
+	recorder := &recordingMailer{}
+	previous := mailer
+	ConfigureMail(recorder)
+	t.Cleanup(func() { mailer = previous })
+
+	finalized := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	batch := models.ProductionBatch{FormulaName: "Chypre", LotNumber: "L-7", TargetQuantity: 50000, Status: models.ProductionBatchFinalized, FinalizedAt: &finalized}
+	digest := jobs.WeeklyDigest{
+		User:     models.User{Email: "ada@example.com"},
+		Since:    time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		Until:    time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC),
+		Formulas: []models.Formula{{Name: "Chypre", Version: 3}},
+		Batches:  []models.ProductionBatch{batch},
+		LowStock: []models.InventoryItem{{QuantityMg: 5000, DilutionPercent: 10, Solvent: models.SolventDPG, AromaChemical: &models.AromaChemical{IngredientName: "Hedione"}}},
+		Checks:   []jobs.DigestCheck{{Batch: batch, Checkpoint: batch.Checkpoints()[0]}},
+	}
+	if err := SendWeeklyDigest(context.Background(), digest); err != nil {
+		t.Fatalf("SendWeeklyDigest: %v", err)
+	}
+	if len(recorder.sent) != 1 || recorder.sent[0].To != "ada@example.com" {
+		t.Fatalf("expected one digest email, got %+v", recorder.sent)
+	}
+	text := recorder.sent[0].Text
+	for _, want := range []string{
+		"- Chypre · v3",
+		"- Chypre lot L-7 · 50000 mg · finalized",
+		"- Hedione 10% in DPG · 500 mg neat left",
+		"- Chypre lot L-7 · day 7 was due 08 Mar 2026",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in %q", want, text)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/views/pages"
+	"perfugo/models"
+)
+
+// WishlistUpdate adds a material to the current user's wishlist, or updates
+// the supplier and note of one already on it. Wishlisted materials are not
+// stock: formulas using them are flagged as not buildable yet.
+func WishlistUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	chemicalID := pages.ParseUint(r.FormValue("aroma_chemical_id"))
+	var chemical models.AromaChemical
+	if chemicalID == 0 || database.WithContext(ctx).
+		Where("id = ? AND (owner_id = ? OR public = ?)", chemicalID, userID, true).
+		First(&chemical).Error != nil {
+		renderWishlist(w, r, userID, "Choose a material from your library.")
+		return
+	}
+
+	var item models.WishlistItem
+	if err := database.WithContext(ctx).
+		Where("owner_id = ? AND aroma_chemical_id = ?", userID, chemicalID).
+		Attrs(models.WishlistItem{OwnerID: userID, AromaChemicalID: chemicalID}).
+		FirstOrInit(&item).Error; err != nil {
+		applog.Error(ctx, "failed to load wishlist item", "error", err, "userID", userID)
+		http.Error(w, "unable to save wishlist", http.StatusInternalServerError)
+		return
+	}
+	item.Supplier = strings.TrimSpace(r.FormValue("supplier"))
+	item.Note = strings.TrimSpace(r.FormValue("note"))
+	if err := database.WithContext(ctx).Save(&item).Error; err != nil {
+		applog.Error(ctx, "failed to save wishlist item", "error", err, "userID", userID)
+		http.Error(w, "unable to save wishlist", http.StatusInternalServerError)
+		return
+	}
+	applog.Debug(ctx, "wishlist updated", "userID", userID, "chemicalID", chemicalID)
+
+	renderWishlist(w, r, userID, chemical.IngredientName+" is on your wishlist.")
+}
+
+// WishlistRemove takes a material off the current user's wishlist.
+func WishlistRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if database == nil {
+		writeNoDatabase(w, r)
+		return
+	}
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	chemicalID := pages.ParseUint(r.FormValue("aroma_chemical_id"))
+	if err := database.WithContext(ctx).Unscoped().
+		Where("owner_id = ? AND aroma_chemical_id = ?", userID, chemicalID).
+		Delete(&models.WishlistItem{}).Error; err != nil {
+		applog.Error(ctx, "failed to remove wishlist item", "error", err, "userID", userID)
+		http.Error(w, "unable to update wishlist", http.StatusInternalServerError)
+		return
+	}
+
+	renderWishlist(w, r, userID, "Removed from your wishlist.")
+}
+
+func renderWishlist(w http.ResponseWriter, r *http.Request, userID uint, message string) {
+	panel := loadWishlistPanel(r.Context(), userID, loadInventoryPanel(r.Context(), userID).Stocked())
+	panel.Message = message
+	renderComponent(w, r, pages.WishlistControl(panel, loadAromaChemicals(r.Context(), userID)))
+}
+
+// loadWishlistPanel lists the owner's wishlisted materials by name, marking
+// those already in stock.
+func loadWishlistPanel(ctx context.Context, ownerID uint, stocked map[uint]bool) pages.WishlistPanel {
+	var panel pages.WishlistPanel
+	if database == nil || ownerID == 0 {
+		return panel
+	}
+
+	var items []models.WishlistItem
+	if err := database.WithContext(ctx).
+		Preload("AromaChemical").
+		Where("owner_id = ?", ownerID).
+		Find(&items).Error; err != nil {
+		applog.Error(ctx, "failed to load wishlist", "error", err, "userID", ownerID)
+		return panel
+	}
+	for _, item := range items {
+		if item.AromaChemical == nil {
+			continue
+		}
+		panel.Items = append(panel.Items, pages.WishlistRow{
+			ChemicalID:     item.AromaChemicalID,
+			IngredientName: item.AromaChemical.IngredientName,
+			Supplier:       item.Supplier,
+			Note:           item.Note,
+			InStock:        stocked[item.AromaChemicalID],
+		})
+	}
+	sort.SliceStable(panel.Items, func(i, j int) bool {
+		return strings.ToLower(panel.Items[i].IngredientName) < strings.ToLower(panel.Items[j].IngredientName)
+	})
+	return panel
+}
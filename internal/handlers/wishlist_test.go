@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestWishlistMarksFormulasNotBuildableYet(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.InventoryItem{}, &models.WishlistItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	const ownerID = 7
+	oud := models.AromaChemical{IngredientName: "Oud oil", OwnerID: ownerID}
+	if err := db.Create(&oud).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+	base := models.Formula{Name: "Oud base", Version: 1, IsLatest: true}
+	finished := models.Formula{Name: "Nuit", Version: 1, IsLatest: true}
+	for _, formula := range []*models.Formula{&base, &finished} {
+		if err := db.Create(formula).Error; err != nil {
+			t.Fatalf("create formula: %v", err)
+		}
+	}
+	rows := []models.FormulaIngredient{
+		{FormulaID: base.ID, AromaChemicalID: &oud.ID, Amount: 1, Unit: "g"},
+		{FormulaID: finished.ID, SubFormulaID: &base.ID, Amount: 5, Unit: "g"},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatalf("create ingredients: %v", err)
+	}
+
+	id := strconv.Itoa(int(oud.ID))
+	post := func(handler http.HandlerFunc, path string, form url.Values) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, authenticatedFormRequest(t, sm, path, form, ownerID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, rec.Code)
+		}
+		return rec.Body.String()
+	}
+	badges := func() int {
+		t.Helper()
+		req := authenticatedFormRequest(t, sm, "/app/sections/formulas/table", nil, ownerID)
+		req.Method = http.MethodGet
+		rec := httptest.NewRecorder()
+		FormulaList(rec, req)
+		return strings.Count(rec.Body.String(), "Not buildable yet")
+	}
+
+	body := post(WishlistUpdate, "/app/reports/wishlist", url.Values{"aroma_chemical_id": {id}, "supplier": {"Hermitage"}})
+	if !strings.Contains(body, "Oud oil is on your wishlist.") || !strings.Contains(body, "Hermitage") {
+		t.Fatalf("unexpected wishlist response: %q", body)
+	}
+	if got := badges(); got != 2 {
+		t.Fatalf("expected both formulas flagged, got %d badges", got)
+	}
+
+	post(InventoryUpdate, "/app/reports/inventory", url.Values{"aroma_chemical_id": {id}, "quantity_mg": {"500"}})
+	if got := badges(); got != 0 {
+		t.Fatalf("expected stocked formulas to be buildable, got %d badges", got)
+	}
+	if !strings.Contains(post(WishlistUpdate, "/app/reports/wishlist", url.Values{"aroma_chemical_id": {id}}), "In stock") {
+		t.Fatal("expected the wishlist to mark the stocked material")
+	}
+
+	post(WishlistRemove, "/app/reports/wishlist/remove", url.Values{"aroma_chemical_id": {id}})
+	var count int64
+	db.Unscoped().Model(&models.WishlistItem{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected the wishlist to be empty, found %d items", count)
+	}
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -11,9 +13,11 @@ import (
 	"github.com/a-h/templ"
 	"gorm.io/gorm"
 
+	"perfugo/internal/analytics"
 	applog "perfugo/internal/log"
 	"perfugo/internal/views/pages"
 	"perfugo/models"
+	"perfugo/models/validation"
 )
 
 func parseOptionalFloat(value string) (float64, error) {
@@ -45,6 +49,91 @@ func checkboxChecked(value string) bool {
 	}
 }
 
+// applyIngredientForm copies the ingredient editor fields onto chemical and
+// returns parse and domain validation errors in form order.
+func applyIngredientForm(r *http.Request, chemical *models.AromaChemical) validation.Errors {
+	var errs validation.Errors
+
+	chemical.IngredientName = strings.TrimSpace(r.FormValue("ingredient_name"))
+	chemical.CASNumber = strings.TrimSpace(r.FormValue("cas_number"))
+	chemical.INCIName = strings.TrimSpace(r.FormValue("inci_name"))
+	chemical.Type = strings.TrimSpace(r.FormValue("type"))
+	chemical.PyramidPosition = strings.TrimSpace(r.FormValue("pyramid_position"))
+	previousWheel := chemical.WheelPosition
+	chemical.WheelPosition = strings.TrimSpace(r.FormValue("wheel_position"))
+	chemical.Duration = strings.TrimSpace(r.FormValue("duration"))
+	chemical.Notes = strings.TrimSpace(r.FormValue("notes"))
+	chemical.Usage = strings.TrimSpace(r.FormValue("usage"))
+	chemical.HistoricRole = strings.TrimSpace(r.FormValue("historic_role"))
+	chemical.Solvent = checkboxChecked(r.FormValue("solvent"))
+	chemical.Origin = strings.TrimSpace(r.FormValue("origin"))
+	chemical.AnimalDerived = checkboxChecked(r.FormValue("animal_derived"))
+
+	if value, err := parseOptionalInt(r.FormValue("strength")); err != nil {
+		errs.Add("strength", "Strength must be a whole number.")
+	} else {
+		chemical.Strength = value
+	}
+
+	floats := []struct {
+		field   string
+		message string
+		target  *float64
+	}{
+		{"recommended_dilution", "Recommended dilution must be a number.", &chemical.RecommendedDilution},
+		{"dilution_percentage", "Dilution percentage must be a number.", &chemical.DilutionPercentage},
+		{"max_ifra_percentage", "Max IFRA percentage must be a number.", &chemical.MaxIFRAPercentage},
+		{"price_per_mg", "Price per mg must be a number.", &chemical.PricePerMg},
+	}
+	for _, input := range floats {
+		value, err := parseOptionalFloat(r.FormValue(input.field))
+		if err != nil {
+			errs.Add(input.field, input.message)
+			continue
+		}
+		*input.target = value
+	}
+
+	if value, err := parseOptionalInt(r.FormValue("popularity")); err != nil {
+		errs.Add("popularity", "Popularity must be a whole number.")
+	} else {
+		chemical.Popularity = value
+	}
+
+	if term, ok := governedTerm(r.Context(), models.TaxonomyWheel, chemical.WheelPosition, previousWheel); ok {
+		chemical.WheelPosition = term
+	} else {
+		errs.Add("wheel_position", "Wheel position must be one of the shared wheel families; suggest a new one for review.")
+	}
+
+	for _, fe := range validation.AromaChemical(chemical) {
+		if !errs.Has(fe.Field) {
+			errs = append(errs, fe)
+		}
+	}
+	sortIngredientFieldErrors(errs)
+	return errs
+}
+
+var ingredientFieldOrder = []string{
+	"ingredient_name", "cas_number", "inci_name", "strength", "pyramid_position", "wheel_position",
+	"recommended_dilution", "dilution_percentage", "max_ifra_percentage", "price_per_mg", "popularity",
+}
+
+func sortIngredientFieldErrors(errs validation.Errors) {
+	rank := func(field string) int {
+		for i, candidate := range ingredientFieldOrder {
+			if candidate == field {
+				return i
+			}
+		}
+		return len(ingredientFieldOrder)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return rank(errs[i].Field) < rank(errs[j].Field)
+	})
+}
+
 func buildFormulaDependencyGraph(formulas []models.Formula) map[uint][]uint {
 	graph := make(map[uint][]uint, len(formulas))
 	for _, formula := range formulas {
@@ -110,7 +199,8 @@ func IngredientTable(w http.ResponseWriter, r *http.Request) {
 	renderComponent(w, r, pages.IngredientTable(chemicals, filters, len(snapshot.AromaChemicals)))
 }
 
-// IngredientDetail renders the detail card for a single aroma chemical.
+// IngredientDetail renders the detail card for a single aroma chemical along
+// with the current user's private note on it.
 func IngredientDetail(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -120,8 +210,13 @@ func IngredientDetail(w http.ResponseWriter, r *http.Request) {
 	snapshot := buildWorkspaceSnapshot(r)
 	id := pages.ParseUint(r.URL.Query().Get("id"))
 	chemical := pages.FindAromaChemical(snapshot.AromaChemicals, id)
+	var note *models.UserIngredientNote
+	if chemical != nil {
+		recordActivity(r.Context(), models.ActivityEntityAromaChemical, chemical.ID, analytics.EventView)
+		note = loadIngredientNote(r, chemical.ID)
+	}
 
-	renderComponent(w, r, pages.IngredientDetail(chemical))
+	renderComponent(w, r, pages.IngredientDetail(chemical, note))
 }
 
 // IngredientEdit renders the edit form for a selected aroma chemical.
@@ -175,82 +270,13 @@ func IngredientUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	name := strings.TrimSpace(r.FormValue("ingredient_name"))
-	if name == "" {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Ingredient name is required."))
-		return
-	}
-
-	strengthInput := strings.TrimSpace(r.FormValue("strength"))
-	strengthValue := chemical.Strength
-	var strengthErr error
-	if strengthInput == "" {
-		strengthValue = 0
-	} else {
-		parsed, err := strconv.Atoi(strengthInput)
-		if err != nil {
-			strengthErr = err
-		} else {
-			strengthValue = parsed
-		}
-	}
-
-	rawPyramid := r.FormValue("pyramid_position")
-	pyramidValue, pyramidOK := pages.NormalizePyramidPosition(rawPyramid)
-	if !pyramidOK {
-		chemical.PyramidPosition = strings.TrimSpace(rawPyramid)
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Select a valid pyramid position."))
-		return
-	}
-	chemical.PyramidPosition = pyramidValue
-
-	recommendedValue, err := parseOptionalFloat(r.FormValue("recommended_dilution"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Recommended dilution must be a number."))
-		return
-	}
-	dilutionValue, err := parseOptionalFloat(r.FormValue("dilution_percentage"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Dilution percentage must be a number."))
-		return
-	}
-	maxIFRAValue, err := parseOptionalFloat(r.FormValue("max_ifra_percentage"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Max IFRA percentage must be a number."))
-		return
-	}
-	priceValue, err := parseOptionalFloat(r.FormValue("price_per_mg"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Price per mg must be a number."))
-		return
-	}
-	popularityValue, err := parseOptionalInt(r.FormValue("popularity"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Popularity must be a whole number."))
+	if errs := applyIngredientForm(r, chemical); len(errs) > 0 {
+		renderComponent(w, r, pages.IngredientEditor(chemical, errs.Error()))
 		return
 	}
 
-	chemical.RecommendedDilution = recommendedValue
-	chemical.DilutionPercentage = dilutionValue
-	chemical.MaxIFRAPercentage = maxIFRAValue
-	chemical.PricePerMg = priceValue
-	chemical.Popularity = popularityValue
-	chemical.Solvent = checkboxChecked(r.FormValue("solvent"))
-	chemical.HistoricRole = strings.TrimSpace(r.FormValue("historic_role"))
-	chemical.Solvent = checkboxChecked(r.FormValue("solvent"))
-
 	if database == nil {
-		message := "Editing is unavailable because no database connection is configured."
-		chemical.IngredientName = name
-		chemical.CASNumber = strings.TrimSpace(r.FormValue("cas_number"))
-		chemical.Type = strings.TrimSpace(r.FormValue("type"))
-		chemical.WheelPosition = strings.TrimSpace(r.FormValue("wheel_position"))
-		chemical.Duration = strings.TrimSpace(r.FormValue("duration"))
-		chemical.Notes = strings.TrimSpace(r.FormValue("notes"))
-		chemical.Usage = strings.TrimSpace(r.FormValue("usage"))
-		if strengthErr == nil {
-			chemical.Strength = strengthValue
-		}
+		message := pages.DemoModeMessage
 		renderComponent(w, r, pages.IngredientEditor(chemical, message))
 		return
 	}
@@ -273,31 +299,31 @@ func IngredientUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if stored.OwnerID != userID {
+	if !canManageChemical(r, stored.OwnerID, userID) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
 	updates := map[string]interface{}{
-		"ingredient_name":      name,
-		"cas_number":           strings.TrimSpace(r.FormValue("cas_number")),
-		"type":                 strings.TrimSpace(r.FormValue("type")),
-		"pyramid_position":     pyramidValue,
-		"wheel_position":       strings.TrimSpace(r.FormValue("wheel_position")),
-		"duration":             strings.TrimSpace(r.FormValue("duration")),
-		"notes":                strings.TrimSpace(r.FormValue("notes")),
-		"usage":                strings.TrimSpace(r.FormValue("usage")),
+		"ingredient_name":      chemical.IngredientName,
+		"cas_number":           chemical.CASNumber,
+		"inci_name":            chemical.INCIName,
+		"type":                 chemical.Type,
+		"pyramid_position":     chemical.PyramidPosition,
+		"wheel_position":       chemical.WheelPosition,
+		"duration":             chemical.Duration,
+		"notes":                chemical.Notes,
+		"usage":                chemical.Usage,
 		"solvent":              chemical.Solvent,
-		"recommended_dilution": recommendedValue,
-		"dilution_percentage":  dilutionValue,
-		"max_ifra_percentage":  maxIFRAValue,
-		"price_per_mg":         priceValue,
-		"historic_role":        strings.TrimSpace(r.FormValue("historic_role")),
-		"popularity":           popularityValue,
-	}
-
-	if strengthErr == nil {
-		updates["strength"] = strengthValue
+		"origin":               chemical.Origin,
+		"animal_derived":       chemical.AnimalDerived,
+		"recommended_dilution": chemical.RecommendedDilution,
+		"dilution_percentage":  chemical.DilutionPercentage,
+		"max_ifra_percentage":  chemical.MaxIFRAPercentage,
+		"price_per_mg":         chemical.PricePerMg,
+		"historic_role":        chemical.HistoricRole,
+		"popularity":           chemical.Popularity,
+		"strength":             chemical.Strength,
 	}
 
 	if err := database.WithContext(ctx).Model(&stored).Updates(updates).Error; err != nil {
@@ -312,12 +338,8 @@ func IngredientUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	status := "Ingredient updated successfully."
-	if strengthErr != nil {
-		status = "Ingredient updated, but the strength value must be a whole number. The previous value was kept."
-	}
-
-	renderComponent(w, r, pages.IngredientEditor(&stored, status))
+	recordActivity(ctx, models.ActivityEntityAromaChemical, stored.ID, analytics.EventEdit)
+	renderComponent(w, r, pages.IngredientEditor(&stored, "Ingredient updated successfully."))
 }
 
 // IngredientCreate persists a new aroma chemical owned by the current user.
@@ -333,77 +355,14 @@ func IngredientCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	name := strings.TrimSpace(r.FormValue("ingredient_name"))
 	chemical := &models.AromaChemical{}
-	chemical.IngredientName = name
-	chemical.CASNumber = strings.TrimSpace(r.FormValue("cas_number"))
-	chemical.Type = strings.TrimSpace(r.FormValue("type"))
-	chemical.WheelPosition = strings.TrimSpace(r.FormValue("wheel_position"))
-	chemical.Duration = strings.TrimSpace(r.FormValue("duration"))
-	chemical.Notes = strings.TrimSpace(r.FormValue("notes"))
-	chemical.Usage = strings.TrimSpace(r.FormValue("usage"))
-	chemical.HistoricRole = strings.TrimSpace(r.FormValue("historic_role"))
-
-	if name == "" {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Ingredient name is required."))
-		return
-	}
-
-	strengthInput := strings.TrimSpace(r.FormValue("strength"))
-	strengthValue := 0
-	if strengthInput != "" {
-		parsed, err := strconv.Atoi(strengthInput)
-		if err != nil {
-			renderComponent(w, r, pages.IngredientEditor(chemical, "Strength must be a whole number."))
-			return
-		}
-		strengthValue = parsed
-	}
-	chemical.Strength = strengthValue
-
-	rawPyramid := r.FormValue("pyramid_position")
-	pyramidValue, pyramidOK := pages.NormalizePyramidPosition(rawPyramid)
-	if !pyramidOK {
-		chemical.PyramidPosition = strings.TrimSpace(rawPyramid)
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Select a valid pyramid position."))
-		return
-	}
-	chemical.PyramidPosition = pyramidValue
-
-	recommendedValue, err := parseOptionalFloat(r.FormValue("recommended_dilution"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Recommended dilution must be a number."))
-		return
-	}
-	dilutionValue, err := parseOptionalFloat(r.FormValue("dilution_percentage"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Dilution percentage must be a number."))
-		return
-	}
-	maxIFRAValue, err := parseOptionalFloat(r.FormValue("max_ifra_percentage"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Max IFRA percentage must be a number."))
-		return
-	}
-	priceValue, err := parseOptionalFloat(r.FormValue("price_per_mg"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Price per mg must be a number."))
+	if errs := applyIngredientForm(r, chemical); len(errs) > 0 {
+		renderComponent(w, r, pages.IngredientEditor(chemical, errs.Error()))
 		return
 	}
-	popularityValue, err := parseOptionalInt(r.FormValue("popularity"))
-	if err != nil {
-		renderComponent(w, r, pages.IngredientEditor(chemical, "Popularity must be a whole number."))
-		return
-	}
-
-	chemical.RecommendedDilution = recommendedValue
-	chemical.DilutionPercentage = dilutionValue
-	chemical.MaxIFRAPercentage = maxIFRAValue
-	chemical.PricePerMg = priceValue
-	chemical.Popularity = popularityValue
 
 	if database == nil {
-		message := "Creating ingredients is unavailable because no database connection is configured."
+		message := pages.DemoModeMessage
 		renderComponent(w, r, pages.IngredientEditor(chemical, message))
 		return
 	}
@@ -418,6 +377,15 @@ func IngredientCreate(w http.ResponseWriter, r *http.Request) {
 	chemical.Public = false
 
 	ctx := r.Context()
+	if err := checkIngredientQuota(database.WithContext(ctx), userID, 1); err != nil {
+		message, ok := quotaMessage(err)
+		if !ok {
+			applog.Error(ctx, "failed to check ingredient quota", "error", err)
+			message = "We couldn't create this ingredient. Please try again."
+		}
+		renderComponent(w, r, pages.IngredientEditor(chemical, message))
+		return
+	}
 	if err := database.WithContext(ctx).Create(chemical).Error; err != nil {
 		applog.Error(ctx, "failed to create ingredient", "error", err)
 		renderComponent(w, r, pages.IngredientEditor(chemical, "We couldn't create this ingredient. Please try again."))
@@ -461,6 +429,9 @@ func FormulaDetail(w http.ResponseWriter, r *http.Request) {
 	id := pages.ParseUint(r.URL.Query().Get("id"))
 	formula := pages.FindFormula(snapshot.Formulas, id)
 	ingredients := pages.FormulaIngredientsFor(snapshot.FormulaIngredients, id)
+	if formula != nil {
+		recordActivity(r.Context(), models.ActivityEntityFormula, formula.ID, analytics.EventView)
+	}
 
 	renderComponent(w, r, pages.FormulaDetail(formula, ingredients))
 }
@@ -478,8 +449,7 @@ func FormulaCreate(w http.ResponseWriter, r *http.Request) {
 	total := len(snapshot.Formulas)
 
 	if database == nil {
-		message := "Creating formulas is unavailable because no database connection is configured."
-		renderComponent(w, r, pages.FormulaCreationError(message, filtered, filters, total))
+		renderComponent(w, r, pages.FormulaCreationError(pages.DemoModeMessage, filtered, filters, total))
 		return
 	}
 
@@ -490,6 +460,19 @@ func FormulaCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	userID, _ := currentUserID(r)
+	if userID != 0 {
+		record.CreatedByID = &userID
+	}
+	if err := checkFormulaQuota(database.WithContext(ctx), userID, 1); err != nil {
+		message, ok := quotaMessage(err)
+		if !ok {
+			applog.Error(ctx, "failed to check formula quota", "error", err)
+			message = "We couldn't start a new formula. Please try again."
+		}
+		renderComponent(w, r, pages.FormulaCreationError(message, filtered, filters, total))
+		return
+	}
 	if err := database.WithContext(ctx).Create(&record).Error; err != nil {
 		applog.Error(ctx, "failed to create formula", "error", err)
 		renderComponent(w, r, pages.FormulaCreationError("We couldn't start a new formula. Please try again.", filtered, filters, total))
@@ -504,7 +487,7 @@ func FormulaCreate(w http.ResponseWriter, r *http.Request) {
 	ingredients := pages.FormulaIngredientsFor(refreshed.FormulaIngredients, created.ID)
 
 	refreshedFiltered := pages.FilterFormulas(refreshed.Formulas, filters)
-	if filters.Query != "" {
+	if filters.Active() {
 		found := false
 		for _, formula := range refreshedFiltered {
 			if formula.ID == created.ID {
@@ -551,29 +534,172 @@ type formulaIngredientUpdate struct {
 	Unit            string
 	AromaChemicalID *uint
 	SubFormulaID    *uint
+	StockSolutionID *uint
+	Percentage      float64
+	Position        int
 	RowKey          string
 }
 
-func parseIngredientSource(value string) (*uint, *uint, error) {
+// percentEntryUnit marks editor rows whose amount is a share of the formula.
+const percentEntryUnit = "%"
+
+var (
+	errMixedEntryModes   = errors.New("formula rows mix percentages and amounts")
+	errEmptyPercentTotal = errors.New("formula rows add up to zero")
+)
+
+// applyFormulaEntryMode normalises submitted rows. Rows entered as
+// percentages are scaled to 100% and given an amount out of a 1000 mg batch.
+// In percentage mode, rows entered as amounts keep their amounts and gain the
+// matching percentage; otherwise percentages are cleared.
+func applyFormulaEntryMode(updates []formulaIngredientUpdate, percent bool) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	percentRows := 0
+	for _, update := range updates {
+		if update.Unit == percentEntryUnit {
+			percentRows++
+		}
+	}
+	if percentRows != 0 && percentRows != len(updates) {
+		return errMixedEntryModes
+	}
+
+	total := 0.0
+	for _, update := range updates {
+		if percentRows > 0 {
+			total += update.Amount
+		} else {
+			total += normalizeAmount(update.Amount, update.Unit)
+		}
+	}
+
+	for i := range updates {
+		update := &updates[i]
+		if percentRows > 0 {
+			if total <= 0 {
+				return errEmptyPercentTotal
+			}
+			share := update.Amount / total * 100
+			update.Amount = math.Round(share/100*targetFormulaTotalMG*1000) / 1000
+			update.Unit = "mg"
+			update.Percentage = 0
+			if percent {
+				update.Percentage = share
+			}
+			continue
+		}
+		update.Percentage = 0
+		if percent {
+			if total <= 0 {
+				return errEmptyPercentTotal
+			}
+			update.Percentage = normalizeAmount(update.Amount, update.Unit) / total * 100
+		}
+	}
+	return nil
+}
+
+// ingredientSourceKey identifies what a row adds to the formula, so rows
+// listing the same chemical, stock solution or sub-formula share a key.
+func ingredientSourceKey(update formulaIngredientUpdate) string {
+	switch {
+	case update.StockSolutionID != nil:
+		return fmt.Sprintf("stock:%d", *update.StockSolutionID)
+	case update.SubFormulaID != nil:
+		return fmt.Sprintf("formula:%d", *update.SubFormulaID)
+	case update.AromaChemicalID != nil:
+		return fmt.Sprintf("chem:%d", *update.AromaChemicalID)
+	}
+	return ""
+}
+
+// consolidateIngredientRows merges rows listing the same source into the
+// first of them, summing amounts and percentages. Amounts in different units
+// are summed in grams. It returns the merged rows, the index each kept row
+// had in updates and the stored entries the merge made redundant.
+func consolidateIngredientRows(updates []formulaIngredientUpdate) ([]formulaIngredientUpdate, []int, []uint) {
+	merged := make([]formulaIngredientUpdate, 0, len(updates))
+	kept := make([]int, 0, len(updates))
+	var redundant []uint
+	first := map[string]int{}
+	for i, update := range updates {
+		key := ingredientSourceKey(update)
+		target, seen := first[key]
+		if !seen {
+			first[key] = len(merged)
+			update.Position = len(merged)
+			merged = append(merged, update)
+			kept = append(kept, i)
+			continue
+		}
+		row := &merged[target]
+		if row.Unit == update.Unit {
+			row.Amount += update.Amount
+		} else {
+			row.Amount = normalizeAmount(row.Amount, row.Unit) + normalizeAmount(update.Amount, update.Unit)
+			row.Unit = "g"
+		}
+		row.Percentage += update.Percentage
+		if update.ID > 0 {
+			if row.ID == 0 {
+				row.ID = update.ID
+			} else {
+				redundant = append(redundant, update.ID)
+			}
+		}
+	}
+	return merged, kept, redundant
+}
+
+// duplicateRowWarning names the ingredients listed in more than one row.
+func duplicateRowWarning(updates []formulaIngredientUpdate, snapshot pages.WorkspaceSnapshot) string {
+	counts := map[string]int{}
+	var names []string
+	for _, update := range updates {
+		key := ingredientSourceKey(update)
+		counts[key]++
+		if counts[key] != 2 {
+			continue
+		}
+		var stock *models.InventoryItem
+		if update.StockSolutionID != nil {
+			stock = pages.FindStockSolution(snapshot.AromaChemicals, *update.StockSolutionID)
+		}
+		names = append(names, ingredientRowName(snapshot, update.AromaChemicalID, update.SubFormulaID, stock))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Listed in more than one row: %s. Tick \"Combine duplicate rows\" to merge them on save.", strings.Join(names, ", "))
+}
+
+// parseIngredientSource decodes an editor source value into an aroma
+// chemical, sub-formula or stock solution identifier.
+func parseIngredientSource(value string) (*uint, *uint, *uint, error) {
 	trimmed := strings.TrimSpace(value)
 	if trimmed == "" {
-		return nil, nil, errors.New("ingredient source missing")
+		return nil, nil, nil, errors.New("ingredient source missing")
 	}
 	parts := strings.SplitN(trimmed, ":", 2)
 	if len(parts) != 2 {
-		return nil, nil, fmt.Errorf("invalid ingredient source: %s", trimmed)
+		return nil, nil, nil, fmt.Errorf("invalid ingredient source: %s", trimmed)
 	}
 	id := pages.ParseUint(parts[1])
 	if id == 0 {
-		return nil, nil, fmt.Errorf("invalid ingredient identifier: %s", trimmed)
+		return nil, nil, nil, fmt.Errorf("invalid ingredient identifier: %s", trimmed)
 	}
 	switch parts[0] {
 	case "chem":
-		return &id, nil, nil
+		return &id, nil, nil, nil
 	case "formula":
-		return nil, &id, nil
+		return nil, &id, nil, nil
+	case "stock":
+		return nil, nil, &id, nil
 	default:
-		return nil, nil, fmt.Errorf("unknown ingredient source prefix: %s", parts[0])
+		return nil, nil, nil, fmt.Errorf("unknown ingredient source prefix: %s", parts[0])
 	}
 }
 
@@ -606,8 +732,25 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 	currentIngredients := pages.FormulaIngredientsFor(snapshot.FormulaIngredients, id)
 
 	name := strings.TrimSpace(r.FormValue("formula_name"))
-	if name == "" {
-		renderComponent(w, r, pages.FormulaEditor(formula, currentIngredients, snapshot.AromaChemicals, snapshot.Formulas, "Formula name is required."))
+	deadline, err := pages.ParseDateInput(r.FormValue("deadline"))
+	if err != nil {
+		renderComponent(w, r, pages.FormulaEditor(formula, currentIngredients, snapshot.AromaChemicals, snapshot.Formulas, "Enter the deadline as a date."))
+		return
+	}
+	brief := models.Formula{
+		Name:           name,
+		Version:        formula.Version,
+		Client:         r.FormValue("client"),
+		TargetAudience: r.FormValue("target_audience"),
+		Inspiration:    strings.TrimSpace(r.FormValue("inspiration")),
+		Deadline:       deadline,
+		Status:         formula.Status,
+		NaturalsOnly:   checkboxChecked(r.FormValue("naturals_only")),
+		Vegan:          checkboxChecked(r.FormValue("vegan")),
+		Published:      checkboxChecked(r.FormValue("published")),
+	}
+	if errs := validation.Formula(&brief); len(errs) > 0 {
+		renderComponent(w, r, pages.FormulaEditor(formula, currentIngredients, snapshot.AromaChemicals, snapshot.Formulas, errs.Error()))
 		return
 	}
 
@@ -618,6 +761,14 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 		action = "update"
 	}
 
+	// An approved revision is locked: it can only be copied or succeeded by
+	// a new version, which is saved as a separate draft formula.
+	if formula.Locked() && action == "update" {
+		renderComponent(w, r, pages.FormulaEditor(formula, currentIngredients, snapshot.AromaChemicals, snapshot.Formulas, fmt.Sprintf("Version %d is approved and locked. Save a new version to make changes.", formula.Version)))
+		return
+	}
+	forkVersion := action == "new_version" && formula.Locked()
+
 	versionValue := formula.Version
 	if action == "new_version" {
 		versionValue = formula.Version + 1
@@ -671,7 +822,17 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		chemID, subID, err := parseIngredientSource(source)
+		chemID, subID, stockID, err := parseIngredientSource(source)
+		var stock *models.InventoryItem
+		if err == nil && stockID != nil {
+			// A stock solution is stored against its neat chemical as well.
+			if stock = pages.FindStockSolution(snapshot.AromaChemicals, *stockID); stock == nil {
+				err = errors.New("stock solution not found")
+			} else {
+				neatID := stock.AromaChemicalID
+				chemID = &neatID
+			}
+		}
 		if err != nil {
 			renderComponent(w, r, pages.FormulaEditor(formula, currentIngredients, snapshot.AromaChemicals, snapshot.Formulas, "Select an ingredient for each composition row before saving."))
 			return
@@ -696,12 +857,19 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 			amountValue = parsedAmount
 		}
 
+		if errs := validation.FormulaIngredient(models.FormulaIngredient{Amount: amountValue, Unit: unit, AromaChemicalID: chemID, SubFormulaID: subID}); len(errs) > 0 {
+			renderComponent(w, r, pages.FormulaEditor(formula, currentIngredients, snapshot.AromaChemicals, snapshot.Formulas, errs.Error()))
+			return
+		}
+
 		update := formulaIngredientUpdate{
 			ID:              entryID,
 			Amount:          amountValue,
 			Unit:            unit,
 			AromaChemicalID: chemID,
 			SubFormulaID:    subID,
+			StockSolutionID: stockID,
+			Position:        len(updates),
 			RowKey:          rowKey,
 		}
 		updates = append(updates, update)
@@ -716,6 +884,9 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 			Unit:            unit,
 			AromaChemicalID: chemID,
 			SubFormulaID:    subID,
+			StockSolutionID: stockID,
+			StockSolution:   stock,
+			Position:        update.Position,
 		}
 		ingredientRecord.ID = update.ID
 		if chemID != nil {
@@ -727,33 +898,113 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 		updatedIngredients = append(updatedIngredients, ingredientRecord)
 	}
 
+	percentEntry := checkboxChecked(r.FormValue("percent_entry"))
+	if err := applyFormulaEntryMode(updates, percentEntry); err != nil {
+		message := "Percentages must add up to more than zero."
+		if errors.Is(err, errMixedEntryModes) {
+			message = "Use either percentages or amounts for every row, not both."
+		}
+		renderComponent(w, r, pages.FormulaEditor(formula, currentIngredients, snapshot.AromaChemicals, snapshot.Formulas, message))
+		return
+	}
+	duplicateWarning := ""
+	if checkboxChecked(r.FormValue("consolidate_duplicates")) {
+		merged, kept, redundant := consolidateIngredientRows(updates)
+		consolidated := make([]models.FormulaIngredient, len(kept))
+		for i, index := range kept {
+			consolidated[i] = updatedIngredients[index]
+		}
+		updates, updatedIngredients = merged, consolidated
+		deletes = append(deletes, redundant...)
+	} else {
+		duplicateWarning = duplicateRowWarning(updates, snapshot)
+	}
+	for i := range updates {
+		updatedIngredients[i].ID = updates[i].ID
+		updatedIngredients[i].Amount = updates[i].Amount
+		updatedIngredients[i].Unit = updates[i].Unit
+		updatedIngredients[i].Percentage = updates[i].Percentage
+		updatedIngredients[i].Position = updates[i].Position
+	}
+
+	chemIDs := make([]uint, 0, len(updates))
+	for _, update := range updates {
+		if update.AromaChemicalID != nil {
+			chemIDs = append(chemIDs, *update.AromaChemicalID)
+		}
+	}
+	prohibited, restricted := blockedChemicals(loadBlocklist(r.Context()), snapshot.AromaChemicals, chemIDs)
+	if len(prohibited) > 0 {
+		message := blocklistMessage(prohibited) + " Remove prohibited materials before saving."
+		renderComponent(w, r, pages.FormulaEditor(formula, updatedIngredients, snapshot.AromaChemicals, snapshot.Formulas, message))
+		return
+	}
+	restrictedWarning := blocklistMessage(restricted)
+
 	status := "Formula updated successfully."
 
 	if database == nil {
 		formula.Name = name
 		formula.Notes = notes
+		formula.PercentEntry = percentEntry
+		formula.Client = brief.Client
+		formula.TargetAudience = brief.TargetAudience
+		formula.Inspiration = brief.Inspiration
+		formula.Deadline = brief.Deadline
+		formula.NaturalsOnly = brief.NaturalsOnly
+		formula.Vegan = brief.Vegan
+		formula.Published = brief.Published
 		if action == "new_version" {
 			formula.Version = versionValue
 		}
-		renderComponent(w, r, pages.FormulaEditor(formula, updatedIngredients, snapshot.AromaChemicals, snapshot.Formulas, "Editing is unavailable because no database connection is configured."))
+		renderComponent(w, r, pages.FormulaEditor(formula, updatedIngredients, snapshot.AromaChemicals, snapshot.Formulas, pages.DemoModeMessage))
 		return
 	}
 
 	ctx := r.Context()
 
-	if action == "save_as" {
-		copyName := pages.NextCopiedFormulaName(snapshot.Formulas, name)
+	if action == "save_as" || forkVersion {
 		newFormula := models.Formula{
-			Name:     copyName,
-			Notes:    notes,
-			Version:  1,
-			IsLatest: true,
+			Name:           pages.NextCopiedFormulaName(snapshot.Formulas, name),
+			Notes:          notes,
+			Version:        1,
+			IsLatest:       true,
+			PercentEntry:   percentEntry,
+			Client:         brief.Client,
+			TargetAudience: brief.TargetAudience,
+			Inspiration:    brief.Inspiration,
+			Deadline:       brief.Deadline,
+			Status:         models.FormulaStatusDraft,
+			NaturalsOnly:   brief.NaturalsOnly,
+			Vegan:          brief.Vegan,
+		}
+		if forkVersion {
+			newFormula.Name = name
+			newFormula.Version = versionValue
+			newFormula.Published = brief.Published
+			newFormula.ParentFormulaID = &formula.ID
+		}
+		userID, _ := currentUserID(r)
+		if userID != 0 {
+			newFormula.CreatedByID = &userID
 		}
 
 		err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := checkFormulaQuota(tx, userID, 1); err != nil {
+				return err
+			}
 			if err := tx.Create(&newFormula).Error; err != nil {
 				return err
 			}
+			if forkVersion {
+				if err := tx.Model(&models.Formula{}).Where("id = ?", id).Update("is_latest", false).Error; err != nil {
+					return err
+				}
+				summary := fmt.Sprintf("%s v%d started from approved v%d", newFormula.Name, newFormula.Version, formula.Version)
+				if err := recordAudit(tx, r, models.AuditFormulaVersioned, models.ActivityEntityFormula, newFormula.ID, summary); err != nil {
+					return err
+				}
+			}
 			for _, update := range updates {
 				record := models.FormulaIngredient{
 					FormulaID:       newFormula.ID,
@@ -761,6 +1012,9 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 					Unit:            update.Unit,
 					AromaChemicalID: update.AromaChemicalID,
 					SubFormulaID:    update.SubFormulaID,
+					StockSolutionID: update.StockSolutionID,
+					Percentage:      update.Percentage,
+					Position:        update.Position,
 				}
 				if err := tx.Create(&record).Error; err != nil {
 					return err
@@ -768,6 +1022,10 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 			}
 			return nil
 		})
+		if message, ok := quotaMessage(err); ok {
+			renderComponent(w, r, pages.FormulaEditor(formula, updatedIngredients, snapshot.AromaChemicals, snapshot.Formulas, message))
+			return
+		}
 		if err != nil {
 			applog.Error(ctx, "failed to save formula copy", "error", err, "formulaID", id)
 			renderComponent(w, r, pages.FormulaEditor(formula, currentIngredients, snapshot.AromaChemicals, snapshot.Formulas, "We couldn't create a copy of this formula. Please try again."))
@@ -781,7 +1039,7 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 		}
 		newComposition := pages.FormulaIngredientsFor(refreshed.FormulaIngredients, newFormula.ID)
 		refreshedFiltered := pages.FilterFormulas(refreshed.Formulas, filters)
-		if filters.Query != "" {
+		if filters.Active() {
 			found := false
 			for _, candidate := range refreshedFiltered {
 				if candidate.ID == created.ID {
@@ -794,7 +1052,20 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		recordActivity(ctx, models.ActivityEntityFormula, created.ID, analytics.EventEdit)
 		statusCopy := fmt.Sprintf("Saved copy as %s.", created.Name)
+		if forkVersion {
+			statusCopy = fmt.Sprintf("Started version %d as a draft. Version %d stays approved.", created.Version, formula.Version)
+		}
+		if duplicateWarning != "" {
+			statusCopy += " " + duplicateWarning
+		}
+		if restrictedWarning != "" {
+			statusCopy += " " + restrictedWarning
+		}
+		if warning := constraintWarning(*created, refreshed); warning != "" {
+			statusCopy += " " + warning
+		}
 		renderComponent(w, r, pages.FormulaCreationSuccess(
 			created,
 			newComposition,
@@ -808,10 +1079,18 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		updatesMap := map[string]interface{}{
-			"name":  name,
-			"notes": notes,
+			"name":            name,
+			"notes":           notes,
+			"percent_entry":   percentEntry,
+			"client":          brief.Client,
+			"target_audience": brief.TargetAudience,
+			"inspiration":     brief.Inspiration,
+			"deadline":        brief.Deadline,
+			"naturals_only":   brief.NaturalsOnly,
+			"vegan":           brief.Vegan,
+			"published":       brief.Published,
 		}
 		if versionValue > 0 {
 			updatesMap["version"] = versionValue
@@ -835,6 +1114,9 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 						"unit":              update.Unit,
 						"aroma_chemical_id": update.AromaChemicalID,
 						"sub_formula_id":    update.SubFormulaID,
+						"stock_solution_id": update.StockSolutionID,
+						"percentage":        update.Percentage,
+						"position":          update.Position,
 					}).Error; err != nil {
 					return err
 				}
@@ -845,6 +1127,9 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 					Unit:            update.Unit,
 					AromaChemicalID: update.AromaChemicalID,
 					SubFormulaID:    update.SubFormulaID,
+					StockSolutionID: update.StockSolutionID,
+					Percentage:      update.Percentage,
+					Position:        update.Position,
 				}
 				if err := tx.Create(&record).Error; err != nil {
 					return err
@@ -866,7 +1151,7 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 	updatedComposition := pages.FormulaIngredientsFor(refreshed.FormulaIngredients, id)
 	refreshedFiltered := pages.FilterFormulas(refreshed.Formulas, filters)
-	if filters.Query != "" && updatedFormula != nil {
+	if filters.Active() && updatedFormula != nil {
 		found := false
 		for _, candidate := range refreshedFiltered {
 			if candidate.ID == updatedFormula.ID {
@@ -881,6 +1166,19 @@ func FormulaUpdate(w http.ResponseWriter, r *http.Request) {
 	if action == "new_version" {
 		status = fmt.Sprintf("Version bumped to %d and saved.", versionValue)
 	}
+	if warning := duplicateFormulaWarning(id, refreshed.Formulas, refreshed.FormulaIngredients); warning != "" {
+		status += " " + warning
+	}
+	if duplicateWarning != "" {
+		status += " " + duplicateWarning
+	}
+	if restrictedWarning != "" {
+		status += " " + restrictedWarning
+	}
+	if warning := constraintWarning(*updatedFormula, refreshed); warning != "" {
+		status += " " + warning
+	}
+	recordActivity(ctx, models.ActivityEntityFormula, id, analytics.EventEdit)
 
 	renderComponent(w, r, pages.FormulaCreationSuccess(
 		updatedFormula,
@@ -922,7 +1220,114 @@ func FormulaIngredientRow(w http.ResponseWriter, r *http.Request) {
 
 	position := len(r.Form["ingredient_row_key"])
 	rowKey := fmt.Sprintf("new-%d", time.Now().UnixNano())
-	renderComponent(w, r, pages.FormulaIngredientEditorRow(rowKey, position, nil, snapshot.AromaChemicals, snapshot.Formulas, formula.ID))
+	renderComponent(w, r, pages.FormulaIngredientEditorRow(rowKey, position, nil, snapshot.AromaChemicals, snapshot.Formulas, formula.ID, formula.PercentEntry))
+}
+
+// FormulaReorder persists the row order chosen by dragging ingredients in the
+// formula detail pane and re-renders it. Approved formulas are locked.
+func FormulaReorder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		applog.Error(r.Context(), "failed to parse formula reorder form", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	id := pages.ParseUint(r.FormValue("id"))
+	if id == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if database != nil {
+		ctx := r.Context()
+		var locked int64
+		if err := database.WithContext(ctx).Model(&models.Formula{}).
+			Where("id = ? AND status = ?", id, models.FormulaStatusApproved).
+			Count(&locked).Error; err != nil {
+			applog.Error(ctx, "failed to check formula lock for reorder", "error", err, "formulaID", id)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if locked > 0 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		var current []models.FormulaIngredient
+		if err := database.WithContext(ctx).
+			Where("formula_id = ?", id).
+			Order("position asc, id asc").
+			Find(&current).Error; err != nil {
+			applog.Error(ctx, "failed to load formula ingredients for reorder", "error", err, "formulaID", id)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(current) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		currentIDs := make([]uint, 0, len(current))
+		for _, ingredient := range current {
+			currentIDs = append(currentIDs, ingredient.ID)
+		}
+		requested := make([]uint, 0, len(r.Form["ingredient_id"]))
+		for _, value := range r.Form["ingredient_id"] {
+			requested = append(requested, pages.ParseUint(value))
+		}
+
+		ordered := reorderIngredientIDs(currentIDs, requested)
+		if err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for position, ingredientID := range ordered {
+				if err := tx.Model(&models.FormulaIngredient{}).
+					Where("id = ? AND formula_id = ?", ingredientID, id).
+					Update("position", position).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			applog.Error(ctx, "failed to reorder formula ingredients", "error", err, "formulaID", id)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	snapshot := buildWorkspaceSnapshot(r)
+	formula := pages.FindFormula(snapshot.Formulas, id)
+	ingredients := pages.FormulaIngredientsFor(snapshot.FormulaIngredients, id)
+	renderComponent(w, r, pages.FormulaDetail(formula, ingredients))
+}
+
+// reorderIngredientIDs applies the requested order to a formula's rows.
+// Identifiers outside the formula are ignored and rows missing from the
+// request keep their relative order after the requested ones, so a stale
+// view cannot drop or duplicate a row.
+func reorderIngredientIDs(current, requested []uint) []uint {
+	members := make(map[uint]bool, len(current))
+	for _, id := range current {
+		members[id] = false
+	}
+
+	ordered := make([]uint, 0, len(current))
+	for _, id := range requested {
+		placed, ok := members[id]
+		if !ok || placed {
+			continue
+		}
+		members[id] = true
+		ordered = append(ordered, id)
+	}
+	for _, id := range current {
+		if !members[id] {
+			ordered = append(ordered, id)
+		}
+	}
+	return ordered
 }
 
 // FormulaDelete removes a formula record and refreshes the list/detail panes.
@@ -952,8 +1357,7 @@ func FormulaDelete(w http.ResponseWriter, r *http.Request) {
 	if database == nil {
 		snapshot := buildWorkspaceSnapshot(r)
 		filtered := pages.FilterFormulas(snapshot.Formulas, filters)
-		message := "Deleting formulas is unavailable because no database connection is configured."
-		renderComponent(w, r, pages.FormulaDeletionResult(message, filtered, filters, len(snapshot.Formulas)))
+		renderComponent(w, r, pages.FormulaDeletionResult(pages.DemoModeMessage, "", filtered, filters, len(snapshot.Formulas)))
 		return
 	}
 
@@ -982,11 +1386,15 @@ func FormulaDelete(w http.ResponseWriter, r *http.Request) {
 		snapshot := buildWorkspaceSnapshot(r)
 		filtered := pages.FilterFormulas(snapshot.Formulas, filters)
 		message := "This formula is used as a sub-formula in other compositions. Remove those references before deleting."
-		renderComponent(w, r, pages.FormulaDeletionResult(message, filtered, filters, len(snapshot.Formulas)))
+		renderComponent(w, r, pages.FormulaDeletionResult(message, "", filtered, filters, len(snapshot.Formulas)))
 		return
 	}
 
+	var ingredientIDs []uint
 	if err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.FormulaIngredient{}).Where("formula_id = ?", id).Pluck("id", &ingredientIDs).Error; err != nil {
+			return err
+		}
 		if err := tx.Where("formula_id = ?", id).Delete(&models.FormulaIngredient{}).Error; err != nil {
 			return err
 		}
@@ -998,14 +1406,15 @@ func FormulaDelete(w http.ResponseWriter, r *http.Request) {
 		applog.Error(ctx, "failed to delete formula", "error", err, "formulaID", id)
 		snapshot := buildWorkspaceSnapshot(r)
 		filtered := pages.FilterFormulas(snapshot.Formulas, filters)
-		renderComponent(w, r, pages.FormulaDeletionResult("We couldn't delete this formula. Please try again.", filtered, filters, len(snapshot.Formulas)))
+		renderComponent(w, r, pages.FormulaDeletionResult("We couldn't delete this formula. Please try again.", "", filtered, filters, len(snapshot.Formulas)))
 		return
 	}
 
+	undoToken := rememberUndo(r, undoKindFormula, formula.Name, []uint{id}, ingredientIDs)
 	refreshed := buildWorkspaceSnapshot(r)
 	filtered := pages.FilterFormulas(refreshed.Formulas, filters)
 	message := fmt.Sprintf("\"%s\" deleted successfully.", formula.Name)
-	renderComponent(w, r, pages.FormulaDeletionResult(message, filtered, filters, len(refreshed.Formulas)))
+	renderComponent(w, r, pages.FormulaDeletionResult(message, undoToken, filtered, filters, len(refreshed.Formulas)))
 }
 
 // IngredientDelete removes an aroma chemical owned by the current user when it is not referenced.
@@ -1035,8 +1444,7 @@ func IngredientDelete(w http.ResponseWriter, r *http.Request) {
 	if database == nil {
 		snapshot := buildWorkspaceSnapshot(r)
 		filtered := pages.FilterAromaChemicals(snapshot.AromaChemicals, filters)
-		message := "Deleting ingredients is unavailable because no database connection is configured."
-		renderComponent(w, r, pages.IngredientDeletionResult(message, filtered, filters, len(snapshot.AromaChemicals)))
+		renderComponent(w, r, pages.IngredientDeletionResult(pages.DemoModeMessage, "", filtered, filters, len(snapshot.AromaChemicals)))
 		return
 	}
 
@@ -1058,7 +1466,7 @@ func IngredientDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if chemical.OwnerID != userID {
+	if !canManageChemical(r, chemical.OwnerID, userID) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
@@ -1077,7 +1485,7 @@ func IngredientDelete(w http.ResponseWriter, r *http.Request) {
 		snapshot := buildWorkspaceSnapshot(r)
 		filtered := pages.FilterAromaChemicals(snapshot.AromaChemicals, filters)
 		message := "This ingredient is used in one or more formulas. Remove those references before deleting."
-		renderComponent(w, r, pages.IngredientDeletionResult(message, filtered, filters, len(snapshot.AromaChemicals)))
+		renderComponent(w, r, pages.IngredientDeletionResult(message, "", filtered, filters, len(snapshot.AromaChemicals)))
 		return
 	}
 
@@ -1085,14 +1493,15 @@ func IngredientDelete(w http.ResponseWriter, r *http.Request) {
 		applog.Error(ctx, "failed to delete ingredient", "error", err, "ingredientID", id)
 		snapshot := buildWorkspaceSnapshot(r)
 		filtered := pages.FilterAromaChemicals(snapshot.AromaChemicals, filters)
-		renderComponent(w, r, pages.IngredientDeletionResult("We couldn't delete this ingredient. Please try again.", filtered, filters, len(snapshot.AromaChemicals)))
+		renderComponent(w, r, pages.IngredientDeletionResult("We couldn't delete this ingredient. Please try again.", "", filtered, filters, len(snapshot.AromaChemicals)))
 		return
 	}
 
+	undoToken := rememberUndo(r, undoKindIngredient, chemical.IngredientName, []uint{id}, nil)
 	refreshed := buildWorkspaceSnapshot(r)
 	filtered := pages.FilterAromaChemicals(refreshed.AromaChemicals, filters)
 	message := fmt.Sprintf("\"%s\" deleted successfully.", chemical.IngredientName)
-	renderComponent(w, r, pages.IngredientDeletionResult(message, filtered, filters, len(refreshed.AromaChemicals)))
+	renderComponent(w, r, pages.IngredientDeletionResult(message, undoToken, filtered, filters, len(refreshed.AromaChemicals)))
 }
 
 func renderComponent(w http.ResponseWriter, r *http.Request, component templ.Component) {
@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 
 	"gorm.io/gorm"
@@ -43,3 +48,208 @@ func TestWouldCreateFormulaCycle(t *testing.T) {
 		t.Fatalf("expected cycle when referencing the same formula")
 	}
 }
+
+func TestApplyIngredientForm(t *testing.T) {
+	tests := []struct {
+		name        string
+		form        url.Values
+		wantMessage string
+	}{
+		{name: "valid", form: url.Values{"ingredient_name": {"Hedione"}, "pyramid_position": {"Heart"}, "strength": {"3"}}},
+		{name: "missing name", form: url.Values{"strength": {"x"}}, wantMessage: "Ingredient name is required."},
+		{name: "bad strength", form: url.Values{"ingredient_name": {"Hedione"}, "strength": {"x"}}, wantMessage: "Strength must be a whole number."},
+		{name: "bad CAS", form: url.Values{"ingredient_name": {"Hedione"}, "cas_number": {"24851-98-1"}}, wantMessage: "CAS number must look like 64-17-5 and have a valid check digit."},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/app/sections/ingredients/create", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if err := req.ParseForm(); err != nil {
+				t.Fatalf("parse form: %v", err)
+			}
+
+			chemical := &models.AromaChemical{}
+			errs := applyIngredientForm(req, chemical)
+			if got := errs.Error(); got != tt.wantMessage {
+				t.Fatalf("first error = %q, want %q", got, tt.wantMessage)
+			}
+			if tt.wantMessage == "" && chemical.PyramidPosition != "heart" {
+				t.Fatalf("expected pyramid to be canonicalised, got %q", chemical.PyramidPosition)
+			}
+		})
+	}
+}
+
+func TestApplyFormulaEntryMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		updates     []formulaIngredientUpdate
+		percent     bool
+		wantErr     error
+		wantAmounts []float64
+		wantShares  []float64
+	}{
+		{
+			name:        "percent rows normalised",
+			updates:     []formulaIngredientUpdate{{Amount: 30, Unit: "%"}, {Amount: 10, Unit: "%"}},
+			percent:     true,
+			wantAmounts: []float64{750, 250},
+			wantShares:  []float64{75, 25},
+		},
+		{
+			name:    "mixed rows rejected",
+			updates: []formulaIngredientUpdate{{Amount: 30, Unit: "%"}, {Amount: 10, Unit: "mg"}},
+			percent: true,
+			wantErr: errMixedEntryModes,
+		},
+		{
+			name:    "empty percent total rejected",
+			updates: []formulaIngredientUpdate{{Amount: 0, Unit: "%"}},
+			percent: true,
+			wantErr: errEmptyPercentTotal,
+		},
+		{
+			name:        "amounts gain shares in percent mode",
+			updates:     []formulaIngredientUpdate{{Amount: 1, Unit: "g"}, {Amount: 3000, Unit: "mg"}},
+			percent:     true,
+			wantAmounts: []float64{1, 3000},
+			wantShares:  []float64{25, 75},
+		},
+		{
+			name:        "shares cleared outside percent mode",
+			updates:     []formulaIngredientUpdate{{Amount: 5, Unit: "mg", Percentage: 40}},
+			wantAmounts: []float64{5},
+			wantShares:  []float64{0},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := applyFormulaEntryMode(tt.updates, tt.percent)
+			if err != tt.wantErr {
+				t.Fatalf("unexpected error: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			for i, update := range tt.updates {
+				if update.Amount != tt.wantAmounts[i] {
+					t.Fatalf("row %d amount: got %v, want %v", i, update.Amount, tt.wantAmounts[i])
+				}
+				if update.Percentage != tt.wantShares[i] {
+					t.Fatalf("row %d share: got %v, want %v", i, update.Percentage, tt.wantShares[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConsolidateIngredientRows(t *testing.T) {
+	t.Parallel()
+	u := func(v uint) *uint { return &v }
+
+	updates := []formulaIngredientUpdate{
+		{ID: 0, Amount: 2, Unit: "g", AromaChemicalID: u(1), Percentage: 20},
+		{ID: 11, Amount: 500, Unit: "mg", AromaChemicalID: u(2), Percentage: 5},
+		{ID: 12, Amount: 1, Unit: "g", AromaChemicalID: u(1), Percentage: 10},
+		{ID: 13, Amount: 500, Unit: "mg", AromaChemicalID: u(1), StockSolutionID: u(7)},
+		{ID: 14, Amount: 3000, Unit: "mg", AromaChemicalID: u(1), Percentage: 30},
+	}
+	merged, kept, redundant := consolidateIngredientRows(updates)
+
+	if len(merged) != 3 || kept[0] != 0 || kept[1] != 1 || kept[2] != 3 {
+		t.Fatalf("unexpected rows: %+v kept %v", merged, kept)
+	}
+	first := merged[0]
+	if first.ID != 12 || first.Unit != "g" || first.Amount != 6 || first.Percentage != 60 || first.Position != 0 {
+		t.Fatalf("duplicates not summed into the first row: %+v", first)
+	}
+	if merged[2].StockSolutionID == nil || merged[2].Position != 2 {
+		t.Fatalf("stock solution should stay a separate row: %+v", merged[2])
+	}
+	if len(redundant) != 1 || redundant[0] != 14 {
+		t.Fatalf("unexpected redundant entries: %v", redundant)
+	}
+}
+
+func TestReorderIngredientIDs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		current   []uint
+		requested []uint
+		want      []uint
+	}{
+		{name: "full reorder", current: []uint{1, 2, 3}, requested: []uint{3, 1, 2}, want: []uint{3, 1, 2}},
+		{name: "missing rows keep order", current: []uint{1, 2, 3, 4}, requested: []uint{4, 2}, want: []uint{4, 2, 1, 3}},
+		{name: "foreign and duplicate ids ignored", current: []uint{1, 2}, requested: []uint{9, 2, 2, 0}, want: []uint{2, 1}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := reorderIngredientIDs(tt.current, tt.requested)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormulaReorderPersistsPositions(t *testing.T) {
+	sm, smCleanup := withTestSessionManager(t)
+	t.Cleanup(smCleanup)
+
+	db := newToolsTestDB(t)
+	prevDB := database
+	database = db
+	t.Cleanup(func() { database = prevDB })
+
+	formula := models.Formula{Name: "Chypre study"}
+	if err := db.Create(&formula).Error; err != nil {
+		t.Fatalf("create formula: %v", err)
+	}
+	rows := make([]models.FormulaIngredient, 3)
+	for i := range rows {
+		rows[i] = models.FormulaIngredient{FormulaID: formula.ID, Amount: float64(i + 1), Unit: "mg", Position: i}
+		if err := db.Create(&rows[i]).Error; err != nil {
+			t.Fatalf("create ingredient: %v", err)
+		}
+	}
+
+	form := url.Values{
+		"id":            {strconv.Itoa(int(formula.ID))},
+		"ingredient_id": {strconv.Itoa(int(rows[2].ID)), strconv.Itoa(int(rows[0].ID))},
+	}
+	rr := httptest.NewRecorder()
+	FormulaReorder(rr, authenticatedFormRequest(t, sm, "/app/sections/formulas/reorder", form, 1))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var stored []models.FormulaIngredient
+	if err := db.Where("formula_id = ?", formula.ID).Order("position asc").Find(&stored).Error; err != nil {
+		t.Fatalf("load ingredients: %v", err)
+	}
+	want := []uint{rows[2].ID, rows[0].ID, rows[1].ID}
+	for i, ingredient := range stored {
+		if ingredient.ID != want[i] || ingredient.Position != i {
+			t.Fatalf("unexpected order: %+v", stored)
+		}
+	}
+}
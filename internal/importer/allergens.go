@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+// Columns read by ImportConstituents.
+const (
+	columnMaterial      = "Material"
+	columnMaterialCAS   = "Material CAS"
+	columnAllergen      = "Allergen"
+	columnAllergenCAS   = "Allergen CAS"
+	columnPercentage    = "Percentage"
+	columnOrigin        = "Origin"
+	columnAnimalDerived = "Animal Derived"
+)
+
+// ImportConstituents replaces the allergen composition rows of the dataset
+// named source with records, one allergen of one natural material per row.
+// Regulated allergens are stored under their canonical name and CAS number.
+// Rows may also give a material's Origin and whether it is Animal Derived;
+// those are applied to the matching chemicals owned by ownerID. Created
+// counts constituent rows and Updated the chemicals whose origin changed.
+func ImportConstituents(ctx context.Context, db *gorm.DB, records []map[string]string, source string, ownerID uint, report *Report) error {
+	if db == nil {
+		return errors.New("database handle is nil")
+	}
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return errors.New("constituent dataset needs a source name")
+	}
+	report.Rows = len(records)
+
+	constituents := make([]models.Constituent, 0, len(records))
+	origins := make(map[string]originUpdate)
+	var order []string
+	for idx, record := range records {
+		row := idx + 1
+		constituent, origin, issues := buildConstituent(record)
+		material := constituentMaterialLabel(constituent)
+		if material == "" || constituent.Allergen == "" {
+			report.Skipped++
+			report.add(row, material, RowIssue{Kind: IssueSkipped, Code: "missing_identity", Field: columnMaterial, Detail: "row needs a material and an allergen"})
+			continue
+		}
+		report.add(row, material, issues...)
+		if origin != nil {
+			key := strings.ToLower(constituent.MaterialCAS + "|" + constituent.MaterialName)
+			if _, seen := origins[key]; !seen {
+				order = append(order, key)
+			}
+			origins[key] = originUpdate{Row: row, CAS: constituent.MaterialCAS, Name: constituent.MaterialName, Fields: origin}
+		}
+		if constituent.Percentage <= 0 {
+			continue
+		}
+		constituent.Source = source
+		constituents = append(constituents, constituent)
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source = ?", source).Delete(&models.Constituent{}).Error; err != nil {
+			return fmt.Errorf("clear constituents from %q: %w", source, err)
+		}
+		if len(constituents) > 0 {
+			if err := tx.CreateInBatches(constituents, 200).Error; err != nil {
+				return fmt.Errorf("create constituents: %w", err)
+			}
+		}
+		report.Created = len(constituents)
+		if len(origins) == 0 {
+			return nil
+		}
+
+		var chemicals []models.AromaChemical
+		if err := tx.Preload("OtherNames").Where("owner_id = ?", ownerID).Find(&chemicals).Error; err != nil {
+			return fmt.Errorf("load chemicals for origin updates: %w", err)
+		}
+		for _, key := range order {
+			update := origins[key]
+			for _, chemical := range chemicals {
+				if !models.MatchesMaterial(update.CAS, update.Name, chemical) {
+					continue
+				}
+				changed := changedColumns(chemical, update.Fields)
+				if len(changed) == 0 {
+					continue
+				}
+				if err := tx.Model(&models.AromaChemical{}).Where("id = ?", chemical.ID).Updates(update.Fields).Error; err != nil {
+					return fmt.Errorf("update origin of %q: %w", chemical.IngredientName, err)
+				}
+				report.Updated++
+				report.Changes = append(report.Changes, Change{Row: update.Row, Ingredient: chemical.IngredientName, Action: ChangeUpdated, Fields: changed})
+			}
+		}
+		return nil
+	})
+}
+
+// originUpdate is the origin data given for one material.
+type originUpdate struct {
+	Row    int
+	CAS    string
+	Name   string
+	Fields map[string]any
+}
+
+// buildConstituent maps a CSV row onto a constituent and, when the row gives
+// origin data, the chemical columns to update.
+func buildConstituent(row map[string]string) (models.Constituent, map[string]any, []RowIssue) {
+	var issues []RowIssue
+	constituent := models.Constituent{
+		MaterialName: normalizeText(row[columnMaterial]),
+		MaterialCAS:  strings.TrimSpace(row[columnMaterialCAS]),
+		Allergen:     normalizeText(row[columnAllergen]),
+		AllergenCAS:  strings.TrimSpace(row[columnAllergenCAS]),
+	}
+	if allergen, ok := models.FindAllergen(constituent.AllergenCAS, constituent.Allergen); ok {
+		constituent.Allergen = allergen.Name
+		constituent.AllergenCAS = allergen.CASNumber
+	}
+
+	if raw := strings.TrimSpace(row[columnPercentage]); raw != "" {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+		switch {
+		case err != nil:
+			value = parseFirstNumber(raw)
+			issues = append(issues, RowIssue{Kind: IssueCoercion, Code: "number_parsed", Field: columnPercentage, Value: raw, Detail: fmt.Sprintf("read as %g", value)})
+		case value < 0 || value > 100:
+			issues = append(issues, RowIssue{Kind: IssueCoercion, Code: "out_of_range", Field: columnPercentage, Value: raw, Detail: "percentage must be between 0 and 100; row ignored"})
+			value = 0
+		}
+		constituent.Percentage = value
+	}
+
+	var origin map[string]any
+	if raw := strings.TrimSpace(row[columnOrigin]); raw != "" {
+		value := strings.ToLower(raw)
+		if models.ValidOrigin(value) {
+			origin = map[string]any{"origin": value}
+		} else {
+			issues = append(issues, RowIssue{Kind: IssueCoercion, Code: "invalid_origin", Field: columnOrigin, Value: raw, Detail: "origin must be natural or synthetic; ignored"})
+		}
+	}
+	if raw := strings.TrimSpace(row[columnAnimalDerived]); raw != "" {
+		if origin == nil {
+			origin = map[string]any{}
+		}
+		switch strings.ToLower(raw) {
+		case "yes", "true", "1", "y":
+			origin["animal_derived"] = true
+		case "no", "false", "0", "n":
+			origin["animal_derived"] = false
+		default:
+			issues = append(issues, RowIssue{Kind: IssueCoercion, Code: "invalid_flag", Field: columnAnimalDerived, Value: raw, Detail: "expected yes or no; ignored"})
+		}
+		if len(origin) == 0 {
+			origin = nil
+		}
+	}
+	return constituent, origin, issues
+}
+
+func constituentMaterialLabel(constituent models.Constituent) string {
+	if constituent.MaterialName != "" {
+		return constituent.MaterialName
+	}
+	return constituent.MaterialCAS
+}
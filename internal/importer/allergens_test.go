@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func TestImportConstituents(t *testing.T) {
+	dsn := fmt.Sprintf("file:constituents-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.AromaChemical{}, &models.OtherName{}, &models.Constituent{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	lavender := models.AromaChemical{IngredientName: "Lavender oil", CASNumber: "8000-28-0", OwnerID: 1}
+	foreign := models.AromaChemical{IngredientName: "Lavender oil", CASNumber: "8000-28-0", OwnerID: 2}
+	for _, chemical := range []*models.AromaChemical{&lavender, &foreign} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("create chemical: %v", err)
+		}
+	}
+	if err := db.Create(&models.Constituent{MaterialName: "Old", Allergen: "Linalool", Percentage: 1, Source: "ifra"}).Error; err != nil {
+		t.Fatalf("create stale constituent: %v", err)
+	}
+
+	records, err := ParseCSV(strings.NewReader(strings.Join([]string{
+		"Material,Material CAS,Allergen,Allergen CAS,Percentage,Origin,Animal Derived",
+		"Lavender oil,8000-28-0,linalool,,35%,Natural,no",
+		"Lavender oil,8000-28-0,Limonene,5989-27-5,0.4,,",
+		"Lavender oil,8000-28-0,Coumarin,,140,,",
+		",,Geraniol,,2,,",
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+
+	var report Report
+	if err := ImportConstituents(context.Background(), db, records, "ifra", 1, &report); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if report.Created != 2 || report.Skipped != 1 || report.Updated != 1 || report.Count(IssueCoercion) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	var stored []models.Constituent
+	if err := db.Order("allergen asc").Find(&stored).Error; err != nil {
+		t.Fatalf("load constituents: %v", err)
+	}
+	if len(stored) != 2 || stored[0].Allergen != "Limonene" || stored[1].Allergen != "Linalool" || stored[1].AllergenCAS != "78-70-6" || stored[1].Percentage != 35 {
+		t.Fatalf("unexpected constituents: %+v", stored)
+	}
+
+	var reloaded models.AromaChemical
+	db.First(&reloaded, lavender.ID)
+	if reloaded.Origin != models.OriginNatural {
+		t.Fatalf("origin not applied: %+v", reloaded)
+	}
+	var other models.AromaChemical
+	db.First(&other, foreign.ID)
+	if other.Origin != "" {
+		t.Fatalf("origin applied to another user's chemical: %+v", other)
+	}
+}
@@ -0,0 +1,809 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+var (
+	bracketPattern  = regexp.MustCompile(`\[[^\]]*\]`)
+	numberPattern   = regexp.MustCompile(`[-+]?\d*\.?\d+`)
+	cleanWhitespace = regexp.MustCompile(`\s+`)
+	slugPattern     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type options struct {
+	// Source is a local CSV path or an https:// URL to a CSV export.
+	Source       string
+	ReportPath   string
+	ReportFormat string
+}
+
+// DefaultBatchSize is the number of rows ImportAroma commits per transaction
+// when AromaImport.BatchSize is zero.
+const DefaultBatchSize = 500
+
+// AromaImport configures ImportAroma.
+type AromaImport struct {
+	// OwnerID owns the imported chemicals. Public imports share the
+	// chemicals they create or update for OwnerID with every user.
+	OwnerID uint
+	Public  bool
+	// BatchSize is the number of rows committed per transaction.
+	BatchSize int
+	// Progress, when set, is called after each committed batch with the
+	// number of rows processed so far.
+	Progress func(done, total int)
+}
+
+// ImportAroma upserts each record as an aroma chemical owned by
+// opts.OwnerID, matching existing chemicals by name and then CAS number. A
+// match with another user's public chemical is resolved by the configured
+// CollisionStrategy. Rows are written in batches, each in one transaction
+// with the chemicals it may touch fetched up front. Row issues and the
+// created or updated records are accumulated in report; a database failure
+// stops the import at the failing batch, keeping the batches before it.
+func ImportAroma(ctx context.Context, db *gorm.DB, records []map[string]string, opts AromaImport, report *Report) error {
+	if db == nil {
+		return errors.New("database handle is nil")
+	}
+	started := time.Now()
+	defer func() { report.Seconds = time.Since(started).Seconds() }()
+	report.Rows = len(records)
+
+	size := opts.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	seen := make(map[string]int, len(records))
+	for start := 0; start < len(records); start += size {
+		end := min(start+size, len(records))
+		if err := importAromaBatch(ctx, db, records[start:end], start, opts, seen, report); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			opts.Progress(end, len(records))
+		}
+	}
+	return nil
+}
+
+// aromaRow is a parsed CSV row waiting to be written.
+type aromaRow struct {
+	row      int
+	source   string
+	chemical models.AromaChemical
+}
+
+// batchOutcome holds the counts and changes of a batch until it commits.
+type batchOutcome struct {
+	created, updated, skipped int
+	changes                   []Change
+}
+
+func importAromaBatch(ctx context.Context, db *gorm.DB, records []map[string]string, offset int, opts AromaImport, seen map[string]int, report *Report) error {
+	rows := make([]aromaRow, 0, len(records))
+	for idx, record := range records {
+		row := offset + idx + 1
+		chemical, issues := buildAromaChemical(record)
+		if chemical.IngredientName == "" {
+			report.Skipped++
+			report.add(row, "", RowIssue{Kind: IssueSkipped, Code: "missing_name", Field: "Ingredient Name", Detail: "row has no ingredient name"})
+			continue
+		}
+		report.add(row, chemical.IngredientName, issues...)
+
+		key := strings.ToLower(chemical.IngredientName)
+		if first, ok := seen[key]; ok {
+			report.add(row, chemical.IngredientName, RowIssue{
+				Kind:   IssueConflict,
+				Code:   "duplicate_row",
+				Field:  "Ingredient Name",
+				Detail: fmt.Sprintf("also defined on row %d; this row overwrites it", first),
+			})
+		} else {
+			seen[key] = row
+		}
+		rows = append(rows, aromaRow{row: row, source: record["Ingredient Name"], chemical: chemical})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var outcome batchOutcome
+	if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		index, err := prefetchChemicals(tx, rows)
+		if err != nil {
+			return fmt.Errorf("load records %d-%d: %w", rows[0].row, rows[len(rows)-1].row, err)
+		}
+		for _, row := range rows {
+			if err := importAromaRow(tx, index, row, opts, report, &outcome); err != nil {
+				return fmt.Errorf("record %d (%s): %w", row.row, row.source, err)
+			}
+		}
+		if err := index.flush(tx, len(rows)); err != nil {
+			return fmt.Errorf("write records %d-%d: %w", rows[0].row, rows[len(rows)-1].row, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	report.Created += outcome.created
+	report.Updated += outcome.updated
+	report.Skipped += outcome.skipped
+	report.Changes = append(report.Changes, outcome.changes...)
+	return nil
+}
+
+// importAromaRow applies one row to the batch's index. Nothing is written
+// until the index is flushed, except the lookups that name private copies.
+func importAromaRow(tx *gorm.DB, index *chemicalIndex, row aromaRow, opts AromaImport, report *Report, outcome *batchOutcome) error {
+	chemical := row.chemical
+	chemical.OwnerID = opts.OwnerID
+	chemical.Public = opts.Public
+
+	existing, foundByCAS := index.find(chemical, opts.OwnerID)
+	if existing != nil && existing.chemical.OwnerID != opts.OwnerID && existing.public() {
+		switch collisionStrategy {
+		case CollisionSkip:
+			outcome.skipped++
+			report.add(row.row, chemical.IngredientName, RowIssue{
+				Kind:   IssueSkipped,
+				Code:   "public_collision",
+				Detail: fmt.Sprintf("matches public %q; not imported", existing.name()),
+			})
+			return nil
+		case CollisionAlias:
+			aliases := append([]models.OtherName{{Name: chemical.IngredientName}}, chemical.OtherNames...)
+			outcome.updated++
+			if existing.linkAliases(aliases) {
+				outcome.changes = append(outcome.changes, Change{Row: row.row, Ingredient: chemical.IngredientName, Action: ChangeUpdated, Fields: []string{"other_names"}})
+			}
+			report.add(row.row, chemical.IngredientName, RowIssue{
+				Kind:   IssueConflict,
+				Code:   "public_collision",
+				Detail: fmt.Sprintf("matches public %q; linked as an alias", existing.name()),
+			})
+			return nil
+		default:
+			privateName, err := privateCopyName(tx, chemical.IngredientName, index.hasName)
+			if err != nil {
+				return fmt.Errorf("name private copy of %q: %w", chemical.IngredientName, err)
+			}
+			report.add(row.row, chemical.IngredientName, RowIssue{
+				Kind:   IssueConflict,
+				Code:   "public_collision",
+				Detail: fmt.Sprintf("matches public %q; imported as %q", existing.name(), privateName),
+			})
+			chemical.IngredientName = privateName
+			existing = nil
+		}
+	}
+
+	if existing == nil {
+		index.create(chemical)
+		outcome.created++
+		outcome.changes = append(outcome.changes, Change{Row: row.row, Ingredient: chemical.IngredientName, Action: ChangeCreated})
+		return nil
+	}
+
+	if existing.chemical.OwnerID != opts.OwnerID {
+		report.add(row.row, chemical.IngredientName, RowIssue{
+			Kind:   IssueConflict,
+			Code:   "foreign_owner",
+			Detail: fmt.Sprintf("updates %q, which belongs to user %d", existing.name(), existing.chemical.OwnerID),
+		})
+	}
+	updates := map[string]any{
+		"notes":                chemical.Notes,
+		"wheel_position":       chemical.WheelPosition,
+		"pyramid_position":     chemical.PyramidPosition,
+		"type":                 chemical.Type,
+		"strength":             chemical.Strength,
+		"recommended_dilution": chemical.RecommendedDilution,
+		"dilution_percentage":  chemical.DilutionPercentage,
+		"max_ifra_percentage":  chemical.MaxIFRAPercentage,
+		"duration":             chemical.Duration,
+		"historic_role":        chemical.HistoricRole,
+		"popularity":           chemical.Popularity,
+		"usage":                chemical.Usage,
+	}
+	if chemical.CASNumber != "" {
+		updates["cas_number"] = chemical.CASNumber
+		updates["synthetic_id"] = ""
+	} else if existing.cas() == "" {
+		updates["synthetic_id"] = chemical.SyntheticID
+	}
+	if opts.Public && existing.chemical.OwnerID == opts.OwnerID {
+		updates["public"] = true
+	}
+
+	var extraAliases []string
+	canonicalName := chemical.IngredientName
+	if foundByCAS && !strings.EqualFold(existing.name(), chemical.IngredientName) {
+		canonicalName = existing.name()
+		extraAliases = append(extraAliases, chemical.IngredientName)
+		report.add(row.row, chemical.IngredientName, RowIssue{
+			Kind:   IssueConflict,
+			Code:   "cas_name_mismatch",
+			Field:  "CAS Number",
+			Value:  chemical.CASNumber,
+			Detail: fmt.Sprintf("CAS already belongs to %q; name kept as an alias", existing.name()),
+		})
+	} else {
+		updates["ingredient_name"] = chemical.IngredientName
+	}
+
+	outcome.updated++
+	if changed := index.update(existing, updates); len(changed) > 0 {
+		outcome.changes = append(outcome.changes, Change{Row: row.row, Ingredient: chemical.IngredientName, Action: ChangeUpdated, Fields: changed})
+	}
+	existing.names = combineOtherNames(canonicalName, existing.names, chemical.OtherNames, extraAliases)
+	return nil
+}
+
+// indexedChemical is a chemical a batch may touch: one already stored, with
+// its other names, or one the batch creates (ID zero until flushed).
+// columns holds its current values and updates the ones still to write.
+type indexedChemical struct {
+	chemical models.AromaChemical
+	columns  map[string]any
+	updates  map[string]any
+	stored   []models.OtherName
+	names    []models.OtherName
+}
+
+func (c *indexedChemical) name() string { return c.columns["ingredient_name"].(string) }
+func (c *indexedChemical) cas() string  { return c.columns["cas_number"].(string) }
+func (c *indexedChemical) public() bool { return c.columns["public"].(bool) }
+
+// linkAliases adds aliases the chemical does not have yet, reporting
+// whether any were added. The chemical's own name is never an alias.
+func (c *indexedChemical) linkAliases(aliases []models.OtherName) bool {
+	before := len(c.names)
+	c.names = combineOtherNames(c.name(), c.names, aliases, nil)
+	return len(c.names) != before
+}
+
+// chemicalIndex finds the chemicals of one batch by name and CAS number the
+// way the database would, including those created earlier in the batch.
+type chemicalIndex struct {
+	all    []*indexedChemical
+	byName map[string][]*indexedChemical
+	byCAS  map[string][]*indexedChemical
+}
+
+// prefetchChemicals loads every stored chemical sharing a name or CAS
+// number with rows, whoever owns it, in one query.
+func prefetchChemicals(tx *gorm.DB, rows []aromaRow) (*chemicalIndex, error) {
+	names := make([]string, 0, len(rows))
+	numbers := make([]string, 0, len(rows))
+	for _, row := range rows {
+		names = append(names, row.chemical.IngredientName)
+		if row.chemical.CASNumber != "" {
+			numbers = append(numbers, row.chemical.CASNumber)
+		}
+	}
+	query := tx.Preload("OtherNames").Where("ingredient_name IN ?", names)
+	if len(numbers) > 0 {
+		query = query.Or("cas_number IN ?", numbers)
+	}
+	var stored []models.AromaChemical
+	if err := query.Order("id asc").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	index := &chemicalIndex{byName: map[string][]*indexedChemical{}, byCAS: map[string][]*indexedChemical{}}
+	for _, chemical := range stored {
+		entry := &indexedChemical{chemical: chemical, columns: chemicalColumns(chemical), updates: map[string]any{}, stored: chemical.OtherNames}
+		entry.names = append([]models.OtherName(nil), chemical.OtherNames...)
+		entry.chemical.OtherNames = nil
+		index.add(entry)
+	}
+	return index, nil
+}
+
+func (x *chemicalIndex) add(entry *indexedChemical) {
+	x.all = append(x.all, entry)
+	x.byName[entry.name()] = append(x.byName[entry.name()], entry)
+	if entry.cas() != "" {
+		x.byCAS[entry.cas()] = append(x.byCAS[entry.cas()], entry)
+	}
+}
+
+// find looks for the chemical a row updates, preferring the importing
+// owner's records and matching by name before CAS number, and reports
+// whether the match was by CAS number. Among equal matches the oldest wins.
+func (x *chemicalIndex) find(chemical models.AromaChemical, ownerID uint) (*indexedChemical, bool) {
+	first := func(entries []*indexedChemical, owned bool) *indexedChemical {
+		for _, entry := range entries {
+			if !owned || entry.chemical.OwnerID == ownerID {
+				return entry
+			}
+		}
+		return nil
+	}
+	if entry := first(x.byName[chemical.IngredientName], true); entry != nil {
+		return entry, false
+	}
+	if chemical.CASNumber != "" {
+		if entry := first(x.byCAS[chemical.CASNumber], true); entry != nil {
+			return entry, true
+		}
+	}
+	if entry := first(x.byName[chemical.IngredientName], false); entry != nil {
+		return entry, false
+	}
+	if chemical.CASNumber != "" {
+		if entry := first(x.byCAS[chemical.CASNumber], false); entry != nil {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// hasName reports whether a chemical in the batch is named name, ignoring case.
+func (x *chemicalIndex) hasName(name string) bool {
+	for _, entry := range x.all {
+		if strings.EqualFold(entry.name(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// create queues a new chemical for the batch.
+func (x *chemicalIndex) create(chemical models.AromaChemical) {
+	names := chemical.OtherNames
+	chemical.OtherNames = nil
+	entry := &indexedChemical{chemical: chemical, columns: chemicalColumns(chemical), updates: map[string]any{}}
+	entry.names = combineOtherNames(chemical.IngredientName, nil, names, nil)
+	x.add(entry)
+}
+
+// update applies updates to entry, re-indexing it under a new name or CAS
+// number, and returns the columns whose values changed.
+func (x *chemicalIndex) update(entry *indexedChemical, updates map[string]any) []string {
+	changed := changedFrom(entry.columns, updates)
+	oldName, oldCAS := entry.name(), entry.cas()
+	for _, column := range changed {
+		entry.columns[column] = updates[column]
+		entry.updates[column] = updates[column]
+	}
+	if entry.name() != oldName {
+		x.byName[oldName] = removeEntry(x.byName[oldName], entry)
+		x.byName[entry.name()] = insertEntry(x.byName[entry.name()], entry)
+	}
+	if entry.cas() != oldCAS {
+		x.byCAS[oldCAS] = removeEntry(x.byCAS[oldCAS], entry)
+		x.byCAS[entry.cas()] = insertEntry(x.byCAS[entry.cas()], entry)
+	}
+	return changed
+}
+
+func removeEntry(entries []*indexedChemical, entry *indexedChemical) []*indexedChemical {
+	return slices.DeleteFunc(entries, func(candidate *indexedChemical) bool { return candidate == entry })
+}
+
+// insertEntry keeps entries in the order they were indexed, which is the
+// order the database would return them in.
+func insertEntry(entries []*indexedChemical, entry *indexedChemical) []*indexedChemical {
+	entries = append(entries, entry)
+	sort.SliceStable(entries, func(i, j int) bool { return entryOrder(entries[i]) < entryOrder(entries[j]) })
+	return entries
+}
+
+func entryOrder(entry *indexedChemical) uint {
+	if entry.chemical.ID == 0 {
+		return math.MaxUint
+	}
+	return entry.chemical.ID
+}
+
+// flush writes the batch: new chemicals and their other names in bulk, then
+// the changed columns of stored chemicals, then the other names they gained
+// or lost.
+func (x *chemicalIndex) flush(tx *gorm.DB, batchSize int) error {
+	var created []*indexedChemical
+	var chemicals []models.AromaChemical
+	for _, entry := range x.all {
+		if entry.chemical.ID != 0 {
+			continue
+		}
+		chemical := entry.chemical
+		applyColumns(&chemical, entry.columns)
+		chemical.OtherNames = entry.names
+		created = append(created, entry)
+		chemicals = append(chemicals, chemical)
+	}
+	if len(chemicals) > 0 {
+		if err := tx.CreateInBatches(&chemicals, batchSize).Error; err != nil {
+			return fmt.Errorf("create aroma chemicals: %w", err)
+		}
+		for i, entry := range created {
+			entry.chemical.ID = chemicals[i].ID
+			entry.stored, entry.names = chemicals[i].OtherNames, chemicals[i].OtherNames
+			entry.updates = map[string]any{}
+		}
+	}
+
+	var addNames []models.OtherName
+	var dropNames []uint
+	for _, entry := range x.all {
+		if len(entry.updates) > 0 {
+			if err := tx.Model(&models.AromaChemical{}).Where("id = ?", entry.chemical.ID).Updates(entry.updates).Error; err != nil {
+				return fmt.Errorf("update aroma chemical %q: %w", entry.name(), err)
+			}
+			entry.updates = map[string]any{}
+		}
+		kept := make(map[string]bool, len(entry.names))
+		for _, name := range entry.names {
+			kept[strings.ToLower(name.Name)] = true
+		}
+		stored := make(map[string]bool, len(entry.stored))
+		for _, name := range entry.stored {
+			key := strings.ToLower(name.Name)
+			if kept[key] && !stored[key] {
+				stored[key] = true
+				continue
+			}
+			dropNames = append(dropNames, name.ID)
+		}
+		for _, name := range entry.names {
+			if !stored[strings.ToLower(name.Name)] {
+				name.AromaChemicalID = entry.chemical.ID
+				addNames = append(addNames, name)
+			}
+		}
+	}
+	if len(dropNames) > 0 {
+		if err := tx.Delete(&models.OtherName{}, dropNames).Error; err != nil {
+			return fmt.Errorf("remove other names: %w", err)
+		}
+	}
+	if len(addNames) > 0 {
+		if err := tx.CreateInBatches(&addNames, batchSize).Error; err != nil {
+			return fmt.Errorf("add other names: %w", err)
+		}
+	}
+	return nil
+}
+
+// changedColumns lists the update columns whose value differs from existing.
+func changedColumns(existing models.AromaChemical, updates map[string]any) []string {
+	return changedFrom(chemicalColumns(existing), updates)
+}
+
+// changedFrom lists the update columns whose value differs from current.
+func changedFrom(current, updates map[string]any) []string {
+	changed := make([]string, 0)
+	for column, value := range updates {
+		if current[column] != value {
+			changed = append(changed, column)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// chemicalColumns returns the columns an import may update, by name.
+func chemicalColumns(chemical models.AromaChemical) map[string]any {
+	return map[string]any{
+		"ingredient_name":      chemical.IngredientName,
+		"cas_number":           chemical.CASNumber,
+		"synthetic_id":         chemical.SyntheticID,
+		"notes":                chemical.Notes,
+		"wheel_position":       chemical.WheelPosition,
+		"pyramid_position":     chemical.PyramidPosition,
+		"type":                 chemical.Type,
+		"strength":             chemical.Strength,
+		"recommended_dilution": chemical.RecommendedDilution,
+		"dilution_percentage":  chemical.DilutionPercentage,
+		"max_ifra_percentage":  chemical.MaxIFRAPercentage,
+		"duration":             chemical.Duration,
+		"historic_role":        chemical.HistoricRole,
+		"popularity":           chemical.Popularity,
+		"usage":                chemical.Usage,
+		"origin":               chemical.Origin,
+		"animal_derived":       chemical.AnimalDerived,
+		"public":               chemical.Public,
+	}
+}
+
+// applyColumns is the inverse of chemicalColumns.
+func applyColumns(chemical *models.AromaChemical, columns map[string]any) {
+	chemical.IngredientName = columns["ingredient_name"].(string)
+	chemical.CASNumber = columns["cas_number"].(string)
+	chemical.SyntheticID = columns["synthetic_id"].(string)
+	chemical.Notes = columns["notes"].(string)
+	chemical.WheelPosition = columns["wheel_position"].(string)
+	chemical.PyramidPosition = columns["pyramid_position"].(string)
+	chemical.Type = columns["type"].(string)
+	chemical.Strength = columns["strength"].(int)
+	chemical.RecommendedDilution = columns["recommended_dilution"].(float64)
+	chemical.DilutionPercentage = columns["dilution_percentage"].(float64)
+	chemical.MaxIFRAPercentage = columns["max_ifra_percentage"].(float64)
+	chemical.Duration = columns["duration"].(string)
+	chemical.HistoricRole = columns["historic_role"].(string)
+	chemical.Popularity = columns["popularity"].(int)
+	chemical.Usage = columns["usage"].(string)
+	chemical.Origin = columns["origin"].(string)
+	chemical.AnimalDerived = columns["animal_derived"].(bool)
+	chemical.Public = columns["public"].(bool)
+}
+
+// ParseCSV reads a master list into one map per data row, keyed by header.
+func ParseCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("csv is empty")
+	}
+
+	header := rows[0]
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		if len(row) == 0 {
+			continue
+		}
+
+		record := make(map[string]string, len(header))
+		for idx, key := range header {
+			if idx >= len(row) {
+				continue
+			}
+			value := strings.TrimSpace(row[idx])
+			record[key] = value
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// buildAromaChemical maps a CSV row onto a chemical and reports the values it
+// had to coerce along the way.
+func buildAromaChemical(row map[string]string) (models.AromaChemical, []RowIssue) {
+	name := strings.TrimSpace(row["Ingredient Name"])
+	casNumber, syntheticID := normalizeCAS(row["CAS Number"], name)
+	var issues []RowIssue
+
+	chemical := models.AromaChemical{
+		IngredientName:      name,
+		CASNumber:           casNumber,
+		SyntheticID:         syntheticID,
+		Notes:               normalizeText(row["Notes"]),
+		WheelPosition:       normalizeValue(row["Wheel Position"]),
+		PyramidPosition:     normalizeValue(row["Pyramid Position"]),
+		Type:                normalizeValue(row["Type"]),
+		Strength:            models.StrengthScale().Value(row["Strength"]),
+		RecommendedDilution: parseFirstNumber(row["Recommended Dilution"]),
+		DilutionPercentage:  parseFirstNumber(row["Recommended Dilution"]),
+		MaxIFRAPercentage:   parseFirstNumber(row["Max % in Concentrate (IFRA Cat. 4)"]),
+		Duration:            normalizeValue(row["Duration (on blotter)"]),
+		HistoricRole:        normalizeValue(row["Historic Role"]),
+		Popularity:          models.PopularityScale().Value(row["Popularity"]),
+		Usage:               normalizeText(row["Usage"]),
+	}
+
+	chemical.OtherNames = buildOtherNames(row["Other Names"])
+
+	if syntheticID != "" {
+		issues = append(issues, RowIssue{
+			Kind:   IssueCoercion,
+			Code:   "synthetic_cas",
+			Field:  "CAS Number",
+			Value:  row["CAS Number"],
+			Detail: "no CAS number; assigned synthetic id " + syntheticID,
+		})
+	}
+	scales := []struct {
+		field string
+		value int
+	}{
+		{"Strength", chemical.Strength},
+		{"Popularity", chemical.Popularity},
+	}
+	for _, scale := range scales {
+		if label := normalizeValue(row[scale.field]); label != "" && scale.value == 0 {
+			issues = append(issues, RowIssue{
+				Kind:   IssueCoercion,
+				Code:   strings.ToLower(scale.field) + "_unmapped",
+				Field:  scale.field,
+				Value:  label,
+				Detail: "label is not on the configured scale; stored as 0",
+			})
+		}
+	}
+	for _, field := range []string{"Recommended Dilution", "Max % in Concentrate (IFRA Cat. 4)"} {
+		if raw := normalizeValue(row[field]); raw != "" && numberPattern.FindString(raw) == "" {
+			issues = append(issues, RowIssue{
+				Kind:   IssueCoercion,
+				Code:   "number_unparsed",
+				Field:  field,
+				Value:  raw,
+				Detail: "no number found; stored as 0",
+			})
+		}
+	}
+
+	return chemical, issues
+}
+
+func normalizeValue(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "N/A") {
+		return ""
+	}
+	return value
+}
+
+func normalizeText(value string) string {
+	value = normalizeValue(value)
+	if value == "" {
+		return value
+	}
+	value = cleanWhitespace.ReplaceAllString(value, " ")
+	return strings.TrimSpace(value)
+}
+
+func parseFirstNumber(value string) float64 {
+	value = normalizeValue(value)
+	if value == "" {
+		return 0
+	}
+
+	matches := numberPattern.FindString(value)
+	if matches == "" {
+		return 0
+	}
+
+	parsed, err := strconv.ParseFloat(matches, 64)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
+
+func buildOtherNames(value string) []models.OtherName {
+	value = normalizeValue(value)
+	if value == "" {
+		return nil
+	}
+
+	parts := splitOtherNames(value)
+	names := make([]models.OtherName, 0, len(parts))
+	seen := map[string]struct{}{}
+	for _, part := range parts {
+		clean := strings.TrimSpace(part)
+		if clean == "" {
+			continue
+		}
+		clean = stripFootnotes(clean)
+		clean = strings.Trim(clean, ";,")
+		clean = strings.TrimSpace(clean)
+		if clean == "" {
+			continue
+		}
+		if _, ok := seen[strings.ToLower(clean)]; ok {
+			continue
+		}
+		seen[strings.ToLower(clean)] = struct{}{}
+		names = append(names, models.OtherName{Name: clean})
+	}
+	return names
+}
+
+func splitOtherNames(value string) []string {
+	value = strings.ReplaceAll(value, ";", ",")
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		result = append(result, strings.TrimSpace(part))
+	}
+	return result
+}
+
+func stripFootnotes(value string) string {
+	return strings.TrimSpace(bracketPattern.ReplaceAllString(value, ""))
+}
+
+// combineOtherNames merges current, newNames and extra into one list sorted
+// by name, dropping blanks, case-insensitive duplicates and the canonical
+// ingredient name. Current names come first so their language and kind
+// survive a re-import of the same name.
+func combineOtherNames(canonical string, current, newNames []models.OtherName, extra []string) []models.OtherName {
+	nameMap := make(map[string]models.OtherName)
+
+	addName := func(entry models.OtherName) {
+		value := strings.TrimSpace(entry.Name)
+		if value == "" {
+			return
+		}
+		if strings.EqualFold(value, canonical) {
+			return
+		}
+		key := strings.ToLower(value)
+		if _, ok := nameMap[key]; !ok {
+			nameMap[key] = models.OtherName{Name: value, Language: entry.Language, Kind: entry.Kind}
+		}
+	}
+
+	for _, entry := range current {
+		addName(entry)
+	}
+	for _, entry := range newNames {
+		addName(entry)
+	}
+	for _, alias := range extra {
+		addName(models.OtherName{Name: alias})
+	}
+
+	keys := make([]string, 0, len(nameMap))
+	for key := range nameMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	combined := make([]models.OtherName, 0, len(keys))
+	for _, key := range keys {
+		combined = append(combined, nameMap[key])
+	}
+	return combined
+}
+
+// normalizeCAS returns the row's CAS number, or an empty one and a
+// synthetic identifier when the row names a mixture or has no number.
+func normalizeCAS(raw string, ingredient string) (cas, synthetic string) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return "", syntheticCAS("UNASSIGNED", ingredient)
+	}
+
+	upper := strings.ToUpper(value)
+	switch {
+	case upper == "N/A", upper == "NA", upper == "NOT APPLICABLE", upper == "NOT ASSIGNED", upper == "UNKNOWN", upper == "NONE":
+		return "", syntheticCAS("UNASSIGNED", ingredient)
+	case strings.Contains(upper, "MIXTURE"):
+		return "", syntheticCAS("MIXTURE", ingredient)
+	case strings.Contains(upper, "BLEND"):
+		return "", syntheticCAS("BLEND", ingredient)
+	}
+
+	return value, ""
+}
+
+func syntheticCAS(prefix, ingredient string) string {
+	slug := slugify(ingredient)
+	if slug == "" {
+		slug = "component"
+	}
+	return fmt.Sprintf("%s-%s", prefix, slug)
+}
+
+func slugify(value string) string {
+	value = strings.ToLower(value)
+	value = slugPattern.ReplaceAllString(value, "-")
+	value = strings.Trim(value, "-")
+	return value
+}
@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func TestImportAromaBatchesRows(t *testing.T) {
+	ctx := context.Background()
+	dsn := fmt.Sprintf("file:aroma-batch-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.AromaChemical{}, &models.OtherName{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	stored := models.AromaChemical{IngredientName: "Hedione", CASNumber: "24851-98-7", OwnerID: 1, OtherNames: []models.OtherName{{Name: "MDJ", Language: "en"}}}
+	if err := db.Create(&stored).Error; err != nil {
+		t.Fatalf("create chemical: %v", err)
+	}
+
+	records, err := ParseCSV(strings.NewReader(strings.Join([]string{
+		"Ingredient Name,CAS Number,Other Names,Notes",
+		"Iso E Super,54464-57-2,OTNE,first",
+		"Methyl Dihydrojasmonate,24851-98-7,,renamed by CAS",
+		"Ambroxan,6790-58-5,Ambrox,",
+		"Iso E Super,54464-57-2,Orbitone,second",
+		",,,",
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+
+	var progress []int
+	var report Report
+	opts := AromaImport{OwnerID: 1, BatchSize: 2, Progress: func(done, total int) { progress = append(progress, done) }}
+	if err := ImportAroma(ctx, db, records, opts, &report); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if fmt.Sprint(progress) != "[2 4 5]" {
+		t.Fatalf("expected progress after each batch, got %v", progress)
+	}
+	if report.Created != 2 || report.Updated != 2 || report.Skipped != 1 || report.Seconds <= 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	var chemicals []models.AromaChemical
+	if err := db.Preload("OtherNames").Order("id").Find(&chemicals).Error; err != nil {
+		t.Fatalf("load chemicals: %v", err)
+	}
+	if len(chemicals) != 3 {
+		t.Fatalf("expected three chemicals, got %+v", chemicals)
+	}
+	names := func(c models.AromaChemical) string {
+		var out []string
+		for _, name := range c.OtherNames {
+			out = append(out, name.Name+"/"+name.Language)
+		}
+		return strings.Join(out, ",")
+	}
+	if hedione := chemicals[0]; hedione.IngredientName != "Hedione" || hedione.Notes != "renamed by CAS" || names(hedione) != "MDJ/en,Methyl Dihydrojasmonate/" {
+		t.Fatalf("expected the CAS match to keep its name and gain an alias, got %+v %s", hedione, names(hedione))
+	}
+	if iso := chemicals[1]; iso.IngredientName != "Iso E Super" || iso.Notes != "second" || names(iso) != "OTNE/,Orbitone/" {
+		t.Fatalf("expected the duplicate row to update the chemical created before it, got %+v %s", iso, names(iso))
+	}
+
+	// Importing the same list again changes nothing and rewrites no names.
+	var before int64
+	db.Unscoped().Model(&models.OtherName{}).Count(&before)
+	report = Report{}
+	if err := ImportAroma(ctx, db, records, AromaImport{OwnerID: 1}, &report); err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	var after int64
+	db.Unscoped().Model(&models.OtherName{}).Count(&after)
+	if report.Created != 0 || after != before {
+		t.Fatalf("expected an idempotent re-import, got report %+v and %d -> %d names", report, before, after)
+	}
+	for _, change := range report.Changes {
+		if change.Ingredient != "Iso E Super" {
+			t.Fatalf("expected only the duplicated row to change, got %+v", report.Changes)
+		}
+	}
+}
+
+func TestImportAromaKeepsMaterialsWithoutCASApart(t *testing.T) {
+	dsn := fmt.Sprintf("file:aroma-synthetic-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.AromaChemical{}, &models.OtherName{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	records, err := ParseCSV(strings.NewReader("Ingredient Name,CAS Number\nRose Oil,Mixture\nRose-Oil,mixture\n"))
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	var report Report
+	if err := ImportAroma(context.Background(), db, records, AromaImport{OwnerID: 1}, &report); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	var chemicals []models.AromaChemical
+	if err := db.Order("id").Find(&chemicals).Error; err != nil {
+		t.Fatalf("load chemicals: %v", err)
+	}
+	if report.Created != 2 || len(chemicals) != 2 {
+		t.Fatalf("expected two separate materials, got %+v", chemicals)
+	}
+	for _, chemical := range chemicals {
+		if chemical.CASNumber != "" || chemical.SyntheticID != "MIXTURE-rose-oil" {
+			t.Fatalf("expected an empty CAS and a synthetic id, got %+v", chemical)
+		}
+	}
+}
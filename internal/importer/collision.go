@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+// CollisionStrategy decides what an import does when an incoming ingredient
+// matches, by name or CAS number, a public chemical owned by another user.
+type CollisionStrategy string
+
+const (
+	// CollisionDuplicate creates a private copy named "<name> (Private)".
+	CollisionDuplicate CollisionStrategy = "duplicate"
+	// CollisionSkip leaves the public record alone and imports nothing.
+	CollisionSkip CollisionStrategy = "skip"
+	// CollisionAlias adds the imported names to the public record's other
+	// names instead of creating a chemical.
+	CollisionAlias CollisionStrategy = "alias"
+)
+
+var collisionStrategy = CollisionDuplicate
+
+// ParseCollisionStrategy validates a configured strategy. An empty value
+// selects CollisionDuplicate.
+func ParseCollisionStrategy(value string) (CollisionStrategy, error) {
+	switch strategy := CollisionStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case "":
+		return CollisionDuplicate, nil
+	case CollisionDuplicate, CollisionSkip, CollisionAlias:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown import collision strategy %q", value)
+	}
+}
+
+// SetCollisionStrategy installs the strategy used by ImportAroma and the AI
+// ingredient import.
+func SetCollisionStrategy(strategy CollisionStrategy) {
+	collisionStrategy = strategy
+}
+
+// CurrentCollisionStrategy reports the strategy installed by
+// SetCollisionStrategy.
+func CurrentCollisionStrategy() CollisionStrategy {
+	return collisionStrategy
+}
+
+// PrivateCopyName returns the first unused "<base> (Private)" or
+// "<base> (Private N)" ingredient name.
+func PrivateCopyName(tx *gorm.DB, base string) (string, error) {
+	return privateCopyName(tx, base, nil)
+}
+
+// privateCopyName is PrivateCopyName that also skips names taken reports
+// as used, such as chemicals not yet written.
+func privateCopyName(tx *gorm.DB, base string, taken func(string) bool) (string, error) {
+	candidate := fmt.Sprintf("%s (Private)", base)
+	for suffix := 2; ; suffix++ {
+		if taken != nil && taken(candidate) {
+			candidate = fmt.Sprintf("%s (Private %d)", base, suffix)
+			continue
+		}
+		var count int64
+		if err := tx.Model(&models.AromaChemical{}).
+			Where("lower(ingredient_name) = ?", strings.ToLower(candidate)).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s (Private %d)", base, suffix)
+	}
+}
+
+// LinkAliases adds names to the chemical's other names, skipping blanks, its
+// own ingredient name and names it already has. It returns the names added.
+func LinkAliases(tx *gorm.DB, chemical models.AromaChemical, names ...string) ([]string, error) {
+	aliases := make([]models.OtherName, 0, len(names))
+	for _, name := range names {
+		aliases = append(aliases, models.OtherName{Name: name})
+	}
+	return LinkTypedAliases(tx, chemical, aliases...)
+}
+
+// LinkTypedAliases is LinkAliases for aliases carrying a language and kind,
+// which are stored with them.
+func LinkTypedAliases(tx *gorm.DB, chemical models.AromaChemical, aliases ...models.OtherName) ([]string, error) {
+	var current []models.OtherName
+	if err := tx.Where("aroma_chemical_id = ?", chemical.ID).Find(&current).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]struct{}{strings.ToLower(chemical.IngredientName): {}}
+	for _, entry := range current {
+		seen[strings.ToLower(entry.Name)] = struct{}{}
+	}
+
+	var added []string
+	for _, alias := range aliases {
+		name := strings.TrimSpace(alias.Name)
+		key := strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		entry := models.OtherName{Name: name, Language: alias.Language, Kind: alias.Kind, AromaChemicalID: chemical.ID}
+		if err := tx.Create(&entry).Error; err != nil {
+			return nil, err
+		}
+		added = append(added, name)
+	}
+	return added, nil
+}
@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func TestImportAromaPublicCollisionStrategies(t *testing.T) {
+	records, err := ParseCSV(strings.NewReader(strings.Join([]string{
+		"Ingredient Name,CAS Number,Other Names,Notes",
+		"Hedione,24851-98-7,MDJ,Imported",
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+
+	tests := []struct {
+		strategy CollisionStrategy
+		check    func(t *testing.T, db *gorm.DB, report Report)
+	}{
+		{CollisionSkip, func(t *testing.T, db *gorm.DB, report Report) {
+			var count int64
+			db.Model(&models.AromaChemical{}).Count(&count)
+			if report.Skipped != 1 || count != 1 {
+				t.Fatalf("expected the row to be skipped, got report %+v and %d chemicals", report, count)
+			}
+		}},
+		{CollisionDuplicate, func(t *testing.T, db *gorm.DB, report Report) {
+			var copy models.AromaChemical
+			if err := db.Where("owner_id = ?", 1).First(&copy).Error; err != nil {
+				t.Fatalf("load private copy: %v", err)
+			}
+			if report.Created != 1 || copy.IngredientName != "Hedione (Private)" || copy.Public {
+				t.Fatalf("unexpected private copy %+v (report %+v)", copy, report)
+			}
+		}},
+		{CollisionAlias, func(t *testing.T, db *gorm.DB, report Report) {
+			var names []models.OtherName
+			db.Order("name asc").Find(&names)
+			if report.Updated != 1 || len(names) != 1 || names[0].Name != "MDJ" {
+				t.Fatalf("expected MDJ linked to the public record, got %+v (report %+v)", names, report)
+			}
+			var public models.AromaChemical
+			db.First(&public)
+			if public.Notes != "Shared" {
+				t.Fatalf("public record was modified: %+v", public)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			dsn := fmt.Sprintf("file:collision-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+			db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+				Logger:                                   logger.Default.LogMode(logger.Silent),
+				DisableForeignKeyConstraintWhenMigrating: true,
+			})
+			if err != nil {
+				t.Fatalf("open sqlite: %v", err)
+			}
+			if err := db.AutoMigrate(&models.AromaChemical{}, &models.OtherName{}); err != nil {
+				t.Fatalf("automigrate: %v", err)
+			}
+			public := models.AromaChemical{IngredientName: "Hedione", CASNumber: "24851-98-7", Notes: "Shared", OwnerID: 2, Public: true}
+			if err := db.Create(&public).Error; err != nil {
+				t.Fatalf("create public chemical: %v", err)
+			}
+
+			prev := CurrentCollisionStrategy()
+			SetCollisionStrategy(tt.strategy)
+			t.Cleanup(func() { SetCollisionStrategy(prev) })
+
+			var report Report
+			if err := ImportAroma(context.Background(), db, records, AromaImport{OwnerID: 1}, &report); err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			if len(report.Issues) == 0 || report.Issues[0].Code != "public_collision" {
+				t.Fatalf("expected a public_collision issue, got %+v", report.Issues)
+			}
+			tt.check(t, db, report)
+		})
+	}
+}
+
+func TestParseCollisionStrategy(t *testing.T) {
+	if strategy, err := ParseCollisionStrategy(""); err != nil || strategy != CollisionDuplicate {
+		t.Fatalf("empty strategy = %q, %v", strategy, err)
+	}
+	if strategy, err := ParseCollisionStrategy(" Alias "); err != nil || strategy != CollisionAlias {
+		t.Fatalf("alias strategy = %q, %v", strategy, err)
+	}
+	if _, err := ParseCollisionStrategy("rename"); err == nil {
+		t.Fatal("expected an error for an unknown strategy")
+	}
+}
@@ -0,0 +1,46 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+// OwnerEnv names the environment variable the import commands read the
+// owner's email from when no owner flag is given.
+const OwnerEnv = "PERFUGO_AROMA_OWNER_EMAIL"
+
+// ErrNoOwner is returned by ResolveOwner when no owner was named.
+var ErrNoOwner = errors.New("no owner given: pass --owner or --owner-id, or set " + OwnerEnv)
+
+// ResolveOwner returns the user that owns imported library data, named by
+// either email or id. Naming neither, or both, is an error: imports never
+// fall back to an arbitrary user.
+func ResolveOwner(ctx context.Context, db *gorm.DB, email string, id uint) (uint, error) {
+	if db == nil {
+		return 0, errors.New("database handle is nil")
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	var user models.User
+	switch {
+	case email != "" && id != 0:
+		return 0, errors.New("name the owner by email or by id, not both")
+	case email != "":
+		if err := db.WithContext(ctx).Where("lower(email) = ?", email).First(&user).Error; err != nil {
+			return 0, fmt.Errorf("find owner by email %q: %w", email, err)
+		}
+	case id != 0:
+		if err := db.WithContext(ctx).First(&user, id).Error; err != nil {
+			return 0, fmt.Errorf("find owner %d: %w", id, err)
+		}
+	default:
+		return 0, ErrNoOwner
+	}
+	return user.ID, nil
+}
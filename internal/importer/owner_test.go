@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func TestResolveOwnerRequiresAnExplicitOwner(t *testing.T) {
+	ctx := context.Background()
+	dsn := fmt.Sprintf("file:owner-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.AromaChemical{}, &models.OtherName{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	first := models.User{Email: "first@example.com", PasswordHash: "x"}
+	second := models.User{Email: "second@example.com", PasswordHash: "x"}
+	for _, user := range []*models.User{&first, &second} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	if _, err := ResolveOwner(ctx, db, "", 0); !errors.Is(err, ErrNoOwner) {
+		t.Fatalf("expected ErrNoOwner without an owner, got %v", err)
+	}
+	if _, err := ResolveOwner(ctx, db, second.Email, second.ID); err == nil {
+		t.Fatal("expected an error when both email and id are given")
+	}
+	if id, err := ResolveOwner(ctx, db, " SECOND@example.com ", 0); err != nil || id != second.ID {
+		t.Fatalf("expected the owner by email, got %d, %v", id, err)
+	}
+	if id, err := ResolveOwner(ctx, db, "", second.ID); err != nil || id != second.ID {
+		t.Fatalf("expected the owner by id, got %d, %v", id, err)
+	}
+	if _, err := ResolveOwner(ctx, db, "", 99); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected an unknown id to fail, got %v", err)
+	}
+
+	records, err := ParseCSV(strings.NewReader("Ingredient Name,CAS Number\nHedione,24851-98-7\n"))
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if err := ImportAroma(ctx, db, records, AromaImport{OwnerID: second.ID}, &Report{}); err != nil {
+		t.Fatalf("private import: %v", err)
+	}
+	if err := ImportAroma(ctx, db, records, AromaImport{OwnerID: second.ID, Public: true}, &Report{}); err != nil {
+		t.Fatalf("shared import: %v", err)
+	}
+	var chemicals []models.AromaChemical
+	if err := db.Find(&chemicals).Error; err != nil {
+		t.Fatalf("load chemicals: %v", err)
+	}
+	if len(chemicals) != 1 || chemicals[0].OwnerID != second.ID || !chemicals[0].Public {
+		t.Fatalf("expected the shared import to publish the owner's chemical, got %+v", chemicals)
+	}
+}
@@ -0,0 +1,168 @@
+// Package importer loads the master aroma chemical list and allergen
+// composition data into the library. It backs the import_aroma and
+// import_allergens commands and scheduled imports.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Issue kinds recorded in the import report.
+const (
+	IssueSkipped  = "skipped"
+	IssueCoercion = "coercion"
+	IssueConflict = "conflict"
+)
+
+// Report formats accepted by WriteReport.
+const (
+	ReportFormatJSON = "json"
+	ReportFormatCSV  = "csv"
+)
+
+// Report is the machine-readable summary written after an import.
+type Report struct {
+	Source   string     `json:"source"`
+	Checksum string     `json:"checksum"`
+	Rows     int        `json:"rows"`
+	Created  int        `json:"created"`
+	Updated  int        `json:"updated"`
+	Skipped  int        `json:"skipped"`
+	Issues   []RowIssue `json:"issues"`
+	Changes  []Change   `json:"changes"`
+	Complete bool       `json:"complete"`
+	// Seconds is how long the import took.
+	Seconds float64 `json:"seconds"`
+}
+
+// RowsPerSecond is the import's throughput, or zero before it has run.
+func (r *Report) RowsPerSecond() float64 {
+	if r.Seconds <= 0 {
+		return 0
+	}
+	return float64(r.Rows) / r.Seconds
+}
+
+// Change actions recorded for imported records.
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+)
+
+// Change records a chemical the import created, or updated together with the
+// columns whose values changed. Updates that changed nothing are omitted.
+type Change struct {
+	Row        int      `json:"row"`
+	Ingredient string   `json:"ingredient"`
+	Action     string   `json:"action"`
+	Fields     []string `json:"fields,omitempty"`
+}
+
+// RowIssue describes one problem with a CSV row. Row counts data rows from 1,
+// so the matching spreadsheet line is Row+1.
+type RowIssue struct {
+	Row        int    `json:"row"`
+	Ingredient string `json:"ingredient"`
+	Kind       string `json:"kind"`
+	Code       string `json:"code"`
+	Field      string `json:"field,omitempty"`
+	Value      string `json:"value,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+func (r *Report) add(row int, ingredient string, issues ...RowIssue) {
+	for _, issue := range issues {
+		issue.Row = row
+		issue.Ingredient = ingredient
+		r.Issues = append(r.Issues, issue)
+	}
+}
+
+// Count returns the number of issues of the given kind.
+func (r *Report) Count(kind string) int {
+	total := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			total++
+		}
+	}
+	return total
+}
+
+// ResolveReportFormat picks the report format from the explicit format or, when
+// that is empty, from the report file's extension.
+func ResolveReportFormat(path, format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case ReportFormatCSV:
+		return ReportFormatCSV, nil
+	case ReportFormatJSON, "":
+		return ReportFormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported report format %q (want json or csv)", format)
+	}
+}
+
+// WriteReportFile stores the report at path in the requested format.
+func WriteReportFile(path, format string, report Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteReport(file, format, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func WriteReport(w io.Writer, format string, report Report) error {
+	if format == ReportFormatCSV {
+		writer := csv.NewWriter(w)
+		_ = writer.Write([]string{"Row", "Ingredient", "Kind", "Code", "Field", "Value", "Detail"})
+		for _, issue := range report.Issues {
+			_ = writer.Write([]string{
+				strconv.Itoa(issue.Row),
+				issue.Ingredient,
+				issue.Kind,
+				issue.Code,
+				issue.Field,
+				issue.Value,
+				issue.Detail,
+			})
+		}
+		for _, change := range report.Changes {
+			_ = writer.Write([]string{
+				strconv.Itoa(change.Row),
+				change.Ingredient,
+				change.Action,
+				"",
+				strings.Join(change.Fields, ";"),
+				"",
+				"",
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	if report.Issues == nil {
+		report.Issues = []RowIssue{}
+	}
+	if report.Changes == nil {
+		report.Changes = []Change{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
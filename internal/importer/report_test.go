@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBuildAromaChemicalReportsCoercions(t *testing.T) {
+	chemical, issues := buildAromaChemical(map[string]string{
+		"Ingredient Name":      "Vetiver Heart",
+		"CAS Number":           "Mixture",
+		"Strength":             "Thunderous",
+		"Popularity":           "N/A",
+		"Recommended Dilution": "neat",
+	})
+	if chemical.CASNumber != "" || !strings.HasPrefix(chemical.SyntheticID, "MIXTURE-") {
+		t.Fatalf("expected no CAS and a synthetic id, got %q and %q", chemical.CASNumber, chemical.SyntheticID)
+	}
+
+	codes := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		if issue.Kind != IssueCoercion {
+			t.Fatalf("unexpected issue kind: %+v", issue)
+		}
+		codes = append(codes, issue.Code)
+	}
+	want := "synthetic_cas,strength_unmapped,number_unparsed"
+	if got := strings.Join(codes, ","); got != want {
+		t.Fatalf("expected issues %s, got %s", want, got)
+	}
+
+	if _, issues := buildAromaChemical(map[string]string{"Ingredient Name": "Hedione", "CAS Number": "24851-98-7"}); len(issues) != 0 {
+		t.Fatalf("expected a clean row to report nothing, got %+v", issues)
+	}
+}
+
+func TestResolveReportFormat(t *testing.T) {
+	cases := []struct {
+		path, format, want string
+		wantErr            bool
+	}{
+		{path: "report.csv", want: ReportFormatCSV},
+		{path: "report.json", want: ReportFormatJSON},
+		{path: "report", want: ReportFormatJSON},
+		{path: "report.txt", format: "CSV", want: ReportFormatCSV},
+		{path: "report.xml", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ResolveReportFormat(tc.path, tc.format)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Fatalf("ResolveReportFormat(%q, %q) = %q, %v", tc.path, tc.format, got, err)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	report := Report{Source: "master.csv", Rows: 2, Created: 1, Skipped: 1, Complete: true}
+	report.add(2, "", RowIssue{Kind: IssueSkipped, Code: "missing_name", Detail: "row has no ingredient name"})
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, ReportFormatCSV, report); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	if !strings.Contains(buf.String(), "2,,skipped,missing_name") {
+		t.Fatalf("unexpected csv report: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteReport(&buf, ReportFormatJSON, report); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if decoded.Skipped != 1 || len(decoded.Issues) != 1 || decoded.Issues[0].Row != 2 {
+		t.Fatalf("unexpected json report: %+v", decoded)
+	}
+}
@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxSourceBytes caps how much of a remote CSV export is read.
+const maxSourceBytes = 32 << 20
+
+// sourceClient downloads remote sources; tests swap it for a TLS test client.
+var sourceClient = &http.Client{Timeout: time.Minute}
+
+// Source is the raw master list together with a display name and the
+// SHA-256 checksum logged for each import.
+type Source struct {
+	Name     string
+	Data     []byte
+	Checksum string
+}
+
+// isRemoteSource reports whether source names a URL rather than a local path.
+func isRemoteSource(source string) bool {
+	lower := strings.ToLower(strings.TrimSpace(source))
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// LoadSource reads the master list from a local path or an https:// URL such
+// as a published Google Sheets CSV export. Plain http is refused.
+func LoadSource(ctx context.Context, source string) (Source, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return Source{}, fmt.Errorf("csv path must not be empty")
+	}
+
+	if !isRemoteSource(source) {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return Source{}, fmt.Errorf("locate csv: %w", err)
+		}
+		return newSource(filepath.Base(source), data), nil
+	}
+
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return Source{}, fmt.Errorf("parse source url: %w", err)
+	}
+	if parsed.Scheme != "https" {
+		return Source{}, fmt.Errorf("remote sources must use https, got %q", parsed.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return Source{}, fmt.Errorf("build source request: %w", err)
+	}
+	req.Header.Set("Accept", "text/csv")
+
+	resp, err := sourceClient.Do(req)
+	if err != nil {
+		return Source{}, fmt.Errorf("download csv: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Source{}, fmt.Errorf("download csv: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceBytes+1))
+	if err != nil {
+		return Source{}, fmt.Errorf("download csv: %w", err)
+	}
+	if len(data) > maxSourceBytes {
+		return Source{}, fmt.Errorf("download csv: source exceeds %d bytes", maxSourceBytes)
+	}
+
+	// The query string of a published sheet often carries its access key, so
+	// only the host and path are shown.
+	return newSource(parsed.Host+parsed.Path, data), nil
+}
+
+func newSource(name string, data []byte) Source {
+	sum := sha256.Sum256(data)
+	return Source{Name: name, Data: data, Checksum: hex.EncodeToString(sum[:])}
+}
@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleCSV = "Ingredient Name,CAS Number\nHedione,24851-98-7\n"
+
+func TestLoadSourceFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "master.csv")
+	if err := os.WriteFile(path, []byte(sampleCSV), 0o600); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+
+	source, err := LoadSource(context.Background(), path)
+	if err != nil {
+		t.Fatalf("LoadSource: %v", err)
+	}
+	sum := sha256.Sum256([]byte(sampleCSV))
+	if source.Name != "master.csv" || source.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected source: %+v", source)
+	}
+}
+
+func TestLoadSourceFromURL(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sheet/pub" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		_, _ = w.Write([]byte(sampleCSV))
+	}))
+	defer server.Close()
+
+	previous := sourceClient
+	sourceClient = server.Client()
+	t.Cleanup(func() { sourceClient = previous })
+
+	source, err := LoadSource(context.Background(), server.URL+"/sheet/pub?output=csv&key=secret")
+	if err != nil {
+		t.Fatalf("LoadSource: %v", err)
+	}
+	if string(source.Data) != sampleCSV {
+		t.Fatalf("unexpected body: %q", source.Data)
+	}
+	if strings.Contains(source.Name, "secret") || !strings.HasSuffix(source.Name, "/sheet/pub") {
+		t.Fatalf("expected the query string to be dropped from the name, got %q", source.Name)
+	}
+
+	if _, err := LoadSource(context.Background(), server.URL+"/missing"); err == nil {
+		t.Fatal("expected a 404 to fail the import")
+	}
+}
+
+func TestLoadSourceRejectsPlainHTTP(t *testing.T) {
+	if _, err := LoadSource(context.Background(), "http://example.com/master.csv"); err == nil || !strings.Contains(err.Error(), "https") {
+		t.Fatalf("expected plain http to be refused, got %v", err)
+	}
+}
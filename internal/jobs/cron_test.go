@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *", "0 0 31 2 *", "0 0 31 2,4 *"} {
+		expr := expr
+		t.Run(expr, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseCron(expr); err == nil {
+				t.Fatalf("expected %q to be rejected", expr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	// Wednesday 15 January 2025.
+	from := time.Date(2025, time.January, 15, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{name: "every quarter hour", expr: "*/15 * * * *", want: time.Date(2025, time.January, 15, 10, 15, 0, 0, time.UTC)},
+		{name: "daily shorthand", expr: "@daily", want: time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{name: "monday mornings", expr: "0 3 * * 1", want: time.Date(2025, time.January, 20, 3, 0, 0, 0, time.UTC)},
+		{name: "sunday as seven", expr: "30 6 * * 7", want: time.Date(2025, time.January, 19, 6, 30, 0, 0, time.UTC)},
+		{name: "day or weekday", expr: "0 0 1 * 5", want: time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "month list", expr: "0 12 1 3,6 *", want: time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.expr, err)
+			}
+			if got := cron.Next(from); !got.Equal(tt.want) {
+				t.Fatalf("Next(%q) = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"perfugo/internal/importer"
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// ScheduledImportsJob runs due import schedules. The interval only controls
+// how often schedules are checked; each schedule's cron expression decides
+// when it actually runs.
+func ScheduledImportsJob(db *gorm.DB, interval time.Duration) Job {
+	return Job{
+		Name:     "scheduled-imports",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := RunDueImports(ctx, db, time.Now())
+			return err
+		},
+	}
+}
+
+// RunDueImports executes every enabled schedule whose next run is at or
+// before now and returns how many ran. Schedules that have never been
+// planned are given a next run time without running.
+func RunDueImports(ctx context.Context, db *gorm.DB, now time.Time) (int, error) {
+	if db == nil {
+		return 0, errors.New("database handle is nil")
+	}
+
+	var schedules []models.ImportSchedule
+	if err := db.WithContext(ctx).
+		Where("enabled = ?", true).
+		Where("next_run_at IS NULL OR next_run_at <= ?", now).
+		Order("id asc").
+		Find(&schedules).Error; err != nil {
+		return 0, err
+	}
+
+	ran := 0
+	for i := range schedules {
+		schedule := &schedules[i]
+		if schedule.NextRunAt == nil {
+			if err := planNextImport(ctx, db, schedule, now); err != nil {
+				applog.Error(ctx, "failed to plan import schedule", "error", err, "scheduleID", schedule.ID)
+			}
+			continue
+		}
+		if _, err := RunImportSchedule(ctx, db, schedule, now); err != nil {
+			if ctx.Err() != nil {
+				requeued := make([]uint, 0, len(schedules)-i)
+				for _, pending := range schedules[i:] {
+					requeued = append(requeued, pending.ID)
+				}
+				applog.Info(context.Background(), "scheduled imports interrupted; left due for the next run", "scheduleIDs", requeued)
+				return ran, ctx.Err()
+			}
+			applog.Error(ctx, "scheduled import failed", "error", err, "scheduleID", schedule.ID, "source", schedule.Source)
+		}
+		ran++
+	}
+	return ran, nil
+}
+
+// RunImportSchedule imports the schedule's source now, records an ImportRun
+// and moves the schedule to its next cron time. A source whose checksum
+// matches the last successful import is recorded as unchanged. A run cut
+// short by ctx is not recorded and leaves the schedule due, so it is retried.
+func RunImportSchedule(ctx context.Context, db *gorm.DB, schedule *models.ImportSchedule, now time.Time) (models.ImportRun, error) {
+	run := models.ImportRun{ScheduleID: schedule.ID, StartedAt: now, Status: models.ImportRunFailed}
+	runErr := executeImport(ctx, db, schedule, &run)
+	if ctx.Err() != nil {
+		return run, ctx.Err()
+	}
+	run.FinishedAt = time.Now()
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+
+	if err := db.WithContext(ctx).Create(&run).Error; err != nil {
+		return run, err
+	}
+
+	updates := map[string]any{"last_run_at": now}
+	if runErr == nil {
+		updates["last_checksum"] = run.Checksum
+	}
+	if next, ok := nextImportRun(schedule, now); ok {
+		updates["next_run_at"] = next
+	} else {
+		updates["enabled"] = false
+	}
+	if err := db.WithContext(ctx).Model(&models.ImportSchedule{}).Where("id = ?", schedule.ID).Updates(updates).Error; err != nil {
+		return run, err
+	}
+
+	applog.Info(ctx, "scheduled import finished",
+		"scheduleID", schedule.ID,
+		"status", run.Status,
+		"checksum", run.Checksum,
+		"created", run.Created,
+		"updated", run.Updated,
+	)
+	return run, runErr
+}
+
+func executeImport(ctx context.Context, db *gorm.DB, schedule *models.ImportSchedule, run *models.ImportRun) error {
+	if _, err := ParseCron(schedule.Cron); err != nil {
+		return err
+	}
+
+	source, err := importer.LoadSource(ctx, schedule.Source)
+	if err != nil {
+		return err
+	}
+	run.Checksum = source.Checksum
+	if source.Checksum == schedule.LastChecksum {
+		run.Status = models.ImportRunUnchanged
+		return nil
+	}
+
+	records, err := importer.ParseCSV(bytes.NewReader(source.Data))
+	if err != nil {
+		return err
+	}
+
+	report := importer.Report{Source: source.Name, Checksum: source.Checksum}
+	importErr := importer.ImportAroma(ctx, db, records, importer.AromaImport{OwnerID: schedule.OwnerID}, &report)
+	run.Created, run.Updated, run.Skipped = report.Created, report.Updated, report.Skipped
+	if report.Changes != nil {
+		changes, err := json.Marshal(report.Changes)
+		if err != nil {
+			return err
+		}
+		run.Changes = string(changes)
+	}
+	if importErr != nil {
+		return importErr
+	}
+	run.Status = models.ImportRunSucceeded
+	return nil
+}
+
+func planNextImport(ctx context.Context, db *gorm.DB, schedule *models.ImportSchedule, now time.Time) error {
+	next, ok := nextImportRun(schedule, now)
+	if !ok {
+		return db.WithContext(ctx).Model(&models.ImportSchedule{}).Where("id = ?", schedule.ID).Update("enabled", false).Error
+	}
+	return db.WithContext(ctx).Model(&models.ImportSchedule{}).Where("id = ?", schedule.ID).Update("next_run_at", next).Error
+}
+
+// nextImportRun returns when the schedule runs next after now. It returns
+// false when the cron expression is invalid or never matches, in which case
+// the schedule is disabled rather than left due on every poll.
+func nextImportRun(schedule *models.ImportSchedule, now time.Time) (*time.Time, bool) {
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, false
+	}
+	next := cron.Next(now)
+	if next.IsZero() {
+		return nil, false
+	}
+	return &next, true
+}
@@ -0,0 +1,181 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"perfugo/internal/importer"
+	"perfugo/models"
+)
+
+func TestRunImportScheduleRecordsRunsAndDiffs(t *testing.T) {
+	db := newJobsTestDB(t)
+	if err := db.AutoMigrate(&models.OtherName{}, &models.ImportSchedule{}, &models.ImportRun{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "master.csv")
+	writeCSV := func(notes string) {
+		t.Helper()
+		data := "Ingredient Name,CAS Number,Notes\nHedione,24851-98-7," + notes + "\n"
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatalf("write csv: %v", err)
+		}
+	}
+	writeCSV("Jasmine")
+
+	now := time.Date(2025, time.January, 15, 10, 0, 0, 0, time.UTC)
+	schedule := models.ImportSchedule{Name: "Master list", Source: path, Cron: "0 * * * *", OwnerID: 1, Enabled: true, NextRunAt: &now}
+	if err := db.Create(&schedule).Error; err != nil {
+		t.Fatalf("seed schedule: %v", err)
+	}
+
+	ran, err := RunDueImports(context.Background(), db, now)
+	if err != nil {
+		t.Fatalf("run due imports: %v", err)
+	}
+	if ran != 1 {
+		t.Fatalf("expected one schedule to run, got %d", ran)
+	}
+
+	var runs []models.ImportRun
+	if err := db.Order("id asc").Find(&runs).Error; err != nil {
+		t.Fatalf("load runs: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != models.ImportRunSucceeded || runs[0].Created != 1 {
+		t.Fatalf("unexpected first run: %+v", runs)
+	}
+	var changes []importer.Change
+	if err := json.Unmarshal([]byte(runs[0].Changes), &changes); err != nil {
+		t.Fatalf("decode changes: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Action != importer.ChangeCreated || changes[0].Ingredient != "Hedione" {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	if err := db.First(&schedule, schedule.ID).Error; err != nil {
+		t.Fatalf("reload schedule: %v", err)
+	}
+	if schedule.NextRunAt == nil || !schedule.NextRunAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected next run an hour later, got %v", schedule.NextRunAt)
+	}
+	if schedule.LastChecksum == "" {
+		t.Fatal("expected the checksum of the imported source to be stored")
+	}
+
+	run, err := RunImportSchedule(context.Background(), db, &schedule, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("rerun unchanged source: %v", err)
+	}
+	if run.Status != models.ImportRunUnchanged {
+		t.Fatalf("expected unchanged run, got %q", run.Status)
+	}
+
+	writeCSV("Jasmine and magnolia")
+	if err := db.First(&schedule, schedule.ID).Error; err != nil {
+		t.Fatalf("reload schedule: %v", err)
+	}
+	run, err = RunImportSchedule(context.Background(), db, &schedule, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("rerun changed source: %v", err)
+	}
+	if run.Status != models.ImportRunSucceeded || run.Updated != 1 {
+		t.Fatalf("unexpected changed run: %+v", run)
+	}
+	changes = nil
+	if err := json.Unmarshal([]byte(run.Changes), &changes); err != nil {
+		t.Fatalf("decode changes: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Action != importer.ChangeUpdated || len(changes[0].Fields) != 1 || changes[0].Fields[0] != "notes" {
+		t.Fatalf("expected a notes update, got %+v", changes)
+	}
+}
+
+func TestRunDueImportsPlansUnscheduledEntries(t *testing.T) {
+	db := newJobsTestDB(t)
+	if err := db.AutoMigrate(&models.ImportSchedule{}, &models.ImportRun{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	schedule := models.ImportSchedule{Name: "Nightly", Source: "https://example.com/master.csv", Cron: "@daily", Enabled: true}
+	if err := db.Create(&schedule).Error; err != nil {
+		t.Fatalf("seed schedule: %v", err)
+	}
+
+	now := time.Date(2025, time.January, 15, 10, 0, 0, 0, time.UTC)
+	ran, err := RunDueImports(context.Background(), db, now)
+	if err != nil {
+		t.Fatalf("run due imports: %v", err)
+	}
+	if ran != 0 {
+		t.Fatalf("expected nothing to run, got %d", ran)
+	}
+	if err := db.First(&schedule, schedule.ID).Error; err != nil {
+		t.Fatalf("reload schedule: %v", err)
+	}
+	if schedule.NextRunAt == nil || !schedule.NextRunAt.Equal(time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected next run at midnight, got %v", schedule.NextRunAt)
+	}
+}
+
+func TestRunDueImportsDisablesSchedulesThatNeverMatch(t *testing.T) {
+	db := newJobsTestDB(t)
+	if err := db.AutoMigrate(&models.ImportSchedule{}, &models.ImportRun{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	schedule := models.ImportSchedule{Name: "February 31", Source: "https://example.com/master.csv", Cron: "0 0 31 2 *", Enabled: true}
+	if err := db.Create(&schedule).Error; err != nil {
+		t.Fatalf("seed schedule: %v", err)
+	}
+
+	if _, err := RunDueImports(context.Background(), db, time.Date(2025, time.January, 15, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("run due imports: %v", err)
+	}
+	if err := db.First(&schedule, schedule.ID).Error; err != nil {
+		t.Fatalf("reload schedule: %v", err)
+	}
+	if schedule.Enabled || schedule.NextRunAt != nil {
+		t.Fatalf("expected the schedule to be disabled, got enabled=%t next=%v", schedule.Enabled, schedule.NextRunAt)
+	}
+}
+
+func TestRunImportScheduleLeavesInterruptedRunsDue(t *testing.T) {
+	db := newJobsTestDB(t)
+	if err := db.AutoMigrate(&models.OtherName{}, &models.ImportSchedule{}, &models.ImportRun{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "master.csv")
+	if err := os.WriteFile(path, []byte("Ingredient Name,CAS Number\nHedione,24851-98-7\n"), 0o600); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	now := time.Date(2025, time.January, 15, 10, 0, 0, 0, time.UTC)
+	schedule := models.ImportSchedule{Name: "Master list", Source: path, Cron: "@hourly", Enabled: true, NextRunAt: &now}
+	if err := db.Create(&schedule).Error; err != nil {
+		t.Fatalf("seed schedule: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RunImportSchedule(ctx, db, &schedule, now); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+
+	var runs int64
+	db.Model(&models.ImportRun{}).Count(&runs)
+	if runs != 0 {
+		t.Fatalf("expected no run to be recorded, got %d", runs)
+	}
+	if err := db.First(&schedule, schedule.ID).Error; err != nil {
+		t.Fatalf("reload schedule: %v", err)
+	}
+	if schedule.NextRunAt == nil || !schedule.NextRunAt.Equal(now) {
+		t.Fatalf("expected the schedule to stay due, got %v", schedule.NextRunAt)
+	}
+}
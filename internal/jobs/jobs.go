@@ -0,0 +1,160 @@
+// Package jobs runs periodic background work alongside the HTTP server.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	applog "perfugo/internal/log"
+)
+
+// Job describes a unit of background work executed on a fixed interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler executes registered jobs until it is stopped.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []Job
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+	// running maps the jobs currently executing to when they started.
+	running map[string]time.Time
+}
+
+// NewScheduler builds an empty Scheduler.
+func NewScheduler() *Scheduler {
+	return &Scheduler{running: map[string]time.Time{}}
+}
+
+// Register adds a job; jobs with a non-positive interval are ignored.
+func (s *Scheduler) Register(job Job) {
+	if job.Run == nil || job.Interval <= 0 {
+		applog.Debug(context.Background(), "job disabled", "job", job.Name, "interval", job.Interval.String())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job)
+	applog.Debug(context.Background(), "job registered", "job", job.Name, "interval", job.Interval.String())
+}
+
+// Start launches every registered job. Each job runs once immediately and then on its interval.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		job := job
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+	applog.Info(ctx, "job scheduler started", "jobs", len(s.jobs))
+}
+
+// Stop cancels running jobs and waits for them to return.
+func (s *Scheduler) Stop() {
+	_ = s.Shutdown(context.Background())
+}
+
+// Shutdown cancels running jobs and waits for them to return until ctx ends.
+// Jobs still running at the deadline are logged and left to finish in the
+// background; their work is picked up again on the next start.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	inFlight := s.runningJobs()
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	if len(inFlight) > 0 {
+		applog.Info(ctx, "waiting for running jobs to stop", "jobs", inFlight)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		applog.Debug(ctx, "job scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		unfinished := s.runningJobs()
+		s.mu.Unlock()
+		applog.Error(ctx, "job scheduler shutdown deadline exceeded", "unfinished", unfinished)
+		return ctx.Err()
+	}
+}
+
+// runningJobs describes the in-flight jobs and how long each has been
+// running. Callers must hold s.mu.
+func (s *Scheduler) runningJobs() []string {
+	names := make([]string, 0, len(s.running))
+	for name, started := range s.running {
+		names = append(names, fmt.Sprintf("%s (%s)", name, time.Since(started).Round(time.Millisecond)))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		s.running[job.Name] = time.Now()
+		s.mu.Unlock()
+		runJob(ctx, job)
+		s.mu.Lock()
+		delete(s.running, job.Name)
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runJob(ctx context.Context, job Job) {
+	started := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			applog.Error(ctx, "job panicked", "job", job.Name, "panic", fmt.Sprint(recovered))
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		if ctx.Err() == nil {
+			applog.Error(ctx, "job failed", "job", job.Name, "error", err)
+		} else {
+			applog.Info(context.Background(), "job interrupted by shutdown; it runs again on the next start",
+				"job", job.Name,
+				"duration", time.Since(started).String(),
+			)
+		}
+		return
+	}
+	applog.Debug(ctx, "job completed", "job", job.Name, "duration", time.Since(started).String())
+}
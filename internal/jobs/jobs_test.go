@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerShutdownWaitsForCooperativeJobs(t *testing.T) {
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	scheduler := NewScheduler()
+	scheduler.Register(Job{Name: "cooperative", Interval: time.Hour, Run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	}})
+
+	scheduler.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := scheduler.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("expected the job to have returned before shutdown completed")
+	}
+}
+
+func TestSchedulerShutdownGivesUpAtDeadline(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	scheduler := NewScheduler()
+	scheduler.Register(Job{Name: "stubborn", Interval: time.Hour, Run: func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}})
+
+	scheduler.Start(context.Background())
+	<-started
+	t.Cleanup(func() {
+		close(release)
+		scheduler.Stop()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := scheduler.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be reported, got %v", err)
+	}
+
+	scheduler.mu.Lock()
+	unfinished := scheduler.runningJobs()
+	scheduler.mu.Unlock()
+	if len(unfinished) != 1 {
+		t.Fatalf("expected the stubborn job to still be running, got %v", unfinished)
+	}
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// UsagePopularityJob keeps AromaChemical.UsagePopularity in sync with formula usage.
+func UsagePopularityJob(db *gorm.DB, interval time.Duration) Job {
+	return Job{
+		Name:     "usage-popularity",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := RecomputeUsagePopularity(ctx, db)
+			return err
+		},
+	}
+}
+
+// RecomputeUsagePopularity stores, for every aroma chemical, the number of distinct
+// formulas that reference it directly and the number of users who created them.
+// Only the latest version of each formula counts. It returns the number of
+// chemicals in use.
+func RecomputeUsagePopularity(ctx context.Context, db *gorm.DB) (int, error) {
+	if db == nil {
+		return 0, errors.New("database handle is nil")
+	}
+
+	var counts []struct {
+		AromaChemicalID uint
+		Formulas        int
+		Users           int
+	}
+	if err := db.WithContext(ctx).
+		Model(&models.FormulaIngredient{}).
+		Select("formula_ingredients.aroma_chemical_id, COUNT(DISTINCT formula_ingredients.formula_id) AS formulas, COUNT(DISTINCT formulas.created_by_id) AS users").
+		Joins("JOIN formulas ON formulas.id = formula_ingredients.formula_id AND formulas.deleted_at IS NULL AND formulas.is_latest = ?", true).
+		Where("formula_ingredients.aroma_chemical_id IS NOT NULL").
+		Group("formula_ingredients.aroma_chemical_id").
+		Scan(&counts).Error; err != nil {
+		return 0, err
+	}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AromaChemical{}).
+			Where("usage_popularity <> 0 OR usage_users <> 0").
+			Updates(map[string]any{"usage_popularity": 0, "usage_users": 0}).Error; err != nil {
+			return err
+		}
+		for _, count := range counts {
+			if err := tx.Model(&models.AromaChemical{}).
+				Where("id = ?", count.AromaChemicalID).
+				Updates(map[string]any{"usage_popularity": count.Formulas, "usage_users": count.Users}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	applog.Debug(ctx, "usage popularity recomputed", "chemicalsInUse", len(counts))
+	return len(counts), nil
+}
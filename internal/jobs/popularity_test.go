@@ -0,0 +1,118 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func newJobsTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:jobs-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.AromaChemical{}, &models.Formula{}, &models.FormulaIngredient{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	return db
+}
+
+func TestRecomputeUsagePopularity(t *testing.T) {
+	db := newJobsTestDB(t)
+
+	hedione := models.AromaChemical{IngredientName: "Hedione"}
+	ambroxan := models.AromaChemical{IngredientName: "Ambroxan", UsagePopularity: 7}
+	unused := models.AromaChemical{IngredientName: "Civetone"}
+	for _, chemical := range []*models.AromaChemical{&hedione, &ambroxan, &unused} {
+		if err := db.Create(chemical).Error; err != nil {
+			t.Fatalf("seed chemical: %v", err)
+		}
+	}
+
+	ada, bo := uint(1), uint(2)
+	dawn := models.Formula{Name: "Dawn", Version: 2, CreatedByID: &ada}
+	dusk := models.Formula{Name: "Dusk", CreatedByID: &bo}
+	retired := models.Formula{Name: "Retired", CreatedByID: &ada}
+	draft := models.Formula{Name: "Dawn", Version: 1, CreatedByID: &ada}
+	for _, formula := range []*models.Formula{&dawn, &dusk, &retired, &draft} {
+		if err := db.Create(formula).Error; err != nil {
+			t.Fatalf("seed formula: %v", err)
+		}
+	}
+	if err := db.Model(&draft).Update("is_latest", false).Error; err != nil {
+		t.Fatalf("supersede formula: %v", err)
+	}
+
+	rows := []models.FormulaIngredient{
+		{FormulaID: dawn.ID, AromaChemicalID: &hedione.ID, Amount: 10, Unit: "g"},
+		{FormulaID: dawn.ID, AromaChemicalID: &hedione.ID, Amount: 5, Unit: "g"},
+		{FormulaID: dusk.ID, AromaChemicalID: &hedione.ID, Amount: 2, Unit: "g"},
+		{FormulaID: retired.ID, AromaChemicalID: &ambroxan.ID, Amount: 1, Unit: "g"},
+		{FormulaID: draft.ID, AromaChemicalID: &hedione.ID, Amount: 8, Unit: "g"},
+		{FormulaID: draft.ID, AromaChemicalID: &unused.ID, Amount: 1, Unit: "g"},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatalf("seed formula ingredients: %v", err)
+	}
+	if err := db.Delete(&retired).Error; err != nil {
+		t.Fatalf("delete formula: %v", err)
+	}
+
+	inUse, err := RecomputeUsagePopularity(context.Background(), db)
+	if err != nil {
+		t.Fatalf("recompute: %v", err)
+	}
+	if inUse != 1 {
+		t.Fatalf("expected 1 chemical in use, got %d", inUse)
+	}
+
+	want := map[uint][2]int{hedione.ID: {2, 2}, ambroxan.ID: {0, 0}, unused.ID: {0, 0}}
+	for id, expected := range want {
+		var chemical models.AromaChemical
+		if err := db.First(&chemical, id).Error; err != nil {
+			t.Fatalf("load chemical %d: %v", id, err)
+		}
+		if got := [2]int{chemical.UsagePopularity, chemical.UsageUsers}; got != expected {
+			t.Fatalf("%s: expected formulas and users %v, got %v", chemical.IngredientName, expected, got)
+		}
+	}
+}
+
+func TestSchedulerRunsJobsUntilStopped(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	scheduler := NewScheduler()
+	scheduler.Register(Job{Name: "disabled", Run: func(context.Context) error {
+		t.Error("disabled job should not run")
+		return nil
+	}})
+	scheduler.Register(Job{Name: "panics", Interval: time.Hour, Run: func(context.Context) error {
+		panic("boom")
+	}})
+	scheduler.Register(Job{Name: "tick", Interval: time.Hour, Run: func(context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	}})
+
+	scheduler.Start(context.Background())
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected job to run on start")
+	}
+	scheduler.Stop()
+}
@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// RetentionPolicy says how long each kind of record is kept. A zero
+// duration keeps those records forever.
+type RetentionPolicy struct {
+	// Audit is measured from when an audit entry was recorded.
+	Audit time.Duration
+	// AIExchanges is measured from when a model call was recorded.
+	AIExchanges time.Duration
+	// Deleted is measured from when a record was soft-deleted.
+	Deleted time.Duration
+}
+
+// Enabled reports whether the policy purges anything.
+func (p RetentionPolicy) Enabled() bool {
+	return p.Audit > 0 || p.AIExchanges > 0 || p.Deleted > 0
+}
+
+// purgeableModels lists the soft-deleted tables the retention job empties,
+// children before parents so foreign keys hold. Users, production batches
+// and blind tests are left alone: they back accounts and traceability.
+var purgeableModels = []any{
+	&models.OtherName{},
+	&models.Constituent{},
+	&models.FormulaIngredient{},
+	&models.InventoryItem{},
+	&models.WishlistItem{},
+	&models.Substitution{},
+	&models.UserIngredientNote{},
+	&models.OrganSlot{},
+	&models.Organ{},
+	&models.BatchPreset{},
+	&models.ReportDefinition{},
+	&models.APIToken{},
+	&models.Invitation{},
+	&models.TaxonomyTerm{},
+	&models.ImportRun{},
+	&models.ImportSchedule{},
+	&models.BlockedMaterial{},
+	&models.Formula{},
+	&models.AromaChemical{},
+}
+
+// PurgeReport counts the rows removed by one retention run.
+type PurgeReport struct {
+	AuditEntries int64
+	AIExchanges  int64
+	// Deleted counts the soft-deleted rows purged, by table.
+	Deleted map[string]int64
+}
+
+// Total is the number of rows removed.
+func (r PurgeReport) Total() int64 {
+	total := r.AuditEntries + r.AIExchanges
+	for _, n := range r.Deleted {
+		total += n
+	}
+	return total
+}
+
+// String lists what was purged, eg. "3 audit entries, deleted formulas (12)".
+func (r PurgeReport) String() string {
+	var parts []string
+	if r.AuditEntries > 0 {
+		parts = append(parts, plural(r.AuditEntries, "audit entry", "audit entries"))
+	}
+	if r.AIExchanges > 0 {
+		parts = append(parts, plural(r.AIExchanges, "AI exchange", "AI exchanges"))
+	}
+	tables := make([]string, 0, len(r.Deleted))
+	for table, n := range r.Deleted {
+		if n > 0 {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		parts = append(parts, fmt.Sprintf("deleted %s (%d)", strings.ReplaceAll(table, "_", " "), r.Deleted[table]))
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int64, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// RetentionJob purges the records policy no longer keeps. It is disabled
+// when the policy keeps everything.
+func RetentionJob(db *gorm.DB, interval time.Duration, policy RetentionPolicy) Job {
+	if !policy.Enabled() {
+		interval = 0
+	}
+	return Job{
+		Name:     "retention",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := PurgeExpired(ctx, db, time.Now(), policy)
+			return err
+		},
+	}
+}
+
+// PurgeExpired permanently deletes the audit entries, AI exchanges and
+// soft-deleted records older than policy allows at now, and records what it
+// removed in the audit log. A table that cannot be purged is skipped and
+// reported in the returned error; the rest are still purged.
+func PurgeExpired(ctx context.Context, db *gorm.DB, now time.Time, policy RetentionPolicy) (PurgeReport, error) {
+	report := PurgeReport{Deleted: map[string]int64{}}
+	if db == nil {
+		return report, errors.New("database handle is nil")
+	}
+
+	var errs []error
+	if policy.Audit > 0 {
+		result := db.WithContext(ctx).Unscoped().
+			Where("created_at < ?", now.Add(-policy.Audit)).
+			Delete(&models.AuditEntry{})
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("purge audit entries: %w", result.Error))
+		}
+		report.AuditEntries = result.RowsAffected
+	}
+	if policy.AIExchanges > 0 {
+		result := db.WithContext(ctx).Unscoped().
+			Where("created_at < ?", now.Add(-policy.AIExchanges)).
+			Delete(&models.AIExchange{})
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("purge AI exchanges: %w", result.Error))
+		}
+		report.AIExchanges = result.RowsAffected
+	}
+	if policy.Deleted > 0 {
+		cutoff := now.Add(-policy.Deleted)
+		for _, model := range purgeableModels {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(model); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			result := db.WithContext(ctx).Unscoped().
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Delete(model)
+			if result.Error != nil {
+				applog.Error(ctx, "failed to purge deleted records", "error", result.Error, "table", stmt.Schema.Table)
+				errs = append(errs, fmt.Errorf("purge %s: %w", stmt.Schema.Table, result.Error))
+				continue
+			}
+			if result.RowsAffected > 0 {
+				report.Deleted[stmt.Schema.Table] = result.RowsAffected
+			}
+		}
+	}
+
+	if report.Total() > 0 {
+		entry := models.AuditEntry{
+			Action:     models.AuditRetentionPurged,
+			EntityType: models.AuditEntityRetention,
+			Summary:    "Purged " + report.String() + ".",
+		}
+		if err := db.WithContext(ctx).Create(&entry).Error; err != nil {
+			errs = append(errs, fmt.Errorf("record purge: %w", err))
+		}
+		applog.Info(ctx, "expired records purged", "purged", report.String(), "rows", report.Total())
+	} else {
+		applog.Debug(ctx, "no expired records to purge")
+	}
+	return report, errors.Join(errs...)
+}
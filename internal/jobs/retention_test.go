@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+func TestPurgeExpired(t *testing.T) {
+	db := newJobsTestDB(t)
+	if err := db.AutoMigrate(append([]any{&models.AuditEntry{}, &models.AIExchange{}}, purgeableModels...)...); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	now := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+	audits := []models.AuditEntry{
+		{Model: gorm.Model{CreatedAt: daysAgo(400)}, Action: models.AuditFormulaApproved, EntityType: "formula", EntityID: 1},
+		{Model: gorm.Model{CreatedAt: daysAgo(10)}, Action: models.AuditFormulaApproved, EntityType: "formula", EntityID: 2},
+	}
+	exchanges := []models.AIExchange{
+		{Model: gorm.Model{CreatedAt: daysAgo(40)}, Operation: "profile"},
+		{Model: gorm.Model{CreatedAt: daysAgo(40)}, Operation: "formula"},
+		{Model: gorm.Model{CreatedAt: daysAgo(1)}, Operation: "profile"},
+	}
+	formulas := []models.Formula{
+		{Name: "Deleted long ago", Model: gorm.Model{DeletedAt: gorm.DeletedAt{Time: daysAgo(120), Valid: true}}},
+		{Name: "Deleted recently", Model: gorm.Model{DeletedAt: gorm.DeletedAt{Time: daysAgo(5), Valid: true}}},
+		{Name: "Live"},
+	}
+	for _, rows := range []any{&audits, &exchanges, &formulas} {
+		if err := db.Create(rows).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	policy := RetentionPolicy{Audit: 365 * 24 * time.Hour, AIExchanges: 30 * 24 * time.Hour, Deleted: 90 * 24 * time.Hour}
+	report, err := PurgeExpired(context.Background(), db, now, policy)
+	if err != nil {
+		t.Fatalf("PurgeExpired: %v", err)
+	}
+	if report.AuditEntries != 1 || report.AIExchanges != 2 || report.Deleted["formulas"] != 1 || report.Total() != 4 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if got := report.String(); got != "1 audit entry, 2 AI exchanges, deleted formulas (1)" {
+		t.Fatalf("unexpected summary %q", got)
+	}
+
+	var names []string
+	db.Unscoped().Model(&models.Formula{}).Order("name").Pluck("name", &names)
+	if len(names) != 2 || names[0] != "Deleted recently" || names[1] != "Live" {
+		t.Fatalf("unexpected formulas kept: %v", names)
+	}
+	var purges []models.AuditEntry
+	db.Where("action = ?", models.AuditRetentionPurged).Find(&purges)
+	if len(purges) != 1 || purges[0].Summary != "Purged "+report.String()+"." {
+		t.Fatalf("expected the purge to be audited, got %+v", purges)
+	}
+
+	again, err := PurgeExpired(context.Background(), db, now, policy)
+	if err != nil || again.Total() != 0 {
+		t.Fatalf("expected nothing left to purge, got %+v, %v", again, err)
+	}
+}
+
+func TestRetentionJobDisabledWithoutPolicy(t *testing.T) {
+	if job := RetentionJob(nil, time.Hour, RetentionPolicy{}); job.Interval != 0 {
+		t.Fatalf("expected the job to be disabled, got interval %s", job.Interval)
+	}
+}
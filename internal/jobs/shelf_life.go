@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// ExpiryAlert tells one owner about inventory items that have started to
+// expire. Returning an error leaves the items to be announced on the next run.
+type ExpiryAlert func(ctx context.Context, ownerID uint, items []models.InventoryItem) error
+
+// ShelfLifeJob alerts owners when opened materials near or pass their shelf
+// life.
+func ShelfLifeJob(db *gorm.DB, interval time.Duration, alert ExpiryAlert) Job {
+	return Job{
+		Name:     "shelf-life",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := AlertExpiringInventory(ctx, db, time.Now(), alert)
+			return err
+		},
+	}
+}
+
+// AlertExpiringInventory finds the opened materials that are expiring or
+// expired at now and have not been announced yet, alerts each owner once
+// with all of theirs and marks them announced. It returns how many owners
+// were alerted.
+func AlertExpiringInventory(ctx context.Context, db *gorm.DB, now time.Time, alert ExpiryAlert) (int, error) {
+	if db == nil {
+		return 0, errors.New("database handle is nil")
+	}
+	if alert == nil {
+		return 0, errors.New("expiry alert is nil")
+	}
+
+	var items []models.InventoryItem
+	if err := db.WithContext(ctx).
+		Preload("AromaChemical").
+		Where("shelf_life_months > 0 AND opened_on IS NOT NULL AND expiry_notified_at IS NULL").
+		Find(&items).Error; err != nil {
+		return 0, err
+	}
+
+	byOwner := map[uint][]models.InventoryItem{}
+	for _, item := range items {
+		if item.ExpiryState(now) != models.InventoryFresh {
+			byOwner[item.OwnerID] = append(byOwner[item.OwnerID], item)
+		}
+	}
+	owners := make([]uint, 0, len(byOwner))
+	for ownerID := range byOwner {
+		owners = append(owners, ownerID)
+	}
+	sort.Slice(owners, func(i, j int) bool { return owners[i] < owners[j] })
+
+	alerted := 0
+	for _, ownerID := range owners {
+		if err := ctx.Err(); err != nil {
+			return alerted, err
+		}
+		due := byOwner[ownerID]
+		sort.SliceStable(due, func(i, j int) bool {
+			a, _ := due[i].ExpiresOn()
+			b, _ := due[j].ExpiresOn()
+			return a.Before(b)
+		})
+		if err := alert(ctx, ownerID, due); err != nil {
+			applog.Error(ctx, "failed to send expiry alert", "error", err, "ownerID", ownerID)
+			continue
+		}
+		ids := make([]uint, 0, len(due))
+		for _, item := range due {
+			ids = append(ids, item.ID)
+		}
+		if err := db.WithContext(ctx).Model(&models.InventoryItem{}).
+			Where("id IN ?", ids).
+			Update("expiry_notified_at", now).Error; err != nil {
+			return alerted, err
+		}
+		alerted++
+	}
+
+	applog.Debug(ctx, "expiring inventory checked", "ownersAlerted", alerted)
+	return alerted, nil
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+func TestAlertExpiringInventory(t *testing.T) {
+	db := newJobsTestDB(t)
+	if err := db.AutoMigrate(&models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	now := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	chemical := models.AromaChemical{IngredientName: "Hedione"}
+	if err := db.Create(&chemical).Error; err != nil {
+		t.Fatalf("seed chemical: %v", err)
+	}
+	opened := func(monthsAgo int) *time.Time {
+		date := now.AddDate(0, -monthsAgo, 0)
+		return &date
+	}
+	items := []models.InventoryItem{
+		{OwnerID: 1, AromaChemicalID: chemical.ID, ShelfLifeMonths: 6, OpenedOn: opened(7)},
+		{OwnerID: 1, AromaChemicalID: chemical.ID, ShelfLifeMonths: 12, OpenedOn: opened(2)},
+		{OwnerID: 2, AromaChemicalID: chemical.ID, ShelfLifeMonths: 6, OpenedOn: opened(6)},
+		{OwnerID: 3, AromaChemicalID: chemical.ID, OpenedOn: opened(36)},
+	}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatalf("seed inventory: %v", err)
+	}
+
+	got := map[uint]int{}
+	failOwner := uint(2)
+	alert := func(_ context.Context, ownerID uint, due []models.InventoryItem) error {
+		if ownerID == failOwner {
+			return errors.New("mail server unavailable")
+		}
+		got[ownerID] += len(due)
+		return nil
+	}
+
+	alerted, err := AlertExpiringInventory(context.Background(), db, now, alert)
+	if err != nil {
+		t.Fatalf("AlertExpiringInventory: %v", err)
+	}
+	if alerted != 1 || got[1] != 1 || len(got) != 1 {
+		t.Fatalf("expected owner 1 alerted about one item, got %d alerts: %v", alerted, got)
+	}
+
+	// Announced items stay quiet; the failed owner is retried.
+	failOwner = 0
+	alerted, err = AlertExpiringInventory(context.Background(), db, now.Add(time.Hour), alert)
+	if err != nil {
+		t.Fatalf("AlertExpiringInventory retry: %v", err)
+	}
+	if alerted != 1 || got[1] != 1 || got[2] != 1 {
+		t.Fatalf("expected only owner 2 on the retry, got %d alerts: %v", alerted, got)
+	}
+}
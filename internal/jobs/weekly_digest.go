@@ -0,0 +1,173 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/models"
+)
+
+// DigestPeriod is how often an opted-in user receives the weekly digest.
+const DigestPeriod = 7 * 24 * time.Hour
+
+// WeeklyDigest summarises one user's week from Since to Until.
+type WeeklyDigest struct {
+	User  models.User
+	Since time.Time
+	Until time.Time
+	// Formulas are the latest versions of the formulas the user edited or
+	// acted on.
+	Formulas []models.Formula
+	// Batches are the production batches the user started.
+	Batches []models.ProductionBatch
+	// LowStock lists the user's inventory below models.LowStockMg.
+	LowStock []models.InventoryItem
+	// Checks are the maceration checks due from Since until a period after
+	// Until, soonest first.
+	Checks []DigestCheck
+}
+
+// DigestCheck is a maceration check due on one of the user's batches.
+type DigestCheck struct {
+	Batch      models.ProductionBatch
+	Checkpoint models.BatchCheckpoint
+}
+
+// Empty reports whether the digest has nothing to tell.
+func (d WeeklyDigest) Empty() bool {
+	return len(d.Formulas) == 0 && len(d.Batches) == 0 && len(d.LowStock) == 0 && len(d.Checks) == 0
+}
+
+// DigestSender delivers a digest. Returning an error leaves the user due, so
+// the digest is retried on the next run.
+type DigestSender func(ctx context.Context, digest WeeklyDigest) error
+
+// WeeklyDigestJob sends the weekly digest to opted-in users as they fall due.
+func WeeklyDigestJob(db *gorm.DB, interval time.Duration, send DigestSender) Job {
+	return Job{
+		Name:     "weekly-digest",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := SendWeeklyDigests(ctx, db, time.Now(), send)
+			return err
+		},
+	}
+}
+
+// SendWeeklyDigests builds and sends the digest of every active, opted-in
+// user whose last digest is at least a DigestPeriod old at now, then records
+// now as their last digest. Users with a quiet week are skipped without a
+// message. It returns how many digests were sent.
+func SendWeeklyDigests(ctx context.Context, db *gorm.DB, now time.Time, send DigestSender) (int, error) {
+	if db == nil {
+		return 0, errors.New("database handle is nil")
+	}
+	if send == nil {
+		return 0, errors.New("digest sender is nil")
+	}
+
+	var users []models.User
+	if err := db.WithContext(ctx).
+		Where("weekly_digest = ? AND deactivated_at IS NULL", true).
+		Where("digest_sent_at IS NULL OR digest_sent_at <= ?", now.Add(-DigestPeriod)).
+		Order("id asc").
+		Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		since := now.Add(-DigestPeriod)
+		if user.DigestSentAt != nil && user.DigestSentAt.After(since) {
+			since = *user.DigestSentAt
+		}
+		digest, err := BuildWeeklyDigest(ctx, db, user, since, now)
+		if err != nil {
+			return sent, err
+		}
+		if !digest.Empty() {
+			if err := send(ctx, digest); err != nil {
+				applog.Error(ctx, "failed to send weekly digest", "error", err, "userID", user.ID)
+				continue
+			}
+			sent++
+		}
+		if err := db.WithContext(ctx).Model(&models.User{}).
+			Where("id = ?", user.ID).
+			Update("digest_sent_at", now).Error; err != nil {
+			return sent, err
+		}
+	}
+
+	applog.Debug(ctx, "weekly digests checked", "due", len(users), "sent", sent)
+	return sent, nil
+}
+
+// BuildWeeklyDigest assembles user's digest for the week from since to
+// until out of the audit log, their formulas, batches and inventory.
+func BuildWeeklyDigest(ctx context.Context, db *gorm.DB, user models.User, since, until time.Time) (WeeklyDigest, error) {
+	digest := WeeklyDigest{User: user, Since: since, Until: until}
+
+	audited := db.WithContext(ctx).Model(&models.AuditEntry{}).
+		Select("entity_id").
+		Where("actor_id = ? AND entity_type = ? AND created_at >= ?", user.ID, models.ActivityEntityFormula, since)
+	if err := db.WithContext(ctx).
+		Where("is_latest = ?", true).
+		Where("(created_by_id = ? AND updated_at >= ?) OR id IN (?)", user.ID, since, audited).
+		Order("name asc").Order("id asc").
+		Find(&digest.Formulas).Error; err != nil {
+		return digest, err
+	}
+
+	if err := db.WithContext(ctx).
+		Where("owner_id = ? AND created_at >= ?", user.ID, since).
+		Order("created_at asc").
+		Find(&digest.Batches).Error; err != nil {
+		return digest, err
+	}
+
+	var items []models.InventoryItem
+	if err := db.WithContext(ctx).
+		Preload("AromaChemical").
+		Where("owner_id = ?", user.ID).
+		Order("id asc").
+		Find(&items).Error; err != nil {
+		return digest, err
+	}
+	for _, item := range items {
+		if item.LowStock() {
+			digest.LowStock = append(digest.LowStock, item)
+		}
+	}
+
+	// Maceration ends by its last checkpoint, so older batches have none due.
+	horizon := until.Add(DigestPeriod)
+	lastCheck := slices.Max(models.MacerationCheckpointDays)
+	var batches []models.ProductionBatch
+	if err := db.WithContext(ctx).
+		Where("owner_id = ? AND status = ? AND finalized_at >= ?", user.ID, models.ProductionBatchFinalized, since.AddDate(0, 0, -lastCheck)).
+		Find(&batches).Error; err != nil {
+		return digest, err
+	}
+	for _, batch := range batches {
+		for _, checkpoint := range batch.Checkpoints() {
+			if checkpoint.Kind != models.CheckpointMaceration || checkpoint.Due.Before(since) || !checkpoint.Due.Before(horizon) {
+				continue
+			}
+			digest.Checks = append(digest.Checks, DigestCheck{Batch: batch, Checkpoint: checkpoint})
+		}
+	}
+	sort.SliceStable(digest.Checks, func(i, j int) bool {
+		return digest.Checks[i].Checkpoint.Due.Before(digest.Checks[j].Checkpoint.Due)
+	})
+	return digest, nil
+}
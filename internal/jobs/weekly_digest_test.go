@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+func TestSendWeeklyDigests(t *testing.T) {
+	db := newJobsTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.AuditEntry{}, &models.ProductionBatch{}, &models.InventoryItem{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	now := time.Now()
+	lastWeek := now.Add(-DigestPeriod - time.Hour)
+	subscriber := models.User{Email: "ada@example.com", PasswordHash: "x", WeeklyDigest: true}
+	quiet := models.User{Email: "quiet@example.com", PasswordHash: "x", WeeklyDigest: true}
+	recent := models.User{Email: "recent@example.com", PasswordHash: "x", WeeklyDigest: true, DigestSentAt: &now}
+	optedOut := models.User{Email: "out@example.com", PasswordHash: "x"}
+	for _, user := range []*models.User{&subscriber, &quiet, &recent, &optedOut} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("seed user: %v", err)
+		}
+	}
+
+	edited := models.Formula{Name: "Chypre", CreatedByID: &subscriber.ID, IsLatest: true}
+	stale := models.Formula{Name: "Old fougère", CreatedByID: &subscriber.ID, IsLatest: true}
+	approved := models.Formula{Name: "Colleague's cologne", IsLatest: true}
+	for _, formula := range []*models.Formula{&edited, &stale, &approved} {
+		db.Create(formula)
+	}
+	db.Model(&stale).UpdateColumn("updated_at", lastWeek)
+	db.Create(&models.AuditEntry{ActorID: &subscriber.ID, Action: models.AuditFormulaApproved, EntityType: models.ActivityEntityFormula, EntityID: approved.ID})
+
+	finalized := now.AddDate(0, 0, -10)
+	db.Create(&models.ProductionBatch{FormulaID: edited.ID, FormulaName: "Chypre", OwnerID: subscriber.ID, LotNumber: "L-7", Status: models.ProductionBatchFinalized, FinalizedAt: &finalized})
+	chemical := models.AromaChemical{IngredientName: "Hedione"}
+	db.Create(&chemical)
+	db.Create(&models.InventoryItem{OwnerID: subscriber.ID, AromaChemicalID: chemical.ID, QuantityMg: 5000, DilutionPercent: 10})
+	db.Create(&models.InventoryItem{OwnerID: subscriber.ID, AromaChemicalID: chemical.ID, QuantityMg: 25000})
+
+	var got []WeeklyDigest
+	send := func(_ context.Context, digest WeeklyDigest) error {
+		got = append(got, digest)
+		return nil
+	}
+	sent, err := SendWeeklyDigests(context.Background(), db, now, send)
+	if err != nil {
+		t.Fatalf("SendWeeklyDigests: %v", err)
+	}
+	if sent != 1 || len(got) != 1 || got[0].User.ID != subscriber.ID {
+		t.Fatalf("expected one digest for the subscriber, got %d: %+v", sent, got)
+	}
+	digest := got[0]
+	if len(digest.Formulas) != 2 || digest.Formulas[0].Name != "Chypre" || digest.Formulas[1].Name != "Colleague's cologne" {
+		t.Fatalf("unexpected formulas: %+v", digest.Formulas)
+	}
+	if len(digest.Batches) != 1 || len(digest.LowStock) != 1 || digest.LowStock[0].DilutionPercent != 10 {
+		t.Fatalf("unexpected batches or stock: %d batches, %+v", len(digest.Batches), digest.LowStock)
+	}
+	// Finalized ten days ago: the day 7 check fell in the past week and the
+	// day 14 check falls in the coming one.
+	if len(digest.Checks) != 2 || digest.Checks[0].Checkpoint.Day != 7 || digest.Checks[1].Checkpoint.Day != 14 {
+		t.Fatalf("unexpected checks: %+v", digest.Checks)
+	}
+
+	var reloaded models.User
+	db.First(&reloaded, quiet.ID)
+	if reloaded.DigestSentAt == nil {
+		t.Fatal("expected a quiet week to be recorded so the user is not checked again")
+	}
+
+	// Everyone is up to date now; a failing sender changes nothing.
+	sent, err = SendWeeklyDigests(context.Background(), db, now.Add(time.Hour), func(context.Context, WeeklyDigest) error {
+		return errors.New("mail server unavailable")
+	})
+	if err != nil || sent != 0 {
+		t.Fatalf("expected nothing due, got %d (%v)", sent, err)
+	}
+}
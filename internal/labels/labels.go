@@ -0,0 +1,177 @@
+// Package labels writes batch and bottle labels in the command languages of
+// thermal label printers: ZPL for Zebra printers and EPL for older Zebra
+// and Eltron models. Labels are laid out for 2 x 1 inch stock at 203 dpi.
+package labels
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Supported printer languages.
+const (
+	LanguageZPL = "zpl"
+	LanguageEPL = "epl"
+)
+
+// Label dimensions and layout, in printer dots at 203 dpi.
+const (
+	Width       = 406
+	Height      = 203
+	marginX     = 16
+	titleTop    = 12
+	titleSize   = 30
+	lineTop     = 48
+	lineSize    = 20
+	lineGap     = 24
+	barcodeTop  = 118
+	barcodeSize = 50
+	// maxLines is how many text lines fit between the title and barcode.
+	maxLines = 3
+	// maxRunes keeps text within the label width at the line font size.
+	maxRunes = 36
+)
+
+// MaxCopies caps the copies of each label sent in one job.
+const MaxCopies = 500
+
+// Label is the content of one label. Lines beyond what fits are dropped and
+// an empty Barcode leaves the barcode off.
+type Label struct {
+	Title   string
+	Lines   []string
+	Barcode string
+}
+
+// ValidLanguage reports whether language is a supported printer language.
+func ValidLanguage(language string) bool {
+	return language == LanguageZPL || language == LanguageEPL
+}
+
+// ContentType is the media type of a job in language.
+func ContentType(language string) string {
+	if language == LanguageEPL {
+		return "application/vnd.eltron-epl"
+	}
+	return "application/vnd.zebra-zpl"
+}
+
+// Render writes the labels to w in language, each printed copies times.
+func Render(w io.Writer, language string, labels []Label, copies int) error {
+	copies = min(max(copies, 1), MaxCopies)
+	for _, label := range labels {
+		var err error
+		switch language {
+		case LanguageZPL:
+			err = renderZPL(w, label, copies)
+		case LanguageEPL:
+			err = renderEPL(w, label, copies)
+		default:
+			return fmt.Errorf("labels: unsupported language %q", language)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderZPL(w io.Writer, label Label, copies int) error {
+	var b strings.Builder
+	b.WriteString("^XA\n^CI28\n")
+	fmt.Fprintf(&b, "^PW%d\n^LL%d\n", Width, Height)
+	fmt.Fprintf(&b, "^FO%d,%d^A0N,%d,%d^FH^FD%s^FS\n", marginX, titleTop, titleSize, titleSize, zplField(fit(label.Title, maxRunes*2/3)))
+	for i, line := range fitLines(label.Lines) {
+		fmt.Fprintf(&b, "^FO%d,%d^A0N,%d,%d^FH^FD%s^FS\n", marginX, lineTop+i*lineGap, lineSize, lineSize, zplField(line))
+	}
+	if barcode := barcodeData(label.Barcode); barcode != "" {
+		fmt.Fprintf(&b, "^FO%d,%d^BY2^BCN,%d,N,N,N^FD%s^FS\n", marginX, barcodeTop, barcodeSize, barcode)
+	}
+	fmt.Fprintf(&b, "^PQ%d\n^XZ\n", copies)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func renderEPL(w io.Writer, label Label, copies int) error {
+	var b strings.Builder
+	// The leading blank line ends any command a previous job left open.
+	b.WriteString("\nN\n")
+	fmt.Fprintf(&b, "q%d\nQ%d,24\n", Width, Height)
+	fmt.Fprintf(&b, "A%d,%d,0,4,1,1,N,%s\n", marginX, titleTop, eplString(fit(label.Title, maxRunes*2/3)))
+	for i, line := range fitLines(label.Lines) {
+		fmt.Fprintf(&b, "A%d,%d,0,3,1,1,N,%s\n", marginX, lineTop+i*lineGap, eplString(line))
+	}
+	if barcode := barcodeData(label.Barcode); barcode != "" {
+		fmt.Fprintf(&b, "B%d,%d,0,1,2,4,%d,N,%s\n", marginX, barcodeTop, barcodeSize, eplString(barcode))
+	}
+	fmt.Fprintf(&b, "P%d\n", copies)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func fitLines(lines []string) []string {
+	fitted := make([]string, 0, maxLines)
+	for _, line := range lines {
+		if line = fit(line, maxRunes); line != "" && len(fitted) < maxLines {
+			fitted = append(fitted, line)
+		}
+	}
+	return fitted
+}
+
+// fit collapses whitespace and shortens text to limit runes.
+func fit(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+// barcodeData keeps the printable ASCII of value, which Code 128 can encode.
+func barcodeData(value string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(value) {
+		if r >= ' ' && r <= '~' && r != '^' && r != '~' && r != '"' && r != '\\' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// zplField hex-escapes the characters ^FH treats specially, and anything
+// outside printable ASCII as its UTF-8 bytes for ^CI28.
+func zplField(text string) string {
+	var b strings.Builder
+	for _, c := range []byte(text) {
+		if c < ' ' || c > '~' || c == '^' || c == '~' || c == '_' {
+			fmt.Fprintf(&b, "_%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// eplString quotes text for an EPL command. EPL fonts are single-byte, so
+// characters outside printable ASCII are replaced.
+func eplString(text string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range text {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= ' ' && r <= '~':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package labels
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestRenderZPL(t *testing.T) {
+	var out strings.Builder
+	label := Label{Title: "Nuit d'Été", Lines: []string{"Lot PERF-20250101-001", "50_ml ^ bottle", "", "four", "five"}, Barcode: "PERF-20250101-001"}
+	if err := Render(&out, LanguageZPL, []Label{label}, 3); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"^XA\n^CI28\n^PW406\n^LL203\n",
+		"^FDNuit d'_C3_89t_C3_A9^FS",
+		"^FD50_5Fml _5E bottle^FS",
+		"^BCN,50,N,N,N^FDPERF-20250101-001^FS",
+		"^PQ3\n^XZ\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("ZPL missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "five") || strings.Count(got, "^A0N,20,20") != 3 {
+		t.Fatalf("expected three text lines:\n%s", got)
+	}
+}
+
+func TestRenderEPL(t *testing.T) {
+	var out strings.Builder
+	label := Label{Title: `Say "Été"`, Lines: []string{`C:\lab`}}
+	if err := Render(&out, LanguageEPL, []Label{label, label}, 0); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, `A16,12,0,4,1,1,N,"Say \"?t?\""`) || !strings.Contains(got, `N,"C:\\lab"`) {
+		t.Fatalf("EPL text not escaped:\n%s", got)
+	}
+	if strings.Count(got, "\nP1\n") != 2 || strings.Contains(got, "\nB") {
+		t.Fatalf("expected two single-copy labels without barcodes:\n%s", got)
+	}
+	if err := Render(&out, "pdf", []Label{label}, 1); err == nil {
+		t.Fatal("expected an unsupported language error")
+	}
+}
+
+func TestPrinterSendsJob(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(bufio.NewReader(conn))
+		received <- string(data)
+	}()
+
+	printer, err := NewPrinter(listener.Addr().String(), "")
+	if err != nil {
+		t.Fatalf("NewPrinter() error = %v", err)
+	}
+	if printer.Language() != LanguageZPL {
+		t.Fatalf("default language = %q", printer.Language())
+	}
+	if err := printer.Print(context.Background(), []Label{{Title: "Nuit", Barcode: "LOT-1"}}, 2); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	if job := <-received; !strings.HasPrefix(job, "^XA") || !strings.Contains(job, "^PQ2") {
+		t.Fatalf("unexpected job: %q", job)
+	}
+
+	if _, err := NewPrinter("printer.local", "ipl"); err == nil {
+		t.Fatal("expected an unsupported language error")
+	}
+	if printer, err := NewPrinter("printer.local", "EPL"); err != nil || printer.addr != "printer.local:9100" {
+		t.Fatalf("expected the default port, got %+v, %v", printer, err)
+	}
+}
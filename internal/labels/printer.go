@@ -0,0 +1,71 @@
+package labels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultPort is the raw printing port of network label printers.
+const DefaultPort = "9100"
+
+const defaultPrintTimeout = 10 * time.Second
+
+// Printer sends jobs to a network label printer over raw TCP.
+type Printer struct {
+	addr     string
+	language string
+	timeout  time.Duration
+}
+
+// NewPrinter builds a Printer for the printer at addr, a host with an
+// optional port, which prints jobs written in language.
+func NewPrinter(addr, language string) (*Printer, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return nil, errors.New("labels: printer address is required")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultPort)
+	}
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		language = LanguageZPL
+	}
+	if !ValidLanguage(language) {
+		return nil, fmt.Errorf("labels: unsupported printer language %q", language)
+	}
+	return &Printer{addr: addr, language: language, timeout: defaultPrintTimeout}, nil
+}
+
+// Language is the command language the printer expects.
+func (p *Printer) Language() string {
+	return p.language
+}
+
+// Print renders the labels in the printer's language and sends them.
+func (p *Printer) Print(ctx context.Context, labels []Label, copies int) error {
+	var job strings.Builder
+	if err := Render(&job, p.language, labels, copies); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("labels: connect to printer: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetWriteDeadline(deadline)
+	}
+	if _, err := conn.Write([]byte(job.String())); err != nil {
+		return fmt.Errorf("labels: send job: %w", err)
+	}
+	return nil
+}
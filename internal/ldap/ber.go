@@ -0,0 +1,185 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER tags used by the LDAPv3 messages this package exchanges.
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	tagBindRequest       = 0x60
+	tagBindResponse      = 0x61
+	tagUnbindRequest     = 0x42
+	tagSearchRequest     = 0x63
+	tagSearchResultEntry = 0x64
+	tagSearchResultDone  = 0x65
+	tagSearchResultRef   = 0x73
+	tagSimpleAuth        = 0x80
+
+	tagFilterAnd      = 0xa0
+	tagFilterOr       = 0xa1
+	tagFilterNot      = 0xa2
+	tagFilterEquality = 0xa3
+	tagFilterPresent  = 0x87
+)
+
+const maxPacketSize = 1 << 20
+
+// packet is a decoded BER element. Constructed elements expose their children.
+type packet struct {
+	Tag      byte
+	Value    []byte
+	Children []packet
+}
+
+func encode(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	out = append(out, encodeLength(len(value))...)
+	return append(out, value...)
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var buf []byte
+	for n > 0 {
+		buf = append([]byte{byte(n)}, buf...)
+		n >>= 8
+	}
+	return append([]byte{0x80 | byte(len(buf))}, buf...)
+}
+
+func constructed(tag byte, children ...[]byte) []byte {
+	var value []byte
+	for _, child := range children {
+		value = append(value, child...)
+	}
+	return encode(tag, value)
+}
+
+func encodeInt(tag byte, n int) []byte {
+	if n == 0 {
+		return encode(tag, []byte{0})
+	}
+	var buf []byte
+	for v := n; v > 0; v >>= 8 {
+		buf = append([]byte{byte(v)}, buf...)
+	}
+	if buf[0]&0x80 != 0 {
+		buf = append([]byte{0}, buf...)
+	}
+	return encode(tag, buf)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeBool(b bool) []byte {
+	if b {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0x00})
+}
+
+// readPacket reads a single BER element from the stream.
+func readPacket(r *bufio.Reader) (packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length := int(first)
+	if first&0x80 != 0 {
+		count := int(first & 0x7f)
+		if count == 0 || count > 4 {
+			return packet{}, errors.New("ldap: unsupported BER length encoding")
+		}
+		length = 0
+		for i := 0; i < count; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return packet{}, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxPacketSize {
+		return packet{}, fmt.Errorf("ldap: packet of %d bytes exceeds limit", length)
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return packet{}, err
+	}
+	return decodeValue(tag, value)
+}
+
+func decodePackets(data []byte) ([]packet, error) {
+	var packets []packet
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errors.New("ldap: truncated BER element")
+		}
+		tag := data[0]
+		length := int(data[1])
+		offset := 2
+		if data[1]&0x80 != 0 {
+			count := int(data[1] & 0x7f)
+			if count == 0 || count > 4 || len(data) < 2+count {
+				return nil, errors.New("ldap: invalid BER length")
+			}
+			length = 0
+			for _, b := range data[2 : 2+count] {
+				length = length<<8 | int(b)
+			}
+			offset += count
+		}
+		if len(data) < offset+length {
+			return nil, errors.New("ldap: truncated BER value")
+		}
+		p, err := decodeValue(tag, data[offset:offset+length])
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, p)
+		data = data[offset+length:]
+	}
+	return packets, nil
+}
+
+func decodeValue(tag byte, value []byte) (packet, error) {
+	p := packet{Tag: tag, Value: value}
+	if tag&0x20 != 0 {
+		children, err := decodePackets(value)
+		if err != nil {
+			return packet{}, err
+		}
+		p.Children = children
+	}
+	return p, nil
+}
+
+func (p packet) int() int {
+	n := 0
+	for _, b := range p.Value {
+		n = n<<8 | int(b)
+	}
+	return n
+}
+
+func (p packet) string() string {
+	return string(p.Value)
+}
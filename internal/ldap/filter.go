@@ -0,0 +1,115 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EscapeFilter escapes a value for safe inclusion in a search filter (RFC 4515).
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter encodes the subset of RFC 4515 filters used for user lookups:
+// and, or, not, equality and presence.
+func compileFilter(filter string) ([]byte, error) {
+	encoded, rest, err := parseFilter(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("ldap: unexpected trailing filter text %q", rest)
+	}
+	return encoded, nil
+}
+
+func parseFilter(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", errors.New("ldap: filter must start with '('")
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", errors.New("ldap: truncated filter")
+	}
+
+	switch s[0] {
+	case '&', '|':
+		tag := byte(tagFilterAnd)
+		if s[0] == '|' {
+			tag = tagFilterOr
+		}
+		s = s[1:]
+		var children [][]byte
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			children = append(children, child)
+			s = rest
+		}
+		if !strings.HasPrefix(s, ")") || len(children) == 0 {
+			return nil, "", errors.New("ldap: malformed filter set")
+		}
+		return constructed(tag, children...), s[1:], nil
+	case '!':
+		child, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", errors.New("ldap: malformed not filter")
+		}
+		return constructed(tagFilterNot, child), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", errors.New("ldap: unterminated filter")
+	}
+	attr, value, ok := strings.Cut(s[:end], "=")
+	if !ok || attr == "" {
+		return nil, "", fmt.Errorf("ldap: unsupported filter item %q", s[:end])
+	}
+	if value == "*" {
+		return encodeString(tagFilterPresent, attr), s[end+1:], nil
+	}
+	if strings.Contains(value, "*") {
+		return nil, "", fmt.Errorf("ldap: substring filters are not supported: %q", s[:end])
+	}
+	unescaped, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, "", err
+	}
+	return constructed(tagFilterEquality, encodeString(tagOctetString, attr), encodeString(tagOctetString, unescaped)), s[end+1:], nil
+}
+
+func unescapeFilterValue(value string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+2 >= len(value) {
+			return "", errors.New("ldap: invalid escape in filter")
+		}
+		var c byte
+		if _, err := fmt.Sscanf(value[i+1:i+3], "%02x", &c); err != nil {
+			return "", errors.New("ldap: invalid escape in filter")
+		}
+		b.WriteByte(c)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,333 @@
+// Package ldap implements the small slice of LDAPv3 needed to authenticate
+// users against an on-prem directory such as OpenLDAP or Active Directory:
+// simple binds and a single-entry subtree search.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCredentials reports that the directory rejected the username or
+// password, or that no unique entry matched the username.
+var ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+
+const resultInvalidCredentials = 49
+
+// Config describes how to reach the directory and how to map entries to users.
+type Config struct {
+	URL          string
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// UserFilter is an RFC 4515 filter with a single %s placeholder for the
+	// escaped username, e.g. "(mail=%s)" or "(sAMAccountName=%s)".
+	UserFilter     string
+	EmailAttribute string
+	NameAttribute  string
+	Timeout        time.Duration
+	TLSConfig      *tls.Config
+}
+
+// Identity is the user profile resolved from a successful directory login.
+type Identity struct {
+	DN    string
+	Email string
+	Name  string
+}
+
+// Authenticator validates credentials by binding as the matching directory entry.
+type Authenticator struct {
+	cfg     Config
+	network string
+	address string
+	useTLS  bool
+}
+
+// NewAuthenticator validates the configuration and applies attribute defaults.
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	u, err := url.Parse(strings.TrimSpace(cfg.URL))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("ldap: invalid url %q", cfg.URL)
+	}
+	a := &Authenticator{cfg: cfg, network: "tcp", address: u.Host}
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			a.address = net.JoinHostPort(u.Hostname(), "389")
+		}
+	case "ldaps":
+		a.useTLS = true
+		if u.Port() == "" {
+			a.address = net.JoinHostPort(u.Hostname(), "636")
+		}
+	default:
+		return nil, fmt.Errorf("ldap: unsupported url scheme %q", u.Scheme)
+	}
+	if strings.TrimSpace(cfg.BaseDN) == "" {
+		return nil, errors.New("ldap: base DN is required")
+	}
+	if a.cfg.UserFilter == "" {
+		a.cfg.UserFilter = "(mail=%s)"
+	}
+	if strings.Count(a.cfg.UserFilter, "%s") != 1 {
+		return nil, errors.New("ldap: user filter must contain exactly one %s placeholder")
+	}
+	if _, err := compileFilter(fmt.Sprintf(a.cfg.UserFilter, "probe")); err != nil {
+		return nil, err
+	}
+	if a.cfg.EmailAttribute == "" {
+		a.cfg.EmailAttribute = "mail"
+	}
+	if a.cfg.NameAttribute == "" {
+		a.cfg.NameAttribute = "displayName"
+	}
+	if a.cfg.Timeout <= 0 {
+		a.cfg.Timeout = 10 * time.Second
+	}
+	return a, nil
+}
+
+// Authenticate looks up the entry matching username using the service
+// account, then binds as that entry with password to verify it.
+func (a *Authenticator) Authenticate(ctx context.Context, username, password string) (Identity, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || password == "" {
+		// An empty password would be an unauthenticated bind, which most
+		// directories accept; never treat it as a successful login.
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	conn, err := a.dial(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	defer conn.close()
+
+	if a.cfg.BindDN != "" {
+		if err := conn.bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return Identity{}, fmt.Errorf("ldap: service bind: %w", err)
+		}
+	}
+
+	filter := fmt.Sprintf(a.cfg.UserFilter, EscapeFilter(username))
+	entries, err := conn.search(a.cfg.BaseDN, filter, []string{a.cfg.EmailAttribute, a.cfg.NameAttribute})
+	if err != nil {
+		return Identity{}, err
+	}
+	if len(entries) != 1 {
+		return Identity{}, ErrInvalidCredentials
+	}
+	entry := entries[0]
+
+	if err := conn.bind(entry.dn, password); err != nil {
+		return Identity{}, err
+	}
+
+	identity := Identity{
+		DN:    entry.dn,
+		Email: strings.ToLower(strings.TrimSpace(entry.first(a.cfg.EmailAttribute))),
+		Name:  strings.TrimSpace(entry.first(a.cfg.NameAttribute)),
+	}
+	if identity.Email == "" {
+		return Identity{}, fmt.Errorf("ldap: entry %s has no %s attribute", entry.dn, a.cfg.EmailAttribute)
+	}
+	return identity, nil
+}
+
+type connection struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	deadline time.Time
+	nextID   int
+}
+
+type entry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+func (e entry) first(name string) string {
+	for key, values := range e.attributes {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func (a *Authenticator) dial(ctx context.Context) (*connection, error) {
+	dialer := &net.Dialer{Timeout: a.cfg.Timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if a.useTLS {
+		tlsConfig := a.cfg.TLSConfig
+		if tlsConfig == nil {
+			host, _, _ := net.SplitHostPort(a.address)
+			tlsConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, a.network, a.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, a.network, a.address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap: connect: %w", err)
+	}
+
+	deadline := time.Now().Add(a.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	return &connection{conn: conn, reader: bufio.NewReader(conn), deadline: deadline}, nil
+}
+
+func (c *connection) close() {
+	c.nextID++
+	_, _ = c.conn.Write(constructed(tagSequence, encodeInt(tagInteger, c.nextID), encode(tagUnbindRequest, nil)))
+	_ = c.conn.Close()
+}
+
+func (c *connection) send(op []byte) (int, error) {
+	c.nextID++
+	if _, err := c.conn.Write(constructed(tagSequence, encodeInt(tagInteger, c.nextID), op)); err != nil {
+		return 0, fmt.Errorf("ldap: write: %w", err)
+	}
+	return c.nextID, nil
+}
+
+// receive reads the next message for id and returns its protocol operation.
+func (c *connection) receive(id int) (packet, error) {
+	for {
+		msg, err := readPacket(c.reader)
+		if err != nil {
+			return packet{}, fmt.Errorf("ldap: read: %w", err)
+		}
+		if msg.Tag != tagSequence || len(msg.Children) < 2 {
+			return packet{}, errors.New("ldap: malformed response")
+		}
+		if msg.Children[0].int() != id {
+			continue
+		}
+		return msg.Children[1], nil
+	}
+}
+
+func (c *connection) bind(dn, password string) error {
+	id, err := c.send(constructed(tagBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(tagSimpleAuth, password),
+	))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.Tag != tagBindResponse {
+		return fmt.Errorf("ldap: unexpected bind response tag 0x%02x", op.Tag)
+	}
+	return resultError(op)
+}
+
+func (c *connection) search(baseDN, filter string, attributes []string) ([]entry, error) {
+	encodedFilter, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make([][]byte, 0, len(attributes))
+	for _, attr := range attributes {
+		attrs = append(attrs, encodeString(tagOctetString, attr))
+	}
+	id, err := c.send(constructed(tagSearchRequest,
+		encodeString(tagOctetString, baseDN),
+		encodeInt(tagEnumerated, 2), // wholeSubtree
+		encodeInt(tagEnumerated, 0), // neverDerefAliases
+		encodeInt(tagInteger, 2),    // two results are enough to detect ambiguity
+		encodeInt(tagInteger, c.timeoutSeconds()),
+		encodeBool(false),
+		encodedFilter,
+		constructed(tagSequence, attrs...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Tag {
+		case tagSearchResultEntry:
+			entries = append(entries, parseEntry(op))
+		case tagSearchResultRef:
+			// Referrals are not followed.
+		case tagSearchResultDone:
+			if err := resultError(op); err != nil {
+				// sizeLimitExceeded still tells us the filter is ambiguous.
+				if len(op.Children) > 0 && op.Children[0].int() == 4 {
+					return entries, nil
+				}
+				return nil, err
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("ldap: unexpected search response tag 0x%02x", op.Tag)
+		}
+	}
+}
+
+func (c *connection) timeoutSeconds() int {
+	seconds := int(time.Until(c.deadline).Seconds())
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+func parseEntry(op packet) entry {
+	e := entry{attributes: map[string][]string{}}
+	if len(op.Children) > 0 {
+		e.dn = op.Children[0].string()
+	}
+	if len(op.Children) > 1 {
+		for _, attr := range op.Children[1].Children {
+			if len(attr.Children) < 2 {
+				continue
+			}
+			name := attr.Children[0].string()
+			for _, value := range attr.Children[1].Children {
+				e.attributes[name] = append(e.attributes[name], value.string())
+			}
+		}
+	}
+	return e
+}
+
+func resultError(op packet) error {
+	if len(op.Children) < 3 {
+		return errors.New("ldap: malformed result")
+	}
+	code := op.Children[0].int()
+	switch code {
+	case 0:
+		return nil
+	case resultInvalidCredentials:
+		return ErrInvalidCredentials
+	default:
+		return fmt.Errorf("ldap: result code %d: %s", code, op.Children[2].string())
+	}
+}
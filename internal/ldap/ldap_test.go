@@ -0,0 +1,200 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+type fakeEntry struct {
+	dn       string
+	password string
+	attrs    map[string]string
+}
+
+// serveFakeDirectory answers binds and equality searches from entries.
+func serveFakeDirectory(t *testing.T, entries []fakeEntry) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handleFakeConn(conn, entries)
+		}
+	}()
+	return "ldap://" + listener.Addr().String()
+}
+
+func handleFakeConn(conn net.Conn, entries []fakeEntry) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(id int, op []byte) {
+		_, _ = conn.Write(constructed(tagSequence, encodeInt(tagInteger, id), op))
+	}
+	result := func(tag byte, code int) []byte {
+		return constructed(tag, encodeInt(tagEnumerated, code), encodeString(tagOctetString, ""), encodeString(tagOctetString, ""))
+	}
+
+	for {
+		msg, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		id := msg.Children[0].int()
+		op := msg.Children[1]
+		switch op.Tag {
+		case tagUnbindRequest:
+			return
+		case tagBindRequest:
+			dn, password := op.Children[1].string(), op.Children[2].string()
+			code := resultInvalidCredentials
+			if dn == "cn=service" && password == "service-secret" {
+				code = 0
+			}
+			for _, e := range entries {
+				if e.dn == dn && e.password == password {
+					code = 0
+				}
+			}
+			reply(id, result(tagBindResponse, code))
+		case tagSearchRequest:
+			filter := op.Children[6]
+			if filter.Tag == tagFilterAnd {
+				filter = filter.Children[len(filter.Children)-1]
+			}
+			attr, value := filter.Children[0].string(), filter.Children[1].string()
+			for _, e := range entries {
+				if e.attrs[attr] != value {
+					continue
+				}
+				var attrs [][]byte
+				for name, v := range e.attrs {
+					attrs = append(attrs, constructed(tagSequence,
+						encodeString(tagOctetString, name),
+						constructed(tagSet, encodeString(tagOctetString, v)),
+					))
+				}
+				reply(id, constructed(tagSearchResultEntry, encodeString(tagOctetString, e.dn), constructed(tagSequence, attrs...)))
+			}
+			reply(id, result(tagSearchResultDone, 0))
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	url := serveFakeDirectory(t, []fakeEntry{
+		{dn: "uid=ada,ou=people,dc=lab", password: "correct horse", attrs: map[string]string{"uid": "ada", "mail": "Ada@Lab.Example", "displayName": "Ada Perfumer"}},
+		{dn: "uid=nomail,ou=people,dc=lab", password: "secret", attrs: map[string]string{"uid": "nomail"}},
+		{dn: "uid=twin1,ou=people,dc=lab", password: "secret", attrs: map[string]string{"uid": "twin", "mail": "twin1@lab.example"}},
+		{dn: "uid=twin2,ou=people,dc=lab", password: "secret", attrs: map[string]string{"uid": "twin", "mail": "twin2@lab.example"}},
+	})
+
+	auth, err := NewAuthenticator(Config{
+		URL:          url,
+		BindDN:       "cn=service",
+		BindPassword: "service-secret",
+		BaseDN:       "dc=lab",
+		UserFilter:   "(&(objectClass=person)(uid=%s))",
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     Identity
+		wantErr  error
+	}{
+		{name: "valid credentials", username: "ada", password: "correct horse", want: Identity{DN: "uid=ada,ou=people,dc=lab", Email: "ada@lab.example", Name: "Ada Perfumer"}},
+		{name: "wrong password", username: "ada", password: "wrong", wantErr: ErrInvalidCredentials},
+		{name: "unknown user", username: "grace", password: "secret", wantErr: ErrInvalidCredentials},
+		{name: "empty password", username: "ada", password: "", wantErr: ErrInvalidCredentials},
+		{name: "ambiguous user", username: "twin", password: "secret", wantErr: ErrInvalidCredentials},
+		{name: "filter injection", username: "*)(uid=ada", password: "correct horse", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := auth.Authenticate(context.Background(), tt.username, tt.password)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected identity: %+v", got)
+			}
+		})
+	}
+
+	t.Run("missing email attribute", func(t *testing.T) {
+		t.Parallel()
+		if _, err := auth.Authenticate(context.Background(), "nomail", "secret"); err == nil || errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("expected a mapping error, got %v", err)
+		}
+	})
+}
+
+func TestNewAuthenticatorValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "missing url", cfg: Config{BaseDN: "dc=lab"}},
+		{name: "bad scheme", cfg: Config{URL: "http://dir", BaseDN: "dc=lab"}},
+		{name: "missing base dn", cfg: Config{URL: "ldap://dir"}},
+		{name: "filter without placeholder", cfg: Config{URL: "ldap://dir", BaseDN: "dc=lab", UserFilter: "(uid=ada)"}},
+		{name: "substring filter", cfg: Config{URL: "ldap://dir", BaseDN: "dc=lab", UserFilter: "(cn=*%s*)"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewAuthenticator(tt.cfg); err == nil {
+				t.Fatal("expected validation error")
+			}
+		})
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	t.Parallel()
+
+	if got := EscapeFilter(`a*(b)\c`); got != `a\2a\28b\29\5cc` {
+		t.Fatalf("unexpected escape: %s", got)
+	}
+	encoded, err := compileFilter("(uid=" + EscapeFilter("x)(y") + ")")
+	if err != nil {
+		t.Fatalf("compileFilter: %v", err)
+	}
+	packets, err := decodePackets(encoded)
+	if err != nil || len(packets) != 1 {
+		t.Fatalf("decode: %v", err)
+	}
+	if value := packets[0].Children[1].string(); value != "x)(y" {
+		t.Fatalf("unexpected filter value %q", value)
+	}
+}
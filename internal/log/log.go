@@ -48,13 +48,15 @@ func newHandler(w io.Writer) slog.Handler {
 }
 
 // SetLevel updates the minimum logging level accepted by the global logger.
-// Supported levels are "debug", "info", and "error". Values are case-insensitive.
+// Supported levels are "debug", "info", "warn" and "error". Values are case-insensitive.
 func SetLevel(level string) error {
 	switch strings.ToLower(strings.TrimSpace(level)) {
 	case "", "info":
 		levelVar.Set(slog.LevelInfo)
 	case "debug":
 		levelVar.Set(slog.LevelDebug)
+	case "warn":
+		levelVar.Set(slog.LevelWarn)
 	case "error":
 		levelVar.Set(slog.LevelError)
 	default:
@@ -86,17 +88,28 @@ func ReplaceLogger(l *slog.Logger) {
 
 // Info logs a message at the info level using the global logger.
 func Info(ctx context.Context, msg string, args ...any) {
-	Logger().InfoContext(withContext(ctx), msg, args...)
+	Logger().InfoContext(withContext(ctx), msg, withRequestID(ctx, args)...)
 }
 
 // Debug logs a message at the debug level using the global logger.
 func Debug(ctx context.Context, msg string, args ...any) {
-	Logger().DebugContext(withContext(ctx), msg, args...)
+	Logger().DebugContext(withContext(ctx), msg, withRequestID(ctx, args)...)
+}
+
+// Warn logs a message at the warn level using the global logger.
+func Warn(ctx context.Context, msg string, args ...any) {
+	Logger().WarnContext(withContext(ctx), msg, withRequestID(ctx, args)...)
 }
 
 // Error logs a message at the error level using the global logger.
 func Error(ctx context.Context, msg string, args ...any) {
-	Logger().ErrorContext(withContext(ctx), msg, args...)
+	Logger().ErrorContext(withContext(ctx), msg, withRequestID(ctx, args)...)
+}
+
+// Enabled reports whether a message at level would be logged, so callers
+// can skip building expensive arguments.
+func Enabled(ctx context.Context, level slog.Level) bool {
+	return Logger().Enabled(withContext(ctx), level)
 }
 
 func withContext(ctx context.Context) context.Context {
@@ -106,6 +119,30 @@ func withContext(ctx context.Context) context.Context {
 	return ctx
 }
 
+type requestIDKey struct{}
+
+// WithRequestID tags ctx with the ID of the request it serves; messages
+// logged with the context carry it as requestID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(withContext(ctx), requestIDKey{}, id)
+}
+
+// RequestID returns the request ID attached to ctx, if any.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func withRequestID(ctx context.Context, args []any) []any {
+	if id := RequestID(ctx); id != "" {
+		return append([]any{"requestID", id}, args...)
+	}
+	return args
+}
+
 // Sync ensures any buffered log entries are flushed. The default slog text handler
 // writes directly to stdout, so Sync is a no-op but is provided for API completeness.
 func Sync() error {
@@ -35,3 +35,24 @@ func TestInfoProducesLogfmtWithTimestamp(t *testing.T) {
 		t.Fatalf("expected structured field in log line, got %q", line)
 	}
 }
+
+func TestRequestIDIsLogged(t *testing.T) {
+	buf := new(bytes.Buffer)
+	original := Logger()
+	ReplaceLogger(slog.New(newHandler(buf)))
+	t.Cleanup(func() {
+		ReplaceLogger(original)
+	})
+
+	ctx := WithRequestID(context.Background(), "req-42")
+	Info(ctx, "hello", "user", "test")
+	Info(context.Background(), "bye")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "requestID=req-42 user=test") {
+		t.Fatalf("expected the request ID on the first line only, got %q", lines)
+	}
+	if strings.Contains(lines[1], "requestID") {
+		t.Fatalf("unexpected request ID in %q", lines[1])
+	}
+}
@@ -0,0 +1,136 @@
+// Package mail delivers transactional email over SMTP.
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a single email. Text is always sent; when HTML is set the
+// message carries both as multipart/alternative so clients pick the richer one.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers messages. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config describes the SMTP relay used for outgoing mail.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSender sends mail through an SMTP relay, upgrading with STARTTLS when
+// the server offers it.
+type SMTPSender struct {
+	cfg  Config
+	send func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPSender validates the configuration and builds an SMTPSender.
+func NewSMTPSender(cfg Config) (*SMTPSender, error) {
+	cfg.Host = strings.TrimSpace(cfg.Host)
+	cfg.From = strings.TrimSpace(cfg.From)
+	if cfg.Host == "" {
+		return nil, errors.New("mail: smtp host is required")
+	}
+	if cfg.From == "" {
+		return nil, errors.New("mail: from address is required")
+	}
+	if cfg.Port <= 0 {
+		cfg.Port = 587
+	}
+	return &SMTPSender{cfg: cfg, send: smtp.SendMail}, nil
+}
+
+// Send delivers msg. The context is only consulted before dialing because
+// net/smtp does not support cancellation.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	to := strings.TrimSpace(msg.To)
+	if to == "" || strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("mail: invalid recipient %q", msg.To)
+	}
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	if err := s.send(addr, auth, s.cfg.From, []string{to}, s.compose(to, msg)); err != nil {
+		return fmt.Errorf("mail: send to %s: %w", to, err)
+	}
+	return nil
+}
+
+func (s *SMTPSender) compose(to string, msg Message) []byte {
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Subject)
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(crlf(msg.Text))
+		return []byte(b.String())
+	}
+
+	boundary := mimeBoundary()
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n", boundary)
+	b.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(quotedPrintable(crlf(part.body)))
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return []byte(b.String())
+}
+
+func crlf(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
+
+func quotedPrintable(text string) string {
+	var b strings.Builder
+	w := quotedprintable.NewWriter(&b)
+	_, _ = w.Write([]byte(text))
+	_ = w.Close()
+	return b.String()
+}
+
+// mimeBoundary returns a random multipart boundary.
+func mimeBoundary() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return "perfugo-" + hex.EncodeToString(buf)
+}
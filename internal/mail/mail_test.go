@@ -0,0 +1,109 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestSMTPSenderSend(t *testing.T) {
+	t.Parallel()
+
+	sender, err := NewSMTPSender(Config{Host: "smtp.example.com", Username: "perfugo", Password: "secret", From: "Perfugo <no-reply@example.com>"})
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+
+	var gotAddr string
+	var gotTo []string
+	var gotBody string
+	sender.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo, gotBody = addr, to, string(msg)
+		return nil
+	}
+
+	err = sender.Send(context.Background(), Message{To: "ada@example.com", Subject: "Hello\r\nBcc: evil@example.com", Text: "line one\nline two"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if gotAddr != "smtp.example.com:587" {
+		t.Fatalf("unexpected addr %q", gotAddr)
+	}
+	if len(gotTo) != 1 || gotTo[0] != "ada@example.com" {
+		t.Fatalf("unexpected recipients %v", gotTo)
+	}
+	if strings.Contains(gotBody, "\r\nBcc:") {
+		t.Fatalf("header injection not neutralised: %q", gotBody)
+	}
+	if !strings.Contains(gotBody, "\r\n\r\nline one\r\nline two") {
+		t.Fatalf("unexpected body %q", gotBody)
+	}
+
+	if err := sender.Send(context.Background(), Message{To: "a@example.com\r\nBcc: b@example.com"}); err == nil {
+		t.Fatal("expected invalid recipient error")
+	}
+}
+
+func TestNewSMTPSenderValidation(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewSMTPSender(Config{From: "a@example.com"}); err == nil {
+		t.Fatal("expected missing host error")
+	}
+	if _, err := NewSMTPSender(Config{Host: "smtp.example.com"}); err == nil {
+		t.Fatal("expected missing from error")
+	}
+}
+
+func TestSMTPSenderSendsHTMLAlternative(t *testing.T) {
+	t.Parallel()
+
+	sender, err := NewSMTPSender(Config{Host: "smtp.example.com", From: "no-reply@example.com"})
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+	var raw string
+	sender.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		raw = string(msg)
+		return nil
+	}
+
+	err = sender.Send(context.Background(), Message{To: "ada@example.com", Subject: "Hi", Text: "plain body", HTML: "<p>rich body</p>"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	msg, err := netmail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var types, bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Fatalf("unexpected parts %v", types)
+	}
+	if bodies[0] != "plain body" || bodies[1] != "<p>rich body</p>" {
+		t.Fatalf("unexpected bodies %q", bodies)
+	}
+}
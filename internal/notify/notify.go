@@ -0,0 +1,124 @@
+// Package notify fans out real-time events, such as toast messages and
+// badge counts, to each signed-in user's open browser tabs.
+package notify
+
+import (
+	"sync"
+)
+
+// Event kinds understood by the workspace layout.
+const (
+	KindToast = "toast"
+	KindBadge = "badge"
+)
+
+// Toast levels.
+const (
+	LevelInfo    = "info"
+	LevelSuccess = "success"
+	LevelWarning = "warning"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events to it are dropped.
+const subscriberBuffer = 16
+
+// Event is one message pushed to a user. Toasts carry Level and Message;
+// badges carry the Badge name and its new Count.
+type Event struct {
+	Kind    string `json:"kind"`
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message,omitempty"`
+	Badge   string `json:"badge,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// Toast builds a toast event.
+func Toast(level, message string) Event {
+	return Event{Kind: KindToast, Level: level, Message: message}
+}
+
+// Badge builds a badge count event.
+func Badge(name string, count int) Event {
+	return Event{Kind: KindBadge, Badge: name, Count: count}
+}
+
+// Hub keeps one channel per open subscription, grouped by user. The zero
+// value is not usable; create hubs with NewHub.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan Event]struct{}
+	closed      bool
+}
+
+// NewHub returns an empty hub.
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uint]map[chan Event]struct{})}
+}
+
+// Subscribe opens a channel receiving the user's events. The returned
+// function unsubscribes; the channel is closed when the subscription ends
+// or the hub shuts down.
+func (h *Hub) Subscribe(userID uint) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := h.subscribers[userID][ch]; !ok {
+				return
+			}
+			delete(h.subscribers[userID], ch)
+			if len(h.subscribers[userID]) == 0 {
+				delete(h.subscribers, userID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish sends event to every open subscription of the user. It never
+// blocks: a subscriber whose buffer is full misses the event.
+func (h *Hub) Publish(userID uint, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers reports how many subscriptions the user has open.
+func (h *Hub) Subscribers(userID uint) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers[userID])
+}
+
+// Close ends every subscription so streaming handlers return, letting the
+// HTTP server shut down. Later subscriptions are closed immediately.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for userID, channels := range h.subscribers {
+		for ch := range channels {
+			close(ch)
+		}
+		delete(h.subscribers, userID)
+	}
+}
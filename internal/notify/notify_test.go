@@ -0,0 +1,71 @@
+package notify
+
+import "testing"
+
+func TestHubDeliversOnlyToTheUser(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub()
+	first, unsubscribeFirst := hub.Subscribe(1)
+	second, unsubscribeSecond := hub.Subscribe(1)
+	other, unsubscribeOther := hub.Subscribe(2)
+	defer unsubscribeOther()
+
+	hub.Publish(1, Toast(LevelSuccess, "Batch finalized"))
+
+	for _, ch := range []<-chan Event{first, second} {
+		if got := <-ch; got.Kind != KindToast || got.Message != "Batch finalized" {
+			t.Fatalf("unexpected event %+v", got)
+		}
+	}
+	select {
+	case got := <-other:
+		t.Fatalf("user 2 received %+v", got)
+	default:
+	}
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	if _, ok := <-first; ok {
+		t.Fatal("expected the channel to be closed after unsubscribing")
+	}
+	if n := hub.Subscribers(1); n != 1 {
+		t.Fatalf("Subscribers(1) = %d, want 1", n)
+	}
+	unsubscribeSecond()
+	if n := hub.Subscribers(1); n != 0 {
+		t.Fatalf("Subscribers(1) = %d, want 0", n)
+	}
+}
+
+func TestHubDropsEventsForSlowSubscribers(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub()
+	ch, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer+5; i++ {
+		hub.Publish(1, Badge("batches", i))
+	}
+	if len(ch) != subscriberBuffer {
+		t.Fatalf("buffered %d events, want %d", len(ch), subscriberBuffer)
+	}
+}
+
+func TestHubCloseEndsSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub()
+	ch, unsubscribe := hub.Subscribe(1)
+	hub.Close()
+	if _, ok := <-ch; ok {
+		t.Fatal("expected Close to close open subscriptions")
+	}
+	unsubscribe()
+
+	late, _ := hub.Subscribe(1)
+	if _, ok := <-late; ok {
+		t.Fatal("expected subscriptions after Close to be closed")
+	}
+}
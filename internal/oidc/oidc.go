@@ -0,0 +1,361 @@
+// Package oidc implements the subset of OpenID Connect needed for single
+// sign-on: discovery, the authorization code flow with PKCE and RP-initiated
+// logout.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config describes the relying party registration with the identity provider.
+type Config struct {
+	IssuerURL             string
+	ClientID              string
+	ClientSecret          string
+	RedirectURL           string
+	PostLogoutRedirectURL string
+	Scopes                []string
+	HTTPClient            *http.Client
+
+	// RolesClaim names the claim carrying group or role membership. Nested
+	// claims use dots, e.g. "realm_access.roles".
+	RolesClaim string
+	// RoleMapping maps provider groups to application role names.
+	RoleMapping map[string]string
+	// LinkByEmail lets a first login attach to an existing account with the
+	// same verified email address instead of failing.
+	LinkByEmail bool
+}
+
+// Provider holds the discovered endpoints of an OpenID provider.
+type Provider struct {
+	config Config
+	client *http.Client
+	now    func() time.Time
+
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// Tokens are the credentials returned by the token endpoint.
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// Claims are the identity claims extracted from an ID token.
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Audience      []string
+	Expiry        time.Time
+	Nonce         string
+	Raw           map[string]any
+}
+
+// Discover fetches the provider metadata from the issuer's well-known document.
+func Discover(ctx context.Context, cfg Config) (*Provider, error) {
+	issuer := strings.TrimRight(strings.TrimSpace(cfg.IssuerURL), "/")
+	if issuer == "" {
+		return nil, errors.New("oidc: issuer url must not be empty")
+	}
+	if strings.TrimSpace(cfg.ClientID) == "" {
+		return nil, errors.New("oidc: client id must not be empty")
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: build discovery request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery responded with %s", resp.Status)
+	}
+
+	provider := &Provider{config: cfg, client: client, now: time.Now}
+	if err := json.NewDecoder(resp.Body).Decode(provider); err != nil {
+		return nil, fmt.Errorf("oidc: decode discovery document: %w", err)
+	}
+	if strings.TrimRight(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch: expected %q, got %q", issuer, provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" {
+		return nil, errors.New("oidc: discovery document is missing required endpoints")
+	}
+	return provider, nil
+}
+
+// SupportsLogout reports whether the provider advertises an end_session_endpoint.
+func (p *Provider) SupportsLogout() bool {
+	return p != nil && p.EndSessionEndpoint != ""
+}
+
+// LinksByEmail reports whether identities may be linked to existing accounts
+// by verified email address.
+func (p *Provider) LinksByEmail() bool {
+	return p != nil && p.config.LinkByEmail
+}
+
+// AuthCodeURL builds the authorization request URL for the code flow.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	values := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	return appendQuery(p.AuthorizationEndpoint, values)
+}
+
+// Exchange redeems an authorization code at the token endpoint.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (Tokens, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Tokens{}, fmt.Errorf("oidc: build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Tokens{}, fmt.Errorf("oidc: read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Tokens{}, fmt.Errorf("oidc: token endpoint responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var tokens Tokens
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return Tokens{}, fmt.Errorf("oidc: decode token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return Tokens{}, errors.New("oidc: token response did not include an id_token")
+	}
+	return tokens, nil
+}
+
+// VerifyIDToken decodes an ID token received directly from the token endpoint
+// and validates its issuer, audience, expiry and nonce. The TLS connection to
+// the token endpoint authenticates the issuer, as permitted by OpenID Connect
+// Core §3.1.3.7, so the signature is not re-checked here.
+func (p *Provider) VerifyIDToken(raw, nonce string) (Claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return Claims{}, errors.New("oidc: malformed id token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, fmt.Errorf("oidc: decode id token payload: %w", err)
+	}
+
+	var rawClaims map[string]any
+	if err := json.Unmarshal(payload, &rawClaims); err != nil {
+		return Claims{}, fmt.Errorf("oidc: decode id token claims: %w", err)
+	}
+
+	claims := Claims{
+		Issuer:        stringClaim(rawClaims, "iss"),
+		Subject:       stringClaim(rawClaims, "sub"),
+		Email:         stringClaim(rawClaims, "email"),
+		EmailVerified: boolClaim(rawClaims, "email_verified"),
+		Name:          stringClaim(rawClaims, "name"),
+		Audience:      StringsClaim(rawClaims, "aud"),
+		Nonce:         stringClaim(rawClaims, "nonce"),
+		Raw:           rawClaims,
+	}
+	if exp, ok := rawClaims["exp"].(float64); ok {
+		claims.Expiry = time.Unix(int64(exp), 0)
+	}
+
+	switch {
+	case strings.TrimRight(claims.Issuer, "/") != strings.TrimRight(p.Issuer, "/"):
+		return Claims{}, fmt.Errorf("oidc: unexpected issuer %q", claims.Issuer)
+	case !contains(claims.Audience, p.config.ClientID):
+		return Claims{}, errors.New("oidc: id token was not issued for this client")
+	case claims.Expiry.IsZero() || p.now().After(claims.Expiry):
+		return Claims{}, errors.New("oidc: id token has expired")
+	case nonce == "" || claims.Nonce != nonce:
+		return Claims{}, errors.New("oidc: nonce mismatch")
+	case claims.Subject == "":
+		return Claims{}, errors.New("oidc: id token is missing a subject")
+	}
+	return claims, nil
+}
+
+// MappedRoles translates the configured groups claim into application role
+// names. It returns false when no mapping is configured or the claim is
+// absent, in which case callers should leave the stored role untouched.
+func (p *Provider) MappedRoles(claims Claims) ([]string, bool) {
+	if len(p.config.RoleMapping) == 0 || p.config.RolesClaim == "" {
+		return nil, false
+	}
+	groups, ok := nestedClaim(claims.Raw, p.config.RolesClaim)
+	if !ok {
+		return nil, false
+	}
+
+	var roles []string
+	for _, group := range groups {
+		if role, ok := p.config.RoleMapping[group]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles, true
+}
+
+// EndSessionURL builds the RP-initiated logout URL. It returns false when the
+// provider does not support logout.
+func (p *Provider) EndSessionURL(idTokenHint, state string) (string, bool) {
+	if !p.SupportsLogout() {
+		return "", false
+	}
+	values := url.Values{"client_id": {p.config.ClientID}}
+	if idTokenHint != "" {
+		values.Set("id_token_hint", idTokenHint)
+	}
+	if p.config.PostLogoutRedirectURL != "" {
+		values.Set("post_logout_redirect_uri", p.config.PostLogoutRedirectURL)
+	}
+	if state != "" {
+		values.Set("state", state)
+	}
+	return appendQuery(p.EndSessionEndpoint, values), true
+}
+
+// RandomString returns a URL-safe random value suitable for state, nonce and PKCE verifiers.
+func RandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("oidc: generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge for a verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// StringsClaim reads a claim that may be encoded as a string or a list of strings.
+func StringsClaim(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		if value == "" {
+			return nil
+		}
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func nestedClaim(claims map[string]any, path string) ([]string, bool) {
+	segments := strings.Split(path, ".")
+	current := claims
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := current[segment].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	last := segments[len(segments)-1]
+	if _, ok := current[last]; !ok {
+		return nil, false
+	}
+	return StringsClaim(current, last), true
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim reads a boolean claim. Some providers send booleans as strings.
+func boolClaim(claims map[string]any, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	}
+	return false
+}
+
+func contains(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+func appendQuery(endpoint string, values url.Values) string {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	return endpoint + separator + values.Encode()
+}
@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func unsignedToken(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+func newTestProvider(t *testing.T, endSession bool, token func(url.Values) string) (*httptest.Server, Config) {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			doc := map[string]string{
+				"issuer":                 srv.URL,
+				"authorization_endpoint": srv.URL + "/authorize",
+				"token_endpoint":         srv.URL + "/token",
+			}
+			if endSession {
+				doc["end_session_endpoint"] = srv.URL + "/logout"
+			}
+			_ = json.NewEncoder(w).Encode(doc)
+		case "/token":
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("parse token form: %v", err)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"id_token": token(r.PostForm), "access_token": "at"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, Config{
+		IssuerURL:             srv.URL,
+		ClientID:              "perfugo",
+		RedirectURL:           "https://app.example/auth/oidc/callback",
+		PostLogoutRedirectURL: "https://app.example/login",
+	}
+}
+
+func TestDiscoverAndEndSessionURL(t *testing.T) {
+	_, cfg := newTestProvider(t, true, nil)
+	provider, err := Discover(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if !provider.SupportsLogout() {
+		t.Fatal("expected end_session_endpoint to be discovered")
+	}
+
+	logoutURL, ok := provider.EndSessionURL("raw-id-token", "")
+	if !ok {
+		t.Fatal("expected logout url")
+	}
+	parsed, err := url.Parse(logoutURL)
+	if err != nil {
+		t.Fatalf("parse logout url: %v", err)
+	}
+	query := parsed.Query()
+	if parsed.Path != "/logout" || query.Get("id_token_hint") != "raw-id-token" ||
+		query.Get("post_logout_redirect_uri") != cfg.PostLogoutRedirectURL || query.Get("client_id") != "perfugo" {
+		t.Fatalf("unexpected logout url %s", logoutURL)
+	}
+
+	_, cfg = newTestProvider(t, false, nil)
+	provider, err = Discover(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if _, ok := provider.EndSessionURL("raw-id-token", ""); ok {
+		t.Fatal("expected no logout url when the provider lacks end_session_endpoint")
+	}
+
+	var nilProvider *Provider
+	if nilProvider.SupportsLogout() {
+		t.Fatal("nil provider must not support logout")
+	}
+}
+
+func TestDiscoverRejectsIssuerMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://elsewhere.example",
+			"authorization_endpoint": "https://elsewhere.example/authorize",
+			"token_endpoint":         "https://elsewhere.example/token",
+		})
+	}))
+	defer srv.Close()
+
+	if _, err := Discover(context.Background(), Config{IssuerURL: srv.URL, ClientID: "perfugo"}); err == nil {
+		t.Fatal("expected issuer mismatch error")
+	}
+}
+
+func TestCodeFlow(t *testing.T) {
+	var issuer string
+	verifier := "verifier-value"
+	srv, cfg := newTestProvider(t, true, func(form url.Values) string {
+		if form.Get("code_verifier") != verifier || form.Get("code") != "the-code" {
+			t.Errorf("unexpected token request: %v", form)
+		}
+		return unsignedToken(t, map[string]any{
+			"iss":            issuer,
+			"sub":            "user-123",
+			"aud":            "perfugo",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"nonce":          "nonce-value",
+			"email":          "ada@example.com",
+			"email_verified": "true",
+		})
+	})
+	issuer = srv.URL
+
+	provider, err := Discover(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+
+	authURL := provider.AuthCodeURL("state-value", "nonce-value", verifier)
+	if !strings.Contains(authURL, "code_challenge="+CodeChallenge(verifier)) || !strings.Contains(authURL, "state=state-value") {
+		t.Fatalf("unexpected auth url %s", authURL)
+	}
+
+	tokens, err := provider.Exchange(context.Background(), "the-code", verifier)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	claims, err := provider.VerifyIDToken(tokens.IDToken, "nonce-value")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "ada@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	if _, err := provider.VerifyIDToken(tokens.IDToken, "other-nonce"); err == nil {
+		t.Fatal("expected nonce mismatch to be rejected")
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidClaims(t *testing.T) {
+	provider := &Provider{config: Config{ClientID: "perfugo"}, Issuer: "https://idp.example", now: time.Now}
+	valid := map[string]any{
+		"iss":   "https://idp.example",
+		"sub":   "user-123",
+		"aud":   []any{"perfugo", "other"},
+		"exp":   float64(time.Now().Add(time.Hour).Unix()),
+		"nonce": "n",
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(map[string]any)
+	}{
+		{name: "wrong issuer", mutate: func(c map[string]any) { c["iss"] = "https://evil.example" }},
+		{name: "wrong audience", mutate: func(c map[string]any) { c["aud"] = "someone-else" }},
+		{name: "expired", mutate: func(c map[string]any) { c["exp"] = float64(time.Now().Add(-time.Minute).Unix()) }},
+		{name: "missing subject", mutate: func(c map[string]any) { delete(c, "sub") }},
+	}
+
+	if _, err := provider.VerifyIDToken(unsignedToken(t, valid), "n"); err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]any{}
+			for k, v := range valid {
+				claims[k] = v
+			}
+			tt.mutate(claims)
+			if _, err := provider.VerifyIDToken(unsignedToken(t, claims), "n"); err == nil {
+				t.Fatal("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestMappedRoles(t *testing.T) {
+	mapping := map[string]string{"perfugo-admins": "admin", "perfumers": "member"}
+	tests := []struct {
+		name   string
+		claim  string
+		raw    map[string]any
+		want   []string
+		wantOK bool
+	}{
+		{name: "flat groups", claim: "groups", raw: map[string]any{"groups": []any{"perfumers", "perfugo-admins", "unrelated"}}, want: []string{"member", "admin"}, wantOK: true},
+		{name: "nested roles", claim: "realm_access.roles", raw: map[string]any{"realm_access": map[string]any{"roles": []any{"perfugo-admins"}}}, want: []string{"admin"}, wantOK: true},
+		{name: "no matching groups", claim: "groups", raw: map[string]any{"groups": "unrelated"}, want: nil, wantOK: true},
+		{name: "claim absent", claim: "groups", raw: map[string]any{}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &Provider{config: Config{RolesClaim: tt.claim, RoleMapping: mapping}}
+			got, ok := provider.MappedRoles(Claims{Raw: tt.raw})
+			if ok != tt.wantOK || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("MappedRoles() = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	unmapped := &Provider{config: Config{RolesClaim: "groups"}}
+	if _, ok := unmapped.MappedRoles(Claims{Raw: map[string]any{"groups": "perfugo-admins"}}); ok {
+		t.Fatal("expected no roles without a configured mapping")
+	}
+}
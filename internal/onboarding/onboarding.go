@@ -0,0 +1,215 @@
+// Package onboarding seeds a new account's private library so the workspace
+// is not empty on first login.
+package onboarding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+// Chemical is a starter aroma chemical copied into the user's private library.
+type Chemical struct {
+	Name                string   `json:"name"`
+	CASNumber           string   `json:"cas_number"`
+	OtherNames          []string `json:"other_names,omitempty"`
+	Notes               string   `json:"notes"`
+	Type                string   `json:"type"`
+	WheelPosition       string   `json:"wheel_position"`
+	PyramidPosition     string   `json:"pyramid_position"`
+	Strength            int      `json:"strength"`
+	RecommendedDilution float64  `json:"recommended_dilution"`
+	DilutionPercentage  float64  `json:"dilution_percentage"`
+	MaxIFRAPercentage   float64  `json:"max_ifra_percentage"`
+	Solvent             bool     `json:"solvent"`
+}
+
+// FormulaLine references a template chemical by name.
+type FormulaLine struct {
+	Chemical string  `json:"chemical"`
+	Amount   float64 `json:"amount"`
+	Unit     string  `json:"unit"`
+}
+
+// Formula is the example formula built from the starter chemicals.
+type Formula struct {
+	Name  string        `json:"name"`
+	Notes string        `json:"notes"`
+	Lines []FormulaLine `json:"lines"`
+}
+
+// Template describes what a new account is seeded with.
+type Template struct {
+	Chemicals []Chemical `json:"chemicals"`
+	Formula   *Formula   `json:"formula,omitempty"`
+}
+
+// Result reports what Seed created.
+type Result struct {
+	Chemicals int
+	Formula   bool
+}
+
+// DefaultTemplate is a small, widely used starter palette and a simple
+// cologne-style example.
+var DefaultTemplate = Template{
+	Chemicals: []Chemical{
+		{Name: "Hedione", CASNumber: "24851-98-7", OtherNames: []string{"Methyl Dihydrojasmonate"}, Notes: "Transparent jasmine with a fresh citrus lift.", Type: "Aroma Chemical (Ester)", WheelPosition: "Floral", PyramidPosition: "Heart", Strength: 3, RecommendedDilution: 100, DilutionPercentage: 100},
+		{Name: "Iso E Super", CASNumber: "54464-57-2", OtherNames: []string{"OTNE"}, Notes: "Velvety cedar wood with an ambery hum.", Type: "Aroma Chemical (Ketone)", WheelPosition: "Woods", PyramidPosition: "Base", Strength: 3, RecommendedDilution: 100, DilutionPercentage: 100, MaxIFRAPercentage: 21.4},
+		{Name: "Linalool", CASNumber: "78-70-6", Notes: "Fresh floral-woody lift with a hint of lavender.", Type: "Aroma Chemical (Alcohol)", WheelPosition: "Floral", PyramidPosition: "Top", Strength: 3, RecommendedDilution: 10, DilutionPercentage: 10},
+		{Name: "Galaxolide", CASNumber: "1222-05-5", OtherNames: []string{"HHCB"}, Notes: "Clean, sweet floral musk.", Type: "Aroma Chemical (Musk)", WheelPosition: "Musk", PyramidPosition: "Base", Strength: 4, RecommendedDilution: 50, DilutionPercentage: 50},
+		{Name: "Ambroxan", CASNumber: "6790-58-5", OtherNames: []string{"Ambrox"}, Notes: "Dry ambergris facet with a warm mineral glow.", Type: "Aroma Chemical (Ether)", WheelPosition: "Amber", PyramidPosition: "Base", Strength: 6, RecommendedDilution: 10, DilutionPercentage: 10},
+		{Name: "Ethanol", CASNumber: "64-17-5", Notes: "Perfumer's alcohol used for dilutions and finished products.", Type: "Solvent", Solvent: true, RecommendedDilution: 100, DilutionPercentage: 100},
+	},
+	Formula: &Formula{
+		Name:  "Example: Clean Cologne",
+		Notes: "A starter accord to explore the editor. Scale it, swap materials or delete it.",
+		Lines: []FormulaLine{
+			{Chemical: "Hedione", Amount: 40, Unit: "g"},
+			{Chemical: "Iso E Super", Amount: 25, Unit: "g"},
+			{Chemical: "Linalool", Amount: 15, Unit: "g"},
+			{Chemical: "Galaxolide", Amount: 15, Unit: "g"},
+			{Chemical: "Ambroxan", Amount: 5, Unit: "g"},
+		},
+	},
+}
+
+// LoadTemplate reads a JSON template from path.
+func LoadTemplate(path string) (Template, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Template{}, fmt.Errorf("read onboarding template: %w", err)
+	}
+	var tmpl Template
+	if err := json.Unmarshal(raw, &tmpl); err != nil {
+		return Template{}, fmt.Errorf("parse onboarding template %s: %w", path, err)
+	}
+	if err := tmpl.Validate(); err != nil {
+		return Template{}, fmt.Errorf("onboarding template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// Validate checks that every chemical is named and that formula lines only
+// reference chemicals from the template.
+func (t Template) Validate() error {
+	names := map[string]bool{}
+	for i, chemical := range t.Chemicals {
+		key := strings.ToLower(strings.TrimSpace(chemical.Name))
+		if key == "" {
+			return fmt.Errorf("chemical %d has no name", i+1)
+		}
+		names[key] = true
+	}
+	if t.Formula == nil {
+		return nil
+	}
+	if strings.TrimSpace(t.Formula.Name) == "" {
+		return errors.New("formula has no name")
+	}
+	for _, line := range t.Formula.Lines {
+		if !names[strings.ToLower(strings.TrimSpace(line.Chemical))] {
+			return fmt.Errorf("formula line references unknown chemical %q", line.Chemical)
+		}
+		if line.Amount <= 0 {
+			return fmt.Errorf("formula line %q must have a positive amount", line.Chemical)
+		}
+	}
+	return nil
+}
+
+// Seed copies the template chemicals into userID's private library, skipping
+// names the user already owns. Formulas are shared across the instance, so
+// the example formula is only created while no formulas exist yet.
+func Seed(ctx context.Context, db *gorm.DB, userID uint, tmpl Template) (Result, error) {
+	var result Result
+	if db == nil {
+		return result, errors.New("database handle is nil")
+	}
+	if userID == 0 {
+		return result, errors.New("user id is required")
+	}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.AromaChemical
+		if err := tx.Where("owner_id = ?", userID).Find(&existing).Error; err != nil {
+			return err
+		}
+		byName := make(map[string]uint, len(existing))
+		for _, chemical := range existing {
+			byName[strings.ToLower(chemical.IngredientName)] = chemical.ID
+		}
+
+		for _, tmplChemical := range tmpl.Chemicals {
+			key := strings.ToLower(strings.TrimSpace(tmplChemical.Name))
+			if _, ok := byName[key]; ok {
+				continue
+			}
+			chemical := models.AromaChemical{
+				IngredientName:      strings.TrimSpace(tmplChemical.Name),
+				CASNumber:           tmplChemical.CASNumber,
+				Notes:               tmplChemical.Notes,
+				Type:                tmplChemical.Type,
+				WheelPosition:       tmplChemical.WheelPosition,
+				PyramidPosition:     tmplChemical.PyramidPosition,
+				Strength:            tmplChemical.Strength,
+				RecommendedDilution: tmplChemical.RecommendedDilution,
+				DilutionPercentage:  tmplChemical.DilutionPercentage,
+				MaxIFRAPercentage:   tmplChemical.MaxIFRAPercentage,
+				Solvent:             tmplChemical.Solvent,
+				OwnerID:             userID,
+			}
+			for _, name := range tmplChemical.OtherNames {
+				chemical.OtherNames = append(chemical.OtherNames, models.OtherName{Name: name})
+			}
+			if err := tx.Create(&chemical).Error; err != nil {
+				return err
+			}
+			byName[key] = chemical.ID
+			result.Chemicals++
+		}
+
+		if tmpl.Formula == nil {
+			return nil
+		}
+		var formulas int64
+		if err := tx.Model(&models.Formula{}).Count(&formulas).Error; err != nil {
+			return err
+		}
+		if formulas > 0 {
+			return nil
+		}
+
+		formula := models.Formula{Name: tmpl.Formula.Name, Notes: tmpl.Formula.Notes, Version: 1, IsLatest: true}
+		if err := tx.Create(&formula).Error; err != nil {
+			return err
+		}
+		for position, line := range tmpl.Formula.Lines {
+			chemicalID, ok := byName[strings.ToLower(strings.TrimSpace(line.Chemical))]
+			if !ok {
+				continue
+			}
+			unit := line.Unit
+			if unit == "" {
+				unit = "g"
+			}
+			ingredient := models.FormulaIngredient{FormulaID: formula.ID, Amount: line.Amount, Unit: unit, AromaChemicalID: &chemicalID, Position: position}
+			if err := tx.Create(&ingredient).Error; err != nil {
+				return err
+			}
+		}
+		result.Formula = true
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
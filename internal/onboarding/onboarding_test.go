@@ -0,0 +1,104 @@
+package onboarding
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func newOnboardingTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:onboarding-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.AromaChemical{}, &models.OtherName{}, &models.Formula{}, &models.FormulaIngredient{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	return db
+}
+
+func TestSeed(t *testing.T) {
+	db := newOnboardingTestDB(t)
+	ctx := context.Background()
+
+	if err := db.Create(&models.AromaChemical{IngredientName: "hedione", OwnerID: 1}).Error; err != nil {
+		t.Fatalf("seed existing chemical: %v", err)
+	}
+
+	result, err := Seed(ctx, db, 1, DefaultTemplate)
+	if err != nil {
+		t.Fatalf("Seed: %v", err)
+	}
+	if result.Chemicals != len(DefaultTemplate.Chemicals)-1 {
+		t.Fatalf("expected existing chemical to be skipped, created %d", result.Chemicals)
+	}
+	if !result.Formula {
+		t.Fatal("expected the example formula on an empty instance")
+	}
+
+	var formula models.Formula
+	if err := db.Preload("Ingredients").First(&formula).Error; err != nil {
+		t.Fatalf("load formula: %v", err)
+	}
+	if len(formula.Ingredients) != len(DefaultTemplate.Formula.Lines) {
+		t.Fatalf("expected %d formula lines, got %d", len(DefaultTemplate.Formula.Lines), len(formula.Ingredients))
+	}
+
+	var public int64
+	db.Model(&models.AromaChemical{}).Where("public = ?", true).Count(&public)
+	if public != 0 {
+		t.Fatal("expected seeded chemicals to be private")
+	}
+
+	second, err := Seed(ctx, db, 2, DefaultTemplate)
+	if err != nil {
+		t.Fatalf("Seed second user: %v", err)
+	}
+	if second.Chemicals != len(DefaultTemplate.Chemicals) || second.Formula {
+		t.Fatalf("expected private chemicals but no second shared formula, got %+v", second)
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: `{"chemicals":[{"name":"Vanillin"}],"formula":{"name":"Vanilla","lines":[{"chemical":"vanillin","amount":1}]}}`},
+		{name: "unknown chemical", content: `{"chemicals":[{"name":"Vanillin"}],"formula":{"name":"Vanilla","lines":[{"chemical":"Coumarin","amount":1}]}}`, wantErr: true},
+		{name: "unnamed chemical", content: `{"chemicals":[{"cas_number":"121-33-5"}]}`, wantErr: true},
+		{name: "invalid json", content: `{`, wantErr: true},
+	}
+	for i, tt := range tests {
+		tt := tt
+		path := filepath.Join(dir, fmt.Sprintf("template-%d.json", i))
+		if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+			t.Fatalf("write template: %v", err)
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := LoadTemplate(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadTemplate error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Package ratelimit throttles requests per client with token buckets: each
+// key may make a burst of requests at once and then a steady number per
+// minute.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter hands out requests per key. The zero value is not usable; build
+// one with New.
+type Limiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// Now is the limiter's clock; tests replace it.
+	Now func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// New returns a limiter allowing perMinute requests a minute per key, with
+// bursts of up to burst requests. A burst below one is raised to one.
+func New(perMinute, burst int) *Limiter {
+	return &Limiter{
+		perSecond: float64(max(perMinute, 1)) / 60,
+		burst:     float64(max(burst, 1)),
+		buckets:   make(map[string]*bucket),
+		Now:       time.Now,
+	}
+}
+
+// Allow takes a request from key's bucket. When the bucket is empty it
+// returns false and how long until the next request would be allowed.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, seen: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.seen).Seconds()*l.perSecond)
+	b.seen = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.perSecond * float64(time.Second))
+	return false, wait
+}
+
+// sweep forgets buckets that have refilled completely, at most once a
+// minute, so one-off clients do not accumulate.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	refill := time.Duration(l.burst / l.perSecond * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllowsBurstThenSteadyRate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(6, 2)
+	l.Now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("203.0.113.7"); !ok {
+			t.Fatalf("request %d of the burst was refused", i+1)
+		}
+	}
+	ok, wait := l.Allow("203.0.113.7")
+	if ok || wait != 10*time.Second {
+		t.Fatalf("expected a refusal with a 10s wait, got %v %v", ok, wait)
+	}
+	if ok, _ := l.Allow("198.51.100.1"); !ok {
+		t.Fatal("another client shares the first one's bucket")
+	}
+
+	now = now.Add(10 * time.Second)
+	if ok, _ := l.Allow("203.0.113.7"); !ok {
+		t.Fatal("expected a token after 10s")
+	}
+	if ok, _ := l.Allow("203.0.113.7"); ok {
+		t.Fatal("expected only one token after 10s")
+	}
+}
+
+func TestLimiterForgetsIdleClients(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(60, 5)
+	l.Now = func() time.Time { return now }
+
+	l.Allow("203.0.113.7")
+	now = now.Add(2 * time.Minute)
+	l.Allow("198.51.100.1")
+	if _, ok := l.buckets["203.0.113.7"]; ok || len(l.buckets) != 1 {
+		t.Fatalf("expected the idle client to be forgotten, have %d buckets", len(l.buckets))
+	}
+}
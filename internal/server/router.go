@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"perfugo/internal/handlers"
 	applog "perfugo/internal/log"
@@ -11,16 +12,102 @@ import (
 func newRouter() http.Handler {
 	mux := http.NewServeMux()
 	applog.Debug(context.Background(), "registering http routes")
-	mux.HandleFunc("/healthz", handlers.Health)
-	applog.Debug(context.Background(), "route registered", "path", "/healthz")
+	mux.Handle("/healthz", handlers.RequireProbeAccess(http.HandlerFunc(handlers.Health)))
+	applog.Debug(context.Background(), "route registered", "path", "/healthz", "probe", true)
+	mux.Handle("/readyz", handlers.RequireProbeAccess(http.HandlerFunc(handlers.Ready)))
+	applog.Debug(context.Background(), "route registered", "path", "/readyz", "probe", true)
+	mux.Handle("/metrics", handlers.RequireProbeAccess(http.HandlerFunc(handlers.Metrics)))
+	applog.Debug(context.Background(), "route registered", "path", "/metrics", "probe", true)
+	mux.HandleFunc("/version", handlers.Version)
+	applog.Debug(context.Background(), "route registered", "path", "/version")
+	mux.HandleFunc("/maintenance", handlers.Maintenance)
+	applog.Debug(context.Background(), "route registered", "path", "/maintenance")
 	mux.HandleFunc("/login", handlers.Login)
 	applog.Debug(context.Background(), "route registered", "path", "/login")
 	mux.HandleFunc("/signup", handlers.Signup)
 	applog.Debug(context.Background(), "route registered", "path", "/signup")
 	mux.HandleFunc("/logout", handlers.Logout)
+	mux.HandleFunc("/feed/library", handlers.LibraryFeed)
+	applog.Debug(context.Background(), "route registered", "path", "/feed/library")
+	mux.HandleFunc("/feed/calendar.ics", handlers.CalendarFeed)
+	applog.Debug(context.Background(), "route registered", "path", "/feed/calendar.ics")
+	mux.Handle("/api/gallery/formulas", handlers.LimitGalleryRequests(http.HandlerFunc(handlers.GalleryFormulas)))
+	applog.Debug(context.Background(), "route registered", "path", "/api/gallery/formulas", "rateLimited", true)
+	mux.Handle("/api/gallery/formulas/{id}", handlers.LimitGalleryRequests(http.HandlerFunc(handlers.GalleryFormula)))
+	applog.Debug(context.Background(), "route registered", "path", "/api/gallery/formulas/{id}", "rateLimited", true)
+	mux.Handle("/hooks/v1/ingredients", handlers.RequireAPIToken(http.HandlerFunc(handlers.HookIngredientCreate)))
+	applog.Debug(context.Background(), "route registered", "path", "/hooks/v1/ingredients", "token", true)
+	mux.Handle("/hooks/v1/evaluation-notes", handlers.RequireAPIToken(http.HandlerFunc(handlers.HookEvaluationNote)))
+	applog.Debug(context.Background(), "route registered", "path", "/hooks/v1/evaluation-notes", "token", true)
+
+	mux.HandleFunc("/auth/oidc/login", handlers.OIDCLogin)
+	mux.HandleFunc("/auth/oidc/callback", handlers.OIDCCallback)
+
+	mux.Handle("/scim/v2/Users", handlers.RequireSCIMToken(http.HandlerFunc(handlers.SCIMUsers)))
+	mux.Handle("/scim/v2/Users/", handlers.RequireSCIMToken(http.HandlerFunc(handlers.SCIMUsers)))
 	applog.Debug(context.Background(), "route registered", "path", "/logout")
 	mux.Handle("/app/preferences", handlers.RequireAuthentication(http.HandlerFunc(handlers.Preferences)))
 	applog.Debug(context.Background(), "route registered", "path", "/app/preferences", "protected", true)
+	mux.Handle("/app/preferences/production", handlers.RequireAuthentication(http.HandlerFunc(handlers.ProductionPreferences)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/production", "protected", true)
+	mux.Handle("/app/preferences/print", handlers.RequireAuthentication(http.HandlerFunc(handlers.PrintPreferences)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/print", "protected", true)
+	mux.Handle("/app/preferences/numbers", handlers.RequireAuthentication(http.HandlerFunc(handlers.NumberPreferences)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/numbers", "protected", true)
+	mux.Handle("/app/preferences/landing", handlers.RequireAuthentication(http.HandlerFunc(handlers.LandingPreferences)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/landing", "protected", true)
+	mux.Handle("/app/preferences/digest", handlers.RequireAuthentication(http.HandlerFunc(handlers.DigestPreferences)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/digest", "protected", true)
+	mux.Handle("/app/preferences/shortcuts", handlers.RequireAuthentication(http.HandlerFunc(handlers.ShortcutPreferences)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/shortcuts", "protected", true)
+	mux.Handle("/app/preferences/feed", handlers.RequireAuthentication(handlers.DenyWhileImpersonating(http.HandlerFunc(handlers.LibraryFeedPreferences))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/feed", "protected", true)
+	mux.Handle("/app/preferences/calendar", handlers.RequireAuthentication(handlers.DenyWhileImpersonating(http.HandlerFunc(handlers.CalendarFeedPreferences))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/calendar", "protected", true)
+	mux.Handle("/app/preferences/api-tokens", handlers.RequireAuthentication(handlers.DenyWhileImpersonating(http.HandlerFunc(handlers.APITokenCreate))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/api-tokens", "protected", true)
+	mux.Handle("/app/preferences/api-tokens/revoke", handlers.RequireAuthentication(handlers.DenyWhileImpersonating(http.HandlerFunc(handlers.APITokenRevoke))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/api-tokens/revoke", "protected", true)
+	mux.Handle("/app/preferences/avatar", handlers.RequireAuthentication(http.HandlerFunc(handlers.AvatarUpload)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/avatar", "protected", true)
+	mux.Handle("/app/preferences/avatar/delete", handlers.RequireAuthentication(http.HandlerFunc(handlers.AvatarDelete)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/preferences/avatar/delete", "protected", true)
+	mux.Handle("/app/avatar", handlers.RequireAuthentication(http.HandlerFunc(handlers.Avatar)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/avatar", "protected", true)
+	mux.Handle("/app/notifications/stream", handlers.RequireAuthentication(http.HandlerFunc(handlers.NotificationStream)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/notifications/stream", "protected", true)
+	mux.Handle("/app/admin/maintenance", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.MaintenanceToggle))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/maintenance", "protected", true, "admin", true)
+	mux.Handle("/app/admin/invitations", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.InvitationCreate))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/invitations", "protected", true, "admin", true)
+	mux.Handle("/app/admin/imports", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.ImportSchedules))))
+	mux.Handle("/app/admin/imports/run", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.ImportScheduleRun))))
+	mux.Handle("/app/admin/imports/delete", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.ImportScheduleDelete))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/imports", "protected", true, "admin", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/imports/run", "protected", true, "admin", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/imports/delete", "protected", true, "admin", true)
+	mux.Handle("/app/admin/blocklist", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.BlocklistAdd))))
+	mux.Handle("/app/admin/blocklist/import", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.BlocklistImport))))
+	mux.Handle("/app/admin/blocklist/delete", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.BlocklistDelete))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/blocklist", "protected", true, "admin", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/blocklist/import", "protected", true, "admin", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/blocklist/delete", "protected", true, "admin", true)
+	mux.Handle("/app/admin/taxonomy", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.TaxonomyAdd))))
+	mux.Handle("/app/admin/taxonomy/review", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.TaxonomyReview))))
+	mux.Handle("/app/admin/taxonomy/delete", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.TaxonomyDelete))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/taxonomy", "protected", true, "admin", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/taxonomy/review", "protected", true, "admin", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/taxonomy/delete", "protected", true, "admin", true)
+	mux.Handle("/app/admin/retention/run", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.RetentionRun))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/retention/run", "protected", true, "admin", true)
+	mux.Handle("/app/admin/impersonate", handlers.RequireAuthentication(handlers.RequireAdmin(http.HandlerFunc(handlers.ImpersonationStart))))
+	applog.Debug(context.Background(), "route registered", "path", "/app/admin/impersonate", "protected", true, "admin", true)
+	mux.Handle("/app/impersonate/stop", handlers.RequireAuthentication(http.HandlerFunc(handlers.ImpersonationStop)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/impersonate/stop", "protected", true)
+	mux.Handle("/app/taxonomy/terms", handlers.RequireAuthentication(http.HandlerFunc(handlers.TaxonomyTerms)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/taxonomy/terms", "protected", true)
+	mux.Handle("/app/taxonomy/suggest", handlers.RequireAuthentication(http.HandlerFunc(handlers.TaxonomySuggest)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/taxonomy/suggest", "protected", true)
 	mux.Handle("/app", handlers.RequireAuthentication(http.HandlerFunc(handlers.Dashboard)))
 	mux.Handle("/app/", handlers.RequireAuthentication(http.HandlerFunc(handlers.Dashboard)))
 	applog.Debug(context.Background(), "route registered", "path", "/app", "protected", true)
@@ -31,8 +118,33 @@ func newRouter() http.Handler {
 	mux.Handle("/app/sections/ingredients/new", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientNew)))
 	mux.Handle("/app/sections/ingredients/create", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientCreate)))
 	mux.Handle("/app/sections/ingredients/delete", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientDelete)))
+	mux.Handle("/app/sections/ingredients/undo", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientUndo)))
+	mux.Handle("/app/sections/ingredients/cards", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientCueCards)))
+	mux.Handle("/app/sections/ingredients/publish", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientPublish)))
+	mux.Handle("/app/sections/ingredients/transfer", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientTransfer)))
+	mux.Handle("/app/sections/ingredients/note", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientNoteSave)))
+	mux.Handle("/app/sections/ingredients/price-history", handlers.RequireAuthentication(http.HandlerFunc(handlers.IngredientPriceHistory)))
 	mux.Handle("/app/sections/tools/import", handlers.RequireAuthentication(http.HandlerFunc(handlers.ToolsImportIngredient)))
+	mux.Handle("/app/sections/tools/accord", handlers.RequireAuthentication(http.HandlerFunc(handlers.AccordBuilder)))
+	mux.Handle("/app/sections/tools/accord/promote", handlers.RequireAuthentication(http.HandlerFunc(handlers.AccordPromote)))
+	mux.Handle("/app/sections/tools/organ", handlers.RequireAuthentication(http.HandlerFunc(handlers.OrganPanel)))
+	mux.Handle("/app/sections/tools/organ/resize", handlers.RequireAuthentication(http.HandlerFunc(handlers.OrganResize)))
+	mux.Handle("/app/sections/tools/organ/assign", handlers.RequireAuthentication(http.HandlerFunc(handlers.OrganAssign)))
+	mux.Handle("/app/sections/tools/organ/print", handlers.RequireAuthentication(http.HandlerFunc(handlers.OrganPrint)))
+	mux.Handle("/app/sections/tools/stale-profiles", handlers.RequireAuthentication(http.HandlerFunc(handlers.StaleProfiles)))
+	mux.Handle("/app/sections/tools/stale-profiles/reenrich", handlers.RequireAuthentication(http.HandlerFunc(handlers.StaleProfileReenrich)))
+	mux.Handle("/app/sections/tools/notes-search", handlers.RequireAuthentication(http.HandlerFunc(handlers.NotesSearch)))
 	mux.Handle("/app/sections/tools/import-formula", handlers.RequireAuthentication(http.HandlerFunc(handlers.ToolsImportFormula)))
+	mux.Handle("/app/sections/tools/import-formula-json", handlers.RequireAuthentication(http.HandlerFunc(handlers.ToolsImportFormulaJSON)))
+	mux.Handle("/app/api/aroma-chemicals/lookup", handlers.RequireAuthenticationOrAPIToken(http.HandlerFunc(handlers.AliasLookup)))
+	mux.Handle("/app/api/aroma-chemicals/{id}", handlers.RequireAuthenticationOrAPIToken(http.HandlerFunc(handlers.AromaChemicalResource)))
+	mux.Handle("/app/api/aroma-chemicals/{id}/audit", handlers.RequireAuthenticationOrAPIToken(http.HandlerFunc(handlers.AromaChemicalAudit)))
+	mux.Handle("/app/api/formulas/{id}/ingredients", handlers.RequireAuthenticationOrAPIToken(http.HandlerFunc(handlers.FormulaIngredients)))
+	mux.Handle("/app/api/formulas/{id}/audit", handlers.RequireAuthenticationOrAPIToken(http.HandlerFunc(handlers.FormulaAudit)))
+	mux.Handle("/app/api/reports/batch", handlers.RequireAuthenticationOrAPIToken(http.HandlerFunc(handlers.BatchReportAPI)))
+	mux.Handle("/app/sections/tools/substitutions", handlers.RequireAuthentication(http.HandlerFunc(handlers.Substitutions)))
+	mux.Handle("/app/sections/tools/substitutions/update", handlers.RequireAuthentication(http.HandlerFunc(handlers.SubstitutionUpdate)))
+	mux.Handle("/app/sections/tools/substitutions/delete", handlers.RequireAuthentication(http.HandlerFunc(handlers.SubstitutionDelete)))
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/table", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/detail", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/edit", "protected", true)
@@ -40,27 +152,144 @@ func newRouter() http.Handler {
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/new", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/create", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/delete", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/undo", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/cards", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/publish", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/transfer", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/note", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/ingredients/price-history", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/import", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/accord", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/accord/promote", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/organ", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/organ/resize", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/organ/assign", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/organ/print", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/stale-profiles", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/stale-profiles/reenrich", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/notes-search", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/import-formula", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/import-formula-json", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/api/aroma-chemicals/lookup", "protected", true, "token", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/api/aroma-chemicals/{id}", "protected", true, "token", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/api/aroma-chemicals/{id}/audit", "protected", true, "token", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/api/formulas/{id}/ingredients", "protected", true, "token", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/api/formulas/{id}/audit", "protected", true, "token", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/api/reports/batch", "protected", true, "token", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/substitutions", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/substitutions/update", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/tools/substitutions/delete", "protected", true)
 	mux.Handle("/app/sections/formulas/list", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaList)))
 	mux.Handle("/app/sections/formulas/detail", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaDetail)))
 	mux.Handle("/app/sections/formulas/create", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaCreate)))
 	mux.Handle("/app/sections/formulas/edit", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaEdit)))
 	mux.Handle("/app/sections/formulas/update", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaUpdate)))
+	mux.Handle("/app/sections/formulas/validate", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaValidate)))
+	mux.Handle("/app/sections/formulas/totals", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaTotals)))
 	mux.Handle("/app/sections/formulas/ingredient-row", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaIngredientRow)))
+	mux.Handle("/app/sections/formulas/ingredient-options", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaIngredientOptions)))
+	mux.Handle("/app/sections/formulas/print", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaPrint)))
+	mux.Handle("/app/sections/formulas/export", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaExport)))
+	mux.Handle("/app/sections/formulas/similar", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaSimilar)))
+	mux.Handle("/app/sections/formulas/reorder", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaReorder)))
 	mux.Handle("/app/sections/formulas/delete", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaDelete)))
+	mux.Handle("/app/sections/formulas/undo", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaUndo)))
+	mux.Handle("/app/sections/formulas/board", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaBoard)))
+	mux.Handle("/app/sections/formulas/board/move", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaBoardMove)))
+	mux.Handle("/app/sections/formulas/submit", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaSubmit)))
+	mux.Handle("/app/sections/formulas/approve", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaApprove)))
+	mux.Handle("/app/sections/formulas/return", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaReturn)))
+	mux.Handle("/app/sections/formulas/transfer", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaTransfer)))
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/list", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/detail", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/create", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/edit", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/update", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/validate", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/totals", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/ingredient-row", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/ingredient-options", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/print", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/export", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/similar", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/reorder", "protected", true)
 	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/delete", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/undo", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/board", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/submit", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/approve", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/return", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/transfer", "protected", true)
+	mux.Handle("/app/sections/formulas/compliance", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaCompliance)))
+	mux.Handle("/app/sections/formulas/allergens", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaAllergens)))
+	mux.Handle("/app/sections/formulas/inci", handlers.RequireAuthentication(http.HandlerFunc(handlers.FormulaINCI)))
+	mux.Handle("/app/sections/formulas/matrix", handlers.RequireAuthentication(http.HandlerFunc(handlers.ExperimentMatrix)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/board/move", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/compliance", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/allergens", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/inci", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/matrix", "protected", true)
+	mux.Handle("/app/sections/formulas/blind-tests", handlers.RequireAuthentication(http.HandlerFunc(handlers.BlindTests)))
+	mux.Handle("/app/sections/formulas/blind-tests/view", handlers.RequireAuthentication(http.HandlerFunc(handlers.BlindTestView)))
+	mux.Handle("/app/sections/formulas/blind-tests/score", handlers.RequireAuthentication(http.HandlerFunc(handlers.BlindTestScore)))
+	mux.Handle("/app/sections/formulas/blind-tests/reveal", handlers.RequireAuthentication(http.HandlerFunc(handlers.BlindTestReveal)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/blind-tests", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/blind-tests/view", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/blind-tests/score", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/sections/formulas/blind-tests/reveal", "protected", true)
 	mux.Handle("/app/reports/batch-production", handlers.RequireAuthentication(http.HandlerFunc(handlers.GenerateBatchProductionReport)))
+	mux.Handle("/app/reports/shopping-list", handlers.RequireAuthentication(http.HandlerFunc(handlers.ShoppingList)))
+	mux.Handle("/app/reports/inventory", handlers.RequireAuthentication(http.HandlerFunc(handlers.InventoryUpdate)))
+	mux.Handle("/app/reports/wishlist", handlers.RequireAuthentication(http.HandlerFunc(handlers.WishlistUpdate)))
+	mux.Handle("/app/reports/wishlist/remove", handlers.RequireAuthentication(http.HandlerFunc(handlers.WishlistRemove)))
+	mux.Handle("/app/reports/pick-list", handlers.RequireAuthentication(http.HandlerFunc(handlers.PickList)))
+	mux.Handle("/app/reports/batch-presets", handlers.RequireAuthentication(http.HandlerFunc(handlers.BatchPresetSave)))
+	mux.Handle("/app/reports/batch-presets/delete", handlers.RequireAuthentication(http.HandlerFunc(handlers.BatchPresetDelete)))
+	mux.Handle("/app/reports/custom", handlers.RequireAuthentication(http.HandlerFunc(handlers.CustomReportSave)))
+	mux.Handle("/app/reports/custom/delete", handlers.RequireAuthentication(http.HandlerFunc(handlers.CustomReportDelete)))
+	mux.Handle("/app/reports/custom/run", handlers.RequireAuthentication(http.HandlerFunc(handlers.CustomReportRun)))
 	applog.Debug(context.Background(), "route registered", "path", "/app/reports/batch-production", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/shopping-list", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/inventory", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/wishlist", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/wishlist/remove", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/pick-list", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/batch-presets", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/batch-presets/delete", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/custom", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/custom/delete", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/reports/custom/run", "protected", true)
+	mux.Handle("/app/production/batches", handlers.RequireAuthentication(http.HandlerFunc(handlers.ProductionBatchStart)))
+	mux.Handle("/app/production/batch", handlers.RequireAuthentication(http.HandlerFunc(handlers.ProductionBatchView)))
+	mux.Handle("/app/production/variance", handlers.RequireAuthentication(http.HandlerFunc(handlers.BatchVariance)))
+	mux.Handle("/app/production/batch/weigh", handlers.RequireAuthentication(http.HandlerFunc(handlers.ProductionBatchWeigh)))
+	mux.Handle("/app/production/batch/finalize", handlers.RequireAuthentication(http.HandlerFunc(handlers.ProductionBatchFinalize)))
+	mux.Handle("/app/production/batch/labels", handlers.RequireAuthentication(http.HandlerFunc(handlers.BatchLabels)))
+	mux.Handle("/app/production/batch/labels/print", handlers.RequireAuthentication(http.HandlerFunc(handlers.BatchLabelsPrint)))
+	applog.Debug(context.Background(), "route registered", "path", "/app/production/batches", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/production/batch", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/production/variance", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/production/batch/weigh", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/production/batch/finalize", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/production/batch/labels", "protected", true)
+	applog.Debug(context.Background(), "route registered", "path", "/app/production/batch/labels/print", "protected", true)
 	mux.HandleFunc("/", handlers.Home)
 	applog.Debug(context.Background(), "route registered", "path", "/")
 	mux.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("web/static"))))
 	applog.Debug(context.Background(), "route registered", "path", "/assets/", "static", true)
 	return mux
 }
+
+// mountRouter serves handler under basePath, so a route registered as /app
+// answers at /perfugo/app. Requests outside the base path are not found.
+func mountRouter(handler http.Handler, basePath string) http.Handler {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return handler
+	}
+	basePath = "/" + basePath
+	root := http.NewServeMux()
+	root.Handle(basePath+"/", http.StripPrefix(basePath, handler))
+	applog.Debug(context.Background(), "routes mounted under base path", "basePath", basePath)
+	return root
+}
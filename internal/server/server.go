@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -10,24 +11,105 @@ import (
 	"gorm.io/gorm"
 
 	"perfugo/internal/ai"
+	"perfugo/internal/captcha"
 	"perfugo/internal/handlers"
+	"perfugo/internal/jobs"
+	"perfugo/internal/labels"
+	"perfugo/internal/ldap"
 	applog "perfugo/internal/log"
+	"perfugo/internal/mail"
+	"perfugo/internal/notify"
+	"perfugo/internal/oidc"
+	"perfugo/internal/onboarding"
+	"perfugo/internal/storage"
+	"perfugo/models"
 )
 
 // Config captures the runtime configuration for the HTTP server.
 type Config struct {
-	Addr     string
-	Session  SessionConfig
-	Database *gorm.DB
-	AIClient *ai.Client
+	Addr            string
+	Session         SessionConfig
+	Database        *gorm.DB
+	AIClient        ai.Client
+	MaintenanceMode bool
+	InviteOnly      bool
+	Captcha         *captcha.Verifier
+	Mailer          mail.Sender
+	// Storage keeps uploaded files; uploads are disabled when nil.
+	Storage storage.Store
+	// LabelPrinter receives batch labels; they can only be downloaded when nil.
+	LabelPrinter *labels.Printer
+	// OnboardingTemplate seeds new accounts when set.
+	OnboardingTemplate *onboarding.Template
+	OIDCProvider       *oidc.Provider
+	SCIMToken          string
+	// LDAPAuthenticator switches Login to the LDAP credentials backend when set.
+	LDAPAuthenticator *ldap.Authenticator
+	Timeouts          TimeoutConfig
+	// Quotas caps what each user may create; zero limits are not enforced.
+	Quotas QuotaConfig
+	// Retention is the policy the retention job enforces, shown to admins.
+	Retention jobs.RetentionPolicy
+	// Probes decides who may reach /healthz and /readyz.
+	Probes ProbeConfig
+	// Gallery throttles the public gallery API; zero limits keep the default.
+	Gallery GalleryConfig
+	// StaleProfiles decides which AI-generated profiles are due for a refresh.
+	StaleProfiles StaleProfileConfig
+	// BasePath serves every route, link and redirect under a prefix such as
+	// "/perfugo"; empty serves from the root.
+	BasePath string
+	// TrustedProxies are the reverse proxies whose X-Forwarded-* headers
+	// decide the client address, scheme and host.
+	TrustedProxies []netip.Prefix
 }
 
-// SessionConfig controls session behavior for the HTTP server.
+// SessionConfig controls session behavior for the HTTP server. A zero
+// CookieSameSite means Lax and an empty CookiePath means the base path.
 type SessionConfig struct {
-	Lifetime     time.Duration
-	CookieName   string
-	CookieDomain string
-	CookieSecure bool
+	Lifetime          time.Duration
+	CookieName        string
+	CookieDomain      string
+	CookiePath        string
+	CookieSecure      bool
+	CookieSameSite    http.SameSite
+	CookiePartitioned bool
+}
+
+// TimeoutConfig bounds slow handlers such as AI calls, reports and imports.
+type TimeoutConfig struct {
+	AI     time.Duration
+	Report time.Duration
+	Import time.Duration
+}
+
+// ProbeConfig lists the probe paths open without authentication, the
+// networks probes are restricted to and the Bearer token for the others.
+type ProbeConfig struct {
+	PublicPaths     []string
+	AllowedNetworks []netip.Prefix
+	Token           string
+}
+
+// GalleryConfig lets each client make PerMinute gallery API requests a
+// minute after a burst of Burst.
+type GalleryConfig struct {
+	PerMinute int
+	Burst     int
+}
+
+// StaleProfileConfig lists AI profiles older than Months, or generated by
+// one of DeprecatedModels, in the stale profile report.
+type StaleProfileConfig struct {
+	Months           int
+	DeprecatedModels []string
+}
+
+// QuotaConfig caps the formulas, ingredients and stored bytes per user.
+type QuotaConfig struct {
+	MaxFormulas     int
+	MaxIngredients  int
+	MaxStorageBytes int64
 }
 
 // Server wraps an http.Server and exposes helpers for bootstrapping a
@@ -54,6 +136,12 @@ func New(cfg Config) (*Server, error) {
 		applog.Debug(context.Background(), "session cookie name not provided, using default")
 		sessionCfg.CookieName = "perfugo_session"
 	}
+	if sessionCfg.CookieSameSite == 0 {
+		sessionCfg.CookieSameSite = http.SameSiteLaxMode
+	}
+	if strings.TrimSpace(sessionCfg.CookiePath) == "" {
+		sessionCfg.CookiePath = "/" + strings.Trim(strings.TrimSpace(cfg.BasePath), "/")
+	}
 
 	sessionManager := scs.New()
 	sessionManager.Lifetime = sessionCfg.Lifetime
@@ -61,31 +149,80 @@ func New(cfg Config) (*Server, error) {
 	sessionManager.Cookie.Domain = sessionCfg.CookieDomain
 	sessionManager.Cookie.HttpOnly = true
 	sessionManager.Cookie.Persist = true
-	sessionManager.Cookie.SameSite = http.SameSiteLaxMode
+	sessionManager.Cookie.Path = sessionCfg.CookiePath
+	sessionManager.Cookie.SameSite = sessionCfg.CookieSameSite
 	sessionManager.Cookie.Secure = sessionCfg.CookieSecure
+	sessionManager.Cookie.Partitioned = sessionCfg.CookiePartitioned
 
 	applog.Debug(context.Background(), "session manager configured",
 		"cookieName", sessionCfg.CookieName,
 		"cookieDomain", sessionCfg.CookieDomain,
+		"cookiePath", sessionCfg.CookiePath,
 		"cookieSecure", sessionCfg.CookieSecure,
+		"cookiePartitioned", sessionCfg.CookiePartitioned,
 	)
 
 	handlers.Configure(sessionManager, cfg.Database)
+	handlers.ConfigureBasePath(cfg.BasePath)
+	handlers.ConfigureTrustedProxies(cfg.TrustedProxies)
 	handlers.ConfigureAI(cfg.AIClient)
+	handlers.ConfigureStaleProfiles(handlers.StaleProfilePolicy{
+		Months:           cfg.StaleProfiles.Months,
+		DeprecatedModels: cfg.StaleProfiles.DeprecatedModels,
+	})
+	handlers.SetMaintenanceMode(cfg.MaintenanceMode)
+	handlers.SetInviteOnly(cfg.InviteOnly)
+	handlers.ConfigureCaptcha(cfg.Captcha)
+	handlers.ConfigureMail(cfg.Mailer)
+	handlers.ConfigureStorage(cfg.Storage)
+	handlers.ConfigureLabelPrinter(cfg.LabelPrinter)
+	hub := notify.NewHub()
+	handlers.ConfigureNotifications(hub)
+	handlers.ConfigureOnboarding(cfg.OnboardingTemplate)
+	handlers.ConfigureOIDC(cfg.OIDCProvider)
+	handlers.ConfigureSCIM(cfg.SCIMToken)
+	handlers.ConfigureLDAP(cfg.LDAPAuthenticator)
+	handlers.ConfigureTimeouts(handlers.Timeouts{
+		AI:     cfg.Timeouts.AI,
+		Report: cfg.Timeouts.Report,
+		Import: cfg.Timeouts.Import,
+	})
+	handlers.ConfigureQuotas(handlers.Quotas{
+		MaxFormulas:     cfg.Quotas.MaxFormulas,
+		MaxIngredients:  cfg.Quotas.MaxIngredients,
+		MaxStorageBytes: cfg.Quotas.MaxStorageBytes,
+	})
+	handlers.ConfigureRetention(cfg.Retention)
+	if cfg.Probes.PublicPaths != nil || cfg.Probes.AllowedNetworks != nil || cfg.Probes.Token != "" {
+		handlers.ConfigureProbes(handlers.ProbeAccess{
+			Public:   cfg.Probes.PublicPaths,
+			Networks: cfg.Probes.AllowedNetworks,
+			Token:    cfg.Probes.Token,
+		})
+	}
+
+	if cfg.Gallery.PerMinute > 0 {
+		handlers.ConfigureGalleryRateLimit(cfg.Gallery.PerMinute, cfg.Gallery.Burst)
+	}
 
 	applog.Debug(context.Background(), "handler dependencies configured")
 
-	handler := sessionManager.LoadAndSave(newRouter())
+	handler := handlers.RequestID(handlers.ForwardedHeaders(sessionManager.LoadAndSave(mountRouter(handlers.HTMXErrors(handlers.MaintenanceGate(newRouter())), cfg.BasePath))))
 
 	applog.Debug(context.Background(), "http handler chain prepared")
 
+	httpServer := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	// Notification streams never go idle, so they are ended explicitly or
+	// Shutdown would wait for them until its deadline.
+	httpServer.RegisterOnShutdown(hub.Close)
+
 	return &Server{
-		config: cfg,
-		httpServer: &http.Server{
-			Addr:              cfg.Addr,
-			Handler:           handler,
-			ReadHeaderTimeout: 5 * time.Second,
-		},
+		config:     cfg,
+		httpServer: httpServer,
 	}, nil
 }
 
@@ -103,6 +240,19 @@ func (s *Server) Stop() error {
 	return s.httpServer.Shutdown(ctx)
 }
 
+// SendExpiryAlert notifies a user about expiring inventory through the
+// notification hub and mailer installed by New. It is the alert behind the
+// shelf-life job.
+func SendExpiryAlert(ctx context.Context, ownerID uint, items []models.InventoryItem) error {
+	return handlers.SendExpiryAlert(ctx, ownerID, items)
+}
+
+// SendWeeklyDigest emails a user's weekly digest through the mailer
+// installed by New. It is the sender behind the weekly digest job.
+func SendWeeklyDigest(ctx context.Context, digest jobs.WeeklyDigest) error {
+	return handlers.SendWeeklyDigest(ctx, digest)
+}
+
 // Handler exposes the configured HTTP handler, enabling integration tests.
 func (s *Server) Handler() http.Handler {
 	applog.Debug(context.Background(), "server handler requested")
@@ -16,42 +16,129 @@ import (
 )
 
 func TestNewAppliesSessionDefaults(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	cfg := Config{Addr: ":8080", Session: SessionConfig{CookieSecure: true}, Database: newLoginTestDB(t)}
+	srv, err := New(cfg)
 	if err != nil {
-		t.Fatalf("failed to open sqlite database: %v", err)
+		t.Fatalf("New() returned error: %v", err)
 	}
 	t.Cleanup(func() {
-		if sqlDB, err := db.DB(); err == nil {
-			sqlDB.Close()
-		}
+		handlers.Configure(nil, nil)
 	})
-	if err := db.AutoMigrate(&models.User{}); err != nil {
-		t.Fatalf("failed to migrate schema: %v", err)
+
+	if srv.httpServer.Addr != ":8080" {
+		t.Fatalf("expected server addr :8080, got %q", srv.httpServer.Addr)
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	if srv.httpServer.Handler == nil {
+		t.Fatal("expected handler to be configured")
+	}
+
+	cookie := loginCookie(t, srv)
+	if cookie.Name != "perfugo_session" {
+		t.Fatalf("expected default session cookie name, got %q", cookie.Name)
+	}
+	if !cookie.Secure {
+		t.Fatal("expected cookie secure flag to be true")
+	}
+	if cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/" || cookie.Partitioned {
+		t.Fatalf("expected a Lax, unpartitioned cookie on /, got %+v", cookie)
+	}
+}
+
+func TestNewAppliesSessionCookieAttributes(t *testing.T) {
+	cfg := Config{Addr: ":8080", Database: newLoginTestDB(t), Session: SessionConfig{
+		CookieSecure:      true,
+		CookiePath:        "/perfugo",
+		CookieSameSite:    http.SameSiteNoneMode,
+		CookiePartitioned: true,
+	}}
+	srv, err := New(cfg)
 	if err != nil {
-		t.Fatalf("failed to hash password: %v", err)
+		t.Fatalf("New() returned error: %v", err)
 	}
-	if err := db.Create(&models.User{Email: "user@example.com", PasswordHash: string(hash), Theme: models.DefaultTheme}).Error; err != nil {
-		t.Fatalf("failed to seed user: %v", err)
+	t.Cleanup(func() {
+		handlers.Configure(nil, nil)
+	})
+
+	cookie := loginCookie(t, srv)
+	if cookie.SameSite != http.SameSiteNoneMode || cookie.Path != "/perfugo" || !cookie.Partitioned {
+		t.Fatalf("expected an embeddable cookie on /perfugo, got %+v", cookie)
 	}
+}
 
-	cfg := Config{Addr: ":8080", Session: SessionConfig{CookieSecure: true}, Database: db}
+func TestNewServesUnderBasePath(t *testing.T) {
+	cfg := Config{Addr: ":8080", BasePath: "/perfugo/", Database: newLoginTestDB(t), Session: SessionConfig{CookieSecure: true}}
 	srv, err := New(cfg)
 	if err != nil {
 		t.Fatalf("New() returned error: %v", err)
 	}
 	t.Cleanup(func() {
 		handlers.Configure(nil, nil)
+		handlers.ConfigureBasePath("")
 	})
 
-	if srv.httpServer.Addr != ":8080" {
-		t.Fatalf("expected server addr :8080, got %q", srv.httpServer.Addr)
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
 	}
-	if srv.httpServer.Handler == nil {
-		t.Fatal("expected handler to be configured")
+	if rr := get("/perfugo/healthz"); rr.Code != http.StatusOK {
+		t.Fatalf("expected /perfugo/healthz to return 200, got %d", rr.Code)
+	}
+	if rr := get("/healthz"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected routes outside the base path to be missing, got %d", rr.Code)
+	}
+	if rr := get("/perfugo/login"); !strings.Contains(rr.Body.String(), `href="/perfugo/signup"`) {
+		t.Fatal("expected links on the login page to carry the base path")
+	}
+	if rr := get("/perfugo/app"); rr.Header().Get("Location") != "/perfugo/login" {
+		t.Fatalf("expected a redirect to /perfugo/login, got %q", rr.Header().Get("Location"))
 	}
 
+	data := url.Values{}
+	data.Set("email", "user@example.com")
+	data.Set("password", "password123")
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/perfugo/login", strings.NewReader(data.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Header().Get("Location") != "/perfugo/app" {
+		t.Fatalf("expected login to redirect to /perfugo/app, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+	if cookies := rr.Result().Cookies(); len(cookies) == 0 || cookies[0].Path != "/perfugo" {
+		t.Fatalf("expected the session cookie to be scoped to the base path, got %+v", cookies)
+	}
+}
+
+// newLoginTestDB returns a database holding user@example.com with the
+// password "password123".
+func newLoginTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := db.Create(&models.User{Email: "user@example.com", PasswordHash: string(hash), Theme: models.DefaultTheme}).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+	return db
+}
+
+// loginCookie signs in as the newLoginTestDB user and returns the session
+// cookie the server set.
+func loginCookie(t *testing.T, srv *Server) *http.Cookie {
+	t.Helper()
 	data := url.Values{}
 	data.Set("email", "user@example.com")
 	data.Set("password", "password123")
@@ -67,12 +154,7 @@ func TestNewAppliesSessionDefaults(t *testing.T) {
 	if len(cookies) == 0 {
 		t.Fatal("expected session cookie to be set")
 	}
-	if cookies[0].Name != "perfugo_session" {
-		t.Fatalf("expected default session cookie name, got %q", cookies[0].Name)
-	}
-	if !cookies[0].Secure {
-		t.Fatal("expected cookie secure flag to be true")
-	}
+	return cookies[0]
 }
 
 func TestServerHandler(t *testing.T) {
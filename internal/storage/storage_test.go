@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFilesystemRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFilesystem: %v", err)
+	}
+
+	if err := store.Put(ctx, "avatars/1-abc.png", []byte("first")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := store.Put(ctx, "avatars/1-abc.png", []byte("second")); err != nil {
+		t.Fatalf("Put replace: %v", err)
+	}
+	data, err := store.Get(ctx, "avatars/1-abc.png")
+	if err != nil || string(data) != "second" {
+		t.Fatalf("Get = %q, %v", data, err)
+	}
+	if size, err := store.Size(ctx, "avatars/1-abc.png"); err != nil || size != 6 {
+		t.Fatalf("Size = %d, %v", size, err)
+	}
+
+	if err := store.Delete(ctx, "avatars/1-abc.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "avatars/1-abc.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after delete = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Size(ctx, "avatars/1-abc.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Size after delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "avatars/1-abc.png"); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+}
+
+func TestFilesystemRejectsEscapingKeys(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFilesystem: %v", err)
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "avatars/../../outside", "avatars//x"} {
+		if err := store.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Fatalf("Put(%q) succeeded, want error", key)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Package telemetry sends an anonymous, opt-in usage summary for the instance.
+//
+// Reports only ever contain the build version, aggregate record counts and
+// which optional features are switched on. Formula contents, ingredient data
+// and user details are never collected.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	applog "perfugo/internal/log"
+	"perfugo/internal/version"
+	"perfugo/models"
+)
+
+// Features records which optional capabilities are active on the instance.
+type Features struct {
+	AI              bool `json:"ai"`
+	AIMock          bool `json:"aiMock"`
+	MockDatabase    bool `json:"mockDatabase"`
+	MaintenanceMode bool `json:"maintenanceMode"`
+	CustomScales    bool `json:"customScales"`
+}
+
+// Counts holds aggregate record totals.
+type Counts struct {
+	Users          int64 `json:"users"`
+	Formulas       int64 `json:"formulas"`
+	AromaChemicals int64 `json:"aromaChemicals"`
+}
+
+// Report is the payload posted to the telemetry endpoint.
+type Report struct {
+	InstanceID string       `json:"instanceId"`
+	Version    version.Info `json:"version"`
+	Counts     Counts       `json:"counts"`
+	Features   Features     `json:"features"`
+	SentAt     time.Time    `json:"sentAt"`
+}
+
+// Reporter collects and sends telemetry reports.
+type Reporter struct {
+	Endpoint   string
+	InstanceID string
+	Features   Features
+	DB         *gorm.DB
+	HTTPClient *http.Client
+	Now        func() time.Time
+}
+
+// NewReporter builds a Reporter. A random instance identifier is generated
+// when instanceID is empty.
+func NewReporter(endpoint, instanceID string, db *gorm.DB, features Features) (*Reporter, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		return nil, errors.New("telemetry: endpoint must not be empty")
+	}
+	instanceID = strings.TrimSpace(instanceID)
+	if instanceID == "" {
+		generated, err := randomInstanceID()
+		if err != nil {
+			return nil, err
+		}
+		instanceID = generated
+	}
+	return &Reporter{
+		Endpoint:   endpoint,
+		InstanceID: instanceID,
+		Features:   features,
+		DB:         db,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		Now:        time.Now,
+	}, nil
+}
+
+// Collect assembles the report without sending it.
+func (r *Reporter) Collect(ctx context.Context) (Report, error) {
+	report := Report{
+		InstanceID: r.InstanceID,
+		Version:    version.Get(),
+		Features:   r.Features,
+		SentAt:     r.Now().UTC(),
+	}
+	if r.DB == nil {
+		return report, nil
+	}
+
+	counts := []struct {
+		model any
+		dest  *int64
+	}{
+		{&models.User{}, &report.Counts.Users},
+		{&models.Formula{}, &report.Counts.Formulas},
+		{&models.AromaChemical{}, &report.Counts.AromaChemicals},
+	}
+	for _, c := range counts {
+		if err := r.DB.WithContext(ctx).Model(c.model).Count(c.dest).Error; err != nil {
+			return Report{}, fmt.Errorf("telemetry: count records: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// Send collects a report and posts it to the configured endpoint.
+func (r *Reporter) Send(ctx context.Context) error {
+	report, err := r.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("telemetry: encode report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telemetry: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "perfugo/"+report.Version.Version)
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("telemetry: send report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry: endpoint responded with %s", resp.Status)
+	}
+
+	applog.Debug(ctx, "telemetry report sent", "instanceID", r.InstanceID, "status", resp.StatusCode)
+	return nil
+}
+
+func randomInstanceID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("telemetry: generate instance id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"perfugo/models"
+)
+
+func TestNewReporterRequiresEndpoint(t *testing.T) {
+	if _, err := NewReporter("  ", "", nil, Features{}); err == nil {
+		t.Fatal("expected error for empty endpoint")
+	}
+
+	reporter, err := NewReporter("https://telemetry.example", "", nil, Features{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reporter.InstanceID) != 32 {
+		t.Fatalf("expected generated instance id, got %q", reporter.InstanceID)
+	}
+}
+
+func TestSendPostsAggregateReport(t *testing.T) {
+	dsn := fmt.Sprintf("file:telemetry-test-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Formula{}, &models.AromaChemical{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	if err := db.Create(&models.Formula{Name: "Secret Accord", Notes: "confidential"}).Error; err != nil {
+		t.Fatalf("seed formula: %v", err)
+	}
+
+	var received []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		received = body
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	reporter, err := NewReporter(srv.URL, "instance-1", db, Features{AI: true})
+	if err != nil {
+		t.Fatalf("new reporter: %v", err)
+	}
+	if err := reporter.Send(context.Background()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if strings.Contains(string(received), "Secret Accord") || strings.Contains(string(received), "confidential") {
+		t.Fatalf("report leaked formula data: %s", received)
+	}
+
+	var report Report
+	if err := json.Unmarshal(received, &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if report.InstanceID != "instance-1" || report.Counts.Formulas != 1 || !report.Features.AI {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestSendFailsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	reporter, err := NewReporter(srv.URL, "instance-1", nil, Features{})
+	if err != nil {
+		t.Fatalf("new reporter: %v", err)
+	}
+	if err := reporter.Send(context.Background()); err == nil {
+		t.Fatal("expected error for failing endpoint")
+	}
+}
@@ -0,0 +1,34 @@
+// Package version exposes build metadata injected at link time, e.g.
+//
+//	go build -ldflags "-X perfugo/internal/version.Version=v1.2.3 \
+//		-X perfugo/internal/version.Commit=$(git rev-parse --short HEAD) \
+//		-X perfugo/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+// Values are overridden through -ldflags at build time.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info groups the build metadata for presentation.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+}
+
+// Get returns the build metadata for the running binary.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+	}
+}
+
+// String renders the metadata in a compact human readable form.
+func (i Info) String() string {
+	return i.Version + " (" + i.Commit + ", built " + i.BuildDate + ")"
+}
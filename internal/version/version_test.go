@@ -0,0 +1,20 @@
+package version
+
+import "testing"
+
+func TestGetReflectsLinkerVariables(t *testing.T) {
+	originalVersion, originalCommit, originalDate := Version, Commit, BuildDate
+	t.Cleanup(func() {
+		Version, Commit, BuildDate = originalVersion, originalCommit, originalDate
+	})
+
+	Version, Commit, BuildDate = "v1.2.3", "abc1234", "2024-01-02T03:04:05Z"
+
+	info := Get()
+	if info.Version != "v1.2.3" || info.Commit != "abc1234" || info.BuildDate != "2024-01-02T03:04:05Z" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if got, want := info.String(), "v1.2.3 (abc1234, built 2024-01-02T03:04:05Z)"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
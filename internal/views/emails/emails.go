@@ -0,0 +1,116 @@
+// Package emails renders transactional email. Each email pairs a templ
+// component for the HTML part with a plain-text fallback built from the same
+// data, so both stay in step with each other and with the app's view layer.
+package emails
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+
+	"perfugo/internal/mail"
+)
+
+// Email is a rendered-on-demand transactional message.
+type Email struct {
+	Subject string
+	Text    string
+	HTML    templ.Component
+}
+
+// Message renders the HTML part and addresses the email to to.
+func (e Email) Message(ctx context.Context, to string) (mail.Message, error) {
+	var html strings.Builder
+	if err := e.HTML.Render(ctx, &html); err != nil {
+		return mail.Message{}, fmt.Errorf("emails: render %q: %w", e.Subject, err)
+	}
+	return mail.Message{To: to, Subject: e.Subject, Text: e.Text, HTML: html.String()}, nil
+}
+
+// InvitationData describes a signup invitation.
+type InvitationData struct {
+	Link      string
+	ExpiresAt time.Time
+}
+
+// Invitation asks the recipient to create an account through a one-time link.
+func Invitation(data InvitationData) Email {
+	return Email{
+		Subject: "You're invited to Perfugo",
+		Text: fmt.Sprintf("You have been invited to join Perfugo.\n\nCreate your account here:\n%s\n\nThis link can be used once and expires on %s.\n",
+			data.Link, formatDate(data.ExpiresAt)),
+		HTML: invitationHTML(data),
+	}
+}
+
+// NotificationData is a general notice: a title, a few lines of detail and
+// an optional call to action.
+type NotificationData struct {
+	Title       string
+	Intro       string
+	Lines       []string
+	ActionLabel string
+	ActionURL   string
+}
+
+// Notification builds a notice email with the given subject.
+func Notification(subject string, data NotificationData) Email {
+	var text strings.Builder
+	text.WriteString(data.Title + "\n\n")
+	if data.Intro != "" {
+		text.WriteString(data.Intro + "\n\n")
+	}
+	for _, line := range data.Lines {
+		text.WriteString("- " + line + "\n")
+	}
+	if len(data.Lines) > 0 {
+		text.WriteString("\n")
+	}
+	if data.ActionURL != "" {
+		text.WriteString(data.ActionLabel + ":\n" + data.ActionURL + "\n")
+	}
+	return Email{Subject: subject, Text: text.String(), HTML: notificationHTML(data)}
+}
+
+// DigestSection is one heading of the weekly digest with its lines.
+type DigestSection struct {
+	Heading string
+	Lines   []string
+}
+
+// WeeklyDigestData fills the weekly digest. Sections without lines are
+// left out.
+type WeeklyDigestData struct {
+	Since    time.Time
+	Until    time.Time
+	Sections []DigestSection
+}
+
+// Period describes the week covered, such as "2 March 2026 – 9 March 2026".
+func (d WeeklyDigestData) Period() string {
+	return formatDate(d.Since) + " – " + formatDate(d.Until)
+}
+
+// WeeklyDigest is the opt-in summary of a member's week.
+func WeeklyDigest(data WeeklyDigestData) Email {
+	var text strings.Builder
+	text.WriteString("Your week in Perfugo\n" + data.Period() + "\n")
+	for _, section := range data.Sections {
+		if len(section.Lines) == 0 {
+			continue
+		}
+		text.WriteString("\n" + section.Heading + "\n")
+		for _, line := range section.Lines {
+			text.WriteString("- " + line + "\n")
+		}
+	}
+	text.WriteString("\nYou receive this because the weekly digest is on in your preferences.\n")
+	return Email{Subject: "Your week in Perfugo", Text: text.String(), HTML: weeklyDigestHTML(data)}
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2 January 2006")
+}
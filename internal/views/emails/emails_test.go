@@ -0,0 +1,87 @@
+package emails
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInvitationRendersBothParts(t *testing.T) {
+	t.Parallel()
+
+	email := Invitation(InvitationData{
+		Link:      "https://perfugo.example.com/signup?invite=abc&x=<y>",
+		ExpiresAt: time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC),
+	})
+	msg, err := email.Message(context.Background(), "guest@example.com")
+	if err != nil {
+		t.Fatalf("Message: %v", err)
+	}
+
+	if msg.To != "guest@example.com" || msg.Subject != "You're invited to Perfugo" {
+		t.Fatalf("unexpected envelope %+v", msg)
+	}
+	for _, part := range []string{msg.Text, msg.HTML} {
+		if !strings.Contains(part, "9 March 2025") {
+			t.Fatalf("expiry date missing from %q", part)
+		}
+	}
+	if !strings.Contains(msg.Text, "https://perfugo.example.com/signup?invite=abc&x=<y>") {
+		t.Fatalf("plain text should carry the raw link: %q", msg.Text)
+	}
+	if strings.Contains(msg.HTML, "<y>") {
+		t.Fatalf("link was not escaped in HTML: %q", msg.HTML)
+	}
+}
+
+func TestNotificationPlainTextFallback(t *testing.T) {
+	t.Parallel()
+
+	email := Notification("Materials expiring", NotificationData{
+		Title:       "Materials expiring soon",
+		Intro:       "These opened materials pass their shelf life this month.",
+		Lines:       []string{"Hedione · 12 May", "Iso E Super · 20 May"},
+		ActionLabel: "Review inventory",
+		ActionURL:   "https://perfugo.example.com/app/reports",
+	})
+
+	want := "Materials expiring soon\n\nThese opened materials pass their shelf life this month.\n\n- Hedione · 12 May\n- Iso E Super · 20 May\n\nReview inventory:\nhttps://perfugo.example.com/app/reports\n"
+	if email.Text != want {
+		t.Fatalf("Text = %q, want %q", email.Text, want)
+	}
+
+	msg, err := email.Message(context.Background(), "ada@example.com")
+	if err != nil {
+		t.Fatalf("Message: %v", err)
+	}
+	if !strings.Contains(msg.HTML, "<li>Iso E Super · 20 May</li>") {
+		t.Fatalf("HTML missing lines: %q", msg.HTML)
+	}
+}
+
+func TestWeeklyDigestSkipsEmptySections(t *testing.T) {
+	t.Parallel()
+
+	email := WeeklyDigest(WeeklyDigestData{
+		Since: time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+		Until: time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC),
+		Sections: []DigestSection{
+			{Heading: "Formulas edited", Lines: []string{"Chypre · v3"}},
+			{Heading: "Batches made"},
+			{Heading: "Running low", Lines: []string{"Hedione · 500 mg left"}},
+		},
+	})
+
+	want := "Your week in Perfugo\n2 March 2026 – 9 March 2026\n\nFormulas edited\n- Chypre · v3\n\nRunning low\n- Hedione · 500 mg left\n\nYou receive this because the weekly digest is on in your preferences.\n"
+	if email.Text != want {
+		t.Fatalf("Text = %q, want %q", email.Text, want)
+	}
+	msg, err := email.Message(context.Background(), "ada@example.com")
+	if err != nil {
+		t.Fatalf("Message: %v", err)
+	}
+	if strings.Contains(msg.HTML, "Batches made") || !strings.Contains(msg.HTML, "<li>Hedione · 500 mg left</li>") {
+		t.Fatalf("unexpected HTML: %q", msg.HTML)
+	}
+}
@@ -0,0 +1,32 @@
+package layout
+
+import "strings"
+
+// basePath is the prefix perfugo is served under, such as "/perfugo", or
+// empty when it is served from the root.
+var basePath string
+
+// SetBasePath sets the prefix every link rendered by the views starts with.
+// Surrounding slashes are ignored, so "/perfugo/" and "perfugo" are the same.
+func SetBasePath(path string) {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		basePath = ""
+		return
+	}
+	basePath = "/" + path
+}
+
+// BasePath returns the prefix set by SetBasePath.
+func BasePath() string {
+	return basePath
+}
+
+// Path prefixes a root-relative path such as "/app/formulas" with the base
+// path. Other URLs are returned unchanged.
+func Path(path string) string {
+	if basePath == "" || !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") {
+		return path
+	}
+	return basePath + path
+}
@@ -8,6 +8,8 @@ package layout
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
+import "perfugo/internal/version"
+
 func Layout(title string, sidebar templ.Component, content templ.Component, showSidebar bool, theme ThemeDefinition) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
@@ -36,53 +38,79 @@ func Layout(title string, sidebar templ.Component, content templ.Component, show
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(title)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 9, Col: 17}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 11, Col: 17}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</title><link rel=\"stylesheet\" href=\"https://cdn.jsdelivr.net/npm/tailwindcss@2.2.19/dist/tailwind.min.css\"><link rel=\"stylesheet\" href=\"/assets/css/report-batch.css\"><link rel=\"preconnect\" href=\"https://fonts.googleapis.com\"><link rel=\"preconnect\" href=\"https://fonts.gstatic.com\" crossorigin><link href=\"https://fonts.googleapis.com/css2?family=Playfair+Display:wght@400;600;700&family=Poppins:wght@300;400;500;600&display=swap\" rel=\"stylesheet\"><script src=\"https://unpkg.com/htmx.org@1.9.12\" defer></script><style>\n                                :root {\n                                        --app-bg: #07090f;\n                                        --app-shell-bg: linear-gradient(180deg, rgba(12, 19, 33, 0.9), rgba(7, 9, 15, 0.95));\n                                        --app-surface: rgba(18, 24, 38, 0.85);\n                                        --app-border: rgba(148, 163, 184, 0.18);\n                                        --app-text: #e2e8f0;\n                                        --app-text-muted: rgba(203, 213, 225, 0.75);\n                                        --app-badge-bg: rgba(59, 130, 246, 0.18);\n                                        --app-badge-text: #bae6fd;\n                                        --app-sidebar-bg: rgba(10, 12, 21, 0.9);\n                                        --app-shadow: rgba(8, 15, 31, 0.4);\n                                        --app-button-bg: #38bdf8;\n                                        --app-button-text: #02101b;\n                                        --app-input-bg: rgba(15, 23, 42, 0.75);\n                                        --app-input-border: rgba(148, 163, 184, 0.35);\n                                        --app-input-focus: rgba(56, 189, 248, 0.65);\n                                        --app-input-focus-shadow: rgba(56, 189, 248, 0.28);\n                                        --app-footer-bg: rgba(7, 10, 18, 0.85);\n                                        --app-accent: #38bdf8;\n                                        --app-accent-border: rgba(56, 189, 248, 0.45);\n                                        --app-accent-soft: rgba(56, 189, 248, 0.18);\n                                }\n\n                                body[data-theme=\"atelier_ivory\"] {\n                                        --app-bg: #f8faf5;\n                                        --app-shell-bg: linear-gradient(180deg, rgba(255, 255, 255, 0.95), rgba(248, 250, 245, 0.95));\n                                        --app-surface: rgba(255, 255, 255, 0.9);\n                                        --app-border: rgba(31, 41, 55, 0.15);\n                                        --app-text: #1f2937;\n                                        --app-text-muted: rgba(55, 65, 81, 0.65);\n                                        --app-badge-bg: rgba(253, 186, 116, 0.35);\n                                        --app-badge-text: #7c2d12;\n                                        --app-sidebar-bg: rgba(254, 252, 244, 0.96);\n                                        --app-shadow: rgba(15, 23, 42, 0.08);\n                                        --app-button-bg: #1f2937;\n                                        --app-button-text: #f8fafc;\n                                        --app-input-bg: rgba(255, 255, 255, 0.9);\n                                        --app-input-border: rgba(75, 85, 99, 0.18);\n                                        --app-input-focus: rgba(249, 115, 22, 0.5);\n                                        --app-input-focus-shadow: rgba(249, 115, 22, 0.25);\n                                        --app-footer-bg: rgba(248, 250, 252, 0.95);\n                                        --app-accent: #c2410c;\n                                        --app-accent-border: rgba(194, 65, 12, 0.45);\n                                        --app-accent-soft: rgba(251, 146, 60, 0.18);\n                                }\n\n                                body[data-theme=\"midnight_draft\"] {\n                                        --app-bg: #0b1220;\n                                        --app-shell-bg: linear-gradient(180deg, rgba(15, 23, 42, 0.92), rgba(12, 20, 35, 0.94));\n                                        --app-surface: rgba(19, 28, 45, 0.9);\n                                        --app-border: rgba(148, 163, 184, 0.22);\n                                        --app-text: #f1f5f9;\n                                        --app-text-muted: rgba(186, 199, 224, 0.72);\n                                        --app-badge-bg: rgba(129, 140, 248, 0.25);\n                                        --app-badge-text: #dbeafe;\n                                        --app-sidebar-bg: rgba(11, 18, 30, 0.92);\n                                        --app-shadow: rgba(15, 23, 42, 0.35);\n                                        --app-button-bg: #818cf8;\n                                        --app-button-text: #111827;\n                                        --app-input-bg: rgba(30, 41, 59, 0.85);\n                                        --app-input-border: rgba(129, 140, 248, 0.35);\n                                        --app-input-focus: rgba(129, 140, 248, 0.65);\n                                        --app-input-focus-shadow: rgba(99, 102, 241, 0.35);\n                                        --app-footer-bg: rgba(11, 17, 30, 0.88);\n                                        --app-accent: #818cf8;\n                                        --app-accent-border: rgba(129, 140, 248, 0.45);\n                                        --app-accent-soft: rgba(129, 140, 248, 0.2);\n                                }\n\n                                body {\n                                        font-family: \"Poppins\", sans-serif;\n                                        letter-spacing: 0.01em;\n                                        background-color: var(--app-bg);\n                                }\n\n                                h1, h2, h3, h4 {\n                                        font-family: \"Playfair Display\", serif;\n                                        letter-spacing: 0.04em;\n                                }\n\n                                .app-root {\n                                        min-height: 100%;\n                                        background-color: var(--app-bg);\n                                        color: var(--app-text);\n                                        transition: background-color 180ms ease, color 180ms ease;\n                                }\n\n                                .app-shell {\n                                        background: var(--app-shell-bg);\n                                }\n\n                                .app-sidebar {\n                                        background-color: var(--app-sidebar-bg);\n                                        border-right: 1px solid var(--app-border);\n                                        color: var(--app-text);\n                                }\n\n                                .app-card {\n                                        background-color: var(--app-surface);\n                                        border: 1px solid var(--app-border);\n                                        border-radius: 1.25rem;\n                                        box-shadow: 0 18px 36px var(--app-shadow);\n                                }\n\n                                .app-card--flat {\n                                        box-shadow: none;\n                                }\n\n                                .app-badge {\n                                        display: inline-flex;\n                                        align-items: center;\n                                        gap: 0.5rem;\n                                        border-radius: 9999px;\n                                        padding: 0.35rem 0.85rem;\n                                        background-color: var(--app-badge-bg);\n                                        color: var(--app-badge-text);\n                                        font-size: 0.75rem;\n                                        font-weight: 500;\n                                        letter-spacing: 0.08em;\n                                        text-transform: uppercase;\n                                }\n\n                                .app-muted {\n                                        color: var(--app-text-muted);\n                                }\n\n                                .app-divider {\n                                        background-color: var(--app-border);\n                                }\n\n                                .app-button {\n                                        background-color: var(--app-button-bg);\n                                        color: var(--app-button-text);\n                                        border-radius: 9999px;\n                                        padding: 0.55rem 1.5rem;\n                                        font-size: 0.7rem;\n                                        letter-spacing: 0.16em;\n                                        text-transform: uppercase;\n                                        font-weight: 600;\n                                        transition: opacity 150ms ease, transform 150ms ease;\n                                }\n\n                                .app-button:hover {\n                                        opacity: 0.92;\n                                        transform: translateY(-1px);\n                                }\n\n                                .app-button--ghost {\n                                        background-color: transparent;\n                                        color: var(--app-text);\n                                        border: 1px solid var(--app-border);\n                                }\n\n                                .app-button--ghost:hover {\n                                        opacity: 1;\n                                        background-color: var(--app-input-bg);\n                                }\n\n                                .app-label {\n                                        color: var(--app-text);\n                                        font-weight: 500;\n                                        letter-spacing: 0.04em;\n                                }\n\n                                .app-link {\n                                        color: var(--app-accent);\n                                        font-weight: 600;\n                                        transition: opacity 150ms ease;\n                                }\n\n                                .app-link:hover {\n                                        opacity: 0.85;\n                                }\n\n                                .app-alert {\n                                        border-radius: 1rem;\n                                        border: 1px solid var(--app-accent-border);\n                                        background-color: var(--app-accent-soft);\n                                        color: var(--app-accent);\n                                        padding: 0.75rem 1rem;\n                                        font-size: 0.9rem;\n                                        font-weight: 500;\n                                }\n\n                                .app-input {\n                                        background-color: var(--app-input-bg);\n                                        border: 1px solid var(--app-input-border);\n                                        border-radius: 0.9rem;\n                                        padding: 0.65rem 1rem;\n                                        color: inherit;\n                                        transition: border-color 150ms ease, box-shadow 150ms ease;\n                                }\n\n                                .app-input:focus {\n                                        border-color: var(--app-input-focus);\n                                        outline: none;\n                                        box-shadow: 0 0 0 2px var(--app-input-focus-shadow);\n                                }\n\n                                .app-footer {\n                                        background-color: var(--app-footer-bg);\n                                        border-top: 1px solid var(--app-border);\n                                }\n\n                                .app-nav-link {\n                                        display: flex;\n                                        align-items: center;\n                                        justify-content: space-between;\n                                        border-radius: 9999px;\n                                        padding: 0.65rem 1.1rem;\n                                        font-size: 0.68rem;\n                                        letter-spacing: 0.16em;\n                                        text-transform: uppercase;\n                                        border: 1px solid transparent;\n                                        color: inherit;\n                                        transition: background-color 150ms ease, border-color 150ms ease, color 150ms ease, box-shadow 150ms ease;\n                                }\n\n                                .app-nav-link:hover {\n                                        border-color: var(--app-border);\n                                }\n\n                                .app-nav-link[data-state=\"active\"] {\n                                        background-color: var(--app-button-bg);\n                                        color: var(--app-button-text);\n                                        box-shadow: 0 12px 24px var(--app-shadow);\n                                }\n\n                                .app-nav-link span[data-role=\"meta\"] {\n                                        opacity: 0.4;\n                                        font-size: 0.55rem;\n                                        letter-spacing: 0.22em;\n                                }\n\n                                .app-nav-link[data-state=\"active\"] span[data-role=\"meta\"] {\n                                        opacity: 1;\n                                }\n\n                                .app-secondary-link {\n                                        display: flex;\n                                        align-items: center;\n                                        justify-content: space-between;\n                                        border-radius: 0.9rem;\n                                        padding: 0.6rem 1rem;\n                                        font-size: 0.65rem;\n                                        letter-spacing: 0.15em;\n                                        text-transform: uppercase;\n                                        border: 1px solid var(--app-border);\n                                        color: var(--app-text-muted);\n                                        transition: background-color 150ms ease, border-color 150ms ease, color 150ms ease;\n                                }\n\n                                .app-secondary-link:hover {\n                                        border-color: var(--app-button-bg);\n                                        color: var(--app-text);\n                                }\n\n                                .app-secondary-link[data-state=\"active\"] {\n                                        border-color: var(--app-button-bg);\n                                        color: var(--app-text);\n                                }\n\n                                .app-secondary-tag {\n                                        border-radius: 9999px;\n                                        border: 1px solid var(--app-border);\n                                        padding: 0.25rem 0.75rem;\n                                        font-size: 0.55rem;\n                                        letter-spacing: 0.18em;\n                                        text-transform: uppercase;\n                                        color: inherit;\n                                }\n\n                                .app-theme-option {\n                                        border-radius: 1rem;\n                                        border: 1px solid var(--app-border);\n                                        background-color: transparent;\n                                        padding: 1rem;\n                                        text-align: left;\n                                        color: inherit;\n                                        transition: border-color 150ms ease, box-shadow 150ms ease, transform 150ms ease;\n                                }\n\n                                .app-theme-option:hover {\n                                        border-color: var(--app-button-bg);\n                                        transform: translateY(-2px);\n                                }\n\n                                .app-theme-option[data-state=\"active\"] {\n                                        border-color: var(--app-button-bg);\n                                        box-shadow: 0 12px 24px var(--app-shadow);\n                                }\n\n                                .workspace-shell form[data-action] input,\n                                .workspace-shell form[data-action] select,\n                                .workspace-shell form[data-action] textarea {\n                                        background-color: var(--app-input-bg);\n                                        border: 1px solid var(--app-input-border);\n                                        border-radius: 0.9rem;\n                                        padding: 0.65rem 1rem;\n                                        color: inherit;\n                                        transition: border-color 150ms ease, box-shadow 150ms ease;\n                                }\n\n                                .workspace-shell form[data-action] input:focus,\n                                .workspace-shell form[data-action] select:focus,\n                                .workspace-shell form[data-action] textarea:focus {\n                                        border-color: var(--app-input-focus);\n                                        outline: none;\n                                        box-shadow: 0 0 0 2px var(--app-input-focus-shadow);\n                                }\n\n                                .workspace-shell .bg-black\\/35,\n                                .workspace-shell .bg-black\\/40,\n                                .workspace-shell .bg-black\\/25,\n                                .workspace-shell .bg-gradient-to-br {\n                                        background-color: var(--app-surface) !important;\n                                        background-image: none !important;\n                                }\n\n                                .workspace-shell .border-white\\/10,\n                                .workspace-shell .border-white\\/15,\n                                .workspace-shell .border-white\\/20,\n                                .workspace-shell .border-white\\/30,\n                                .workspace-shell .border-white\\/40 {\n                                        border-color: var(--app-border) !important;\n                                }\n\n                                .workspace-shell .text-white {\n                                        color: var(--app-text) !important;\n                                }\n\n                                .workspace-shell .text-white\\/40,\n                                .workspace-shell .text-white\\/50,\n                                .workspace-shell .text-white\\/60,\n                                .workspace-shell .text-white\\/70,\n                                .workspace-shell .text-white\\/80 {\n                                        color: var(--app-text-muted) !important;\n                                }\n                        </style></head><body class=\"h-full antialiased app-root\" data-theme=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</title><link rel=\"stylesheet\" href=\"https://cdn.jsdelivr.net/npm/tailwindcss@2.2.19/dist/tailwind.min.css\"><link rel=\"stylesheet\" href=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var3 string
-		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(theme.ID)
+		var templ_7745c5c3_Var3 templ.SafeURL
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinURLErrs(Path("/assets/css/report-batch.css"))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 360, Col: 65}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 16, Col: 69}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" hx-boost=\"true\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"><link rel=\"preconnect\" href=\"https://fonts.googleapis.com\"><link rel=\"preconnect\" href=\"https://fonts.gstatic.com\" crossorigin><link href=\"https://fonts.googleapis.com/css2?family=Playfair+Display:wght@400;600;700&family=Poppins:wght@300;400;500;600&display=swap\" rel=\"stylesheet\"><script src=\"https://unpkg.com/htmx.org@1.9.12\" defer></script><style>\n                                :root {\n                                        --app-bg: #07090f;\n                                        --app-shell-bg: linear-gradient(180deg, rgba(12, 19, 33, 0.9), rgba(7, 9, 15, 0.95));\n                                        --app-surface: rgba(18, 24, 38, 0.85);\n                                        --app-border: rgba(148, 163, 184, 0.18);\n                                        --app-text: #e2e8f0;\n                                        --app-text-muted: rgba(203, 213, 225, 0.75);\n                                        --app-badge-bg: rgba(59, 130, 246, 0.18);\n                                        --app-badge-text: #bae6fd;\n                                        --app-sidebar-bg: rgba(10, 12, 21, 0.9);\n                                        --app-shadow: rgba(8, 15, 31, 0.4);\n                                        --app-button-bg: #38bdf8;\n                                        --app-button-text: #02101b;\n                                        --app-input-bg: rgba(15, 23, 42, 0.75);\n                                        --app-input-border: rgba(148, 163, 184, 0.35);\n                                        --app-input-focus: rgba(56, 189, 248, 0.65);\n                                        --app-input-focus-shadow: rgba(56, 189, 248, 0.28);\n                                        --app-footer-bg: rgba(7, 10, 18, 0.85);\n                                        --app-accent: #38bdf8;\n                                        --app-accent-border: rgba(56, 189, 248, 0.45);\n                                        --app-accent-soft: rgba(56, 189, 248, 0.18);\n                                }\n\n                                body[data-theme=\"atelier_ivory\"] {\n                                        --app-bg: #f8faf5;\n                                        --app-shell-bg: linear-gradient(180deg, rgba(255, 255, 255, 0.95), rgba(248, 250, 245, 0.95));\n                                        --app-surface: rgba(255, 255, 255, 0.9);\n                                        --app-border: rgba(31, 41, 55, 0.15);\n                                        --app-text: #1f2937;\n                                        --app-text-muted: rgba(55, 65, 81, 0.65);\n                                        --app-badge-bg: rgba(253, 186, 116, 0.35);\n                                        --app-badge-text: #7c2d12;\n                                        --app-sidebar-bg: rgba(254, 252, 244, 0.96);\n                                        --app-shadow: rgba(15, 23, 42, 0.08);\n                                        --app-button-bg: #1f2937;\n                                        --app-button-text: #f8fafc;\n                                        --app-input-bg: rgba(255, 255, 255, 0.9);\n                                        --app-input-border: rgba(75, 85, 99, 0.18);\n                                        --app-input-focus: rgba(249, 115, 22, 0.5);\n                                        --app-input-focus-shadow: rgba(249, 115, 22, 0.25);\n                                        --app-footer-bg: rgba(248, 250, 252, 0.95);\n                                        --app-accent: #c2410c;\n                                        --app-accent-border: rgba(194, 65, 12, 0.45);\n                                        --app-accent-soft: rgba(251, 146, 60, 0.18);\n                                }\n\n                                body[data-theme=\"midnight_draft\"] {\n                                        --app-bg: #0b1220;\n                                        --app-shell-bg: linear-gradient(180deg, rgba(15, 23, 42, 0.92), rgba(12, 20, 35, 0.94));\n                                        --app-surface: rgba(19, 28, 45, 0.9);\n                                        --app-border: rgba(148, 163, 184, 0.22);\n                                        --app-text: #f1f5f9;\n                                        --app-text-muted: rgba(186, 199, 224, 0.72);\n                                        --app-badge-bg: rgba(129, 140, 248, 0.25);\n                                        --app-badge-text: #dbeafe;\n                                        --app-sidebar-bg: rgba(11, 18, 30, 0.92);\n                                        --app-shadow: rgba(15, 23, 42, 0.35);\n                                        --app-button-bg: #818cf8;\n                                        --app-button-text: #111827;\n                                        --app-input-bg: rgba(30, 41, 59, 0.85);\n                                        --app-input-border: rgba(129, 140, 248, 0.35);\n                                        --app-input-focus: rgba(129, 140, 248, 0.65);\n                                        --app-input-focus-shadow: rgba(99, 102, 241, 0.35);\n                                        --app-footer-bg: rgba(11, 17, 30, 0.88);\n                                        --app-accent: #818cf8;\n                                        --app-accent-border: rgba(129, 140, 248, 0.45);\n                                        --app-accent-soft: rgba(129, 140, 248, 0.2);\n                                }\n\n                                body {\n                                        font-family: \"Poppins\", sans-serif;\n                                        letter-spacing: 0.01em;\n                                        background-color: var(--app-bg);\n                                }\n\n                                h1, h2, h3, h4 {\n                                        font-family: \"Playfair Display\", serif;\n                                        letter-spacing: 0.04em;\n                                }\n\n                                .app-root {\n                                        min-height: 100%;\n                                        background-color: var(--app-bg);\n                                        color: var(--app-text);\n                                        transition: background-color 180ms ease, color 180ms ease;\n                                }\n\n                                .app-shell {\n                                        background: var(--app-shell-bg);\n                                }\n\n                                .app-sidebar {\n                                        background-color: var(--app-sidebar-bg);\n                                        border-right: 1px solid var(--app-border);\n                                        color: var(--app-text);\n                                }\n\n                                .app-card {\n                                        background-color: var(--app-surface);\n                                        border: 1px solid var(--app-border);\n                                        border-radius: 1.25rem;\n                                        box-shadow: 0 18px 36px var(--app-shadow);\n                                }\n\n                                .app-card--flat {\n                                        box-shadow: none;\n                                }\n\n                                .app-badge {\n                                        display: inline-flex;\n                                        align-items: center;\n                                        gap: 0.5rem;\n                                        border-radius: 9999px;\n                                        padding: 0.35rem 0.85rem;\n                                        background-color: var(--app-badge-bg);\n                                        color: var(--app-badge-text);\n                                        font-size: 0.75rem;\n                                        font-weight: 500;\n                                        letter-spacing: 0.08em;\n                                        text-transform: uppercase;\n                                }\n\n                                .app-muted {\n                                        color: var(--app-text-muted);\n                                }\n\n                                .app-divider {\n                                        background-color: var(--app-border);\n                                }\n\n                                .app-button {\n                                        background-color: var(--app-button-bg);\n                                        color: var(--app-button-text);\n                                        border-radius: 9999px;\n                                        padding: 0.55rem 1.5rem;\n                                        font-size: 0.7rem;\n                                        letter-spacing: 0.16em;\n                                        text-transform: uppercase;\n                                        font-weight: 600;\n                                        transition: opacity 150ms ease, transform 150ms ease;\n                                }\n\n                                .app-button:hover {\n                                        opacity: 0.92;\n                                        transform: translateY(-1px);\n                                }\n\n                                .app-button--ghost {\n                                        background-color: transparent;\n                                        color: var(--app-text);\n                                        border: 1px solid var(--app-border);\n                                }\n\n                                .app-button--ghost:hover {\n                                        opacity: 1;\n                                        background-color: var(--app-input-bg);\n                                }\n\n                                .app-label {\n                                        color: var(--app-text);\n                                        font-weight: 500;\n                                        letter-spacing: 0.04em;\n                                }\n\n                                .app-link {\n                                        color: var(--app-accent);\n                                        font-weight: 600;\n                                        transition: opacity 150ms ease;\n                                }\n\n                                .app-link:hover {\n                                        opacity: 0.85;\n                                }\n\n                                .app-alert {\n                                        border-radius: 1rem;\n                                        border: 1px solid var(--app-accent-border);\n                                        background-color: var(--app-accent-soft);\n                                        color: var(--app-accent);\n                                        padding: 0.75rem 1rem;\n                                        font-size: 0.9rem;\n                                        font-weight: 500;\n                                }\n\n                                .app-input {\n                                        background-color: var(--app-input-bg);\n                                        border: 1px solid var(--app-input-border);\n                                        border-radius: 0.9rem;\n                                        padding: 0.65rem 1rem;\n                                        color: inherit;\n                                        transition: border-color 150ms ease, box-shadow 150ms ease;\n                                }\n\n                                .app-input:focus {\n                                        border-color: var(--app-input-focus);\n                                        outline: none;\n                                        box-shadow: 0 0 0 2px var(--app-input-focus-shadow);\n                                }\n\n                                .app-footer {\n                                        background-color: var(--app-footer-bg);\n                                        border-top: 1px solid var(--app-border);\n                                }\n\n                                .app-nav-link {\n                                        display: flex;\n                                        align-items: center;\n                                        justify-content: space-between;\n                                        border-radius: 9999px;\n                                        padding: 0.65rem 1.1rem;\n                                        font-size: 0.68rem;\n                                        letter-spacing: 0.16em;\n                                        text-transform: uppercase;\n                                        border: 1px solid transparent;\n                                        color: inherit;\n                                        transition: background-color 150ms ease, border-color 150ms ease, color 150ms ease, box-shadow 150ms ease;\n                                }\n\n                                .app-nav-link:hover {\n                                        border-color: var(--app-border);\n                                }\n\n                                .app-nav-link[data-state=\"active\"] {\n                                        background-color: var(--app-button-bg);\n                                        color: var(--app-button-text);\n                                        box-shadow: 0 12px 24px var(--app-shadow);\n                                }\n\n                                .app-nav-link span[data-role=\"meta\"] {\n                                        opacity: 0.4;\n                                        font-size: 0.55rem;\n                                        letter-spacing: 0.22em;\n                                }\n\n                                .app-nav-link[data-state=\"active\"] span[data-role=\"meta\"] {\n                                        opacity: 1;\n                                }\n\n                                .app-secondary-link {\n                                        display: flex;\n                                        align-items: center;\n                                        justify-content: space-between;\n                                        border-radius: 0.9rem;\n                                        padding: 0.6rem 1rem;\n                                        font-size: 0.65rem;\n                                        letter-spacing: 0.15em;\n                                        text-transform: uppercase;\n                                        border: 1px solid var(--app-border);\n                                        color: var(--app-text-muted);\n                                        transition: background-color 150ms ease, border-color 150ms ease, color 150ms ease;\n                                }\n\n                                .app-secondary-link:hover {\n                                        border-color: var(--app-button-bg);\n                                        color: var(--app-text);\n                                }\n\n                                .app-secondary-link[data-state=\"active\"] {\n                                        border-color: var(--app-button-bg);\n                                        color: var(--app-text);\n                                }\n\n                                .app-nav-badge {\n                                        min-width: 1.4rem;\n                                        border-radius: 9999px;\n                                        background-color: var(--app-badge-bg);\n                                        padding: 0.1rem 0.45rem;\n                                        font-size: 0.65rem;\n                                        font-weight: 600;\n                                        letter-spacing: 0;\n                                        text-align: center;\n                                        color: var(--app-badge-text);\n                                }\n\n                                .app-toast-region {\n                                        position: fixed;\n                                        right: 1.5rem;\n                                        bottom: 1.5rem;\n                                        z-index: 50;\n                                        display: flex;\n                                        flex-direction: column;\n                                        gap: 0.75rem;\n                                        max-width: 22rem;\n                                }\n\n                                .app-toast {\n                                        border-radius: 1rem;\n                                        border: 1px solid var(--app-border);\n                                        border-left-width: 4px;\n                                        background-color: var(--app-surface);\n                                        padding: 0.75rem 1rem;\n                                        font-size: 0.85rem;\n                                        color: var(--app-text);\n                                        box-shadow: 0 12px 24px var(--app-shadow);\n                                }\n\n                                .app-toast[data-level=\"success\"] {\n                                        border-left-color: #34d399;\n                                }\n\n                                .app-toast[data-level=\"warning\"] {\n                                        border-left-color: #fbbf24;\n                                }\n\n                                .app-request-error-region {\n                                        position: fixed;\n                                        top: 1.5rem;\n                                        left: 50%;\n                                        z-index: 60;\n                                        width: min(36rem, calc(100% - 3rem));\n                                        transform: translateX(-50%);\n                                }\n\n                                .app-request-error {\n                                        display: flex;\n                                        align-items: center;\n                                        justify-content: space-between;\n                                        gap: 1rem;\n                                        border-radius: 1rem;\n                                        border: 1px solid var(--app-border);\n                                        border-left: 4px solid #f87171;\n                                        background-color: var(--app-surface);\n                                        padding: 0.75rem 1rem;\n                                        color: var(--app-text);\n                                        box-shadow: 0 12px 24px var(--app-shadow);\n                                }\n\n                                .app-secondary-tag {\n                                        border-radius: 9999px;\n                                        border: 1px solid var(--app-border);\n                                        padding: 0.25rem 0.75rem;\n                                        font-size: 0.55rem;\n                                        letter-spacing: 0.18em;\n                                        text-transform: uppercase;\n                                        color: inherit;\n                                }\n\n                                .app-theme-option {\n                                        border-radius: 1rem;\n                                        border: 1px solid var(--app-border);\n                                        background-color: transparent;\n                                        padding: 1rem;\n                                        text-align: left;\n                                        color: inherit;\n                                        transition: border-color 150ms ease, box-shadow 150ms ease, transform 150ms ease;\n                                }\n\n                                .app-theme-option:hover {\n                                        border-color: var(--app-button-bg);\n                                        transform: translateY(-2px);\n                                }\n\n                                .app-theme-option[data-state=\"active\"] {\n                                        border-color: var(--app-button-bg);\n                                        box-shadow: 0 12px 24px var(--app-shadow);\n                                }\n\n                                .workspace-shell form[data-action] input,\n                                .workspace-shell form[data-action] select,\n                                .workspace-shell form[data-action] textarea {\n                                        background-color: var(--app-input-bg);\n                                        border: 1px solid var(--app-input-border);\n                                        border-radius: 0.9rem;\n                                        padding: 0.65rem 1rem;\n                                        color: inherit;\n                                        transition: border-color 150ms ease, box-shadow 150ms ease;\n                                }\n\n                                .workspace-shell form[data-action] input:focus,\n                                .workspace-shell form[data-action] select:focus,\n                                .workspace-shell form[data-action] textarea:focus {\n                                        border-color: var(--app-input-focus);\n                                        outline: none;\n                                        box-shadow: 0 0 0 2px var(--app-input-focus-shadow);\n                                }\n\n                                .workspace-shell .bg-black\\/35,\n                                .workspace-shell .bg-black\\/40,\n                                .workspace-shell .bg-black\\/25,\n                                .workspace-shell .bg-gradient-to-br {\n                                        background-color: var(--app-surface) !important;\n                                        background-image: none !important;\n                                }\n\n                                .workspace-shell .border-white\\/10,\n                                .workspace-shell .border-white\\/15,\n                                .workspace-shell .border-white\\/20,\n                                .workspace-shell .border-white\\/30,\n                                .workspace-shell .border-white\\/40 {\n                                        border-color: var(--app-border) !important;\n                                }\n\n                                .workspace-shell .text-white {\n                                        color: var(--app-text) !important;\n                                }\n\n                                .workspace-shell .text-white\\/40,\n                                .workspace-shell .text-white\\/50,\n                                .workspace-shell .text-white\\/60,\n                                .workspace-shell .text-white\\/70,\n                                .workspace-shell .text-white\\/80 {\n                                        color: var(--app-text-muted) !important;\n                                }\n                        </style></head><body class=\"h-full antialiased app-root\" data-theme=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var4 = []any{bodyWrapperClass(showSidebar)}
-		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var4...)
+		var templ_7745c5c3_Var4 string
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(theme.ID)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 427, Col: 65}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<div class=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\" data-shortcuts=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var5 string
-		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var4).String())
+		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(shortcutsJSON(ctx))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 1, Col: 0}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 427, Col: 103}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\" hx-boost=\"true\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var6 = []any{bodyWrapperClass(showSidebar)}
+		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var6...)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<div class=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var7 string
+		templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var6).String())
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 1, Col: 0}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if showSidebar {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<aside class=\"app-sidebar hidden lg:flex lg:w-72 xl:w-80 lg:flex-col\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<aside class=\"app-sidebar hidden lg:flex lg:w-72 xl:w-80 lg:flex-col\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -92,34 +120,34 @@ func Layout(title string, sidebar templ.Component, content templ.Component, show
 					return templ_7745c5c3_Err
 				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</aside>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</aside>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<div class=\"flex min-h-screen flex-1 flex-col\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<div class=\"flex min-h-screen flex-1 flex-col\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var6 = []any{mainClass(showSidebar)}
-		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var6...)
+		var templ_7745c5c3_Var8 = []any{mainClass(showSidebar)}
+		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var8...)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<main id=\"primary-content\" class=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<main id=\"primary-content\" class=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var7 string
-		templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var6).String())
+		var templ_7745c5c3_Var9 string
+		templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var8).String())
 		if templ_7745c5c3_Err != nil {
 			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 1, Col: 0}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -127,7 +155,69 @@ func Layout(title string, sidebar templ.Component, content templ.Component, show
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</main><footer class=\"app-footer\"><div class=\"mx-auto flex max-w-6xl flex-col gap-1 px-6 py-6 text-xs uppercase tracking-[0.25em] app-muted sm:flex-row sm:items-center sm:justify-between\"><span>Perfugo Digital Atelier</span> <span class=\"hidden h-px w-16 app-divider sm:block\"></span> <span>Where craft meets alchemy</span></div></footer></div></div><script>\n                                window.addEventListener('DOMContentLoaded', function () {\n                                        const namespace = window.PerfugoWorkspace || (window.PerfugoWorkspace = {});\n                                        namespace.modules = namespace.modules || {};\n\n                                        namespace.initModules = function (container) {\n                                                if (!container) {\n                                                        return;\n                                                }\n                                                const moduleRoot = container.querySelector('[data-module]');\n                                                if (!moduleRoot) {\n                                                        return;\n                                                }\n                                                const name = moduleRoot.dataset.module;\n                                                const init = namespace.modules[name];\n                                                if (typeof init === 'function') {\n                                                        init(moduleRoot);\n                                                }\n                                        };\n\n                                        namespace.highlightActiveLink = function (path) {\n                                                const current = (path.replace(/^\\/app\\/?/, '') || 'ingredients').split('/')[0];\n                                                document.querySelectorAll('[data-nav-section]').forEach(function (link) {\n                                                        link.dataset.state = link.dataset.navSection === current ? 'active' : 'inactive';\n                                                });\n                                        };\n\n                                        namespace.updateTheme = function (identifier) {\n                                                if (typeof identifier !== 'string' || !identifier.trim()) {\n                                                        return;\n                                                }\n                                                document.body.dataset.theme = identifier.trim();\n                                        };\n\n                                        const assignSeeds = function (container) {\n                                                if (!container) {\n                                                        return;\n                                                }\n                                                if (namespace.seedsApplied) {\n                                                        return;\n                                                }\n                                                const seeds = container.dataset.seeds;\n                                                if (!seeds) {\n                                                        return;\n                                                }\n                                                try {\n                                                        window.PerfugoWorkspaceSeeds = window.PerfugoWorkspaceSeeds || JSON.parse(seeds);\n                                                        namespace.seedsApplied = true;\n                                                } catch (error) {\n                                                        console.warn('Perfugo workspace seeds parse error', error);\n                                                }\n                                        };\n\n                                        const container = document.getElementById('workspace-content');\n                                        if (container) {\n                                                assignSeeds(container);\n                                                namespace.initModules(container);\n                                                namespace.highlightActiveLink(window.location.pathname);\n                                        }\n\n                                        document.body.addEventListener('htmx:afterSwap', function (event) {\n                                                if (!event.detail || !event.detail.target) {\n                                                        return;\n                                                }\n                                                if (event.detail.target.id !== 'workspace-content') {\n                                                        return;\n                                                }\n                                                assignSeeds(event.detail.target);\n                                                namespace.initModules(event.detail.target);\n                                                const path = (event.detail.requestConfig && event.detail.requestConfig.path) || window.location.pathname;\n                                                namespace.highlightActiveLink(path);\n                                        });\n                                });\n                        </script></body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "</main><footer class=\"app-footer\"><div class=\"mx-auto flex max-w-6xl flex-col gap-1 px-6 py-6 text-xs uppercase tracking-[0.25em] app-muted sm:flex-row sm:items-center sm:justify-between\"><span>Perfugo Digital Atelier</span> <span class=\"hidden h-px w-16 app-divider sm:block\"></span> <span>Where craft meets alchemy</span> <span class=\"normal-case tracking-normal\" title=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var10 string
+		templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(version.Get().String())
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 445, Col: 79}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var11 string
+		templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(version.Version)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 445, Col: 99}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, " · ")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var12 string
+		templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(version.Commit)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 445, Col: 121}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "</span></div></footer></div></div><div id=\"request-error\" class=\"app-request-error-region\" aria-live=\"assertive\"></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if showSidebar {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "<div id=\"toast-region\" class=\"app-toast-region\" role=\"status\" aria-live=\"polite\" data-notification-stream=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var13 string
+			templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(Path("/app/notifications/stream"))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/layout/layout.templ`, Line: 452, Col: 145}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "\"></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "<script>\n                                window.addEventListener('DOMContentLoaded', function () {\n                                        const namespace = window.PerfugoWorkspace || (window.PerfugoWorkspace = {});\n                                        namespace.modules = namespace.modules || {};\n\n                                        namespace.initModules = function (container) {\n                                                if (!container) {\n                                                        return;\n                                                }\n                                                const moduleRoot = container.querySelector('[data-module]');\n                                                if (!moduleRoot) {\n                                                        return;\n                                                }\n                                                const name = moduleRoot.dataset.module;\n                                                const init = namespace.modules[name];\n                                                if (typeof init === 'function') {\n                                                        init(moduleRoot);\n                                                }\n                                        };\n\n                                        namespace.highlightActiveLink = function (path) {\n                                                const current = (path.replace(/^.*?\\/app(?:\\/|$)/, '') || 'ingredients').split('/')[0];\n                                                document.querySelectorAll('[data-nav-section]').forEach(function (link) {\n                                                        link.dataset.state = link.dataset.navSection === current ? 'active' : 'inactive';\n                                                });\n                                        };\n\n                                        namespace.updateTheme = function (identifier) {\n                                                if (typeof identifier !== 'string' || !identifier.trim()) {\n                                                        return;\n                                                }\n                                                document.body.dataset.theme = identifier.trim();\n                                        };\n\n                                        const assignSeeds = function (container) {\n                                                if (!container) {\n                                                        return;\n                                                }\n                                                if (namespace.seedsApplied) {\n                                                        return;\n                                                }\n                                                const seeds = container.dataset.seeds;\n                                                if (!seeds) {\n                                                        return;\n                                                }\n                                                try {\n                                                        window.PerfugoWorkspaceSeeds = window.PerfugoWorkspaceSeeds || JSON.parse(seeds);\n                                                        namespace.seedsApplied = true;\n                                                } catch (error) {\n                                                        console.warn('Perfugo workspace seeds parse error', error);\n                                                }\n                                        };\n\n                                        const container = document.getElementById('workspace-content');\n                                        if (container) {\n                                                assignSeeds(container);\n                                                namespace.initModules(container);\n                                                namespace.highlightActiveLink(window.location.pathname);\n                                        }\n\n                                        let draggedRow = null;\n                                        document.body.addEventListener('dragstart', function (event) {\n                                                const row = event.target.closest && event.target.closest('[data-sortable-item]');\n                                                if (!row) {\n                                                        return;\n                                                }\n                                                draggedRow = row;\n                                                event.dataTransfer.effectAllowed = 'move';\n                                        });\n                                        document.body.addEventListener('dragover', function (event) {\n                                                const row = event.target.closest && event.target.closest('[data-sortable-item]');\n                                                if (!draggedRow || !row || row === draggedRow || row.parentNode !== draggedRow.parentNode) {\n                                                        return;\n                                                }\n                                                event.preventDefault();\n                                                const box = row.getBoundingClientRect();\n                                                const after = event.clientY > box.top + box.height / 2;\n                                                row.parentNode.insertBefore(draggedRow, after ? row.nextSibling : row);\n                                        });\n                                        document.body.addEventListener('drop', function (event) {\n                                                if (draggedRow) {\n                                                        event.preventDefault();\n                                                }\n                                        });\n                                        document.body.addEventListener('dragend', function () {\n                                                if (!draggedRow) {\n                                                        return;\n                                                }\n                                                const form = draggedRow.closest('[data-sortable]');\n                                                draggedRow = null;\n                                                if (form && window.htmx) {\n                                                        window.htmx.trigger(form, 'sorted');\n                                                }\n                                        });\n\n                                        document.body.addEventListener('click', function (event) {\n                                                const preset = event.target.closest && event.target.closest('[data-fill-target]');\n                                                if (!preset) {\n                                                        return;\n                                                }\n                                                const input = document.getElementById(preset.dataset.fillTarget);\n                                                if (input) {\n                                                        input.value = preset.dataset.fillValue;\n                                                        input.dispatchEvent(new Event('change', { bubbles: true }));\n                                                }\n                                        });\n\n                                        let draggedCard = null;\n                                        document.body.addEventListener('dragstart', function (event) {\n                                                const card = event.target.closest && event.target.closest('[data-board-card]');\n                                                if (!card) {\n                                                        return;\n                                                }\n                                                draggedCard = card;\n                                                event.dataTransfer.effectAllowed = 'move';\n                                        });\n                                        document.body.addEventListener('dragover', function (event) {\n                                                if (draggedCard && event.target.closest && event.target.closest('[data-board-column]')) {\n                                                        event.preventDefault();\n                                                }\n                                        });\n                                        document.body.addEventListener('drop', function (event) {\n                                                const column = draggedCard && event.target.closest && event.target.closest('[data-board-column]');\n                                                const card = draggedCard;\n                                                draggedCard = null;\n                                                if (!column || column.contains(card)) {\n                                                        return;\n                                                }\n                                                event.preventDefault();\n                                                column.querySelector('input[name=\"id\"]').value = card.dataset.boardCard;\n                                                if (window.htmx) {\n                                                        window.htmx.trigger(column, 'board-drop');\n                                                }\n                                        });\n                                        document.body.addEventListener('dragend', function () {\n                                                draggedCard = null;\n                                        });\n\n                                        // Shortcut bindings come from the user's preferences as\n                                        // action to key, eg. {\"save\": \"mod+s\"}. An action activates\n                                        // the first visible element marked data-shortcut with its\n                                        // name: fields are focused, anything else is clicked.\n                                        const shortcuts = (function () {\n                                                try {\n                                                        return JSON.parse(document.body.dataset.shortcuts || '{}');\n                                                } catch (error) {\n                                                        console.warn('Perfugo shortcut parse error', error);\n                                                        return {};\n                                                }\n                                        })();\n                                        const shortcutCombo = function (event) {\n                                                let key = event.key === ' ' ? 'space' : (event.key || '').toLowerCase();\n                                                const parts = [];\n                                                if (event.ctrlKey || event.metaKey) {\n                                                        parts.push('mod');\n                                                }\n                                                if (event.altKey) {\n                                                        parts.push('alt');\n                                                        if (event.code && event.code.indexOf('Key') === 0) {\n                                                                key = event.code.slice(3).toLowerCase();\n                                                        }\n                                                }\n                                                if (event.shiftKey && (key.length > 1 || /^[a-z]$/.test(key))) {\n                                                        parts.push('shift');\n                                                }\n                                                parts.push(key);\n                                                return parts.join('+');\n                                        };\n                                        document.addEventListener('keydown', function (event) {\n                                                if (event.defaultPrevented || event.isComposing) {\n                                                        return;\n                                                }\n                                                const combo = shortcutCombo(event);\n                                                const action = Object.keys(shortcuts).find(function (name) {\n                                                        return shortcuts[name] === combo;\n                                                });\n                                                if (!action) {\n                                                        return;\n                                                }\n                                                const field = event.target.closest && event.target.closest('input, textarea, select, [contenteditable]');\n                                                if (field && !event.ctrlKey && !event.metaKey && !event.altKey) {\n                                                        return;\n                                                }\n                                                const target = Array.prototype.find.call(document.querySelectorAll('[data-shortcut=\"' + action + '\"]'), function (element) {\n                                                        return element.offsetParent !== null && !element.disabled;\n                                                });\n                                                if (!target) {\n                                                        return;\n                                                }\n                                                event.preventDefault();\n                                                if (target.matches('input, textarea, select')) {\n                                                        target.focus();\n                                                        if (typeof target.select === 'function') {\n                                                                target.select();\n                                                        }\n                                                        return;\n                                                }\n                                                target.click();\n                                        });\n\n                                        namespace.showToast = function (level, message) {\n                                                const region = document.getElementById('toast-region');\n                                                if (!region || !message) {\n                                                        return;\n                                                }\n                                                const toast = document.createElement('div');\n                                                toast.className = 'app-toast';\n                                                toast.dataset.level = level || 'info';\n                                                toast.textContent = message;\n                                                region.appendChild(toast);\n                                                window.setTimeout(function () {\n                                                        toast.remove();\n                                                }, 6000);\n                                        };\n\n                                        namespace.setBadge = function (name, count) {\n                                                document.querySelectorAll('[data-badge=\"' + name + '\"]').forEach(function (badge) {\n                                                        badge.textContent = count > 99 ? '99+' : String(count);\n                                                        badge.hidden = !(count > 0);\n                                                });\n                                        };\n\n                                        // One stream per tab; it survives boosted navigation and is\n                                        // opened as soon as a page with the toast region appears.\n                                        namespace.connectNotifications = function () {\n                                                const region = document.getElementById('toast-region');\n                                                if (namespace.notifications || !region || !window.EventSource) {\n                                                        return;\n                                                }\n                                                const source = new EventSource(region.dataset.notificationStream);\n                                                const parse = function (event) {\n                                                        try {\n                                                                return JSON.parse(event.data);\n                                                        } catch (error) {\n                                                                console.warn('Perfugo notification parse error', error);\n                                                                return null;\n                                                        }\n                                                };\n                                                source.addEventListener('badge', function (event) {\n                                                        const data = parse(event);\n                                                        if (data) {\n                                                                namespace.setBadge(data.badge, data.count);\n                                                        }\n                                                });\n                                                source.addEventListener('toast', function (event) {\n                                                        const data = parse(event);\n                                                        if (data) {\n                                                                namespace.showToast(data.level, data.message);\n                                                        }\n                                                });\n                                                namespace.notifications = source;\n                                        };\n                                        namespace.connectNotifications();\n\n                                        // HTMX drops error responses unless told otherwise; failures\n                                        // answered with the error envelope carry HX-Retarget and are\n                                        // swapped into the request-error region.\n                                        document.body.addEventListener('htmx:beforeSwap', function (event) {\n                                                const xhr = event.detail.xhr;\n                                                if (xhr && xhr.status >= 400 && xhr.getResponseHeader('HX-Retarget')) {\n                                                        event.detail.shouldSwap = true;\n                                                        event.detail.isError = false;\n                                                }\n                                        });\n\n                                        document.body.addEventListener('htmx:afterSwap', function (event) {\n                                                namespace.connectNotifications();\n                                                if (!event.detail || !event.detail.target) {\n                                                        return;\n                                                }\n                                                if (event.detail.target.id !== 'workspace-content') {\n                                                        return;\n                                                }\n                                                assignSeeds(event.detail.target);\n                                                namespace.initModules(event.detail.target);\n                                                const path = (event.detail.requestConfig && event.detail.requestConfig.path) || window.location.pathname;\n                                                namespace.highlightActiveLink(path);\n                                        });\n                                });\n                        </script></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
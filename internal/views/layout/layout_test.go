@@ -70,3 +70,22 @@ func TestBodyWrapperClassReflectsSidebarState(t *testing.T) {
 		t.Fatal("expected different main class depending on sidebar state")
 	}
 }
+
+func TestPathPrefixesBasePath(t *testing.T) {
+	t.Cleanup(func() { SetBasePath("") })
+
+	if got := Path("/app"); got != "/app" {
+		t.Fatalf("without a base path Path(/app) = %q", got)
+	}
+	SetBasePath(" perfugo/ ")
+	for path, want := range map[string]string{
+		"/app/formulas?id=1":     "/perfugo/app/formulas?id=1",
+		"/":                      "/perfugo/",
+		"https://example.com/x":  "https://example.com/x",
+		"//cdn.example.com/a.js": "//cdn.example.com/a.js",
+	} {
+		if got := Path(path); got != want {
+			t.Fatalf("Path(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
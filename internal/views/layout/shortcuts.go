@@ -0,0 +1,33 @@
+package layout
+
+import (
+	"context"
+	"encoding/json"
+
+	"perfugo/models"
+)
+
+type shortcutsKey struct{}
+
+// WithShortcuts attaches the signed-in user's keyboard shortcut bindings,
+// action to key, to ctx so the layout can hand them to the browser.
+func WithShortcuts(ctx context.Context, bindings map[string]string) context.Context {
+	return context.WithValue(ctx, shortcutsKey{}, bindings)
+}
+
+// ShortcutsFrom returns the bindings attached to ctx, or the defaults when
+// there are none.
+func ShortcutsFrom(ctx context.Context) map[string]string {
+	if bindings, ok := ctx.Value(shortcutsKey{}).(map[string]string); ok {
+		return bindings
+	}
+	return models.ShortcutBindings(nil)
+}
+
+func shortcutsJSON(ctx context.Context) string {
+	encoded, err := json.Marshal(ShortcutsFrom(ctx))
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
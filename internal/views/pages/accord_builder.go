@@ -0,0 +1,71 @@
+package pages
+
+import (
+	"fmt"
+
+	"perfugo/models"
+)
+
+// MaxAccordRows caps the materials on the accord builder scratchpad.
+const MaxAccordRows = 12
+
+// AccordRow is one material on the accord builder scratchpad, measured in
+// parts.
+type AccordRow struct {
+	Chemical models.AromaChemical
+	Parts    float64
+	Percent  float64
+}
+
+// AccordScratchpad is the unsaved state of the accord builder. It lives in
+// the form and is posted back on every change.
+type AccordScratchpad struct {
+	Name    string
+	Rows    []AccordRow
+	Message string
+	// SavedID is the formula the scratchpad was just promoted to.
+	SavedID uint
+}
+
+// Normalize sets each row's share of the total parts.
+func (s *AccordScratchpad) Normalize() {
+	total := s.TotalParts()
+	for i := range s.Rows {
+		s.Rows[i].Percent = 0
+		if total > 0 && s.Rows[i].Parts > 0 {
+			s.Rows[i].Percent = s.Rows[i].Parts / total * 100
+		}
+	}
+}
+
+// TotalParts sums the parts of every row.
+func (s AccordScratchpad) TotalParts() float64 {
+	total := 0.0
+	for _, row := range s.Rows {
+		if row.Parts > 0 {
+			total += row.Parts
+		}
+	}
+	return total
+}
+
+// Has reports whether the chemical is already on the scratchpad.
+func (s AccordScratchpad) Has(chemicalID uint) bool {
+	for _, row := range s.Rows {
+		if row.Chemical.ID == chemicalID {
+			return true
+		}
+	}
+	return false
+}
+
+// FormulaEditURL deep links to the editor for a formula.
+func FormulaEditURL(id uint) string {
+	return Path(fmt.Sprintf("/app/formulas?edit=%d", id))
+}
+
+// FormulaEditorFragmentURL is the HTMX endpoint that renders the formula
+// editor.
+func FormulaEditorFragmentURL(id uint) string {
+	return Path(fmt.Sprintf("/app/sections/formulas/edit?id=%d", id))
+}
@@ -0,0 +1,102 @@
+package pages
+
+import (
+	"sort"
+	"strings"
+
+	"perfugo/models"
+)
+
+// DefaultAllergenConcentration is the finished-product concentration, in
+// percent, used when the allergen report is opened without one.
+const DefaultAllergenConcentration = 20.0
+
+// AllergenSource is a material in a formula's expanded composition with its
+// share of the concentrate, in percent.
+type AllergenSource struct {
+	Chemical models.AromaChemical
+	Percent  float64
+}
+
+// AllergenLine totals one allergen across the materials that contain it.
+type AllergenLine struct {
+	Name         string
+	CASNumber    string
+	Percent      float64
+	Contributors []string
+}
+
+// ProductPercent is the allergen's share of a finished product made at
+// concentration percent.
+func (l AllergenLine) ProductPercent(concentration float64) float64 {
+	return l.Percent * concentration / 100
+}
+
+// AllergenReport lists the allergens in a formula, counting pure materials
+// and the constituents of naturals.
+type AllergenReport struct {
+	FormulaID     uint
+	Concentration float64
+	Lines         []AllergenLine
+	Message       string
+}
+
+// LeaveOn reports whether the line must be declared on a leave-on product.
+func (r AllergenReport) LeaveOn(line AllergenLine) bool {
+	return line.ProductPercent(r.Concentration) > models.AllergenLeaveOnThreshold
+}
+
+// RinseOff reports whether the line must be declared on a rinse-off product.
+func (r AllergenReport) RinseOff(line AllergenLine) bool {
+	return line.ProductPercent(r.Concentration) > models.AllergenRinseOffThreshold
+}
+
+// BuildAllergenReport totals the allergens contributed by each source, either
+// because the material is itself an allergen or through its recorded
+// constituents. Lines are ordered by descending share.
+func BuildAllergenReport(formulaID uint, concentration float64, sources []AllergenSource, data models.Constituents) AllergenReport {
+	report := AllergenReport{FormulaID: formulaID, Concentration: concentration}
+	lines := map[string]*AllergenLine{}
+	add := func(name, cas string, percent float64, contributor string) {
+		if allergen, ok := models.FindAllergen(cas, name); ok {
+			name, cas = allergen.Name, allergen.CASNumber
+		}
+		key := strings.ToLower(name)
+		line, ok := lines[key]
+		if !ok {
+			line = &AllergenLine{Name: name, CASNumber: cas}
+			lines[key] = line
+		}
+		line.Percent += percent
+		for _, existing := range line.Contributors {
+			if existing == contributor {
+				return
+			}
+		}
+		line.Contributors = append(line.Contributors, contributor)
+	}
+
+	for _, source := range sources {
+		if source.Percent <= 0 {
+			continue
+		}
+		if allergen, ok := models.AllergenOf(source.Chemical); ok {
+			add(allergen.Name, allergen.CASNumber, source.Percent, source.Chemical.IngredientName)
+			continue
+		}
+		for _, constituent := range data.For(source.Chemical) {
+			add(constituent.Allergen, constituent.AllergenCAS, source.Percent*constituent.Percentage/100, source.Chemical.IngredientName)
+		}
+	}
+
+	for _, line := range lines {
+		report.Lines = append(report.Lines, *line)
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		if report.Lines[i].Percent != report.Lines[j].Percent {
+			return report.Lines[i].Percent > report.Lines[j].Percent
+		}
+		return report.Lines[i].Name < report.Lines[j].Name
+	})
+	return report
+}
@@ -0,0 +1,38 @@
+package pages
+
+import (
+	"math"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBuildAllergenReport(t *testing.T) {
+	t.Parallel()
+
+	data := models.Constituents{
+		{MaterialName: "Lavender oil", Allergen: "Linalool", AllergenCAS: "78-70-6", Percentage: 30},
+		{MaterialName: "Lavender oil", Allergen: "Limonene", AllergenCAS: "5989-27-5", Percentage: 0.01},
+	}
+	sources := []AllergenSource{
+		{Chemical: models.AromaChemical{IngredientName: "Lavender oil"}, Percent: 20},
+		{Chemical: models.AromaChemical{IngredientName: "Linalool", CASNumber: "78-70-6"}, Percent: 10},
+		{Chemical: models.AromaChemical{IngredientName: "Iso E Super", CASNumber: "54464-57-2"}, Percent: 70},
+	}
+
+	report := BuildAllergenReport(1, 10, sources, data)
+	if len(report.Lines) != 2 {
+		t.Fatalf("expected two allergens, got %+v", report.Lines)
+	}
+	linalool, limonene := report.Lines[0], report.Lines[1]
+	if linalool.Name != "Linalool" || math.Abs(linalool.Percent-16) > 1e-9 || len(linalool.Contributors) != 2 {
+		t.Fatalf("unexpected linalool line: %+v", linalool)
+	}
+	if !report.RinseOff(linalool) {
+		t.Fatal("1.6% linalool in the product must be declared")
+	}
+	// 0.002% of the concentrate is 0.0002% of the product, under both thresholds.
+	if math.Abs(limonene.Percent-0.002) > 1e-9 || report.LeaveOn(limonene) {
+		t.Fatalf("unexpected limonene line: %+v", limonene)
+	}
+}
@@ -0,0 +1,164 @@
+package pages
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"perfugo/models"
+)
+
+// An ingredient is flagged as systematically mis-weighed when at least
+// BiasMinBatches batches all erred the same way and the mean deviation
+// exceeds BiasThresholdPercent of the target.
+const (
+	BiasMinBatches       = 3
+	BiasThresholdPercent = 1.0
+)
+
+// BatchVarianceReport compares actual weights with targets across the
+// finalized batches of a formula.
+type BatchVarianceReport struct {
+	FormulaID   uint
+	FormulaName string
+	Batches     []BatchYield
+	Lines       []BatchVarianceLine
+	Target      float64
+	Actual      float64
+}
+
+// BatchYield is the target and weighed total of one batch, in milligrams.
+type BatchYield struct {
+	ID        uint
+	LotNumber string
+	Date      string
+	Target    float64
+	Actual    float64
+}
+
+// BatchVarianceLine aggregates the deviations of one ingredient across
+// batches. Deviations are in milligrams and percent of the target.
+type BatchVarianceLine struct {
+	Ingredient     string
+	Dilution       string
+	Samples        int
+	Over           int
+	Under          int
+	MeanDeviation  float64
+	MeanPercent    float64
+	MeanAbsPercent float64
+}
+
+// Yield is the weighed total as a percentage of the target total.
+func (b BatchYield) Yield() float64 {
+	return yieldPercent(b.Actual, b.Target)
+}
+
+// Yield is the overall weighed total as a percentage of the target total.
+func (r BatchVarianceReport) Yield() float64 {
+	return yieldPercent(r.Actual, r.Target)
+}
+
+// Bias describes a systematic weighing error, such as "Consistently over",
+// or returns "" when the deviations do not point one way.
+func (l BatchVarianceLine) Bias() string {
+	if l.Samples < BiasMinBatches || math.Abs(l.MeanPercent) <= BiasThresholdPercent {
+		return ""
+	}
+	switch l.Samples {
+	case l.Over:
+		return "Consistently over"
+	case l.Under:
+		return "Consistently under"
+	}
+	return ""
+}
+
+// BuildBatchVariance aggregates the weighed lines of the batches. Lines are
+// matched by ingredient and dilution, and sorted with the largest mean
+// absolute deviation first.
+func BuildBatchVariance(formulaID uint, batches []models.ProductionBatch) BatchVarianceReport {
+	report := BatchVarianceReport{FormulaID: formulaID}
+	type accumulator struct {
+		line       BatchVarianceLine
+		sumDev     float64
+		sumPct     float64
+		sumAbsPct  float64
+		percentCnt int
+	}
+	byKey := make(map[string]*accumulator)
+	var order []string
+
+	for _, batch := range batches {
+		if report.FormulaName == "" {
+			report.FormulaName = batch.FormulaName
+		}
+		yield := BatchYield{ID: batch.ID, LotNumber: batch.LotNumber, Date: FormatReportDate(batch.CreatedAt)}
+		for _, line := range batch.Lines {
+			if !line.Weighed() {
+				continue
+			}
+			yield.Target += line.TargetQuantity
+			yield.Actual += *line.ActualQuantity
+
+			key := strings.ToLower(strings.TrimSpace(line.IngredientName)) + "|" + line.Dilution
+			acc, ok := byKey[key]
+			if !ok {
+				acc = &accumulator{line: BatchVarianceLine{Ingredient: line.IngredientName, Dilution: line.Dilution}}
+				byKey[key] = acc
+				order = append(order, key)
+			}
+			deviation := line.Deviation()
+			acc.line.Samples++
+			switch {
+			case deviation > 1e-9:
+				acc.line.Over++
+			case deviation < -1e-9:
+				acc.line.Under++
+			}
+			acc.sumDev += deviation
+			if line.TargetQuantity != 0 {
+				acc.sumPct += line.DeviationPercent()
+				acc.sumAbsPct += math.Abs(line.DeviationPercent())
+				acc.percentCnt++
+			}
+		}
+		report.Target += yield.Target
+		report.Actual += yield.Actual
+		report.Batches = append(report.Batches, yield)
+	}
+
+	for _, key := range order {
+		acc := byKey[key]
+		acc.line.MeanDeviation = acc.sumDev / float64(acc.line.Samples)
+		if acc.percentCnt > 0 {
+			acc.line.MeanPercent = acc.sumPct / float64(acc.percentCnt)
+			acc.line.MeanAbsPercent = acc.sumAbsPct / float64(acc.percentCnt)
+		}
+		report.Lines = append(report.Lines, acc.line)
+	}
+	sort.SliceStable(report.Lines, func(i, j int) bool {
+		return report.Lines[i].MeanAbsPercent > report.Lines[j].MeanAbsPercent
+	})
+	return report
+}
+
+// BatchVarianceURL links to the variance report of a formula.
+func BatchVarianceURL(formulaID uint) string {
+	return Path(fmt.Sprintf("/app/production/variance?formula_id=%d", formulaID))
+}
+
+func finalizedBatchCount(n int) string {
+	if n == 1 {
+		return "1 finalized batch"
+	}
+	return fmt.Sprintf("%d finalized batches", n)
+}
+
+func yieldPercent(actual, target float64) float64 {
+	if target == 0 {
+		return 0
+	}
+	return actual / target * 100
+}
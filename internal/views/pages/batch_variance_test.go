@@ -0,0 +1,43 @@
+package pages
+
+import (
+	"math"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBuildBatchVariance(t *testing.T) {
+	t.Parallel()
+
+	weighed := func(v float64) *float64 { return &v }
+	batch := func(lot string, iso, hedione float64) models.ProductionBatch {
+		return models.ProductionBatch{FormulaName: "Eau", LotNumber: lot, Lines: []models.ProductionBatchLine{
+			{IngredientName: "Iso E Super", TargetQuantity: 1000, ActualQuantity: weighed(iso)},
+			{IngredientName: "Hedione", Dilution: "10%", TargetQuantity: 500, ActualQuantity: weighed(hedione)},
+			{IngredientName: "Ambroxan", TargetQuantity: 10},
+		}}
+	}
+	report := BuildBatchVariance(4, []models.ProductionBatch{
+		batch("L1", 1020, 500),
+		batch("L2", 1030, 495),
+		batch("L3", 1010, 505),
+	})
+
+	if len(report.Batches) != 3 || report.FormulaName != "Eau" {
+		t.Fatalf("unexpected batches: %+v", report.Batches)
+	}
+	if report.Target != 4500 || report.Actual != 4560 || math.Abs(report.Batches[0].Yield()-101.3333) > 1e-3 {
+		t.Fatalf("unexpected yield: target %v actual %v first %v", report.Target, report.Actual, report.Batches[0].Yield())
+	}
+	if len(report.Lines) != 2 {
+		t.Fatalf("unweighed lines should be skipped: %+v", report.Lines)
+	}
+	iso, hedione := report.Lines[0], report.Lines[1]
+	if iso.Ingredient != "Iso E Super" || iso.MeanDeviation != 20 || iso.MeanPercent != 2 || iso.Bias() != "Consistently over" {
+		t.Fatalf("unexpected iso line: %+v bias %q", iso, iso.Bias())
+	}
+	if hedione.Over != 1 || hedione.Under != 1 || hedione.MeanDeviation != 0 || hedione.Bias() != "" {
+		t.Fatalf("unexpected hedione line: %+v", hedione)
+	}
+}
@@ -0,0 +1,106 @@
+package pages
+
+import (
+	"sort"
+	"strings"
+
+	"perfugo/models"
+)
+
+// Limits on the number of samples in a blind test.
+const (
+	MinBlindSamples = 2
+	MaxBlindSamples = 24
+)
+
+// BlindTestsPanel lists the user's blind tests alongside the form to start
+// one.
+type BlindTestsPanel struct {
+	Tests    []models.BlindTest
+	Formulas []models.Formula
+	Message  string
+}
+
+// BlindTestView is a single blind test. Before the reveal it only exposes
+// sample codes; afterwards Results maps them back to formulas.
+type BlindTestView struct {
+	Test       models.BlindTest
+	Evaluators []string
+	Results    []BlindResult
+	Message    string
+}
+
+// BlindResult aggregates the scores given to one sample.
+type BlindResult struct {
+	Code     string
+	Formula  string
+	Mean     float64
+	Scores   int
+	Rank     int
+	Comments []string
+}
+
+// BlindSampleCodes lists the sample codes in ascending order, which says
+// nothing about the formulas behind them.
+func BlindSampleCodes(test models.BlindTest) []models.BlindSample {
+	samples := append([]models.BlindSample(nil), test.Samples...)
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Code < samples[j].Code })
+	return samples
+}
+
+// BlindEvaluators lists who has scored the test, in the order they first
+// appear.
+func BlindEvaluators(test models.BlindTest) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, sample := range BlindSampleCodes(test) {
+		for _, score := range sample.Scores {
+			key := strings.ToLower(score.Evaluator)
+			if !seen[key] {
+				seen[key] = true
+				names = append(names, score.Evaluator)
+			}
+		}
+	}
+	return names
+}
+
+// BlindTestResults ranks the samples by mean score, best first. Samples
+// without scores come last and share the lowest rank.
+func BlindTestResults(test models.BlindTest) []BlindResult {
+	results := make([]BlindResult, 0, len(test.Samples))
+	for _, sample := range test.Samples {
+		result := BlindResult{Code: sample.Code, Scores: len(sample.Scores)}
+		if sample.Formula != nil {
+			result.Formula = sample.Formula.Name
+		}
+		total := 0
+		for _, score := range sample.Scores {
+			total += score.Score
+			if comment := strings.TrimSpace(score.Comment); comment != "" {
+				result.Comments = append(result.Comments, score.Evaluator+": "+comment)
+			}
+		}
+		if result.Scores > 0 {
+			result.Mean = float64(total) / float64(result.Scores)
+		}
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Scores > 0) != (b.Scores > 0) {
+			return a.Scores > 0
+		}
+		if a.Mean != b.Mean {
+			return a.Mean > b.Mean
+		}
+		return a.Code < b.Code
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+		if i > 0 && results[i].Mean == results[i-1].Mean && results[i].Scores > 0 == (results[i-1].Scores > 0) {
+			results[i].Rank = results[i-1].Rank
+		}
+	}
+	return results
+}
@@ -0,0 +1,39 @@
+package pages
+
+import (
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBlindTestResults(t *testing.T) {
+	t.Parallel()
+
+	test := models.BlindTest{Samples: []models.BlindSample{
+		{Code: "731", Formula: &models.Formula{Name: "Mod A"}, Scores: []models.BlindScore{{Evaluator: "Ana", Score: 6}, {Evaluator: "Ben", Score: 8}}},
+		{Code: "118", Formula: &models.Formula{Name: "Mod B"}, Scores: []models.BlindScore{{Evaluator: "Ana", Score: 9, Comment: "Brighter"}, {Evaluator: "ben", Score: 5}}},
+		{Code: "402", Formula: &models.Formula{Name: "Mod C"}},
+		{Code: "265", Formula: &models.Formula{Name: "Mod D"}, Scores: []models.BlindScore{{Evaluator: "Cleo", Score: 3}}},
+	}}
+
+	results := BlindTestResults(test)
+	want := []struct {
+		code string
+		rank int
+	}{{"118", 1}, {"731", 1}, {"265", 3}, {"402", 4}}
+	for i, w := range want {
+		if results[i].Code != w.code || results[i].Rank != w.rank {
+			t.Fatalf("result %d = %+v, want code %s rank %d", i, results[i], w.code, w.rank)
+		}
+	}
+	if results[0].Formula != "Mod B" || results[0].Mean != 7 || len(results[0].Comments) != 1 {
+		t.Fatalf("unexpected top result: %+v", results[0])
+	}
+
+	if got := BlindEvaluators(test); len(got) != 3 || got[0] != "Ana" || got[2] != "Cleo" {
+		t.Fatalf("BlindEvaluators = %v", got)
+	}
+	if codes := BlindSampleCodes(test); codes[0].Code != "118" || codes[3].Code != "731" {
+		t.Fatalf("codes not sorted: %+v", codes)
+	}
+}
@@ -0,0 +1,93 @@
+package pages
+
+import "perfugo/models"
+
+// BlocklistPanel drives the administrator's blocklist controls.
+type BlocklistPanel struct {
+	Entries []models.BlockedMaterial
+	Message string
+}
+
+// ComplianceFinding is a blocklisted material found in a formula. Via names
+// the sub-formula it comes from, or is empty for a direct ingredient.
+type ComplianceFinding struct {
+	Ingredient string
+	Via        string
+	Entry      models.BlockedMaterial
+}
+
+// ConstraintFinding is a material that breaks the formula's naturals-only or
+// vegan constraint.
+type ConstraintFinding struct {
+	Ingredient string
+	Via        string
+	Violations []string
+}
+
+// ComplianceReport lists the blocklisted materials and constraint violations
+// in a formula's expanded composition.
+type ComplianceReport struct {
+	FormulaID   uint
+	Findings    []ComplianceFinding
+	Constraints []ConstraintFinding
+}
+
+// Blocked reports whether the formula contains a prohibited material.
+func (r ComplianceReport) Blocked() bool {
+	for _, finding := range r.Findings {
+		if finding.Entry.Prohibited() {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildComplianceReport walks the formula and its sub-formulas, checking
+// every aroma chemical against the blocklist and the formula's constraints.
+// A material reached along several paths is reported once per path.
+func BuildComplianceReport(formula models.Formula, ingredients []models.FormulaIngredient, chemicals []models.AromaChemical, list models.Blocklist) ComplianceReport {
+	report := ComplianceReport{FormulaID: formula.ID}
+	if len(list) == 0 && !formula.HasConstraints() {
+		return report
+	}
+	byFormula := map[uint][]models.FormulaIngredient{}
+	for _, ingredient := range ingredients {
+		byFormula[ingredient.FormulaID] = append(byFormula[ingredient.FormulaID], ingredient)
+	}
+
+	visiting := map[uint]bool{}
+	var walk func(id uint, via string)
+	walk = func(id uint, via string) {
+		if visiting[id] {
+			return
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+		for _, ingredient := range byFormula[id] {
+			switch {
+			case ingredient.AromaChemicalID != nil:
+				chemical := FindAromaChemical(chemicals, *ingredient.AromaChemicalID)
+				if chemical == nil {
+					continue
+				}
+				if entry, ok := list.Match(*chemical); ok {
+					report.Findings = append(report.Findings, ComplianceFinding{Ingredient: chemical.IngredientName, Via: via, Entry: entry})
+				}
+				if violations := formula.ConstraintViolations(*chemical); len(violations) > 0 {
+					report.Constraints = append(report.Constraints, ConstraintFinding{Ingredient: chemical.IngredientName, Via: via, Violations: violations})
+				}
+			case ingredient.SubFormulaID != nil:
+				name := "sub-formula"
+				if ingredient.SubFormula != nil {
+					name = ingredient.SubFormula.Name
+				}
+				if via != "" {
+					name = via + " › " + name
+				}
+				walk(*ingredient.SubFormulaID, name)
+			}
+		}
+	}
+	walk(formula.ID, "")
+	return report
+}
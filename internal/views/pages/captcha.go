@@ -0,0 +1,14 @@
+package pages
+
+// CaptchaWidget describes the challenge widget rendered on the login and
+// signup forms. The zero value renders nothing.
+type CaptchaWidget struct {
+	ScriptURL string
+	Class     string
+	SiteKey   string
+}
+
+// Enabled reports whether a widget should be rendered.
+func (c CaptchaWidget) Enabled() bool {
+	return c.ScriptURL != "" && c.SiteKey != ""
+}
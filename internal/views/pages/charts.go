@@ -0,0 +1,61 @@
+package pages
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/a-h/templ"
+
+	"perfugo/internal/charts"
+)
+
+// costChartBars is how many ingredients the cost breakdown names before
+// grouping the rest as "Other".
+const costChartBars = 6
+
+// chartSVG renders a chart inline as SVG.
+func chartSVG(chart interface{ Render(io.Writer) error }) templ.Component {
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		return chart.Render(w)
+	})
+}
+
+// PyramidChart draws a pyramid distribution as bars on a 0–100% scale.
+func PyramidChart(shares []PyramidShare, format NumberFormat) charts.BarChart {
+	chart := charts.BarChart{Title: "Pyramid distribution", Max: 100}
+	for _, share := range shares {
+		chart.Bars = append(chart.Bars, charts.Bar{
+			Label: share.Label,
+			Value: share.Share,
+			Note:  format.FormatPercent(share.Share),
+		})
+	}
+	return chart
+}
+
+// CostChart draws the costliest lines of a formula sheet, largest first,
+// with the remaining costed lines summed as "Other".
+func CostChart(lines []FormulaPrintLine) charts.BarChart {
+	costed := make([]FormulaPrintLine, 0, len(lines))
+	for _, line := range lines {
+		if line.Cost > 0 {
+			costed = append(costed, line)
+		}
+	}
+	sort.SliceStable(costed, func(i, j int) bool { return costed[i].Cost > costed[j].Cost })
+
+	chart := charts.BarChart{Title: "Cost breakdown"}
+	other := 0.0
+	for idx, line := range costed {
+		if idx >= costChartBars {
+			other += line.Cost
+			continue
+		}
+		chart.Bars = append(chart.Bars, charts.Bar{Label: line.Name, Value: line.Cost, Note: FormatCost(line.Cost)})
+	}
+	if other > 0 {
+		chart.Bars = append(chart.Bars, charts.Bar{Label: "Other", Value: other, Note: FormatCost(other)})
+	}
+	return chart
+}
@@ -0,0 +1,342 @@
+package pages
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"perfugo/models"
+)
+
+// MaxReportFilters is how many filter rows the report builder offers.
+const MaxReportFilters = 3
+
+// ReportColumn is a column a custom report can show. Numeric columns can be
+// filtered with greater and less than.
+type ReportColumn struct {
+	Key     string
+	Label   string
+	Numeric bool
+}
+
+// ReportSource names a record type custom reports can list.
+type ReportSource struct {
+	ID      string
+	Label   string
+	Columns []ReportColumn
+}
+
+// ReportSources lists what custom reports can be built over, with the
+// columns each offers in display order.
+var ReportSources = []ReportSource{
+	{ID: models.ReportSourceIngredients, Label: "Ingredients", Columns: []ReportColumn{
+		{Key: "name", Label: "Name"},
+		{Key: "cas", Label: "CAS"},
+		{Key: "type", Label: "Type"},
+		{Key: "pyramid", Label: "Pyramid"},
+		{Key: "wheel", Label: "Wheel"},
+		{Key: "origin", Label: "Origin"},
+		{Key: "strength", Label: "Strength", Numeric: true},
+		{Key: "max_ifra", Label: "Max IFRA %", Numeric: true},
+		{Key: "price_per_mg", Label: "Price per mg", Numeric: true},
+		{Key: "public", Label: "Public"},
+	}},
+	{ID: models.ReportSourceFormulas, Label: "Formulas", Columns: []ReportColumn{
+		{Key: "name", Label: "Name"},
+		{Key: "version", Label: "Version", Numeric: true},
+		{Key: "status", Label: "Status"},
+		{Key: "client", Label: "Client"},
+		{Key: "deadline", Label: "Deadline"},
+		{Key: "ingredients", Label: "Ingredients", Numeric: true},
+		{Key: "missing", Label: "Missing materials", Numeric: true},
+		{Key: "created", Label: "Created"},
+	}},
+	{ID: models.ReportSourceBatches, Label: "Production batches", Columns: []ReportColumn{
+		{Key: "formula", Label: "Formula"},
+		{Key: "version", Label: "Version", Numeric: true},
+		{Key: "lot", Label: "Lot"},
+		{Key: "status", Label: "Status"},
+		{Key: "target", Label: "Target (mg)", Numeric: true},
+		{Key: "lines", Label: "Lines", Numeric: true},
+		{Key: "weighed", Label: "Weighed", Numeric: true},
+		{Key: "signed_by", Label: "Signed by"},
+		{Key: "started", Label: "Started"},
+	}},
+}
+
+// FindReportSource returns the source with the given ID.
+func FindReportSource(id string) (ReportSource, bool) {
+	for _, source := range ReportSources {
+		if source.ID == id {
+			return source, true
+		}
+	}
+	return ReportSource{}, false
+}
+
+// Column returns the source's column with the given key.
+func (s ReportSource) Column(key string) (ReportColumn, bool) {
+	for _, column := range s.Columns {
+		if column.Key == key {
+			return column, true
+		}
+	}
+	return ReportColumn{}, false
+}
+
+// ReportFilterOperators lists the filter operators offered by the builder.
+var ReportFilterOperators = []struct{ ID, Label string }{
+	{models.ReportFilterContains, "contains"},
+	{models.ReportFilterEquals, "equals"},
+	{models.ReportFilterGreater, "greater than"},
+	{models.ReportFilterLess, "less than"},
+}
+
+// CustomReportURL links to a saved report's results, or to its CSV export.
+func CustomReportURL(id uint, csv bool) string {
+	url := fmt.Sprintf("/app/reports/custom/run?id=%d", id)
+	if csv {
+		url += "&format=csv"
+	}
+	return Path(url)
+}
+
+// ReportSourceLabel names a report source for display.
+func ReportSourceLabel(id string) string {
+	if source, ok := FindReportSource(id); ok {
+		return source.Label
+	}
+	return id
+}
+
+// CheckReportDefinition explains why a definition cannot be run, or returns
+// an empty string when it can.
+func CheckReportDefinition(definition models.ReportDefinition) string {
+	if strings.TrimSpace(definition.Name) == "" {
+		return "Name the report."
+	}
+	source, ok := FindReportSource(definition.Source)
+	if !ok {
+		return "Choose what the report lists."
+	}
+	keys := definition.ColumnKeys()
+	if len(keys) == 0 {
+		return "Choose at least one column."
+	}
+	for _, key := range keys {
+		if _, ok := source.Column(key); !ok {
+			return fmt.Sprintf("%s reports have no %q column.", source.Label, key)
+		}
+	}
+	for _, filter := range definition.FilterList() {
+		column, ok := source.Column(filter.Column)
+		if !ok {
+			return fmt.Sprintf("%s reports cannot be filtered by %q.", source.Label, filter.Column)
+		}
+		switch filter.Operator {
+		case models.ReportFilterContains, models.ReportFilterEquals:
+		case models.ReportFilterGreater, models.ReportFilterLess:
+			if !column.Numeric {
+				return fmt.Sprintf("%s is not a number, so it cannot be compared.", column.Label)
+			}
+			if _, err := strconv.ParseFloat(strings.TrimSpace(filter.Value), 64); err != nil {
+				return fmt.Sprintf("Compare %s with a number.", column.Label)
+			}
+		default:
+			return "Choose one of the listed filter operators."
+		}
+	}
+	return ""
+}
+
+// CustomReportData holds the records a custom report can list. Formulas
+// are expected to carry their stock for the viewing user.
+type CustomReportData struct {
+	AromaChemicals     []models.AromaChemical
+	Formulas           []models.Formula
+	FormulaIngredients []models.FormulaIngredient
+	Batches            []models.ProductionBatch
+}
+
+// CustomReport is an executed report: one header per column and one row of
+// cells per matching record.
+type CustomReport struct {
+	ID      uint
+	Name    string
+	Headers []string
+	Rows    [][]string
+}
+
+// RunCustomReport lists the source records that pass every filter. The
+// definition must have passed CheckReportDefinition.
+func RunCustomReport(definition models.ReportDefinition, data CustomReportData) CustomReport {
+	report := CustomReport{ID: definition.ID, Name: definition.Name}
+	source, ok := FindReportSource(definition.Source)
+	if !ok {
+		return report
+	}
+
+	var columns []ReportColumn
+	for _, key := range definition.ColumnKeys() {
+		if column, ok := source.Column(key); ok {
+			columns = append(columns, column)
+			report.Headers = append(report.Headers, column.Label)
+		}
+	}
+	filters := definition.FilterList()
+
+	emit := func(value func(key string) string) {
+		for _, filter := range filters {
+			column, _ := source.Column(filter.Column)
+			if !reportFilterMatches(filter, column, value(filter.Column)) {
+				return
+			}
+		}
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, value(column.Key))
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	switch source.ID {
+	case models.ReportSourceIngredients:
+		for _, chemical := range data.AromaChemicals {
+			emit(func(key string) string { return ingredientReportValue(chemical, key) })
+		}
+	case models.ReportSourceFormulas:
+		rows := make(map[uint]int)
+		for _, ingredient := range data.FormulaIngredients {
+			rows[ingredient.FormulaID]++
+		}
+		for _, formula := range data.Formulas {
+			emit(func(key string) string { return formulaReportValue(formula, rows[formula.ID], key) })
+		}
+	case models.ReportSourceBatches:
+		for _, batch := range data.Batches {
+			emit(func(key string) string { return batchReportValue(batch, key) })
+		}
+	}
+	return report
+}
+
+func reportFilterMatches(filter models.ReportFilter, column ReportColumn, value string) bool {
+	want := strings.TrimSpace(filter.Value)
+	switch filter.Operator {
+	case models.ReportFilterEquals:
+		return strings.EqualFold(value, want)
+	case models.ReportFilterGreater, models.ReportFilterLess:
+		got, err := strconv.ParseFloat(value, 64)
+		limit, limitErr := strconv.ParseFloat(want, 64)
+		if !column.Numeric || err != nil || limitErr != nil {
+			return false
+		}
+		if filter.Operator == models.ReportFilterGreater {
+			return got > limit
+		}
+		return got < limit
+	default:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(want))
+	}
+}
+
+func ingredientReportValue(chemical models.AromaChemical, key string) string {
+	switch key {
+	case "name":
+		return chemical.IngredientName
+	case "cas":
+		return strings.TrimSpace(chemical.CASNumber)
+	case "type":
+		return chemical.Type
+	case "pyramid":
+		return PyramidPositionLabel(chemical.PyramidPosition)
+	case "wheel":
+		return chemical.WheelPosition
+	case "origin":
+		return OriginLabel(chemical)
+	case "strength":
+		return strconv.Itoa(chemical.Strength)
+	case "max_ifra":
+		return reportNumber(chemical.MaxIFRAPercentage)
+	case "price_per_mg":
+		return reportNumber(chemical.PricePerMg)
+	case "public":
+		return reportYesNo(chemical.Public)
+	}
+	return ""
+}
+
+func formulaReportValue(formula models.Formula, ingredients int, key string) string {
+	switch key {
+	case "name":
+		return formula.Name
+	case "version":
+		return strconv.Itoa(formula.Version)
+	case "status":
+		return FormulaStatusLabel(formula.Status)
+	case "client":
+		return formula.Client
+	case "deadline":
+		if formula.Deadline == nil {
+			return ""
+		}
+		return reportDate(*formula.Deadline)
+	case "ingredients":
+		return strconv.Itoa(ingredients)
+	case "missing":
+		return strconv.Itoa(formula.Stock.Missing)
+	case "created":
+		return reportDate(formula.CreatedAt)
+	}
+	return ""
+}
+
+func batchReportValue(batch models.ProductionBatch, key string) string {
+	switch key {
+	case "formula":
+		return batch.FormulaName
+	case "version":
+		return strconv.Itoa(batch.FormulaVersion)
+	case "lot":
+		return batch.LotNumber
+	case "status":
+		return batch.Status
+	case "target":
+		return reportNumber(batch.TargetQuantity)
+	case "lines":
+		return strconv.Itoa(len(batch.Lines))
+	case "weighed":
+		weighed := 0
+		for _, line := range batch.Lines {
+			if line.Weighed() {
+				weighed++
+			}
+		}
+		return strconv.Itoa(weighed)
+	case "signed_by":
+		return batch.SignedByName
+	case "started":
+		return reportDate(batch.CreatedAt)
+	}
+	return ""
+}
+
+// reportDate uses ISO dates so exported reports sort and import cleanly.
+func reportDate(v time.Time) string {
+	if v.IsZero() {
+		return ""
+	}
+	return v.Format("2006-01-02")
+}
+
+func reportNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func reportYesNo(v bool) string {
+	if v {
+		return "Yes"
+	}
+	return "No"
+}
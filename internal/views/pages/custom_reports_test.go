@@ -0,0 +1,63 @@
+package pages
+
+import (
+	"reflect"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestRunCustomReport(t *testing.T) {
+	data := CustomReportData{
+		AromaChemicals: []models.AromaChemical{
+			{IngredientName: "Rose absolute", CASNumber: "8007-01-0", Origin: models.OriginNatural, PricePerMg: 0.9},
+			{IngredientName: "Hedione", CASNumber: "24851-98-7", Origin: models.OriginSynthetic, PricePerMg: 0.01},
+			{IngredientName: "Oakmoss absolute", Origin: models.OriginNatural, PricePerMg: 0.2},
+		},
+	}
+	definition := models.ReportDefinition{
+		Name:    "Costly naturals",
+		Source:  models.ReportSourceIngredients,
+		Columns: "name,price_per_mg,cas",
+	}
+	if err := definition.SetFilters([]models.ReportFilter{
+		{Column: "origin", Operator: models.ReportFilterContains, Value: "natural"},
+		{Column: "price_per_mg", Operator: models.ReportFilterGreater, Value: "0.5"},
+	}); err != nil {
+		t.Fatalf("set filters: %v", err)
+	}
+	if problem := CheckReportDefinition(definition); problem != "" {
+		t.Fatalf("unexpected problem: %s", problem)
+	}
+
+	report := RunCustomReport(definition, data)
+	if want := []string{"Name", "Price per mg", "CAS"}; !reflect.DeepEqual(report.Headers, want) {
+		t.Fatalf("headers = %v, want %v", report.Headers, want)
+	}
+	if want := [][]string{{"Rose absolute", "0.9", "8007-01-0"}}; !reflect.DeepEqual(report.Rows, want) {
+		t.Fatalf("rows = %v, want %v", report.Rows, want)
+	}
+}
+
+func TestCheckReportDefinition(t *testing.T) {
+	cases := map[string]struct {
+		definition models.ReportDefinition
+		filters    []models.ReportFilter
+		want       string
+	}{
+		"no columns":      {models.ReportDefinition{Name: "x", Source: models.ReportSourceFormulas}, nil, "Choose at least one column."},
+		"unknown source":  {models.ReportDefinition{Name: "x", Source: "suppliers", Columns: "name"}, nil, "Choose what the report lists."},
+		"foreign column":  {models.ReportDefinition{Name: "x", Source: models.ReportSourceBatches, Columns: "cas"}, nil, `Production batches reports have no "cas" column.`},
+		"text comparison": {models.ReportDefinition{Name: "x", Source: models.ReportSourceFormulas, Columns: "name"}, []models.ReportFilter{{Column: "client", Operator: models.ReportFilterLess, Value: "3"}}, "Client is not a number, so it cannot be compared."},
+		"bad number":      {models.ReportDefinition{Name: "x", Source: models.ReportSourceBatches, Columns: "lot"}, []models.ReportFilter{{Column: "lines", Operator: models.ReportFilterGreater, Value: "many"}}, "Compare Lines with a number."},
+	}
+	for name, tc := range cases {
+		definition := tc.definition
+		if err := definition.SetFilters(tc.filters); err != nil {
+			t.Fatalf("%s: set filters: %v", name, err)
+		}
+		if got := CheckReportDefinition(definition); got != tc.want {
+			t.Errorf("%s: got %q, want %q", name, got, tc.want)
+		}
+	}
+}
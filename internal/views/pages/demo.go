@@ -0,0 +1,5 @@
+package pages
+
+// DemoModeMessage explains why a change is refused when the workspace runs
+// on the read-only demo library because no database is configured.
+const DemoModeMessage = "This workspace is a read-only demo because no database is configured, so changes cannot be saved."
@@ -0,0 +1,190 @@
+package pages
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"perfugo/models"
+)
+
+// Limits on the experiment matrix generator.
+const (
+	MaxMatrixVariables = 2
+	MaxMatrixLevels    = 6
+	MaxMatrixMods      = 24
+)
+
+// MatrixVariable is a material whose share of the formula is varied across
+// the listed levels, in percent.
+type MatrixVariable struct {
+	Chemical models.AromaChemical
+	Levels   []float64
+}
+
+// MatrixSetting is the level a mod uses for one variable.
+type MatrixSetting struct {
+	Name    string
+	Percent float64
+}
+
+// MatrixMod is one generated variation of the base formula.
+type MatrixMod struct {
+	Number      int
+	Name        string
+	Settings    []MatrixSetting
+	Ingredients []models.FormulaIngredient
+}
+
+// Label lists the mod's settings, such as "Iso E Super 10%, Hedione 5%".
+func (m MatrixMod) Label() string {
+	parts := make([]string, 0, len(m.Settings))
+	for _, setting := range m.Settings {
+		parts = append(parts, fmt.Sprintf("%s %s%%", setting.Name, strconv.FormatFloat(setting.Percent, 'f', -1, 64)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseMatrixLevels reads levels written as "5, 10, 15" or "5/10/15".
+func ParseMatrixLevels(raw string) ([]float64, error) {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '/' || r == ';' || r == ' '
+	})
+	levels := make([]float64, 0, len(fields))
+	seen := map[float64]bool{}
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(field, "%"), 64)
+		if err != nil || value < 0 || value >= 100 {
+			return nil, fmt.Errorf("%q is not a percentage below 100", field)
+		}
+		if !seen[value] {
+			seen[value] = true
+			levels = append(levels, value)
+		}
+	}
+	if len(levels) == 0 {
+		return nil, errors.New("give at least one level")
+	}
+	if len(levels) > MaxMatrixLevels {
+		return nil, fmt.Errorf("use at most %d levels", MaxMatrixLevels)
+	}
+	return levels, nil
+}
+
+// BuildExperimentMatrix generates one mod per combination of variable
+// levels. Each variable is set to its level as a share of the mod, replacing
+// the base rows that use the material neat, and the other rows are scaled to
+// fill the rest so each mod weighs the same as the base. Mods are numbered
+// from 1 with the first variable changing slowest.
+func BuildExperimentMatrix(base models.Formula, ingredients []models.FormulaIngredient, variables []MatrixVariable) ([]MatrixMod, error) {
+	if len(variables) == 0 || len(variables) > MaxMatrixVariables {
+		return nil, fmt.Errorf("choose one or %d variables", MaxMatrixVariables)
+	}
+	count := 1
+	for i, variable := range variables {
+		for _, other := range variables[:i] {
+			if other.Chemical.ID == variable.Chemical.ID {
+				return nil, errors.New("choose a different material for each variable")
+			}
+		}
+		count *= len(variable.Levels)
+	}
+	if count > MaxMatrixMods {
+		return nil, fmt.Errorf("the matrix would create %d mods; the limit is %d", count, MaxMatrixMods)
+	}
+
+	varied := func(ingredient models.FormulaIngredient) bool {
+		if ingredient.AromaChemicalID == nil || ingredient.StockSolutionID != nil {
+			return false
+		}
+		for _, variable := range variables {
+			if *ingredient.AromaChemicalID == variable.Chemical.ID {
+				return true
+			}
+		}
+		return false
+	}
+	total, rest := 0.0, 0.0
+	for _, ingredient := range ingredients {
+		grams := ingredientGrams(ingredient)
+		total += grams
+		if !varied(ingredient) {
+			rest += grams
+		}
+	}
+	if total <= 0 {
+		return nil, errors.New("the base formula has no weight to vary")
+	}
+
+	mods := make([]MatrixMod, 0, count)
+	levels := make([]int, len(variables))
+	for number := 1; number <= count; number++ {
+		mod := MatrixMod{Number: number, Name: fmt.Sprintf("%s M%02d", base.Name, number)}
+		share := 0.0
+		for i, variable := range variables {
+			percent := variable.Levels[levels[i]]
+			share += percent
+			mod.Settings = append(mod.Settings, MatrixSetting{Name: variable.Chemical.IngredientName, Percent: percent})
+		}
+		if share >= 100 {
+			return nil, fmt.Errorf("%s adds up to %g%%; the variables must leave room for the rest of the formula", mod.Label(), share)
+		}
+		if rest <= 0 && share < 100 {
+			return nil, errors.New("the base formula has nothing besides the variables to scale")
+		}
+
+		scale := total * (100 - share) / 100 / rest
+		for _, ingredient := range ingredients {
+			if varied(ingredient) {
+				continue
+			}
+			row := models.FormulaIngredient{
+				Amount:          roundGrams(ingredientGrams(ingredient) * scale),
+				Unit:            "g",
+				AromaChemicalID: ingredient.AromaChemicalID,
+				SubFormulaID:    ingredient.SubFormulaID,
+				StockSolutionID: ingredient.StockSolutionID,
+				Position:        len(mod.Ingredients),
+			}
+			mod.Ingredients = append(mod.Ingredients, row)
+		}
+		for i, variable := range variables {
+			id := variable.Chemical.ID
+			mod.Ingredients = append(mod.Ingredients, models.FormulaIngredient{
+				Amount:          roundGrams(total * mod.Settings[i].Percent / 100),
+				Unit:            "g",
+				AromaChemicalID: &id,
+				Position:        len(mod.Ingredients),
+			})
+		}
+		mods = append(mods, mod)
+
+		for i := len(levels) - 1; i >= 0; i-- {
+			levels[i]++
+			if levels[i] < len(variables[i].Levels) {
+				break
+			}
+			levels[i] = 0
+		}
+	}
+	return mods, nil
+}
+
+// ingredientGrams converts a composition row to grams, taking millilitres as
+// grams.
+func ingredientGrams(ingredient models.FormulaIngredient) float64 {
+	switch strings.ToLower(strings.TrimSpace(ingredient.Unit)) {
+	case "mg":
+		return ingredient.Amount / 1000
+	case "kg":
+		return ingredient.Amount * 1000
+	default:
+		return ingredient.Amount
+	}
+}
+
+func roundGrams(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
@@ -0,0 +1,64 @@
+package pages
+
+import (
+	"math"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBuildExperimentMatrix(t *testing.T) {
+	t.Parallel()
+
+	iso := models.AromaChemical{IngredientName: "Iso E Super"}
+	iso.ID = 1
+	hedione := models.AromaChemical{IngredientName: "Hedione"}
+	hedione.ID = 2
+	bergamot := uint(3)
+	base := models.Formula{Name: "Citrus"}
+	ingredients := []models.FormulaIngredient{
+		{AromaChemicalID: &bergamot, Amount: 9, Unit: "g"},
+		{AromaChemicalID: &iso.ID, Amount: 1000, Unit: "mg"},
+	}
+
+	mods, err := BuildExperimentMatrix(base, ingredients, []MatrixVariable{
+		{Chemical: iso, Levels: []float64{5, 20}},
+		{Chemical: hedione, Levels: []float64{0, 10}},
+	})
+	if err != nil {
+		t.Fatalf("BuildExperimentMatrix: %v", err)
+	}
+	if len(mods) != 4 {
+		t.Fatalf("expected 4 mods, got %d", len(mods))
+	}
+	want := []string{"Iso E Super 5%, Hedione 0%", "Iso E Super 5%, Hedione 10%", "Iso E Super 20%, Hedione 0%", "Iso E Super 20%, Hedione 10%"}
+	for i, mod := range mods {
+		if mod.Label() != want[i] {
+			t.Fatalf("mod %d label = %q, want %q", i+1, mod.Label(), want[i])
+		}
+	}
+	last := mods[3]
+	if last.Name != "Citrus M04" {
+		t.Fatalf("unexpected name %q", last.Name)
+	}
+	// 10 g base: Iso E Super 2 g, Hedione 1 g and bergamot fills the other 7 g.
+	got := map[uint]float64{}
+	total := 0.0
+	for _, row := range last.Ingredients {
+		got[*row.AromaChemicalID] = row.Amount
+		total += row.Amount
+	}
+	if math.Abs(got[bergamot]-7) > 1e-9 || math.Abs(got[iso.ID]-2) > 1e-9 || math.Abs(got[hedione.ID]-1) > 1e-9 || math.Abs(total-10) > 1e-9 {
+		t.Fatalf("unexpected composition: %+v", last.Ingredients)
+	}
+
+	if _, err := BuildExperimentMatrix(base, ingredients, []MatrixVariable{{Chemical: iso, Levels: []float64{60}}, {Chemical: hedione, Levels: []float64{40}}}); err == nil {
+		t.Fatal("expected an error when the variables fill the formula")
+	}
+	if _, err := ParseMatrixLevels("5 / 10%, 15"); err != nil {
+		t.Fatalf("ParseMatrixLevels: %v", err)
+	}
+	if _, err := ParseMatrixLevels("5, lots"); err == nil {
+		t.Fatal("expected ParseMatrixLevels to reject text")
+	}
+}
@@ -0,0 +1,51 @@
+package pages
+
+import (
+	"sort"
+	"strings"
+
+	"perfugo/models"
+)
+
+// FormulaBoardColumn is one status lane on the formula board.
+type FormulaBoardColumn struct {
+	Status   string
+	Label    string
+	Formulas []models.Formula
+}
+
+// FormulaBoardColumns groups formulas into one lane per brief status, in
+// workflow order. Within a lane the nearest deadline comes first and
+// formulas without one follow alphabetically.
+func FormulaBoardColumns(formulas []models.Formula) []FormulaBoardColumn {
+	statuses := models.FormulaStatuses()
+	columns := make([]FormulaBoardColumn, 0, len(statuses))
+	index := make(map[string]int, len(statuses))
+	for i, status := range statuses {
+		index[status] = i
+		columns = append(columns, FormulaBoardColumn{Status: status, Label: FormulaStatusLabel(status)})
+	}
+	for _, formula := range formulas {
+		i, ok := index[FormulaStatus(formula)]
+		if !ok {
+			i = index[models.FormulaStatusDraft]
+		}
+		columns[i].Formulas = append(columns[i].Formulas, formula)
+	}
+	for i := range columns {
+		lane := columns[i].Formulas
+		sort.SliceStable(lane, func(a, b int) bool {
+			da, db := lane[a].Deadline, lane[b].Deadline
+			switch {
+			case da != nil && db != nil && !da.Equal(*db):
+				return da.Before(*db)
+			case da != nil && db == nil:
+				return true
+			case da == nil && db != nil:
+				return false
+			}
+			return strings.ToLower(lane[a].Name) < strings.ToLower(lane[b].Name)
+		})
+	}
+	return columns
+}
@@ -0,0 +1,119 @@
+package pages
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"perfugo/models"
+)
+
+// dateInputLayout is the value format of date inputs.
+const dateInputLayout = "2006-01-02"
+
+// FormulaStatus returns the formula's brief status, treating rows saved
+// before the brief existed as drafts.
+func FormulaStatus(formula models.Formula) string {
+	if formula.Status == "" {
+		return models.FormulaStatusDraft
+	}
+	return formula.Status
+}
+
+// FormulaStatusLabel names a brief status for display.
+func FormulaStatusLabel(status string) string {
+	switch status {
+	case models.FormulaStatusInReview:
+		return "In review"
+	case models.FormulaStatusApproved:
+		return "Approved"
+	default:
+		return "Draft"
+	}
+}
+
+// FormulaClients lists the distinct clients named in formula briefs,
+// alphabetically, for the client filter.
+func FormulaClients(formulas []models.Formula) []string {
+	seen := map[string]struct{}{}
+	clients := []string{}
+	for _, formula := range formulas {
+		client := strings.TrimSpace(formula.Client)
+		key := strings.ToLower(client)
+		if client == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return strings.ToLower(clients[i]) < strings.ToLower(clients[j]) })
+	return clients
+}
+
+// HasBrief reports whether any brief field beyond the status is filled in.
+func HasBrief(formula models.Formula) bool {
+	return strings.TrimSpace(formula.Client) != "" || strings.TrimSpace(formula.TargetAudience) != "" ||
+		strings.TrimSpace(formula.Inspiration) != "" || formula.Deadline != nil
+}
+
+// DeadlineInputValue formats the deadline for a date input.
+func DeadlineInputValue(formula *models.Formula) string {
+	if formula == nil {
+		return ""
+	}
+	return DateInputValue(formula.Deadline)
+}
+
+// DateInputValue formats an optional date for a date input.
+func DateInputValue(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(dateInputLayout)
+}
+
+// DeadlineLabel formats the deadline for display, or "" without one.
+func DeadlineLabel(formula models.Formula) string {
+	if formula.Deadline == nil {
+		return ""
+	}
+	return formula.Deadline.Format("02 Jan 2006")
+}
+
+// ParseDateInput reads a date input value. A blank value yields nil, which
+// clears the date.
+func ParseDateInput(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(dateInputLayout, value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// FormulaFilterSummary describes the active formula filters.
+func FormulaFilterSummary(filters FormulaFilters) string {
+	parts := []string{}
+	if filters.Query != "" {
+		parts = append(parts, "“"+filters.Query+"”")
+	}
+	if filters.Status != "" {
+		parts = append(parts, "status "+FormulaStatusLabel(filters.Status))
+	}
+	if filters.Client != "" {
+		parts = append(parts, "client "+filters.Client)
+	}
+	switch filters.Stock {
+	case StockFilterBuildable:
+		parts = append(parts, "all materials in stock")
+	case StockFilterMissing:
+		parts = append(parts, "missing materials")
+	}
+	return "Filtered by " + strings.Join(parts, ", ") + "."
+}
@@ -0,0 +1,57 @@
+package pages
+
+import "time"
+
+// PrintOptions selects the optional columns of the formula print view.
+type PrintOptions struct {
+	CAS      bool
+	Cost     bool
+	Supplier bool
+	Notes    bool
+}
+
+// DefaultPrintOptions includes every optional column.
+func DefaultPrintOptions() PrintOptions {
+	return PrintOptions{CAS: true, Cost: true, Supplier: true, Notes: true}
+}
+
+// FormulaPrintLine is a single composition row of the print view.
+type FormulaPrintLine struct {
+	Order     int
+	Name      string
+	Kind      string
+	Dilution  string
+	CASNumber string
+	Amount    float64
+	Unit      string
+	Percent   float64
+	Cost      float64
+	Supplier  string
+}
+
+// FormulaPrintData drives the print-optimised formula sheet.
+type FormulaPrintData struct {
+	FormulaName    string
+	FormulaVersion int
+	Notes          string
+	Lines          []FormulaPrintLine
+	TotalCost      float64
+	// Pyramid spreads the composition, sub-formulas included, over the
+	// pyramid positions of its materials.
+	Pyramid   []PyramidShare
+	Options   PrintOptions
+	PrintedAt time.Time
+}
+
+// ColumnCount returns the number of table columns so the totals row can
+// span the composition columns.
+func (d FormulaPrintData) ColumnCount() int {
+	count := 4
+	if d.Options.CAS {
+		count++
+	}
+	if d.Options.Supplier {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,79 @@
+package pages
+
+import (
+	"fmt"
+
+	"perfugo/models"
+)
+
+// AnnotateFormulaStock sets each formula's Stock from the materials the user
+// has on hand and on their wishlist, for the buildability badges and filter. Materials used through sub-formulas
+// count towards the formulas that include them.
+func AnnotateFormulaStock(formulas []models.Formula, ingredients []models.FormulaIngredient, onHand, wishlisted map[uint]bool) {
+	rows := make(map[uint][]models.FormulaIngredient)
+	for _, ingredient := range ingredients {
+		rows[ingredient.FormulaID] = append(rows[ingredient.FormulaID], ingredient)
+	}
+
+	var collect func(formulaID uint, materials, visited map[uint]bool)
+	collect = func(formulaID uint, materials, visited map[uint]bool) {
+		if visited[formulaID] {
+			return
+		}
+		visited[formulaID] = true
+		for _, row := range rows[formulaID] {
+			switch {
+			case row.AromaChemicalID != nil:
+				materials[*row.AromaChemicalID] = true
+			case row.SubFormulaID != nil:
+				collect(*row.SubFormulaID, materials, visited)
+			}
+		}
+	}
+
+	for i := range formulas {
+		materials := make(map[uint]bool)
+		collect(formulas[i].ID, materials, make(map[uint]bool))
+		stock := models.FormulaStock{Materials: len(materials)}
+		for id := range materials {
+			if onHand[id] {
+				continue
+			}
+			stock.Missing++
+			if wishlisted[id] {
+				stock.Wishlisted++
+			}
+		}
+		formulas[i].Stock = stock
+	}
+}
+
+// Formula stock filter values.
+const (
+	StockFilterBuildable = "buildable"
+	StockFilterMissing   = "missing"
+)
+
+// FormulaStockLabel is the buildability badge text, or empty for formulas
+// without materials.
+func FormulaStockLabel(stock models.FormulaStock) string {
+	switch {
+	case stock.Materials == 0:
+		return ""
+	case stock.Buildable():
+		return "All in stock"
+	case stock.Missing == 1:
+		return "Missing 1 material"
+	default:
+		return fmt.Sprintf("Missing %d materials", stock.Missing)
+	}
+}
+
+// FormulaStockState is the badge's data-stock attribute: "in-stock" or
+// "missing".
+func FormulaStockState(stock models.FormulaStock) string {
+	if stock.Buildable() {
+		return "in-stock"
+	}
+	return "missing"
+}
@@ -0,0 +1,28 @@
+package pages
+
+// FormulaTotals summarises the formula editor's unsaved rows. Shares are
+// percentages of the whole composition.
+type FormulaTotals struct {
+	// Percent is set when the rows are entered as percentages, in which case
+	// Total is their sum in percent rather than a weight in grams.
+	Percent bool
+	Total   float64
+	Rows    []FormulaTotalRow
+	// Pyramid spreads the composition, sub-formulas included, over the
+	// pyramid positions of its materials, base notes first.
+	Pyramid []PyramidShare
+}
+
+// FormulaTotalRow is one editor row with its share of the composition.
+type FormulaTotalRow struct {
+	Name   string
+	Amount float64
+	Share  float64
+}
+
+// PyramidShare is the part of a composition sitting at one pyramid position.
+type PyramidShare struct {
+	Position string
+	Label    string
+	Share    float64
+}
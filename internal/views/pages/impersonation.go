@@ -0,0 +1,12 @@
+package pages
+
+import "time"
+
+// ImpersonationBanner flags a workspace an administrator is viewing as
+// another member. The zero value means no impersonation is running.
+type ImpersonationBanner struct {
+	Active     bool
+	UserEmail  string
+	AdminEmail string
+	EndsAt     time.Time
+}
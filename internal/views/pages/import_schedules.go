@@ -0,0 +1,34 @@
+package pages
+
+// ImportSchedulePanel drives the administrator's scheduled import controls.
+type ImportSchedulePanel struct {
+	Schedules []ImportScheduleRow
+	Message   string
+}
+
+// ImportScheduleRow summarises a schedule and its most recent runs.
+type ImportScheduleRow struct {
+	ID      uint
+	Name    string
+	Source  string
+	Cron    string
+	Enabled bool
+	NextRun string
+	LastRun string
+	Runs    []ImportRunRow
+}
+
+// ImportRunRow describes one recorded import run. Changes lists a readable
+// diff line per created or updated record, capped for display, with
+// MoreChanges counting the rest.
+type ImportRunRow struct {
+	Started     string
+	Status      string
+	Checksum    string
+	Created     int
+	Updated     int
+	Skipped     int
+	Changes     []string
+	MoreChanges int
+	Error       string
+}
@@ -0,0 +1,100 @@
+package pages
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// INCIOrderThreshold is the share of the product, in percent, at or below
+// which ingredients may be listed in any order after the others.
+const INCIOrderThreshold = 1.0
+
+// INCISource is one part of a finished product: a material of the formula,
+// or the solvent diluting it, with its share of the product in percent.
+// INCIName is empty when the material has none recorded.
+type INCISource struct {
+	INCIName       string
+	IngredientName string
+	Percent        float64
+}
+
+// INCILine totals one declared name across the sources declared under it.
+// Missing marks a material without an INCI name, listed under its
+// ingredient name until one is recorded.
+type INCILine struct {
+	Name    string
+	Percent float64
+	Sources []string
+	Missing bool
+}
+
+// INCIDeclaration is the ingredient list of a finished product made from a
+// formula at Concentration percent in Solvent.
+type INCIDeclaration struct {
+	FormulaID     uint
+	Concentration float64
+	Solvent       string
+	Lines         []INCILine
+	Message       string
+}
+
+// Text joins the declared names in order, as printed on a label.
+func (d INCIDeclaration) Text() string {
+	names := make([]string, 0, len(d.Lines))
+	for _, line := range d.Lines {
+		names = append(names, line.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// MissingCount is the number of lines without an INCI name.
+func (d INCIDeclaration) MissingCount() int {
+	count := 0
+	for _, line := range d.Lines {
+		if line.Missing {
+			count++
+		}
+	}
+	return count
+}
+
+// BuildINCIDeclaration merges sources declared under the same name and
+// orders the lines by descending share of the product.
+func BuildINCIDeclaration(formulaID uint, concentration float64, solvent string, sources []INCISource) INCIDeclaration {
+	declaration := INCIDeclaration{FormulaID: formulaID, Concentration: concentration, Solvent: solvent}
+	lines := map[string]*INCILine{}
+	for _, source := range sources {
+		if source.Percent <= 0 {
+			continue
+		}
+		name, missing := strings.TrimSpace(source.INCIName), false
+		if name == "" {
+			name, missing = strings.TrimSpace(source.IngredientName), true
+		}
+		key := strings.ToLower(name)
+		if missing {
+			key = "\x00" + key
+		}
+		line, ok := lines[key]
+		if !ok {
+			line = &INCILine{Name: name, Missing: missing}
+			lines[key] = line
+		}
+		line.Percent += source.Percent
+		if source.IngredientName != "" && !slices.Contains(line.Sources, source.IngredientName) {
+			line.Sources = append(line.Sources, source.IngredientName)
+		}
+	}
+
+	for _, line := range lines {
+		declaration.Lines = append(declaration.Lines, *line)
+	}
+	sort.Slice(declaration.Lines, func(i, j int) bool {
+		if declaration.Lines[i].Percent != declaration.Lines[j].Percent {
+			return declaration.Lines[i].Percent > declaration.Lines[j].Percent
+		}
+		return declaration.Lines[i].Name < declaration.Lines[j].Name
+	})
+	return declaration
+}
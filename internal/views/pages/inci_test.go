@@ -0,0 +1,31 @@
+package pages
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBuildINCIDeclaration(t *testing.T) {
+	t.Parallel()
+
+	sources := []INCISource{
+		{INCIName: "Alcohol Denat.", IngredientName: "Ethanol", Percent: 80},
+		{INCIName: "Parfum", IngredientName: "Hedione", Percent: 9},
+		{INCIName: "parfum", IngredientName: "Iso E Super", Percent: 6},
+		{INCIName: "Linalool", IngredientName: "Linalool", Percent: 0.5},
+		{IngredientName: "House accord", Percent: 4.5},
+		{INCIName: "Limonene", IngredientName: "Limonene", Percent: 0},
+	}
+
+	declaration := BuildINCIDeclaration(1, 20, "ethanol", sources)
+	if got, want := declaration.Text(), "Alcohol Denat., Parfum, House accord, Linalool"; got != want {
+		t.Fatalf("Text() = %q, want %q", got, want)
+	}
+	parfum := declaration.Lines[1]
+	if math.Abs(parfum.Percent-15) > 1e-9 || len(parfum.Sources) != 2 {
+		t.Fatalf("expected both materials under Parfum, got %+v", parfum)
+	}
+	if declaration.MissingCount() != 1 || !declaration.Lines[2].Missing {
+		t.Fatalf("expected the accord to be flagged as missing an INCI name, got %+v", declaration.Lines)
+	}
+}
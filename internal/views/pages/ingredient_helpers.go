@@ -6,15 +6,12 @@ import (
 	"strings"
 
 	"perfugo/models"
+	"perfugo/models/validation"
 )
 
-var allowedPyramidPositions = []string{"top", "top-heart", "heart", "heart-base", "base", "all"}
-
 // AllowedPyramidPositions returns the list of canonical pyramid position values.
 func AllowedPyramidPositions() []string {
-	result := make([]string, len(allowedPyramidPositions))
-	copy(result, allowedPyramidPositions)
-	return result
+	return validation.PyramidPositions()
 }
 
 // WheelPositionOptions returns a sorted list of unique, non-empty wheel positions.
@@ -44,21 +41,7 @@ func WheelPositionOptions(chemicals []models.AromaChemical) []string {
 // NormalizePyramidPosition converts the supplied value to its canonical representation.
 // It returns the normalized value along with a boolean indicating whether the input was valid.
 func NormalizePyramidPosition(value string) (string, bool) {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
-		return "", true
-	}
-
-	normalized := strings.ToLower(trimmed)
-	normalized = strings.ReplaceAll(normalized, "_", "-")
-	normalized = strings.ReplaceAll(normalized, " ", "-")
-
-	for _, option := range allowedPyramidPositions {
-		if normalized == option {
-			return option, true
-		}
-	}
-	return "", false
+	return validation.NormalizePyramidPosition(value)
 }
 
 // CanonicalPyramidPosition returns the normalized value or an empty string when it cannot be canonicalised.
@@ -86,32 +69,66 @@ func PyramidPositionLabel(value string) string {
 }
 
 func FormatPercentage(value float64) string {
-	if value <= 0 {
-		return DefaultDash("")
-	}
-	return fmt.Sprintf("%.2f%%", value)
+	return DefaultNumberFormat().FormatOptionalPercent(value)
 }
 
 func FormatPricePerMg(value float64) string {
+	return DefaultNumberFormat().FormatPricePerMg(value)
+}
+
+// FormatPopularity renders the popularity label from the active scale, falling back to the raw value.
+func FormatPopularity(value int) string {
 	if value <= 0 {
 		return DefaultDash("")
 	}
-	return fmt.Sprintf("$%.4f", value)
+	if label := models.PopularityScale().Label(value); label != "" {
+		return fmt.Sprintf("%d · %s", value, label)
+	}
+	return fmt.Sprintf("%d", value)
 }
 
-func FormatPopularity(value int) string {
+// FormatUsagePopularity describes how many formulas, and how many of their
+// creators, reference an ingredient.
+func FormatUsagePopularity(formulas, users int) string {
+	switch {
+	case formulas <= 0:
+		return "No formulas yet"
+	case formulas == 1:
+		return "1 formula"
+	case users <= 1:
+		return fmt.Sprintf("%d formulas", formulas)
+	default:
+		return fmt.Sprintf("%d formulas by %d members", formulas, users)
+	}
+}
+
+// FormatStrength renders the strength label from the active scale, falling back to the raw value.
+func FormatStrength(value int) string {
 	if value <= 0 {
 		return DefaultDash("")
 	}
+	if label := models.StrengthScale().Label(value); label != "" {
+		return fmt.Sprintf("%d · %s", value, label)
+	}
 	return fmt.Sprintf("%d", value)
 }
 
+// StrengthScaleLevels exposes the active strength scale to templates.
+func StrengthScaleLevels() []models.ScaleLevel {
+	return models.StrengthScale().Levels
+}
+
+// PopularityScaleLevels exposes the active popularity scale to templates.
+func PopularityScaleLevels() []models.ScaleLevel {
+	return models.PopularityScale().Levels
+}
+
 // IngredientFormAction derives the correct HTMX endpoint for creating or updating ingredients.
 func IngredientFormAction(chemical *models.AromaChemical) string {
 	if chemical == nil || chemical.ID == 0 {
-		return "/app/sections/ingredients/create"
+		return Path("/app/sections/ingredients/create")
 	}
-	return "/app/sections/ingredients/update"
+	return Path("/app/sections/ingredients/update")
 }
 
 func FormatFloatInput(value float64, precision int) string {
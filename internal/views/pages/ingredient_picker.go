@@ -0,0 +1,101 @@
+package pages
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"perfugo/models"
+)
+
+// IngredientOptionsPath is the endpoint a composition row queries for the
+// ingredient options matching what was typed into its search box.
+const IngredientOptionsPath = "/app/sections/formulas/ingredient-options"
+
+// IngredientOption is one choice of a composition row's ingredient select.
+// Value uses the chem:, stock: and formula: prefixes the editor submits.
+type IngredientOption struct {
+	Value string
+	Label string
+}
+
+// IngredientOptionPage is one page of the options matching a picker search.
+type IngredientOptionPage struct {
+	// Row is the index of the editor row the options are for.
+	Row       int
+	FormulaID uint
+	Query     string
+	Page      int
+	// Selected is the row's current choice, kept at the top of the first
+	// page so a new search does not drop it.
+	Selected  *IngredientOption
+	Chemicals []IngredientOption
+	Stocks    []IngredientOption
+	Formulas  []IngredientOption
+	More      bool
+}
+
+// NextURL loads the page after p for the same search.
+func (p IngredientOptionPage) NextURL() string {
+	return IngredientOptionsURL(p.FormulaID, p.Row, p.Query, p.Page+1)
+}
+
+// IngredientOptionsURL addresses one page of picker options for row.
+func IngredientOptionsURL(formulaID uint, row int, query string, page int) string {
+	values := url.Values{}
+	values.Set("formula_id", strconv.FormatUint(uint64(formulaID), 10))
+	values.Set("row", strconv.Itoa(row))
+	if query != "" {
+		values.Set("ingredient_query", query)
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	return Path(IngredientOptionsPath + "?" + values.Encode())
+}
+
+func ingredientSourceID(row int) string {
+	return fmt.Sprintf("ingredient-source-%d", row)
+}
+
+func ingredientMoreID(row int) string {
+	return fmt.Sprintf("ingredient-more-%d", row)
+}
+
+// SelectedIngredientOption describes the ingredient a composition row
+// already uses, preferring the preloaded relations and falling back to the
+// workspace lists. It returns nil for an empty row.
+func SelectedIngredientOption(ingredient *models.FormulaIngredient, chemicals []models.AromaChemical, formulas []models.Formula) *IngredientOption {
+	value := FormulaIngredientSourceValue(ingredient)
+	if value == "" {
+		return nil
+	}
+	option := &IngredientOption{Value: value, Label: "Current ingredient"}
+	switch {
+	case ingredient.StockSolutionID != nil && *ingredient.StockSolutionID != 0:
+		stock := ingredient.StockSolution
+		if stock == nil {
+			stock = FindStockSolution(chemicals, *ingredient.StockSolutionID)
+		}
+		chemical := ingredient.AromaChemical
+		if chemical == nil && stock != nil {
+			chemical = FindAromaChemical(chemicals, stock.AromaChemicalID)
+		}
+		if chemical != nil && stock != nil {
+			option.Label = chemical.IngredientName + " " + stock.DilutionLabel()
+		}
+	case ingredient.AromaChemical != nil:
+		option.Label = ingredient.AromaChemical.IngredientName
+	case ingredient.AromaChemicalID != nil:
+		if chemical := FindAromaChemical(chemicals, *ingredient.AromaChemicalID); chemical != nil {
+			option.Label = chemical.IngredientName
+		}
+	case ingredient.SubFormula != nil:
+		option.Label = ingredient.SubFormula.Name
+	case ingredient.SubFormulaID != nil:
+		if formula := FindFormula(formulas, *ingredient.SubFormulaID); formula != nil {
+			option.Label = formula.Name
+		}
+	}
+	return option
+}
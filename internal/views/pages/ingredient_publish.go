@@ -0,0 +1,79 @@
+package pages
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// IngredientPublishPath shares or withdraws the ticked aroma chemicals.
+const IngredientPublishPath = "/app/sections/ingredients/publish"
+
+// IngredientPublish describes a bulk change of the Public flag, either
+// waiting for confirmation or done.
+type IngredientPublish struct {
+	// IDs are the ticked chemicals the member owns; the rest are Skipped.
+	IDs     []uint
+	Skipped int
+	Public  bool
+	// OtherFormulas counts the formulas created by other members that use
+	// the chemicals, such as copies of the member's own formulas.
+	OtherFormulas int64
+	Done          bool
+}
+
+// IDValues lists IDs as form values for the confirmation form.
+func (p IngredientPublish) IDValues() []string {
+	values := make([]string, 0, len(p.IDs))
+	for _, id := range p.IDs {
+		values = append(values, strconv.FormatUint(uint64(id), 10))
+	}
+	return values
+}
+
+// PublicValue is the form value of Public.
+func (p IngredientPublish) PublicValue() string {
+	return strconv.FormatBool(p.Public)
+}
+
+// Verb names the change, eg. "Publish".
+func (p IngredientPublish) Verb() string {
+	if p.Public {
+		return "Publish"
+	}
+	return "Unpublish"
+}
+
+// Summary says what the change does, or did once Done.
+func (p IngredientPublish) Summary() string {
+	chemicals := fmt.Sprintf("%d aroma chemicals", len(p.IDs))
+	if len(p.IDs) == 1 {
+		chemicals = "1 aroma chemical"
+	}
+	var summary string
+	switch {
+	case p.Done && p.Public:
+		summary = "Published " + chemicals + "."
+	case p.Done:
+		summary = "Unpublished " + chemicals + "."
+	default:
+		summary = p.Verb() + " " + chemicals + "?"
+		switch {
+		case p.OtherFormulas == 1:
+			summary += " 1 formula by another member uses them."
+		case p.OtherFormulas > 1:
+			summary += fmt.Sprintf(" %d formulas by other members use them.", p.OtherFormulas)
+		default:
+			summary += " No formulas by other members use them."
+		}
+		if p.OtherFormulas > 0 && !p.Public {
+			summary += " Those formulas will no longer show them."
+		}
+	}
+	switch {
+	case p.Skipped == 1:
+		summary += " 1 ticked chemical belongs to another member and was left as is."
+	case p.Skipped > 1:
+		summary += fmt.Sprintf(" %d ticked chemicals belong to other members and were left as is.", p.Skipped)
+	}
+	return summary
+}
@@ -0,0 +1,28 @@
+package pages
+
+import "strings"
+
+// LandingSections lists the workspace sections /app may open with.
+func LandingSections() []string {
+	return []string{"ingredients", "formulas", "reports"}
+}
+
+// NormalizeLandingSection returns section when /app may open with it and
+// the default workspace section otherwise.
+func NormalizeLandingSection(section string) string {
+	section = strings.ToLower(strings.TrimSpace(section))
+	for _, candidate := range LandingSections() {
+		if candidate == section {
+			return section
+		}
+	}
+	return defaultWorkspaceSection
+}
+
+// LandingSectionLabel names a landing section, eg. "Formulas".
+func LandingSectionLabel(section string) string {
+	if section == "" {
+		return ""
+	}
+	return strings.ToUpper(section[:1]) + section[1:]
+}
@@ -92,7 +92,59 @@ func landingContent() templ.Component {
 			templ_7745c5c3_Var3 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"flex flex-col\"><section class=\"relative isolate overflow-hidden bg-slate-900 text-white\"><img src=\"https://images.unsplash.com/photo-1519741497674-611481863552?auto=format&fit=crop&w=1600&q=80\" alt=\"Essence bottles\" class=\"absolute inset-0 -z-10 h-full w-full object-cover opacity-60\"><div class=\"absolute inset-0 -z-10 bg-gradient-to-br from-slate-900/80 via-slate-900/60 to-indigo-900/80\"></div><div class=\"mx-auto flex max-w-6xl flex-col gap-10 px-6 py-24 text-center sm:px-8 lg:px-10\"><p class=\"text-sm font-semibold uppercase tracking-widest text-indigo-200\">Craft Bespoke Aromas</p><h1 class=\"text-4xl font-bold leading-tight tracking-tight sm:text-5xl lg:text-6xl\">Perfugo brings artisan perfumery to life</h1><p class=\"mx-auto max-w-3xl text-lg text-slate-200\">Perfugo is the perfumery platform for creators who blend art and science. Manage formulas, streamline sourcing, and keep every batch consistent—from inspiration to finished fragrance. To access the full suite of tools you will need to log in.</p><div class=\"flex flex-col items-center justify-center gap-4 sm:flex-row\"><a href=\"/login\" hx-get=\"/login\" hx-target=\"#primary-content\" hx-push-url=\"true\" hx-swap=\"innerHTML\" class=\"inline-flex items-center justify-center rounded-full bg-indigo-500 px-6 py-3 text-sm font-semibold text-white shadow-lg transition hover:bg-indigo-400\">Login to Perfugo</a> <a href=\"/signup\" hx-get=\"/signup\" hx-target=\"#primary-content\" hx-push-url=\"true\" hx-swap=\"innerHTML\" class=\"inline-flex items-center justify-center rounded-full border border-indigo-200/80 px-6 py-3 text-sm font-semibold text-indigo-100 transition hover:border-white hover:text-white\">Create an account</a></div><div class=\"mt-4 text-sm font-semibold text-indigo-200\"><a href=\"#discover\" class=\"transition hover:text-white\">Discover the atelier experience →</a></div></div></section><section id=\"discover\" class=\"bg-white py-20\"><div class=\"mx-auto grid max-w-6xl gap-12 px-6 sm:px-8 lg:grid-cols-2 lg:items-center lg:px-10\"><div class=\"space-y-6\"><h2 class=\"text-3xl font-semibold text-slate-900\">Formula mastery without compromise</h2><p class=\"text-base leading-7 text-slate-600\">Build and refine fragrance formulas with deep control over every ingredient, dilution, and IFRA limit. Perfugo keeps precise histories so you can revisit iterations, compare compositions, and maintain compliance at every step.</p><ul class=\"space-y-4 text-sm text-slate-600\"><li class=\"flex items-start gap-3\"><span class=\"mt-1 inline-flex h-2.5 w-2.5 flex-shrink-0 rounded-full bg-indigo-500\"></span> Collaborative formula boards for creative teams and evaluators.</li><li class=\"flex items-start gap-3\"><span class=\"mt-1 inline-flex h-2.5 w-2.5 flex-shrink-0 rounded-full bg-rose-400\"></span> Integrated costing and sourcing insights to balance artistry and margins.</li><li class=\"flex items-start gap-3\"><span class=\"mt-1 inline-flex h-2.5 w-2.5 flex-shrink-0 rounded-full bg-emerald-400\"></span> Compliance guardrails that automatically flag allergen and region limits.</li></ul></div><div class=\"grid gap-5\"><img src=\"https://images.unsplash.com/photo-1544025162-d76694265947?auto=format&fit=crop&w=900&q=80\" alt=\"Fragrance mixing\" class=\"h-64 w-full rounded-3xl object-cover shadow-lg\"><div class=\"grid grid-cols-2 gap-5\"><img src=\"https://images.unsplash.com/photo-1519669011783-4eaa95fa0632?auto=format&fit=crop&w=600&q=80\" alt=\"Perfume ingredients\" class=\"h-48 w-full rounded-2xl object-cover shadow\"> <img src=\"https://images.unsplash.com/photo-1523297719200-5cc33d6cc407?auto=format&fit=crop&w=600&q=80\" alt=\"Bottled fragrances\" class=\"h-48 w-full rounded-2xl object-cover shadow\"></div></div></div></section><section class=\"bg-indigo-50 py-20\"><div class=\"mx-auto max-w-6xl space-y-12 px-6 text-center sm:px-8 lg:px-10\"><h2 class=\"text-3xl font-semibold text-indigo-900\">What awaits inside Perfugo</h2><div class=\"grid gap-8 md:grid-cols-3\"><div class=\"rounded-3xl bg-white p-8 text-left shadow-lg\"><h3 class=\"text-xl font-semibold text-slate-900\">Formula Management</h3><p class=\"mt-3 text-sm leading-6 text-slate-600\">Organize notes, accords, and concentrations with precise version control so nothing is lost in development.</p></div><div class=\"rounded-3xl bg-white p-8 text-left shadow-lg\"><h3 class=\"text-xl font-semibold text-slate-900\">Production Oversight</h3><p class=\"mt-3 text-sm leading-6 text-slate-600\">Monitor maceration, filtration, and filling milestones with dashboards tuned for fragrance houses.</p></div><div class=\"rounded-3xl bg-white p-8 text-left shadow-lg\"><h3 class=\"text-xl font-semibold text-slate-900\">Scent Library</h3><p class=\"mt-3 text-sm leading-6 text-slate-600\">Preserve evaluations, marketing briefs, and storytelling notes so every launch feels unforgettable.</p></div></div><p class=\"text-sm text-indigo-700\">Ready to blend brilliance? Login or create an account to enter the atelier. Access is exclusive to authenticated perfumers.</p></div></section></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"flex flex-col\"><section class=\"relative isolate overflow-hidden bg-slate-900 text-white\"><img src=\"https://images.unsplash.com/photo-1519741497674-611481863552?auto=format&fit=crop&w=1600&q=80\" alt=\"Essence bottles\" class=\"absolute inset-0 -z-10 h-full w-full object-cover opacity-60\"><div class=\"absolute inset-0 -z-10 bg-gradient-to-br from-slate-900/80 via-slate-900/60 to-indigo-900/80\"></div><div class=\"mx-auto flex max-w-6xl flex-col gap-10 px-6 py-24 text-center sm:px-8 lg:px-10\"><p class=\"text-sm font-semibold uppercase tracking-widest text-indigo-200\">Craft Bespoke Aromas</p><h1 class=\"text-4xl font-bold leading-tight tracking-tight sm:text-5xl lg:text-6xl\">Perfugo brings artisan perfumery to life</h1><p class=\"mx-auto max-w-3xl text-lg text-slate-200\">Perfugo is the perfumery platform for creators who blend art and science. Manage formulas, streamline sourcing, and keep every batch consistent—from inspiration to finished fragrance. To access the full suite of tools you will need to log in.</p><div class=\"flex flex-col items-center justify-center gap-4 sm:flex-row\"><a href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 templ.SafeURL
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinURLErrs(Path("/login"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/pages/landing.templ`, Line: 30, Col: 29}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" hx-get=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var5 string
+		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(Path("/login"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/pages/landing.templ`, Line: 30, Col: 55}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" hx-target=\"#primary-content\" hx-push-url=\"true\" hx-swap=\"innerHTML\" class=\"inline-flex items-center justify-center rounded-full bg-indigo-500 px-6 py-3 text-sm font-semibold text-white shadow-lg transition hover:bg-indigo-400\">Login to Perfugo</a> <a href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var6 templ.SafeURL
+		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinURLErrs(Path("/signup"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/pages/landing.templ`, Line: 33, Col: 30}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\" hx-get=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var7 string
+		templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(Path("/signup"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/pages/landing.templ`, Line: 33, Col: 57}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\" hx-target=\"#primary-content\" hx-push-url=\"true\" hx-swap=\"innerHTML\" class=\"inline-flex items-center justify-center rounded-full border border-indigo-200/80 px-6 py-3 text-sm font-semibold text-indigo-100 transition hover:border-white hover:text-white\">Create an account</a></div><div class=\"mt-4 text-sm font-semibold text-indigo-200\"><a href=\"#discover\" class=\"transition hover:text-white\">Discover the atelier experience →</a></div></div></section><section id=\"discover\" class=\"bg-white py-20\"><div class=\"mx-auto grid max-w-6xl gap-12 px-6 sm:px-8 lg:grid-cols-2 lg:items-center lg:px-10\"><div class=\"space-y-6\"><h2 class=\"text-3xl font-semibold text-slate-900\">Formula mastery without compromise</h2><p class=\"text-base leading-7 text-slate-600\">Build and refine fragrance formulas with deep control over every ingredient, dilution, and IFRA limit. Perfugo keeps precise histories so you can revisit iterations, compare compositions, and maintain compliance at every step.</p><ul class=\"space-y-4 text-sm text-slate-600\"><li class=\"flex items-start gap-3\"><span class=\"mt-1 inline-flex h-2.5 w-2.5 flex-shrink-0 rounded-full bg-indigo-500\"></span> Collaborative formula boards for creative teams and evaluators.</li><li class=\"flex items-start gap-3\"><span class=\"mt-1 inline-flex h-2.5 w-2.5 flex-shrink-0 rounded-full bg-rose-400\"></span> Integrated costing and sourcing insights to balance artistry and margins.</li><li class=\"flex items-start gap-3\"><span class=\"mt-1 inline-flex h-2.5 w-2.5 flex-shrink-0 rounded-full bg-emerald-400\"></span> Compliance guardrails that automatically flag allergen and region limits.</li></ul></div><div class=\"grid gap-5\"><img src=\"https://images.unsplash.com/photo-1544025162-d76694265947?auto=format&fit=crop&w=900&q=80\" alt=\"Fragrance mixing\" class=\"h-64 w-full rounded-3xl object-cover shadow-lg\"><div class=\"grid grid-cols-2 gap-5\"><img src=\"https://images.unsplash.com/photo-1519669011783-4eaa95fa0632?auto=format&fit=crop&w=600&q=80\" alt=\"Perfume ingredients\" class=\"h-48 w-full rounded-2xl object-cover shadow\"> <img src=\"https://images.unsplash.com/photo-1523297719200-5cc33d6cc407?auto=format&fit=crop&w=600&q=80\" alt=\"Bottled fragrances\" class=\"h-48 w-full rounded-2xl object-cover shadow\"></div></div></div></section><section class=\"bg-indigo-50 py-20\"><div class=\"mx-auto max-w-6xl space-y-12 px-6 text-center sm:px-8 lg:px-10\"><h2 class=\"text-3xl font-semibold text-indigo-900\">What awaits inside Perfugo</h2><div class=\"grid gap-8 md:grid-cols-3\"><div class=\"rounded-3xl bg-white p-8 text-left shadow-lg\"><h3 class=\"text-xl font-semibold text-slate-900\">Formula Management</h3><p class=\"mt-3 text-sm leading-6 text-slate-600\">Organize notes, accords, and concentrations with precise version control so nothing is lost in development.</p></div><div class=\"rounded-3xl bg-white p-8 text-left shadow-lg\"><h3 class=\"text-xl font-semibold text-slate-900\">Production Oversight</h3><p class=\"mt-3 text-sm leading-6 text-slate-600\">Monitor maceration, filtration, and filling milestones with dashboards tuned for fragrance houses.</p></div><div class=\"rounded-3xl bg-white p-8 text-left shadow-lg\"><h3 class=\"text-xl font-semibold text-slate-900\">Scent Library</h3><p class=\"mt-3 text-sm leading-6 text-slate-600\">Preserve evaluations, marketing briefs, and storytelling notes so every launch feels unforgettable.</p></div></div><p class=\"text-sm text-indigo-700\">Ready to blend brilliance? Login or create an account to enter the atelier. Access is exclusive to authenticated perfumers.</p></div></section></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,95 @@
+package pages
+
+import (
+	"fmt"
+	"strings"
+
+	"perfugo/models"
+)
+
+// LibraryHealth lists the data quality problems in a user's own ingredients,
+// grouped by check, so an imported library can be cleaned up one rule at a time.
+type LibraryHealth struct {
+	Checked int
+	Flagged int
+	Checks  []LibraryHealthCheck
+}
+
+// LibraryHealthCheck is one data quality rule and the ingredients failing it.
+type LibraryHealthCheck struct {
+	ID    string
+	Label string
+	Hint  string
+	Items []LibraryHealthItem
+}
+
+// LibraryHealthItem links a flagged ingredient to its editor.
+type LibraryHealthItem struct {
+	ID   uint
+	Name string
+}
+
+// EditURL opens the workspace with the ingredient's editor loaded.
+func (i LibraryHealthItem) EditURL() string {
+	return IngredientEditURL(i.ID)
+}
+
+// IngredientEditURL deep links to the editor for an ingredient.
+func IngredientEditURL(id uint) string {
+	return Path(fmt.Sprintf("/app/ingredients?edit=%d", id))
+}
+
+var libraryHealthRules = []struct {
+	id, label, hint string
+	failing         func(models.AromaChemical) bool
+}{
+	{"cas", "Missing CAS number", "No CAS number, or a placeholder assigned on import.", func(c models.AromaChemical) bool {
+		cas := strings.TrimSpace(c.CASNumber)
+		return cas == "" || strings.HasPrefix(cas, "UNASSIGNED-")
+	}},
+	{"ifra", "No IFRA limit", "Record the maximum concentration, or 100% for unrestricted materials.", func(c models.AromaChemical) bool {
+		return c.MaxIFRAPercentage <= 0
+	}},
+	{"strength", "No strength", "Odour strength is unset.", func(c models.AromaChemical) bool {
+		return c.Strength == 0
+	}},
+	{"pyramid", "No pyramid position", "Top, heart or base is unset.", func(c models.AromaChemical) bool {
+		return strings.TrimSpace(c.PyramidPosition) == ""
+	}},
+	{"price", "No price", "Costing and shopping lists treat the material as free.", func(c models.AromaChemical) bool {
+		return c.PricePerMg <= 0
+	}},
+}
+
+// BuildLibraryHealth runs every check over the chemicals owned by ownerID.
+// Public chemicals owned by others are skipped since the user cannot edit them.
+func BuildLibraryHealth(chemicals []models.AromaChemical, ownerID uint) LibraryHealth {
+	health := LibraryHealth{}
+	flagged := map[uint]bool{}
+	checks := make([]LibraryHealthCheck, len(libraryHealthRules))
+	for i, rule := range libraryHealthRules {
+		checks[i] = LibraryHealthCheck{ID: rule.id, Label: rule.label, Hint: rule.hint}
+	}
+
+	for _, chemical := range chemicals {
+		if chemical.OwnerID != ownerID {
+			continue
+		}
+		health.Checked++
+		for i, rule := range libraryHealthRules {
+			if rule.failing(chemical) {
+				checks[i].Items = append(checks[i].Items, LibraryHealthItem{ID: chemical.ID, Name: chemical.IngredientName})
+				flagged[chemical.ID] = true
+			}
+		}
+	}
+
+	health.Flagged = len(flagged)
+	health.Checks = checks
+	return health
+}
+
+// IngredientEditorFragmentURL is the HTMX endpoint that renders the editor.
+func IngredientEditorFragmentURL(id uint) string {
+	return Path(fmt.Sprintf("/app/sections/ingredients/edit?id=%d", id))
+}
@@ -0,0 +1,53 @@
+package pages
+
+import (
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestBuildLibraryHealth(t *testing.T) {
+	complete := models.AromaChemical{
+		IngredientName:    "Hedione",
+		CASNumber:         "24851-98-7",
+		MaxIFRAPercentage: 100,
+		Strength:          2,
+		PyramidPosition:   "heart",
+		PricePerMg:        0.002,
+		OwnerID:           1,
+	}
+	complete.ID = 1
+	placeholder := complete
+	placeholder.ID = 2
+	placeholder.IngredientName = "Mystery base"
+	placeholder.CASNumber = "UNASSIGNED-mystery-base"
+	placeholder.PricePerMg = 0
+	mixture := complete
+	mixture.ID = 3
+	mixture.CASNumber = "MIXTURE-oakmoss"
+	foreign := models.AromaChemical{IngredientName: "Public", OwnerID: 2, Public: true}
+	foreign.ID = 4
+
+	health := BuildLibraryHealth([]models.AromaChemical{complete, placeholder, mixture, foreign}, 1)
+	if health.Checked != 3 || health.Flagged != 1 {
+		t.Fatalf("expected 3 checked and 1 flagged, got %+v", health)
+	}
+
+	failing := map[string][]LibraryHealthItem{}
+	for _, check := range health.Checks {
+		failing[check.ID] = check.Items
+	}
+	for _, id := range []string{"cas", "price"} {
+		if items := failing[id]; len(items) != 1 || items[0].ID != 2 {
+			t.Fatalf("expected %s check to flag only the placeholder, got %+v", id, items)
+		}
+	}
+	for _, id := range []string{"ifra", "strength", "pyramid"} {
+		if len(failing[id]) != 0 {
+			t.Fatalf("expected %s check to pass, got %+v", id, failing[id])
+		}
+	}
+	if got := failing["cas"][0].EditURL(); got != "/app/ingredients?edit=2" {
+		t.Fatalf("unexpected edit URL %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package pages
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// NotesSearchPath searches the free text members write down while working.
+const NotesSearchPath = "/app/sections/tools/notes-search"
+
+// Kinds of text the notes search covers.
+const (
+	NoteHitFormula    = "formula"
+	NoteHitEvaluation = "evaluation"
+	NoteHitComment    = "comment"
+	NoteHitBatch      = "batch"
+)
+
+// Characters of context kept around a match in a snippet.
+const (
+	snippetBefore = 60
+	snippetAfter  = 100
+)
+
+// NotesSearch is one search over formula notes, the member's evaluation
+// notes, blind test comments and batch observations.
+type NotesSearch struct {
+	Query string
+	Hits  []NoteHit
+	// Truncated is set when a kind had more matches than are listed.
+	Truncated bool
+}
+
+// NoteHit is a piece of text matching the search. Title says where it was
+// written, such as a formula name or a batch lot.
+type NoteHit struct {
+	Kind    string
+	Title   string
+	Snippet NoteSnippet
+}
+
+// KindLabel names the kind of text for the results list.
+func (h NoteHit) KindLabel() string {
+	switch h.Kind {
+	case NoteHitFormula:
+		return "Formula notes"
+	case NoteHitEvaluation:
+		return "Evaluation note"
+	case NoteHitComment:
+		return "Blind test comment"
+	case NoteHitBatch:
+		return "Batch observation"
+	default:
+		return "Note"
+	}
+}
+
+// NoteSnippet is the part of a note around the first match, split so the
+// match can be highlighted.
+type NoteSnippet struct {
+	Before string
+	Match  string
+	After  string
+}
+
+// NoteSnippetOf cuts text down to the first match of query and some
+// context either side, marking cuts with an ellipsis. Case is ignored.
+func NoteSnippetOf(text, query string) NoteSnippet {
+	text = strings.Join(strings.Fields(text), " ")
+	query = strings.TrimSpace(query)
+	var loc []int
+	if query != "" {
+		loc = regexp.MustCompile("(?i)" + regexp.QuoteMeta(query)).FindStringIndex(text)
+	}
+	if loc == nil {
+		return NoteSnippet{After: clipText(text, snippetBefore+snippetAfter)}
+	}
+
+	start := runeBoundary(text, loc[0]-snippetBefore)
+	end := runeBoundary(text, loc[1]+snippetAfter)
+	snippet := NoteSnippet{Before: text[start:loc[0]], Match: text[loc[0]:loc[1]], After: text[loc[1]:end]}
+	if start > 0 {
+		snippet.Before = "…" + snippet.Before
+	}
+	if end < len(text) {
+		snippet.After += "…"
+	}
+	return snippet
+}
+
+// runeBoundary clamps offset to text and moves it back to the start of a
+// rune.
+func runeBoundary(text string, offset int) int {
+	offset = min(max(offset, 0), len(text))
+	for offset > 0 && offset < len(text) && !utf8.RuneStart(text[offset]) {
+		offset--
+	}
+	return offset
+}
+
+// clipText keeps the first length bytes of text, on a rune boundary.
+func clipText(text string, length int) string {
+	if end := runeBoundary(text, length); end < len(text) {
+		return text[:end] + "…"
+	}
+	return text
+}
@@ -0,0 +1,25 @@
+package pages
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNoteSnippetOfKeepsContextAroundMatch(t *testing.T) {
+	text := strings.Repeat("citrus top, ", 10) + "then a Métallic edge " + strings.Repeat("in the dry down, ", 10)
+	snippet := NoteSnippetOf(text, "métallic")
+	if snippet.Match != "Métallic" {
+		t.Fatalf("expected the match in its original case, got %q", snippet.Match)
+	}
+	if !strings.HasPrefix(snippet.Before, "…") || !strings.HasSuffix(snippet.After, "…") {
+		t.Fatalf("expected both cuts marked: %+v", snippet)
+	}
+	if len(snippet.Before) > snippetBefore+len("…") || len(snippet.After) > snippetAfter+len("…") {
+		t.Fatalf("expected context to be trimmed: %+v", snippet)
+	}
+
+	short := NoteSnippetOf("  Soft\n  musk ", "amber")
+	if short.Match != "" || short.After != "Soft musk" {
+		t.Fatalf("expected the whole note without a match, got %+v", short)
+	}
+}
@@ -0,0 +1,97 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MaxDecimals bounds every precision preference.
+const MaxDecimals = 6
+
+// NumberFormat holds how many decimals are shown for each kind of number.
+// Amount applies to grams, millilitres and other units; milligrams are
+// shown with three fewer decimals so both read to the same resolution.
+// Price applies to unit prices such as the price per milligram.
+type NumberFormat struct {
+	Amount  int
+	Percent int
+	Price   int
+}
+
+// DefaultNumberFormat is used until a user saves their own precision.
+func DefaultNumberFormat() NumberFormat {
+	return NumberFormat{Amount: 2, Percent: 2, Price: 4}
+}
+
+// Normalize clamps every precision to 0–MaxDecimals.
+func (f NumberFormat) Normalize() NumberFormat {
+	clamp := func(v int) int { return min(max(v, 0), MaxDecimals) }
+	return NumberFormat{Amount: clamp(f.Amount), Percent: clamp(f.Percent), Price: clamp(f.Price)}
+}
+
+// AmountDecimals returns the precision used for a quantity in unit.
+func (f NumberFormat) AmountDecimals(unit string) int {
+	if strings.EqualFold(strings.TrimSpace(unit), "mg") {
+		return max(f.Amount-3, 0)
+	}
+	return f.Amount
+}
+
+// FormatAmount renders a quantity without its unit.
+func (f NumberFormat) FormatAmount(value float64, unit string) string {
+	return fmt.Sprintf("%.*f", f.AmountDecimals(unit), value)
+}
+
+// FormatQuantity renders a quantity followed by its unit, eg. "12.50 g".
+func (f NumberFormat) FormatQuantity(value float64, unit string) string {
+	return fmt.Sprintf("%s %s", f.FormatAmount(value, unit), unit)
+}
+
+// FormatPercent renders a share such as "12.50%".
+func (f NumberFormat) FormatPercent(value float64) string {
+	return fmt.Sprintf("%.*f%%", f.Percent, value)
+}
+
+// FormatOptionalPercent is FormatPercent with an em dash for values that
+// are not set.
+func (f NumberFormat) FormatOptionalPercent(value float64) string {
+	if value <= 0 {
+		return DefaultDash("")
+	}
+	return f.FormatPercent(value)
+}
+
+// FormatPricePerMg renders a unit price, or an em dash when none is known.
+func (f NumberFormat) FormatPricePerMg(value float64) string {
+	if value <= 0 {
+		return DefaultDash("")
+	}
+	return fmt.Sprintf("$%.*f", f.Price, value)
+}
+
+type numberFormatKey struct{}
+
+// WithNumberFormat attaches the signed-in user's number format to ctx so
+// templates can read it with NumberFormatFrom.
+func WithNumberFormat(ctx context.Context, format NumberFormat) context.Context {
+	return context.WithValue(ctx, numberFormatKey{}, format.Normalize())
+}
+
+// NumberFormatFrom returns the number format attached to ctx, or the
+// default when there is none.
+func NumberFormatFrom(ctx context.Context) NumberFormat {
+	if format, ok := ctx.Value(numberFormatKey{}).(NumberFormat); ok {
+		return format
+	}
+	return DefaultNumberFormat()
+}
+
+// DecimalStep is the step attribute of a number input that accepts the
+// given number of decimals, eg. "0.01" for 2.
+func DecimalStep(decimals int) string {
+	if decimals <= 0 {
+		return "1"
+	}
+	return "0." + strings.Repeat("0", decimals-1) + "1"
+}
@@ -0,0 +1,39 @@
+package pages
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNumberFormat(t *testing.T) {
+	t.Parallel()
+
+	format := NumberFormat{Amount: 3, Percent: 1, Price: 5}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"grams", format.FormatQuantity(12.34567, "g"), "12.346 g"},
+		{"milligrams drop three decimals", format.FormatQuantity(2000.4, "mg"), "2000 mg"},
+		{"percent", format.FormatPercent(12.345), "12.3%"},
+		{"unset percent", format.FormatOptionalPercent(0), "—"},
+		{"price", format.FormatPricePerMg(0.0123456), "$0.01235"},
+		{"default report quantity", FormatReportQuantity(1.5, "g"), "1.50 g"},
+		{"step", DecimalStep(3), "0.001"},
+		{"whole step", DecimalStep(0), "1"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if got := NumberFormatFrom(context.Background()); got != DefaultNumberFormat() {
+		t.Fatalf("empty context format = %+v", got)
+	}
+	ctx := WithNumberFormat(context.Background(), NumberFormat{Amount: 9, Percent: -1, Price: 4})
+	if got := NumberFormatFrom(ctx); got != (NumberFormat{Amount: MaxDecimals, Percent: 0, Price: 4}) {
+		t.Fatalf("format was not clamped: %+v", got)
+	}
+}
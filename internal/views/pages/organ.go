@@ -0,0 +1,94 @@
+package pages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"perfugo/models"
+)
+
+// OrganCell is one slot of the organ grid. Chemical is nil for a free slot.
+type OrganCell struct {
+	Label    string
+	Chemical *models.AromaChemical
+}
+
+// OrganLayout is the organ grid alongside the materials that have no slot.
+// Organ is nil until the user has set the organ's size.
+type OrganLayout struct {
+	Organ      *models.Organ
+	Grid       [][]OrganCell
+	Unassigned []models.AromaChemical
+	Free       int
+	Message    string
+}
+
+// BuildOrganLayout places the organ's slots on a grid. Slots holding a
+// material that is no longer in the library count as free.
+func BuildOrganLayout(organ *models.Organ, chemicals []models.AromaChemical) OrganLayout {
+	layout := OrganLayout{Organ: organ}
+	assigned := make(map[uint]bool)
+	if organ != nil {
+		layout.Grid = make([][]OrganCell, organ.Rows)
+		for row := range layout.Grid {
+			layout.Grid[row] = make([]OrganCell, organ.Positions)
+			for pos := range layout.Grid[row] {
+				layout.Grid[row][pos].Label = models.OrganSlotLabel(row+1, pos+1)
+			}
+		}
+		for _, slot := range organ.Slots {
+			if slot.Row < 1 || slot.Row > organ.Rows || slot.Position < 1 || slot.Position > organ.Positions {
+				continue
+			}
+			chemical := FindAromaChemical(chemicals, slot.AromaChemicalID)
+			if chemical == nil {
+				continue
+			}
+			layout.Grid[slot.Row-1][slot.Position-1].Chemical = chemical
+			assigned[chemical.ID] = true
+		}
+		layout.Free = organ.Rows*organ.Positions - len(assigned)
+	}
+	for _, chemical := range chemicals {
+		if !assigned[chemical.ID] {
+			layout.Unassigned = append(layout.Unassigned, chemical)
+		}
+	}
+	sort.SliceStable(layout.Unassigned, func(i, j int) bool {
+		return strings.ToLower(layout.Unassigned[i].IngredientName) < strings.ToLower(layout.Unassigned[j].IngredientName)
+	})
+	return layout
+}
+
+// FirstFreeSlot returns the label of the first free slot, reading each row
+// left to right, or "" when the organ is full.
+func (l OrganLayout) FirstFreeSlot() string {
+	for _, row := range l.Grid {
+		for _, cell := range row {
+			if cell.Chemical == nil {
+				return cell.Label
+			}
+		}
+	}
+	return ""
+}
+
+// OrganRowLetter is the letter marking a row of the organ.
+func OrganRowLetter(row int) string {
+	return string(rune('A' + row - 1))
+}
+
+func organRowsValue(organ *models.Organ) string {
+	if organ == nil {
+		return "6"
+	}
+	return fmt.Sprintf("%d", organ.Rows)
+}
+
+func organPositionsValue(organ *models.Organ) string {
+	if organ == nil {
+		return "12"
+	}
+	return fmt.Sprintf("%d", organ.Positions)
+}
@@ -0,0 +1,42 @@
+package pages
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+
+	"perfugo/models"
+)
+
+func TestBuildOrganLayout(t *testing.T) {
+	t.Parallel()
+
+	chemicals := []models.AromaChemical{
+		{Model: gorm.Model{ID: 1}, IngredientName: "Vanillin"},
+		{Model: gorm.Model{ID: 2}, IngredientName: "ambrettolide"},
+		{Model: gorm.Model{ID: 3}, IngredientName: "Calone"},
+	}
+	organ := &models.Organ{Rows: 2, Positions: 2, Slots: []models.OrganSlot{
+		{Row: 1, Position: 1, AromaChemicalID: 3},
+		{Row: 2, Position: 1, AromaChemicalID: 99},
+		{Row: 3, Position: 1, AromaChemicalID: 1},
+	}}
+
+	layout := BuildOrganLayout(organ, chemicals)
+	if layout.Grid[0][0].Chemical == nil || layout.Grid[0][0].Chemical.IngredientName != "Calone" || layout.Grid[1][1].Label != "B2" {
+		t.Fatalf("unexpected grid: %+v", layout.Grid)
+	}
+	if layout.Grid[1][0].Chemical != nil {
+		t.Fatal("a slot holding a deleted material should be free")
+	}
+	if layout.Free != 3 || layout.FirstFreeSlot() != "A2" {
+		t.Fatalf("free = %d, first = %q", layout.Free, layout.FirstFreeSlot())
+	}
+	if len(layout.Unassigned) != 2 || layout.Unassigned[0].IngredientName != "ambrettolide" {
+		t.Fatalf("unexpected unassigned: %+v", layout.Unassigned)
+	}
+
+	if empty := BuildOrganLayout(nil, chemicals); len(empty.Unassigned) != 3 || empty.FirstFreeSlot() != "" {
+		t.Fatalf("unexpected layout without an organ: %+v", empty)
+	}
+}
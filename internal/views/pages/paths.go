@@ -0,0 +1,10 @@
+package pages
+
+import "perfugo/internal/views/layout"
+
+// Path prefixes a root-relative link with the base path perfugo is served
+// under. Every link a page renders, and every redirect a handler sends,
+// goes through it; the URL helpers in this package already do.
+func Path(path string) string {
+	return layout.Path(path)
+}
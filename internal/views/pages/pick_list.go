@@ -0,0 +1,173 @@
+package pages
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"perfugo/models"
+)
+
+// Pick list groups for lines that are not picked from a stored container.
+const (
+	UnassignedLocation = "Unassigned location"
+	PickListSolvent    = "Solvent"
+	PickListShortfall  = "Not in stock"
+)
+
+// PickList tells whoever prepares a batch which containers to fetch, grouped
+// by where they are stored.
+type PickList struct {
+	FormulaName    string
+	FormulaVersion int
+	TargetQuantity float64
+	LotNumber      string
+	RunDate        time.Time
+	Groups         []PickListGroup
+}
+
+// PickListGroup is one storage location and the lines picked from it.
+type PickListGroup struct {
+	Location string
+	Lines    []PickListLine
+}
+
+// PickListLine is one container to take, or the shortfall of a material. A
+// material whose requirement spans several lots gets one line per lot. Order
+// is the line's position in the batch report.
+type PickListLine struct {
+	Order          int
+	IngredientName string
+	CASNumber      string
+	Dilution       string
+	QuantityMg     float64
+	LotNumber      string
+	OnHandMg       float64
+	Expires        string
+}
+
+// Lines counts the lines across every group.
+func (p PickList) Lines() int {
+	count := 0
+	for _, group := range p.Groups {
+		count += len(group.Lines)
+	}
+	return count
+}
+
+// BuildPickList allocates each line of the batch report to the owner's
+// stock. Lots are used first-expiring-first; expired lots are never picked
+// and lots without a tracked expiry come after the dated ones. Quantities
+// the stock cannot cover are listed under PickListShortfall.
+func BuildPickList(report BatchProductionReportData, stock []models.InventoryItem, now time.Time) PickList {
+	list := PickList{
+		FormulaName:    report.FormulaName,
+		FormulaVersion: report.FormulaVersion,
+		TargetQuantity: report.TargetQuantity,
+		LotNumber:      report.LotNumber,
+		RunDate:        report.RunDate,
+	}
+
+	groups := map[string]*PickListGroup{}
+	add := func(location string, line PickListLine) {
+		group, ok := groups[location]
+		if !ok {
+			group = &PickListGroup{Location: location}
+			groups[location] = group
+		}
+		group.Lines = append(group.Lines, line)
+	}
+
+	for _, item := range report.Ingredients {
+		line := PickListLine{
+			Order:          item.Order,
+			IngredientName: item.IngredientName,
+			CASNumber:      item.CASNumber,
+			Dilution:       item.Dilution,
+		}
+		if item.Solvent {
+			line.QuantityMg = item.FinalQuantity
+			add(PickListSolvent, line)
+			continue
+		}
+
+		remaining := item.FinalQuantity
+		for _, lot := range pickableLots(stock, item, now) {
+			if remaining <= 0 {
+				break
+			}
+			picked := line
+			picked.QuantityMg = min(remaining, lot.QuantityMg)
+			picked.LotNumber = lot.LotNumber
+			picked.OnHandMg = lot.QuantityMg
+			if expires, ok := lot.ExpiresOn(); ok {
+				picked.Expires = FormatReportDate(expires)
+			}
+			location := strings.TrimSpace(lot.Location)
+			if location == "" {
+				location = UnassignedLocation
+			}
+			add(location, picked)
+			remaining -= picked.QuantityMg
+		}
+		if remaining > 0 {
+			line.QuantityMg = remaining
+			add(PickListShortfall, line)
+		}
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group.Lines, func(i, j int) bool { return group.Lines[i].Order < group.Lines[j].Order })
+		list.Groups = append(list.Groups, *group)
+	}
+	sort.Slice(list.Groups, func(i, j int) bool {
+		a, b := pickListGroupRank(list.Groups[i].Location), pickListGroupRank(list.Groups[j].Location)
+		if a != b {
+			return a < b
+		}
+		return strings.ToLower(list.Groups[i].Location) < strings.ToLower(list.Groups[j].Location)
+	})
+	return list
+}
+
+// pickableLots returns the unexpired containers holding the report line's
+// material in the strength it is weighed from, in picking order.
+func pickableLots(stock []models.InventoryItem, item BatchProductionReportIngredient, now time.Time) []models.InventoryItem {
+	lots := make([]models.InventoryItem, 0)
+	for _, lot := range stock {
+		if lot.AromaChemicalID != item.ChemicalID || lot.DilutionLabel() != item.Dilution || lot.QuantityMg <= 0 {
+			continue
+		}
+		if lot.ExpiryState(now) == models.InventoryExpired {
+			continue
+		}
+		lots = append(lots, lot)
+	}
+	sort.SliceStable(lots, func(i, j int) bool {
+		a, aok := lots[i].ExpiresOn()
+		b, bok := lots[j].ExpiresOn()
+		switch {
+		case aok && bok && !a.Equal(b):
+			return a.Before(b)
+		case aok != bok:
+			return aok
+		}
+		return lots[i].QuantityMg > lots[j].QuantityMg
+	})
+	return lots
+}
+
+// pickListGroupRank keeps named locations first, then unlocated stock, the
+// solvent and finally the shortfall.
+func pickListGroupRank(location string) int {
+	switch location {
+	case UnassignedLocation:
+		return 1
+	case PickListSolvent:
+		return 2
+	case PickListShortfall:
+		return 3
+	default:
+		return 0
+	}
+}
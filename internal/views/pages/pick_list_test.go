@@ -0,0 +1,68 @@
+package pages
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"perfugo/models"
+)
+
+func TestBuildPickList(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	openedLongAgo := now.AddDate(-2, 0, 0)
+	openedRecently := now.AddDate(0, -3, 0)
+	openedLastYear := now.AddDate(-1, 0, 0)
+
+	report := BatchProductionReportData{
+		FormulaName: "Night Garden",
+		LotNumber:   "PERF-20260601-001",
+		Ingredients: []BatchProductionReportIngredient{
+			{Order: 1, ChemicalID: 1, IngredientName: "Hedione", FinalQuantity: 600},
+			{Order: 2, ChemicalID: 2, IngredientName: "Ambroxan", Dilution: "10% in DPG", FinalQuantity: 50},
+			{Order: 3, ChemicalID: 3, IngredientName: "Iso E Super", FinalQuantity: 200},
+			{Order: 4, IngredientName: "Ethanol", FinalQuantity: 1000, Solvent: true},
+		},
+	}
+	stock := []models.InventoryItem{
+		// Expired: never picked.
+		{AromaChemicalID: 1, QuantityMg: 5000, LotNumber: "H-OLD", Location: "Shelf A", ShelfLifeMonths: 12, OpenedOn: &openedLongAgo},
+		// Untracked expiry: picked after the dated lot.
+		{AromaChemicalID: 1, QuantityMg: 5000, LotNumber: "H-2", Location: "Shelf A"},
+		{AromaChemicalID: 1, QuantityMg: 400, LotNumber: "H-1", Location: "Fridge", ShelfLifeMonths: 24, OpenedOn: &openedLastYear},
+		{AromaChemicalID: 2, QuantityMg: 100, DilutionPercent: 10, Solvent: "dpg", Location: "Shelf A", ShelfLifeMonths: 12, OpenedOn: &openedRecently},
+		// Neat Ambroxan does not satisfy a line weighed from the dilution.
+		{AromaChemicalID: 2, QuantityMg: 100, Location: "Shelf B"},
+		{AromaChemicalID: 3, QuantityMg: 150},
+	}
+
+	list := BuildPickList(report, stock, now)
+
+	want := []struct {
+		location string
+		lines    []string
+	}{
+		{"Fridge", []string{"Hedione H-1 400"}},
+		{"Shelf A", []string{"Hedione H-2 200", "Ambroxan  50"}},
+		{UnassignedLocation, []string{"Iso E Super  150"}},
+		{PickListSolvent, []string{"Ethanol  1000"}},
+		{PickListShortfall, []string{"Iso E Super  50"}},
+	}
+	if len(list.Groups) != len(want) {
+		t.Fatalf("expected %d groups, got %+v", len(want), list.Groups)
+	}
+	for i, group := range list.Groups {
+		if group.Location != want[i].location || len(group.Lines) != len(want[i].lines) {
+			t.Fatalf("group %d: expected %s with %d lines, got %+v", i, want[i].location, len(want[i].lines), group)
+		}
+		for j, line := range group.Lines {
+			got := fmt.Sprintf("%s %s %.0f", line.IngredientName, line.LotNumber, line.QuantityMg)
+			if got != want[i].lines[j] {
+				t.Fatalf("group %s line %d: got %q, want %q", group.Location, j, got, want[i].lines[j])
+			}
+		}
+	}
+	if list.Lines() != 6 {
+		t.Fatalf("Lines() = %d, want 6", list.Lines())
+	}
+}
@@ -0,0 +1,37 @@
+package pages
+
+import (
+	"time"
+
+	"perfugo/internal/charts"
+)
+
+// IngredientPriceHistoryPath serves the price history of an aroma chemical.
+const IngredientPriceHistoryPath = "/app/sections/ingredients/price-history"
+
+// PricePoint is the price an aroma chemical had when a production batch
+// was priced, or its current price for the last point.
+type PricePoint struct {
+	At         time.Time
+	PricePerMg float64
+}
+
+// PriceHistory is an aroma chemical's price over the member's production
+// batches, oldest first.
+type PriceHistory struct {
+	ChemicalID uint
+	Points     []PricePoint
+}
+
+// Chart plots the history. A single point has nothing to compare, so it
+// draws no line.
+func (h PriceHistory) Chart(format NumberFormat) charts.LineChart {
+	chart := charts.LineChart{Title: "Price per mg", FormatValue: format.FormatPricePerMg}
+	if len(h.Points) < 2 {
+		return chart
+	}
+	for _, point := range h.Points {
+		chart.Points = append(chart.Points, charts.Point{At: point.At, Value: point.PricePerMg})
+	}
+	return chart
+}
@@ -0,0 +1,166 @@
+package pages
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"perfugo/models"
+)
+
+// ProductionBatchPage drives the weighing workflow for one batch. Message
+// reports the outcome of the last action and Problems lists what stopped
+// the batch from being finalized.
+type ProductionBatchPage struct {
+	Batch    models.ProductionBatch
+	Message  string
+	Problems []string
+	Costs    BatchCosts
+	// LabelPrinter is the language of the configured label printer, or
+	// empty when labels can only be downloaded.
+	LabelPrinter string
+}
+
+// BatchCostLine prices the neat chemical weighed on one batch line.
+type BatchCostLine struct {
+	Position     int
+	Name         string
+	Dilution     string
+	NeatQuantity float64
+	PricePerMg   float64
+	Cost         float64
+}
+
+// BatchCosts prices a batch either with the prices recorded when it was
+// started or, when Current is set, with today's prices. Recorded is false
+// for batches started before prices were kept, which can only be priced
+// at today's prices.
+type BatchCosts struct {
+	Current  bool
+	Recorded bool
+	AsOf     time.Time
+	Lines    []BatchCostLine
+	Total    float64
+}
+
+// BatchCostsFor prices the batch's chemical lines. current maps chemical
+// IDs to today's prices and is used when recorded prices are not wanted or
+// not available.
+func BatchCostsFor(batch models.ProductionBatch, current map[uint]float64, useCurrent bool, now time.Time) BatchCosts {
+	costs := BatchCosts{Recorded: batch.PricedAt != nil}
+	costs.Current = useCurrent || !costs.Recorded
+	costs.AsOf = now
+	if !costs.Current {
+		costs.AsOf = *batch.PricedAt
+	}
+	for _, line := range batch.Lines {
+		if line.Solvent || line.AromaChemicalID == nil {
+			continue
+		}
+		neat := line.NeatQuantity
+		if !costs.Recorded && line.Dilution == "" {
+			// Older batches did not record the neat share; undiluted
+			// lines are weighed neat.
+			neat = line.TargetQuantity
+		}
+		price := line.PricePerMg
+		if costs.Current {
+			price = current[*line.AromaChemicalID]
+		}
+		costLine := BatchCostLine{
+			Position:     line.Position,
+			Name:         line.IngredientName,
+			Dilution:     line.Dilution,
+			NeatQuantity: neat,
+			PricePerMg:   price,
+			Cost:         neat * price,
+		}
+		costs.Total += costLine.Cost
+		costs.Lines = append(costs.Lines, costLine)
+	}
+	return costs
+}
+
+// ProductionBatchCostURL links to a batch priced at the recorded prices or,
+// when current is set, at today's prices.
+func ProductionBatchCostURL(id uint, current bool) string {
+	url := ProductionBatchURL(id)
+	if current {
+		url += "&prices=current"
+	}
+	return url + "#costs"
+}
+
+// ProductionBatchSummary lists a batch on the reports page.
+type ProductionBatchSummary struct {
+	ID        uint
+	FormulaID uint
+	Formula   string
+	LotNumber string
+	Status    string
+	Started   string
+	Lines     int
+	Weighed   int
+	Flagged   int
+}
+
+// ProductionBatchURL links to the weighing page of a batch.
+func ProductionBatchURL(id uint) string {
+	return Path(fmt.Sprintf("/app/production/batch?id=%d", id))
+}
+
+// FormatTolerance renders a tolerance for a form input.
+func FormatTolerance(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// BatchToleranceSummary describes a batch's tolerances, such as
+// "± 5 mg or 2%, whichever is larger".
+func BatchToleranceSummary(batch models.ProductionBatch) string {
+	return fmt.Sprintf("± %s mg or %s%%, whichever is larger", FormatTolerance(batch.ToleranceMg), FormatTolerance(batch.TolerancePercent))
+}
+
+// ActualQuantityValue renders a line's recorded weight for its input, or an
+// empty string before it has been weighed.
+func ActualQuantityValue(line models.ProductionBatchLine) string {
+	if line.ActualQuantity == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*line.ActualQuantity, 'f', -1, 64)
+}
+
+// FormatDeviation renders how far a weighed line is from its target, such as
+// "+12 mg (+2.4%)", or a dash before it has been weighed.
+func FormatDeviation(line models.ProductionBatchLine) string {
+	if !line.Weighed() {
+		return "—"
+	}
+	return fmt.Sprintf("%+.0f mg (%+.1f%%)", line.Deviation(), line.DeviationPercent())
+}
+
+// BatchLineStatus labels a line for the weighing table.
+func BatchLineStatus(batch models.ProductionBatch, line models.ProductionBatchLine) string {
+	switch {
+	case !line.Weighed():
+		return "Pending"
+	case !batch.OutOfTolerance(line):
+		return "Within tolerance"
+	case line.Justification != "":
+		return "Out of tolerance · justified"
+	default:
+		return "Out of tolerance"
+	}
+}
+
+// BatchSignatureSummary describes the electronic signature on a finalized
+// batch, eg. "Ana Costa · 03 Mar 2025 14:05 UTC · password re-entered".
+func BatchSignatureSummary(batch models.ProductionBatch) string {
+	if !batch.Signed() {
+		return ""
+	}
+	method := "password re-entered"
+	if batch.SignatureMethod == models.SignatureMethodSession {
+		method = "single sign-on session confirmed"
+	}
+	return fmt.Sprintf("%s · %s · %s", batch.SignedByName, batch.SignedAt.UTC().Format("02 Jan 2006 15:04 MST"), method)
+}
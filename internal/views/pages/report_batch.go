@@ -1,21 +1,31 @@
 package pages
 
 import (
-	"fmt"
-	"strings"
+	"strconv"
 	"time"
 )
 
 // BatchProductionReportIngredient captures the scaled contribution of a single aroma chemical.
 type BatchProductionReportIngredient struct {
 	Order          int
+	ChemicalID     uint
 	IngredientName string
 	CASNumber      string
 	Pyramid        string
 	PyramidLabel   string
 	BaseQuantity   float64
 	FinalQuantity  float64
-	Unit           string
+	// Dilution names the stock solution the line is weighed from, such as
+	// "10% in DPG". NeatQuantity is the neat chemical it contains and
+	// DilutionSolvent the ID of the solvent making up the rest.
+	Dilution        string
+	NeatQuantity    float64
+	DilutionSolvent string
+	Unit            string
+	// Solvent marks the carrier line appended for finished-product batches.
+	Solvent bool
+	// PricePerMg is the chemical's price when the report was generated.
+	PricePerMg float64
 }
 
 // BatchProductionReportData aggregates the metadata required to render the production form.
@@ -30,14 +40,51 @@ type BatchProductionReportData struct {
 	LotNumber         string
 	RunDate           time.Time
 	Ingredients       []BatchProductionReportIngredient
+	// ConcentratePercent is set for finished-product batches, where the
+	// scaled concentrate is topped up with SolventName to TargetQuantity.
+	// ConcentrateByVolume records that the percentage was read as v/v.
+	ConcentrateLabel    string
+	ConcentratePercent  float64
+	ConcentrateByVolume bool
+	ConcentrateQuantity float64
+	SolventName         string
+	SolventQuantity     float64
 }
 
-// FormatReportQuantity renders a quantity using two decimal places and a trailing unit.
-func FormatReportQuantity(value float64, unit string) string {
-	if strings.EqualFold(unit, "mg") {
-		return fmt.Sprintf("%.0f %s", value, unit)
+// HasStockSolutions reports whether any line is weighed from a dilution.
+func (d BatchProductionReportData) HasStockSolutions() bool {
+	for _, item := range d.Ingredients {
+		if item.Dilution != "" {
+			return true
+		}
 	}
-	return fmt.Sprintf("%.2f %s", value, unit)
+	return false
+}
+
+// FinishedProduct reports whether the batch includes a solvent line.
+func (d BatchProductionReportData) FinishedProduct() bool {
+	return d.ConcentratePercent > 0
+}
+
+// ConcentrationSummary describes the finished-product strength, such as
+// "Eau de Parfum · 18% by volume".
+func (d BatchProductionReportData) ConcentrationSummary() string {
+	basis := "by weight"
+	if d.ConcentrateByVolume {
+		basis = "by volume"
+	}
+	summary := FormatConcentration(d.ConcentratePercent) + " " + basis
+	if d.ConcentrateLabel != "" {
+		summary = d.ConcentrateLabel + " · " + summary
+	}
+	return summary
+}
+
+// FormatReportQuantity renders a quantity and its unit in the default
+// number format. Templates use NumberFormatFrom(ctx).FormatQuantity so the
+// reader's precision applies.
+func FormatReportQuantity(value float64, unit string) string {
+	return DefaultNumberFormat().FormatQuantity(value, unit)
 }
 
 // FormatReportDate renders the supplied time using a production-friendly layout.
@@ -47,3 +94,27 @@ func FormatReportDate(v time.Time) string {
 	}
 	return v.Format("02 Jan 2006")
 }
+
+// FormatConcentration renders a concentrate share such as "18%" or "12.5%".
+func FormatConcentration(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', -1, 64) + "%"
+}
+
+// PresetQuantityLabel names a batch quantity in milligrams with the largest
+// whole unit, such as "10 g" or "1 kg".
+func PresetQuantityLabel(mg float64) string {
+	switch {
+	case mg >= 1e6:
+		return strconv.FormatFloat(mg/1e6, 'f', -1, 64) + " kg"
+	case mg >= 1e3:
+		return strconv.FormatFloat(mg/1e3, 'f', -1, 64) + " g"
+	default:
+		return strconv.FormatFloat(mg, 'f', -1, 64) + " mg"
+	}
+}
+
+// PresetQuantityValue is a preset's quantity as the batch form's mg input
+// value.
+func PresetQuantityValue(mg float64) string {
+	return strconv.FormatFloat(mg, 'f', -1, 64)
+}
@@ -0,0 +1,18 @@
+package pages
+
+import "perfugo/models"
+
+// RetentionRule is how long one kind of record is kept; zero Days keeps it
+// forever.
+type RetentionRule struct {
+	Label string
+	Days  int
+}
+
+// RetentionPanel drives the administrator's data retention controls.
+// Purges are the latest audited cleanup runs, newest first.
+type RetentionPanel struct {
+	Rules   []RetentionRule
+	Purges  []models.AuditEntry
+	Message string
+}
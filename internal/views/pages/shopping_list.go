@@ -0,0 +1,112 @@
+package pages
+
+import (
+	"fmt"
+	"math"
+
+	"perfugo/models"
+)
+
+// UnassignedSupplier groups shopping list lines without a known supplier.
+const UnassignedSupplier = "Unassigned supplier"
+
+// ShoppingList is the consolidated purchase plan for a set of planned batches.
+type ShoppingList struct {
+	Batches []PlannedBatch
+	Groups  []ShoppingListGroup
+	// Covered counts the materials that current inventory already satisfies.
+	Covered int
+}
+
+// PlannedBatch echoes a batch that contributed to a shopping list.
+type PlannedBatch struct {
+	FormulaName string
+	Quantity    float64
+}
+
+// ShoppingListGroup collects the lines bought from a single supplier.
+type ShoppingListGroup struct {
+	Supplier      string
+	Lines         []ShoppingListLine
+	EstimatedCost float64
+}
+
+// ShoppingListLine is the shortfall of a single material across all batches.
+type ShoppingListLine struct {
+	ChemicalID     uint
+	IngredientName string
+	CASNumber      string
+	RequiredMg     float64
+	OnHandMg       float64
+	ToBuyMg        float64
+	EstimatedCost  float64
+}
+
+// Empty reports whether nothing needs to be bought.
+func (l ShoppingList) Empty() bool {
+	return len(l.Groups) == 0
+}
+
+// EstimatedCost totals the priced lines across every supplier.
+func (l ShoppingList) EstimatedCost() float64 {
+	var total float64
+	for _, group := range l.Groups {
+		total += group.EstimatedCost
+	}
+	return total
+}
+
+// FormatCost renders a price estimate, or an em dash when no price is known.
+func FormatCost(value float64) string {
+	if value <= 0 || math.IsNaN(value) {
+		return "—"
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+// InventoryPanel lists the current user's stock for the inventory card.
+// Expiring holds the opened materials that are expired or expire within
+// models.ExpiryWarningDays, soonest first.
+type InventoryPanel struct {
+	Items    []InventoryRow
+	Expiring []InventoryRow
+	Message  string
+}
+
+// Stocked returns the chemical IDs with a positive quantity on hand.
+func (p InventoryPanel) Stocked() map[uint]bool {
+	stocked := make(map[uint]bool, len(p.Items))
+	for _, item := range p.Items {
+		if item.QuantityMg > 0 {
+			stocked[item.ChemicalID] = true
+		}
+	}
+	return stocked
+}
+
+// InventoryRow describes a single stocked material. Expires is blank when
+// the material's shelf life is not tracked; Expiry is one of the
+// models.Inventory* expiry states.
+type InventoryRow struct {
+	ChemicalID     uint
+	IngredientName string
+	Dilution       string
+	QuantityMg     float64
+	Supplier       string
+	Location       string
+	LotNumber      string
+	Expires        string
+	Expiry         string
+}
+
+// ExpiryLabel describes a row's expiry for the inventory list.
+func (r InventoryRow) ExpiryLabel() string {
+	switch {
+	case r.Expires == "":
+		return ""
+	case r.Expiry == models.InventoryExpired:
+		return "expired " + r.Expires
+	default:
+		return "expires " + r.Expires
+	}
+}
@@ -0,0 +1,29 @@
+package pages
+
+import "perfugo/models"
+
+// ShortcutBinding is one row of the keyboard shortcut preferences.
+type ShortcutBinding struct {
+	Action  string
+	Label   string
+	Key     string
+	Default string
+}
+
+// ShortcutRows lists every bindable action with its key in bindings.
+func ShortcutRows(bindings map[string]string) []ShortcutBinding {
+	rows := make([]ShortcutBinding, 0, len(models.Shortcuts))
+	for _, shortcut := range models.Shortcuts {
+		key := bindings[shortcut.Action]
+		if key == "" {
+			key = shortcut.Default
+		}
+		rows = append(rows, ShortcutBinding{
+			Action:  shortcut.Action,
+			Label:   shortcut.Label,
+			Key:     key,
+			Default: shortcut.Default,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,48 @@
+package pages
+
+import (
+	"fmt"
+
+	"perfugo/models"
+)
+
+const (
+	// StaleProfilesPath lists the AI-generated profiles due for a refresh.
+	StaleProfilesPath = "/app/sections/tools/stale-profiles"
+	// StaleProfileReenrichPath regenerates one of them.
+	StaleProfileReenrichPath = "/app/sections/tools/stale-profiles/reenrich"
+)
+
+// StaleProfile is one AI-generated ingredient profile due for a refresh.
+type StaleProfile struct {
+	Chemical models.AromaChemical
+	// Deprecated is set when the profile came from a deprecated model.
+	Deprecated bool
+}
+
+// Reason says why the profile is listed.
+func (p StaleProfile) Reason() string {
+	if p.Deprecated {
+		return fmt.Sprintf("Generated by %s, which is deprecated.", p.Chemical.AIModel)
+	}
+	model := p.Chemical.AIModel
+	if model == "" {
+		model = "an unrecorded model"
+	}
+	return fmt.Sprintf("Generated by %s on %s.", model, p.Chemical.AIFetchedAt.Format("2 Jan 2006"))
+}
+
+// StaleProfileReport lists the member's AI-generated profiles older than
+// Months or generated by a deprecated model, oldest first.
+type StaleProfileReport struct {
+	Months   int
+	Profiles []StaleProfile
+	Message  string
+	// CanReenrich is false when no AI client is configured.
+	CanReenrich bool
+}
+
+// MonthsValue is Months as a form value.
+func (r StaleProfileReport) MonthsValue() string {
+	return fmt.Sprintf("%d", r.Months)
+}
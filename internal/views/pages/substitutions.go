@@ -0,0 +1,23 @@
+package pages
+
+import "strconv"
+
+// SubstitutionPanel lists the current user's substitutions on the tools page.
+type SubstitutionPanel struct {
+	Rows    []SubstitutionRow
+	Message string
+}
+
+// SubstitutionRow describes a single substitution rule.
+type SubstitutionRow struct {
+	ID             uint
+	OriginalName   string
+	SubstituteName string
+	Ratio          float64
+	Notes          string
+}
+
+// FormatRatio renders a substitution ratio for display and form values.
+func FormatRatio(ratio float64) string {
+	return strconv.FormatFloat(ratio, 'f', -1, 64)
+}
@@ -0,0 +1,24 @@
+package pages
+
+import "perfugo/models"
+
+// TaxonomyPanel drives the administrator's wheel taxonomy controls: the
+// approved terms members pick from and the queue of suggested ones.
+type TaxonomyPanel struct {
+	Terms       []models.TaxonomyTerm
+	Suggestions []models.TaxonomyTerm
+	Message     string
+}
+
+// TaxonomyOptions is the editor's view of a governed vocabulary. Terms is
+// empty while the vocabulary is ungoverned and any value is accepted.
+type TaxonomyOptions struct {
+	Vocabulary string
+	Terms      []string
+	Message    string
+}
+
+// Governed reports whether members must pick from Terms.
+func (o TaxonomyOptions) Governed() bool {
+	return len(o.Terms) > 0
+}
@@ -0,0 +1,66 @@
+package pages
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// UserProfile identifies the signed-in user in the workspace header and on
+// the preferences page. UploadsEnabled reports whether an avatar can be
+// uploaded on this instance, FeedEnabled whether the user's public library
+// feed is on and CalendarEnabled whether their calendar feed is.
+// LandingSection is the section /app opens with and WeeklyDigest whether
+// the user receives the weekly digest email.
+type UserProfile struct {
+	ID              uint
+	Name            string
+	Email           string
+	AvatarKey       string
+	UploadsEnabled  bool
+	FeedEnabled     bool
+	CalendarEnabled bool
+	LandingSection  string
+	WeeklyDigest    bool
+}
+
+// DisplayName prefers the user's name and falls back to their email.
+func (p UserProfile) DisplayName() string {
+	if name := strings.TrimSpace(p.Name); name != "" {
+		return name
+	}
+	return strings.TrimSpace(p.Email)
+}
+
+// Initials returns up to two letters standing in for a missing avatar, taken
+// from the first and last words of the name, or of the email's local part.
+func (p UserProfile) Initials() string {
+	source := strings.TrimSpace(p.Name)
+	if source == "" {
+		source, _, _ = strings.Cut(strings.TrimSpace(p.Email), "@")
+	}
+	words := strings.FieldsFunc(source, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "?"
+	}
+	initials := []rune{[]rune(words[0])[0]}
+	if len(words) > 1 {
+		initials = append(initials, []rune(words[len(words)-1])[0])
+	}
+	return strings.ToUpper(string(initials))
+}
+
+// HasAvatar reports whether the user has uploaded an avatar.
+func (p UserProfile) HasAvatar() bool {
+	return p.AvatarKey != ""
+}
+
+// AvatarURL links to the user's avatar. The version parameter changes with
+// every upload so browsers can cache each image for long.
+func (p UserProfile) AvatarURL() string {
+	version := strings.TrimSuffix(path.Base(p.AvatarKey), path.Ext(p.AvatarKey))
+	return Path(fmt.Sprintf("/app/avatar?id=%d&v=%s", p.ID, version))
+}
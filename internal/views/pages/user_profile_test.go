@@ -0,0 +1,38 @@
+package pages
+
+import "testing"
+
+func TestUserProfileInitials(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		profile UserProfile
+		want    string
+	}{
+		{"full name", UserProfile{Name: "Ada Lovelace"}, "AL"},
+		{"middle names skipped", UserProfile{Name: "jean claude ellena"}, "JE"},
+		{"single name", UserProfile{Name: "Germaine"}, "G"},
+		{"email local part", UserProfile{Email: "jane.doe@example.com"}, "JD"},
+		{"nothing to go on", UserProfile{}, "?"},
+	}
+
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.profile.Initials(); got != tt.want {
+				t.Fatalf("Initials() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserProfileAvatarURLChangesWithKey(t *testing.T) {
+	t.Parallel()
+
+	profile := UserProfile{ID: 7, AvatarKey: "avatars/7-0a1b2c.png"}
+	if got, want := profile.AvatarURL(), "/app/avatar?id=7&v=7-0a1b2c"; got != want {
+		t.Fatalf("AvatarURL() = %q, want %q", got, want)
+	}
+}
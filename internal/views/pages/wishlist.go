@@ -0,0 +1,40 @@
+package pages
+
+import (
+	"fmt"
+
+	"perfugo/models"
+)
+
+// WishlistPanel lists the materials the current user plans to buy.
+type WishlistPanel struct {
+	Items   []WishlistRow
+	Message string
+}
+
+// WishlistRow is a single wishlisted material. InStock is set once the user
+// has recorded stock of it, so the entry can be struck off.
+type WishlistRow struct {
+	ChemicalID     uint
+	IngredientName string
+	Supplier       string
+	Note           string
+	InStock        bool
+}
+
+// Materials returns the wishlisted chemical IDs.
+func (p WishlistPanel) Materials() map[uint]bool {
+	materials := make(map[uint]bool, len(p.Items))
+	for _, item := range p.Items {
+		materials[item.ChemicalID] = true
+	}
+	return materials
+}
+
+// WishlistStockTitle explains a "not buildable yet" badge.
+func WishlistStockTitle(stock models.FormulaStock) string {
+	if stock.Wishlisted == 1 {
+		return "1 material is only on your wishlist"
+	}
+	return fmt.Sprintf("%d materials are only on your wishlist", stock.Wishlisted)
+}
@@ -1,8 +1,11 @@
 package pages
 
 import (
+	"strconv"
 	"strings"
 	"time"
+
+	"perfugo/models"
 )
 
 // DefaultDash returns an em dash when the provided value is empty or whitespace.
@@ -57,3 +60,35 @@ func SolventLabel(value bool) string {
 	}
 	return "Aromatic Ingredient"
 }
+
+// OriginLabel describes where an ingredient comes from.
+func OriginLabel(chemical models.AromaChemical) string {
+	label := "Unknown origin"
+	switch chemical.Origin {
+	case models.OriginNatural:
+		label = "Natural"
+	case models.OriginSynthetic:
+		label = "Synthetic"
+	}
+	if chemical.AnimalDerived {
+		label += " · animal-derived"
+	}
+	return label
+}
+
+// InvitationRecipient labels a pending invitation by its address, if any.
+func InvitationRecipient(item InvitationItem) string {
+	if item.Email == "" {
+		return "Open invitation"
+	}
+	return item.Email
+}
+
+// ProductionConcentrationValue renders the saved concentration for the
+// preferences input, leaving it blank when none is set.
+func ProductionConcentrationValue(production ProductionDefaults) string {
+	if production.Concentration <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(production.Concentration, 'f', -1, 64)
+}
@@ -2,6 +2,7 @@ package pages
 
 import (
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -13,8 +14,16 @@ type IngredientFilters struct {
 	Query   string
 	Pyramid string
 	Wheel   string
+	Sort    string
 }
 
+// Ingredient sort orders understood by FilterAromaChemicals.
+const (
+	IngredientSortName       = ""
+	IngredientSortPopularity = "popularity"
+	IngredientSortUsage      = "usage"
+)
+
 // IngredientFiltersFromRequest extracts filter inputs from an HTTP request.
 func IngredientFiltersFromRequest(r *http.Request) IngredientFilters {
 	filters := IngredientFilters{}
@@ -24,6 +33,10 @@ func IngredientFiltersFromRequest(r *http.Request) IngredientFilters {
 	filters.Query = strings.TrimSpace(r.FormValue("q"))
 	filters.Pyramid = strings.TrimSpace(r.FormValue("pyramid"))
 	filters.Wheel = strings.TrimSpace(r.FormValue("wheel"))
+	switch sortOrder := strings.ToLower(strings.TrimSpace(r.FormValue("sort"))); sortOrder {
+	case IngredientSortPopularity, IngredientSortUsage:
+		filters.Sort = sortOrder
+	}
 	return filters
 }
 
@@ -38,7 +51,8 @@ func FilterAromaChemicals(all []models.AromaChemical, filters IngredientFilters)
 		if query != "" &&
 			!(containsFold(chemical.IngredientName, query) ||
 				containsFold(chemical.CASNumber, query) ||
-				containsFold(chemical.Type, query)) {
+				containsFold(chemical.Type, query) ||
+				otherNameContains(chemical.OtherNames, query)) {
 			continue
 		}
 
@@ -57,9 +71,31 @@ func FilterAromaChemicals(all []models.AromaChemical, filters IngredientFilters)
 
 		filtered = append(filtered, chemical)
 	}
+	sortAromaChemicals(filtered, filters.Sort)
 	return filtered
 }
 
+// sortAromaChemicals orders chemicals by the requested score, highest first,
+// falling back to name. The name order leaves the slice untouched.
+func sortAromaChemicals(chemicals []models.AromaChemical, order string) {
+	var score func(models.AromaChemical) int
+	switch order {
+	case IngredientSortPopularity:
+		score = func(c models.AromaChemical) int { return c.Popularity }
+	case IngredientSortUsage:
+		score = func(c models.AromaChemical) int { return c.UsagePopularity }
+	default:
+		return
+	}
+	sort.SliceStable(chemicals, func(i, j int) bool {
+		left, right := score(chemicals[i]), score(chemicals[j])
+		if left != right {
+			return left > right
+		}
+		return strings.ToLower(chemicals[i].IngredientName) < strings.ToLower(chemicals[j].IngredientName)
+	})
+}
+
 // FindAromaChemical returns the first aroma chemical matching the requested identifier.
 func FindAromaChemical(all []models.AromaChemical, id uint) *models.AromaChemical {
 	for i := range all {
@@ -72,7 +108,17 @@ func FindAromaChemical(all []models.AromaChemical, id uint) *models.AromaChemica
 
 // FormulaFilters capture the client-driven state for formula lookups.
 type FormulaFilters struct {
-	Query string
+	Query  string
+	Status string
+	Client string
+	// Stock is StockFilterBuildable or StockFilterMissing to narrow the list
+	// by the viewing user's inventory.
+	Stock string
+}
+
+// Active reports whether any filter narrows the list.
+func (f FormulaFilters) Active() bool {
+	return f.Query != "" || f.Status != "" || f.Client != "" || f.Stock != ""
 }
 
 // FormulaFiltersFromRequest extracts filter inputs for formula listings.
@@ -82,20 +128,44 @@ func FormulaFiltersFromRequest(r *http.Request) FormulaFilters {
 		return filters
 	}
 	filters.Query = strings.TrimSpace(r.FormValue("q"))
+	// Prefixed so they do not collide with the brief fields of the editor,
+	// which submits the list filters alongside.
+	filters.Status = strings.TrimSpace(r.FormValue("filter_status"))
+	filters.Client = strings.TrimSpace(r.FormValue("filter_client"))
+	switch stock := strings.TrimSpace(r.FormValue("filter_stock")); stock {
+	case StockFilterBuildable, StockFilterMissing:
+		filters.Stock = stock
+	}
 	return filters
 }
 
-// FilterFormulas applies the provided filters to a list of formulas.
+// FilterFormulas applies the provided filters to a list of formulas. The
+// query matches the name, notes, client and inspiration; status and client
+// must match exactly, and the stock filter reads each formula's Stock.
 func FilterFormulas(all []models.Formula, filters FormulaFilters) []models.Formula {
-	if filters.Query == "" {
+	if !filters.Active() {
 		return all
 	}
 	query := strings.ToLower(filters.Query)
 	filtered := make([]models.Formula, 0, len(all))
 	for _, formula := range all {
-		if containsFold(formula.Name, query) || containsFold(formula.Notes, query) {
-			filtered = append(filtered, formula)
+		if filters.Status != "" && FormulaStatus(formula) != filters.Status {
+			continue
 		}
+		if filters.Client != "" && !strings.EqualFold(strings.TrimSpace(formula.Client), filters.Client) {
+			continue
+		}
+		if filters.Stock == StockFilterBuildable && !formula.Stock.Buildable() {
+			continue
+		}
+		if filters.Stock == StockFilterMissing && formula.Stock.Missing == 0 {
+			continue
+		}
+		if query != "" && !containsFold(formula.Name, query) && !containsFold(formula.Notes, query) &&
+			!containsFold(formula.Client, query) && !containsFold(formula.Inspiration, query) {
+			continue
+		}
+		filtered = append(filtered, formula)
 	}
 	return filtered
 }
@@ -137,9 +207,22 @@ func ParseUint(value string) uint {
 	return uint(parsed)
 }
 
+// containsFold reports whether haystack contains needle, ignoring case and
+// accents.
 func containsFold(haystack, needle string) bool {
 	if needle == "" {
 		return true
 	}
-	return strings.Contains(strings.ToLower(haystack), needle)
+	return strings.Contains(models.FoldName(haystack), models.FoldName(needle))
+}
+
+// otherNameContains reports whether any of names contains needle, in any
+// language.
+func otherNameContains(names []models.OtherName, needle string) bool {
+	for _, name := range names {
+		if containsFold(name.Name, needle) {
+			return true
+		}
+	}
+	return false
 }
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"gorm.io/gorm"
 	"perfugo/models"
@@ -39,6 +40,7 @@ func TestFilterAromaChemicals(t *testing.T) {
 	chemicals := []models.AromaChemical{
 		{IngredientName: "Alpha", CASNumber: "111", PyramidPosition: "Top", WheelPosition: "Citrus"},
 		{IngredientName: "Beta", Type: "Base", PyramidPosition: "Base", WheelPosition: "Woody"},
+		{IngredientName: "Hedione", OtherNames: []models.OtherName{{Name: "Dihydrojasmonate de méthyle", Language: "fr"}}},
 	}
 	f := IngredientFilters{Query: "beta"}
 	filtered := FilterAromaChemicals(chemicals, f)
@@ -46,6 +48,12 @@ func TestFilterAromaChemicals(t *testing.T) {
 		t.Fatalf("expected Beta chemical, got %+v", filtered)
 	}
 
+	f = IngredientFilters{Query: "de methyle"}
+	filtered = FilterAromaChemicals(chemicals, f)
+	if len(filtered) != 1 || filtered[0].IngredientName != "Hedione" {
+		t.Fatalf("expected the French name to match without accents, got %+v", filtered)
+	}
+
 	f = IngredientFilters{Pyramid: "base"}
 	filtered = FilterAromaChemicals(chemicals, f)
 	if len(filtered) != 1 || filtered[0].PyramidPosition != "Base" {
@@ -65,6 +73,34 @@ func TestFilterAromaChemicals(t *testing.T) {
 	}
 }
 
+func TestFilterAromaChemicalsSort(t *testing.T) {
+	chemicals := []models.AromaChemical{
+		{IngredientName: "Alpha", Popularity: 1, UsagePopularity: 4},
+		{IngredientName: "beta", Popularity: 3, UsagePopularity: 4},
+		{IngredientName: "Gamma", Popularity: 2, UsagePopularity: 9},
+	}
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{sort: IngredientSortName, want: []string{"Alpha", "beta", "Gamma"}},
+		{sort: IngredientSortPopularity, want: []string{"beta", "Gamma", "Alpha"}},
+		{sort: IngredientSortUsage, want: []string{"Gamma", "Alpha", "beta"}},
+	}
+	for _, tt := range tests {
+		filtered := FilterAromaChemicals(chemicals, IngredientFilters{Sort: tt.sort})
+		for i, name := range tt.want {
+			if filtered[i].IngredientName != name {
+				t.Fatalf("sort %q: expected %v at %d, got %s", tt.sort, tt.want, i, filtered[i].IngredientName)
+			}
+		}
+	}
+	if chemicals[0].IngredientName != "Alpha" {
+		t.Fatal("expected sorting to leave the input slice untouched")
+	}
+}
+
 func TestFilterFormulas(t *testing.T) {
 	formulas := []models.Formula{{Name: "Dawn", Notes: "citrus"}, {Name: "Dusk"}}
 	filters := FormulaFilters{Query: "cit"}
@@ -74,6 +110,85 @@ func TestFilterFormulas(t *testing.T) {
 	}
 }
 
+func TestFilterFormulasByBrief(t *testing.T) {
+	formulas := []models.Formula{
+		{Name: "Dawn", Client: "Maison Lune", Status: models.FormulaStatusApproved},
+		{Name: "Dusk", Client: "maison lune"},
+		{Name: "Noon", Client: "Atelier Sol", Status: models.FormulaStatusInReview},
+	}
+
+	filtered := FilterFormulas(formulas, FormulaFilters{Status: models.FormulaStatusDraft})
+	if len(filtered) != 1 || filtered[0].Name != "Dusk" {
+		t.Fatalf("expected legacy formula to count as draft, got %+v", filtered)
+	}
+
+	filtered = FilterFormulas(formulas, FormulaFilters{Client: "Maison Lune"})
+	if len(filtered) != 2 {
+		t.Fatalf("expected client filter to ignore case, got %+v", filtered)
+	}
+
+	if clients := FormulaClients(formulas); len(clients) != 2 || clients[0] != "Atelier Sol" {
+		t.Fatalf("expected distinct sorted clients, got %v", clients)
+	}
+}
+
+func TestFilterFormulasByStock(t *testing.T) {
+	oud, rose, iso := uint(1), uint(2), uint(3)
+	formulas := []models.Formula{{Name: "Base"}, {Name: "Nuit"}, {Name: "Rose"}, {Name: "Empty"}}
+	for i := range formulas {
+		formulas[i].ID = uint(i + 1)
+	}
+	base := uint(1)
+	ingredients := []models.FormulaIngredient{
+		{FormulaID: 1, AromaChemicalID: &oud},
+		{FormulaID: 1, AromaChemicalID: &iso},
+		{FormulaID: 2, SubFormulaID: &base},
+		{FormulaID: 2, AromaChemicalID: &rose},
+		{FormulaID: 3, AromaChemicalID: &rose},
+	}
+	AnnotateFormulaStock(formulas, ingredients, map[uint]bool{rose: true, iso: true}, map[uint]bool{oud: true})
+
+	if got := formulas[1].Stock; got != (models.FormulaStock{Materials: 3, Missing: 1, Wishlisted: 1}) {
+		t.Fatalf("unexpected stock for Nuit: %+v", got)
+	}
+	if label := FormulaStockLabel(formulas[2].Stock); label != "All in stock" {
+		t.Fatalf("unexpected label for Rose: %q", label)
+	}
+	if label := FormulaStockLabel(formulas[3].Stock); label != "" {
+		t.Fatalf("expected no badge for an empty formula, got %q", label)
+	}
+
+	if filtered := FilterFormulas(formulas, FormulaFilters{Stock: StockFilterBuildable}); len(filtered) != 1 || filtered[0].Name != "Rose" {
+		t.Fatalf("expected only Rose to be buildable, got %+v", filtered)
+	}
+	if filtered := FilterFormulas(formulas, FormulaFilters{Stock: StockFilterMissing}); len(filtered) != 2 {
+		t.Fatalf("expected Base and Nuit to miss materials, got %+v", filtered)
+	}
+}
+
+func TestFormulaBoardColumns(t *testing.T) {
+	soon := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	later := soon.AddDate(0, 1, 0)
+	formulas := []models.Formula{
+		{Name: "Undated"},
+		{Name: "Later", Deadline: &later, Status: models.FormulaStatusDraft},
+		{Name: "Soon", Deadline: &soon},
+		{Name: "Signed off", Status: models.FormulaStatusApproved},
+	}
+
+	columns := FormulaBoardColumns(formulas)
+	if len(columns) != 3 || columns[0].Status != models.FormulaStatusDraft || columns[2].Status != models.FormulaStatusApproved {
+		t.Fatalf("expected one lane per status in workflow order, got %+v", columns)
+	}
+	drafts := columns[0].Formulas
+	if len(drafts) != 3 || drafts[0].Name != "Soon" || drafts[1].Name != "Later" || drafts[2].Name != "Undated" {
+		t.Fatalf("expected drafts ordered by deadline, got %+v", drafts)
+	}
+	if len(columns[1].Formulas) != 0 || len(columns[2].Formulas) != 1 {
+		t.Fatalf("unexpected lane sizes: %+v", columns)
+	}
+}
+
 func TestFormulaIngredientsFor(t *testing.T) {
 	ingredients := []models.FormulaIngredient{{FormulaID: 1, Amount: 2}, {FormulaID: 2, Amount: 3}}
 	matches := FormulaIngredientsFor(ingredients, 1)
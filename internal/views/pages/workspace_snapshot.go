@@ -2,8 +2,10 @@ package pages
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
+	"strings"
 
 	"perfugo/models"
 )
@@ -15,6 +17,125 @@ type WorkspaceSnapshot struct {
 	AromaChemicals     []models.AromaChemical
 	Theme              string
 	UserID             uint
+	Profile            UserProfile
+	IsAdmin            bool
+	Impersonation      ImpersonationBanner
+	MaintenanceMode    bool
+	// DemoMode is set when no database is configured and the library is the
+	// read-only demo one.
+	DemoMode          bool
+	Activity          ActivityInsights
+	Invitations       InvitationPanel
+	ImportSchedules   ImportSchedulePanel
+	Blocklist         BlocklistPanel
+	Taxonomy          TaxonomyPanel
+	Retention         RetentionPanel
+	Production        ProductionDefaults
+	Print             PrintOptions
+	Inventory         InventoryPanel
+	Wishlist          WishlistPanel
+	Substitutions     SubstitutionPanel
+	LibraryHealth     LibraryHealth
+	ProductionBatches []ProductionBatchSummary
+	BatchPresets      []models.BatchPreset
+	CustomReports     []models.ReportDefinition
+	APITokens         []models.APIToken
+	// EditIngredientID opens the ingredient editor on load when set.
+	EditIngredientID uint
+	// EditFormulaID opens the formula editor on load when set.
+	EditFormulaID uint
+}
+
+// ProductionDefaults holds the user's finished-product settings and weighing
+// tolerances. A zero Concentration means no target has been saved.
+type ProductionDefaults struct {
+	Solvent          string
+	Concentration    float64
+	ToleranceMg      float64
+	TolerancePercent float64
+}
+
+// HasConcentration reports whether a usable target concentration is saved.
+func (d ProductionDefaults) HasConcentration() bool {
+	return d.Concentration > 0 && d.Concentration < 100
+}
+
+// InvitationPanel drives the administrator's invitation controls. Link holds
+// a freshly issued invitation URL, which is only available right after creation.
+type InvitationPanel struct {
+	InviteOnly bool
+	Link       string
+	Message    string
+	Pending    []InvitationItem
+}
+
+// InvitationItem describes an outstanding invitation.
+type InvitationItem struct {
+	Email   string
+	Expires string
+}
+
+// APITokenPanel drives the API token preferences. NewToken holds a freshly
+// issued token, which is only available right after creation.
+type APITokenPanel struct {
+	Tokens   []models.APIToken
+	NewToken string
+	Message  string
+}
+
+// APITokenUsage describes when a token was created and last used.
+func APITokenUsage(token models.APIToken) string {
+	usage := "Created " + token.CreatedAt.Format("02 Jan 2006")
+	if token.LastUsedAt == nil {
+		return usage + " · never used"
+	}
+	return usage + " · last used " + token.LastUsedAt.Format("02 Jan 2006")
+}
+
+// APITokenAccess describes what a token may do, eg. "Read-only · reports".
+func APITokenAccess(token models.APIToken) string {
+	resources := token.Resources()
+	switch {
+	case token.ReadOnly() && len(resources) > 0:
+		return "Read-only · " + strings.Join(resources, ", ")
+	case token.ReadOnly():
+		return "Read-only"
+	case len(resources) > 0:
+		return "Only " + strings.Join(resources, ", ")
+	default:
+		return "Full access"
+	}
+}
+
+// LibraryFeedPanel drives the public library feed preference. JSONURL and
+// AtomURL are only set right after the feed is enabled, as the token behind
+// them is not stored.
+type LibraryFeedPanel struct {
+	Enabled bool
+	JSONURL string
+	AtomURL string
+	Message string
+}
+
+// CalendarFeedPanel drives the calendar feed preference. URL is only set
+// right after the feed is enabled.
+type CalendarFeedPanel struct {
+	Enabled bool
+	URL     string
+	Message string
+}
+
+// ActivityInsights summarises which formulas and ingredients are being worked on.
+type ActivityInsights struct {
+	MostActive []ActivityItem
+	Untouched  []ActivityItem
+}
+
+// ActivityItem is a single entry in an activity list.
+type ActivityItem struct {
+	Kind   string
+	Name   string
+	Detail string
 }
 
 // NewWorkspaceSnapshot normalises and sorts the data required by the workspace views.
@@ -25,6 +146,9 @@ func NewWorkspaceSnapshot(formulas []models.Formula, ingredients []models.Formul
 
 	sort.SliceStable(ingredients, func(i, j int) bool {
 		if ingredients[i].FormulaID == ingredients[j].FormulaID {
+			if ingredients[i].Position != ingredients[j].Position {
+				return ingredients[i].Position < ingredients[j].Position
+			}
 			return ingredients[i].ID < ingredients[j].ID
 		}
 		return ingredients[i].FormulaID < ingredients[j].FormulaID
@@ -51,6 +175,9 @@ func EmptyWorkspaceSnapshot() WorkspaceSnapshot {
 // IngredientDisplayName returns a user-friendly label for the formula ingredient's source.
 func IngredientDisplayName(ingredient models.FormulaIngredient) string {
 	if ingredient.AromaChemical != nil && ingredient.AromaChemical.IngredientName != "" {
+		if ingredient.StockSolution != nil && ingredient.StockSolution.Diluted() {
+			return ingredient.AromaChemical.IngredientName + " " + ingredient.StockSolution.DilutionLabel()
+		}
 		return ingredient.AromaChemical.IngredientName
 	}
 	if ingredient.SubFormula != nil && ingredient.SubFormula.Name != "" {
@@ -100,6 +227,9 @@ func FormulaIngredientSourceValue(ingredient *models.FormulaIngredient) string {
 	if ingredient == nil {
 		return ""
 	}
+	if ingredient.StockSolutionID != nil && *ingredient.StockSolutionID != 0 {
+		return fmt.Sprintf("stock:%d", *ingredient.StockSolutionID)
+	}
 	if ingredient.AromaChemicalID != nil && *ingredient.AromaChemicalID != 0 {
 		return fmt.Sprintf("chem:%d", *ingredient.AromaChemicalID)
 	}
@@ -125,6 +255,15 @@ func FormulaIngredientAmountValue(ingredient *models.FormulaIngredient) string {
 	return strconv.FormatFloat(ingredient.Amount, 'f', -1, 64)
 }
 
+// FormulaIngredientPercentValue formats the row's share of the formula for
+// percentage entry, rounded to four decimal places.
+func FormulaIngredientPercentValue(ingredient *models.FormulaIngredient) string {
+	if ingredient == nil {
+		return ""
+	}
+	return strconv.FormatFloat(math.Round(ingredient.Percentage*1e4)/1e4, 'f', -1, 64)
+}
+
 // FormulaIngredientUnitValue returns the unit text for the ingredient row.
 func FormulaIngredientUnitValue(ingredient *models.FormulaIngredient) string {
 	if ingredient == nil {
@@ -132,3 +271,15 @@ func FormulaIngredientUnitValue(ingredient *models.FormulaIngredient) string {
 	}
 	return ingredient.Unit
 }
+
+// FindStockSolution looks up a stock solution preloaded on the chemicals.
+func FindStockSolution(chemicals []models.AromaChemical, id uint) *models.InventoryItem {
+	for i := range chemicals {
+		for j := range chemicals[i].StockSolutions {
+			if chemicals[i].StockSolutions[j].ID == id {
+				return &chemicals[i].StockSolutions[j]
+			}
+		}
+	}
+	return nil
+}
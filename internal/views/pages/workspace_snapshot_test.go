@@ -100,6 +100,19 @@ func TestFormulaIngredientSourceValueFallsBackToAssociations(t *testing.T) {
 	if got := FormulaIngredientSourceValue(&ing); got != "formula:7" {
 		t.Fatalf("expected formula fallback, got %s", got)
 	}
+
+	ing = models.FormulaIngredient{
+		AromaChemicalID: ptr(uint(11)),
+		StockSolutionID: ptr(uint(3)),
+		AromaChemical:   &models.AromaChemical{Model: gorm.Model{ID: 11}, IngredientName: "Cashmeran"},
+		StockSolution:   &models.InventoryItem{DilutionPercent: 10, Solvent: models.SolventDPG},
+	}
+	if got := FormulaIngredientSourceValue(&ing); got != "stock:3" {
+		t.Fatalf("expected stock solution source, got %s", got)
+	}
+	if got := IngredientDisplayName(ing); got != "Cashmeran 10% in DPG" {
+		t.Fatalf("expected dilution in display name, got %s", got)
+	}
 }
 
 func ptr[T any](value T) *T {
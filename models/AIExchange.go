@@ -0,0 +1,28 @@
+package models
+
+import "gorm.io/gorm"
+
+// AIExchange is a recorded call to the AI model, kept when AI debug logging
+// is on so failed extractions can be inspected and replayed. JobID links
+// the calls made for one import. Secrets and personal data are redacted
+// and each text field is capped before it is stored; Truncated reports
+// whether anything was cut.
+type AIExchange struct {
+	gorm.Model
+	JobID      string `gorm:"size:36;index" json:"job_id"`
+	UserID     *uint  `gorm:"index" json:"user_id,omitempty"`
+	Operation  string `gorm:"size:40;not null" json:"operation"`
+	ModelName  string `gorm:"size:120" json:"model"`
+	Input      string `gorm:"type:text" json:"input"`
+	Request    string `gorm:"type:text" json:"request"`
+	Response   string `gorm:"type:text" json:"response"`
+	Status     int    `json:"status"`
+	Error      string `gorm:"type:text" json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Truncated  bool   `gorm:"not null;default:false" json:"truncated"`
+}
+
+// Failed reports whether the call returned an error.
+func (e AIExchange) Failed() bool {
+	return e.Error != ""
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxAPITokens caps how many API tokens one user may hold.
+const MaxAPITokens = 10
+
+// API token scopes. A token without scopes has its owner's full access;
+// ScopeReadOnly refuses writes and the others limit which endpoints answer.
+const (
+	ScopeReadOnly    = "read"
+	ScopeIngredients = "ingredients"
+	ScopeReports     = "reports"
+)
+
+// APIResourceFormulas names the formula endpoints, which no resource scope
+// covers.
+const APIResourceFormulas = "formulas"
+
+// APIScopes lists the token scopes in display order.
+func APIScopes() []string {
+	return []string{ScopeReadOnly, ScopeIngredients, ScopeReports}
+}
+
+// NormalizeScopes validates requested scopes and returns them
+// space-separated in display order.
+func NormalizeScopes(requested []string) (string, bool) {
+	wanted := map[string]bool{}
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" {
+			continue
+		}
+		if scope != ScopeReadOnly && scope != ScopeIngredients && scope != ScopeReports {
+			return "", false
+		}
+		wanted[scope] = true
+	}
+	scopes := make([]string, 0, len(wanted))
+	for _, scope := range APIScopes() {
+		if wanted[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	return strings.Join(scopes, " "), true
+}
+
+// APIToken lets scripts and automation tools call the API on behalf of its
+// owner. Only the SHA-256 hash of the token is stored; the raw token is
+// shown once, when it is created. Scopes is space-separated; a token
+// without scopes has full access.
+type APIToken struct {
+	gorm.Model
+	OwnerID    uint       `gorm:"not null;index" json:"owner_id"`
+	Name       string     `gorm:"size:80;not null" json:"name"`
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"size:120" json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// ReadOnly reports whether the token refuses writes.
+func (t APIToken) ReadOnly() bool {
+	for _, scope := range strings.Fields(t.Scopes) {
+		if scope == ScopeReadOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// Resources returns the resource scopes the token is limited to, or nil
+// when it reaches every endpoint.
+func (t APIToken) Resources() []string {
+	var resources []string
+	for _, scope := range strings.Fields(t.Scopes) {
+		if scope != ScopeReadOnly {
+			resources = append(resources, scope)
+		}
+	}
+	return resources
+}
+
+// Allows reports whether the token may call an endpoint working on
+// resource, and writing to it when write is set. Resource scopes add up: a
+// token scoped to ingredients and reports reaches both.
+func (t APIToken) Allows(resource string, write bool) bool {
+	if write && t.ReadOnly() {
+		return false
+	}
+	resources := t.Resources()
+	if len(resources) == 0 {
+		return true
+	}
+	for _, allowed := range resources {
+		if allowed == resource {
+			return true
+		}
+	}
+	return false
+}
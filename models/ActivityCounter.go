@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ActivityEntityFormula identifies formula counters.
+	ActivityEntityFormula = "formula"
+	// ActivityEntityAromaChemical identifies aroma chemical counters.
+	ActivityEntityAromaChemical = "aroma_chemical"
+)
+
+// ActivityCounter tallies how often an entity was viewed, edited or produced
+// during a calendar month (Period, formatted as YYYY-MM).
+type ActivityCounter struct {
+	gorm.Model
+	EntityType     string    `gorm:"not null;uniqueIndex:idx_activity_entity_period" json:"entity_type"`
+	EntityID       uint      `gorm:"not null;uniqueIndex:idx_activity_entity_period" json:"entity_id"`
+	Period         string    `gorm:"not null;size:7;uniqueIndex:idx_activity_entity_period" json:"period"`
+	Views          int       `gorm:"not null;default:0" json:"views"`
+	Edits          int       `gorm:"not null;default:0" json:"edits"`
+	Batches        int       `gorm:"not null;default:0" json:"batches"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// Total returns the combined number of recorded interactions.
+func (c ActivityCounter) Total() int {
+	return c.Views + c.Edits + c.Batches
+}
@@ -1,14 +1,33 @@
 package models
 
 import (
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// OrganizationOwnerID owns the aroma chemicals transferred to the
+// organization rather than to a member, such as those of someone who left.
+// They are kept public so every member still sees them, and only
+// administrators can edit them.
+const OrganizationOwnerID uint = 0
+
 type AromaChemical struct {
 	gorm.Model
 	// IngredientName      string      `gorm:"uniqueIndex;not null" json:"ingredient_name"`
-	IngredientName      string      `gorm:"not null"`
-	CASNumber           string      `gorm:"index" json:"cas_number"`
+	IngredientName string `gorm:"not null"`
+	// CASNumber is the CAS registry number, stored as NULL when the material
+	// has none.
+	CASNumber string `gorm:"index;default:null" json:"cas_number"`
+	// SyntheticID labels a material without a CAS number, such as a mixture,
+	// eg. "MIXTURE-rose-absolute". Imports never match on it, so names that
+	// slug alike cannot merge different materials.
+	SyntheticID string `gorm:"size:160;index" json:"synthetic_id,omitempty"`
+	// INCIName is the name the material is declared under on cosmetic
+	// ingredient lists, such as "Linalool" or "Citrus Aurantium Dulcis Peel
+	// Oil". It is empty when unknown.
+	INCIName            string      `gorm:"size:200" json:"inci_name,omitempty"`
 	OtherNames          []OtherName `gorm:"foreignKey:AromaChemicalID" json:"other_names"`
 	Notes               string      `gorm:"type:text" json:"notes"`
 	WheelPosition       string      `json:"wheel_position"`
@@ -22,16 +41,64 @@ type AromaChemical struct {
 	Duration            string      `json:"duration"`
 	HistoricRole        string      `json:"historic_role"`
 	Popularity          int         `json:"popularity"`
+	UsagePopularity     int         `gorm:"not null;default:0" json:"usage_popularity"`
+	UsageUsers          int         `gorm:"not null;default:0" json:"usage_users"`
 	Usage               string      `gorm:"type:text" json:"usage"`
 	Solvent             bool        `gorm:"not null;default:false" json:"solvent"`
 	OwnerID             uint        `gorm:"not null" json:"owner_id"`
 	Owner               *User       `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
 	Public              bool        `gorm:"not null;default:false" json:"public"`
+	// Origin records whether the material is natural or synthetic and is
+	// empty when unknown. AnimalDerived is recorded separately because a
+	// natural may come from an animal.
+	Origin        string `gorm:"size:20" json:"origin,omitempty"`
+	AnimalDerived bool   `gorm:"not null;default:false" json:"animal_derived"`
+	// AIModel and AIFetchedAt record which model last generated the profile
+	// and when. Both are empty for ingredients never enriched by AI.
+	AIModel     string     `gorm:"size:120" json:"ai_model,omitempty"`
+	AIFetchedAt *time.Time `gorm:"index" json:"ai_fetched_at,omitempty"`
+	// StockSolutions holds pre-diluted inventory of this chemical. It is only
+	// populated when preloaded for a specific owner.
+	StockSolutions []InventoryItem `gorm:"foreignKey:AromaChemicalID" json:"-"`
+}
+
+// AIGenerated reports whether the profile was generated by AI.
+func (c AromaChemical) AIGenerated() bool {
+	return c.AIFetchedAt != nil
+}
+
+// DeprecatedAIModel reports whether the profile was generated by one of the
+// deprecated models, compared case-insensitively.
+func (c AromaChemical) DeprecatedAIModel(deprecated []string) bool {
+	model := strings.TrimSpace(c.AIModel)
+	if model == "" {
+		return false
+	}
+	for _, candidate := range deprecated {
+		if strings.EqualFold(strings.TrimSpace(candidate), model) {
+			return true
+		}
+	}
+	return false
+}
+
+// StaleAIProfile reports whether an AI-generated profile is due for a
+// refresh: it was fetched before cutoff or by a deprecated model.
+func (c AromaChemical) StaleAIProfile(cutoff time.Time, deprecated []string) bool {
+	if !c.AIGenerated() {
+		return false
+	}
+	return c.AIFetchedAt.Before(cutoff) || c.DeprecatedAIModel(deprecated)
 }
 
-// OtherName holds an alternative name for an AromaChemical.
+// OtherName holds an alternative name for an AromaChemical. Language is an
+// ISO 639-1 code for names in a specific language, such as the French INCI
+// name, and is empty for names used across languages. Kind is one of the
+// AliasKind constants.
 type OtherName struct {
 	gorm.Model
 	Name            string `gorm:"not null" json:"name"`
+	Language        string `gorm:"size:8;index" json:"language,omitempty"`
+	Kind            string `gorm:"size:20" json:"kind,omitempty"`
 	AromaChemicalID uint
 }
@@ -0,0 +1,56 @@
+package models
+
+import "gorm.io/gorm"
+
+// Audit actions recorded for the formula approval workflow.
+const (
+	AuditFormulaSubmitted = "formula.submitted"
+	AuditFormulaApproved  = "formula.approved"
+	AuditFormulaReturned  = "formula.returned"
+	AuditFormulaVersioned = "formula.versioned"
+)
+
+// Ownership transfer audit actions; the Summary names the previous and the
+// new owner.
+const (
+	AuditChemicalTransferred = "aroma_chemical.transferred"
+	AuditFormulaTransferred  = "formula.transferred"
+)
+
+// Impersonation audit actions. Their EntityType is AuditEntityUser, the
+// entity is the impersonated user and the actor the administrator.
+// AuditImpersonatedRequest records each change made while impersonating.
+const (
+	AuditEntityUser           = "user"
+	AuditImpersonationStarted = "impersonation.started"
+	AuditImpersonationEnded   = "impersonation.ended"
+	AuditImpersonatedRequest  = "impersonation.request"
+)
+
+// AuditRetentionPurged records a retention cleanup that removed rows; its
+// EntityType is AuditEntityRetention and its Summary lists what went.
+const (
+	AuditRetentionPurged = "retention.purged"
+	AuditEntityRetention = "retention"
+)
+
+// Publishing audit actions recorded when a member shares or withdraws one of
+// their aroma chemicals; their EntityType is ActivityEntityAromaChemical.
+const (
+	AuditChemicalPublished   = "aroma_chemical.published"
+	AuditChemicalUnpublished = "aroma_chemical.unpublished"
+)
+
+// AuditEntry records who did what to which record. Entries are append-only
+// until the retention job purges them; EntityType uses the same names as ActivityCounter.
+type AuditEntry struct {
+	gorm.Model
+	ActorID    *uint  `gorm:"index" json:"actor_id,omitempty"`
+	Action     string `gorm:"size:40;not null;index" json:"action"`
+	EntityType string `gorm:"size:40;not null;index:idx_audit_entity" json:"entity_type"`
+	EntityID   uint   `gorm:"not null;index:idx_audit_entity" json:"entity_id"`
+	Summary    string `gorm:"type:text" json:"summary"`
+	// ClientIP is the address the change was made from, behind any
+	// trusted proxy.
+	ClientIP string `gorm:"size:45" json:"client_ip,omitempty"`
+}
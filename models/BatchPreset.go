@@ -0,0 +1,18 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// MaxBatchPresets caps how many target quantities a user can keep.
+const MaxBatchPresets = 8
+
+// BatchPreset is a target quantity a user batches often, such as a 10 g
+// trial or a 1 kg production run, offered as a quick pick in the batch
+// report form.
+type BatchPreset struct {
+	gorm.Model
+	OwnerID    uint    `gorm:"not null;index" json:"owner_id"`
+	Label      string  `gorm:"size:60;not null" json:"label"`
+	QuantityMg float64 `gorm:"not null" json:"quantity_mg"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Blind test scores run from BlindScoreMin to BlindScoreMax.
+const (
+	BlindScoreMin = 1
+	BlindScoreMax = 10
+)
+
+// BlindTest is a smelling session in which evaluators score formulas known
+// only by sample codes. The mapping is hidden until the owner reveals it.
+type BlindTest struct {
+	gorm.Model
+	OwnerID    uint          `gorm:"not null;index" json:"owner_id"`
+	Name       string        `gorm:"size:255;not null" json:"name"`
+	RevealedAt *time.Time    `json:"revealed_at,omitempty"`
+	Samples    []BlindSample `gorm:"foreignKey:BlindTestID" json:"samples"`
+}
+
+// Revealed reports whether the codes have been mapped back to formulas.
+func (t BlindTest) Revealed() bool {
+	return t.RevealedAt != nil
+}
+
+// BlindSample is one formula in a blind test under its anonymous code.
+type BlindSample struct {
+	gorm.Model
+	BlindTestID uint         `gorm:"not null;uniqueIndex:idx_blind_sample_code" json:"blind_test_id"`
+	FormulaID   uint         `gorm:"not null" json:"formula_id"`
+	Formula     *Formula     `gorm:"foreignKey:FormulaID" json:"-"`
+	Code        string       `gorm:"size:8;not null;uniqueIndex:idx_blind_sample_code" json:"code"`
+	Scores      []BlindScore `gorm:"foreignKey:BlindSampleID" json:"scores"`
+}
+
+// BlindScore is one evaluator's score for a sample.
+type BlindScore struct {
+	gorm.Model
+	BlindSampleID uint   `gorm:"not null;index" json:"blind_sample_id"`
+	Evaluator     string `gorm:"size:80;not null" json:"evaluator"`
+	Score         int    `gorm:"not null" json:"score"`
+	Comment       string `gorm:"type:text" json:"comment,omitempty"`
+}
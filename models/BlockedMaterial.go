@@ -0,0 +1,100 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Blocklist levels. Prohibited materials cannot be saved in a formula;
+// restricted ones are saved with a warning.
+const (
+	BlockProhibited = "prohibited"
+	BlockRestricted = "restricted"
+)
+
+// BlockedMaterial is an entry on the administrator-maintained blocklist of
+// prohibited and restricted materials. It matches aroma chemicals by CAS
+// number and, for entries or chemicals without one, by name. Source records
+// the dataset an entry was imported from, or "manual".
+type BlockedMaterial struct {
+	gorm.Model
+	CASNumber string `gorm:"size:32;index" json:"cas_number"`
+	Name      string `gorm:"size:255" json:"name"`
+	Level     string `gorm:"size:20;not null;default:prohibited" json:"level"`
+	Reason    string `gorm:"type:text" json:"reason"`
+	Source    string `gorm:"size:120" json:"source"`
+}
+
+// Prohibited reports whether the entry blocks saving rather than warning.
+func (b BlockedMaterial) Prohibited() bool {
+	return b.Level != BlockRestricted
+}
+
+// Label names the entry as "Name (CAS)", using whichever parts are known.
+func (b BlockedMaterial) Label() string {
+	switch {
+	case b.Name != "" && b.CASNumber != "":
+		return b.Name + " (" + b.CASNumber + ")"
+	case b.Name != "":
+		return b.Name
+	default:
+		return b.CASNumber
+	}
+}
+
+// Blocklist is the set of entries checked when materials are used.
+type Blocklist []BlockedMaterial
+
+// Match returns the entry that applies to chemical, preferring a prohibited
+// entry when several match.
+func (l Blocklist) Match(chemical AromaChemical) (BlockedMaterial, bool) {
+	cas, names := materialKeys(chemical)
+
+	var found BlockedMaterial
+	matched := false
+	for _, entry := range l {
+		if !materialMatches(entry.CASNumber, entry.Name, cas, names) {
+			continue
+		}
+		if !matched || (entry.Prohibited() && !found.Prohibited()) {
+			found, matched = entry, true
+		}
+	}
+	return found, matched
+}
+
+// materialKeys returns the CAS number and every name a chemical is known by.
+func materialKeys(chemical AromaChemical) (string, []string) {
+	names := []string{chemical.IngredientName}
+	for _, other := range chemical.OtherNames {
+		names = append(names, other.Name)
+	}
+	return strings.TrimSpace(chemical.CASNumber), names
+}
+
+// MatchesMaterial reports whether a dataset entry naming a material by CAS
+// number and name refers to chemical.
+func MatchesMaterial(cas, name string, chemical AromaChemical) bool {
+	chemicalCAS, names := materialKeys(chemical)
+	return materialMatches(cas, name, chemicalCAS, names)
+}
+
+// materialMatches compares a dataset entry with a chemical's keys: by CAS
+// number when both have one, otherwise by name.
+func materialMatches(entryCAS, entryName, cas string, names []string) bool {
+	entryCAS = strings.TrimSpace(entryCAS)
+	if entryCAS != "" && cas != "" {
+		return strings.EqualFold(entryCAS, cas)
+	}
+	entryName = strings.TrimSpace(entryName)
+	if entryName == "" {
+		return false
+	}
+	for _, name := range names {
+		if strings.EqualFold(strings.TrimSpace(name), entryName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package models
+
+import "gorm.io/gorm"
+
+// Constituent records how much of an allergen a natural material contains,
+// such as the linalool in lavender oil, so allergen reports can count
+// contributions from essential oils. Materials are identified by CAS number
+// and name rather than by row, so the data applies to every user's copy.
+// Source records the dataset the row was imported from.
+type Constituent struct {
+	gorm.Model
+	MaterialCAS  string `gorm:"size:32;index" json:"material_cas"`
+	MaterialName string `gorm:"size:255" json:"material_name"`
+	Allergen     string `gorm:"size:255;not null" json:"allergen"`
+	AllergenCAS  string `gorm:"size:32" json:"allergen_cas"`
+	// Percentage is the allergen content of the material, w/w.
+	Percentage float64 `gorm:"not null" json:"percentage"`
+	Source     string  `gorm:"size:120;index" json:"source"`
+}
+
+// Constituents is the allergen composition data checked by allergen reports.
+type Constituents []Constituent
+
+// For returns the constituents recorded for chemical.
+func (c Constituents) For(chemical AromaChemical) []Constituent {
+	cas, names := materialKeys(chemical)
+	var found []Constituent
+	for _, constituent := range c {
+		if materialMatches(constituent.MaterialCAS, constituent.MaterialName, cas, names) {
+			found = append(found, constituent)
+		}
+	}
+	return found
+}
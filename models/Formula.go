@@ -1,9 +1,23 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Formula brief statuses, in workflow order.
+const (
+	FormulaStatusDraft    = "draft"
+	FormulaStatusInReview = "in-review"
+	FormulaStatusApproved = "approved"
+)
+
+// FormulaStatuses lists the brief statuses in workflow order.
+func FormulaStatuses() []string {
+	return []string{FormulaStatusDraft, FormulaStatusInReview, FormulaStatusApproved}
+}
+
 type Formula struct {
 	gorm.Model
 	Name            string              `gorm:"not null" json:"name"`
@@ -12,4 +26,71 @@ type Formula struct {
 	IsLatest        bool                `gorm:"not null;default:true" json:"is_latest"`
 	ParentFormulaID *uint               `json:"parent_formula_id"`
 	Ingredients     []FormulaIngredient `gorm:"foreignKey:FormulaID" json:"ingredients"`
+	// PercentEntry switches the editor to entering rows as percentages, which
+	// are normalised to 100% on save alongside a derived amount.
+	PercentEntry bool `gorm:"not null;default:false" json:"percent_entry"`
+	// UUID identifies the formula across instances so exported sub-formula
+	// references survive a round trip. Rows created before it existed get
+	// one on first export.
+	UUID string `gorm:"size:36;index" json:"uuid"`
+	// CreatedByID records who created the formula, for per-user quotas.
+	// Formulas created before it existed have none and are not counted.
+	CreatedByID *uint `gorm:"index" json:"created_by_id,omitempty"`
+
+	// The brief records who the formula is for and where it stands, so the
+	// library doubles as a project tracker.
+	Client         string     `gorm:"size:255" json:"client,omitempty"`
+	TargetAudience string     `gorm:"size:255" json:"target_audience,omitempty"`
+	Inspiration    string     `gorm:"type:text" json:"inspiration,omitempty"`
+	Deadline       *time.Time `json:"deadline,omitempty"`
+	Status         string     `gorm:"size:20;not null;default:draft;index" json:"status"`
+	// ApprovedAt and ApprovedByID record who signed off an approved revision.
+	ApprovedAt   *time.Time `json:"approved_at,omitempty"`
+	ApprovedByID *uint      `json:"approved_by_id,omitempty"`
+
+	// NaturalsOnly and Vegan constrain which materials the formula may use.
+	// Violations are flagged, not refused.
+	NaturalsOnly bool `gorm:"not null;default:false" json:"naturals_only"`
+	Vegan        bool `gorm:"not null;default:false" json:"vegan"`
+
+	// Published lists the formula in its creator's public library feed.
+	Published bool `gorm:"not null;default:false" json:"published"`
+
+	// Stock is worked out for the viewing user when the workspace loads; it
+	// is never stored.
+	Stock FormulaStock `gorm:"-" json:"-"`
+}
+
+// FormulaStock summarises how a formula's materials stand against the
+// viewing user's inventory. Materials counts the distinct materials used,
+// including through sub-formulas; Missing counts those with nothing on hand,
+// and Wishlisted those of them that are on the user's wishlist.
+type FormulaStock struct {
+	Materials  int
+	Missing    int
+	Wishlisted int
+}
+
+// Buildable reports whether every material is in stock.
+func (s FormulaStock) Buildable() bool {
+	return s.Materials > 0 && s.Missing == 0
+}
+
+// NotBuildableYet reports whether the formula waits on wishlisted materials.
+func (s FormulaStock) NotBuildableYet() bool {
+	return s.Wishlisted > 0
+}
+
+// BeforeCreate assigns a UUID to formulas created without one.
+func (f *Formula) BeforeCreate(tx *gorm.DB) error {
+	if f.UUID == "" {
+		f.UUID = NewUUID()
+	}
+	return nil
+}
+
+// Locked reports whether the formula is an approved revision. Locked
+// formulas cannot be edited; changes go into a new version instead.
+func (f Formula) Locked() bool {
+	return f.Status == FormulaStatusApproved
 }
@@ -9,16 +9,25 @@ type FormulaIngredient struct {
 	FormulaID uint    `gorm:"not null" json:"formula_id"` // Parent Formula
 	Amount    float64 `gorm:"not null" json:"amount"`
 	Unit      string  `gorm:"not null" json:"unit"`
+	// Percentage is the row's share of the formula when it is edited in
+	// percentage mode; zero otherwise.
+	Percentage float64 `gorm:"not null;default:0" json:"percentage,omitempty"`
+	// Position orders the rows within their formula, lowest first.
+	Position int `gorm:"not null;default:0;index" json:"position"`
 
 	// --- Ingredient Link ---
 	// One of these will be non-null, the other will be null.
 	AromaChemicalID *uint `json:"aroma_chemical_id,omitempty"`
 	SubFormulaID    *uint `json:"sub_formula_id,omitempty"`
+	// StockSolutionID optionally narrows an aroma chemical line to a
+	// pre-diluted inventory item; Amount is then the weight of the dilution.
+	StockSolutionID *uint `json:"stock_solution_id,omitempty"`
 
 	// --- Preloadable Data ---
 	// These allow GORM to fetch the ingredient's details.
 	// We use pointers so they can be null.
 	AromaChemical *AromaChemical `gorm:"foreignKey:AromaChemicalID" json:"aroma_chemical,omitempty"`
 	SubFormula    *Formula       `gorm:"foreignKey:SubFormulaID" json:"sub_formula,omitempty"`
+	StockSolution *InventoryItem `gorm:"foreignKey:StockSolutionID" json:"stock_solution,omitempty"`
 	Formula       *Formula       `gorm:"foreignKey:FormulaID" json:"formula,omitempty"`
 }
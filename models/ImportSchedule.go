@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Import run statuses.
+const (
+	ImportRunSucceeded = "succeeded"
+	ImportRunUnchanged = "unchanged"
+	ImportRunFailed    = "failed"
+)
+
+// ImportSchedule re-runs an aroma chemical import from Source, a local path or
+// https:// URL, whenever its five-field Cron expression comes due. Imported
+// chemicals are owned by OwnerID.
+type ImportSchedule struct {
+	gorm.Model
+	Name    string `gorm:"not null" json:"name"`
+	Source  string `gorm:"not null" json:"source"`
+	Cron    string `gorm:"not null" json:"cron"`
+	OwnerID uint   `gorm:"not null;index" json:"owner_id"`
+	Enabled bool   `gorm:"not null;default:true" json:"enabled"`
+	// LastChecksum is the SHA-256 of the last successfully imported source;
+	// a run whose download matches it is recorded as unchanged.
+	LastChecksum string      `json:"last_checksum"`
+	LastRunAt    *time.Time  `json:"last_run_at"`
+	NextRunAt    *time.Time  `gorm:"index" json:"next_run_at"`
+	Runs         []ImportRun `gorm:"foreignKey:ScheduleID" json:"runs,omitempty"`
+}
+
+// ImportRun is one execution of an ImportSchedule. Changes holds the JSON
+// list of created and updated records.
+type ImportRun struct {
+	gorm.Model
+	ScheduleID uint      `gorm:"not null;index" json:"schedule_id"`
+	StartedAt  time.Time `gorm:"not null" json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Status     string    `gorm:"not null" json:"status"`
+	Checksum   string    `json:"checksum"`
+	Created    int       `gorm:"not null;default:0" json:"created"`
+	Updated    int       `gorm:"not null;default:0" json:"updated"`
+	Skipped    int       `gorm:"not null;default:0" json:"skipped"`
+	Changes    string    `gorm:"type:text" json:"changes"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+}
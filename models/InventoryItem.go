@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExpiryWarningDays is how long before its expiry an opened material is
+// flagged as expiring.
+const ExpiryWarningDays = 30
+
+// LowStockMg is the neat quantity below which a stocked material is
+// reported as running low.
+const LowStockMg = 1000.0
+
+// Expiry states reported by InventoryItem.ExpiryState.
+const (
+	InventoryFresh    = ""
+	InventoryExpiring = "expiring"
+	InventoryExpired  = "expired"
+)
+
+// InventoryItem records how much of an aroma chemical a user has on hand and
+// where it is bought from. Items with a DilutionPercent below 100 are stock
+// solutions: QuantityMg is the weight of the dilution, of which only
+// DilutionPercent is the neat chemical and the rest is Solvent.
+//
+// Location is where the container is stored and LotNumber the supplier lot
+// it came from; an owner may hold several lots of the same material.
+//
+// A material opened on OpenedOn with a ShelfLifeMonths above zero expires
+// that many months later. ExpiryNotifiedAt records when its owner was
+// alerted, so each expiry is only announced once.
+type InventoryItem struct {
+	gorm.Model
+	OwnerID          uint           `gorm:"not null;index" json:"owner_id"`
+	AromaChemicalID  uint           `gorm:"not null;index" json:"aroma_chemical_id"`
+	AromaChemical    *AromaChemical `gorm:"foreignKey:AromaChemicalID" json:"aroma_chemical,omitempty"`
+	QuantityMg       float64        `gorm:"not null;default:0" json:"quantity_mg"`
+	Supplier         string         `json:"supplier"`
+	DilutionPercent  float64        `gorm:"not null;default:0" json:"dilution_percent"`
+	Solvent          string         `json:"solvent"`
+	Location         string         `gorm:"size:120" json:"location"`
+	LotNumber        string         `gorm:"size:80" json:"lot_number"`
+	ShelfLifeMonths  int            `gorm:"not null;default:0" json:"shelf_life_months"`
+	OpenedOn         *time.Time     `json:"opened_on,omitempty"`
+	ExpiryNotifiedAt *time.Time     `json:"-"`
+}
+
+// ExpiresOn returns when the opened material passes its shelf life. It
+// reports false when the opening date or shelf life is unknown.
+func (i InventoryItem) ExpiresOn() (time.Time, bool) {
+	if i.OpenedOn == nil || i.ShelfLifeMonths <= 0 {
+		return time.Time{}, false
+	}
+	return i.OpenedOn.AddDate(0, i.ShelfLifeMonths, 0), true
+}
+
+// ExpiryState classifies the item at now as fresh, expiring within
+// ExpiryWarningDays or expired.
+func (i InventoryItem) ExpiryState(now time.Time) string {
+	expires, ok := i.ExpiresOn()
+	switch {
+	case !ok:
+		return InventoryFresh
+	case !now.Before(expires):
+		return InventoryExpired
+	case now.AddDate(0, 0, ExpiryWarningDays).After(expires):
+		return InventoryExpiring
+	default:
+		return InventoryFresh
+	}
+}
+
+// Diluted reports whether the item is a stock solution rather than neat material.
+func (i InventoryItem) Diluted() bool {
+	return i.DilutionPercent > 0 && i.DilutionPercent < 100
+}
+
+// NeatFraction is the share of the item's weight that is the neat chemical.
+func (i InventoryItem) NeatFraction() float64 {
+	if !i.Diluted() {
+		return 1
+	}
+	return i.DilutionPercent / 100
+}
+
+// NeatQuantityMg converts the stocked quantity into its neat equivalent.
+func (i InventoryItem) NeatQuantityMg() float64 {
+	return i.QuantityMg * i.NeatFraction()
+}
+
+// LowStock reports whether less than LowStockMg of the neat material is
+// left.
+func (i InventoryItem) LowStock() bool {
+	return i.NeatQuantityMg() < LowStockMg
+}
+
+// DilutionLabel describes a stock solution such as "10% in DPG", or returns
+// an empty string for neat material.
+func (i InventoryItem) DilutionLabel() string {
+	if !i.Diluted() {
+		return ""
+	}
+	return strconv.FormatFloat(i.DilutionPercent, 'f', -1, 64) + "% in " + SolventByID(i.Solvent).ShortLabel
+}
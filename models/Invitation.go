@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation grants a single account registration while signup is invite-only.
+// Only the SHA-256 hash of the token is stored; the raw token is shown to the
+// inviting administrator once and travels in the invitation link.
+type Invitation struct {
+	gorm.Model
+	TokenHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	Email        string     `json:"email"`
+	InvitedByID  uint       `gorm:"not null;index" json:"invited_by_id"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt   *time.Time `json:"accepted_at"`
+	AcceptedByID *uint      `json:"accepted_by_id"`
+}
+
+// Usable reports whether the invitation can still be accepted at now.
+func (i Invitation) Usable(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Limits on the size of an organ.
+const (
+	MaxOrganRows      = 26
+	MaxOrganPositions = 60
+)
+
+// Organ is the physical layout of a user's perfumer's organ: rows of
+// positions, each holding one material. Every user has at most one.
+type Organ struct {
+	gorm.Model
+	OwnerID   uint        `gorm:"not null;uniqueIndex" json:"owner_id"`
+	Rows      int         `gorm:"not null" json:"rows"`
+	Positions int         `gorm:"not null" json:"positions"`
+	Slots     []OrganSlot `gorm:"foreignKey:OrganID" json:"slots"`
+}
+
+// OrganSlot places a material at a position in a row of an organ. Rows and
+// positions count from 1. A material occupies at most one slot. The row is
+// stored as row_number because ROW is reserved in SQL.
+type OrganSlot struct {
+	gorm.Model
+	OrganID         uint           `gorm:"not null;uniqueIndex:idx_organ_slot_position;uniqueIndex:idx_organ_slot_chemical" json:"organ_id"`
+	Row             int            `gorm:"column:row_number;not null;uniqueIndex:idx_organ_slot_position" json:"row"`
+	Position        int            `gorm:"not null;uniqueIndex:idx_organ_slot_position" json:"position"`
+	AromaChemicalID uint           `gorm:"not null;uniqueIndex:idx_organ_slot_chemical" json:"aroma_chemical_id"`
+	AromaChemical   *AromaChemical `gorm:"foreignKey:AromaChemicalID" json:"aroma_chemical,omitempty"`
+}
+
+// OrganSlotLabel names a slot the way it is marked on the organ: a row
+// letter followed by the position, eg. "C12".
+func OrganSlotLabel(row, position int) string {
+	if row < 1 || row > MaxOrganRows {
+		return fmt.Sprintf("%d-%d", row, position)
+	}
+	return fmt.Sprintf("%c%d", 'A'+rune(row-1), position)
+}
+
+// ParseOrganSlotLabel reads a label such as "c12" back into its row and
+// position.
+func ParseOrganSlotLabel(label string) (row, position int, ok bool) {
+	label = strings.ToUpper(strings.TrimSpace(label))
+	if len(label) < 2 || label[0] < 'A' || label[0] > 'Z' {
+		return 0, 0, false
+	}
+	position, err := strconv.Atoi(label[1:])
+	if err != nil || position < 1 {
+		return 0, 0, false
+	}
+	return int(label[0]-'A') + 1, position, true
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Production batch statuses.
+const (
+	ProductionBatchOpen      = "open"
+	ProductionBatchFinalized = "finalized"
+)
+
+// How the producing user signed a finalized batch: by re-entering their
+// password, or, for single sign-on accounts, by confirming a fresh session.
+const (
+	SignatureMethodPassword = "password"
+	SignatureMethodSession  = "session"
+)
+
+// Weighing tolerances used until a user saves their own.
+const (
+	DefaultWeighToleranceMg      = 5.0
+	DefaultWeighTolerancePercent = 2.0
+)
+
+// Kinds of BatchCheckpoint.
+const (
+	CheckpointMaceration = "maceration"
+	CheckpointStability  = "stability"
+)
+
+// MacerationCheckpointDays are the days after finalizing at which a batch
+// is smelled while it macerates, and StabilityCheckDays those at which its
+// retained sample is checked for colour, clarity and odour drift.
+var (
+	MacerationCheckpointDays = []int{7, 14, 28}
+	StabilityCheckDays       = []int{30, 90, 180}
+)
+
+// BatchCheckpoint is a check due on a finalized batch.
+type BatchCheckpoint struct {
+	Kind string
+	Day  int
+	Due  time.Time
+}
+
+// ProductionBatch is a batch weighed out from a formula, line by line. The
+// lines and tolerances are copied in when the batch is started so later
+// edits to the formula or to preferences do not change a batch in progress.
+// Quantities are in milligrams.
+type ProductionBatch struct {
+	gorm.Model
+	FormulaID      uint    `gorm:"not null;index" json:"formula_id"`
+	FormulaName    string  `gorm:"not null" json:"formula_name"`
+	FormulaVersion int     `json:"formula_version"`
+	OwnerID        uint    `gorm:"not null;index" json:"owner_id"`
+	LotNumber      string  `gorm:"not null" json:"lot_number"`
+	TargetQuantity float64 `json:"target_quantity"`
+	// ToleranceMg and TolerancePercent bound how far an actual weight may
+	// stray from its target; a line may use whichever allowance is larger.
+	ToleranceMg      float64               `gorm:"not null;default:0" json:"tolerance_mg"`
+	TolerancePercent float64               `gorm:"not null;default:0" json:"tolerance_percent"`
+	Status           string                `gorm:"not null;default:open" json:"status"`
+	FinalizedAt      *time.Time            `json:"finalized_at"`
+	Lines            []ProductionBatchLine `gorm:"foreignKey:BatchID" json:"lines,omitempty"`
+
+	// The electronic signature applied when the batch was finalized. The
+	// signer's name is copied so the record survives renames.
+	SignedByID      *uint      `json:"signed_by_id,omitempty"`
+	SignedByName    string     `json:"signed_by_name,omitempty"`
+	SignedAt        *time.Time `json:"signed_at,omitempty"`
+	SignatureMethod string     `gorm:"size:20" json:"signature_method,omitempty"`
+
+	// PricedAt is when the lines' prices were copied in, so the batch's
+	// cost can be shown as it stood then. It is nil for batches started
+	// before prices were kept.
+	PricedAt *time.Time `json:"priced_at,omitempty"`
+}
+
+// ProductionBatchLine is one weighing step. ActualQuantity stays nil until
+// the line has been weighed; Justification documents why an out-of-tolerance
+// weight was accepted.
+type ProductionBatchLine struct {
+	gorm.Model
+	BatchID         uint     `gorm:"not null;index" json:"batch_id"`
+	Position        int      `gorm:"not null" json:"position"`
+	AromaChemicalID *uint    `json:"aroma_chemical_id"`
+	IngredientName  string   `gorm:"not null" json:"ingredient_name"`
+	CASNumber       string   `json:"cas_number"`
+	Dilution        string   `json:"dilution"`
+	Solvent         bool     `gorm:"not null;default:false" json:"solvent"`
+	TargetQuantity  float64  `json:"target_quantity"`
+	ActualQuantity  *float64 `json:"actual_quantity"`
+	Justification   string   `gorm:"type:text" json:"justification"`
+	// NeatQuantity is the neat chemical in TargetQuantity and PricePerMg its
+	// price when the batch was started.
+	NeatQuantity float64 `gorm:"not null;default:0" json:"neat_quantity"`
+	PricePerMg   float64 `gorm:"not null;default:0" json:"price_per_mg"`
+}
+
+// Finalized reports whether the batch has been closed.
+func (b ProductionBatch) Finalized() bool {
+	return b.Status == ProductionBatchFinalized
+}
+
+// Checkpoints lists the maceration and stability checks due on the batch,
+// in date order. Open batches have none.
+func (b ProductionBatch) Checkpoints() []BatchCheckpoint {
+	if !b.Finalized() || b.FinalizedAt == nil {
+		return nil
+	}
+	var checkpoints []BatchCheckpoint
+	for _, day := range MacerationCheckpointDays {
+		checkpoints = append(checkpoints, BatchCheckpoint{Kind: CheckpointMaceration, Day: day, Due: b.FinalizedAt.AddDate(0, 0, day)})
+	}
+	for _, day := range StabilityCheckDays {
+		checkpoints = append(checkpoints, BatchCheckpoint{Kind: CheckpointStability, Day: day, Due: b.FinalizedAt.AddDate(0, 0, day)})
+	}
+	sort.SliceStable(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Due.Before(checkpoints[j].Due)
+	})
+	return checkpoints
+}
+
+// Signed reports whether the batch carries an electronic signature.
+func (b ProductionBatch) Signed() bool {
+	return b.SignedAt != nil && b.SignedByName != ""
+}
+
+// Allowance returns the largest deviation accepted for a target quantity.
+func (b ProductionBatch) Allowance(target float64) float64 {
+	return math.Max(b.ToleranceMg, math.Abs(target)*b.TolerancePercent/100)
+}
+
+// OutOfTolerance reports whether a weighed line strays further from its
+// target than the batch allows. Lines not yet weighed are never flagged.
+func (b ProductionBatch) OutOfTolerance(line ProductionBatchLine) bool {
+	if !line.Weighed() {
+		return false
+	}
+	// Allow for float noise so a weight exactly on the limit passes.
+	return math.Abs(line.Deviation()) > b.Allowance(line.TargetQuantity)+1e-9
+}
+
+// FinalizeBlockers lists what stops the batch from being finalized: lines
+// that have not been weighed and out-of-tolerance lines without a
+// justification. An empty result means the batch can be closed.
+func (b ProductionBatch) FinalizeBlockers() []string {
+	var blockers []string
+	for _, line := range b.Lines {
+		switch {
+		case !line.Weighed():
+			blockers = append(blockers, fmt.Sprintf("Line %d (%s) has not been weighed.", line.Position, line.IngredientName))
+		case b.OutOfTolerance(line) && line.Justification == "":
+			blockers = append(blockers, fmt.Sprintf("Line %d (%s) is out of tolerance and needs a justification.", line.Position, line.IngredientName))
+		}
+	}
+	return blockers
+}
+
+// Weighed reports whether an actual weight has been recorded.
+func (l ProductionBatchLine) Weighed() bool {
+	return l.ActualQuantity != nil
+}
+
+// Deviation is the actual weight minus the target, or 0 when not weighed.
+func (l ProductionBatchLine) Deviation() float64 {
+	if l.ActualQuantity == nil {
+		return 0
+	}
+	return *l.ActualQuantity - l.TargetQuantity
+}
+
+// DeviationPercent expresses Deviation relative to the target.
+func (l ProductionBatchLine) DeviationPercent() float64 {
+	if l.TargetQuantity == 0 {
+		return 0
+	}
+	return l.Deviation() / l.TargetQuantity * 100
+}
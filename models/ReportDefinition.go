@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Sources a custom report can list.
+const (
+	ReportSourceIngredients = "ingredients"
+	ReportSourceFormulas    = "formulas"
+	ReportSourceBatches     = "batches"
+)
+
+// Filter operators. Greater and less than only apply to numeric columns.
+const (
+	ReportFilterContains = "contains"
+	ReportFilterEquals   = "equals"
+	ReportFilterGreater  = "gt"
+	ReportFilterLess     = "lt"
+)
+
+// MaxReportDefinitions caps how many custom reports a user can keep.
+const MaxReportDefinitions = 20
+
+// ReportDefinition is a custom tabular report saved by a user. Columns holds
+// the comma-separated column keys in display order and Filters the JSON list
+// of conditions every row must meet.
+type ReportDefinition struct {
+	gorm.Model
+	OwnerID uint   `gorm:"not null;index" json:"owner_id"`
+	Name    string `gorm:"size:120;not null" json:"name"`
+	Source  string `gorm:"size:20;not null" json:"source"`
+	Columns string `gorm:"type:text;not null" json:"columns"`
+	Filters string `gorm:"type:text" json:"filters"`
+}
+
+// ReportFilter keeps the rows whose Column matches Value under Operator.
+type ReportFilter struct {
+	Column   string `json:"column"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// ColumnKeys returns the report's column keys in display order.
+func (d ReportDefinition) ColumnKeys() []string {
+	var keys []string
+	for _, key := range strings.Split(d.Columns, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// FilterList decodes the stored filters. A missing or unreadable list
+// yields no filters.
+func (d ReportDefinition) FilterList() []ReportFilter {
+	if strings.TrimSpace(d.Filters) == "" {
+		return nil
+	}
+	var filters []ReportFilter
+	if err := json.Unmarshal([]byte(d.Filters), &filters); err != nil {
+		return nil
+	}
+	return filters
+}
+
+// SetFilters stores the filters as JSON, clearing them when empty.
+func (d *ReportDefinition) SetFilters(filters []ReportFilter) error {
+	if len(filters) == 0 {
+		d.Filters = ""
+		return nil
+	}
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return err
+	}
+	d.Filters = string(encoded)
+	return nil
+}
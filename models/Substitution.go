@@ -0,0 +1,19 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Substitution records that a material from the owner's library can stand in
+// for another material, identified by name because the replaced material is
+// usually one the owner does not have. Ratio is the amount of substitute used
+// per unit of the original.
+type Substitution struct {
+	gorm.Model
+	OwnerID      uint           `gorm:"not null;index" json:"owner_id"`
+	OriginalName string         `gorm:"not null" json:"original_name"`
+	SubstituteID uint           `gorm:"not null;index" json:"substitute_id"`
+	Substitute   *AromaChemical `gorm:"foreignKey:SubstituteID" json:"substitute,omitempty"`
+	Ratio        float64        `gorm:"not null;default:1" json:"ratio"`
+	Notes        string         `gorm:"type:text" json:"notes"`
+}
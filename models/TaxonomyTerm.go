@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TaxonomyWheel is the vocabulary of olfactive wheel families used for
+// AromaChemical.WheelPosition.
+const TaxonomyWheel = "wheel"
+
+// Taxonomy term statuses. Approved terms form the shared vocabulary;
+// suggested ones wait in the administrators' review queue.
+const (
+	TermApproved  = "approved"
+	TermSuggested = "suggested"
+	TermRejected  = "rejected"
+)
+
+// maxTermLength caps a taxonomy term.
+const maxTermLength = 80
+
+// TaxonomyTerm is a canonical term of a shared vocabulary, or a member's
+// suggestion for one. While a vocabulary has approved terms, members must
+// pick from them.
+type TaxonomyTerm struct {
+	gorm.Model
+	Vocabulary    string `gorm:"size:40;not null;index" json:"vocabulary"`
+	Name          string `gorm:"size:80;not null" json:"name"`
+	Status        string `gorm:"size:20;not null;default:approved;index" json:"status"`
+	Reason        string `gorm:"size:255" json:"reason,omitempty"`
+	SuggestedByID *uint  `gorm:"index" json:"suggested_by_id,omitempty"`
+}
+
+// ValidVocabulary reports whether vocabulary names a governed vocabulary.
+func ValidVocabulary(vocabulary string) bool {
+	return vocabulary == TaxonomyWheel
+}
+
+// NormalizeTerm collapses runs of whitespace in a term and reports whether
+// the result is a usable term.
+func NormalizeTerm(name string) (string, bool) {
+	name = strings.Join(strings.Fields(name), " ")
+	return name, name != "" && len(name) <= maxTermLength
+}
+
+// CanonicalTerm returns the approved term matching value, ignoring case,
+// accents and spacing.
+func CanonicalTerm(terms []string, value string) (string, bool) {
+	key := FoldName(strings.Join(strings.Fields(value), " "))
+	if key == "" {
+		return "", false
+	}
+	for _, term := range terms {
+		if FoldName(term) == key {
+			return term, true
+		}
+	}
+	return "", false
+}
@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 const (
 	// ThemeNocturne represents the dark studio palette.
@@ -31,6 +35,21 @@ func NormalizeTheme(value string) string {
 	return DefaultTheme
 }
 
+const (
+	// RoleMember is granted to every registered account.
+	RoleMember = "member"
+	// RoleAdmin unlocks instance-wide controls such as maintenance mode.
+	RoleAdmin = "admin"
+)
+
+// NormalizeRole coerces a stored role to a supported value, falling back to member.
+func NormalizeRole(value string) string {
+	if value == RoleAdmin {
+		return RoleAdmin
+	}
+	return RoleMember
+}
+
 // User represents an application account that can authenticate with the platform.
 type User struct {
 	gorm.Model
@@ -39,4 +58,58 @@ type User struct {
 	PasswordHash string `gorm:"not null"`
 	Name         string
 	Theme        string `gorm:"not null;default:nocturne"`
+	Role         string `gorm:"not null;default:member"`
+	// DefaultSolvent and TargetConcentration describe how the user usually
+	// finishes a concentrate; TargetConcentration is the concentrate share
+	// of the finished product in percent, with 0 meaning "not set".
+	DefaultSolvent      string  `gorm:"not null;default:ethanol"`
+	TargetConcentration float64 `gorm:"not null;default:0"`
+	// WeighToleranceMg and WeighTolerancePercent are copied onto each
+	// production batch the user starts.
+	WeighToleranceMg      float64 `gorm:"not null;default:5"`
+	WeighTolerancePercent float64 `gorm:"not null;default:2"`
+	// The PrintHide flags drop columns from the formula print view, which
+	// is often shared with manufacturers; everything is shown by default.
+	PrintHideCAS      bool   `gorm:"not null;default:false"`
+	PrintHideCost     bool   `gorm:"not null;default:false"`
+	PrintHideSupplier bool   `gorm:"not null;default:false"`
+	PrintHideNotes    bool   `gorm:"not null;default:false"`
+	OIDCSubject       string `gorm:"column:oidc_subject;index"`
+	ExternalID        string `gorm:"index"`
+	// DeactivatedAt is set when the account has been deprovisioned; such
+	// users can no longer sign in.
+	DeactivatedAt *time.Time
+	// AvatarKey locates the user's cropped avatar in object storage; it is
+	// empty until one is uploaded.
+	AvatarKey string
+	// AmountDecimals, PercentDecimals and PriceDecimals set how many
+	// decimals amounts, percentages and unit prices are shown with.
+	AmountDecimals  int `gorm:"not null;default:2"`
+	PercentDecimals int `gorm:"not null;default:2"`
+	PriceDecimals   int `gorm:"not null;default:4"`
+	// FeedTokenHash is the SHA-256 hash of the token that unlocks the
+	// user's public library feed; the feed is off while it is empty.
+	FeedTokenHash string `gorm:"size:64;index" json:"-"`
+	// CalendarTokenHash likewise unlocks the user's iCalendar feed.
+	CalendarTokenHash string `gorm:"size:64;index" json:"-"`
+	// KeyboardShortcuts holds the user's shortcut overrides as a JSON
+	// object of action to key; see ShortcutBindings.
+	KeyboardShortcuts string `gorm:"type:text" json:"keyboard_shortcuts"`
+	// LandingSection is the workspace section /app opens with; empty means
+	// the default section.
+	LandingSection string `gorm:"size:20"`
+	// WeeklyDigest opts the user in to the weekly summary email;
+	// DigestSentAt records when the last one went out.
+	WeeklyDigest bool       `gorm:"not null;default:false"`
+	DigestSentAt *time.Time `json:"-"`
+}
+
+// IsActive reports whether the account may sign in.
+func (u User) IsActive() bool {
+	return u.DeactivatedAt == nil
+}
+
+// IsAdmin reports whether the user holds the administrator role.
+func (u User) IsAdmin() bool {
+	return NormalizeRole(u.Role) == RoleAdmin
 }
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// UserIngredientNote holds one user's private annotations on an aroma
+// chemical. It is kept apart from the chemical so users can annotate shared
+// records they do not own; each user has at most one note per chemical.
+type UserIngredientNote struct {
+	gorm.Model
+	UserID          uint   `gorm:"not null;uniqueIndex:idx_user_ingredient_note" json:"user_id"`
+	AromaChemicalID uint   `gorm:"not null;uniqueIndex:idx_user_ingredient_note" json:"aroma_chemical_id"`
+	Perception      string `gorm:"type:text" json:"perception"`
+	DilutionHabit   string `gorm:"size:255" json:"dilution_habit"`
+}
+
+// Blank reports whether the note has nothing worth keeping.
+func (n UserIngredientNote) Blank() bool {
+	return strings.TrimSpace(n.Perception) == "" && strings.TrimSpace(n.DilutionHabit) == ""
+}
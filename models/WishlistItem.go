@@ -0,0 +1,17 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// WishlistItem is a material a user plans to buy. It is kept apart from
+// InventoryItem so planned purchases never count as stock; each user lists a
+// material at most once.
+type WishlistItem struct {
+	gorm.Model
+	OwnerID         uint           `gorm:"not null;uniqueIndex:idx_wishlist_owner_chemical" json:"owner_id"`
+	AromaChemicalID uint           `gorm:"not null;uniqueIndex:idx_wishlist_owner_chemical" json:"aroma_chemical_id"`
+	AromaChemical   *AromaChemical `gorm:"foreignKey:AromaChemicalID" json:"aroma_chemical,omitempty"`
+	Supplier        string         `gorm:"size:120" json:"supplier"`
+	Note            string         `gorm:"size:255" json:"note"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import "strings"
+
+// Labelling thresholds for fragrance allergens, as a percentage of the
+// finished product. Above them the allergen must be declared.
+const (
+	AllergenLeaveOnThreshold  = 0.001
+	AllergenRinseOffThreshold = 0.01
+)
+
+// Allergen is a fragrance allergen that must be declared on labels above
+// the thresholds.
+type Allergen struct {
+	Name      string
+	CASNumber string
+}
+
+// Allergens lists the 26 fragrance allergens of the EU Cosmetics Regulation.
+var Allergens = []Allergen{
+	{"Amyl cinnamal", "122-40-7"},
+	{"Amylcinnamyl alcohol", "101-85-9"},
+	{"Anise alcohol", "105-13-5"},
+	{"Benzyl alcohol", "100-51-6"},
+	{"Benzyl benzoate", "120-51-4"},
+	{"Benzyl cinnamate", "103-41-3"},
+	{"Benzyl salicylate", "118-58-1"},
+	{"Butylphenyl methylpropional", "80-54-6"},
+	{"Cinnamal", "104-55-2"},
+	{"Cinnamyl alcohol", "104-54-1"},
+	{"Citral", "5392-40-5"},
+	{"Citronellol", "106-22-9"},
+	{"Coumarin", "91-64-5"},
+	{"Eugenol", "97-53-0"},
+	{"Evernia furfuracea extract", "90028-67-4"},
+	{"Evernia prunastri extract", "90028-68-5"},
+	{"Farnesol", "4602-84-0"},
+	{"Geraniol", "106-24-1"},
+	{"Hexyl cinnamal", "101-86-0"},
+	{"Hydroxycitronellal", "107-75-5"},
+	{"Hydroxyisohexyl 3-cyclohexene carboxaldehyde", "31906-04-4"},
+	{"Isoeugenol", "97-54-1"},
+	{"Limonene", "5989-27-5"},
+	{"Linalool", "78-70-6"},
+	{"Alpha-isomethyl ionone", "127-51-5"},
+	{"Methyl 2-octynoate", "111-12-6"},
+}
+
+// FindAllergen returns the regulated allergen with the given CAS number or,
+// failing that, name.
+func FindAllergen(cas, name string) (Allergen, bool) {
+	cas, name = strings.TrimSpace(cas), strings.TrimSpace(name)
+	for _, allergen := range Allergens {
+		if cas != "" && allergen.CASNumber == cas {
+			return allergen, true
+		}
+	}
+	for _, allergen := range Allergens {
+		if name != "" && strings.EqualFold(allergen.Name, name) {
+			return allergen, true
+		}
+	}
+	return Allergen{}, false
+}
+
+// AllergenOf returns the allergen a pure chemical is, if any.
+func AllergenOf(chemical AromaChemical) (Allergen, bool) {
+	cas, names := materialKeys(chemical)
+	if allergen, ok := FindAllergen(cas, ""); ok {
+		return allergen, true
+	}
+	if cas != "" {
+		return Allergen{}, false
+	}
+	for _, name := range names {
+		if allergen, ok := FindAllergen("", name); ok {
+			return allergen, true
+		}
+	}
+	return Allergen{}, false
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestConstituentsAndAllergens(t *testing.T) {
+	t.Parallel()
+
+	data := Constituents{
+		{MaterialCAS: "8000-28-0", MaterialName: "Lavender oil", Allergen: "Linalool", Percentage: 35},
+		{MaterialName: "Lavender oil", Allergen: "Limonene", Percentage: 0.5},
+		{MaterialCAS: "8008-56-8", MaterialName: "Lemon oil", Allergen: "Limonene", Percentage: 65},
+	}
+
+	if got := len(data.For(AromaChemical{IngredientName: "Lavandula angustifolia", CASNumber: "8000-28-0"})); got != 1 {
+		t.Fatalf("CAS match: got %d constituents, want 1", got)
+	}
+	if got := len(data.For(AromaChemical{IngredientName: "Lavender", OtherNames: []OtherName{{Name: "lavender oil"}}})); got != 2 {
+		t.Fatalf("name match: got %d constituents, want 2", got)
+	}
+
+	if allergen, ok := AllergenOf(AromaChemical{IngredientName: "Linalol", CASNumber: "78-70-6"}); !ok || allergen.Name != "Linalool" {
+		t.Fatalf("AllergenOf by CAS = %+v, %v", allergen, ok)
+	}
+	if _, ok := AllergenOf(AromaChemical{IngredientName: "Linalool", CASNumber: "126-91-0"}); ok {
+		t.Fatal("a different CAS number must not match on name")
+	}
+	if allergen, ok := AllergenOf(AromaChemical{IngredientName: "coumarin"}); !ok || allergen.CASNumber != "91-64-5" {
+		t.Fatalf("AllergenOf by name = %+v, %v", allergen, ok)
+	}
+}
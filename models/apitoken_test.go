@@ -0,0 +1,43 @@
+package models
+
+import "testing"
+
+func TestNormalizeScopes(t *testing.T) {
+	t.Parallel()
+
+	if got, ok := NormalizeScopes([]string{"Reports", " read ", "reports", ""}); !ok || got != "read reports" {
+		t.Fatalf("NormalizeScopes = %q, %v", got, ok)
+	}
+	if got, ok := NormalizeScopes(nil); !ok || got != "" {
+		t.Fatalf("expected no scopes for full access, got %q, %v", got, ok)
+	}
+	if _, ok := NormalizeScopes([]string{"admin"}); ok {
+		t.Fatalf("expected an unknown scope to be refused")
+	}
+}
+
+func TestAPITokenAllows(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		scopes   string
+		resource string
+		write    bool
+		want     bool
+	}{
+		{"", APIResourceFormulas, true, true},
+		{"read", ScopeIngredients, false, true},
+		{"read", ScopeIngredients, true, false},
+		{"ingredients", ScopeIngredients, true, true},
+		{"ingredients", ScopeReports, false, false},
+		{"ingredients reports", ScopeReports, false, true},
+		{"read reports", ScopeReports, false, true},
+		{"read reports", APIResourceFormulas, false, false},
+	}
+	for _, tc := range cases {
+		token := APIToken{Scopes: tc.scopes}
+		if got := token.Allows(tc.resource, tc.write); got != tc.want {
+			t.Errorf("APIToken{Scopes: %q}.Allows(%q, %v) = %v, want %v", tc.scopes, tc.resource, tc.write, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAromaChemicalStaleAIProfile(t *testing.T) {
+	cutoff := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := cutoff.AddDate(0, -1, 0)
+	recent := cutoff.AddDate(0, 1, 0)
+	deprecated := []string{" GPT-3.5-Turbo "}
+
+	tests := []struct {
+		name     string
+		chemical AromaChemical
+		want     bool
+	}{
+		{name: "entered by hand", chemical: AromaChemical{}, want: false},
+		{name: "fetched before cutoff", chemical: AromaChemical{AIModel: "gpt-4.1-mini", AIFetchedAt: &old}, want: true},
+		{name: "recent", chemical: AromaChemical{AIModel: "gpt-4.1-mini", AIFetchedAt: &recent}, want: false},
+		{name: "recent but deprecated", chemical: AromaChemical{AIModel: "gpt-3.5-turbo", AIFetchedAt: &recent}, want: true},
+		{name: "model only", chemical: AromaChemical{AIModel: "gpt-3.5-turbo"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.chemical.StaleAIProfile(cutoff, deprecated); got != tt.want {
+			t.Errorf("%s: StaleAIProfile = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestBlocklistMatch(t *testing.T) {
+	t.Parallel()
+
+	list := Blocklist{
+		{CASNumber: "90-02-8", Name: "Salicylaldehyde", Level: BlockRestricted},
+		{CASNumber: "90-02-8", Name: "Salicylic aldehyde", Level: BlockProhibited},
+		{Name: "Musk ambrette", Level: BlockProhibited},
+		{CASNumber: "81-14-1", Name: "Musk ketone", Level: BlockRestricted},
+	}
+
+	cases := []struct {
+		name     string
+		chemical AromaChemical
+		want     string
+	}{
+		{"prohibited entry wins on CAS", AromaChemical{IngredientName: "Anything", CASNumber: " 90-02-8 "}, "Salicylic aldehyde"},
+		{"name-only entry", AromaChemical{IngredientName: "musk ambrette"}, "Musk ambrette"},
+		{"matches another name", AromaChemical{IngredientName: "MA", OtherNames: []OtherName{{Name: "Musk Ambrette"}}}, "Musk ambrette"},
+		{"CAS disagreement is not a match", AromaChemical{IngredientName: "Musk ketone", CASNumber: "0000-00-0"}, ""},
+		{"name used without CAS", AromaChemical{IngredientName: "Musk ketone"}, "Musk ketone"},
+		{"unlisted", AromaChemical{IngredientName: "Hedione", CASNumber: "24851-98-7"}, ""},
+	}
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			entry, ok := list.Match(tt.chemical)
+			if ok != (tt.want != "") || entry.Name != tt.want {
+				t.Fatalf("Match() = %+v, %t; want %q", entry, ok, tt.want)
+			}
+		})
+	}
+}
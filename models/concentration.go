@@ -0,0 +1,52 @@
+package models
+
+import "strings"
+
+// ConcentrateDensity approximates the density of a fragrance concentrate in
+// g/mL. It is used to convert volume-based dilutions into weighed quantities.
+const ConcentrateDensity = 1.0
+
+// ConcentrationPreset is a named finished-product strength, expressed as the
+// percentage of concentrate in the final product.
+type ConcentrationPreset struct {
+	ID      string
+	Label   string
+	Percent float64
+}
+
+// ConcentrationPresets lists the finished-product strengths offered on the
+// batch report form, from lightest to strongest.
+var ConcentrationPresets = []ConcentrationPreset{
+	{ID: "edc", Label: "Eau de Cologne", Percent: 5},
+	{ID: "edt", Label: "Eau de Toilette", Percent: 12},
+	{ID: "edp", Label: "Eau de Parfum", Percent: 18},
+	{ID: "extrait", Label: "Extrait de Parfum", Percent: 25},
+}
+
+// ConcentrationPresetByID resolves a preset identifier.
+func ConcentrationPresetByID(id string) (ConcentrationPreset, bool) {
+	key := strings.ToLower(strings.TrimSpace(id))
+	for _, preset := range ConcentrationPresets {
+		if preset.ID == key {
+			return preset, true
+		}
+	}
+	return ConcentrationPreset{}, false
+}
+
+// ConcentrateMassFraction converts a concentrate percentage into the share of
+// the finished product's weight taken by the concentrate. Percentages by
+// weight are returned unchanged; percentages by volume are converted using
+// the concentrate and solvent densities.
+func ConcentrateMassFraction(percent float64, byVolume bool, solvent Solvent) float64 {
+	fraction := percent / 100.0
+	if !byVolume || solvent.Density <= 0 {
+		return fraction
+	}
+	concentrate := fraction * ConcentrateDensity
+	carrier := (1 - fraction) * solvent.Density
+	if concentrate+carrier <= 0 {
+		return 0
+	}
+	return concentrate / (concentrate + carrier)
+}
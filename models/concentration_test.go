@@ -0,0 +1,41 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConcentrateMassFraction(t *testing.T) {
+	t.Parallel()
+
+	ethanol := SolventByID(SolventEthanol)
+	tests := []struct {
+		name     string
+		percent  float64
+		byVolume bool
+		solvent  Solvent
+		want     float64
+	}{
+		{"by weight", 18, false, ethanol, 0.18},
+		{"by volume in ethanol", 18, true, ethanol, 0.18 / (0.18 + 0.82*0.789)},
+		{"by volume in dpg", 25, true, SolventByID(SolventDPG), 0.25 / (0.25 + 0.75*1.023)},
+		{"unknown density", 12, true, Solvent{}, 0.12},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := ConcentrateMassFraction(tt.percent, tt.byVolume, tt.solvent); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("ConcentrateMassFraction(%v, %t) = %.6f, want %.6f", tt.percent, tt.byVolume, got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := ConcentrationPresetByID(" EDP "); !ok {
+		t.Fatal("expected EDP preset to resolve")
+	}
+	if _, ok := ConcentrationPresetByID("splash"); ok {
+		t.Fatal("expected unknown preset to be rejected")
+	}
+}
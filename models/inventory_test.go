@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInventoryItemExpiryState(t *testing.T) {
+	t.Parallel()
+
+	opened := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	item := InventoryItem{OpenedOn: &opened, ShelfLifeMonths: 6}
+
+	expires, ok := item.ExpiresOn()
+	if !ok || !expires.Equal(time.Date(2026, 7, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ExpiresOn() = %v, %t", expires, ok)
+	}
+
+	cases := []struct {
+		name string
+		item InventoryItem
+		now  time.Time
+		want string
+	}{
+		{"no opening date", InventoryItem{ShelfLifeMonths: 6}, opened, InventoryFresh},
+		{"no shelf life", InventoryItem{OpenedOn: &opened}, opened.AddDate(5, 0, 0), InventoryFresh},
+		{"well within shelf life", item, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), InventoryFresh},
+		{"inside the warning window", item, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), InventoryExpiring},
+		{"on the expiry date", item, expires, InventoryExpired},
+	}
+
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.item.ExpiryState(tt.now); got != tt.want {
+				t.Fatalf("ExpiryState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Alias kinds. An empty kind is a plain synonym.
+const (
+	AliasKindSynonym     = ""
+	AliasKindTrade       = "trade"
+	AliasKindINCI        = "inci"
+	AliasKindTranslation = "translation"
+)
+
+// AliasKinds lists the recognised alias kinds.
+func AliasKinds() []string {
+	return []string{AliasKindSynonym, AliasKindTrade, AliasKindINCI, AliasKindTranslation}
+}
+
+// ValidAliasKind reports whether kind is a recognised alias kind.
+func ValidAliasKind(kind string) bool {
+	for _, known := range AliasKinds() {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLanguageCode returns code as a lowercase ISO 639-1 code, keeping
+// only the language of tags such as "fr-FR". It returns "" when code is not
+// a two-letter code.
+func NormalizeLanguageCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	if len(code) != 2 || code[0] < 'a' || code[0] > 'z' || code[1] < 'a' || code[1] > 'z' {
+		return ""
+	}
+	return code
+}
+
+// Label describes the alias for display, such as "fr, INCI".
+func (o OtherName) Label() string {
+	var parts []string
+	if o.Language != "" {
+		parts = append(parts, o.Language)
+	}
+	switch o.Kind {
+	case AliasKindINCI:
+		parts = append(parts, "INCI")
+	case AliasKindTrade, AliasKindTranslation:
+		parts = append(parts, o.Kind)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// foldedRunes spells out letters that do not lose their accent by dropping
+// a combining mark.
+var foldedRunes = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ł': "l", 'ı': "i",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c", 'ĉ': "c", 'ċ': "c",
+	'ď': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i",
+	'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ō': "o", 'ő': "o",
+	'ř': "r",
+	'ś': "s", 'š': "s", 'ş': "s", 'ș': "s",
+	'ť': "t", 'ţ': "t", 'ț': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// FoldName lowercases name and strips the accents of Latin letters, so that
+// "Dihydrojasmonate de Méthyle" and "dihydrojasmonate de methyle" compare
+// equal. Combining marks are dropped; other characters are kept.
+func FoldName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for _, r := range strings.ToLower(name) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if folded, ok := foldedRunes[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
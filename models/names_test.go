@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestFoldName(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"Dihydrojasmonate de Méthyle": "dihydrojasmonate de methyle",
+		"Methyldihydrojasmonat":       "methyldihydrojasmonat",
+		"Öl der Süßen Orange":         "ol der sussen orange",
+		"Cœur":                        "coeur",
+		"Me\u0301thyle":               "methyle",
+		"OTNE":                        "otne",
+	}
+	for in, want := range cases {
+		if got := FoldName(in); got != want {
+			t.Errorf("FoldName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeLanguageCode(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{"FR": "fr", " de-DE ": "de", "pt_BR": "pt", "french": "", "": "", "f1": ""}
+	for in, want := range cases {
+		if got := NormalizeLanguageCode(in); got != want {
+			t.Errorf("NormalizeLanguageCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
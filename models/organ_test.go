@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestOrganSlotLabels(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		label    string
+		row, pos int
+		ok       bool
+	}{
+		{"A1", 1, 1, true},
+		{" c12 ", 3, 12, true},
+		{"Z60", 26, 60, true},
+		{"A0", 0, 0, false},
+		{"12", 0, 0, false},
+		{"B", 0, 0, false},
+		{"Bx", 0, 0, false},
+	}
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.label, func(t *testing.T) {
+			t.Parallel()
+			row, pos, ok := ParseOrganSlotLabel(tt.label)
+			if row != tt.row || pos != tt.pos || ok != tt.ok {
+				t.Fatalf("ParseOrganSlotLabel(%q) = %d, %d, %v", tt.label, row, pos, ok)
+			}
+		})
+	}
+	if got := OrganSlotLabel(3, 12); got != "C12" {
+		t.Fatalf("OrganSlotLabel(3, 12) = %q", got)
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+// Material origins. An empty origin means it is not known.
+const (
+	OriginNatural   = "natural"
+	OriginSynthetic = "synthetic"
+)
+
+// Origins lists the recognised material origins.
+func Origins() []string {
+	return []string{OriginNatural, OriginSynthetic}
+}
+
+// ValidOrigin reports whether origin is empty or a recognised origin.
+func ValidOrigin(origin string) bool {
+	return origin == "" || origin == OriginNatural || origin == OriginSynthetic
+}
+
+// HasConstraints reports whether the formula restricts the materials it may
+// use.
+func (f Formula) HasConstraints() bool {
+	return f.NaturalsOnly || f.Vegan
+}
+
+// ConstraintViolations lists why chemical breaks the formula's constraints,
+// such as "synthetic" in a naturals-only formula. Materials of unknown
+// origin are not flagged.
+func (f Formula) ConstraintViolations(chemical AromaChemical) []string {
+	var violations []string
+	if f.NaturalsOnly && chemical.Origin == OriginSynthetic {
+		violations = append(violations, "synthetic")
+	}
+	if f.Vegan && chemical.AnimalDerived {
+		violations = append(violations, "animal-derived")
+	}
+	return violations
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormulaConstraintViolations(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		formula  Formula
+		chemical AromaChemical
+		want     string
+	}{
+		{"unconstrained", Formula{}, AromaChemical{Origin: OriginSynthetic, AnimalDerived: true}, ""},
+		{"synthetic in naturals", Formula{NaturalsOnly: true}, AromaChemical{Origin: OriginSynthetic}, "synthetic"},
+		{"unknown origin in naturals", Formula{NaturalsOnly: true}, AromaChemical{}, ""},
+		{"animal natural in vegan", Formula{Vegan: true}, AromaChemical{Origin: OriginNatural, AnimalDerived: true}, "animal-derived"},
+		{"synthetic in vegan", Formula{Vegan: true}, AromaChemical{Origin: OriginSynthetic}, ""},
+		{"both", Formula{NaturalsOnly: true, Vegan: true}, AromaChemical{Origin: OriginSynthetic, AnimalDerived: true}, "synthetic, animal-derived"},
+	}
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := strings.Join(tt.formula.ConstraintViolations(tt.chemical), ", "); got != tt.want {
+				t.Fatalf("ConstraintViolations() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProductionBatchOutOfTolerance(t *testing.T) {
+	t.Parallel()
+
+	batch := ProductionBatch{ToleranceMg: 5, TolerancePercent: 2}
+	weighed := func(target, actual float64) ProductionBatchLine {
+		return ProductionBatchLine{TargetQuantity: target, ActualQuantity: &actual}
+	}
+
+	cases := []struct {
+		name string
+		line ProductionBatchLine
+		want bool
+	}{
+		{"not weighed", ProductionBatchLine{TargetQuantity: 100}, false},
+		{"absolute allowance on a small line", weighed(100, 105), false},
+		{"beyond absolute allowance", weighed(100, 94), true},
+		{"percentage allowance on a large line", weighed(1000, 1020), false},
+		{"beyond percentage allowance", weighed(1000, 1021), true},
+	}
+
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := batch.OutOfTolerance(tt.line); got != tt.want {
+				t.Fatalf("OutOfTolerance() = %t, want %t (deviation %.1f)", got, tt.want, tt.line.Deviation())
+			}
+		})
+	}
+}
+
+func TestProductionBatchFinalizeBlockers(t *testing.T) {
+	t.Parallel()
+
+	over := 130.0
+	exact := 50.0
+	batch := ProductionBatch{
+		ToleranceMg: 5,
+		Lines: []ProductionBatchLine{
+			{Position: 1, IngredientName: "Hedione", TargetQuantity: 100, ActualQuantity: &over},
+			{Position: 2, IngredientName: "Iso E Super", TargetQuantity: 50, ActualQuantity: &exact},
+			{Position: 3, IngredientName: "Ethanol", TargetQuantity: 800},
+		},
+	}
+
+	blockers := batch.FinalizeBlockers()
+	if len(blockers) != 2 {
+		t.Fatalf("expected two blockers, got %v", blockers)
+	}
+
+	batch.Lines[0].Justification = "Balance recalibrated mid-run; overage accepted."
+	remain := 800.0
+	batch.Lines[2].ActualQuantity = &remain
+	if blockers := batch.FinalizeBlockers(); len(blockers) != 0 {
+		t.Fatalf("expected no blockers, got %v", blockers)
+	}
+}
+
+func TestProductionBatchCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	if got := (ProductionBatch{Status: ProductionBatchOpen}).Checkpoints(); got != nil {
+		t.Fatalf("open batch should have no checkpoints, got %v", got)
+	}
+
+	finalized := time.Date(2025, 1, 10, 15, 0, 0, 0, time.UTC)
+	checkpoints := ProductionBatch{Status: ProductionBatchFinalized, FinalizedAt: &finalized}.Checkpoints()
+	if len(checkpoints) != len(MacerationCheckpointDays)+len(StabilityCheckDays) {
+		t.Fatalf("unexpected checkpoints: %v", checkpoints)
+	}
+	first, last := checkpoints[0], checkpoints[len(checkpoints)-1]
+	if first.Kind != CheckpointMaceration || !first.Due.Equal(finalized.AddDate(0, 0, 7)) {
+		t.Fatalf("unexpected first checkpoint: %+v", first)
+	}
+	if last.Kind != CheckpointStability || last.Day != 180 {
+		t.Fatalf("unexpected last checkpoint: %+v", last)
+	}
+	for i := 1; i < len(checkpoints); i++ {
+		if checkpoints[i].Due.Before(checkpoints[i-1].Due) {
+			t.Fatalf("checkpoints out of order: %v", checkpoints)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ScaleLevel maps a stored numeric value to its display label and any
+// alternative labels accepted by importers.
+type ScaleLevel struct {
+	Value   int      `json:"value"`
+	Label   string   `json:"label"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Scale is an ordered list of levels used for label↔value conversion.
+type Scale struct {
+	Levels []ScaleLevel `json:"levels"`
+}
+
+// Value resolves a label or alias to its numeric value, returning 0 when unknown.
+func (s Scale) Value(label string) int {
+	key := strings.ToLower(strings.TrimSpace(label))
+	if key == "" {
+		return 0
+	}
+	for _, level := range s.Levels {
+		if strings.ToLower(level.Label) == key {
+			return level.Value
+		}
+		for _, alias := range level.Aliases {
+			if strings.ToLower(alias) == key {
+				return level.Value
+			}
+		}
+	}
+	return 0
+}
+
+// Label returns the display label for value, or an empty string when it is off the scale.
+func (s Scale) Label(value int) string {
+	for _, level := range s.Levels {
+		if level.Value == value {
+			return level.Label
+		}
+	}
+	return ""
+}
+
+// Max returns the highest value on the scale.
+func (s Scale) Max() int {
+	max := 0
+	for _, level := range s.Levels {
+		if level.Value > max {
+			max = level.Value
+		}
+	}
+	return max
+}
+
+// Labels lists the display labels in scale order.
+func (s Scale) Labels() []string {
+	labels := make([]string, 0, len(s.Levels))
+	for _, level := range s.Levels {
+		labels = append(labels, level.Label)
+	}
+	return labels
+}
+
+// DefaultStrengthScale is the built-in 1–8 odour strength scale.
+var DefaultStrengthScale = Scale{Levels: []ScaleLevel{
+	{Value: 1, Label: "Very Low"},
+	{Value: 2, Label: "Low"},
+	{Value: 3, Label: "Low-Medium"},
+	{Value: 4, Label: "Medium"},
+	{Value: 5, Label: "Medium-High"},
+	{Value: 6, Label: "High", Aliases: []string{"High (in effect)"}},
+	{Value: 7, Label: "Very High"},
+	{Value: 8, Label: "Extreme"},
+}}
+
+// DefaultPopularityScale is the built-in 1–4 popularity scale.
+var DefaultPopularityScale = Scale{Levels: []ScaleLevel{
+	{Value: 1, Label: "Low", Aliases: []string{"Low (perfumery)", "Specialist"}},
+	{Value: 2, Label: "Medium", Aliases: []string{"Niche"}},
+	{Value: 3, Label: "High", Aliases: []string{"Foundational", "Restricted", "Restricted/Banned", "Banned/Restricted", "High (endangered)"}},
+	{Value: 4, Label: "High Impact"},
+}}
+
+var (
+	scalesMu        sync.RWMutex
+	strengthScale   = DefaultStrengthScale
+	popularityScale = DefaultPopularityScale
+)
+
+// StrengthScale returns the active strength scale.
+func StrengthScale() Scale {
+	scalesMu.RLock()
+	defer scalesMu.RUnlock()
+	return strengthScale
+}
+
+// PopularityScale returns the active popularity scale.
+func PopularityScale() Scale {
+	scalesMu.RLock()
+	defer scalesMu.RUnlock()
+	return popularityScale
+}
+
+// ConfigureScales replaces the active scales; empty scales keep the current definition.
+func ConfigureScales(strength, popularity Scale) {
+	scalesMu.Lock()
+	defer scalesMu.Unlock()
+	if len(strength.Levels) > 0 {
+		strengthScale = strength
+	}
+	if len(popularity.Levels) > 0 {
+		popularityScale = popularity
+	}
+}
+
+// LoadScalesFile reads custom scale definitions from a JSON file shaped as
+// {"strength": {"levels": [...]}, "popularity": {"levels": [...]}} and installs them.
+func LoadScalesFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read scales file: %w", err)
+	}
+	var file struct {
+		Strength   Scale `json:"strength"`
+		Popularity Scale `json:"popularity"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parse scales file: %w", err)
+	}
+	for name, scale := range map[string]Scale{"strength": file.Strength, "popularity": file.Popularity} {
+		for _, level := range scale.Levels {
+			if level.Value <= 0 || strings.TrimSpace(level.Label) == "" {
+				return fmt.Errorf("%s scale: levels need a positive value and a label", name)
+			}
+		}
+	}
+	ConfigureScales(file.Strength, file.Popularity)
+	return nil
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultScalesMapLabels(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		scale Scale
+		label string
+		want  int
+	}{
+		{"strength label", DefaultStrengthScale, "Medium-High", 5},
+		{"strength alias", DefaultStrengthScale, " high (in effect) ", 6},
+		{"popularity alias", DefaultPopularityScale, "Niche", 2},
+		{"popularity unknown", DefaultPopularityScale, "viral", 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.scale.Value(tt.label); got != tt.want {
+				t.Fatalf("Value(%q) = %d, want %d", tt.label, got, tt.want)
+			}
+		})
+	}
+
+	if got := DefaultStrengthScale.Label(8); got != "Extreme" {
+		t.Fatalf("Label(8) = %q, want Extreme", got)
+	}
+	if got := DefaultPopularityScale.Max(); got != 4 {
+		t.Fatalf("Max() = %d, want 4", got)
+	}
+}
+
+func TestLoadScalesFile(t *testing.T) {
+	t.Cleanup(func() { ConfigureScales(DefaultStrengthScale, DefaultPopularityScale) })
+
+	path := filepath.Join(t.TempDir(), "scales.json")
+	content := `{"strength": {"levels": [{"value": 1, "label": "Faint"}, {"value": 2, "label": "Bold", "aliases": ["Loud"]}]}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write scales file: %v", err)
+	}
+
+	if err := LoadScalesFile(path); err != nil {
+		t.Fatalf("LoadScalesFile returned error: %v", err)
+	}
+	if got := StrengthScale().Value("loud"); got != 2 {
+		t.Fatalf("custom alias resolved to %d, want 2", got)
+	}
+	if got := PopularityScale().Max(); got != 4 {
+		t.Fatalf("expected popularity scale to keep defaults, got max %d", got)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"popularity": {"levels": [{"value": 0, "label": ""}]}}`), 0o600); err != nil {
+		t.Fatalf("write invalid scales file: %v", err)
+	}
+	if err := LoadScalesFile(invalid); err == nil {
+		t.Fatal("expected error for invalid scale levels")
+	}
+}
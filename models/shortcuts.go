@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	// ShortcutNewFormula starts a new formula from the formulas section.
+	ShortcutNewFormula = "new_formula"
+	// ShortcutSearch focuses the search box of the current section.
+	ShortcutSearch = "search"
+	// ShortcutSave submits the open editor.
+	ShortcutSave = "save"
+)
+
+// Shortcut is a workspace action that can be bound to a key combination.
+// Keys are written like "mod+s": any of the modifiers mod (Ctrl, or Cmd on
+// macOS), alt and shift, in that order, followed by a single character or
+// a named key such as "enter" or "f2". The plus key cannot be bound.
+type Shortcut struct {
+	Action  string
+	Label   string
+	Default string
+}
+
+// Shortcuts lists the bindable actions in display order.
+var Shortcuts = []Shortcut{
+	{Action: ShortcutNewFormula, Label: "New formula", Default: "alt+n"},
+	{Action: ShortcutSearch, Label: "Search", Default: "/"},
+	{Action: ShortcutSave, Label: "Save", Default: "mod+s"},
+}
+
+var namedKeys = map[string]bool{
+	"enter": true, "escape": true, "tab": true, "space": true, "backspace": true, "delete": true,
+	"arrowup": true, "arrowdown": true, "arrowleft": true, "arrowright": true, "home": true, "end": true,
+	"f1": true, "f2": true, "f3": true, "f4": true, "f5": true, "f6": true,
+	"f7": true, "f8": true, "f9": true, "f10": true, "f11": true, "f12": true,
+}
+
+// NormalizeShortcutKey canonicalises a key combination such as
+// "Shift + Ctrl + K" to "mod+shift+k". It reports false for combinations
+// that cannot be typed; shift only combines with letters and named keys,
+// since it changes which character other keys produce.
+func NormalizeShortcutKey(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", false
+	}
+	parts := strings.Split(strings.ReplaceAll(value, " ", ""), "+")
+	key := parts[len(parts)-1]
+	modifiers := map[string]bool{}
+	for _, part := range parts[:len(parts)-1] {
+		switch part {
+		case "ctrl", "cmd", "meta", "mod":
+			modifiers["mod"] = true
+		case "alt", "option":
+			modifiers["alt"] = true
+		case "shift":
+			modifiers["shift"] = true
+		default:
+			return "", false
+		}
+	}
+	letter := len(key) == 1 && key[0] >= 'a' && key[0] <= 'z'
+	switch {
+	case namedKeys[key]:
+	case len([]rune(key)) == 1:
+		if modifiers["shift"] && !letter {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	var combo []string
+	for _, modifier := range []string{"mod", "alt", "shift"} {
+		if modifiers[modifier] {
+			combo = append(combo, modifier)
+		}
+	}
+	return strings.Join(append(combo, key), "+"), true
+}
+
+// ParseShortcutOverrides reads the overrides stored on a user, dropping
+// unknown actions and keys that no longer normalise.
+func ParseShortcutOverrides(stored string) map[string]string {
+	overrides := map[string]string{}
+	if strings.TrimSpace(stored) == "" {
+		return overrides
+	}
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(stored), &raw); err != nil {
+		return overrides
+	}
+	for _, shortcut := range Shortcuts {
+		if key, ok := NormalizeShortcutKey(raw[shortcut.Action]); ok {
+			overrides[shortcut.Action] = key
+		}
+	}
+	return overrides
+}
+
+// ShortcutBindings merges overrides over the defaults, returning the key of
+// every action.
+func ShortcutBindings(overrides map[string]string) map[string]string {
+	bindings := make(map[string]string, len(Shortcuts))
+	for _, shortcut := range Shortcuts {
+		bindings[shortcut.Action] = shortcut.Default
+		if key, ok := overrides[shortcut.Action]; ok {
+			bindings[shortcut.Action] = key
+		}
+	}
+	return bindings
+}
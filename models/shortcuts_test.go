@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestNormalizeShortcutKey(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"mod+s", "mod+s", true},
+		{"Shift + Ctrl + K", "mod+shift+k", true},
+		{"cmd+option+F2", "mod+alt+f2", true},
+		{"/", "/", true},
+		{"shift+/", "", false},
+		{"hyper+k", "", false},
+		{"mod+", "", false},
+		{"", "", false},
+	}
+	for _, tt := range cases {
+		got, ok := NormalizeShortcutKey(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Fatalf("NormalizeShortcutKey(%q) = %q, %t, want %q, %t", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestShortcutBindingsIgnoresStaleOverrides(t *testing.T) {
+	t.Parallel()
+
+	bindings := ShortcutBindings(ParseShortcutOverrides(`{"save":"Ctrl+Enter","search":"shift+?","retired":"x"}`))
+	if bindings[ShortcutSave] != "mod+enter" || bindings[ShortcutSearch] != "/" || len(bindings) != len(Shortcuts) {
+		t.Fatalf("unexpected bindings %v", bindings)
+	}
+}
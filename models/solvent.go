@@ -0,0 +1,55 @@
+package models
+
+import "strings"
+
+const (
+	// SolventEthanol is perfumer's alcohol, the default carrier for fine fragrance.
+	SolventEthanol = "ethanol"
+	// SolventDPG is dipropylene glycol, common for diffusers and oil-based work.
+	SolventDPG = "dpg"
+	// SolventIPM is isopropyl myristate, used for oil perfumes.
+	SolventIPM = "ipm"
+	// DefaultSolvent is used when no explicit preference has been saved.
+	DefaultSolvent = SolventEthanol
+)
+
+// Solvent describes a carrier that can dilute a concentrate into a finished
+// product. Density is in g/mL at 20 °C and INCIName is how the carrier is
+// declared on a cosmetic ingredient list.
+type Solvent struct {
+	ID         string
+	Label      string
+	CASNumber  string
+	Density    float64
+	ShortLabel string
+	INCIName   string
+}
+
+// Solvents lists the supported finished-product carriers in display order.
+var Solvents = []Solvent{
+	{ID: SolventEthanol, Label: "Ethanol", CASNumber: "64-17-5", Density: 0.789, ShortLabel: "Ethanol", INCIName: "Alcohol Denat."},
+	{ID: SolventDPG, Label: "Dipropylene Glycol (DPG)", CASNumber: "25265-71-8", Density: 1.023, ShortLabel: "DPG", INCIName: "Dipropylene Glycol"},
+	{ID: SolventIPM, Label: "Isopropyl Myristate (IPM)", CASNumber: "110-27-0", Density: 0.853, ShortLabel: "IPM", INCIName: "Isopropyl Myristate"},
+}
+
+// SolventByID resolves a solvent identifier, falling back to the default.
+func SolventByID(id string) Solvent {
+	key := strings.ToLower(strings.TrimSpace(id))
+	for _, solvent := range Solvents {
+		if solvent.ID == key {
+			return solvent
+		}
+	}
+	return Solvents[0]
+}
+
+// ValidSolvent reports whether id names a supported solvent.
+func ValidSolvent(id string) bool {
+	key := strings.ToLower(strings.TrimSpace(id))
+	for _, solvent := range Solvents {
+		if solvent.ID == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestCanonicalTermIgnoresCaseAccentsAndSpacing(t *testing.T) {
+	t.Parallel()
+
+	terms := []string{"Floral", "Fresh Citrus", "Boisé"}
+	cases := map[string]string{
+		" floral ":       "Floral",
+		"fresh   citrus": "Fresh Citrus",
+		"BOISE":          "Boisé",
+	}
+	for in, want := range cases {
+		if got, ok := CanonicalTerm(terms, in); !ok || got != want {
+			t.Errorf("CanonicalTerm(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+	if got, ok := CanonicalTerm(terms, "Gourmand"); ok {
+		t.Errorf("expected no match for an unknown term, got %q", got)
+	}
+	if _, ok := CanonicalTerm(terms, "  "); ok {
+		t.Errorf("expected no match for a blank value")
+	}
+}
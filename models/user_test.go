@@ -39,3 +39,17 @@ func TestNormalizeTheme(t *testing.T) {
 		t.Fatalf("NormalizeTheme returned %q, want %q", got, DefaultTheme)
 	}
 }
+
+func TestUserIsAdmin(t *testing.T) {
+	t.Parallel()
+
+	if (User{}).IsAdmin() {
+		t.Fatal("expected zero-value user to be a member")
+	}
+	if !(User{Role: RoleAdmin}).IsAdmin() {
+		t.Fatal("expected admin role to be recognised")
+	}
+	if got := NormalizeRole("owner"); got != RoleMember {
+		t.Fatalf("NormalizeRole returned %q, want %q", got, RoleMember)
+	}
+}
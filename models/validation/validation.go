@@ -0,0 +1,216 @@
+// Package validation holds the domain rules shared by every write path that
+// persists perfumery models, returning structured field errors.
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"perfugo/models"
+)
+
+// maxINCINameLength matches the size of the INCI name column.
+const maxINCINameLength = 200
+
+// FieldError describes a rule violation for a single model field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors collects field errors in the order they were detected.
+type Errors []FieldError
+
+// Error implements the error interface using the first message.
+func (e Errors) Error() string {
+	if len(e) == 0 {
+		return ""
+	}
+	return e[0].Message
+}
+
+// Add appends a field error.
+func (e *Errors) Add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// Has reports whether a field has at least one error.
+func (e Errors) Has(field string) bool {
+	for _, fe := range e {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns nil when no errors were collected, simplifying call sites.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+var pyramidPositions = []string{"top", "top-heart", "heart", "heart-base", "base", "all"}
+
+// PyramidPositions returns the canonical pyramid position values.
+func PyramidPositions() []string {
+	result := make([]string, len(pyramidPositions))
+	copy(result, pyramidPositions)
+	return result
+}
+
+// NormalizePyramidPosition converts the supplied value to its canonical representation.
+// It returns the normalized value along with a boolean indicating whether the input was valid.
+func NormalizePyramidPosition(value string) (string, bool) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return "", true
+	}
+
+	normalized := strings.ToLower(trimmed)
+	normalized = strings.ReplaceAll(normalized, "_", "-")
+	normalized = strings.ReplaceAll(normalized, " ", "-")
+
+	for _, option := range pyramidPositions {
+		if normalized == option {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+var casPattern = regexp.MustCompile(`^(\d{2,7})-(\d{2})-(\d)$`)
+
+// syntheticCASPrefixes mark identifiers assigned to mixtures and unregistered materials.
+var syntheticCASPrefixes = []string{"UNASSIGNED-", "MIXTURE", "BLEND"}
+
+// ValidCAS reports whether value is empty, a synthetic identifier, or a CAS
+// registry number with a correct check digit.
+func ValidCAS(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return true
+	}
+	upper := strings.ToUpper(value)
+	for _, prefix := range syntheticCASPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+
+	match := casPattern.FindStringSubmatch(value)
+	if match == nil {
+		return false
+	}
+	digits := match[1] + match[2]
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * (len(digits) - i)
+	}
+	check, _ := strconv.Atoi(match[3])
+	return sum%10 == check
+}
+
+// AromaChemical validates an aroma chemical and canonicalises its pyramid position in place.
+func AromaChemical(chemical *models.AromaChemical) Errors {
+	var errs Errors
+	if chemical == nil {
+		errs.Add("ingredient_name", "Ingredient name is required.")
+		return errs
+	}
+
+	chemical.IngredientName = strings.TrimSpace(chemical.IngredientName)
+	if chemical.IngredientName == "" {
+		errs.Add("ingredient_name", "Ingredient name is required.")
+	}
+
+	if pyramid, ok := NormalizePyramidPosition(chemical.PyramidPosition); ok {
+		chemical.PyramidPosition = pyramid
+	} else {
+		errs.Add("pyramid_position", "Select a valid pyramid position.")
+	}
+
+	if !ValidCAS(chemical.CASNumber) {
+		errs.Add("cas_number", "CAS number must look like 64-17-5 and have a valid check digit.")
+	}
+	chemical.INCIName = strings.TrimSpace(chemical.INCIName)
+	if len([]rune(chemical.INCIName)) > maxINCINameLength {
+		errs.Add("inci_name", fmt.Sprintf("INCI name must be at most %d characters.", maxINCINameLength))
+	}
+	if max := models.StrengthScale().Max(); chemical.Strength < 0 || chemical.Strength > max {
+		errs.Add("strength", fmt.Sprintf("Strength must be between 0 and %d.", max))
+	}
+	if max := models.PopularityScale().Max(); chemical.Popularity < 0 || chemical.Popularity > max {
+		errs.Add("popularity", fmt.Sprintf("Popularity must be between 0 and %d.", max))
+	}
+	percentage(&errs, "recommended_dilution", "Recommended dilution", chemical.RecommendedDilution)
+	percentage(&errs, "dilution_percentage", "Dilution percentage", chemical.DilutionPercentage)
+	percentage(&errs, "max_ifra_percentage", "Max IFRA percentage", chemical.MaxIFRAPercentage)
+	if chemical.PricePerMg < 0 {
+		errs.Add("price_per_mg", "Price per mg cannot be negative.")
+	}
+	if !models.ValidOrigin(chemical.Origin) {
+		errs.Add("origin", "Select a valid origin.")
+	}
+
+	return errs
+}
+
+// FormulaIngredient validates a single composition row.
+func FormulaIngredient(ingredient models.FormulaIngredient) Errors {
+	var errs Errors
+	hasChemical := ingredient.AromaChemicalID != nil && *ingredient.AromaChemicalID != 0
+	hasFormula := ingredient.SubFormulaID != nil && *ingredient.SubFormulaID != 0
+	switch {
+	case !hasChemical && !hasFormula:
+		errs.Add("source", "Select an ingredient or sub-formula.")
+	case hasChemical && hasFormula:
+		errs.Add("source", "Choose either an ingredient or a sub-formula, not both.")
+	}
+	if ingredient.Amount < 0 {
+		errs.Add("amount", "Ingredient amounts cannot be negative.")
+	}
+	return errs
+}
+
+// Formula validates formula metadata.
+func Formula(formula *models.Formula) Errors {
+	var errs Errors
+	if formula == nil {
+		errs.Add("name", "Formula name is required.")
+		return errs
+	}
+	formula.Name = strings.TrimSpace(formula.Name)
+	if formula.Name == "" {
+		errs.Add("name", "Formula name is required.")
+	}
+	if formula.Version < 0 {
+		errs.Add("version", "Version cannot be negative.")
+	}
+	formula.Client = strings.TrimSpace(formula.Client)
+	formula.TargetAudience = strings.TrimSpace(formula.TargetAudience)
+	if len(formula.Client) > 255 {
+		errs.Add("client", "Client must be at most 255 characters.")
+	}
+	if len(formula.TargetAudience) > 255 {
+		errs.Add("target_audience", "Target audience must be at most 255 characters.")
+	}
+	if formula.Status == "" {
+		formula.Status = models.FormulaStatusDraft
+	}
+	if !slices.Contains(models.FormulaStatuses(), formula.Status) {
+		errs.Add("status", "Choose draft, in review or approved.")
+	}
+	return errs
+}
+
+func percentage(errs *Errors, field, label string, value float64) {
+	if value < 0 || value > 100 {
+		errs.Add(field, label+" must be between 0 and 100.")
+	}
+}
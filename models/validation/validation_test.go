@@ -0,0 +1,119 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"perfugo/models"
+)
+
+func TestValidCAS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", true},
+		{"64-17-5", true},
+		{"6790-58-5", true},
+		{"6790-58-4", false},
+		{"abc", false},
+		{"UNASSIGNED-rose-accord", true},
+		{"mixture", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+			if got := ValidCAS(tt.value); got != tt.want {
+				t.Fatalf("ValidCAS(%q) = %t, want %t", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAromaChemical(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		chemical   models.AromaChemical
+		wantFields []string
+		wantPyr    string
+	}{
+		{
+			name:     "valid",
+			chemical: models.AromaChemical{IngredientName: " Ambroxan ", CASNumber: "6790-58-5", PyramidPosition: "Heart Base", Strength: 5, MaxIFRAPercentage: 10},
+			wantPyr:  "heart-base",
+		},
+		{
+			name:       "missing name and bad pyramid",
+			chemical:   models.AromaChemical{PyramidPosition: "middle"},
+			wantFields: []string{"ingredient_name", "pyramid_position"},
+		},
+		{
+			name:       "out of range values",
+			chemical:   models.AromaChemical{IngredientName: "Test", Strength: 9, Popularity: -1, DilutionPercentage: 120, PricePerMg: -0.1},
+			wantFields: []string{"strength", "popularity", "dilution_percentage", "price_per_mg"},
+		},
+		{
+			name:       "overlong INCI name",
+			chemical:   models.AromaChemical{IngredientName: "Test", INCIName: strings.Repeat("x", 201)},
+			wantFields: []string{"inci_name"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			chemical := tt.chemical
+			errs := AromaChemical(&chemical)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("expected %d errors, got %+v", len(tt.wantFields), errs)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Fatalf("error %d field = %q, want %q", i, errs[i].Field, field)
+				}
+			}
+			if tt.wantPyr != "" && chemical.PyramidPosition != tt.wantPyr {
+				t.Fatalf("PyramidPosition = %q, want %q", chemical.PyramidPosition, tt.wantPyr)
+			}
+			if errs.Err() == nil && len(tt.wantFields) > 0 {
+				t.Fatal("expected Err to return an error")
+			}
+		})
+	}
+}
+
+func TestFormulaIngredient(t *testing.T) {
+	t.Parallel()
+
+	id := uint(3)
+	if errs := FormulaIngredient(models.FormulaIngredient{AromaChemicalID: &id, Amount: 10, Unit: "mg"}); len(errs) != 0 {
+		t.Fatalf("expected valid ingredient, got %+v", errs)
+	}
+	errs := FormulaIngredient(models.FormulaIngredient{AromaChemicalID: &id, SubFormulaID: &id, Amount: -1})
+	if !errs.Has("source") || !errs.Has("amount") {
+		t.Fatalf("expected source and amount errors, got %+v", errs)
+	}
+}
+
+func TestFormulaBrief(t *testing.T) {
+	t.Parallel()
+
+	formula := models.Formula{Name: "Commission", Client: "  Maison Nord  "}
+	if errs := Formula(&formula); len(errs) != 0 {
+		t.Fatalf("expected valid brief, got %+v", errs)
+	}
+	if formula.Client != "Maison Nord" || formula.Status != models.FormulaStatusDraft {
+		t.Fatalf("brief not normalised: %+v", formula)
+	}
+	if errs := Formula(&models.Formula{Name: "Commission", Status: "shipped"}); !errs.Has("status") {
+		t.Fatalf("expected status error, got %+v", errs)
+	}
+}